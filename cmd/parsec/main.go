@@ -1,3 +1,6 @@
+// Command parsec runs parsec. Every component, from the trust store to the servers, is
+// built from configuration (a --config file, PARSEC_ environment variables, and flags)
+// by internal/config's Provider; without a config file, the defaults use a stub trust store.
 package main
 
 import (
@@ -0,0 +1,321 @@
+// Package audit records parsec's security decisions, separately from operational logs.
+//
+// Every token issuance and every denial is an audit record. Each record carries the
+// SHA-256 hash of its content and, optionally, the hash of the record before it and a
+// signature over its hash, so deleting, reordering, or editing records is detectable.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// Events
+const (
+	// EventAuthzCheck is an ext_authz check (including forward auth)
+	EventAuthzCheck = "authz_check"
+
+	// EventTokenExchange is a token exchange
+	EventTokenExchange = "token_exchange"
+)
+
+// Outcomes
+const (
+	// OutcomeIssued is a decision that issued tokens
+	OutcomeIssued = "issued"
+
+	// OutcomeDenied is a decision that denied the request
+	OutcomeDenied = "denied"
+)
+
+// Record is an audit record of one decision
+type Record struct {
+	// Sequence numbers the records of an instance from 1, starting again when it restarts
+	Sequence uint64 `json:"seq"`
+
+	// Time is when the record was logged
+	Time time.Time `json:"time"`
+
+	// Instance identifies the replica that made the decision
+	Instance *instance.Identity `json:"instance,omitempty"`
+
+	// Event is the kind of request decided (e.g., EventAuthzCheck)
+	Event string `json:"event"`
+
+	// Outcome is OutcomeIssued or OutcomeDenied
+	Outcome string `json:"outcome"`
+
+	// Code is the error code of a denial: a gRPC code for checks, an OAuth error
+	// code for exchanges
+	Code string `json:"code,omitempty"`
+
+	// Reason explains a denial
+	Reason string `json:"reason,omitempty"`
+
+	// Subject is the party the request was made on behalf of, if validated
+	Subject *Identity `json:"subject,omitempty"`
+
+	// Actor is the party that made the request, if validated
+	Actor *Identity `json:"actor,omitempty"`
+
+	// ClientID is the authenticated OAuth client, if any
+	ClientID string `json:"client_id,omitempty"`
+
+	// Request describes the request being authorized, for checks
+	Request *Request `json:"request,omitempty"`
+
+	// TokenTypes are the types of token issued or requested
+	TokenTypes []string `json:"token_types,omitempty"`
+
+	// Audiences are the audiences tokens were issued or requested for
+	Audiences []string `json:"audiences,omitempty"`
+
+	// Scope is the scope granted (or requested, for denials)
+	Scope string `json:"scope,omitempty"`
+
+	// TransactionID is the txn claim of issued transaction tokens
+	TransactionID string `json:"txn,omitempty"`
+
+	// RedactedClaims are the paths of the claims issuers removed or hashed, never
+	// their values
+	RedactedClaims []string `json:"redacted_claims,omitempty"`
+
+	// Policy records the policy decisions that applied, such as a route's required
+	// scopes or the scope requested before the scope policy
+	Policy map[string]string `json:"policy,omitempty"`
+
+	// PrevHash is the Hash of the instance's previous record, if records are chained
+	PrevHash string `json:"prev_hash,omitempty"`
+
+	// Hash is the hex SHA-256 hash of the record's JSON without Hash, KeyID, and Signature
+	Hash string `json:"hash"`
+
+	// KeyID identifies the key of Signature
+	KeyID string `json:"kid,omitempty"`
+
+	// Signature is a JWS with detached payload (RFC 7515 appendix F) over Hash,
+	// if records are signed
+	Signature string `json:"sig,omitempty"`
+}
+
+// Identity describes a validated party
+type Identity struct {
+	Subject     string `json:"sub"`
+	Issuer      string `json:"iss,omitempty"`
+	TrustDomain string `json:"trust_domain,omitempty"`
+}
+
+// IdentityOf returns the Identity of a validation result, or nil for a nil or
+// anonymous result
+func IdentityOf(result *trust.Result) *Identity {
+	if result == nil || result.Subject == "" {
+		return nil
+	}
+	return &Identity{
+		Subject:     result.Subject,
+		Issuer:      result.Issuer,
+		TrustDomain: result.TrustDomain,
+	}
+}
+
+// Request describes the request an ext_authz check authorized
+type Request struct {
+	Method    string `json:"method,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Authority string `json:"authority,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+// digest returns the SHA-256 hash of the record's content
+func (r *Record) digest() (string, error) {
+	content := *r
+	content.Hash, content.KeyID, content.Signature = "", "", ""
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Config configures a Logger
+type Config struct {
+	// Sinks receive every record, in order
+	Sinks []Sink
+
+	// Chain links each record to the previous one with PrevHash
+	Chain bool
+
+	// Signer, if set, signs every record
+	Signer keys.RotatingSigner
+
+	// Instance identifies this replica in records
+	Instance *instance.Identity
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// Logger writes audit records to its sinks
+// It is safe for concurrent use; records are written one at a time, so sinks receive
+// them in sequence order.
+type Logger struct {
+	sinks    []Sink
+	chain    bool
+	signer   keys.RotatingSigner
+	instance *instance.Identity
+	clock    clock.Clock
+
+	mu       sync.Mutex
+	sequence uint64
+	prevHash string
+}
+
+// NewLogger creates a new audit logger
+func NewLogger(cfg Config) (*Logger, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, fmt.Errorf("audit logger requires at least one sink")
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &Logger{
+		sinks:    cfg.Sinks,
+		chain:    cfg.Chain,
+		signer:   cfg.Signer,
+		instance: cfg.Instance,
+		clock:    clk,
+	}, nil
+}
+
+// Log completes rec (sequence, time, instance, hashes, and signature) and writes it
+// to every sink
+// A record that fails to write to a sink still takes its place in the chain, so the
+// gap it leaves in that sink is detectable.
+func (l *Logger) Log(ctx context.Context, rec Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sequence++
+	rec.Sequence = l.sequence
+	rec.Time = l.clock.Now().UTC()
+	rec.Instance = l.instance
+	if l.chain {
+		rec.PrevHash = l.prevHash
+	}
+
+	hash, err := rec.digest()
+	if err != nil {
+		return err
+	}
+	rec.Hash = hash
+	l.prevHash = hash
+
+	if l.signer != nil {
+		if err := l.sign(ctx, &rec); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	var errs []error
+	for _, sink := range l.sinks {
+		if err := sink.Write(ctx, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sign signs the record's hash with the current key of the logger's signer
+func (l *Logger) sign(ctx context.Context, rec *Record) error {
+	signer, keyID, algorithm, err := l.signer.GetCurrentSigner(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current signer: %w", err)
+	}
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, string(keyID)); err != nil {
+		return fmt.Errorf("failed to set key ID header: %w", err)
+	}
+
+	signature, err := jws.Sign(nil,
+		jws.WithKey(jwa.SignatureAlgorithm(string(algorithm)), signer, jws.WithProtectedHeaders(headers)),
+		jws.WithDetachedPayload([]byte(rec.Hash)))
+	if err != nil {
+		return fmt.Errorf("failed to sign audit record: %w", err)
+	}
+	rec.KeyID = string(keyID)
+	rec.Signature = string(signature)
+	return nil
+}
+
+// Close closes every sink
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var errs []error
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Verify checks records an instance logged, in order: that each record's hash matches
+// its content, that chained records link to the record before them, and, if keySet is
+// not nil, that each record is signed by a key in keySet
+// Records must be consecutive; a missing record breaks the chain.
+func Verify(records []Record, keySet jwk.Set) error {
+	for i := range records {
+		rec := &records[i]
+		hash, err := rec.digest()
+		if err != nil {
+			return err
+		}
+		if hash != rec.Hash {
+			return fmt.Errorf("record %d: content does not match hash", rec.Sequence)
+		}
+
+		if i > 0 {
+			prev := &records[i-1]
+			if rec.Sequence != prev.Sequence+1 {
+				return fmt.Errorf("record %d: expected sequence %d", rec.Sequence, prev.Sequence+1)
+			}
+			if rec.PrevHash != "" && rec.PrevHash != prev.Hash {
+				return fmt.Errorf("record %d: previous hash does not match record %d", rec.Sequence, prev.Sequence)
+			}
+		}
+
+		if keySet != nil {
+			if rec.Signature == "" {
+				return fmt.Errorf("record %d: not signed", rec.Sequence)
+			}
+			if _, err := jws.Verify([]byte(rec.Signature), jws.WithKeySet(keySet), jws.WithDetachedPayload([]byte(rec.Hash))); err != nil {
+				return fmt.Errorf("record %d: invalid signature: %w", rec.Sequence, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/keys"
+)
+
+// readRecords decodes JSON lines of records
+func readRecords(t *testing.T, r io.Reader) []Record {
+	t.Helper()
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("failed to decode record: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestLogger_ChainedSignedRecords(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	var out bytes.Buffer
+	logger, err := NewLogger(Config{
+		Sinks:    []Sink{NewWriterSink(&out)},
+		Chain:    true,
+		Signer:   signer,
+		Instance: &instance.Identity{ID: "parsec-0", Version: "v1.0.0"},
+		Clock:    clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+
+	for _, rec := range []Record{
+		{Event: EventAuthzCheck, Outcome: OutcomeIssued, Subject: &Identity{Subject: "alice"}, TransactionID: "txn-1"},
+		{Event: EventAuthzCheck, Outcome: OutcomeDenied, Code: "Unauthenticated", Reason: "validation failed"},
+		{Event: EventTokenExchange, Outcome: OutcomeIssued, Audiences: []string{"parsec.test"}, Policy: map[string]string{"requested_scope": "read write"}},
+	} {
+		if err := logger.Log(ctx, rec); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+
+	records := readRecords(t, &out)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if records[0].Sequence != 1 || records[0].PrevHash != "" || records[1].PrevHash != records[0].Hash {
+		t.Errorf("expected chained records, got %+v", records[:2])
+	}
+	if records[0].Instance == nil || records[0].Instance.ID != "parsec-0" {
+		t.Errorf("expected instance, got %+v", records[0].Instance)
+	}
+
+	publicKeys, err := signer.PublicKeys(ctx)
+	if err != nil {
+		t.Fatalf("failed to get public keys: %v", err)
+	}
+	keySet := jwk.NewSet()
+	for _, publicKey := range publicKeys {
+		key, err := jwk.FromRaw(publicKey.Key)
+		if err != nil {
+			t.Fatalf("failed to create JWK: %v", err)
+		}
+		_ = key.Set(jwk.KeyIDKey, publicKey.KeyID)
+		_ = key.Set(jwk.AlgorithmKey, publicKey.Algorithm)
+		_ = keySet.AddKey(key)
+	}
+
+	if err := Verify(records, keySet); err != nil {
+		t.Fatalf("expected records to verify: %v", err)
+	}
+
+	t.Run("edited record", func(t *testing.T) {
+		edited := append([]Record(nil), records...)
+		edited[1].Reason = "allowed"
+		if err := Verify(edited, nil); err == nil || !strings.Contains(err.Error(), "does not match hash") {
+			t.Errorf("expected hash mismatch, got %v", err)
+		}
+	})
+
+	t.Run("deleted record", func(t *testing.T) {
+		if err := Verify([]Record{records[0], records[2]}, nil); err == nil {
+			t.Error("expected broken chain")
+		}
+	})
+
+	t.Run("rehashed record", func(t *testing.T) {
+		// Without the key, a record cannot be edited and re-signed
+		edited := append([]Record(nil), records...)
+		edited[2].Outcome = OutcomeDenied
+		edited[2].Hash, _ = edited[2].digest()
+		if err := Verify(edited, keySet); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+			t.Errorf("expected invalid signature, got %v", err)
+		}
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	// Records are appended across restarts
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatalf("failed to open sink: %v", err)
+		}
+		logger, err := NewLogger(Config{Sinks: []Sink{sink}})
+		if err != nil {
+			t.Fatalf("failed to create logger: %v", err)
+		}
+		if err := logger.Log(context.Background(), Record{Event: EventTokenExchange, Outcome: OutcomeIssued}); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+		if err := logger.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+	if records := readRecords(t, file); len(records) != 2 {
+		t.Errorf("expected 2 records, got %d", len(records))
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected owner-only permissions, got %v", info.Mode().Perm())
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var produced kafkaProduceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/parsec-audit" || r.Header.Get("Content-Type") != "application/vnd.kafka.json.v2+json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&produced); err != nil {
+			t.Errorf("failed to decode produce request: %v", err)
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":1}]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewKafkaSink(KafkaSinkConfig{URL: server.URL, Topic: "parsec-audit", Key: "parsec-0"})
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	if err := sink.Write(context.Background(), []byte(`{"seq":1}`)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if len(produced.Records) != 1 || produced.Records[0].Key != "parsec-0" || string(produced.Records[0].Value) != `{"seq":1}` {
+		t.Errorf("unexpected produce request %+v", produced)
+	}
+
+	unknownTopic, _ := NewKafkaSink(KafkaSinkConfig{URL: server.URL, Topic: "other"})
+	if err := unknownTopic.Write(context.Background(), []byte(`{}`)); err == nil {
+		t.Error("expected error for failed produce")
+	}
+}
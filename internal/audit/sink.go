@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Sink receives encoded audit records
+type Sink interface {
+	// Write writes one JSON-encoded record
+	Write(ctx context.Context, record []byte) error
+
+	// Close releases the sink's resources
+	Close() error
+}
+
+// WriterSink writes records to an io.Writer as JSON lines
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a sink that writes to w (e.g., os.Stdout)
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write implements Sink
+func (s *WriterSink) Write(ctx context.Context, record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(record[:len(record):len(record)], '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close implements Sink
+// The writer is not closed; it belongs to the caller.
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// FileSink appends records to a file as JSON lines
+type FileSink struct {
+	WriterSink
+	file *os.File
+}
+
+// NewFileSink opens path for appending, creating it readable only by its owner
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+	return &FileSink{WriterSink: WriterSink{w: file}, file: file}, nil
+}
+
+// Close implements Sink
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// KafkaSink produces records to a Kafka topic through an HTTP bridge that speaks the
+// Kafka REST proxy v2 API (Confluent REST Proxy or the Strimzi Kafka Bridge)
+type KafkaSink struct {
+	endpoint   string
+	key        string
+	httpClient *http.Client
+}
+
+// KafkaSinkConfig configures a KafkaSink
+type KafkaSinkConfig struct {
+	// URL is the base URL of the REST proxy (e.g., "http://kafka-bridge:8080")
+	URL string
+
+	// Topic is the topic records are produced to
+	Topic string
+
+	// Key is the key of every record
+	// Keying by instance keeps an instance's records on one partition, in order.
+	Key string
+
+	// HTTPClient is an optional HTTP client
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+}
+
+// NewKafkaSink creates a sink that produces to a Kafka topic
+func NewKafkaSink(cfg KafkaSinkConfig) (*KafkaSink, error) {
+	if cfg.URL == "" || cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka audit sink requires url and topic")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &KafkaSink{
+		endpoint:   strings.TrimSuffix(cfg.URL, "/") + "/topics/" + url.PathEscape(cfg.Topic),
+		key:        cfg.Key,
+		httpClient: httpClient,
+	}, nil
+}
+
+// kafkaProduceRequest is a Kafka REST proxy v2 produce request
+type kafkaProduceRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string          `json:"key,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Write implements Sink
+func (s *KafkaSink) Write(ctx context.Context, record []byte) error {
+	body, err := json.Marshal(kafkaProduceRequest{
+		Records: []kafkaRecord{{Key: s.key, Value: record}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Kafka produce request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Kafka produce request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to produce audit record: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to produce audit record: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close implements Sink
+func (s *KafkaSink) Close() error {
+	return nil
+}
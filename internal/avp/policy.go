@@ -0,0 +1,310 @@
+// Package avp authorizes token issuance with Amazon Verified Permissions, which
+// evaluates Cedar policies in a policy store
+package avp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+const (
+	// DefaultPrincipalType is the default Cedar entity type of subjects
+	DefaultPrincipalType = "Parsec::Subject"
+
+	// DefaultActionType is the default Cedar entity type of actions
+	DefaultActionType = "Parsec::Action"
+
+	// DefaultActionID is the default action requested
+	DefaultActionID = "IssueToken"
+
+	// DefaultResourceType is the default Cedar entity type of resources
+	DefaultResourceType = "Parsec::Resource"
+)
+
+// signingName is the service name requests are signed for
+const signingName = "verifiedpermissions"
+
+// maxResponseBytes caps the size of responses read
+const maxResponseBytes = 1 << 20
+
+// Policy is a service.AuthorizationPolicy that asks a Verified Permissions policy
+// store whether the subject may be issued tokens for the request
+// The principal is the subject, the resource is the request's authority (or the
+// first audience, for token exchange), and the context carries the rest of the
+// request. Anything but an ALLOW decision denies issuance.
+type Policy struct {
+	policyStoreID string
+	endpoint      string
+	region        string
+	credentials   aws.CredentialsProvider
+	signer        *v4.Signer
+	httpClient    *http.Client
+
+	principalType string
+	actionType    string
+	actionID      string
+	resourceType  string
+}
+
+// Config configures a Verified Permissions policy
+type Config struct {
+	// PolicyStoreID is the policy store evaluating requests
+	PolicyStoreID string
+
+	// Region is the policy store's region
+	Region string
+
+	// Endpoint optionally overrides the Verified Permissions endpoint, such as with a
+	// VPC endpoint (default: https://verifiedpermissions.<region>.amazonaws.com)
+	Endpoint string
+
+	// PrincipalType, ActionType, ActionID, and ResourceType name the Cedar entities
+	// of requests (defaults: DefaultPrincipalType, DefaultActionType, DefaultActionID,
+	// and DefaultResourceType)
+	PrincipalType string
+	ActionType    string
+	ActionID      string
+	ResourceType  string
+
+	// Timeout bounds each request (default: 5 seconds)
+	Timeout time.Duration
+
+	// Credentials sign requests
+	// If nil, credentials come from the default AWS credential chain
+	Credentials aws.CredentialsProvider
+
+	// Transport sends requests (default: http.DefaultTransport)
+	Transport http.RoundTripper
+}
+
+// NewPolicy creates a Verified Permissions policy
+func NewPolicy(ctx context.Context, cfg Config) (*Policy, error) {
+	if cfg.PolicyStoreID == "" {
+		return nil, fmt.Errorf("policy_store_id is required")
+	}
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("region is required")
+	}
+
+	credentials := cfg.Credentials
+	if credentials == nil {
+		awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		credentials = awsCfg.Credentials
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://verifiedpermissions.%s.amazonaws.com", cfg.Region)
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Policy{
+		policyStoreID: cfg.PolicyStoreID,
+		endpoint:      strings.TrimSuffix(endpoint, "/") + "/",
+		region:        cfg.Region,
+		credentials:   credentials,
+		signer:        v4.NewSigner(),
+		httpClient:    &http.Client{Transport: transport, Timeout: timeout},
+		principalType: withDefault(cfg.PrincipalType, DefaultPrincipalType),
+		actionType:    withDefault(cfg.ActionType, DefaultActionType),
+		actionID:      withDefault(cfg.ActionID, DefaultActionID),
+		resourceType:  withDefault(cfg.ResourceType, DefaultResourceType),
+	}, nil
+}
+
+// entityIdentifier identifies a Cedar entity
+type entityIdentifier struct {
+	EntityType string `json:"entityType"`
+	EntityID   string `json:"entityId"`
+}
+
+// actionIdentifier identifies a Cedar action
+type actionIdentifier struct {
+	ActionType string `json:"actionType"`
+	ActionID   string `json:"actionId"`
+}
+
+// attributeValue is a Cedar value in a request's context
+type attributeValue struct {
+	String *string          `json:"string,omitempty"`
+	Set    []attributeValue `json:"set,omitempty"`
+}
+
+// isAuthorizedInput is the body of an IsAuthorized request
+type isAuthorizedInput struct {
+	PolicyStoreID string           `json:"policyStoreId"`
+	Principal     entityIdentifier `json:"principal"`
+	Action        actionIdentifier `json:"action"`
+	Resource      entityIdentifier `json:"resource"`
+	Context       struct {
+		ContextMap map[string]attributeValue `json:"contextMap"`
+	} `json:"context"`
+}
+
+// isAuthorizedOutput is the body of an IsAuthorized response
+type isAuthorizedOutput struct {
+	Decision            string `json:"decision"`
+	DeterminingPolicies []struct {
+		PolicyID string `json:"policyId"`
+	} `json:"determiningPolicies"`
+	Errors []struct {
+		ErrorDescription string `json:"errorDescription"`
+	} `json:"errors"`
+}
+
+// Authorize implements service.AuthorizationPolicy
+func (p *Policy) Authorize(ctx context.Context, req *service.IssueRequest) error {
+	if req.Subject == nil {
+		return fmt.Errorf("%w: no subject", service.ErrIssuanceDenied)
+	}
+
+	input := isAuthorizedInput{
+		PolicyStoreID: p.policyStoreID,
+		Principal:     entityIdentifier{EntityType: p.principalType, EntityID: req.Subject.Subject},
+		Action:        actionIdentifier{ActionType: p.actionType, ActionID: p.actionID},
+		Resource:      entityIdentifier{EntityType: p.resourceType, EntityID: resourceID(req)},
+	}
+	input.Context.ContextMap = requestContext(req)
+
+	var output isAuthorizedOutput
+	if err := p.call(ctx, "IsAuthorized", input, &output); err != nil {
+		return err
+	}
+
+	if output.Decision == "ALLOW" {
+		return nil
+	}
+	if len(output.Errors) > 0 {
+		return fmt.Errorf("%w: policy evaluation failed: %s", service.ErrIssuanceDenied, output.Errors[0].ErrorDescription)
+	}
+	if len(output.DeterminingPolicies) > 0 {
+		ids := make([]string, len(output.DeterminingPolicies))
+		for i, policy := range output.DeterminingPolicies {
+			ids[i] = policy.PolicyID
+		}
+		return fmt.Errorf("%w: forbidden by policies %s", service.ErrIssuanceDenied, strings.Join(ids, ", "))
+	}
+	return fmt.Errorf("%w: no policy permits the request", service.ErrIssuanceDenied)
+}
+
+// call sends a signed Verified Permissions API request
+func (p *Policy) call(ctx context.Context, operation string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", operation, err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", operation, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	httpReq.Header.Set("X-Amz-Target", "VerifiedPermissions."+operation)
+
+	credentials, err := p.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := p.signer.SignHTTP(ctx, credentials, httpReq, hex.EncodeToString(payloadHash[:]), signingName, p.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign %s request: %w", operation, err)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to call verified permissions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", operation, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("verified permissions %s failed with status %d: %s", operation, resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", operation, err)
+	}
+	return nil
+}
+
+// resourceID returns the request's authority, or else its first audience
+func resourceID(req *service.IssueRequest) string {
+	if req.RequestAttributes != nil && req.RequestAttributes.Authority != "" {
+		return req.RequestAttributes.Authority
+	}
+	if len(req.Audiences) > 0 {
+		return req.Audiences[0]
+	}
+	return ""
+}
+
+// requestContext returns the Cedar context of req: the subject's issuer and trust
+// domain, the actor, the HTTP request, and what is requested
+func requestContext(req *service.IssueRequest) map[string]attributeValue {
+	ctx := map[string]attributeValue{}
+	setString := func(name, value string) {
+		if value != "" {
+			ctx[name] = attributeValue{String: &value}
+		}
+	}
+	setStrings := func(name string, values []string) {
+		set := make([]attributeValue, len(values))
+		for i := range values {
+			set[i] = attributeValue{String: &values[i]}
+		}
+		ctx[name] = attributeValue{Set: set}
+	}
+
+	setString("issuer", req.Subject.Issuer)
+	setString("trust_domain", req.Subject.TrustDomain)
+	if req.Actor != nil {
+		setString("actor", req.Actor.Subject)
+		setString("actor_issuer", req.Actor.Issuer)
+	}
+	if attrs := req.RequestAttributes; attrs != nil {
+		setString("method", attrs.Method)
+		setString("path", attrs.Path)
+		setString("ip_address", attrs.IPAddress)
+	}
+	setString("scope", req.Scope)
+	setStrings("audiences", req.Audiences)
+	tokenTypes := make([]string, len(req.TokenTypes))
+	for i, tokenType := range req.TokenTypes {
+		tokenTypes[i] = string(tokenType)
+	}
+	setStrings("token_types", tokenTypes)
+	return ctx
+}
+
+func withDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,91 @@
+package avp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	var received isAuthorizedInput
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "VerifiedPermissions.IsAuthorized" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch received.Principal.EntityID {
+		case "alice":
+			json.NewEncoder(w).Encode(map[string]any{"decision": "ALLOW"})
+		case "mallory":
+			json.NewEncoder(w).Encode(map[string]any{
+				"decision":            "DENY",
+				"determiningPolicies": []map[string]any{{"policyId": "forbid-suspended"}},
+			})
+		default:
+			json.NewEncoder(w).Encode(map[string]any{"decision": "DENY"})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	policy, err := NewPolicy(ctx, Config{
+		PolicyStoreID: "ps-123",
+		Region:        "us-east-1",
+		Endpoint:      server.URL,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	issueRequest := func(subject string) *service.IssueRequest {
+		return &service.IssueRequest{
+			Subject:           &trust.Result{Subject: subject, Issuer: "https://idp.example.com"},
+			RequestAttributes: &request.RequestAttributes{Method: "GET", Path: "/orders", Authority: "api.example.com"},
+			TokenTypes:        []service.TokenType{service.TokenTypeTransactionToken},
+		}
+	}
+
+	t.Run("allows what the policy store allows", func(t *testing.T) {
+		if err := policy.Authorize(ctx, issueRequest("alice")); err != nil {
+			t.Fatalf("expected alice to be allowed, got %v", err)
+		}
+		if received.PolicyStoreID != "ps-123" ||
+			received.Principal != (entityIdentifier{EntityType: DefaultPrincipalType, EntityID: "alice"}) ||
+			received.Action != (actionIdentifier{ActionType: DefaultActionType, ActionID: DefaultActionID}) ||
+			received.Resource != (entityIdentifier{EntityType: DefaultResourceType, EntityID: "api.example.com"}) {
+			t.Errorf("unexpected request: %+v", received)
+		}
+		if path := received.Context.ContextMap["path"].String; path == nil || *path != "/orders" {
+			t.Errorf("expected the path in the context, got %+v", received.Context.ContextMap)
+		}
+	})
+
+	t.Run("denies what the policy store denies", func(t *testing.T) {
+		err := policy.Authorize(ctx, issueRequest("mallory"))
+		if !errors.Is(err, service.ErrIssuanceDenied) || !strings.Contains(err.Error(), "forbid-suspended") {
+			t.Errorf("expected denial naming the determining policy, got %v", err)
+		}
+		if err := policy.Authorize(ctx, issueRequest("bob")); !errors.Is(err, service.ErrIssuanceDenied) {
+			t.Errorf("expected denial, got %v", err)
+		}
+	})
+}
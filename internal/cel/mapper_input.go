@@ -53,6 +53,7 @@ func (lib *mapperInputLib) CompileOptions() []cel.EnvOption {
 		// Declare other variables as dynamic types
 		cel.Variable("subject", cel.DynType),
 		cel.Variable("actor", cel.DynType),
+		cel.Variable("workload", cel.DynType),
 		cel.Variable("request", cel.DynType),
 	}
 }
@@ -1,6 +1,10 @@
 package claims
 
-import "maps"
+import (
+	"fmt"
+	"maps"
+	"reflect"
+)
 
 // Claims represents a set of claims as key-value pairs
 // This is used for both transaction context (tctx) and request context (req_ctx)
@@ -46,3 +50,77 @@ func (c Claims) Has(key string) bool {
 	_, ok := c[key]
 	return ok
 }
+
+// MergeStrategy determines how claims are merged into claims that have some of the
+// same keys
+type MergeStrategy string
+
+const (
+	// MergeOverwrite replaces existing values (the default)
+	MergeOverwrite MergeStrategy = "overwrite"
+
+	// MergeDeep merges objects in both claims sets key by key, recursively, and
+	// replaces other existing values
+	MergeDeep MergeStrategy = "deep"
+
+	// MergeErrorOnConflict fails if a key in both claims sets has different values
+	MergeErrorOnConflict MergeStrategy = "error"
+)
+
+// ParseMergeStrategy parses a merge strategy, defaulting to MergeOverwrite
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	switch MergeStrategy(s) {
+	case "", MergeOverwrite:
+		return MergeOverwrite, nil
+	case MergeDeep, MergeErrorOnConflict:
+		return MergeStrategy(s), nil
+	default:
+		return "", fmt.Errorf("unknown merge strategy: %s (supported: overwrite, deep, error)", s)
+	}
+}
+
+// MergeWith merges the other claims into this claims set with strategy
+func (c Claims) MergeWith(other Claims, strategy MergeStrategy) error {
+	switch strategy {
+	case MergeDeep:
+		deepMerge(c, other)
+	case MergeErrorOnConflict:
+		for key, value := range other {
+			if existing, ok := c[key]; ok && !reflect.DeepEqual(existing, value) {
+				return fmt.Errorf("conflicting values for claim %s", key)
+			}
+		}
+		c.Merge(other)
+	default:
+		c.Merge(other)
+	}
+	return nil
+}
+
+// deepMerge merges src into dst, recursing into objects in both
+// Objects in dst are copied before they are changed, since they may be shared.
+func deepMerge(dst, src map[string]any) {
+	for key, value := range src {
+		srcMap, srcIsMap := asObject(value)
+		dstMap, dstIsMap := asObject(dst[key])
+		if !srcIsMap || !dstIsMap {
+			dst[key] = value
+			continue
+		}
+		merged := maps.Clone(dstMap)
+		deepMerge(merged, srcMap)
+		dst[key] = merged
+	}
+}
+
+// asObject returns value as a map if it is an object
+func asObject(value any) (map[string]any, bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, true
+	case Claims:
+		return v, true
+	default:
+		return nil, false
+	}
+}
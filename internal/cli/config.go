@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/alechenninger/parsec/internal/config"
+)
+
+// NewConfigCmd creates the config command
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Work with parsec configuration",
+	}
+	cmd.AddCommand(newConfigValidateCmd())
+	return cmd
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	var connect bool
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate configuration without starting servers",
+		Long: `Validate configuration by building every component parsec serve would build,
+without listening for requests.
+
+Configuration is loaded as by parsec serve, from the config file, environment
+variables, and flags. By default, components are built without contacting
+external systems: JWKS and bundles are not fetched, key providers and Kubernetes
+are not dialed, and SQL tables are not created, so configuration can be checked
+where those are unreachable. With --connect, components are built as on start,
+contacting external dependencies: validate with the same access as the server.
+Exits non-zero with the first error found.
+
+Examples:
+  # Validate a config file in CI
+  parsec config validate --config ./config.yaml
+
+  # Validate as on start, contacting key providers and JWKS endpoints
+  parsec config validate --config ./config.yaml --connect`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigValidate(cmd, connect)
+		},
+	}
+
+	cmd.Flags().BoolVar(&connect, "connect", false, "build components as parsec serve does, contacting external systems")
+	config.RegisterFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runConfigValidate(cmd *cobra.Command, connect bool) error {
+	cfg, err := loadConfigFile(cmd.Flags())
+	if err != nil {
+		return err
+	}
+
+	provider := config.NewOfflineProvider(cfg)
+	if connect {
+		provider = config.NewProvider(cfg)
+	}
+	defer provider.StopSigners()
+
+	if err := validateProvider(provider); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "configuration is valid")
+	return nil
+}
+
+// loadConfig loads configuration from the config file, environment variables, and flags,
+// and creates a provider to build components from it
+func loadConfig(flags *pflag.FlagSet) (*config.Loader, *config.Provider, error) {
+	loader, cfg, err := newLoader(flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loader, config.NewProvider(cfg), nil
+}
+
+// loadConfigFile loads configuration from the config file, environment variables, and flags
+func loadConfigFile(flags *pflag.FlagSet) (*config.Config, error) {
+	_, cfg, err := newLoader(flags)
+	return cfg, err
+}
+
+func newLoader(flags *pflag.FlagSet) (*config.Loader, *config.Config, error) {
+	// If neither --config nor PARSEC_CONFIG is set, use env vars/flags only
+	configPath := configFile
+	if configPath == "" {
+		configPath = os.Getenv("PARSEC_CONFIG")
+	}
+
+	loader, err := config.NewLoaderWithFlags(configPath, flags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if configPollInterval > 0 {
+		loader.SetPollInterval(configPollInterval)
+	}
+
+	cfg, err := loader.Get()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	return loader, cfg, nil
+}
+
+// validateProvider builds the components parsec serve builds, returning the first error
+func validateProvider(provider *config.Provider) error {
+	if _, err := provider.TrustStore(); err != nil {
+		return fmt.Errorf("failed to create trust store: %w", err)
+	}
+	if _, err := provider.TokenService(); err != nil {
+		return fmt.Errorf("failed to create token service: %w", err)
+	}
+	if _, err := provider.AuthzServerTokenTypes(); err != nil {
+		return fmt.Errorf("failed to get authz token types: %w", err)
+	}
+	if _, err := provider.AuthzServerDenial(); err != nil {
+		return fmt.Errorf("failed to get authz denial config: %w", err)
+	}
+	if _, err := provider.AuthzServerHeaderPolicy(); err != nil {
+		return fmt.Errorf("failed to get authz header policy: %w", err)
+	}
+	if _, err := provider.AuthzServerBodyCredentialRules(); err != nil {
+		return fmt.Errorf("failed to get authz body credential rules: %w", err)
+	}
+	if _, err := provider.AuthzServerValidationCache(); err != nil {
+		return fmt.Errorf("failed to get authz validation cache: %w", err)
+	}
+	if _, err := provider.AuthzServerAuthzRules(); err != nil {
+		return err
+	}
+	if _, err := provider.ExchangeServerAuthzRules(); err != nil {
+		return err
+	}
+	if _, err := provider.ExchangeServerClaimsFilterRegistry(); err != nil {
+		return fmt.Errorf("failed to get exchange server claims filter registry: %w", err)
+	}
+	clientAuthenticator, err := provider.ExchangeServerClientAuthenticator()
+	if err != nil {
+		return fmt.Errorf("failed to get exchange server client authenticator: %w", err)
+	}
+	if clientAuthenticator != nil {
+		clientAuthenticator.Close()
+	}
+	if _, err := provider.ExchangeServerReexchangeTokens(); err != nil {
+		return fmt.Errorf("failed to get exchange server re-exchange tokens: %w", err)
+	}
+	if _, _, err := provider.ExchangeServerScopePolicy(); err != nil {
+		return fmt.Errorf("failed to get exchange server scope policy: %w", err)
+	}
+	if _, _, err := provider.ExchangeServerRateLimits(); err != nil {
+		return fmt.Errorf("failed to get exchange server rate limits: %w", err)
+	}
+	if _, _, err := provider.ExchangeServerSizeLimits(); err != nil {
+		return fmt.Errorf("failed to get exchange server size limits: %w", err)
+	}
+	if _, err := provider.JWKSServerConfig(); err != nil {
+		return fmt.Errorf("failed to get JWKS server config: %w", err)
+	}
+	if _, err := provider.AdminServerConfig(); err != nil {
+		return fmt.Errorf("failed to get admin server config: %w", err)
+	}
+	if _, err := provider.DebugServerConfig(); err != nil {
+		return fmt.Errorf("failed to get debug server config: %w", err)
+	}
+	introspectionServerCfg, err := provider.IntrospectionServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get introspection server config: %w", err)
+	}
+	if introspectionServerCfg != nil {
+		introspectionServerCfg.ClientAuthenticator.Close()
+	}
+	revocationServerCfg, err := provider.RevocationServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get revocation server config: %w", err)
+	}
+	if revocationServerCfg != nil {
+		revocationServerCfg.ClientAuthenticator.Close()
+	}
+	claimsServerCfg, err := provider.ClaimsServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get claims server config: %w", err)
+	}
+	if claimsServerCfg != nil {
+		claimsServerCfg.ClientAuthenticator.Close()
+	}
+	if _, err := provider.VerifyServerConfig(); err != nil {
+		return fmt.Errorf("failed to get verify server config: %w", err)
+	}
+	if _, err := provider.ServerConfig(); err != nil {
+		return fmt.Errorf("failed to get server config: %w", err)
+	}
+
+	// Telemetry and audit sinks are built, then closed without having sent anything
+	if _, err := provider.Observer(); err != nil {
+		return fmt.Errorf("failed to get observer: %w", err)
+	}
+	if tracerProvider, _ := provider.TracerProvider(); tracerProvider != nil {
+		tracerProvider.Shutdown(context.Background())
+	}
+	if eventPublisher, _ := provider.EventPublisher(); eventPublisher != nil {
+		eventPublisher.Close(context.Background())
+	}
+	auditLogger, err := provider.AuditLogger()
+	if err != nil {
+		return fmt.Errorf("failed to get audit logger: %w", err)
+	}
+	if auditLogger != nil {
+		auditLogger.Close()
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/config"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/server"
+)
+
+// NewKeysCmd creates the keys command
+func NewKeysCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Work with signing keys",
+	}
+	cmd.AddCommand(newKeysGenerateCmd())
+	cmd.AddCommand(newKeysExportJWKSCmd())
+	return cmd
+}
+
+func newKeysGenerateCmd() *cobra.Command {
+	var keyType, output string
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a signing key",
+		Long: `Generate a private key as PKCS #8 PEM, the format external signers load.
+
+The key is written to stdout, or to --output with owner-only permissions. Its key
+ID, the RFC 7638 thumbprint parsec publishes it under, is written to stderr.
+
+Examples:
+  # Generate a P-256 key for an external signer's Kubernetes Secret
+  parsec keys generate --output key.pem
+  kubectl create secret generic parsec-signing-key --from-file=key.pem
+
+  # Generate an RSA key
+  parsec keys generate --key-type RSA-2048`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runKeysGenerate(cmd, keys.KeyType(keyType), output)
+		},
+	}
+
+	cmd.Flags().StringVar(&keyType, "key-type", string(keys.KeyTypeECP256), "key type: EC-P256, EC-P384, RSA-2048, RSA-4096")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write the key to (default: stdout)")
+
+	return cmd
+}
+
+func runKeysGenerate(cmd *cobra.Command, keyType keys.KeyType, output string) error {
+	signer, err := keys.GenerateKey(keyType)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal key: %w", err)
+	}
+	keyID, err := keys.ComputeThumbprint(signer.Public())
+	if err != nil {
+		return fmt.Errorf("failed to compute key ID: %w", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if output == "" {
+		cmd.OutOrStdout().Write(data)
+	} else if err := os.WriteFile(output, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key: %w", err)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "key ID: %s\n", keyID)
+	return nil
+}
+
+func newKeysExportJWKSCmd() *cobra.Command {
+	var tokenType string
+	cmd := &cobra.Command{
+		Use:   "export-jwks",
+		Short: "Export the JWKS of the configured issuers",
+		Long: `Export the JSON Web Key Set parsec would publish, without starting servers.
+
+Signers are built from configuration as on start, so the keys are those of the
+configured key providers and stores. Keys of the memory key provider exist only in
+the process that generated them, so are never those a running server publishes.
+
+Examples:
+  # Export the aggregated key set, as at /.well-known/jwks.json
+  parsec keys export-jwks --config ./config.yaml > jwks.json
+
+  # Export the keys of one issuer
+  parsec keys export-jwks --config ./config.yaml \
+    --token-type urn:ietf:params:oauth:token-type:txn_token`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runKeysExportJWKS(cmd, tokenType)
+		},
+	}
+
+	cmd.Flags().StringVar(&tokenType, "token-type", "", "export only the keys of the issuer of this token type")
+	config.RegisterFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runKeysExportJWKS(cmd *cobra.Command, tokenType string) error {
+	ctx := context.Background()
+
+	_, provider, err := loadConfig(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	defer provider.StopSigners()
+
+	jwksServerCfg, err := provider.JWKSServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get JWKS server config: %w", err)
+	}
+
+	// Key sets are built on request, as before the server's first refresh
+	var resp *parsecv1.GetJWKSResponse
+	if tokenType == "" {
+		jwksServer := server.NewJWKSServer(server.JWKSServerConfig{
+			IssuerRegistry: jwksServerCfg.IssuerRegistry,
+		})
+		resp, err = jwksServer.GetJWKS(ctx, &parsecv1.GetJWKSRequest{})
+	} else {
+		jwksServer := server.NewJWKSServer(server.JWKSServerConfig{
+			IssuerRegistry: jwksServerCfg.IssuerRegistry,
+			Publication:    server.JWKSPublicationPerIssuer,
+		})
+		resp, err = jwksServer.GetIssuerJWKS(ctx, &parsecv1.GetIssuerJWKSRequest{TokenType: tokenType})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get JWKS: %w", err)
+	}
+
+	document, err := server.MarshalJWKSDocument(resp)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, document, "", "  "); err != nil {
+		return fmt.Errorf("failed to format JWKS: %w", err)
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(cmd.OutOrStdout())
+	return err
+}
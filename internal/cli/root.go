@@ -3,13 +3,18 @@ package cli
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/alechenninger/parsec/internal/config"
+	"github.com/alechenninger/parsec/internal/instance"
 )
 
 var (
 	// Global flags
-	configFile string
+	configFile         string
+	configPollInterval time.Duration
 )
 
 // NewRootCmd creates the root command for parsec
@@ -22,15 +27,20 @@ func NewRootCmd() *cobra.Command {
   2. OAuth 2.0 Token Exchange (HTTP via gRPC transcoding) - RFC 8693 compliant
 
 Both services issue transaction tokens following the draft-ietf-oauth-transaction-tokens specification.`,
+		Version:       instance.BuildVersion(),
 		SilenceUsage:  true,
 		SilenceErrors: true,
 	}
 
 	// Global flags available to all commands
-	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file path (if not provided, uses PARSEC_CONFIG env var or env vars/flags only)")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "config file path or configmap://, s3://, or http(s):// URL (if not provided, uses PARSEC_CONFIG env var or env vars/flags only)")
+	rootCmd.PersistentFlags().DurationVar(&configPollInterval, "config-poll-interval", config.DefaultPollInterval, "how often S3 and HTTP config files are checked for changes")
 
 	// Add subcommands
 	rootCmd.AddCommand(NewServeCmd())
+	rootCmd.AddCommand(NewConfigCmd())
+	rootCmd.AddCommand(NewTokenCmd())
+	rootCmd.AddCommand(NewKeysCmd())
 
 	return rootCmd
 }
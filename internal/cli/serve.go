@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
@@ -59,28 +60,21 @@ func runServe(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 1. Determine config file path
-	configPath := configFile
-	if configPath == "" {
-		// Check environment variable
-		configPath = os.Getenv("PARSEC_CONFIG")
-	}
-	// If still empty, configPath remains empty and we'll use env vars/flags only
-
-	// 2. Load configuration (file + env vars + flags)
-	loader, err := config.NewLoaderWithFlags(configPath, cmd.Flags())
+	// 1-3. Load configuration (file + env vars + flags) and create a provider to
+	// build all components from it
+	loader, provider, err := loadConfig(cmd.Flags())
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return err
 	}
 
-	cfg, err := loader.Get()
+	// Components log their own messages, like failed refreshes, as configured
+	slog.SetDefault(provider.Logger())
+
+	identity, err := provider.Instance()
 	if err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+		return err
 	}
 
-	// 3. Create provider to build all components from config
-	provider := config.NewProvider(cfg)
-
 	// 4. Build components via provider
 	trustStore, err := provider.TrustStore()
 	if err != nil {
@@ -92,22 +86,133 @@ func runServe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create token service: %w", err)
 	}
 
+	// Signers rotate keys in the background until stopped, last, on shutdown
+	if _, err := provider.SignerRegistry(); err != nil {
+		return fmt.Errorf("failed to get signer registry: %w", err)
+	}
+
 	// 5. Get authz server token types from config
 	authzTokenTypes, err := provider.AuthzServerTokenTypes()
 	if err != nil {
 		return fmt.Errorf("failed to get authz token types: %w", err)
 	}
 
+	authzDenial, err := provider.AuthzServerDenial()
+	if err != nil {
+		return fmt.Errorf("failed to get authz denial config: %w", err)
+	}
+
+	authzHeaderPolicy, err := provider.AuthzServerHeaderPolicy()
+	if err != nil {
+		return fmt.Errorf("failed to get authz header policy: %w", err)
+	}
+
+	authzBodyCredentialRules, err := provider.AuthzServerBodyCredentialRules()
+	if err != nil {
+		return fmt.Errorf("failed to get authz body credential rules: %w", err)
+	}
+
+	authzValidationCache, err := provider.AuthzServerValidationCache()
+	if err != nil {
+		return fmt.Errorf("failed to get authz validation cache: %w", err)
+	}
+
 	// Get exchange server claims filter registry from config
+	authzRules, err := provider.AuthzServerAuthzRules()
+	if err != nil {
+		return err
+	}
+
+	exchangeAuthzRules, err := provider.ExchangeServerAuthzRules()
+	if err != nil {
+		return err
+	}
+
 	claimsFilterRegistry, err := provider.ExchangeServerClaimsFilterRegistry()
 	if err != nil {
 		return fmt.Errorf("failed to get exchange server claims filter registry: %w", err)
 	}
 
-	// Get issuer registry for JWKS endpoint
-	issuerRegistry, err := provider.IssuerRegistry()
+	// Get exchange server client authentication from config
+	clientAuthenticator, err := provider.ExchangeServerClientAuthenticator()
+	if err != nil {
+		return fmt.Errorf("failed to get exchange server client authenticator: %w", err)
+	}
+	if clientAuthenticator != nil {
+		defer clientAuthenticator.Close()
+	}
+
+	// Get exchange server re-exchange tokens from config (nil if disabled)
+	reexchangeTokens, err := provider.ExchangeServerReexchangeTokens()
+	if err != nil {
+		return fmt.Errorf("failed to get exchange server re-exchange tokens: %w", err)
+	}
+
+	// Get exchange server scope policy from config
+	scopePolicy, rejectDeniedScopes, err := provider.ExchangeServerScopePolicy()
+	if err != nil {
+		return fmt.Errorf("failed to get exchange server scope policy: %w", err)
+	}
+
+	// Get exchange server rate and size limits from config
+	rateLimit, clientRateLimit, err := provider.ExchangeServerRateLimits()
+	if err != nil {
+		return fmt.Errorf("failed to get exchange server rate limits: %w", err)
+	}
+	maxTokenBytes, maxRequestBytes, err := provider.ExchangeServerSizeLimits()
+	if err != nil {
+		return fmt.Errorf("failed to get exchange server size limits: %w", err)
+	}
+
+	// Get JWKS endpoint configuration (issuers and external publishers)
+	jwksServerCfg, err := provider.JWKSServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get JWKS server config: %w", err)
+	}
+
+	// Get admin API configuration (nil if disabled)
+	adminServerCfg, err := provider.AdminServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get admin server config: %w", err)
+	}
+
+	// Get debug listener configuration (nil if disabled)
+	debugServerCfg, err := provider.DebugServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get debug server config: %w", err)
+	}
+
+	// Get token introspection configuration (nil if disabled)
+	introspectionServerCfg, err := provider.IntrospectionServerConfig()
 	if err != nil {
-		return fmt.Errorf("failed to get issuer registry: %w", err)
+		return fmt.Errorf("failed to get introspection server config: %w", err)
+	}
+	if introspectionServerCfg != nil {
+		defer introspectionServerCfg.ClientAuthenticator.Close()
+	}
+
+	// Get token revocation configuration (nil if disabled)
+	revocationServerCfg, err := provider.RevocationServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get revocation server config: %w", err)
+	}
+	if revocationServerCfg != nil {
+		defer revocationServerCfg.ClientAuthenticator.Close()
+	}
+
+	// Get claims retrieval configuration (nil if disabled)
+	claimsServerCfg, err := provider.ClaimsServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get claims server config: %w", err)
+	}
+	if claimsServerCfg != nil {
+		defer claimsServerCfg.ClientAuthenticator.Close()
+	}
+
+	// Get token verification configuration
+	verifyServerCfg, err := provider.VerifyServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get verify server config: %w", err)
 	}
 
 	// Get observer for observability
@@ -115,13 +220,55 @@ func runServe(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get observer: %w", err)
 	}
+	tracerProvider, err := provider.TracerProvider()
+	if err != nil {
+		return fmt.Errorf("failed to get tracer provider: %w", err)
+	}
+	eventPublisher, err := provider.EventPublisher()
+	if err != nil {
+		return fmt.Errorf("failed to get event publisher: %w", err)
+	}
+
+	// Get audit logger, if auditing is enabled
+	auditLogger, err := provider.AuditLogger()
+	if err != nil {
+		return fmt.Errorf("failed to get audit logger: %w", err)
+	}
+	if auditLogger != nil {
+		defer auditLogger.Close()
+	}
 
 	// 6. Create service handlers with observability
 	authzServer := server.NewAuthzServer(trustStore, tokenService, authzTokenTypes, observer)
+	authzServer.TrustForwardedClientCert = provider.AuthzServerTrustsForwardedClientCert()
+	authzServer.APIKeyHeaders = provider.AuthzServerAPIKeyHeaders()
+	authzServer.BodyCredentialRules = authzBodyCredentialRules
+	authzServer.CertificateBoundTokens = provider.AuthzServerCertificateBoundTokens()
+	authzServer.TransactionIDHeader = provider.AuthzServerTransactionIDHeader()
+	authzServer.Denial = authzDenial
+	authzServer.Headers = authzHeaderPolicy
+	authzServer.ValidationCache = authzValidationCache
+	authzServer.Audit = auditLogger
+	authzServer.AuthzRules = authzRules
 	exchangeServer := server.NewExchangeServer(trustStore, tokenService, claimsFilterRegistry, observer)
-	jwksServer := server.NewJWKSServer(server.JWKSServerConfig{
-		IssuerRegistry: issuerRegistry,
-		// Use default refresh interval (1 minute)
+	exchangeServer.AllowedAudiences = provider.ExchangeServerAllowedAudiences()
+	exchangeServer.ScopePolicy = scopePolicy
+	exchangeServer.RejectDeniedScopes = rejectDeniedScopes
+	exchangeServer.ClientAuthenticator = clientAuthenticator
+	exchangeServer.CertificateBoundTokens = provider.ExchangeServerCertificateBoundTokens()
+	exchangeServer.ReexchangeTokens = reexchangeTokens
+	exchangeServer.Audit = auditLogger
+	exchangeServer.RateLimit = rateLimit
+	exchangeServer.ClientRateLimit = clientRateLimit
+	exchangeServer.MaxTokenBytes = maxTokenBytes
+	exchangeServer.MaxRequestBytes = maxRequestBytes
+	exchangeServer.AuthzRules = exchangeAuthzRules
+	jwksServer := server.NewJWKSServer(jwksServerCfg)
+	discoveryServer := server.NewDiscoveryServer(server.DiscoveryServerConfig{
+		TrustDomain:     provider.TrustDomain(),
+		IssuerRegistry:  jwksServerCfg.IssuerRegistry,
+		AuthzTokenTypes: authzServer.TokenTypesToIssue,
+		JWKSPublication: jwksServerCfg.Publication,
 	})
 
 	// Start JWKS background refresh
@@ -131,10 +278,51 @@ func runServe(cmd *cobra.Command, args []string) error {
 	defer jwksServer.Stop()
 
 	// 7. Create server configuration
-	serverCfg := provider.ServerConfig()
+	serverCfg, err := provider.ServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get server config: %w", err)
+	}
 	serverCfg.AuthzServer = authzServer
 	serverCfg.ExchangeServer = exchangeServer
 	serverCfg.JWKSServer = jwksServer
+	serverCfg.DiscoveryServer = discoveryServer
+	serverCfg.VerifyServer = server.NewVerifyServer(verifyServerCfg)
+	if provider.ExchangeServerPreview() {
+		serverCfg.PreviewServer = server.NewPreviewServer(exchangeServer)
+	}
+	if adminServerCfg != nil {
+		serverCfg.AdminServer = server.NewAdminServer(*adminServerCfg)
+	}
+	if introspectionServerCfg != nil {
+		serverCfg.IntrospectionServer = server.NewIntrospectionServer(*introspectionServerCfg)
+	}
+	if revocationServerCfg != nil {
+		serverCfg.RevocationServer = server.NewRevocationServer(*revocationServerCfg)
+	}
+	if claimsServerCfg != nil {
+		serverCfg.ClaimsServer = server.NewClaimsServer(*claimsServerCfg)
+	}
+
+	// Distributed caches find their peers on first use, so peers are set up before serving
+	cachePeerPool, err := provider.CachePeerPool()
+	if err != nil {
+		return err
+	}
+	if cachePeerPool != nil {
+		if err := cachePeerPool.Start(ctx); err != nil {
+			return err
+		}
+	}
+	cachePeerDiscovery, err := provider.CachePeerDiscovery()
+	if err != nil {
+		return err
+	}
+	if cachePeerDiscovery != nil {
+		if err := cachePeerDiscovery.Start(ctx); err != nil {
+			return err
+		}
+		defer cachePeerDiscovery.Stop()
+	}
 
 	// 8. Create and start server
 	srv := server.New(serverCfg)
@@ -142,13 +330,53 @@ func runServe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
+	var debugServer *server.DebugServer
+	if debugServerCfg != nil {
+		debugServerCfg.ValidationCache = authzValidationCache
+		debugServer = server.NewDebugServer(*debugServerCfg)
+		if err := debugServer.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start debug server: %w", err)
+		}
+	}
+
+	httpScheme := "http"
+	if serverCfg.TLS != nil {
+		httpScheme = "https"
+	}
+
 	fmt.Println("parsec is running")
+	fmt.Printf("  Instance:              %s (%s)\n", identity.ID, identity.Version)
 	fmt.Printf("  gRPC (ext_authz):      localhost:%d\n", serverCfg.GRPCPort)
-	fmt.Printf("  HTTP (token exchange): http://localhost:%d/v1/token\n", serverCfg.HTTPPort)
-	fmt.Printf("  HTTP (JWKS):           http://localhost:%d/v1/jwks.json\n", serverCfg.HTTPPort)
-	fmt.Printf("                         http://localhost:%d/.well-known/jwks.json\n", serverCfg.HTTPPort)
+	fmt.Printf("  HTTP (token exchange): %s://localhost:%d/v1/token\n", httpScheme, serverCfg.HTTPPort)
+	fmt.Printf("  HTTP (JWKS):           %s://localhost:%d/v1/jwks.json\n", httpScheme, serverCfg.HTTPPort)
+	fmt.Printf("                         %s://localhost:%d/.well-known/jwks.json\n", httpScheme, serverCfg.HTTPPort)
+	fmt.Printf("  HTTP (verify):         %s://localhost:%d/v1/verify\n", httpScheme, serverCfg.HTTPPort)
+	if adminServerCfg != nil {
+		fmt.Printf("  HTTP (admin):          %s://localhost:%d/admin/v1/\n", httpScheme, serverCfg.HTTPPort)
+	}
+	if introspectionServerCfg != nil {
+		fmt.Printf("  HTTP (introspection):  %s://localhost:%d/v1/introspect\n", httpScheme, serverCfg.HTTPPort)
+	}
+	if revocationServerCfg != nil {
+		fmt.Printf("  HTTP (revocation):     %s://localhost:%d/v1/revoke\n", httpScheme, serverCfg.HTTPPort)
+	}
+	if debugServerCfg != nil {
+		fmt.Printf("  HTTP (debug):          http://%s/debug/\n", debugServerCfg.Address)
+	}
+	if cachePeerPool != nil {
+		fmt.Printf("  HTTP (cache peers):    %s\n", cachePeerPool.Self())
+	}
 	fmt.Printf("  Trust Domain:          %s\n", provider.TrustDomain())
-	fmt.Printf("  Config:                %s\n", configPath)
+	fmt.Printf("  Config:                %s\n", loader.Path())
+
+	// Apply changes to validators and issuers in the config file without a restart
+	go func() {
+		if err := loader.Watch(ctx, func(cfg *config.Config) error {
+			return provider.Reload(ctx, cfg)
+		}); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to watch config file", "error", err)
+		}
+	}()
 
 	// 9. Wait for interrupt signal
 	sigCh := make(chan os.Signal, 1)
@@ -157,10 +385,34 @@ func runServe(cmd *cobra.Command, args []string) error {
 
 	fmt.Println("\nShutting down...")
 
-	// 10. Graceful shutdown
+	// 10. Graceful shutdown: drain in-flight requests, then flush what they produced
 	if err := srv.Stop(ctx); err != nil {
-		return fmt.Errorf("error during shutdown: %w", err)
+		slog.Error("Failed to drain requests", "error", err)
+	}
+	if debugServer != nil {
+		if err := debugServer.Stop(ctx); err != nil {
+			slog.Error("Failed to stop debug server", "error", err)
+		}
+	}
+	if cachePeerPool != nil {
+		if err := cachePeerPool.Stop(ctx); err != nil {
+			slog.Error("Failed to stop cache peer server", "error", err)
+		}
+	}
+	if tracerProvider != nil {
+		// Flush spans still buffered for export
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}
+	if eventPublisher != nil {
+		// Send events still queued
+		if err := eventPublisher.Close(ctx); err != nil {
+			slog.Error("Failed to send queued events", "error", err)
+		}
 	}
+	// Key rotation stops only once nothing is signing
+	provider.StopSigners()
 
 	fmt.Println("Shutdown complete")
 	return nil
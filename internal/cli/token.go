@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/config"
+	"github.com/alechenninger/parsec/internal/server"
+)
+
+// NewTokenCmd creates the token command
+func NewTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Work with tokens parsec issues",
+	}
+	cmd.AddCommand(newTokenPreviewCmd())
+	return cmd
+}
+
+// tokenPreviewOptions are the token exchange request of a preview
+type tokenPreviewOptions struct {
+	subjectToken       string
+	subjectTokenType   string
+	actorToken         string
+	actorTokenType     string
+	requestedTokenType string
+	audiences          []string
+	resources          []string
+	scope              string
+}
+
+func newTokenPreviewCmd() *cobra.Command {
+	opts := &tokenPreviewOptions{}
+	cmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Preview the token an exchange would issue",
+		Long: `Preview the claims of the token an exchange would issue, without starting servers.
+
+The subject token is validated and the exchange is authorized as by the token
+endpoint, and every data source and claim mapper runs, but nothing is signed or
+stored: the claims of the token are printed as JSON, as from the token preview
+endpoint. No client authenticates, so authorization rules on clients do not match.
+
+Examples:
+  # Preview the transaction token for a JWT
+  parsec token preview --config ./config.yaml --subject-token "$TOKEN"
+
+  # Preview a token for an audience and scope
+  parsec token preview --config ./config.yaml --subject-token "$TOKEN" \
+    --audience orders.example.com --scope "orders:read"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTokenPreview(cmd, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.subjectToken, "subject-token", "", "token of the subject of the exchange (required)")
+	cmd.Flags().StringVar(&opts.subjectTokenType, "subject-token-type", "urn:ietf:params:oauth:token-type:jwt", "type of the subject token")
+	cmd.Flags().StringVar(&opts.actorToken, "actor-token", "", "token of the actor of the exchange")
+	cmd.Flags().StringVar(&opts.actorTokenType, "actor-token-type", "urn:ietf:params:oauth:token-type:jwt", "type of the actor token")
+	cmd.Flags().StringVar(&opts.requestedTokenType, "requested-token-type", "", "type of the token to preview (default: the exchange's default)")
+	cmd.Flags().StringSliceVar(&opts.audiences, "audience", nil, "audience of the token (repeatable)")
+	cmd.Flags().StringSliceVar(&opts.resources, "resource", nil, "resource the token is for (repeatable)")
+	cmd.Flags().StringVar(&opts.scope, "scope", "", "space-delimited scope of the token")
+	cmd.MarkFlagRequired("subject-token")
+
+	config.RegisterFlags(cmd.Flags())
+
+	return cmd
+}
+
+func runTokenPreview(cmd *cobra.Command, opts *tokenPreviewOptions) error {
+	ctx := context.Background()
+
+	_, provider, err := loadConfig(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	defer provider.StopSigners()
+
+	exchangeServer, err := newPreviewExchangeServer(provider)
+	if err != nil {
+		return err
+	}
+
+	req := &parsecv1.TokenExchangeRequest{
+		GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
+		SubjectToken:       opts.subjectToken,
+		SubjectTokenType:   opts.subjectTokenType,
+		RequestedTokenType: opts.requestedTokenType,
+		Audience:           opts.audiences,
+		Resource:           opts.resources,
+		Scope:              opts.scope,
+	}
+	if opts.actorToken != "" {
+		req.ActorToken = opts.actorToken
+		req.ActorTokenType = opts.actorTokenType
+	}
+
+	preview, err := exchangeServer.Preview(ctx, req)
+	if err != nil {
+		// Exchange errors are gRPC statuses; the message is the OAuth error
+		if st, ok := status.FromError(err); ok {
+			return errors.New(st.Message())
+		}
+		return err
+	}
+
+	out, err := json.MarshalIndent(preview, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal preview: %w", err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), string(out))
+	return nil
+}
+
+// newPreviewExchangeServer builds an exchange server as parsec serve does, less the
+// parts that only apply to requests from clients: client authentication, rate and
+// size limits, and auditing
+func newPreviewExchangeServer(provider *config.Provider) (*server.ExchangeServer, error) {
+	trustStore, err := provider.TrustStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trust store: %w", err)
+	}
+	tokenService, err := provider.TokenService()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token service: %w", err)
+	}
+	claimsFilterRegistry, err := provider.ExchangeServerClaimsFilterRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange server claims filter registry: %w", err)
+	}
+	scopePolicy, rejectDeniedScopes, err := provider.ExchangeServerScopePolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange server scope policy: %w", err)
+	}
+	authzRules, err := provider.ExchangeServerAuthzRules()
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeServer := server.NewExchangeServer(trustStore, tokenService, claimsFilterRegistry, nil)
+	exchangeServer.AllowedAudiences = provider.ExchangeServerAllowedAudiences()
+	exchangeServer.ScopePolicy = scopePolicy
+	exchangeServer.RejectDeniedScopes = rejectDeniedScopes
+	exchangeServer.AuthzRules = authzRules
+	return exchangeServer, nil
+}
@@ -0,0 +1,330 @@
+package clientauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// ErrInvalidClient is returned when a client fails to authenticate
+var ErrInvalidClient = errors.New("invalid client")
+
+// Method is a token endpoint client authentication method
+// (RFC 7591 token_endpoint_auth_method)
+type Method string
+
+const (
+	// MethodClientSecretBasic authenticates with the client secret in an HTTP Basic
+	// Authorization header (RFC 6749 section 2.3.1)
+	MethodClientSecretBasic Method = "client_secret_basic"
+
+	// MethodClientSecretPost authenticates with client_id and client_secret request
+	// parameters (RFC 6749 section 2.3.1)
+	MethodClientSecretPost Method = "client_secret_post"
+
+	// MethodPrivateKeyJWT authenticates with a JWT client_assertion signed by the
+	// client (RFC 7523 section 2.2)
+	MethodPrivateKeyJWT Method = "private_key_jwt"
+
+	// MethodTLSClientAuth authenticates with the client's TLS certificate (RFC 8705 section 2.1)
+	MethodTLSClientAuth Method = "tls_client_auth"
+)
+
+// ClientAssertionTypeJWTBearer is the client_assertion_type of private_key_jwt assertions
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// Client is a client registered to call the token endpoint
+type Client struct {
+	// ID is the client_id
+	ID string
+
+	// Method is how the client authenticates
+	Method Method
+
+	// Secret is the client secret, for client_secret_basic and client_secret_post
+	Secret string
+
+	// Keys verify the client's assertions, for private_key_jwt
+	Keys trust.JWKSSource
+
+	// SubjectDN is the subject distinguished name the client's certificate must have,
+	// for tls_client_auth, if set
+	SubjectDN string
+
+	// SANs are subject alternative names (DNS names, URIs, email addresses, or IP
+	// addresses), one of which the client's certificate must have, for tls_client_auth, if set
+	SANs []string
+}
+
+// Credentials are the client credentials presented with a token request
+type Credentials struct {
+	// Authorization is the HTTP Authorization header, if any
+	Authorization string
+
+	// ClientID and ClientSecret are the client_id and client_secret request parameters
+	ClientID     string
+	ClientSecret string
+
+	// ClientAssertionType and ClientAssertion are the client_assertion_type and
+	// client_assertion request parameters
+	ClientAssertionType string
+	ClientAssertion     string
+
+	// Certificate is the client's TLS certificate, if it presented one
+	// It must already be verified by the TLS handshake
+	Certificate *x509.Certificate
+}
+
+// AuthenticatorConfig is the configuration for creating an Authenticator
+type AuthenticatorConfig struct {
+	// Clients are the registered clients
+	Clients []*Client
+
+	// Audiences are the accepted aud claims of client assertions, such as the token
+	// endpoint URL; required if any client uses private_key_jwt
+	Audiences []string
+
+	// ClockSkew is the clock skew tolerated when validating assertions (default: 30 seconds)
+	ClockSkew time.Duration
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// Authenticator authenticates clients of the token endpoint against a client registry
+type Authenticator struct {
+	clients   map[string]*Client
+	audiences []string
+	clockSkew time.Duration
+	clock     clock.Clock
+
+	// usedAssertions are the IDs of accepted assertions, until they expire,
+	// so assertions cannot be replayed
+	mu             sync.Mutex
+	usedAssertions map[string]time.Time
+}
+
+// NewAuthenticator creates a new client authenticator
+func NewAuthenticator(cfg AuthenticatorConfig) (*Authenticator, error) {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	clockSkew := cfg.ClockSkew
+	if clockSkew == 0 {
+		clockSkew = 30 * time.Second
+	}
+
+	clients := make(map[string]*Client, len(cfg.Clients))
+	for _, client := range cfg.Clients {
+		if client.ID == "" {
+			return nil, fmt.Errorf("client_id is required")
+		}
+		if _, ok := clients[client.ID]; ok {
+			return nil, fmt.Errorf("duplicate client %s", client.ID)
+		}
+		if err := validateClient(client); err != nil {
+			return nil, fmt.Errorf("client %s: %w", client.ID, err)
+		}
+		if client.Method == MethodPrivateKeyJWT && len(cfg.Audiences) == 0 {
+			return nil, fmt.Errorf("client %s: private_key_jwt requires assertion audiences", client.ID)
+		}
+		clients[client.ID] = client
+	}
+
+	return &Authenticator{
+		clients:        clients,
+		audiences:      cfg.Audiences,
+		clockSkew:      clockSkew,
+		clock:          clk,
+		usedAssertions: make(map[string]time.Time),
+	}, nil
+}
+
+// validateClient checks a client has what its authentication method requires
+func validateClient(client *Client) error {
+	switch client.Method {
+	case MethodClientSecretBasic, MethodClientSecretPost:
+		if client.Secret == "" {
+			return fmt.Errorf("%s requires a secret", client.Method)
+		}
+	case MethodPrivateKeyJWT:
+		if client.Keys == nil {
+			return fmt.Errorf("%s requires keys", client.Method)
+		}
+	case MethodTLSClientAuth:
+		if client.SubjectDN == "" && len(client.SANs) == 0 {
+			return fmt.Errorf("%s requires a subject DN or SANs", client.Method)
+		}
+	default:
+		return fmt.Errorf("unknown authentication method %q", client.Method)
+	}
+	return nil
+}
+
+// Authenticate authenticates the client that presented creds
+// The client must use exactly one authentication method, the one it is registered with.
+// Errors wrap ErrInvalidClient.
+func (a *Authenticator) Authenticate(ctx context.Context, creds *Credentials) (*Client, error) {
+	method, clientID, err := presentedMethod(creds)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClient, err)
+	}
+
+	if method == MethodPrivateKeyJWT && clientID == "" {
+		// client_id is optional with an assertion, which identifies the client itself
+		clientID, err = assertionClientID(creds.ClientAssertion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidClient, err)
+		}
+	}
+
+	client, ok := a.clients[clientID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown client %q", ErrInvalidClient, clientID)
+	}
+	if client.Method != method {
+		return nil, fmt.Errorf("%w: client %s must authenticate with %s, not %s", ErrInvalidClient, client.ID, client.Method, method)
+	}
+
+	switch method {
+	case MethodClientSecretBasic:
+		_, secret, _ := basicCredentials(creds.Authorization)
+		err = checkSecret(client, secret)
+	case MethodClientSecretPost:
+		err = checkSecret(client, creds.ClientSecret)
+	case MethodPrivateKeyJWT:
+		err = a.verifyAssertion(ctx, client, creds.ClientAssertion)
+	case MethodTLSClientAuth:
+		err = checkCertificate(client, creds.Certificate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: client %s: %v", ErrInvalidClient, client.ID, err)
+	}
+	return client, nil
+}
+
+// Close releases the clients' key sources
+func (a *Authenticator) Close() {
+	for _, client := range a.clients {
+		if client.Keys != nil {
+			client.Keys.Close()
+		}
+	}
+}
+
+// presentedMethod determines the authentication method creds use and the client ID they
+// claim, which is empty for an assertion without client_id
+// Using more than one method is an error (RFC 6749 section 2.3).
+func presentedMethod(creds *Credentials) (Method, string, error) {
+	var methods []Method
+	basicID, _, hasBasic := basicCredentials(creds.Authorization)
+	if hasBasic {
+		methods = append(methods, MethodClientSecretBasic)
+	}
+	if creds.ClientSecret != "" {
+		methods = append(methods, MethodClientSecretPost)
+	}
+	if creds.ClientAssertion != "" || creds.ClientAssertionType != "" {
+		methods = append(methods, MethodPrivateKeyJWT)
+	}
+	if len(methods) > 1 {
+		return "", "", fmt.Errorf("multiple client authentication methods used: %v", methods)
+	}
+
+	if len(methods) == 0 {
+		if creds.ClientID != "" && creds.Certificate != nil {
+			return MethodTLSClientAuth, creds.ClientID, nil
+		}
+		return "", "", fmt.Errorf("client authentication required")
+	}
+
+	switch method := methods[0]; method {
+	case MethodClientSecretBasic:
+		if creds.ClientID != "" && creds.ClientID != basicID {
+			return "", "", fmt.Errorf("client_id does not match the authorization header")
+		}
+		return method, basicID, nil
+	case MethodClientSecretPost:
+		if creds.ClientID == "" {
+			return "", "", fmt.Errorf("client_secret requires client_id")
+		}
+		return method, creds.ClientID, nil
+	default:
+		if creds.ClientAssertionType != ClientAssertionTypeJWTBearer {
+			return "", "", fmt.Errorf("unsupported client_assertion_type %q", creds.ClientAssertionType)
+		}
+		if creds.ClientAssertion == "" {
+			return "", "", fmt.Errorf("client_assertion_type requires client_assertion")
+		}
+		return method, creds.ClientID, nil
+	}
+}
+
+// basicCredentials parses the client ID and secret of an HTTP Basic authorization header
+// Both are form-urlencoded before being joined (RFC 6749 section 2.3.1).
+func basicCredentials(authorization string) (string, string, bool) {
+	if authorization == "" {
+		return "", "", false
+	}
+	req := http.Request{Header: http.Header{"Authorization": {authorization}}}
+	rawID, rawSecret, ok := req.BasicAuth()
+	if !ok {
+		return "", "", false
+	}
+	id, err := url.QueryUnescape(rawID)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err := url.QueryUnescape(rawSecret)
+	if err != nil {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+// checkSecret checks secret is the client's secret, in constant time
+func checkSecret(client *Client, secret string) error {
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(client.Secret)) != 1 {
+		return fmt.Errorf("incorrect client secret")
+	}
+	return nil
+}
+
+// checkCertificate checks cert is the client's certificate by its subject DN and SANs
+func checkCertificate(client *Client, cert *x509.Certificate) error {
+	if cert == nil {
+		return fmt.Errorf("no client certificate")
+	}
+	if client.SubjectDN != "" && cert.Subject.String() != client.SubjectDN {
+		return fmt.Errorf("certificate subject %q does not match", cert.Subject.String())
+	}
+	if len(client.SANs) > 0 && !slices.ContainsFunc(certificateSANs(cert), func(san string) bool {
+		return slices.Contains(client.SANs, san)
+	}) {
+		return fmt.Errorf("certificate subject alternative names do not match")
+	}
+	return nil
+}
+
+// certificateSANs returns all subject alternative names of cert
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := slices.Concat(cert.DNSNames, cert.EmailAddresses)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
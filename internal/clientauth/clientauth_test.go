@@ -0,0 +1,250 @@
+package clientauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+const tokenEndpoint = "https://parsec.example.com/v1/token"
+
+func basicAuthorization(id, secret string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(url.QueryEscape(id)+":"+url.QueryEscape(secret)))
+}
+
+// newClientKey creates a signing key and a key source with its public key
+func newClientKey(t *testing.T) (jwk.Key, trust.JWKSSource) {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := jwk.FromRaw(privateKey)
+	if err != nil {
+		t.Fatalf("failed to create JWK: %v", err)
+	}
+	_ = key.Set(jwk.KeyIDKey, "client-key")
+	_ = key.Set(jwk.AlgorithmKey, jwa.ES256)
+
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	set := jwk.NewSet()
+	_ = set.AddKey(publicKey)
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to marshal JWKS: %v", err)
+	}
+	source, err := trust.NewStaticJWKSSource(data)
+	if err != nil {
+		t.Fatalf("failed to create key source: %v", err)
+	}
+	return key, source
+}
+
+// signAssertion signs a client assertion with claims overridden by set
+func signAssertion(t *testing.T, key jwk.Key, now time.Time, set map[string]any) string {
+	t.Helper()
+	token := jwt.New()
+	_ = token.Set(jwt.IssuerKey, "batch")
+	_ = token.Set(jwt.SubjectKey, "batch")
+	_ = token.Set(jwt.AudienceKey, tokenEndpoint)
+	_ = token.Set(jwt.ExpirationKey, now.Add(time.Minute))
+	_ = token.Set(jwt.JwtIDKey, "assertion-1")
+	for k, v := range set {
+		_ = token.Set(k, v)
+	}
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, key))
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+	return string(signed)
+}
+
+func TestAuthenticator(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	key, keys := newClientKey(t)
+
+	clientCert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "mesh", Organization: []string{"Example"}},
+		DNSNames: []string{"mesh.example.com"},
+	}
+
+	newAuthenticator := func(t *testing.T) *Authenticator {
+		t.Helper()
+		authenticator, err := NewAuthenticator(AuthenticatorConfig{
+			Clients: []*Client{
+				{ID: "gateway", Method: MethodClientSecretBasic, Secret: "gateway-secret"},
+				{ID: "portal", Method: MethodClientSecretPost, Secret: "portal-secret"},
+				{ID: "batch", Method: MethodPrivateKeyJWT, Keys: keys},
+				{ID: "mesh", Method: MethodTLSClientAuth, SubjectDN: "CN=mesh,O=Example", SANs: []string{"mesh.example.com"}},
+			},
+			Audiences: []string{tokenEndpoint},
+			Clock:     clock.NewFixtureClock(now),
+		})
+		if err != nil {
+			t.Fatalf("failed to create authenticator: %v", err)
+		}
+		return authenticator
+	}
+
+	tests := []struct {
+		name       string
+		creds      *Credentials
+		wantClient string
+	}{
+		{
+			name:       "client_secret_basic",
+			creds:      &Credentials{Authorization: basicAuthorization("gateway", "gateway-secret")},
+			wantClient: "gateway",
+		},
+		{
+			name:       "client_secret_post",
+			creds:      &Credentials{ClientID: "portal", ClientSecret: "portal-secret"},
+			wantClient: "portal",
+		},
+		{
+			name: "private_key_jwt",
+			creds: &Credentials{
+				ClientAssertionType: ClientAssertionTypeJWTBearer,
+				ClientAssertion:     signAssertion(t, key, now, nil),
+			},
+			wantClient: "batch",
+		},
+		{
+			name:       "tls_client_auth",
+			creds:      &Credentials{ClientID: "mesh", Certificate: clientCert},
+			wantClient: "mesh",
+		},
+		{
+			name:  "no credentials",
+			creds: &Credentials{},
+		},
+		{
+			name:  "wrong secret",
+			creds: &Credentials{Authorization: basicAuthorization("gateway", "portal-secret")},
+		},
+		{
+			name:  "unknown client",
+			creds: &Credentials{ClientID: "unknown", ClientSecret: "portal-secret"},
+		},
+		{
+			name:  "method the client is not registered with",
+			creds: &Credentials{ClientID: "gateway", ClientSecret: "gateway-secret"},
+		},
+		{
+			name: "multiple methods",
+			creds: &Credentials{
+				Authorization: basicAuthorization("gateway", "gateway-secret"),
+				ClientID:      "gateway",
+				ClientSecret:  "gateway-secret",
+			},
+		},
+		{
+			name: "expired assertion",
+			creds: &Credentials{
+				ClientAssertionType: ClientAssertionTypeJWTBearer,
+				ClientAssertion:     signAssertion(t, key, now, map[string]any{jwt.ExpirationKey: now.Add(-time.Hour)}),
+			},
+		},
+		{
+			name: "assertion for another audience",
+			creds: &Credentials{
+				ClientAssertionType: ClientAssertionTypeJWTBearer,
+				ClientAssertion:     signAssertion(t, key, now, map[string]any{jwt.AudienceKey: "https://other.example.com"}),
+			},
+		},
+		{
+			name: "assertion about another subject",
+			creds: &Credentials{
+				ClientAssertionType: ClientAssertionTypeJWTBearer,
+				ClientAssertion:     signAssertion(t, key, now, map[string]any{jwt.SubjectKey: "someone-else"}),
+			},
+		},
+		{
+			name: "unsupported assertion type",
+			creds: &Credentials{
+				ClientAssertionType: "urn:ietf:params:oauth:client-assertion-type:saml2-bearer",
+				ClientAssertion:     signAssertion(t, key, now, nil),
+			},
+		},
+		{
+			name: "certificate of another client",
+			creds: &Credentials{ClientID: "mesh", Certificate: &x509.Certificate{
+				Subject:  pkix.Name{CommonName: "other"},
+				DNSNames: []string{"mesh.example.com"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newAuthenticator(t).Authenticate(ctx, tt.creds)
+			if tt.wantClient == "" {
+				if !errors.Is(err, ErrInvalidClient) {
+					t.Errorf("expected ErrInvalidClient, got client %v, error %v", client, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if client.ID != tt.wantClient {
+				t.Errorf("expected client %s, got %s", tt.wantClient, client.ID)
+			}
+		})
+	}
+
+	t.Run("assertions cannot be replayed", func(t *testing.T) {
+		authenticator := newAuthenticator(t)
+		creds := &Credentials{
+			ClientID:            "batch",
+			ClientAssertionType: ClientAssertionTypeJWTBearer,
+			ClientAssertion:     signAssertion(t, key, now, nil),
+		}
+		if _, err := authenticator.Authenticate(ctx, creds); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := authenticator.Authenticate(ctx, creds); !errors.Is(err, ErrInvalidClient) {
+			t.Errorf("expected replayed assertion to be rejected, got %v", err)
+		}
+	})
+}
+
+func TestNewAuthenticator_InvalidClients(t *testing.T) {
+	_, keys := newClientKey(t)
+
+	for name, cfg := range map[string]AuthenticatorConfig{
+		"missing client_id":   {Clients: []*Client{{Method: MethodClientSecretPost, Secret: "s"}}},
+		"duplicate client":    {Clients: []*Client{{ID: "a", Method: MethodClientSecretPost, Secret: "s"}, {ID: "a", Method: MethodClientSecretBasic, Secret: "s"}}},
+		"unknown method":      {Clients: []*Client{{ID: "a", Method: "none"}}},
+		"secret missing":      {Clients: []*Client{{ID: "a", Method: MethodClientSecretBasic}}},
+		"keys missing":        {Clients: []*Client{{ID: "a", Method: MethodPrivateKeyJWT}}, Audiences: []string{tokenEndpoint}},
+		"audiences missing":   {Clients: []*Client{{ID: "a", Method: MethodPrivateKeyJWT, Keys: keys}}},
+		"certificate missing": {Clients: []*Client{{ID: "a", Method: MethodTLSClientAuth}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewAuthenticator(cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
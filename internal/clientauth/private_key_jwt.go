@@ -0,0 +1,73 @@
+package clientauth
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// assertionClientID returns the client an assertion is issued by, without verifying it
+func assertionClientID(assertion string) (string, error) {
+	token, err := jwt.ParseInsecure([]byte(assertion))
+	if err != nil {
+		return "", fmt.Errorf("malformed client_assertion: %v", err)
+	}
+	if token.Issuer() == "" {
+		return "", fmt.Errorf("client_assertion is missing iss")
+	}
+	return token.Issuer(), nil
+}
+
+// verifyAssertion verifies a client assertion (RFC 7523 section 3)
+// It must be signed by one of the client's keys, issued by and about the client,
+// for one of the accepted audiences, unexpired, and not used before.
+func (a *Authenticator) verifyAssertion(ctx context.Context, client *Client, assertion string) error {
+	msg, err := jws.Parse([]byte(assertion))
+	if err != nil || len(msg.Signatures()) == 0 {
+		return fmt.Errorf("malformed client_assertion: %v", err)
+	}
+	keys := client.Keys.Keys(ctx, msg.Signatures()[0].ProtectedHeaders().KeyID())
+
+	now := a.clock.Now()
+	token, err := jwt.Parse(
+		[]byte(assertion),
+		jwt.WithKeySet(keys),
+		jwt.WithValidate(true),
+		jwt.WithIssuer(client.ID),
+		jwt.WithSubject(client.ID),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithRequiredClaim(jwt.JwtIDKey),
+		jwt.WithClock(jwt.ClockFunc(func() time.Time { return now })),
+		jwt.WithAcceptableSkew(a.clockSkew),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid client_assertion: %v", err)
+	}
+	if !slices.ContainsFunc(token.Audience(), func(aud string) bool {
+		return slices.Contains(a.audiences, aud)
+	}) {
+		return fmt.Errorf("client_assertion audience %v is not accepted", token.Audience())
+	}
+
+	return a.useAssertion(client.ID+" "+token.JwtID(), token.Expiration().Add(a.clockSkew), now)
+}
+
+// useAssertion records an assertion as used until it expires, failing if it already was
+func (a *Authenticator) useAssertion(id string, expiresAt, now time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	maps.DeleteFunc(a.usedAssertions, func(_ string, exp time.Time) bool {
+		return now.After(exp)
+	})
+	if _, ok := a.usedAssertions[id]; ok {
+		return fmt.Errorf("client_assertion has already been used")
+	}
+	a.usedAssertions[id] = expiresAt
+	return nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/audit"
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/keys"
+)
+
+// defaultAuditKafkaTimeout bounds each produce request, which a check or exchange waits on
+const defaultAuditKafkaTimeout = 5 * time.Second
+
+// NewAuditLogger creates the audit logger from configuration
+// Records identify the instance that logged them and, with a signer_id, are signed
+// with a signer from signerRegistry.
+func NewAuditLogger(cfg AuditConfig, identity *instance.Identity, signerRegistry *keys.SignerRegistry, transport http.RoundTripper) (*audit.Logger, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, fmt.Errorf("audit requires at least one sink")
+	}
+
+	var signer keys.RotatingSigner
+	if cfg.SignerID != "" {
+		var err error
+		signer, err = signerRegistry.Get(cfg.SignerID)
+		if err != nil {
+			return nil, fmt.Errorf("signer not found: %s", cfg.SignerID)
+		}
+	}
+
+	var key string
+	if identity != nil {
+		key = identity.ID
+	}
+	sinks := make([]audit.Sink, 0, len(cfg.Sinks))
+	for i, sinkCfg := range cfg.Sinks {
+		sink, err := newAuditSink(sinkCfg, key, transport)
+		if err != nil {
+			for _, opened := range sinks {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to create audit sink %d: %w", i, err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return audit.NewLogger(audit.Config{
+		Sinks:    sinks,
+		Chain:    cfg.Chain,
+		Signer:   signer,
+		Instance: identity,
+	})
+}
+
+// newAuditSink creates an audit sink from configuration
+// Kafka records are keyed with key, so one instance's records stay in order.
+func newAuditSink(cfg AuditSinkConfig, key string, transport http.RoundTripper) (audit.Sink, error) {
+	switch cfg.Type {
+	case "stdout":
+		return audit.NewWriterSink(os.Stdout), nil
+
+	case "file":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("file audit sink requires path")
+		}
+		return audit.NewFileSink(cfg.Path)
+
+	case "kafka":
+		timeout := defaultAuditKafkaTimeout
+		if cfg.Timeout != "" {
+			var err error
+			timeout, err = time.ParseDuration(cfg.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid kafka audit sink timeout: %w", err)
+			}
+		}
+		return audit.NewKafkaSink(audit.KafkaSinkConfig{
+			URL:        cfg.URL,
+			Topic:      cfg.Topic,
+			Key:        key,
+			HTTPClient: &http.Client{Transport: transport, Timeout: timeout},
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown audit sink type: %s (supported: stdout, file, kafka)", cfg.Type)
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/avp"
+	"github.com/alechenninger/parsec/internal/mapper"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// NewAuthorizationPolicies creates the policies in the token policy that must allow
+// every issuance, in the order they are consulted
+func NewAuthorizationPolicies(cfg *TokenPolicyConfig, dataSources *service.DataSourceRegistry) ([]service.AuthorizationPolicy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	var policies []service.AuthorizationPolicy
+	if cfg.VerifiedPermissions != nil {
+		policy, err := newVerifiedPermissionsPolicy(*cfg.VerifiedPermissions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create verified permissions policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	if cfg.DecisionDataSource != "" {
+		ds := dataSources.Get(cfg.DecisionDataSource)
+		if ds == nil {
+			return nil, fmt.Errorf("token policy decision data source %s not found", cfg.DecisionDataSource)
+		}
+		policies = append(policies, service.NewDecisionDataSourcePolicy(ds))
+	}
+	return policies, nil
+}
+
+// newVerifiedPermissionsPolicy creates a Verified Permissions policy from configuration
+func newVerifiedPermissionsPolicy(cfg VerifiedPermissionsConfig) (*avp.Policy, error) {
+	var timeout time.Duration
+	if cfg.Timeout != "" {
+		duration, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		timeout = duration
+	}
+
+	return avp.NewPolicy(context.Background(), avp.Config{
+		PolicyStoreID: cfg.PolicyStoreID,
+		Region:        cfg.Region,
+		Endpoint:      cfg.Endpoint,
+		PrincipalType: cfg.PrincipalType,
+		ActionType:    cfg.ActionType,
+		ActionID:      cfg.ActionID,
+		ResourceType:  cfg.ResourceType,
+		Timeout:       timeout,
+	})
+}
+
+// NewAuthzRules creates the CEL rules that deny requests to a server, or returns
+// nil if there are none
+func NewAuthzRules(cfg []AuthzRuleConfig, dataSources *service.DataSourceRegistry) (service.AuthorizationPolicy, error) {
+	if len(cfg) == 0 {
+		return nil, nil
+	}
+	rules := make([]mapper.CELAuthzRule, len(cfg))
+	for i, rule := range cfg {
+		rules[i] = mapper.CELAuthzRule{Name: rule.Name, Deny: rule.Deny, Reason: rule.Reason}
+	}
+	policy, err := mapper.NewCELAuthzRules(rules, dataSources)
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
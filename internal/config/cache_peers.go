@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/datasource"
+)
+
+// defaultCachePeerPort is the port replicas serve their cache peers on by default
+const defaultCachePeerPort = 8090
+
+// NewCachePeerPool creates the cache peer pool from configuration
+// Static peers are set; Kubernetes peers are set once discovery starts.
+func NewCachePeerPool(cfg CachePeersConfig) (*datasource.PeerPool, error) {
+	if len(cfg.Peers) > 0 && cfg.Kubernetes != nil {
+		return nil, fmt.Errorf("peers and kubernetes are mutually exclusive")
+	}
+
+	port := cachePeerPort(cfg)
+	self := cfg.SelfURL
+	if self == "" {
+		var err error
+		if self, err = datasource.DetectSelfPeerURL("http", port); err != nil {
+			return nil, err
+		}
+	}
+
+	pool, err := datasource.NewPeerPool(datasource.PeerPoolConfig{
+		SelfURL: self,
+		Address: fmt.Sprintf(":%d", port),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pool.UpdatePeers(cfg.Peers...)
+	return pool, nil
+}
+
+// NewKubernetesPeerDiscovery creates discovery of the peers of pool from the
+// Kubernetes Service in configuration
+func NewKubernetesPeerDiscovery(cfg CachePeersConfig, pool datasource.PeerUpdater) (*datasource.KubernetesPeerDiscovery, error) {
+	var refreshInterval time.Duration
+	if cfg.Kubernetes.RefreshInterval != "" {
+		var err error
+		refreshInterval, err = time.ParseDuration(cfg.Kubernetes.RefreshInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid refresh_interval: %w", err)
+		}
+	}
+
+	return datasource.NewKubernetesPeerDiscovery(datasource.KubernetesPeerDiscoveryConfig{
+		Service:         cfg.Kubernetes.Service,
+		Namespace:       cfg.Kubernetes.Namespace,
+		Port:            cachePeerPort(cfg),
+		RefreshInterval: refreshInterval,
+		Peers:           pool,
+	})
+}
+
+// cachePeerPort returns the configured cache peer port or its default
+func cachePeerPort(cfg CachePeersConfig) int {
+	if cfg.Port == 0 {
+		return defaultCachePeerPort
+	}
+	return cfg.Port
+}
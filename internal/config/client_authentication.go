@@ -0,0 +1,79 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// NewClientAuthenticator creates a token endpoint client authenticator from configuration
+// transport is used to fetch the JWKS of private_key_jwt clients, if not nil. If
+// offline, their JWKS are not fetched until a client authenticates.
+func NewClientAuthenticator(cfg ClientAuthenticationConfig, transport http.RoundTripper, offline bool) (*clientauth.Authenticator, error) {
+	var clients []*clientauth.Client
+	closeKeys := func() {
+		for _, client := range clients {
+			if client.Keys != nil {
+				client.Keys.Close()
+			}
+		}
+	}
+
+	for _, clientCfg := range cfg.Clients {
+		client, err := newClient(clientCfg, transport, offline)
+		if err != nil {
+			closeKeys()
+			return nil, fmt.Errorf("client %s: %w", clientCfg.ClientID, err)
+		}
+		clients = append(clients, client)
+	}
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients:   clients,
+		Audiences: cfg.AssertionAudiences,
+	})
+	if err != nil {
+		closeKeys()
+		return nil, err
+	}
+	return authenticator, nil
+}
+
+// newClient creates a registered client from configuration, fetching its keys if needed
+func newClient(cfg ClientConfig, transport http.RoundTripper, offline bool) (*clientauth.Client, error) {
+	client := &clientauth.Client{
+		ID:        cfg.ClientID,
+		Method:    clientauth.Method(cfg.Method),
+		Secret:    cfg.Secret,
+		SubjectDN: cfg.TLSSubjectDN,
+		SANs:      cfg.TLSSANs,
+	}
+
+	switch {
+	case cfg.JWKSURL != "" && cfg.JWKS != "":
+		return nil, fmt.Errorf("only one of jwks_url or jwks may be set")
+	case cfg.JWKS != "":
+		keys, err := trust.NewStaticJWKSSource([]byte(cfg.JWKS))
+		if err != nil {
+			return nil, err
+		}
+		client.Keys = keys
+	case cfg.JWKSURL != "":
+		cacheCfg := trust.JWKSCacheConfig{URL: cfg.JWKSURL, Lazy: offline}
+		if transport != nil {
+			cacheCfg.HTTPClient = &http.Client{Transport: transport}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		keys, err := trust.NewJWKSCache(ctx, cacheCfg)
+		if err != nil {
+			return nil, err
+		}
+		client.Keys = keys
+	}
+	return client, nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/clientauth"
+)
+
+func TestNewClientAuthenticator(t *testing.T) {
+	authenticator, err := NewClientAuthenticator(ClientAuthenticationConfig{
+		Clients: []ClientConfig{
+			{ClientID: "gateway", Method: "client_secret_basic", Secret: "gateway-secret"},
+			{ClientID: "mesh", Method: "tls_client_auth", TLSSANs: []string{"spiffe://example.com/mesh"}},
+		},
+	}, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer authenticator.Close()
+
+	client, err := authenticator.Authenticate(context.Background(), &clientauth.Credentials{
+		ClientID:     "gateway",
+		ClientSecret: "gateway-secret",
+	})
+	if !errors.Is(err, clientauth.ErrInvalidClient) {
+		t.Errorf("expected client_secret_basic client to reject client_secret_post, got client %v, error %v", client, err)
+	}
+
+	for name, cfg := range map[string]ClientConfig{
+		"unknown method":      {ClientID: "a", Method: "none"},
+		"invalid inline JWKS": {ClientID: "a", Method: "private_key_jwt", JWKS: `{"keys":[]}`},
+		"both JWKS and URL":   {ClientID: "a", Method: "private_key_jwt", JWKS: `{}`, JWKSURL: "https://example.com/jwks"},
+		"missing secret":      {ClientID: "a", Method: "client_secret_post"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewClientAuthenticator(ClientAuthenticationConfig{Clients: []ClientConfig{cfg}}, nil, false); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
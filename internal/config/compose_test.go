@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLoader_Composition(t *testing.T) {
+	// write writes files into a new directory, returning the path of the first
+	write := func(t *testing.T, files ...string) string {
+		dir := t.TempDir()
+		for i := 0; i < len(files); i += 2 {
+			path := filepath.Join(dir, files[i])
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				t.Fatalf("failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(files[i+1]), 0600); err != nil {
+				t.Fatalf("failed to write %s: %v", files[i], err)
+			}
+		}
+		return filepath.Join(dir, files[0])
+	}
+
+	base := `
+trust_domain: base.example.com
+server:
+  grpc_port: 9000
+  http_port: 8000
+issuers:
+  - token_type: urn:ietf:params:oauth:token-type:txn_token
+    type: unsigned
+`
+
+	t.Run("includes files beneath the including file", func(t *testing.T) {
+		configPath := write(t,
+			"parsec.yaml", `
+include:
+  - shared/base.yaml
+  - shared/ports.json
+server:
+  http_port: 8443
+`,
+			"shared/base.yaml", base,
+			"shared/ports.json", `{"server": {"grpc_port": 9443}}`,
+		)
+		loader, err := NewLoader(configPath)
+		if err != nil {
+			t.Fatalf("NewLoader failed: %v", err)
+		}
+		cfg, err := loader.Get()
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if cfg.TrustDomain != "base.example.com" || len(cfg.Issuers) != 1 {
+			t.Errorf("expected the included base, got %+v", cfg)
+		}
+		if cfg.Server.GRPCPort != 9443 {
+			t.Errorf("expected later includes to take precedence, got grpc port %d", cfg.Server.GRPCPort)
+		}
+		if cfg.Server.HTTPPort != 8443 {
+			t.Errorf("expected the including file to take precedence, got http port %d", cfg.Server.HTTPPort)
+		}
+	})
+
+	t.Run("rejects include cycles", func(t *testing.T) {
+		configPath := write(t,
+			"parsec.yaml", "include: [other.yaml]\n",
+			"other.yaml", "include: [parsec.yaml]\n",
+		)
+		if _, err := NewLoader(configPath); err == nil || !strings.Contains(err.Error(), "includes itself") {
+			t.Errorf("expected a cycle error, got %v", err)
+		}
+	})
+
+	overlays := base + `
+overlays:
+  prod:
+    trust_domain: prod.example.com
+    server:
+      grpc_port: 443
+  dev:
+    trust_domain: dev.example.com
+`
+
+	t.Run("applies the selected overlay", func(t *testing.T) {
+		t.Setenv("PARSEC_OVERLAY", "prod")
+		loader, err := NewLoader(write(t, "parsec.yaml", overlays))
+		if err != nil {
+			t.Fatalf("NewLoader failed: %v", err)
+		}
+		cfg, err := loader.Get()
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if cfg.TrustDomain != "prod.example.com" || cfg.Server.GRPCPort != 443 {
+			t.Errorf("expected the prod overlay, got trust domain %s and grpc port %d", cfg.TrustDomain, cfg.Server.GRPCPort)
+		}
+		if cfg.Server.HTTPPort != 8000 || len(cfg.Issuers) != 1 {
+			t.Errorf("expected options the overlay does not patch to be kept, got %+v", cfg)
+		}
+	})
+
+	t.Run("applies overlays beneath flags", func(t *testing.T) {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		RegisterFlags(flags)
+		if err := flags.Parse([]string{"--overlay=prod", "--server-grpc-port=9999"}); err != nil {
+			t.Fatalf("failed to parse flags: %v", err)
+		}
+		loader, err := NewLoaderWithFlags(write(t, "parsec.yaml", overlays), flags)
+		if err != nil {
+			t.Fatalf("NewLoaderWithFlags failed: %v", err)
+		}
+		cfg, err := loader.Get()
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if cfg.TrustDomain != "prod.example.com" || cfg.Server.GRPCPort != 9999 {
+			t.Errorf("expected the flag over the prod overlay, got trust domain %s and grpc port %d", cfg.TrustDomain, cfg.Server.GRPCPort)
+		}
+	})
+
+	t.Run("rejects unknown overlays", func(t *testing.T) {
+		t.Setenv("PARSEC_OVERLAY", "staging")
+		if _, err := NewLoader(write(t, "parsec.yaml", overlays)); err == nil || !strings.Contains(err.Error(), "unknown config overlay: staging") {
+			t.Errorf("expected an unknown overlay error, got %v", err)
+		}
+	})
+
+	t.Run("reports unknown keys in overlays", func(t *testing.T) {
+		loader, err := NewLoader(write(t, "parsec.yaml", base+`
+overlays:
+  prod:
+    trust_domian: prod.example.com
+`))
+		if err != nil {
+			t.Fatalf("NewLoader failed: %v", err)
+		}
+		if _, err := loader.Get(); err == nil || !strings.Contains(err.Error(), "overlays.prod.trust_domian: unknown key") {
+			t.Errorf("expected an unknown key error, got %v", err)
+		}
+	})
+}
@@ -9,12 +9,32 @@ type Config struct {
 	// Used as the audience for all issued tokens
 	TrustDomain string `koanf:"trust_domain" usage:"trust domain for issued tokens (audience claim)"`
 
+	// TrustDomains are additional trust domains, each with its own issuers and keys,
+	// selected by the requested audience or a route's parsec.trust_domain
+	TrustDomains []TrustDomainConfig `koanf:"trust_domains"`
+
 	// AuthzServer configuration for ext_authz service
 	AuthzServer *AuthzServerConfig `koanf:"authz_server"`
 
 	// ExchangeServer configuration for token exchange service
 	ExchangeServer *ExchangeServerConfig `koanf:"exchange_server"`
 
+	// AdminServer configures the admin API (disabled if not set)
+	AdminServer *AdminServerConfig `koanf:"admin_server"`
+
+	// DebugServer configures the debug listener: pprof, config, key, and cache state (disabled if not set)
+	DebugServer *DebugServerConfig `koanf:"debug_server"`
+
+	// IntrospectionServer configures the token introspection endpoint (disabled if not set)
+	IntrospectionServer *IntrospectionServerConfig `koanf:"introspection_server"`
+
+	// RevocationServer configures the token revocation endpoint (disabled if not set)
+	RevocationServer *RevocationServerConfig `koanf:"revocation_server"`
+
+	// ClaimsServer configures the endpoint serving claims kept out of tokens by
+	// reference (disabled if not set)
+	ClaimsServer *ClaimsServerConfig `koanf:"claims_server"`
+
 	// TrustStore configuration (validators and filtering)
 	TrustStore TrustStoreConfig `koanf:"trust_store"`
 
@@ -27,14 +47,81 @@ type Config struct {
 	// Signers defines named signer instances (e.g., rotating key signers)
 	Signers []SignerConfig `koanf:"signers"`
 
+	// KeySlotStore configures where signers persist key rotation state
+	// Replicas must share a store to coordinate rotation (default: in-memory)
+	KeySlotStore *KeySlotStoreConfig `koanf:"key_slot_store"`
+
 	// Issuers configuration for different token types
 	Issuers []IssuerConfig `koanf:"issuers"`
 
+	// TokenStore configures where opaque issuers keep the claims of their tokens
+	// Replicas must share a store to introspect each other's tokens (default: in-memory)
+	TokenStore *TokenStoreConfig `koanf:"token_store"`
+
+	// Denylist configures where revoked tokens are recorded until they expire
+	// Replicas must share a denylist to deny each other's revocations (default: in-memory)
+	Denylist *DenylistConfig `koanf:"denylist"`
+
+	// Audit, if set, records every token issuance and denial in a tamper-evident audit log
+	Audit *AuditConfig `koanf:"audit"`
+
+	// TokenPolicy sets limits on issued tokens that apply regardless of issuer configuration
+	TokenPolicy *TokenPolicyConfig `koanf:"token_policy"`
+
+	// JWKS configures the JWKS endpoint and external key distribution
+	JWKS *JWKSConfig `koanf:"jwks"`
+
+	// Secrets configures where secret references in sensitive options are read from
+	Secrets *SecretsConfig `koanf:"secrets"`
+
 	// Fixtures for hermetic testing (HTTP rules, etc.)
 	Fixtures []FixtureConfig `koanf:"fixtures"`
 
 	// Observability configuration (logging, metrics, tracing)
 	Observability *ObservabilityConfig `koanf:"observability"`
+
+	// Instance configures how this replica identifies itself in audit logs and tokens
+	Instance *InstanceConfig `koanf:"instance"`
+
+	// CachePeers shares distributed data source caches between replicas (disabled if not set)
+	CachePeers *CachePeersConfig `koanf:"cache_peers"`
+
+	// Include lists config files loaded beneath this one, relative to this file
+	Include []string `koanf:"include"`
+
+	// Overlays are named patches to this configuration, like per-environment differences
+	Overlays map[string]Config `koanf:"overlays"`
+
+	// Overlay selects the overlay applied over the config file
+	Overlay string `koanf:"overlay" usage:"named overlay of the config file to apply (e.g., prod)"`
+}
+
+// InstanceConfig configures the instance identity
+type InstanceConfig struct {
+	// ID is an explicit instance ID (e.g., a StatefulSet pod name)
+	// Takes precedence over IDFile
+	ID string `koanf:"id" usage:"instance ID used in audit logs and tokens (default: generated)"`
+
+	// IDFile persists a generated instance ID across restarts
+	// If unset (and ID is unset), a new ID is generated on every start
+	IDFile string `koanf:"id_file" usage:"file to persist the generated instance ID across restarts"`
+}
+
+// SecretsConfig configures how secret references are resolved
+// Sensitive options, like secret, password, and token, may be a reference instead of
+// the secret itself: env://NAME reads an environment variable, file:///path reads a
+// file, and vault://mount/path#field reads a field of a Vault KV secret.
+type SecretsConfig struct {
+	// Vault configures vault:// references (default: VAULT_ADDR and VAULT_TOKEN)
+	Vault *VaultSecretsConfig `koanf:"vault"`
+}
+
+// VaultSecretsConfig configures how secrets are read from Vault's KV secrets engine
+type VaultSecretsConfig struct {
+	Address        string           `koanf:"address" usage:"Vault address for vault:// references (default: $VAULT_ADDR)"` // Defaults to VAULT_ADDR
+	KVVersion      int              `koanf:"kv_version" usage:"Vault KV secrets engine version: 1, 2 (default: 2)"`        // 1 or 2 (default)
+	VaultNamespace string           `koanf:"vault_namespace" usage:"Vault Enterprise namespace"`                           // Vault Enterprise namespace
+	Auth           *VaultAuthConfig `koanf:"auth"`                                                                         // Vault auth method (default: token from $VAULT_TOKEN)
 }
 
 // ServerConfig contains network-level server settings
@@ -44,12 +131,125 @@ type ServerConfig struct {
 
 	// HTTPPort is the port for HTTP services (gRPC-gateway transcoding)
 	HTTPPort int `koanf:"http_port" usage:"HTTP server port (gRPC-gateway transcoding)"`
+
+	// TLS, if set, serves gRPC and HTTP over TLS from certificate files (reloaded when they change)
+	TLS *ServerTLSConfig `koanf:"tls"`
+
+	// ShutdownTimeout is how long in-flight requests may take to finish on shutdown
+	// before they are cut off (e.g., "30s")
+	// Default: "30s"
+	ShutdownTimeout string `koanf:"shutdown_timeout" usage:"how long in-flight requests may take to finish on shutdown (default: 30s)"`
+}
+
+// ServerTLSConfig configures TLS for the gRPC and HTTP servers
+type ServerTLSConfig struct {
+	// CertFile and KeyFile are the PEM-encoded server certificate and private key
+	CertFile string `koanf:"cert_file" usage:"PEM server certificate file for gRPC and HTTP"`
+	KeyFile  string `koanf:"key_file" usage:"PEM server private key file"`
+
+	// ClientCAFile, if set, verifies client certificates, which authenticate the
+	// calling proxy as the actor of ext_authz checks
+	ClientCAFile string `koanf:"client_ca_file" usage:"PEM CA file verifying client certificates"`
 }
 
 // AuthzServerConfig configures the ext_authz authorization server
 type AuthzServerConfig struct {
 	// TokenTypes specifies which token types to issue and how to deliver them
 	TokenTypes []TokenTypeConfig `koanf:"token_types"`
+
+	// TrustForwardedClientCert reads workload certificates from the x-forwarded-client-cert
+	// header when Envoy does not send the peer certificate
+	// Only enable this if the proxy in front of parsec sanitizes the header
+	TrustForwardedClientCert bool `koanf:"trust_forwarded_client_cert" usage:"read workload certificates from x-forwarded-client-cert (only if the proxy sanitizes it)"`
+
+	// CertificateBoundTokens binds issued tokens to the requesting workload's validated
+	// client certificate (RFC 8705 cnf claim)
+	CertificateBoundTokens bool `koanf:"certificate_bound_tokens" usage:"bind issued tokens to the workload client certificate (cnf claim)"`
+
+	// TransactionIDHeader, if set, adds the txn ID of the issued transaction token to
+	// the upstream request and the response to the client (e.g., "x-transaction-id")
+	TransactionIDHeader string `koanf:"transaction_id_header" usage:"header carrying the txn ID of issued transaction tokens (e.g. x-transaction-id)"`
+
+	// Deny configures the HTTP responses denied requests get
+	Deny *DenyConfig `koanf:"deny"`
+
+	// Headers configures how allowed requests' headers are changed before they go upstream
+	Headers *HeaderPolicyConfig `koanf:"headers"`
+
+	// BodyCredentials are rules for reading bearer tokens from request bodies, used when a
+	// request has no credential header. Envoy must be configured with with_request_body.
+	BodyCredentials []BodyCredentialConfig `koanf:"body_credentials"`
+
+	// ValidationCache, if set, reuses recent validations of the same credential
+	ValidationCache *ValidationCacheConfig `koanf:"validation_cache"`
+
+	// AuthzRules are CEL rules that deny requests before any token is issued
+	AuthzRules []AuthzRuleConfig `koanf:"authz_rules"`
+}
+
+// AuthzRuleConfig configures a CEL rule that denies requests
+type AuthzRuleConfig struct {
+	// Name identifies the rule
+	Name string `koanf:"name"`
+
+	// Deny is a CEL expression that is true for requests to deny, with the same
+	// variables and functions as CEL claim mappers
+	Deny string `koanf:"deny"`
+
+	// Reason explains denials (default: the rule's name)
+	Reason string `koanf:"reason"`
+}
+
+// ValidationCacheConfig configures the ext_authz subject validation cache
+type ValidationCacheConfig struct {
+	// TTL is how long a validation is reused (e.g., "5s")
+	TTL string `koanf:"ttl" usage:"how long a subject validation is reused (e.g. 5s)"`
+
+	// MaxEntries bounds the number of cached validations (default: 10000)
+	MaxEntries int `koanf:"max_entries" usage:"maximum cached validations (default: 10000)"`
+}
+
+// BodyCredentialConfig is a rule for extracting a bearer token from request bodies
+type BodyCredentialConfig struct {
+	// ContentType is the media type of bodies the rule applies to (e.g., "application/json")
+	ContentType string `koanf:"content_type"`
+
+	// JSONPath is a dot-separated path to the token in a JSON body (e.g., "extensions.authorization")
+	JSONPath string `koanf:"json_path"`
+
+	// XMLPath is a slash-separated path of element local names to the token in an XML body
+	// (e.g., "Envelope/Header/Security/BinarySecurityToken")
+	XMLPath string `koanf:"xml_path"`
+}
+
+// HeaderPolicyConfig configures ext_authz header mutations
+type HeaderPolicyConfig struct {
+	// Strip lists request headers to remove besides the credential header (e.g., "cookie")
+	Strip []string `koanf:"strip"`
+
+	// Append lists added headers whose values are appended to the request's rather than replacing them
+	Append []string `koanf:"append"`
+
+	// MaxSize is the largest header value parsec adds, in bytes; larger ones deny the request (0 for no limit)
+	MaxSize int `koanf:"max_size" usage:"largest header value added, in bytes (0: no limit)"`
+
+	// ForwardedUserHeader, if set, carries the validated subject upstream (e.g., "x-forwarded-user")
+	ForwardedUserHeader string `koanf:"forwarded_user_header" usage:"header carrying the validated subject upstream (e.g. x-forwarded-user)"`
+}
+
+// DenyConfig configures ext_authz denial responses
+type DenyConfig struct {
+	// UnauthenticatedStatus is the HTTP status for missing or invalid credentials: 401 (default) or 403
+	UnauthenticatedStatus int `koanf:"unauthenticated_status" usage:"HTTP status for missing or invalid credentials: 401, 403 (default: 401)"`
+
+	// WWWAuthenticate adds a Bearer WWW-Authenticate challenge (RFC 6750) to denials
+	WWWAuthenticate bool `koanf:"www_authenticate" usage:"add a Bearer WWW-Authenticate challenge to denials"`
+
+	// Realm is the realm of WWW-Authenticate challenges (optional)
+	Realm string `koanf:"realm" usage:"realm of WWW-Authenticate challenges"`
+
+	// JSONBody renders denials as JSON OAuth errors instead of plain text
+	JSONBody bool `koanf:"json_body" usage:"render denials as JSON OAuth errors"`
 }
 
 // TokenTypeConfig specifies a token type to issue via ext_authz
@@ -63,13 +263,193 @@ type TokenTypeConfig struct {
 
 	// HeaderName is the HTTP header to use for this token
 	// e.g., "Transaction-Token", "Authorization", "X-Custom-Token"
+	// Optional if Cookie is set
 	HeaderName string `koanf:"header_name"`
+
+	// Cookie delivers the token to browsers as a Secure, HttpOnly cookie
+	Cookie *CookieConfig `koanf:"cookie"`
+}
+
+// CookieConfig configures token delivery as a cookie
+type CookieConfig struct {
+	// Name is the cookie name (e.g., "__Host-txn_token")
+	Name string `koanf:"name"`
+
+	// Domain is the cookie domain; empty for a host-only cookie
+	Domain string `koanf:"domain"`
+
+	// Path is the cookie path (default: "/")
+	Path string `koanf:"path"`
+
+	// SameSite is "strict" (default), "lax", or "none"
+	SameSite string `koanf:"same_site"`
 }
 
 // ExchangeServerConfig configures the token exchange server
 type ExchangeServerConfig struct {
 	// ClaimsFilter determines which request_context claims actors can provide
 	ClaimsFilter ClaimsFilterConfig `koanf:"claims_filter"`
+
+	// AllowedAudiences are the audiences and resources clients may request
+	// besides the trust domain
+	AllowedAudiences []string `koanf:"allowed_audiences"`
+
+	// ScopePolicy decides which requested scopes tokens are issued with
+	ScopePolicy ScopePolicyConfig `koanf:"scope_policy"`
+
+	// ClientAuthentication, if it registers clients, requires clients to authenticate
+	ClientAuthentication ClientAuthenticationConfig `koanf:"client_authentication"`
+
+	// CertificateBoundTokens binds issued tokens to the caller's validated mTLS client
+	// certificate (RFC 8705 cnf claim)
+	CertificateBoundTokens bool `koanf:"certificate_bound_tokens" usage:"bind issued tokens to the caller mTLS client certificate (cnf claim)"`
+
+	// ReexchangeTokens, if set, issues re-exchange tokens with exchanged tokens
+	ReexchangeTokens *ReexchangeTokensConfig `koanf:"reexchange_tokens"`
+
+	// RateLimit, if set, limits the rate of token exchanges
+	RateLimit *ExchangeRateLimitConfig `koanf:"rate_limit"`
+
+	// MaxTokenBytes is the largest subject_token or actor_token accepted (0: unlimited)
+	MaxTokenBytes int `koanf:"max_token_bytes" usage:"largest subject_token or actor_token accepted, in bytes (0: unlimited)"`
+
+	// MaxRequestBytes is the largest HTTP request body of the token endpoint (0: unlimited)
+	MaxRequestBytes int64 `koanf:"max_request_bytes" usage:"largest token endpoint request body, in bytes (0: unlimited)"`
+
+	// AuthzRules are CEL rules that deny exchanges before any token is issued
+	AuthzRules []AuthzRuleConfig `koanf:"authz_rules"`
+
+	// Preview serves POST /v1/token:preview, which returns the claims an exchange
+	// would issue without issuing a token (requires client authentication)
+	Preview bool `koanf:"preview" usage:"serve POST /v1/token:preview (requires client authentication)"`
+}
+
+// ExchangeRateLimitConfig limits the rate of token exchanges, overall and by client
+type ExchangeRateLimitConfig struct {
+	// Global limits all exchanges together
+	Global *RateLimitConfig `koanf:"global"`
+
+	// PerClient limits the exchanges of each authenticated client, or of each actor
+	// if clients do not authenticate
+	PerClient *RateLimitConfig `koanf:"per_client"`
+}
+
+// RateLimitConfig configures a token bucket
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained rate allowed
+	RequestsPerSecond float64 `koanf:"requests_per_second" usage:"sustained exchanges per second allowed"`
+
+	// Burst is how many requests are allowed at once (default: requests_per_second)
+	Burst int `koanf:"burst" usage:"exchanges allowed at once (default: requests_per_second)"`
+
+	// MaxClients bounds the number of clients tracked at once (per_client only, default: 10000)
+	MaxClients int `koanf:"max_clients" usage:"maximum clients tracked at once, per_client only (default: 10000)"`
+}
+
+// ReexchangeTokensConfig configures re-exchange tokens, which callers redeem for fresh
+// tokens without presenting the original subject_token again
+type ReexchangeTokensConfig struct {
+	// SignerID references a named signer from the global signers config
+	SignerID string `koanf:"signer_id" usage:"signer of re-exchange tokens"`
+
+	// TTL is how long a re-exchange token can be redeemed (default: "1h")
+	TTL string `koanf:"ttl" usage:"how long a re-exchange token can be redeemed (default: 1h)"`
+}
+
+// ClientAuthenticationConfig configures the clients of the token exchange endpoint
+type ClientAuthenticationConfig struct {
+	// AssertionAudiences are the accepted aud claims of private_key_jwt client
+	// assertions, such as the token endpoint URL
+	AssertionAudiences []string `koanf:"assertion_audiences"`
+
+	// Clients are the registered clients
+	Clients []ClientConfig `koanf:"clients"`
+}
+
+// ClientConfig registers a client of the token exchange endpoint
+type ClientConfig struct {
+	ClientID string `koanf:"client_id"`
+
+	// Method is how the client authenticates
+	// Options: "client_secret_basic", "client_secret_post", "private_key_jwt", "tls_client_auth"
+	Method string `koanf:"method"`
+
+	// client_secret_basic and client_secret_post
+	Secret string `koanf:"secret"`
+
+	// private_key_jwt: the client's public keys, as a JWKS URL or inline JWKS
+	JWKSURL string `koanf:"jwks_url"`
+	JWKS    string `koanf:"jwks"`
+
+	// tls_client_auth: the client certificate's subject DN and/or subject alternative names
+	TLSSubjectDN string   `koanf:"tls_subject_dn"`
+	TLSSANs      []string `koanf:"tls_sans"`
+}
+
+// ScopePolicyConfig configures the scope policy of the exchange server
+type ScopePolicyConfig struct {
+	// Type selects the policy implementation
+	// Options: "passthrough" (default), "allowlist", "cel"
+	Type string `koanf:"type" usage:"scope policy type: passthrough, allowlist, cel"`
+
+	// OnDenied is "downgrade" (default) to issue tokens with only the granted scopes,
+	// or "reject" to fail exchanges requesting a denied scope with invalid_scope
+	OnDenied string `koanf:"on_denied" usage:"what denied scopes do: downgrade, reject (default: downgrade)"`
+
+	// CEL policy: evaluated per requested scope
+	Script string `koanf:"script" usage:"CEL script deciding each requested scope"`
+
+	// Allowlist policy: the scopes subjects of each trust domain may be granted
+	Subjects []ScopeAllowlistConfig `koanf:"subjects"`
+
+	// Allowlist policy: if set, the scopes actors of each trust domain may request
+	Actors []ScopeAllowlistConfig `koanf:"actors"`
+}
+
+// ScopeAllowlistConfig allows a trust domain scopes
+type ScopeAllowlistConfig struct {
+	TrustDomain string   `koanf:"trust_domain"`
+	Scopes      []string `koanf:"scopes"`
+}
+
+// AdminServerConfig configures the admin API
+type AdminServerConfig struct {
+	// Tokens are bearer tokens that authorize admin calls
+	Tokens []string `koanf:"tokens"`
+
+	// TokenFile is a file with one bearer token per line (alternative to Tokens)
+	TokenFile string `koanf:"token_file" usage:"file with one admin bearer token per line"`
+}
+
+// DebugServerConfig configures the debug listener
+type DebugServerConfig struct {
+	// Address is where the debug listener listens
+	// Default: "localhost:6060", reachable only from the host (e.g., with kubectl port-forward)
+	Address string `koanf:"address" usage:"debug listener address (default: localhost:6060)"`
+
+	// Tokens, if set, are bearer tokens one of which every debug request must carry
+	Tokens []string `koanf:"tokens"`
+
+	// TokenFile is a file with one bearer token per line (alternative to Tokens)
+	TokenFile string `koanf:"token_file" usage:"file with one debug bearer token per line"`
+}
+
+// IntrospectionServerConfig configures the token introspection endpoint (RFC 7662)
+type IntrospectionServerConfig struct {
+	// ClientAuthentication registers the resource servers that may introspect tokens
+	ClientAuthentication ClientAuthenticationConfig `koanf:"client_authentication"`
+}
+
+// RevocationServerConfig configures the token revocation endpoint (RFC 7009)
+type RevocationServerConfig struct {
+	// ClientAuthentication registers the clients that may revoke tokens
+	ClientAuthentication ClientAuthenticationConfig `koanf:"client_authentication"`
+}
+
+// ClaimsServerConfig configures the claims retrieval endpoint
+type ClaimsServerConfig struct {
+	// ClientAuthentication registers the services that may retrieve referenced claims
+	ClientAuthentication ClientAuthenticationConfig `koanf:"client_authentication"`
 }
 
 // TrustStoreConfig configures the trust store and its validators
@@ -83,6 +463,10 @@ type TrustStoreConfig struct {
 
 	// Filter configuration (only used when Type is "filtered_store")
 	Filter *ValidatorFilterConfig `koanf:"filter"`
+
+	// JWKSCacheDir persists the last successfully fetched JWKS of every JWT validator
+	// (unless it sets jwks_cache_file), so parsec can start while an IdP is unreachable
+	JWKSCacheDir string `koanf:"jwks_cache_dir" usage:"directory to persist fetched JWKS for startup when an IdP is unreachable"`
 }
 
 // NamedValidatorConfig is a validator with a name (for FilteredStore)
@@ -97,22 +481,94 @@ type NamedValidatorConfig struct {
 // ValidatorConfig configures a credential validator
 type ValidatorConfig struct {
 	// Type selects the validator implementation
-	// Options: "jwt_validator", "json_validator", "stub_validator"
+	// Options: "jwt_validator", "json_validator", "x509_validator", "spiffe_validator", "introspection", "api_key_validator", "aws_sigv4_validator", "saml_validator", "wasm", "stub_validator"
 	Type string `koanf:"type"`
 
 	// JWT Validator fields
-	Issuer          string `koanf:"issuer"`
-	JWKSURL         string `koanf:"jwks_url"`
-	TrustDomain     string `koanf:"trust_domain"`
-	RefreshInterval string `koanf:"refresh_interval"` // Duration string like "15m"
+	Issuer             string `koanf:"issuer"`
+	JWKSURL            string `koanf:"jwks_url"`
+	TrustDomain        string `koanf:"trust_domain"`
+	RefreshInterval    string `koanf:"refresh_interval"`     // Duration string like "15m"
+	MinRefreshInterval string `koanf:"min_refresh_interval"` // Shortest time between JWKS fetches (e.g., for unknown key IDs), like "1m"
+	JWKSCacheFile      string `koanf:"jwks_cache_file"`      // Last known good JWKS, used if the IdP is unreachable at startup
+	JWKS               string `koanf:"jwks"`                 // Inline JWKS JSON, instead of jwks_url, for IdPs that are unreachable at runtime
+	JWKSFile           string `koanf:"jwks_file"`            // Local JWKS file, instead of jwks_url; reloaded when it changes
+
+	RequiredAudiences []string `koanf:"required_audiences"` // Audiences that must all be in the aud claim
+	AllowedAudiences  []string `koanf:"allowed_audiences"`  // If set, the aud claim must include one of these
+	AuthorizedParties []string `koanf:"authorized_parties"` // If set, the azp claim must be one of these client IDs
+	ClockSkew         string   `koanf:"clock_skew"`         // Leeway for exp/nbf/iat checks, like "30s"
+	MaxTokenAge       string   `koanf:"max_token_age"`      // Rejects tokens issued longer ago than this, like "1h"
 
 	// JSON Validator fields
 	// (TrustDomain is shared)
 
+	// X.509 Validator fields
+	// (TrustDomain is shared; for SPIFFE certificates it is the SPIFFE trust domain)
+	CAFile        string   `koanf:"ca_file"`        // PEM bundle of CAs that issue client certificates (e.g., SPIFFE trust bundle)
+	CAFiles       []string `koanf:"ca_files"`       // Additional PEM bundles
+	CAPEM         string   `koanf:"ca_pem"`         // Inline PEM bundle
+	ExtKeyUsages  []string `koanf:"ext_key_usages"` // Acceptable extended key usages: client_auth (default), server_auth, any
+	AllowedSANs   []string `koanf:"allowed_sans"`   // DNS/URI/email SAN patterns, with a leading or trailing "*" wildcard
+	SubjectSource string   `koanf:"subject_source"` // subject_dn, dns_san, uri_san, email_san (default: SPIFFE ID, otherwise subject DN)
+
+	// SPIFFE Validator fields
+	// (TrustDomain and RefreshInterval are shared; the trust bundle comes from the
+	// Workload API or the trust domain's bundle endpoint)
+	WorkloadAPIAddr   string   `koanf:"workload_api_addr"`   // e.g., "unix:///run/spire/sockets/agent.sock"
+	BundleEndpointURL string   `koanf:"bundle_endpoint_url"` // https_web bundle endpoint, e.g., "https://spire.example.org/bundle"
+	Audiences         []string `koanf:"audiences"`           // Accepted JWT-SVID audiences; JWT-SVIDs are rejected if empty
+
+	// Introspection Validator fields
+	// (TrustDomain is shared; Issuer, if set, is the expected iss of introspected tokens)
+	IntrospectionURL string `koanf:"introspection_url"` // RFC 7662 token introspection endpoint
+	ClientID         string `koanf:"client_id"`         // Client credentials for the introspection endpoint
+	ClientSecret     string `koanf:"client_secret"`
+	ClientSecretFile string `koanf:"client_secret_file"` // Path to a file containing the client secret (alternative to ClientSecret)
+
+	// API Key Validator fields
+	// (TrustDomain is shared)
+	Header   string             `koanf:"header"`    // Request header carrying the API key (e.g., "X-API-Key")
+	KeyStore *APIKeyStoreConfig `koanf:"key_store"` // Where keys are looked up
+
+	// AWS SigV4 Validator fields
+	// (TrustDomain is shared; if empty, the caller's AWS account ID is the trust domain)
+	Audience        string   `koanf:"audience"`         // Required value of the signed x-parsec-audience header
+	AllowedAccounts []string `koanf:"allowed_accounts"` // AWS account IDs allowed to authenticate (default: any)
+	STSHosts        []string `koanf:"sts_hosts"`        // Allowed STS endpoint hosts (default: AWS STS endpoints)
+
+	// SAML Validator fields
+	// (TrustDomain is shared; Audience is this service's entity ID, which assertions must be restricted to)
+	IdPMetadataFile string `koanf:"idp_metadata_file"` // IdP's SAML metadata (entity ID and signing certificates)
+	IdPMetadataURL  string `koanf:"idp_metadata_url"`  // Alternatively, where to fetch the IdP's metadata at startup
+	Recipient       string `koanf:"recipient"`         // Required Recipient of bearer subject confirmations, such as the token endpoint URL (default: audience)
+
+	// WASM Validator fields
+	// (TrustDomain is the default trust domain of results; CredentialTypes is shared)
+	WASM *WASMConfig `koanf:"wasm"`
+
 	// Stub Validator fields
 	CredentialTypes []string `koanf:"credential_types"` // e.g., ["bearer", "jwt"]
 }
 
+// APIKeyStoreConfig configures the store an API key validator looks keys up in
+type APIKeyStoreConfig struct {
+	// Type selects the store implementation
+	// Options: "static", "sql", "http"
+	Type string `koanf:"type"`
+
+	// Static store fields
+	File string `koanf:"file"` // YAML file with a "keys" list
+
+	// SQL store fields
+	Driver string `koanf:"driver"` // postgres, mysql
+	DSN    string `koanf:"dsn"`
+	Query  string `koanf:"query"` // Selects owner, tenant, claims, expires_at by key SHA-256 hex digest
+
+	// HTTP store fields
+	URL string `koanf:"url"` // Lookup endpoint
+}
+
 // ValidatorFilterConfig configures validator filtering for actors
 type ValidatorFilterConfig struct {
 	// Type selects the filter implementation
@@ -132,9 +588,18 @@ type DataSourceConfig struct {
 	Name string `koanf:"name"`
 
 	// Type selects the data source implementation
-	// Options: "lua"
+	// Options: "lua", "redis", "opa", "wasm"
 	Type string `koanf:"type"`
 
+	// WASM data source fields
+	WASM *WASMConfig `koanf:"wasm"`
+
+	// OPA data source fields
+	OPA *OPADataSourceConfig `koanf:"opa"`
+
+	// Redis data source fields
+	Redis *RedisDataSourceConfig `koanf:"redis"`
+
 	// Lua data source fields
 	ScriptFile string         `koanf:"script_file"` // Path to Lua script
 	Script     string         `koanf:"script"`      // Inline Lua script (alternative to ScriptFile)
@@ -143,20 +608,96 @@ type DataSourceConfig struct {
 	// HTTP configuration
 	HTTPConfig *HTTPConfig `koanf:"http"`
 
+	// JSON decoding limits
+	JSON *JSONLimitsConfig `koanf:"json"`
+
 	// Caching configuration
 	Caching *CachingConfig `koanf:"caching"`
 }
 
+// WASMConfig configures a WebAssembly plugin
+type WASMConfig struct {
+	// Path is the .wasm module to load
+	Path string `koanf:"path"`
+
+	// Config is passed to the plugin with every request
+	Config map[string]any `koanf:"config"`
+
+	// MemoryLimitPages caps the plugin's memory, in 64 KiB pages (default: 256, or 16 MiB)
+	MemoryLimitPages uint32 `koanf:"memory_limit_pages"`
+
+	// Timeout bounds each call to the plugin, like "5s" (default: 5s)
+	Timeout string `koanf:"timeout"`
+}
+
+// OPADataSourceConfig configures a data source that queries an Open Policy Agent server
+type OPADataSourceConfig struct {
+	// URL is the OPA server's base URL (e.g., "http://localhost:8181")
+	URL string `koanf:"url"`
+
+	// Path is the path of the document to query, like "parsec/authz"
+	Path string `koanf:"path"`
+
+	// Token, if set, is sent as a bearer token
+	Token string `koanf:"token"`
+
+	// Timeout bounds each query (default: 5s)
+	Timeout string `koanf:"timeout"`
+}
+
+// RedisDataSourceConfig configures a data source that looks up JSON values in Redis
+type RedisDataSourceConfig struct {
+	Address      string `koanf:"address"`        // Redis address (host:port)
+	Username     string `koanf:"username"`       // Redis username
+	Password     string `koanf:"password"`       // Redis password
+	RedisDB      int    `koanf:"redis_db"`       // Redis database number
+	PoolSize     int    `koanf:"pool_size"`      // Connections per CPU (default: 10)
+	MinIdleConns int    `koanf:"min_idle_conns"` // Idle connections kept open (default: 0)
+	Timeout      string `koanf:"timeout"`        // Dial, read, and write timeout (default: go-redis defaults)
+
+	// Key is the template of the key whose value is the result, e.g. "entitlements:{sub}"
+	// Placeholders: {sub}, {iss}, {trust_domain}, {actor_sub}, {actor_iss}
+	Key string `koanf:"key"`
+
+	// Keys are templates of several keys, fetched in one pipeline, by the name of
+	// their value in the resulting object (alternative to Key)
+	Keys map[string]string `koanf:"keys"`
+}
+
 // HTTPConfig configures HTTP client for Lua data sources
 type HTTPConfig struct {
 	// Timeout for HTTP requests (default: 30s)
 	Timeout string `koanf:"timeout"` // Duration string like "30s"
+
+	// MaxResponseBytes caps response bodies read by the script (default: 10 MiB, -1 for unlimited)
+	MaxResponseBytes int64 `koanf:"max_response_bytes"`
+
+	// Truncation selects what happens when a body exceeds MaxResponseBytes
+	// Options: "error" (default, the request fails), "truncate" (body is cut short)
+	Truncation string `koanf:"truncation"`
+}
+
+// JSONLimitsConfig bounds JSON parsed by a Lua data source, both by the script
+// and of the data it returns. Zero values mean unlimited.
+type JSONLimitsConfig struct {
+	// MaxBytes caps the size of a JSON document
+	MaxBytes int64 `koanf:"max_bytes"`
+
+	// MaxDepth caps how deeply objects and arrays may nest
+	MaxDepth int `koanf:"max_depth"`
+
+	// MaxArrayItems caps the number of elements in any one array
+	MaxArrayItems int `koanf:"max_array_items"`
+
+	// Truncation selects what happens when an array exceeds MaxArrayItems
+	// Options: "error" (default), "truncate" (excess elements are dropped)
+	Truncation string `koanf:"truncation"`
 }
 
 // CachingConfig configures caching for a data source
 type CachingConfig struct {
 	// Type selects the caching implementation
-	// Options: "in_memory", "distributed", "none"
+	// Options: "in_memory", "distributed", "redis", "none"
 	Type string `koanf:"type"`
 
 	// TTL is the cache time-to-live
@@ -165,23 +706,105 @@ type CachingConfig struct {
 	// Distributed caching fields
 	GroupName string `koanf:"group_name"` // For groupcache
 	CacheSize int64  `koanf:"cache_size"` // Cache size in bytes
+
+	// Redis caching fields
+	Address      string `koanf:"address"`        // Redis address (host:port)
+	KeyPrefix    string `koanf:"key_prefix"`     // Key prefix (default: parsec:datasource:)
+	Username     string `koanf:"username"`       // Redis username
+	Password     string `koanf:"password"`       // Redis password
+	RedisDB      int    `koanf:"redis_db"`       // Redis database number
+	PoolSize     int    `koanf:"pool_size"`      // Connections per CPU (default: 10)
+	MinIdleConns int    `koanf:"min_idle_conns"` // Idle connections kept open (default: 0)
+	Timeout      string `koanf:"timeout"`        // Dial, read, and write timeout (default: go-redis defaults)
+}
+
+// CachePeersConfig configures the peers that distributed data source caches are shared with
+type CachePeersConfig struct {
+	// Port is where this replica serves its peers (default: 8090)
+	Port int `koanf:"port" usage:"port this replica serves its cache peers on (default: 8090)"`
+
+	// SelfURL is this replica's URL as its peers reach it
+	// Default: http://$POD_IP:<port>, or the first non-loopback address if POD_IP is not set
+	SelfURL string `koanf:"self_url" usage:"URL peers reach this replica at (default: http://$POD_IP:<port>)"`
+
+	// Peers are the URLs of the other replicas, if they are fixed
+	Peers []string `koanf:"peers"`
+
+	// Kubernetes discovers peers from the endpoints of a Service (alternative to Peers)
+	Kubernetes *KubernetesPeersConfig `koanf:"kubernetes"`
+}
+
+// KubernetesPeersConfig configures peer discovery from a Kubernetes Service
+type KubernetesPeersConfig struct {
+	// Service is the (usually headless) Service selecting the parsec pods
+	Service string `koanf:"service" usage:"Kubernetes Service selecting the parsec pods"`
+
+	// Namespace is the Service's namespace (default: the pod's namespace)
+	Namespace string `koanf:"namespace" usage:"namespace of the Service (default: the pod namespace)"`
+
+	// RefreshInterval is how often endpoints are listed (default: 10s)
+	RefreshInterval string `koanf:"refresh_interval" usage:"how often Service endpoints are listed (default: 10s)"`
 }
 
 // ClaimMapperConfig configures a claim mapper
 type ClaimMapperConfig struct {
 	// Type selects the mapper implementation
-	// Options: "cel", "passthrough", "request_attributes", "stub"
+	// Options: "cel", "jmespath", "template", "passthrough", "request_attributes", "hashed_request_attributes", "wasm", "stub"
 	Type string `koanf:"type"`
 
 	// Optional name for the mapper
 	Name string `koanf:"name"`
 
+	// When is a CEL condition; the mapper only runs when it is true (default: always)
+	When string `koanf:"when"`
+
+	// Merge is how the mapper's claims are merged into those of the mappers before it
+	// Options: "overwrite" (default), "deep", "error"
+	Merge string `koanf:"merge"`
+
 	// CEL mapper fields
 	ScriptFile string `koanf:"script_file"` // Path to CEL script file
 	Script     string `koanf:"script"`      // Inline CEL script (alternative to ScriptFile)
 
-	// Stub mapper fields
-	Claims map[string]any `koanf:"claims"`
+	// JMESPath mapper fields
+	ExpressionFile string   `koanf:"expression_file"` // Path to JMESPath expression file
+	Expression     string   `koanf:"expression"`      // Inline JMESPath expression (alternative to ExpressionFile)
+	DataSources    []string `koanf:"data_sources"`    // Data sources fetched for the mapper
+
+	// Stub and template mapper fields
+	Claims map[string]any `koanf:"claims"` // Fixed claims, or for template mappers, claims whose strings are templates
+
+	// WASM mapper fields
+	// (DataSources is shared)
+	WASM *WASMConfig `koanf:"wasm"`
+
+	// Request attributes mapper fields
+	Headers []string `koanf:"headers"` // Request headers to include, with every value (default: none)
+
+	// Hashed request attributes mapper fields
+	Attributes     []string `koanf:"attributes"`      // Attributes to hash (default: ip_address, user_agent)
+	Secret         string   `koanf:"secret"`          // Secret keying the hashes (at least 16 bytes)
+	SecretFile     string   `koanf:"secret_file"`     // Path to a file containing the secret (alternative to Secret)
+	RotationPeriod string   `koanf:"rotation_period"` // How often the salt rotates, like "24h" (default: never)
+	Claim          string   `koanf:"claim"`           // Claim holding the hashes (default: "hashes")
+}
+
+// TrustDomainConfig configures an additional trust domain
+type TrustDomainConfig struct {
+	// Name is the trust domain, which is the default audience of its tokens
+	Name string `koanf:"name"`
+
+	// Audiences are the trust domain's other audiences, like "*.partners.example.com"
+	// Tokens requested for them are issued by the trust domain.
+	Audiences []string `koanf:"audiences"`
+
+	// Issuers issue the trust domain's tokens, with its own issuer_url
+	// They use the global signers, whose keys are namespaced by trust domain.
+	Issuers []IssuerConfig `koanf:"issuers"`
+
+	// Validators, if set, are the only validators (by name) that may validate
+	// credentials for the trust domain's tokens
+	Validators []string `koanf:"validators"`
 }
 
 // IssuerConfig configures a token issuer
@@ -193,8 +816,14 @@ type IssuerConfig struct {
 	//   - "urn:ietf:params:oauth:token-type:jwt" (JWT)
 	TokenType string `koanf:"token_type"`
 
+	// Audiences, if set, limit the issuer to tokens whose every audience (or trust
+	// domain, by default) is listed, so one token type can have other keys and TTLs
+	// for some audiences. Each token type may have one issuer without audiences,
+	// used for all others.
+	Audiences []string `koanf:"audiences"`
+
 	// Type selects the issuer implementation
-	// Options: "stub", "unsigned", "transaction_token", "rh_identity"
+	// Options: "stub", "unsigned", "transaction_token", "rh_identity", "opaque", "biscuit"
 	Type string `koanf:"type"`
 
 	// Common fields
@@ -202,7 +831,7 @@ type IssuerConfig struct {
 	TTL       string `koanf:"ttl"` // Duration string like "5m"
 
 	// SignerID references a named signer from the global signers config
-	// Used for transaction tokens to configure the signer
+	// Used for transaction tokens and biscuits to configure the signer
 	SignerID string `koanf:"signer_id"`
 
 	// Transaction token issuer fields (stub, transaction_token types)
@@ -210,12 +839,218 @@ type IssuerConfig struct {
 	TransactionContextMappers []ClaimMapperConfig `koanf:"transaction_context"`
 	RequestContextMappers     []ClaimMapperConfig `koanf:"request_context"`
 
-	// Simple issuer fields (unsigned, rh_identity types)
+	// Simple issuer fields (unsigned, rh_identity, opaque, biscuit types)
 	// These mappers build the token's claim structure
 	ClaimMappers []ClaimMapperConfig `koanf:"claim_mappers"`
 
 	// Stub issuer fields (deprecated - use mappers instead)
 	IncludeRequestContext bool `koanf:"include_request_context"`
+
+	// InstanceClaim adds a "parsec_instance" claim with the issuing instance's ID and version
+	// (transaction_token type only)
+	InstanceClaim bool `koanf:"instance_claim"`
+
+	// Format is the encoding of issued tokens (transaction_token type only)
+	// Options: "jwt" (default), "cwt"
+	Format string `koanf:"format"`
+
+	// TxnID configures how the txn claim is generated or propagated (transaction_token type only)
+	TxnID *TxnIDConfig `koanf:"txn_id"`
+
+	// JTI configures how token IDs (jti) are generated
+	// (transaction_token, opaque, and biscuit types only)
+	JTI *JTIConfig `koanf:"jti"`
+
+	// NotBeforeSkew backdates the nbf claim, such as "30s", so consumers whose clocks
+	// run behind accept new tokens (transaction_token and opaque types only)
+	NotBeforeSkew string `koanf:"not_before_skew"`
+
+	// Encryption wraps JWTs for some audiences in a JWE encrypted to their public keys
+	// (transaction_token type with jwt format only)
+	Encryption *TokenEncryptionConfig `koanf:"encryption"`
+
+	// SizeBudget bounds the size of tokens and their context claims (transaction_token type only)
+	SizeBudget *SizeBudgetConfig `koanf:"size_budget"`
+
+	// Redaction strips or hashes sensitive mapped claims before issuance, with the
+	// claims removed recorded in the audit log (not stub type)
+	Redaction *RedactionConfig `koanf:"redaction"`
+
+	// Profiles shape the claims of tokens for some audiences with their own mappers
+	// (not stub type)
+	Profiles []AudienceProfileConfig `koanf:"profiles"`
+
+	// TTLPolicy computes the lifetime of each token, bounded by its min_ttl and max_ttl
+	// (transaction_token, opaque, and biscuit types)
+	TTLPolicy *TTLPolicyConfig `koanf:"ttl_policy"`
+
+	// ClaimsByReference keeps context claims out of tokens, in the token store, to be
+	// retrieved from the claims endpoint (transaction_token type only)
+	ClaimsByReference *ClaimsByReferenceConfig `koanf:"claims_by_reference"`
+}
+
+// AudienceProfileConfig shapes the claims of tokens for some audiences
+// A token uses the first profile listing all of its audiences, whose mappers replace
+// the issuer's.
+type AudienceProfileConfig struct {
+	Audiences []string `koanf:"audiences"`
+
+	// Mappers, as for the issuer
+	TransactionContextMappers []ClaimMapperConfig `koanf:"transaction_context"`
+	RequestContextMappers     []ClaimMapperConfig `koanf:"request_context"`
+	ClaimMappers              []ClaimMapperConfig `koanf:"claim_mappers"`
+
+	// Redaction replaces the issuer's redaction (default: the issuer's)
+	Redaction *RedactionConfig `koanf:"redaction"`
+}
+
+// TTLPolicyConfig computes the lifetime of each token of an issuer
+type TTLPolicyConfig struct {
+	// Expression is CEL evaluating to a duration, like duration("2m"), or null for
+	// the issuer's ttl. It sees the variables of CEL claim mappers, plus scopes and audiences.
+	Expression string `koanf:"expression"`
+
+	MinTTL string `koanf:"min_ttl"` // Shortest lifetime, like "30s" (default: no bound)
+	MaxTTL string `koanf:"max_ttl"` // Longest lifetime, like "1h" (required)
+}
+
+// RedactionConfig strips or hashes sensitive mapped claims
+// Claim paths are dotted, like "tctx.email" for transaction tokens or "email" for
+// other issuers, and "*" matches any one key.
+type RedactionConfig struct {
+	// Allow, if set, lists the only claim paths tokens may carry, and everything under them
+	Allow []string `koanf:"allow"`
+
+	// Rules select sensitive claims to remove or hash
+	Rules []RedactionRuleConfig `koanf:"rules"`
+
+	// HashKey keys hashes with HMAC-SHA256, so they cannot be reversed by hashing guesses
+	HashKey string `koanf:"hash_key"`
+}
+
+// RedactionRuleConfig selects sensitive claims by path, value, or CEL condition
+type RedactionRuleConfig struct {
+	Claims  []string `koanf:"claims"`  // Claim paths, like "tctx.email"
+	Pattern string   `koanf:"pattern"` // Regular expression matching string values anywhere
+	When    string   `koanf:"when"`    // CEL condition on path and value
+
+	// Action is what is done with matching claims
+	// Options: "remove" (default), "hash"
+	Action string `koanf:"action"`
+}
+
+// ClaimsByReferenceConfig keeps context claims out of transaction tokens
+// Tokens reference them with a "claims_ref" claim holding their URI and hash.
+type ClaimsByReferenceConfig struct {
+	// Claims are always moved out of tokens, like "tctx.entitlements"
+	Claims []string `koanf:"claims"`
+
+	// BaseURL is the URL consumers reach parsec's HTTP server at (default: issuer_url)
+	BaseURL string `koanf:"base_url"`
+}
+
+// SizeBudgetConfig bounds the size of transaction tokens
+type SizeBudgetConfig struct {
+	MaxTokenBytes int `koanf:"max_token_bytes"` // Longest encoded token (default: no limit)
+	MaxClaimBytes int `koanf:"max_claim_bytes"` // Largest tctx or req_ctx claim, as JSON (default: no limit)
+
+	// LowPriorityClaims may be shed to fit the budget, in order, like "tctx.groups"
+	LowPriorityClaims []string `koanf:"low_priority_claims"`
+
+	// OnExceeded is what is done with low-priority claims when the budget is exceeded
+	// Options: "fail" (default), "truncate", "reference" (moved to the token store)
+	OnExceeded string `koanf:"on_exceeded"`
+}
+
+// TxnIDConfig configures the txn claim of transaction tokens
+type TxnIDConfig struct {
+	// Generator generates txn IDs that are not propagated
+	// Options: "uuid" (default, random UUIDs), "uuidv7" (time-ordered UUIDs), "ulid"
+	Generator string `koanf:"generator"`
+
+	// PropagateFrom is a request header to take the txn ID from, such as "x-request-id"
+	// For "traceparent", the W3C trace ID is used. Requests without it get a generated ID.
+	PropagateFrom string `koanf:"propagate_from"`
+}
+
+// JTIConfig configures the token IDs (jti) of issued tokens
+type JTIConfig struct {
+	// Generator generates token IDs
+	// Options: "uuid" (default, random UUIDs), "uuidv7" (time-ordered UUIDs), "ulid"
+	Generator string `koanf:"generator"`
+
+	// InstancePrefix prefixes token IDs with the issuing instance's ID, so IDs minted
+	// by different instances cannot collide
+	InstancePrefix bool `koanf:"instance_prefix"`
+}
+
+// TokenEncryptionConfig configures JWE encryption of issued tokens
+type TokenEncryptionConfig struct {
+	// KeyAlgorithm encrypts the content encryption key
+	// Options: "RSA-OAEP-256" (default), "RSA-OAEP", "RSA-OAEP-384", "RSA-OAEP-512",
+	// "ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A192KW", "ECDH-ES+A256KW"
+	KeyAlgorithm string `koanf:"key_algorithm"`
+
+	// ContentEncryption encrypts the token
+	// Options: "A256GCM" (default), "A128GCM", "A192GCM", "A128CBC-HS256", "A192CBC-HS384", "A256CBC-HS512"
+	ContentEncryption string `koanf:"content_encryption"`
+
+	// Recipients are the audiences whose tokens are encrypted
+	Recipients []EncryptionRecipientConfig `koanf:"recipients"`
+}
+
+// EncryptionRecipientConfig configures the public keys of an audience tokens are encrypted to
+type EncryptionRecipientConfig struct {
+	Audience string `koanf:"audience"`
+
+	// The recipient's public keys, as a JWKS URL or inline JWKS
+	JWKSURL string `koanf:"jwks_url"`
+	JWKS    string `koanf:"jwks"`
+}
+
+// TokenPolicyConfig sets limits on issued tokens that apply regardless of issuer configuration
+type TokenPolicyConfig struct {
+	// MaxTTLs caps the lifetime of tokens by token type
+	// Issuers configured with a longer TTL fail at startup, and tokens that
+	// would live longer are never issued
+	MaxTTLs []MaxTTLConfig `koanf:"max_ttls"`
+
+	// DecisionDataSource names a data source, such as an OPA policy, that must allow
+	// every issuance: its result must be true or an object with "allow": true
+	DecisionDataSource string `koanf:"decision_data_source" usage:"data source, such as an OPA policy, that must allow every issuance"`
+
+	// VerifiedPermissions asks an Amazon Verified Permissions policy store to allow
+	// every issuance (disabled if not set)
+	VerifiedPermissions *VerifiedPermissionsConfig `koanf:"verified_permissions"`
+
+	// ReplayDetection records the ID of every issued token until it expires, and
+	// issues a token again, with a new ID, if its ID was already issued
+	ReplayDetection bool `koanf:"replay_detection" usage:"record issued token IDs until expiry and reissue tokens with repeated IDs"`
+}
+
+// VerifiedPermissionsConfig configures authorization by Amazon Verified Permissions
+type VerifiedPermissionsConfig struct {
+	PolicyStoreID string `koanf:"policy_store_id" usage:"Amazon Verified Permissions policy store ID"`
+	Region        string `koanf:"region" usage:"AWS region of the policy store"`
+	Endpoint      string `koanf:"endpoint" usage:"Verified Permissions endpoint override, such as a VPC endpoint"` // Optional override, such as a VPC endpoint
+
+	// Cedar entity types and action of requests
+	PrincipalType string `koanf:"principal_type" usage:"Cedar entity type of principals (default: Parsec::Subject)"` // default: Parsec::Subject
+	ActionType    string `koanf:"action_type" usage:"Cedar entity type of actions (default: Parsec::Action)"`        // default: Parsec::Action
+	ActionID      string `koanf:"action_id" usage:"Cedar action ID of issuance (default: IssueToken)"`               // default: IssueToken
+	ResourceType  string `koanf:"resource_type" usage:"Cedar entity type of resources (default: Parsec::Resource)"`  // default: Parsec::Resource
+
+	// Timeout bounds each request (default: 5s)
+	Timeout string `koanf:"timeout" usage:"timeout of each authorization request (default: 5s)"`
+}
+
+// MaxTTLConfig caps the lifetime of one token type
+type MaxTTLConfig struct {
+	// TokenType is the OAuth token type URN
+	TokenType string `koanf:"token_type"`
+
+	// MaxTTL is the longest allowed token lifetime, like "15m"
+	MaxTTL string `koanf:"max_ttl"`
 }
 
 // KeyProviderConfig configures a key provider
@@ -224,7 +1059,7 @@ type KeyProviderConfig struct {
 	ID string `koanf:"id"`
 
 	// Type selects the key provider implementation
-	// Options: "memory", "aws_kms", "disk"
+	// Options: "memory", "aws_kms", "disk", "vault_transit"
 	Type string `koanf:"type"`
 
 	// KeyType is the cryptographic key type this provider creates
@@ -242,6 +1077,118 @@ type KeyProviderConfig struct {
 
 	// Disk key provider fields
 	KeysPath string `koanf:"keys_path"` // Path to directory for storing keys
+
+	// Vault Transit fields
+	Address        string           `koanf:"address"`         // Vault address (default: $VAULT_ADDR)
+	MountPath      string           `koanf:"mount_path"`      // Transit mount path (default: "transit")
+	KeyPrefix      string           `koanf:"key_prefix"`      // Transit key name prefix (default: "parsec-")
+	VaultNamespace string           `koanf:"vault_namespace"` // Vault Enterprise namespace
+	Auth           *VaultAuthConfig `koanf:"auth"`            // Vault auth method (default: token from $VAULT_TOKEN)
+}
+
+// VaultAuthConfig configures how a vault_transit key provider authenticates to Vault
+type VaultAuthConfig struct {
+	// Method selects the auth method
+	// Options: "token", "kubernetes", "approle"
+	Method string `koanf:"method" usage:"Vault auth method: token, kubernetes, approle (default: token)"`
+
+	// MountPath is where the auth method is mounted (defaults to the method name)
+	MountPath string `koanf:"mount_path" usage:"Vault auth method mount path (default: the method name)"`
+
+	// Token auth fields
+	Token     string `koanf:"token" usage:"Vault token (default: $VAULT_TOKEN)"`  // Vault token (default: $VAULT_TOKEN)
+	TokenFile string `koanf:"token_file" usage:"file containing the Vault token"` // File containing the Vault token
+
+	// Kubernetes auth fields
+	Role    string `koanf:"role" usage:"Vault role, for kubernetes auth"`                     // Vault role
+	JWTPath string `koanf:"jwt_path" usage:"service account token file, for kubernetes auth"` // Service account token file
+
+	// AppRole auth fields
+	RoleID       string `koanf:"role_id" usage:"AppRole role ID"`
+	SecretID     string `koanf:"secret_id" usage:"AppRole secret ID"`
+	SecretIDFile string `koanf:"secret_id_file" usage:"file containing the AppRole secret ID"`
+}
+
+// KeySlotStoreConfig configures the key slot store shared by signers
+type KeySlotStoreConfig struct {
+	// Type selects the store implementation
+	// Options: "memory" (default), "kubernetes", "sql", "redis", "etcd"
+	Type string `koanf:"type" usage:"key slot store type: memory, kubernetes, sql, redis, etcd"`
+
+	// Kubernetes store fields
+	Kind      string `koanf:"kind" usage:"kubernetes object kind for key slots: Secret, ConfigMap"`
+	Name      string `koanf:"name" usage:"kubernetes object name for key slots"`
+	Namespace string `koanf:"namespace" usage:"kubernetes namespace for key slots (default: pod namespace)"`
+
+	// SQL store fields
+	Driver       string `koanf:"driver" usage:"sql database for key slots: postgres, mysql"`
+	DSN          string `koanf:"dsn" usage:"sql connection string for key slots"`
+	Table        string `koanf:"table" usage:"sql table for key slots (default: parsec_key_slots)"`
+	CreateTables bool   `koanf:"create_tables" usage:"create the sql key slot tables if missing"`
+
+	// Redis and etcd store fields
+	Address   string   `koanf:"address" usage:"redis address for key slots (host:port)"`
+	Endpoints []string `koanf:"endpoints"` // etcd client URLs
+	Key       string   `koanf:"key" usage:"redis or etcd key for key slots"`
+	Username  string   `koanf:"username" usage:"redis or etcd username for key slots"`
+	Password  string   `koanf:"password" usage:"redis or etcd password for key slots"`
+	RedisDB   int      `koanf:"redis_db" usage:"redis database number for key slots"`
+}
+
+// TokenStoreConfig configures the store of opaque token claims
+type TokenStoreConfig struct {
+	// Type selects the store implementation
+	// Options: "memory" (default), "redis"
+	Type string `koanf:"type" usage:"token store type: memory, redis"`
+
+	// Redis store fields
+	Address   string `koanf:"address" usage:"redis address for opaque tokens (host:port)"`
+	KeyPrefix string `koanf:"key_prefix" usage:"redis key prefix for opaque tokens (default: parsec:tokens:)"`
+	Username  string `koanf:"username" usage:"redis username for opaque tokens"`
+	Password  string `koanf:"password" usage:"redis password for opaque tokens"`
+	RedisDB   int    `koanf:"redis_db" usage:"redis database number for opaque tokens"`
+}
+
+// DenylistConfig configures the denylist of revoked tokens
+type DenylistConfig struct {
+	// Type selects the denylist implementation
+	// Options: "memory" (default), "redis"
+	Type string `koanf:"type" usage:"denylist type: memory, redis"`
+
+	// Redis denylist fields
+	Address   string `koanf:"address" usage:"redis address for the denylist (host:port)"`
+	KeyPrefix string `koanf:"key_prefix" usage:"redis key prefix for the denylist (default: parsec:denylist:)"`
+	Username  string `koanf:"username" usage:"redis username for the denylist"`
+	Password  string `koanf:"password" usage:"redis password for the denylist"`
+	RedisDB   int    `koanf:"redis_db" usage:"redis database number for the denylist"`
+}
+
+// AuditConfig configures the audit log
+type AuditConfig struct {
+	// Sinks are where records are written; every record goes to every sink
+	Sinks []AuditSinkConfig `koanf:"sinks"`
+
+	// Chain links each record to the previous one with its hash
+	Chain bool `koanf:"chain" usage:"link each audit record to the previous one with its hash"`
+
+	// SignerID, if set, references a named signer from the global signers config that
+	// signs every record
+	SignerID string `koanf:"signer_id" usage:"signer that signs audit records"`
+}
+
+// AuditSinkConfig configures an audit log sink
+type AuditSinkConfig struct {
+	// Type selects the sink
+	// Options: "stdout", "file", "kafka"
+	Type string `koanf:"type"`
+
+	// File sink fields
+	Path string `koanf:"path"` // File records are appended to
+
+	// Kafka sink fields
+	URL     string `koanf:"url"`     // Base URL of a Kafka REST proxy v2 (e.g., Strimzi Kafka Bridge)
+	Topic   string `koanf:"topic"`   // Topic records are produced to
+	Timeout string `koanf:"timeout"` // Duration string like "5s" (default: 5s)
 }
 
 // SignerConfig configures a signer
@@ -250,7 +1197,7 @@ type SignerConfig struct {
 	ID string `koanf:"id"`
 
 	// Type selects the signer implementation
-	// Options: "dual_slot"
+	// Options: "dual_slot", "external", "algorithm_migration"
 	Type string `koanf:"type"`
 
 	// Namespace is an optional logical namespace for keys (defaults to ID if not set)
@@ -265,6 +1212,65 @@ type SignerConfig struct {
 	GracePeriod       string `koanf:"grace_period"`       // Duration string like "2h"
 	CheckInterval     string `koanf:"check_interval"`     // Duration string like "1m"
 	PrepareTimeout    string `koanf:"prepare_timeout"`    // Duration string like "1m"
+
+	// Concurrency, if set, bounds the signatures in progress at once (dual_slot and
+	// external signers)
+	Concurrency *SigningConcurrencyConfig `koanf:"concurrency"`
+
+	// Source is where an external signer loads its keys from (external signer only).
+	// The key is reloaded every check_interval.
+	Source *ExternalKeySourceConfig `koanf:"source"`
+
+	// Algorithm migration parameters (algorithm_migration signer only).
+	// The signer signs with from_signer_id until cutover_at, then with to_signer_id,
+	// publishing the keys of both until retire_after has passed since cutover.
+	FromSignerID string `koanf:"from_signer_id"` // Signer being migrated away from
+	ToSignerID   string `koanf:"to_signer_id"`   // Signer being migrated to
+	CutoverAt    string `koanf:"cutover_at"`     // RFC 3339 time like "2025-06-01T00:00:00Z"
+	RetireAfter  string `koanf:"retire_after"`   // Duration string like "24h" (default: 24h)
+}
+
+// SigningConcurrencyConfig bounds a signer's concurrent signatures
+type SigningConcurrencyConfig struct {
+	// MaxConcurrent is how many signatures may be in progress at once
+	MaxConcurrent int `koanf:"max_concurrent"`
+
+	// MaxQueued is how many signatures may wait for a free slot before signing fails
+	// fast (default: 0, fail as soon as every slot is busy)
+	MaxQueued int `koanf:"max_queued"`
+
+	// QueueTimeout is how long a signature waits for a free slot (default: as long as
+	// the request allows)
+	QueueTimeout string `koanf:"queue_timeout"`
+}
+
+// ExternalKeySourceConfig configures where externally managed signing keys are read from
+type ExternalKeySourceConfig struct {
+	// Type selects the source
+	// Options: "kubernetes_secret", "vault_kv"
+	Type string `koanf:"type"`
+
+	// KeyField is the secret field holding the PEM signing key (default: "key.pem")
+	KeyField string `koanf:"key_field"`
+
+	// PreviousKeyField is the secret field holding the previous PEM key,
+	// published for verification only (default: "previous.pem")
+	PreviousKeyField string `koanf:"previous_key_field"`
+
+	// Algorithm is the signing algorithm (default based on the key type)
+	Algorithm string `koanf:"algorithm"`
+
+	// Kubernetes Secret configuration
+	Name      string `koanf:"name"`      // Secret name
+	Namespace string `koanf:"namespace"` // Defaults to the pod's namespace
+
+	// Vault KV configuration
+	Address        string           `koanf:"address"`         // Defaults to VAULT_ADDR
+	MountPath      string           `koanf:"mount_path"`      // Defaults to "secret"
+	Path           string           `koanf:"path"`            // Secret path within the mount
+	KVVersion      int              `koanf:"kv_version"`      // 1 or 2 (default)
+	VaultNamespace string           `koanf:"vault_namespace"` // Vault Enterprise namespace
+	Auth           *VaultAuthConfig `koanf:"auth"`
 }
 
 // ClaimsFilterConfig configures the claims filter registry
@@ -283,6 +1289,45 @@ type ClaimsFilterConfig struct {
 	ActorRules map[string][]string `koanf:"actor_rules"` // Map of actor pattern to allowed claims
 }
 
+// JWKSConfig configures the JWKS endpoint
+type JWKSConfig struct {
+	// RefreshInterval is how often the served key set is rebuilt from issuers
+	// Changes are published to Publishers at most this long after they happen
+	RefreshInterval string `koanf:"refresh_interval" usage:"JWKS refresh interval (e.g. 1m)"`
+
+	// Publication selects which key sets are served
+	// Options: "aggregated" (default, one set at /.well-known/jwks.json),
+	// "per_issuer" (one set per token type at /v1/issuers/{token_type}/jwks.json), "both"
+	Publication string `koanf:"publication" usage:"key sets to serve: aggregated, per_issuer, both (default: aggregated)"`
+
+	// Publishers push the JWKS document to external locations whenever it changes
+	// They publish the aggregated key set, so they require aggregated publication
+	Publishers []JWKSPublisherConfig `koanf:"publishers"`
+}
+
+// JWKSPublisherConfig configures an external JWKS publisher
+type JWKSPublisherConfig struct {
+	// Type selects the publisher implementation
+	// Options: "s3", "gcs", "webhook"
+	Type string `koanf:"type"`
+
+	// CacheControl is the Cache-Control metadata for the published document
+	// Keep max-age well below the signer grace period (default: "public, max-age=300")
+	CacheControl string `koanf:"cache_control"`
+
+	// S3 and GCS fields
+	Bucket       string `koanf:"bucket"`
+	Key          string `koanf:"key"`            // Object key (e.g., ".well-known/jwks.json")
+	Region       string `koanf:"region"`         // Bucket region (GCS defaults to "auto")
+	Endpoint     string `koanf:"endpoint"`       // Optional S3-compatible endpoint override
+	UsePathStyle bool   `koanf:"use_path_style"` // Address bucket in path instead of host
+
+	// Webhook fields
+	URL     string            `koanf:"url"`
+	Method  string            `koanf:"method"` // Default: PUT
+	Headers map[string]string `koanf:"headers"`
+}
+
 // FixtureConfig configures a fixture for hermetic testing
 type FixtureConfig struct {
 	// Type selects the fixture type
@@ -351,6 +1396,80 @@ type ObservabilityConfig struct {
 
 	// Composite observer fields - allows multiple observers
 	Observers []ObservabilityConfig `koanf:"observers"`
+
+	// Tracing, if set, records OpenTelemetry spans for checks and exchanges and exports
+	// them with OTLP, alongside the configured observer
+	Tracing *TracingConfig `koanf:"tracing"`
+
+	// Events, if set, publishes token issuance and denial events to Kafka or a
+	// webhook, alongside the configured observer
+	Events *EventsConfig `koanf:"events"`
+}
+
+// TracingConfig configures OpenTelemetry tracing
+type TracingConfig struct {
+	// Endpoint is the host:port of the OTLP/gRPC collector
+	// Default: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT, else localhost:4317
+	Endpoint string `koanf:"endpoint" usage:"OTLP/gRPC trace collector address (host:port)"`
+
+	// Insecure connects to the collector without TLS
+	Insecure bool `koanf:"insecure" usage:"connect to the trace collector without TLS"`
+
+	// Headers are sent with every export (e.g., collector credentials)
+	Headers map[string]string `koanf:"headers"`
+
+	// ServiceName is the service.name of exported spans
+	// Default: "parsec"
+	ServiceName string `koanf:"service_name" usage:"service name of exported spans (default: parsec)"`
+
+	// SampleRatio is the fraction of traces started by parsec that are sampled
+	// Traces propagated to parsec follow the caller's sampling decision.
+	// Default: 1
+	SampleRatio *float64 `koanf:"sample_ratio" usage:"fraction of new traces to sample, 0 to 1 (default: 1)"`
+}
+
+// EventsConfig configures publishing issuance and denial events
+type EventsConfig struct {
+	// Type selects the sink events are published to
+	// Options: "kafka" (through a Kafka REST proxy), "webhook"
+	Type string `koanf:"type" usage:"event sink type: kafka, webhook"`
+
+	// URL is the webhook endpoint, or the base URL of the Kafka REST proxy
+	URL string `koanf:"url" usage:"webhook URL or Kafka REST proxy base URL"`
+
+	// Topic is the Kafka topic events are produced to
+	Topic string `koanf:"topic" usage:"Kafka topic for events"`
+
+	// Headers are sent with every webhook request (e.g., an authorization header)
+	Headers map[string]string `koanf:"headers"`
+
+	// Timeout bounds each request to the sink (e.g., "10s")
+	// Default: "10s"
+	Timeout string `koanf:"timeout" usage:"timeout of each request to the event sink (default: 10s)"`
+
+	// BatchSize is the most events sent in one request
+	// Default: 100
+	BatchSize int `koanf:"batch_size" usage:"most events sent in one request (default: 100)"`
+
+	// FlushInterval is the longest an event waits for its batch to fill (e.g., "1s")
+	// Default: "1s"
+	FlushInterval string `koanf:"flush_interval" usage:"longest an event waits to be sent (default: 1s)"`
+
+	// QueueSize is the most events waiting to be sent
+	// Default: 10000
+	QueueSize int `koanf:"queue_size" usage:"most events waiting to be sent (default: 10000)"`
+
+	// MaxRetries is how many times a failed batch is retried before it is dropped
+	// Default: 5; negative disables retries
+	MaxRetries int `koanf:"max_retries" usage:"retries of a failed batch before it is dropped (default: 5)"`
+
+	// RetryBackoff is the wait before the first retry, doubling with each attempt
+	// Default: "500ms"
+	RetryBackoff string `koanf:"retry_backoff" usage:"wait before the first retry of a failed batch (default: 500ms)"`
+
+	// Overflow decides what happens to events when the queue is full
+	// Options: "drop" (default), "block" (requests wait for room in the queue)
+	Overflow string `koanf:"overflow" usage:"when the event queue is full: drop, block (default: drop)"`
 }
 
 // EventLoggingConfig configures logging for a specific event type
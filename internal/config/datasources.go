@@ -6,13 +6,18 @@ import (
 	"os"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+
 	"github.com/alechenninger/parsec/internal/datasource"
+	"github.com/alechenninger/parsec/internal/limits"
 	luaservices "github.com/alechenninger/parsec/internal/lua"
+	"github.com/alechenninger/parsec/internal/probe"
 	"github.com/alechenninger/parsec/internal/service"
 )
 
-// NewDataSourceRegistry creates a data source registry from configuration
-func NewDataSourceRegistry(cfg []DataSourceConfig, transport http.RoundTripper) (*service.DataSourceRegistry, error) {
+// NewDataSourceRegistry creates a data source registry from configuration, whose data
+// sources report each fetch to observer
+func NewDataSourceRegistry(cfg []DataSourceConfig, transport http.RoundTripper, observer service.DataSourceObserver) (*service.DataSourceRegistry, error) {
 	registry := service.NewDataSourceRegistry()
 
 	for _, dsCfg := range cfg {
@@ -20,7 +25,7 @@ func NewDataSourceRegistry(cfg []DataSourceConfig, transport http.RoundTripper)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create data source %s: %w", dsCfg.Name, err)
 		}
-		registry.Register(ds)
+		registry.Register(probe.NewObservedDataSource(ds, observer))
 	}
 
 	return registry, nil
@@ -31,8 +36,14 @@ func newDataSource(cfg DataSourceConfig, transport http.RoundTripper) (service.D
 	switch cfg.Type {
 	case "lua":
 		return newLuaDataSource(cfg, transport)
+	case "redis":
+		return newRedisDataSource(cfg)
+	case "opa":
+		return newOPADataSource(cfg, transport)
+	case "wasm":
+		return newWASMDataSource(cfg)
 	default:
-		return nil, fmt.Errorf("unknown data source type: %s (supported: lua)", cfg.Type)
+		return nil, fmt.Errorf("unknown data source type: %s (supported: lua, redis, opa, wasm)", cfg.Type)
 	}
 }
 
@@ -72,12 +83,23 @@ func newLuaDataSource(cfg DataSourceConfig, transport http.RoundTripper) (servic
 		httpConfig = httpCfg
 	}
 
+	// Build JSON limits
+	var jsonLimits limits.JSON
+	if cfg.JSON != nil {
+		jl, err := buildJSONLimits(cfg.JSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build JSON limits: %w", err)
+		}
+		jsonLimits = jl
+	}
+
 	// Create base Lua data source
 	luaDSConfig := datasource.LuaDataSourceConfig{
 		Name:         cfg.Name,
 		Script:       script,
 		ConfigSource: configSource,
 		HTTPConfig:   httpConfig,
+		JSONLimits:   jsonLimits,
 	}
 
 	baseDS, err := datasource.NewLuaDataSource(luaDSConfig)
@@ -113,9 +135,31 @@ func buildHTTPConfig(cfg *HTTPConfig, transport http.RoundTripper) (*luaservices
 		httpServiceCfg.Transport = transport
 	}
 
+	httpServiceCfg.MaxResponseBytes = cfg.MaxResponseBytes
+	policy, err := limits.ParsePolicy(cfg.Truncation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http truncation: %w", err)
+	}
+	httpServiceCfg.TruncationPolicy = policy
+
 	return httpServiceCfg, nil
 }
 
+// buildJSONLimits creates JSON decoding limits from the config structure
+func buildJSONLimits(cfg *JSONLimitsConfig) (limits.JSON, error) {
+	policy, err := limits.ParsePolicy(cfg.Truncation)
+	if err != nil {
+		return limits.JSON{}, fmt.Errorf("invalid json truncation: %w", err)
+	}
+
+	return limits.JSON{
+		MaxBytes:      cfg.MaxBytes,
+		MaxDepth:      cfg.MaxDepth,
+		MaxArrayItems: cfg.MaxArrayItems,
+		Policy:        policy,
+	}, nil
+}
+
 // wrapWithCaching wraps a data source with the configured caching layer
 func wrapWithCaching(ds service.DataSource, cfg CachingConfig) (service.DataSource, error) {
 	switch cfg.Type {
@@ -141,11 +185,123 @@ func wrapWithCaching(ds service.DataSource, cfg CachingConfig) (service.DataSour
 
 		return datasource.NewDistributedCachingDataSource(ds, cachingCfg), nil
 
+	case "redis":
+		return wrapWithRedisCaching(ds, cfg)
+
 	case "none", "":
 		// No caching
 		return ds, nil
 
 	default:
-		return nil, fmt.Errorf("unknown caching type: %s (supported: in_memory, distributed, none)", cfg.Type)
+		return nil, fmt.Errorf("unknown caching type: %s (supported: in_memory, distributed, redis, none)", cfg.Type)
+	}
+}
+
+// wrapWithRedisCaching wraps a data source with a cache in Redis, with its own
+// connection pool
+func wrapWithRedisCaching(ds service.DataSource, cfg CachingConfig) (service.DataSource, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("redis caching requires address")
+	}
+
+	var ttl time.Duration
+	if cfg.TTL != "" {
+		duration, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid caching ttl: %w", err)
+		}
+		ttl = duration
+	}
+
+	options, err := buildRedisOptions(cfg.Address, cfg.Username, cfg.Password, cfg.RedisDB, cfg.PoolSize, cfg.MinIdleConns, cfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid caching redis: %w", err)
+	}
+
+	return datasource.NewRedisCachingDataSource(ds, datasource.RedisCachingConfig{
+		Client:    redis.NewClient(options),
+		KeyPrefix: cfg.KeyPrefix,
+		TTL:       ttl,
+	})
+}
+
+// newRedisDataSource creates a data source that looks up JSON values in Redis,
+// with its own connection pool
+func newRedisDataSource(cfg DataSourceConfig) (service.DataSource, error) {
+	if cfg.Redis == nil {
+		return nil, fmt.Errorf("redis data source requires redis")
+	}
+	if cfg.Caching != nil {
+		// A lookup costs as much as a cache hit in Redis would
+		return nil, fmt.Errorf("redis data sources are not cached")
+	}
+	if cfg.Redis.Address == "" {
+		return nil, fmt.Errorf("redis data source requires address")
+	}
+
+	options, err := buildRedisOptions(cfg.Redis.Address, cfg.Redis.Username, cfg.Redis.Password,
+		cfg.Redis.RedisDB, cfg.Redis.PoolSize, cfg.Redis.MinIdleConns, cfg.Redis.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := datasource.NewRedisDataSource(datasource.RedisDataSourceConfig{
+		Name:   cfg.Name,
+		Client: redis.NewClient(options),
+		Key:    cfg.Redis.Key,
+		Keys:   cfg.Redis.Keys,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create redis data source: %w", err)
+	}
+	return ds, nil
+}
+
+// buildRedisOptions creates options for a Redis client from configuration
+func buildRedisOptions(address, username, password string, db, poolSize, minIdleConns int, timeout string) (*redis.Options, error) {
+	options := &redis.Options{
+		Addr:         address,
+		Username:     username,
+		Password:     password,
+		DB:           db,
+		PoolSize:     poolSize,
+		MinIdleConns: minIdleConns,
+	}
+	if timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		options.DialTimeout, options.ReadTimeout, options.WriteTimeout = duration, duration, duration
+	}
+	return options, nil
+}
+
+// newOPADataSource creates a data source that queries an OPA server
+func newOPADataSource(cfg DataSourceConfig, transport http.RoundTripper) (service.DataSource, error) {
+	if cfg.OPA == nil {
+		return nil, fmt.Errorf("opa data source requires opa")
+	}
+
+	var timeout time.Duration
+	if cfg.OPA.Timeout != "" {
+		duration, err := time.ParseDuration(cfg.OPA.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid opa timeout: %w", err)
+		}
+		timeout = duration
+	}
+
+	ds, err := datasource.NewOPADataSource(datasource.OPADataSourceConfig{
+		Name:      cfg.Name,
+		URL:       cfg.OPA.URL,
+		Path:      cfg.OPA.Path,
+		Token:     cfg.OPA.Token,
+		Timeout:   timeout,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create opa data source: %w", err)
 	}
+	return ds, nil
 }
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/denylist"
+)
+
+// NewDenylist creates the denylist of revoked tokens from configuration
+func NewDenylist(cfg *DenylistConfig) (denylist.Denylist, error) {
+	if cfg == nil {
+		return denylist.NewMemoryDenylist(nil), nil
+	}
+
+	switch cfg.Type {
+	case "", "memory":
+		return denylist.NewMemoryDenylist(nil), nil
+
+	case "redis":
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("redis denylist requires address")
+		}
+		return denylist.NewRedisDenylist(denylist.RedisDenylistConfig{
+			Client: redis.NewClient(&redis.Options{
+				Addr:     cfg.Address,
+				Username: cfg.Username,
+				Password: cfg.Password,
+				DB:       cfg.RedisDB,
+			}),
+			KeyPrefix: cfg.KeyPrefix,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown denylist type: %s (supported: memory, redis)", cfg.Type)
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewDenylist(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *DenylistConfig
+		wantErr string
+	}{
+		{name: "default", cfg: nil},
+		{name: "memory", cfg: &DenylistConfig{Type: "memory"}},
+		{name: "redis", cfg: &DenylistConfig{Type: "redis", Address: "localhost:6379"}},
+		{name: "redis without address", cfg: &DenylistConfig{Type: "redis"}, wantErr: "redis denylist requires address"},
+		{name: "unknown type", cfg: &DenylistConfig{Type: "dynamodb"}, wantErr: "unknown denylist type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := NewDenylist(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if list == nil {
+				t.Error("expected a denylist")
+			}
+		})
+	}
+}
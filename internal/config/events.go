@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/events"
+	"github.com/alechenninger/parsec/internal/instance"
+)
+
+// defaultEventsTimeout bounds each request to the event sink
+const defaultEventsTimeout = 10 * time.Second
+
+// NewEventPublisher creates a publisher of issuance and denial events from configuration
+// Kafka records are keyed with the instance ID, so one instance's events stay in order.
+// The caller must close the publisher to send queued events.
+func NewEventPublisher(cfg *EventsConfig, identity *instance.Identity, transport http.RoundTripper) (*events.Publisher, error) {
+	timeout, err := parseEventsDuration("timeout", cfg.Timeout, defaultEventsTimeout)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := parseEventsDuration("flush_interval", cfg.FlushInterval, events.DefaultFlushInterval)
+	if err != nil {
+		return nil, err
+	}
+	retryBackoff, err := parseEventsDuration("retry_backoff", cfg.RetryBackoff, events.DefaultRetryBackoff)
+	if err != nil {
+		return nil, err
+	}
+
+	var block bool
+	switch cfg.Overflow {
+	case "drop", "":
+	case "block":
+		block = true
+	default:
+		return nil, fmt.Errorf("unknown events overflow: %s (supported: drop, block)", cfg.Overflow)
+	}
+
+	httpClient := &http.Client{Transport: transport, Timeout: timeout}
+	var sink events.Sink
+	switch cfg.Type {
+	case "kafka":
+		var key string
+		if identity != nil {
+			key = identity.ID
+		}
+		sink, err = events.NewKafkaSink(events.KafkaSinkConfig{
+			URL:        cfg.URL,
+			Topic:      cfg.Topic,
+			Key:        key,
+			HTTPClient: httpClient,
+		})
+	case "webhook":
+		sink, err = events.NewWebhookSink(events.WebhookSinkConfig{
+			URL:        cfg.URL,
+			Headers:    cfg.Headers,
+			HTTPClient: httpClient,
+		})
+	default:
+		return nil, fmt.Errorf("unknown events type: %s (supported: kafka, webhook)", cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return events.NewPublisher(events.PublisherConfig{
+		Sink:          sink,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: flushInterval,
+		QueueSize:     cfg.QueueSize,
+		MaxRetries:    cfg.MaxRetries,
+		RetryBackoff:  retryBackoff,
+		Block:         block,
+	})
+}
+
+// parseEventsDuration parses the duration of an events option, or returns def if unset
+func parseEventsDuration(name, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid events %s: %w", name, err)
+	}
+	return d, nil
+}
@@ -1,23 +1,64 @@
 package config
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"fmt"
 	"maps"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/idgen"
+	"github.com/alechenninger/parsec/internal/instance"
 	"github.com/alechenninger/parsec/internal/issuer"
 	"github.com/alechenninger/parsec/internal/keys"
 	"github.com/alechenninger/parsec/internal/mapper"
+	"github.com/alechenninger/parsec/internal/probe"
+	"github.com/alechenninger/parsec/internal/server"
 	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+	"github.com/redis/go-redis/v9"
+
+	// SQL drivers for the sql key slot store
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
 // NewIssuerRegistry creates an issuer registry from configuration
-func NewIssuerRegistry(cfg Config) (service.Registry, error) {
-	registry := service.NewSimpleRegistry()
+// identity is included in tokens of issuers configured with instance_claim
+func NewIssuerRegistry(cfg Config, identity *instance.Identity) (service.Registry, error) {
+	signerRegistry, err := NewSignerRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tokenStore, err := NewTokenStore(cfg.TokenStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token store: %w", err)
+	}
+	return NewIssuerRegistryWithSigners(cfg, signerRegistry, tokenStore, identity, false)
+}
 
+// NewSignerRegistry creates the configured signers and starts them
+func NewSignerRegistry(cfg Config) (*keys.SignerRegistry, error) {
+	registries, err := NewSignerRegistries(cfg, false, cfg.TrustDomain)
+	if err != nil {
+		return nil, err
+	}
+	return registries[0], nil
+}
+
+// NewSignerRegistries creates the configured signers for each of trustDomains and
+// starts them. The trust domains share key providers and the key slot store; key
+// slots are namespaced by trust domain, so each trust domain has its own keys.
+//
+// If offline, signers are built but not started, so no keys are generated or loaded,
+// and stores and key sources that need a Kubernetes cluster are not contacted.
+func NewSignerRegistries(cfg Config, offline bool, trustDomains ...string) ([]*keys.SignerRegistry, error) {
 	// Build key provider registry from global config
 	providerRegistry, err := buildKeyProviderRegistry(cfg.KeyProviders)
 	if err != nil {
@@ -25,21 +66,106 @@ func NewIssuerRegistry(cfg Config) (service.Registry, error) {
 	}
 
 	// Create shared key slot store
-	slotStore := keys.NewInMemoryKeySlotStore()
+	slotStore, err := buildKeySlotStore(cfg.KeySlotStore, offline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build key slot store: %w", err)
+	}
+
+	registries := make([]*keys.SignerRegistry, 0, len(trustDomains))
+	stopAll := func() {
+		for _, registry := range registries {
+			registry.Stop()
+		}
+	}
+	for _, trustDomain := range trustDomains {
+		// Build signer registry from global config
+		signerRegistry, err := buildSignerRegistry(cfg.Signers, trustDomain, providerRegistry, slotStore, offline)
+		if err != nil {
+			stopAll()
+			return nil, fmt.Errorf("failed to build signer registry: %w", err)
+		}
+		if offline {
+			registries = append(registries, signerRegistry)
+			continue
+		}
+
+		// Start all signers
+		ctx := context.Background()
+		if err := signerRegistry.Start(ctx); err != nil {
+			stopAll()
+			return nil, fmt.Errorf("failed to start signers: %w", err)
+		}
+		registries = append(registries, signerRegistry)
+	}
+
+	return registries, nil
+}
 
-	// Build signer registry from global config
-	signerRegistry, err := buildSignerRegistry(cfg.Signers, cfg.TrustDomain, providerRegistry, slotStore)
+// NewIssuerRegistryWithSigners creates an issuer registry from configuration, with
+// signers from signerRegistry; opaque issuers keep their tokens in tokenStore.
+// If offline, the keys of encrypted tokens' recipients are not fetched until used.
+func NewIssuerRegistryWithSigners(cfg Config, signerRegistry *keys.SignerRegistry, tokenStore tokenstore.Store, identity *instance.Identity, offline bool) (service.Registry, error) {
+	maxTTLs, err := parseMaxTTLs(cfg.TokenPolicy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build signer registry: %w", err)
+		return nil, err
+	}
+
+	registry, err := buildIssuerRegistry(cfg.Issuers, maxTTLs, signerRegistry, tokenStore, identity, offline)
+	if err != nil {
+		return nil, err
+	}
+
+	for tokenType := range maxTTLs {
+		if !slices.Contains(registry.ListTokenTypes(), tokenType) {
+			return nil, fmt.Errorf("token_policy max_ttl configured for token type %s, but no issuer handles it", tokenType)
+		}
+	}
+
+	return registry, nil
+}
+
+// NewTrustDomain creates an additional trust domain from configuration, with signers
+// from signerRegistry, which must be namespaced by the trust domain
+func NewTrustDomain(cfg Config, domainCfg TrustDomainConfig, signerRegistry *keys.SignerRegistry, tokenStore tokenstore.Store, identity *instance.Identity, offline bool) (*service.TrustDomain, error) {
+	if domainCfg.Name == "" {
+		return nil, fmt.Errorf("trust domain name is required")
+	}
+	if domainCfg.Name == cfg.TrustDomain {
+		return nil, fmt.Errorf("trust domain %s is already the trust_domain", domainCfg.Name)
+	}
+	for _, aud := range domainCfg.Audiences {
+		if strings.Contains(strings.TrimPrefix(aud, "*."), "*") {
+			return nil, fmt.Errorf("trust domain %s: invalid audience %q (wildcards must be a leading \"*.\")", domainCfg.Name, aud)
+		}
+	}
+	if len(domainCfg.Issuers) == 0 {
+		return nil, fmt.Errorf("trust domain %s: at least one issuer is required", domainCfg.Name)
 	}
 
-	// Start all signers
-	ctx := context.Background()
-	if err := signerRegistry.Start(ctx); err != nil {
-		return nil, fmt.Errorf("failed to start signers: %w", err)
+	maxTTLs, err := parseMaxTTLs(cfg.TokenPolicy)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := buildIssuerRegistry(domainCfg.Issuers, maxTTLs, signerRegistry, tokenStore, identity, offline)
+	if err != nil {
+		return nil, fmt.Errorf("trust domain %s: %w", domainCfg.Name, err)
 	}
 
-	for _, issuerCfg := range cfg.Issuers {
+	return &service.TrustDomain{
+		Name:       domainCfg.Name,
+		Audiences:  domainCfg.Audiences,
+		Issuers:    registry,
+		Validators: domainCfg.Validators,
+	}, nil
+}
+
+// buildIssuerRegistry creates a registry of issuers, checked against maxTTLs
+func buildIssuerRegistry(configs []IssuerConfig, maxTTLs map[service.TokenType]time.Duration, signerRegistry *keys.SignerRegistry, tokenStore tokenstore.Store, identity *instance.Identity, offline bool) (*service.SimpleRegistry, error) {
+	registry := service.NewSimpleRegistry()
+
+	// The audiences of each token type's issuers, which must not overlap
+	audienceIssuers := make(map[service.TokenType][][]string)
+	for _, issuerCfg := range configs {
 		if issuerCfg.TokenType == "" {
 			return nil, fmt.Errorf("token_type is required for issuer")
 		}
@@ -48,18 +174,86 @@ func NewIssuerRegistry(cfg Config) (service.Registry, error) {
 		tokenType := service.TokenType(issuerCfg.TokenType)
 
 		// Create issuer (now using signer registry instead of building signers inline)
-		iss, err := newIssuer(issuerCfg, signerRegistry)
+		iss, err := newIssuer(issuerCfg, signerRegistry, tokenStore, identity, offline)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create issuer for token type %s: %w", issuerCfg.TokenType, err)
 		}
 
-		// Register issuer
+		if err := checkIssuerMaxTTL(tokenType, iss, maxTTLs); err != nil {
+			return nil, err
+		}
+
+		// Register issuer, for its audiences if it has any
+		if len(issuerCfg.Audiences) > 0 {
+			for _, aud := range issuerCfg.Audiences {
+				if slices.ContainsFunc(audienceIssuers[tokenType], func(audiences []string) bool { return slices.Contains(audiences, aud) }) {
+					return nil, fmt.Errorf("more than one issuer for token type %s and audience %s", tokenType, aud)
+				}
+			}
+			audienceIssuers[tokenType] = append(audienceIssuers[tokenType], issuerCfg.Audiences)
+			registry.RegisterForAudiences(tokenType, issuerCfg.Audiences, iss)
+			continue
+		}
+		if _, err := registry.GetIssuer(tokenType); err == nil {
+			return nil, fmt.Errorf("more than one issuer for token type %s without audiences", tokenType)
+		}
 		registry.Register(tokenType, iss)
 	}
 
 	return registry, nil
 }
 
+// parseMaxTTLs parses the max TTL by token type from the token policy
+func parseMaxTTLs(cfg *TokenPolicyConfig) (map[service.TokenType]time.Duration, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	maxTTLs := make(map[service.TokenType]time.Duration, len(cfg.MaxTTLs))
+	for _, maxTTLCfg := range cfg.MaxTTLs {
+		if maxTTLCfg.TokenType == "" {
+			return nil, fmt.Errorf("token_policy max_ttl requires token_type")
+		}
+		tokenType := service.TokenType(maxTTLCfg.TokenType)
+		if _, exists := maxTTLs[tokenType]; exists {
+			return nil, fmt.Errorf("duplicate token_policy max_ttl for token type %s", tokenType)
+		}
+
+		maxTTL, err := time.ParseDuration(maxTTLCfg.MaxTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token_policy max_ttl for token type %s: %w", tokenType, err)
+		}
+		if maxTTL <= 0 {
+			return nil, fmt.Errorf("token_policy max_ttl for token type %s must be positive", tokenType)
+		}
+		maxTTLs[tokenType] = maxTTL
+	}
+
+	return maxTTLs, nil
+}
+
+// checkIssuerMaxTTL fails if the issuer is configured to issue tokens that outlive the token type's max TTL.
+// Issuers that cannot describe their tokens are only checked at issuance.
+func checkIssuerMaxTTL(tokenType service.TokenType, iss service.Issuer, maxTTLs map[service.TokenType]time.Duration) error {
+	maxTTL, ok := maxTTLs[tokenType]
+	if !ok {
+		return nil
+	}
+	describable, ok := iss.(service.DescribableIssuer)
+	if !ok {
+		return nil
+	}
+
+	description := describable.Describe()
+	if description.MaxTTL == 0 {
+		return fmt.Errorf("issuer for token type %s issues tokens that do not expire, but token_policy max_ttl is %s", tokenType, maxTTL)
+	}
+	if description.MaxTTL > maxTTL {
+		return fmt.Errorf("issuer for token type %s has ttl %s, which exceeds token_policy max_ttl %s", tokenType, description.MaxTTL, maxTTL)
+	}
+	return nil
+}
+
 // buildKeyProviderRegistry creates a map of KeyProvider instances from configuration
 func buildKeyProviderRegistry(configs []KeyProviderConfig) (map[string]keys.KeyProvider, error) {
 	registry := make(map[string]keys.KeyProvider)
@@ -116,8 +310,14 @@ func buildKeyProviderRegistry(configs []KeyProviderConfig) (map[string]keys.KeyP
 				return nil, fmt.Errorf("failed to create aws_kms key provider %s: %w", cfg.ID, err)
 			}
 
+		case "vault_transit":
+			provider, err = buildVaultTransitKeyProvider(cfg, keyType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create vault_transit key provider %s: %w", cfg.ID, err)
+			}
+
 		default:
-			return nil, fmt.Errorf("unknown key provider type for %s: %s (supported: memory, disk, aws_kms)", cfg.ID, cfg.Type)
+			return nil, fmt.Errorf("unknown key provider type for %s: %s (supported: memory, disk, aws_kms, vault_transit)", cfg.ID, cfg.Type)
 		}
 
 		registry[cfg.ID] = provider
@@ -126,22 +326,197 @@ func buildKeyProviderRegistry(configs []KeyProviderConfig) (map[string]keys.KeyP
 	return registry, nil
 }
 
+// buildKeySlotStore creates the key slot store shared by all signers
+// If offline, a kubernetes store, which needs the cluster, is checked but replaced with an
+// in-memory one, and SQL tables are not created.
+func buildKeySlotStore(cfg *KeySlotStoreConfig, offline bool) (keys.KeySlotStore, error) {
+	if cfg == nil {
+		return keys.NewInMemoryKeySlotStore(), nil
+	}
+
+	switch cfg.Type {
+	case "", "memory":
+		return keys.NewInMemoryKeySlotStore(), nil
+
+	case "kubernetes":
+		if offline {
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("kubernetes key slot store requires name")
+			}
+			return keys.NewInMemoryKeySlotStore(), nil
+		}
+		return keys.NewKubernetesKeySlotStore(keys.KubernetesKeySlotStoreConfig{
+			Kind:      keys.KubernetesResourceKind(cfg.Kind),
+			Name:      cfg.Name,
+			Namespace: cfg.Namespace,
+		})
+
+	case "sql":
+		return buildSQLKeySlotStore(cfg, offline)
+
+	case "redis":
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("redis key slot store requires address")
+		}
+		return keys.NewRedisKeySlotStore(keys.RedisKeySlotStoreConfig{
+			Client: redis.NewClient(&redis.Options{
+				Addr:     cfg.Address,
+				Username: cfg.Username,
+				Password: cfg.Password,
+				DB:       cfg.RedisDB,
+			}),
+			Key: cfg.Key,
+		})
+
+	case "etcd":
+		return keys.NewEtcdKeySlotStore(keys.EtcdKeySlotStoreConfig{
+			Endpoints: cfg.Endpoints,
+			Key:       cfg.Key,
+			Username:  cfg.Username,
+			Password:  cfg.Password,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown key slot store type: %s (supported: memory, kubernetes, sql, redis, etcd)", cfg.Type)
+	}
+}
+
+// buildSQLKeySlotStore opens the database and creates a SQL key slot store
+func buildSQLKeySlotStore(cfg *KeySlotStoreConfig, offline bool) (keys.KeySlotStore, error) {
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("sql key slot store requires dsn")
+	}
+
+	var driverName string
+	var dialect keys.SQLDialect
+	switch cfg.Driver {
+	case "postgres":
+		driverName, dialect = "pgx", keys.SQLDialectPostgres
+	case "mysql":
+		driverName, dialect = "mysql", keys.SQLDialectMySQL
+	default:
+		return nil, fmt.Errorf("unknown sql key slot store driver: %s (supported: postgres, mysql)", cfg.Driver)
+	}
+
+	db, err := sql.Open(driverName, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	store, err := keys.NewSQLKeySlotStore(keys.SQLKeySlotStoreConfig{
+		DB:      db,
+		Dialect: dialect,
+		Table:   cfg.Table,
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if cfg.CreateTables && !offline {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := store.CreateTables(ctx); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// buildVaultTransitKeyProvider creates a Vault Transit key provider
+// Address and token fall back to the standard VAULT_ADDR and VAULT_TOKEN environment variables
+func buildVaultTransitKeyProvider(cfg KeyProviderConfig, keyType keys.KeyType) (keys.KeyProvider, error) {
+	address := cfg.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("address is required (or set VAULT_ADDR)")
+	}
+
+	auth, err := buildVaultAuth(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys.NewVaultTransitKeyProvider(keys.VaultTransitConfig{
+		KeyType:   keyType,
+		Algorithm: cfg.Algorithm,
+		Address:   address,
+		MountPath: cfg.MountPath,
+		KeyPrefix: cfg.KeyPrefix,
+		Namespace: cfg.VaultNamespace,
+		Auth:      auth,
+	})
+}
+
+// buildVaultAuth creates a Vault auth method from configuration
+func buildVaultAuth(cfg *VaultAuthConfig) (keys.VaultAuthMethod, error) {
+	if cfg == nil {
+		cfg = &VaultAuthConfig{}
+	}
+
+	switch cfg.Method {
+	case "", "token":
+		token := cfg.Token
+		if token == "" && cfg.TokenFile == "" {
+			token = os.Getenv("VAULT_TOKEN")
+		}
+		if token == "" && cfg.TokenFile == "" {
+			return nil, fmt.Errorf("token auth requires token or token_file (or set VAULT_TOKEN)")
+		}
+		return keys.VaultTokenAuth{Token: token, TokenFile: cfg.TokenFile}, nil
+
+	case "kubernetes":
+		if cfg.Role == "" {
+			return nil, fmt.Errorf("kubernetes auth requires role")
+		}
+		return keys.VaultKubernetesAuth{Role: cfg.Role, MountPath: cfg.MountPath, JWTPath: cfg.JWTPath}, nil
+
+	case "approle":
+		if cfg.RoleID == "" {
+			return nil, fmt.Errorf("approle auth requires role_id")
+		}
+		if cfg.SecretID == "" && cfg.SecretIDFile == "" {
+			return nil, fmt.Errorf("approle auth requires secret_id or secret_id_file")
+		}
+		return keys.VaultAppRoleAuth{
+			RoleID:       cfg.RoleID,
+			SecretID:     cfg.SecretID,
+			SecretIDFile: cfg.SecretIDFile,
+			MountPath:    cfg.MountPath,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown vault auth method: %s (supported: token, kubernetes, approle)", cfg.Method)
+	}
+}
+
 // buildSignerRegistry creates a SignerRegistry from configuration
-func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerRegistry map[string]keys.KeyProvider, slotStore keys.KeySlotStore) (*keys.SignerRegistry, error) {
+// If offline, external signers' key sources are not read (see buildExternalKeySigner).
+func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerRegistry map[string]keys.KeyProvider, slotStore keys.KeySlotStore, offline bool) (*keys.SignerRegistry, error) {
 	registry := keys.NewSignerRegistry()
 
+	// Migrations reference other signers, so they are built once all others are registered
+	var migrations []SignerConfig
+
 	for _, cfg := range configs {
 		if cfg.ID == "" {
 			return nil, fmt.Errorf("signer id is required")
 		}
-
-		if cfg.KeyProviderID == "" {
-			return nil, fmt.Errorf("signer %s requires key_provider_id", cfg.ID)
+		if cfg.Type == "algorithm_migration" {
+			if cfg.Concurrency != nil {
+				return nil, fmt.Errorf("signer %s: algorithm_migration signers sign with their from and to signers; limit their concurrency instead", cfg.ID)
+			}
+			migrations = append(migrations, cfg)
+			continue
 		}
 
-		// Validate key provider exists
-		if _, ok := providerRegistry[cfg.KeyProviderID]; !ok {
-			return nil, fmt.Errorf("key provider not found for signer %s: %s", cfg.ID, cfg.KeyProviderID)
+		limiter, err := buildSigningLimiter(cfg.Concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid concurrency for signer %s: %w", cfg.ID, err)
 		}
 
 		// Determine namespace (defaults to ID)
@@ -200,6 +575,19 @@ func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerReg
 		var signer keys.RotatingSigner
 		switch cfg.Type {
 		case "", "dual_slot":
+			if cfg.KeyProviderID == "" {
+				return nil, fmt.Errorf("signer %s requires key_provider_id", cfg.ID)
+			}
+
+			// Validate key provider exists
+			if _, ok := providerRegistry[cfg.KeyProviderID]; !ok {
+				return nil, fmt.Errorf("key provider not found for signer %s: %s", cfg.ID, cfg.KeyProviderID)
+			}
+
+			if err := validateRotationTimings(keyTTL, rotationThreshold, gracePeriod, checkInterval); err != nil {
+				return nil, fmt.Errorf("invalid rotation timings for signer %s: %w", cfg.ID, err)
+			}
+
 			signer = keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
 				Namespace:           namespace,
 				TrustDomain:         trustDomain,
@@ -211,11 +599,28 @@ func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerReg
 				GracePeriod:         gracePeriod,
 				CheckInterval:       checkInterval,
 				PrepareTimeout:      prepareTimeout,
+				Limiter:             limiter,
 			})
+		case "external":
+			external, err := buildExternalKeySigner(cfg.Source, checkInterval, limiter, offline)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create signer %s: %w", cfg.ID, err)
+			}
+			signer = external
 		default:
-			return nil, fmt.Errorf("unknown signer type for %s: %s (supported: dual_slot)", cfg.ID, cfg.Type)
+			return nil, fmt.Errorf("unknown signer type for %s: %s (supported: dual_slot, external, algorithm_migration)", cfg.ID, cfg.Type)
+		}
+
+		if err := registry.Register(cfg.ID, signer); err != nil {
+			return nil, fmt.Errorf("failed to register signer %s: %w", cfg.ID, err)
 		}
+	}
 
+	for _, cfg := range migrations {
+		signer, err := buildAlgorithmMigrationSigner(cfg, registry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create signer %s: %w", cfg.ID, err)
+		}
 		if err := registry.Register(cfg.ID, signer); err != nil {
 			return nil, fmt.Errorf("failed to register signer %s: %w", cfg.ID, err)
 		}
@@ -224,19 +629,165 @@ func buildSignerRegistry(configs []SignerConfig, trustDomain string, providerReg
 	return registry, nil
 }
 
+// validateRotationTimings checks a dual_slot signer's timings leave room for each rotation phase:
+// a new key is generated rotation_threshold before the current key expires, and only signs once
+// its grace_period has passed, which must happen before the current key expires.
+func validateRotationTimings(keyTTL, rotationThreshold, gracePeriod, checkInterval time.Duration) error {
+	if keyTTL <= 0 || rotationThreshold <= 0 || gracePeriod <= 0 || checkInterval <= 0 {
+		return fmt.Errorf("key_ttl, rotation_threshold, grace_period, and check_interval must be positive")
+	}
+	if rotationThreshold >= keyTTL {
+		return fmt.Errorf("rotation_threshold (%s) must be less than key_ttl (%s)", rotationThreshold, keyTTL)
+	}
+	if gracePeriod >= rotationThreshold {
+		return fmt.Errorf("grace_period (%s) must be less than rotation_threshold (%s)", gracePeriod, rotationThreshold)
+	}
+	if checkInterval >= rotationThreshold-gracePeriod {
+		return fmt.Errorf("check_interval (%s) must be less than rotation_threshold minus grace_period (%s)", checkInterval, rotationThreshold-gracePeriod)
+	}
+	return nil
+}
+
+// buildAlgorithmMigrationSigner creates a signer that migrates from one registered signer to another
+func buildAlgorithmMigrationSigner(cfg SignerConfig, registry *keys.SignerRegistry) (keys.RotatingSigner, error) {
+	if cfg.FromSignerID == "" || cfg.ToSignerID == "" {
+		return nil, fmt.Errorf("algorithm_migration signer requires from_signer_id and to_signer_id")
+	}
+	from, err := registry.Get(cfg.FromSignerID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := registry.Get(cfg.ToSignerID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.CutoverAt == "" {
+		return nil, fmt.Errorf("algorithm_migration signer requires cutover_at")
+	}
+	cutoverAt, err := time.Parse(time.RFC3339, cfg.CutoverAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cutover_at: %w", err)
+	}
+
+	var retireAfter time.Duration
+	if cfg.RetireAfter != "" {
+		retireAfter, err = time.ParseDuration(cfg.RetireAfter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retire_after: %w", err)
+		}
+	}
+
+	return keys.NewAlgorithmMigrationSigner(keys.AlgorithmMigrationSignerConfig{
+		From:        from,
+		To:          to,
+		CutoverAt:   cutoverAt,
+		RetireAfter: retireAfter,
+	})
+}
+
+// buildExternalKeySigner creates a signer for keys managed outside parsec
+// If offline, a kubernetes_secret source, which needs the cluster, is checked but not
+// created; the signer fails to load its key.
+func buildExternalKeySigner(cfg *ExternalKeySourceConfig, refreshInterval time.Duration, limiter *keys.SigningLimiter, offline bool) (keys.RotatingSigner, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("external signer requires source")
+	}
+
+	var source keys.ExternalKeySource
+	switch cfg.Type {
+	case "kubernetes_secret":
+		if offline {
+			if cfg.Name == "" {
+				return nil, fmt.Errorf("kubernetes secret key source requires a name")
+			}
+			source = offlineKeySource{}
+			break
+		}
+		kubeSource, err := keys.NewKubernetesSecretKeySource(keys.KubernetesSecretKeySourceConfig{
+			Name:      cfg.Name,
+			Namespace: cfg.Namespace,
+		})
+		if err != nil {
+			return nil, err
+		}
+		source = kubeSource
+
+	case "vault_kv":
+		address := cfg.Address
+		if address == "" {
+			address = os.Getenv("VAULT_ADDR")
+		}
+		if address == "" {
+			return nil, fmt.Errorf("address is required (or set VAULT_ADDR)")
+		}
+		auth, err := buildVaultAuth(cfg.Auth)
+		if err != nil {
+			return nil, err
+		}
+		vaultSource, err := keys.NewVaultKVKeySource(keys.VaultKVKeySourceConfig{
+			Address:   address,
+			MountPath: cfg.MountPath,
+			Path:      cfg.Path,
+			KVVersion: cfg.KVVersion,
+			Namespace: cfg.VaultNamespace,
+			Auth:      auth,
+		})
+		if err != nil {
+			return nil, err
+		}
+		source = vaultSource
+
+	default:
+		return nil, fmt.Errorf("unknown external key source type: %s (supported: kubernetes_secret, vault_kv)", cfg.Type)
+	}
+
+	return keys.NewExternalKeySigner(keys.ExternalKeySignerConfig{
+		Source:           source,
+		KeyField:         cfg.KeyField,
+		PreviousKeyField: cfg.PreviousKeyField,
+		Algorithm:        keys.Algorithm(cfg.Algorithm),
+		RefreshInterval:  refreshInterval,
+		Limiter:          limiter,
+	})
+}
+
+// buildSigningLimiter creates a signing limiter from configuration, or returns nil if cfg is nil
+func buildSigningLimiter(cfg *SigningConcurrencyConfig) (*keys.SigningLimiter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	var queueTimeout time.Duration
+	if cfg.QueueTimeout != "" {
+		duration, err := time.ParseDuration(cfg.QueueTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid queue_timeout: %w", err)
+		}
+		queueTimeout = duration
+	}
+	return keys.NewSigningLimiter(keys.SigningLimiterConfig{
+		MaxConcurrent: cfg.MaxConcurrent,
+		MaxQueued:     cfg.MaxQueued,
+		QueueTimeout:  queueTimeout,
+	})
+}
+
 // newIssuer creates an issuer from configuration
-func newIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry) (service.Issuer, error) {
+func newIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry, tokenStore tokenstore.Store, identity *instance.Identity, offline bool) (service.Issuer, error) {
 	switch cfg.Type {
 	case "stub":
 		return newStubIssuer(cfg)
 	case "unsigned":
 		return newUnsignedIssuer(cfg)
 	case "transaction_token":
-		return newTransactionTokenIssuer(cfg, signerRegistry)
+		return newTransactionTokenIssuer(cfg, signerRegistry, tokenStore, identity, offline)
 	case "rh_identity":
 		return newRHIdentityIssuer(cfg)
+	case "opaque":
+		return newOpaqueIssuer(cfg, tokenStore, identity)
+	case "biscuit":
+		return newBiscuitIssuer(cfg, signerRegistry, identity)
 	default:
-		return nil, fmt.Errorf("unknown issuer type: %s (supported: stub, unsigned, transaction_token, rh_identity)", cfg.Type)
+		return nil, fmt.Errorf("unknown issuer type: %s (supported: stub, unsigned, transaction_token, rh_identity, opaque, biscuit)", cfg.Type)
 	}
 }
 
@@ -286,7 +837,7 @@ func newStubIssuer(cfg IssuerConfig) (service.Issuer, error) {
 
 // newTransactionTokenIssuer creates a transaction token issuer.
 // This issuer signs transaction tokens using a signer from the global signer registry.
-func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry) (service.Issuer, error) {
+func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry, tokenStore tokenstore.Store, identity *instance.Identity, offline bool) (service.Issuer, error) {
 	if cfg.IssuerURL == "" {
 		return nil, fmt.Errorf("transaction_token issuer requires issuer_url")
 	}
@@ -332,13 +883,317 @@ func newTransactionTokenIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegi
 		reqMappers = append(reqMappers, m)
 	}
 
-	return issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+	var format service.TokenFormat
+	switch cfg.Format {
+	case "", "jwt":
+		format = service.TokenFormatJWT
+	case "cwt":
+		format = service.TokenFormatCWT
+	default:
+		return nil, fmt.Errorf("unknown transaction_token format: %s (supported: jwt, cwt)", cfg.Format)
+	}
+
+	idGenerator, err := newTokenIDGenerator(cfg.JTI, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	notBeforeSkew, err := parseNotBeforeSkew(cfg.NotBeforeSkew)
+	if err != nil {
+		return nil, err
+	}
+
+	issuerCfg := issuer.TransactionTokenIssuerConfig{
 		IssuerURL:                 cfg.IssuerURL,
 		TTL:                       ttl,
 		Signer:                    signer,
 		TransactionContextMappers: txnMappers,
 		RequestContextMappers:     reqMappers,
-	}), nil
+		Format:                    format,
+		IDGenerator:               idGenerator,
+		NotBeforeSkew:             notBeforeSkew,
+	}
+	if cfg.TxnID != nil {
+		switch cfg.TxnID.Generator {
+		case "", "uuid":
+		case "uuidv7":
+			issuerCfg.TxnIDGenerator = idgen.NewUUIDv7Generator()
+		case "ulid":
+			issuerCfg.TxnIDGenerator = idgen.NewULIDGenerator(nil)
+		default:
+			return nil, fmt.Errorf("unknown txn_id generator: %s (supported: uuid, uuidv7, ulid)", cfg.TxnID.Generator)
+		}
+		issuerCfg.TxnIDHeader = cfg.TxnID.PropagateFrom
+	}
+	if issuerCfg.TxnIDGenerator == nil && cfg.JTI != nil {
+		// txn IDs are not token IDs, so are never instance-prefixed
+		issuerCfg.TxnIDGenerator = idgen.NewUUIDGenerator()
+	}
+	if cfg.Encryption != nil {
+		if format != service.TokenFormatJWT {
+			return nil, fmt.Errorf("encryption requires jwt format")
+		}
+		encryption, err := newTokenEncryption(*cfg.Encryption, offline)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption: %w", err)
+		}
+		issuerCfg.Encryption = encryption
+	}
+	if cfg.InstanceClaim {
+		if identity == nil {
+			return nil, fmt.Errorf("instance_claim requires an instance identity")
+		}
+		issuerCfg.Instance = identity
+	}
+	if cfg.SizeBudget != nil {
+		budget, err := newSizeBudget(*cfg.SizeBudget)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size_budget: %w", err)
+		}
+		issuerCfg.SizeBudget = budget
+	}
+	redaction, err := newRedaction(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	issuerCfg.Redaction = redaction
+	profiles, err := newAudienceProfiles(cfg.Profiles)
+	if err != nil {
+		return nil, err
+	}
+	issuerCfg.Profiles = profiles
+	dynamicTTL, err := newDynamicTTL(cfg.TTLPolicy, ttl)
+	if err != nil {
+		return nil, err
+	}
+	issuerCfg.DynamicTTL = dynamicTTL
+	if cfg.ClaimsByReference != nil || (cfg.SizeBudget != nil && cfg.SizeBudget.OnExceeded == string(issuer.SizeBudgetReference)) {
+		references, err := newClaimsReferences(cfg, tokenStore)
+		if err != nil {
+			return nil, fmt.Errorf("invalid claims_by_reference: %w", err)
+		}
+		issuerCfg.ClaimsReferences = references
+	}
+
+	return issuer.NewTransactionTokenIssuer(issuerCfg), nil
+}
+
+// newSizeBudget creates a token size budget
+func newSizeBudget(cfg SizeBudgetConfig) (*issuer.SizeBudget, error) {
+	budget := &issuer.SizeBudget{
+		MaxTokenBytes:     cfg.MaxTokenBytes,
+		MaxClaimBytes:     cfg.MaxClaimBytes,
+		LowPriorityClaims: cfg.LowPriorityClaims,
+	}
+	switch issuer.SizeBudgetAction(cfg.OnExceeded) {
+	case "", issuer.SizeBudgetFail:
+		budget.OnExceeded = issuer.SizeBudgetFail
+	case issuer.SizeBudgetTruncate:
+		budget.OnExceeded = issuer.SizeBudgetTruncate
+	case issuer.SizeBudgetReference:
+		budget.OnExceeded = issuer.SizeBudgetReference
+	default:
+		return nil, fmt.Errorf("unknown on_exceeded: %s (supported: fail, truncate, reference)", cfg.OnExceeded)
+	}
+	for _, path := range cfg.LowPriorityClaims {
+		if !isContextClaimPath(path) {
+			return nil, fmt.Errorf("low priority claim %s must be in tctx or req_ctx, like tctx.groups", path)
+		}
+	}
+	return budget, nil
+}
+
+// newAudienceProfiles creates the audience profiles of an issuer
+func newAudienceProfiles(cfgs []AudienceProfileConfig) ([]issuer.AudienceProfile, error) {
+	var profiles []issuer.AudienceProfile
+	for i, cfg := range cfgs {
+		if len(cfg.Audiences) == 0 {
+			return nil, fmt.Errorf("profile %d requires audiences", i)
+		}
+		profile := issuer.AudienceProfile{Audiences: cfg.Audiences}
+		for j, mapperCfg := range cfg.TransactionContextMappers {
+			m, err := newClaimMapper(mapperCfg)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: failed to create transaction context mapper %d: %w", i, j, err)
+			}
+			profile.TransactionContextMappers = append(profile.TransactionContextMappers, m)
+		}
+		for j, mapperCfg := range cfg.RequestContextMappers {
+			m, err := newClaimMapper(mapperCfg)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: failed to create request context mapper %d: %w", i, j, err)
+			}
+			profile.RequestContextMappers = append(profile.RequestContextMappers, m)
+		}
+		for j, mapperCfg := range cfg.ClaimMappers {
+			m, err := newClaimMapper(mapperCfg)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: failed to create claim mapper %d: %w", i, j, err)
+			}
+			profile.ClaimMappers = append(profile.ClaimMappers, m)
+		}
+		redaction, err := newRedaction(cfg.Redaction)
+		if err != nil {
+			return nil, fmt.Errorf("profile %d: %w", i, err)
+		}
+		profile.Redaction = redaction
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// newDynamicTTL creates the TTL policy of an issuer whose tokens otherwise live for
+// ttl, or nil if it has none
+func newDynamicTTL(cfg *TTLPolicyConfig, ttl time.Duration) (*issuer.DynamicTTL, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Expression == "" {
+		return nil, fmt.Errorf("ttl_policy requires expression")
+	}
+	if cfg.MaxTTL == "" {
+		return nil, fmt.Errorf("ttl_policy requires max_ttl")
+	}
+	maxTTL, err := time.ParseDuration(cfg.MaxTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ttl_policy max_ttl: %w", err)
+	}
+	var minTTL time.Duration
+	if cfg.MinTTL != "" {
+		minTTL, err = time.ParseDuration(cfg.MinTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl_policy min_ttl: %w", err)
+		}
+	}
+	if minTTL < 0 || minTTL > maxTTL {
+		return nil, fmt.Errorf("ttl_policy min_ttl %s must be between 0 and max_ttl %s", minTTL, maxTTL)
+	}
+	if ttl < minTTL || ttl > maxTTL {
+		return nil, fmt.Errorf("ttl %s must be between ttl_policy min_ttl %s and max_ttl %s", ttl, minTTL, maxTTL)
+	}
+
+	policy, err := mapper.NewCELTTLPolicy(cfg.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ttl_policy: %w", err)
+	}
+	return &issuer.DynamicTTL{Policy: policy, MinTTL: minTTL, MaxTTL: maxTTL}, nil
+}
+
+// newRedaction creates the redaction of an issuer's mapped claims, or nil if it has none
+func newRedaction(cfg *RedactionConfig) (*issuer.Redaction, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	redactionCfg := issuer.RedactionConfig{
+		Allow:   cfg.Allow,
+		HashKey: []byte(cfg.HashKey),
+	}
+	for _, rule := range cfg.Rules {
+		redactionCfg.Rules = append(redactionCfg.Rules, issuer.RedactionRule{
+			Claims:  rule.Claims,
+			Pattern: rule.Pattern,
+			When:    rule.When,
+			Action:  issuer.RedactionAction(rule.Action),
+		})
+	}
+	redaction, err := issuer.NewRedaction(redactionCfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction: %w", err)
+	}
+	return redaction, nil
+}
+
+// newClaimsReferences keeps the context claims moved out of an issuer's tokens in
+// tokenStore, referenced by URLs of the claims endpoint
+func newClaimsReferences(cfg IssuerConfig, tokenStore tokenstore.Store) (*issuer.ClaimsReferences, error) {
+	if tokenStore == nil {
+		return nil, fmt.Errorf("claims references require a token store")
+	}
+	baseURL := cfg.IssuerURL
+	var paths []string
+	if cfg.ClaimsByReference != nil {
+		if cfg.ClaimsByReference.BaseURL != "" {
+			baseURL = cfg.ClaimsByReference.BaseURL
+		}
+		paths = cfg.ClaimsByReference.Claims
+	}
+	for _, path := range paths {
+		if !isContextClaimPath(path) {
+			return nil, fmt.Errorf("claim %s must be in tctx or req_ctx, like tctx.entitlements", path)
+		}
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("base_url or issuer_url is required")
+	}
+	return &issuer.ClaimsReferences{
+		Store:  tokenStore,
+		URL:    strings.TrimSuffix(baseURL, "/") + server.ClaimsPath,
+		Claims: paths,
+	}, nil
+}
+
+// isContextClaimPath reports whether path names a claim in tctx or req_ctx
+func isContextClaimPath(path string) bool {
+	return strings.HasPrefix(path, "tctx.") || strings.HasPrefix(path, "req_ctx.")
+}
+
+// newTokenEncryption creates JWE encryption for issued tokens, fetching recipient keys if
+// needed, unless offline
+func newTokenEncryption(cfg TokenEncryptionConfig, offline bool) (*issuer.TokenEncryption, error) {
+	encryption := &issuer.TokenEncryption{}
+
+	if cfg.KeyAlgorithm != "" {
+		if err := encryption.KeyAlgorithm.Accept(cfg.KeyAlgorithm); err != nil {
+			return nil, fmt.Errorf("unknown key_algorithm %s", cfg.KeyAlgorithm)
+		}
+	}
+	if cfg.ContentEncryption != "" {
+		if err := encryption.ContentEncryption.Accept(cfg.ContentEncryption); err != nil {
+			return nil, fmt.Errorf("unknown content_encryption %s", cfg.ContentEncryption)
+		}
+	}
+	if len(cfg.Recipients) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+
+	for _, recipientCfg := range cfg.Recipients {
+		if recipientCfg.Audience == "" {
+			encryption.Close()
+			return nil, fmt.Errorf("recipient requires audience")
+		}
+
+		var source trust.JWKSSource
+		switch {
+		case recipientCfg.JWKSURL != "" && recipientCfg.JWKS != "":
+			encryption.Close()
+			return nil, fmt.Errorf("recipient %s: only one of jwks_url or jwks may be set", recipientCfg.Audience)
+		case recipientCfg.JWKS != "":
+			static, err := trust.NewStaticJWKSSource([]byte(recipientCfg.JWKS))
+			if err != nil {
+				encryption.Close()
+				return nil, fmt.Errorf("recipient %s: %w", recipientCfg.Audience, err)
+			}
+			source = static
+		case recipientCfg.JWKSURL != "":
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			cache, err := trust.NewJWKSCache(ctx, trust.JWKSCacheConfig{URL: recipientCfg.JWKSURL, Lazy: offline})
+			cancel()
+			if err != nil {
+				encryption.Close()
+				return nil, fmt.Errorf("recipient %s: %w", recipientCfg.Audience, err)
+			}
+			source = cache
+		default:
+			encryption.Close()
+			return nil, fmt.Errorf("recipient %s requires jwks_url or jwks", recipientCfg.Audience)
+		}
+
+		encryption.Recipients = append(encryption.Recipients, issuer.EncryptionRecipient{
+			Audience: recipientCfg.Audience,
+			Keys:     source,
+		})
+	}
+	return encryption, nil
 }
 
 // newUnsignedIssuer creates an unsigned issuer (for development/testing)
@@ -353,9 +1208,21 @@ func newUnsignedIssuer(cfg IssuerConfig) (service.Issuer, error) {
 		mappers = append(mappers, m)
 	}
 
+	redaction, err := newRedaction(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := newAudienceProfiles(cfg.Profiles)
+	if err != nil {
+		return nil, err
+	}
+
 	return issuer.NewUnsignedIssuer(issuer.UnsignedIssuerConfig{
 		TokenType:    cfg.TokenType,
 		ClaimMappers: mappers,
+		Redaction:    redaction,
+		Profiles:     profiles,
 	}), nil
 }
 
@@ -371,25 +1238,238 @@ func newRHIdentityIssuer(cfg IssuerConfig) (service.Issuer, error) {
 		mappers = append(mappers, m)
 	}
 
+	redaction, err := newRedaction(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := newAudienceProfiles(cfg.Profiles)
+	if err != nil {
+		return nil, err
+	}
+
 	return issuer.NewRHIdentityIssuer(issuer.RHIdentityIssuerConfig{
 		TokenType:    cfg.TokenType,
 		ClaimMappers: mappers,
+		Redaction:    redaction,
+		Profiles:     profiles,
 	}), nil
 }
 
-// newClaimMapper creates a claim mapper from configuration
+// newOpaqueIssuer creates an opaque issuer, whose tokens are introspected
+func newOpaqueIssuer(cfg IssuerConfig, tokenStore tokenstore.Store, identity *instance.Identity) (service.Issuer, error) {
+	if cfg.IssuerURL == "" {
+		return nil, fmt.Errorf("opaque issuer requires issuer_url")
+	}
+
+	// Parse TTL
+	ttl := 5 * time.Minute // default
+	if cfg.TTL != "" {
+		duration, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl: %w", err)
+		}
+		ttl = duration
+	}
+
+	// Create claim mappers
+	var mappers []service.ClaimMapper
+	for i, mapperCfg := range cfg.ClaimMappers {
+		m, err := newClaimMapper(mapperCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create claim mapper %d: %w", i, err)
+		}
+		mappers = append(mappers, m)
+	}
+
+	redaction, err := newRedaction(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := newAudienceProfiles(cfg.Profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicTTL, err := newDynamicTTL(cfg.TTLPolicy, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	idGenerator, err := newTokenIDGenerator(cfg.JTI, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	notBeforeSkew, err := parseNotBeforeSkew(cfg.NotBeforeSkew)
+	if err != nil {
+		return nil, err
+	}
+
+	return issuer.NewOpaqueIssuer(issuer.OpaqueIssuerConfig{
+		IssuerURL:     cfg.IssuerURL,
+		TokenType:     cfg.TokenType,
+		TTL:           ttl,
+		ClaimMappers:  mappers,
+		Store:         tokenStore,
+		Redaction:     redaction,
+		Profiles:      profiles,
+		DynamicTTL:    dynamicTTL,
+		IDGenerator:   idGenerator,
+		NotBeforeSkew: notBeforeSkew,
+	}), nil
+}
+
+// newBiscuitIssuer creates a Biscuit issuer whose root key is a signer from the global signer registry
+func newBiscuitIssuer(cfg IssuerConfig, signerRegistry *keys.SignerRegistry, identity *instance.Identity) (service.Issuer, error) {
+	if cfg.SignerID == "" {
+		return nil, fmt.Errorf("biscuit issuer requires signer_id")
+	}
+	signer, err := signerRegistry.Get(cfg.SignerID)
+	if err != nil {
+		return nil, fmt.Errorf("signer not found: %s", cfg.SignerID)
+	}
+
+	// Parse TTL
+	ttl := 5 * time.Minute // default
+	if cfg.TTL != "" {
+		duration, err := time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl: %w", err)
+		}
+		ttl = duration
+	}
+
+	// Create claim mappers
+	var mappers []service.ClaimMapper
+	for i, mapperCfg := range cfg.ClaimMappers {
+		m, err := newClaimMapper(mapperCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create claim mapper %d: %w", i, err)
+		}
+		mappers = append(mappers, m)
+	}
+
+	redaction, err := newRedaction(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := newAudienceProfiles(cfg.Profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicTTL, err := newDynamicTTL(cfg.TTLPolicy, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	idGenerator, err := newTokenIDGenerator(cfg.JTI, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return issuer.NewBiscuitIssuer(issuer.BiscuitIssuerConfig{
+		IssuerURL:    cfg.IssuerURL,
+		TokenType:    cfg.TokenType,
+		TTL:          ttl,
+		Signer:       signer,
+		ClaimMappers: mappers,
+		Redaction:    redaction,
+		Profiles:     profiles,
+		DynamicTTL:   dynamicTTL,
+		IDGenerator:  idGenerator,
+	}), nil
+}
+
+// newTokenIDGenerator creates the generator of token IDs (nil: the issuer's default)
+func newTokenIDGenerator(cfg *JTIConfig, identity *instance.Identity) (idgen.Generator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	var gen idgen.Generator
+	switch cfg.Generator {
+	case "", "uuid":
+		gen = idgen.NewUUIDGenerator()
+	case "uuidv7":
+		gen = idgen.NewUUIDv7Generator()
+	case "ulid":
+		gen = idgen.NewULIDGenerator(nil)
+	default:
+		return nil, fmt.Errorf("unknown jti generator: %s (supported: uuid, uuidv7, ulid)", cfg.Generator)
+	}
+
+	if cfg.InstancePrefix {
+		if identity == nil {
+			return nil, fmt.Errorf("jti instance_prefix requires an instance identity")
+		}
+		gen = idgen.NewPrefixedGenerator(identity.ID+".", gen)
+	}
+	return gen, nil
+}
+
+// parseNotBeforeSkew parses how far the nbf claim is backdated
+func parseNotBeforeSkew(skew string) (time.Duration, error) {
+	if skew == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(skew)
+	if err != nil {
+		return 0, fmt.Errorf("invalid not_before_skew: %w", err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("not_before_skew must not be negative: %s", skew)
+	}
+	return duration, nil
+}
+
+// newClaimMapper creates a claim mapper from configuration, traced under its name (or type)
 func newClaimMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
+	m, err := newUntracedClaimMapper(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = cfg.Type
+	}
+	m = probe.NewTracingClaimMapper(name, m)
+
+	if cfg.When == "" && cfg.Merge == "" {
+		return m, nil
+	}
+	strategy, err := claims.ParseMergeStrategy(cfg.Merge)
+	if err != nil {
+		return nil, err
+	}
+	return mapper.NewChainedMapper(m, cfg.When, strategy)
+}
+
+// newUntracedClaimMapper creates the claim mapper of the configured type
+func newUntracedClaimMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
 	switch cfg.Type {
 	case "cel":
 		return newCELMapper(cfg)
+	case "jmespath":
+		return newJMESPathMapper(cfg)
+	case "template":
+		return newTemplateMapper(cfg)
 	case "passthrough":
 		return service.NewPassthroughSubjectMapper(), nil
 	case "request_attributes":
-		return service.NewRequestAttributesMapper(), nil
+		return service.NewRequestAttributesMapper(cfg.Headers...), nil
+	case "hashed_request_attributes":
+		return newHashMapper(cfg)
+	case "wasm":
+		return newWASMMapper(cfg)
 	case "stub":
 		return newStubMapper(cfg)
 	default:
-		return nil, fmt.Errorf("unknown claim mapper type: %s (supported: cel, passthrough, request_attributes, stub)", cfg.Type)
+		return nil, fmt.Errorf("unknown claim mapper type: %s (supported: cel, jmespath, template, passthrough, request_attributes, hashed_request_attributes, wasm, stub)", cfg.Type)
 	}
 }
 
@@ -413,6 +1493,60 @@ func newCELMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
 	return mapper.NewCELMapper(script)
 }
 
+// newJMESPathMapper creates a JMESPath-based claim mapper
+func newJMESPathMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
+	expression := cfg.Expression
+	if cfg.ExpressionFile != "" {
+		content, err := os.ReadFile(cfg.ExpressionFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read expression file %s: %w", cfg.ExpressionFile, err)
+		}
+		expression = string(content)
+	}
+
+	if expression == "" {
+		return nil, fmt.Errorf("jmespath mapper requires expression or expression_file")
+	}
+
+	return mapper.NewJMESPathMapper(expression, cfg.DataSources)
+}
+
+// newTemplateMapper creates a mapper that renders claims from Go templates
+func newTemplateMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
+	if cfg.Claims == nil {
+		return nil, fmt.Errorf("template mapper requires claims")
+	}
+	return mapper.NewTemplateMapper(cfg.Claims)
+}
+
+// newHashMapper creates a mapper that emits salted hashes of request attributes
+func newHashMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
+	secret := []byte(cfg.Secret)
+	if cfg.SecretFile != "" {
+		content, err := os.ReadFile(cfg.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret file %s: %w", cfg.SecretFile, err)
+		}
+		secret = bytes.TrimSpace(content)
+	}
+
+	var rotationPeriod time.Duration
+	if cfg.RotationPeriod != "" {
+		duration, err := time.ParseDuration(cfg.RotationPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rotation_period: %w", err)
+		}
+		rotationPeriod = duration
+	}
+
+	return mapper.NewRequestAttributeHashMapper(mapper.RequestAttributeHashMapperConfig{
+		Attributes:     cfg.Attributes,
+		Secret:         secret,
+		RotationPeriod: rotationPeriod,
+		Claim:          cfg.Claim,
+	})
+}
+
 // newStubMapper creates a stub claim mapper that returns fixed claims
 func newStubMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
 	if cfg.Claims == nil {
@@ -0,0 +1,772 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+func TestNewIssuerRegistry_TokenPolicyMaxTTL(t *testing.T) {
+	const txnToken = "urn:ietf:params:oauth:token-type:txn_token"
+
+	newConfig := func(issuerTTL string, maxTTLs ...MaxTTLConfig) Config {
+		return Config{
+			TrustDomain: "example.com",
+			Issuers: []IssuerConfig{
+				{TokenType: txnToken, Type: "stub", IssuerURL: "https://parsec.example.com", TTL: issuerTTL},
+				{TokenType: "urn:redhat:params:oauth:token-type:rh-identity", Type: "rh_identity"},
+			},
+			TokenPolicy: &TokenPolicyConfig{MaxTTLs: maxTTLs},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{
+			name: "issuer within max ttl",
+			cfg:  newConfig("15m", MaxTTLConfig{TokenType: txnToken, MaxTTL: "15m"}),
+		},
+		{
+			name:    "issuer exceeds max ttl",
+			cfg:     newConfig("168h", MaxTTLConfig{TokenType: txnToken, MaxTTL: "15m"}),
+			wantErr: "exceeds token_policy max_ttl",
+		},
+		{
+			name:    "issuer tokens do not expire",
+			cfg:     newConfig("5m", MaxTTLConfig{TokenType: "urn:redhat:params:oauth:token-type:rh-identity", MaxTTL: "1h"}),
+			wantErr: "do not expire",
+		},
+		{
+			name:    "no issuer for token type",
+			cfg:     newConfig("5m", MaxTTLConfig{TokenType: "urn:example:unknown", MaxTTL: "1h"}),
+			wantErr: "no issuer handles it",
+		},
+		{
+			name:    "invalid max ttl",
+			cfg:     newConfig("5m", MaxTTLConfig{TokenType: txnToken, MaxTTL: "soon"}),
+			wantErr: "invalid token_policy max_ttl",
+		},
+		{
+			name:    "duplicate token type",
+			cfg:     newConfig("5m", MaxTTLConfig{TokenType: txnToken, MaxTTL: "1h"}, MaxTTLConfig{TokenType: txnToken, MaxTTL: "2h"}),
+			wantErr: "duplicate token_policy max_ttl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIssuerRegistry(tt.cfg, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBuildSignerRegistry_AlgorithmMigration(t *testing.T) {
+	providers := map[string]keys.KeyProvider{
+		"rsa": keys.NewInMemoryKeyProvider(keys.KeyTypeRSA2048, "RS256"),
+		"ec":  keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256"),
+	}
+
+	newConfigs := func(migration SignerConfig) []SignerConfig {
+		migration.ID = "txn-migration"
+		migration.Type = "algorithm_migration"
+		return []SignerConfig{
+			// Listed first to show migrations may reference signers defined after them
+			migration,
+			{ID: "txn-rsa", Type: "dual_slot", KeyProviderID: "rsa"},
+			{ID: "txn-ec", Type: "dual_slot", KeyProviderID: "ec"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		migration SignerConfig
+		wantErr   string
+	}{
+		{
+			name:      "valid migration",
+			migration: SignerConfig{FromSignerID: "txn-rsa", ToSignerID: "txn-ec", CutoverAt: "2025-06-01T00:00:00Z", RetireAfter: "48h"},
+		},
+		{
+			name:      "missing signer ids",
+			migration: SignerConfig{FromSignerID: "txn-rsa", CutoverAt: "2025-06-01T00:00:00Z"},
+			wantErr:   "requires from_signer_id and to_signer_id",
+		},
+		{
+			name:      "unknown signer",
+			migration: SignerConfig{FromSignerID: "txn-rsa", ToSignerID: "txn-ed25519", CutoverAt: "2025-06-01T00:00:00Z"},
+			wantErr:   "txn-ed25519",
+		},
+		{
+			name:      "missing cutover",
+			migration: SignerConfig{FromSignerID: "txn-rsa", ToSignerID: "txn-ec"},
+			wantErr:   "requires cutover_at",
+		},
+		{
+			name:      "invalid cutover",
+			migration: SignerConfig{FromSignerID: "txn-rsa", ToSignerID: "txn-ec", CutoverAt: "tomorrow"},
+			wantErr:   "invalid cutover_at",
+		},
+		{
+			name:      "invalid retire after",
+			migration: SignerConfig{FromSignerID: "txn-rsa", ToSignerID: "txn-ec", CutoverAt: "2025-06-01T00:00:00Z", RetireAfter: "later"},
+			wantErr:   "invalid retire_after",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := buildSignerRegistry(newConfigs(tt.migration), "example.com", providers, keys.NewInMemoryKeySlotStore(), false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			signer, err := registry.Get("txn-migration")
+			if err != nil {
+				t.Fatalf("migration signer not registered: %v", err)
+			}
+			if _, ok := signer.(keys.AlgorithmMigrator); !ok {
+				t.Errorf("expected migration signer to report migration status, got %T", signer)
+			}
+		})
+	}
+}
+
+func TestBuildSignerRegistry_RotationTimings(t *testing.T) {
+	providers := map[string]keys.KeyProvider{
+		"ec": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256"),
+	}
+
+	tests := []struct {
+		name    string
+		signer  SignerConfig
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			signer: SignerConfig{},
+		},
+		{
+			name:   "custom timings",
+			signer: SignerConfig{KeyTTL: "168h", RotationThreshold: "48h", GracePeriod: "24h", CheckInterval: "5m"},
+		},
+		{
+			name:    "threshold not less than ttl",
+			signer:  SignerConfig{KeyTTL: "1h"},
+			wantErr: "rotation_threshold (6h0m0s) must be less than key_ttl (1h0m0s)",
+		},
+		{
+			name:    "grace period not less than threshold",
+			signer:  SignerConfig{RotationThreshold: "2h"},
+			wantErr: "grace_period (2h0m0s) must be less than rotation_threshold (2h0m0s)",
+		},
+		{
+			name:    "check interval too long to rotate in time",
+			signer:  SignerConfig{CheckInterval: "4h"},
+			wantErr: "check_interval (4h0m0s) must be less than rotation_threshold minus grace_period (4h0m0s)",
+		},
+		{
+			name:    "negative duration",
+			signer:  SignerConfig{GracePeriod: "-1h"},
+			wantErr: "must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := tt.signer
+			signer.ID = "txn"
+			signer.Type = "dual_slot"
+			signer.KeyProviderID = "ec"
+
+			_, err := buildSignerRegistry([]SignerConfig{signer}, "example.com", providers, keys.NewInMemoryKeySlotStore(), false)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_Opaque(t *testing.T) {
+	const accessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	tests := []struct {
+		name       string
+		issuer     IssuerConfig
+		tokenStore *TokenStoreConfig
+		wantErr    string
+	}{
+		{
+			name:   "in-memory token store",
+			issuer: IssuerConfig{TokenType: accessToken, Type: "opaque", IssuerURL: "https://parsec.example.com", TTL: "10m"},
+		},
+		{
+			name:    "missing issuer url",
+			issuer:  IssuerConfig{TokenType: accessToken, Type: "opaque"},
+			wantErr: "opaque issuer requires issuer_url",
+		},
+		{
+			name:       "redis token store without address",
+			issuer:     IssuerConfig{TokenType: accessToken, Type: "opaque", IssuerURL: "https://parsec.example.com"},
+			tokenStore: &TokenStoreConfig{Type: "redis"},
+			wantErr:    "redis token store requires address",
+		},
+		{
+			name:       "unknown token store",
+			issuer:     IssuerConfig{TokenType: accessToken, Type: "opaque", IssuerURL: "https://parsec.example.com"},
+			tokenStore: &TokenStoreConfig{Type: "dynamodb"},
+			wantErr:    "unknown token store type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				TrustDomain: "example.com",
+				Issuers:     []IssuerConfig{tt.issuer},
+				TokenStore:  tt.tokenStore,
+			}
+			registry, err := NewIssuerRegistry(cfg, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			iss, err := registry.GetIssuer(accessToken)
+			if err != nil {
+				t.Fatalf("issuer not registered: %v", err)
+			}
+			if _, ok := iss.(*issuer.OpaqueIssuer); !ok {
+				t.Errorf("expected an opaque issuer, got %T", iss)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_TransactionTokenFormat(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	signers := keys.NewSignerRegistry()
+	if err := signers.Register("txn", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		format     string
+		wantFormat service.TokenFormat
+		wantErr    string
+	}{
+		{name: "default", wantFormat: service.TokenFormatJWT},
+		{name: "jwt", format: "jwt", wantFormat: service.TokenFormatJWT},
+		{name: "cwt", format: "cwt", wantFormat: service.TokenFormatCWT},
+		{name: "unknown", format: "paseto", wantErr: "unknown transaction_token format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				TrustDomain: "example.com",
+				Issuers: []IssuerConfig{{
+					TokenType: string(service.TokenTypeTransactionToken),
+					Type:      "transaction_token",
+					IssuerURL: "https://parsec.example.com",
+					SignerID:  "txn",
+					Format:    tt.format,
+				}},
+			}
+			registry, err := NewIssuerRegistryWithSigners(cfg, signers, nil, nil, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			iss, err := registry.GetIssuer(service.TokenTypeTransactionToken)
+			if err != nil {
+				t.Fatalf("issuer not registered: %v", err)
+			}
+			if format := iss.(service.DescribableIssuer).Describe().Format; format != tt.wantFormat {
+				t.Errorf("expected format %s, got %s", tt.wantFormat, format)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_TransactionTokenEncryption(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	signers := keys.NewSignerRegistry()
+	if err := signers.Register("txn", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+
+	const jwks = `{"keys":[{"kty":"EC","crv":"P-256","kid":"orders-enc","use":"enc",` +
+		`"x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU","y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}]}`
+
+	tests := []struct {
+		name       string
+		format     string
+		encryption TokenEncryptionConfig
+		wantErr    string
+	}{
+		{
+			name: "inline jwks",
+			encryption: TokenEncryptionConfig{
+				KeyAlgorithm: "ECDH-ES",
+				Recipients:   []EncryptionRecipientConfig{{Audience: "orders.example.com", JWKS: jwks}},
+			},
+		},
+		{
+			name:   "cwt format",
+			format: "cwt",
+			encryption: TokenEncryptionConfig{
+				Recipients: []EncryptionRecipientConfig{{Audience: "orders.example.com", JWKS: jwks}},
+			},
+			wantErr: "encryption requires jwt format",
+		},
+		{
+			name: "unknown key algorithm",
+			encryption: TokenEncryptionConfig{
+				KeyAlgorithm: "RSA1_5X",
+				Recipients:   []EncryptionRecipientConfig{{Audience: "orders.example.com", JWKS: jwks}},
+			},
+			wantErr: "unknown key_algorithm",
+		},
+		{
+			name:       "no recipients",
+			encryption: TokenEncryptionConfig{},
+			wantErr:    "at least one recipient",
+		},
+		{
+			name: "recipient without keys",
+			encryption: TokenEncryptionConfig{
+				Recipients: []EncryptionRecipientConfig{{Audience: "orders.example.com"}},
+			},
+			wantErr: "requires jwks_url or jwks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryption := tt.encryption
+			cfg := Config{
+				TrustDomain: "example.com",
+				Issuers: []IssuerConfig{{
+					TokenType:  string(service.TokenTypeTransactionToken),
+					Type:       "transaction_token",
+					IssuerURL:  "https://parsec.example.com",
+					SignerID:   "txn",
+					Format:     tt.format,
+					Encryption: &encryption,
+				}},
+			}
+			_, err := NewIssuerRegistryWithSigners(cfg, signers, nil, nil, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_Biscuit(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	signers := keys.NewSignerRegistry()
+	if err := signers.Register("root", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		signerID string
+		wantErr  string
+	}{
+		{name: "signer", signerID: "root"},
+		{name: "missing signer_id", wantErr: "requires signer_id"},
+		{name: "unknown signer", signerID: "nope", wantErr: "signer not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				TrustDomain: "example.com",
+				Issuers: []IssuerConfig{{
+					TokenType: string(service.TokenTypeBiscuit),
+					Type:      "biscuit",
+					SignerID:  tt.signerID,
+				}},
+			}
+			registry, err := NewIssuerRegistryWithSigners(cfg, signers, nil, nil, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			iss, err := registry.GetIssuer(service.TokenTypeBiscuit)
+			if err != nil {
+				t.Fatalf("issuer not registered: %v", err)
+			}
+			if format := iss.(service.DescribableIssuer).Describe().Format; format != service.TokenFormatBiscuit {
+				t.Errorf("expected format %s, got %s", service.TokenFormatBiscuit, format)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_TransactionTokenTxnID(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	signers := keys.NewSignerRegistry()
+	if err := signers.Register("txn", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		txnID   TxnIDConfig
+		wantErr string
+	}{
+		{name: "uuid", txnID: TxnIDConfig{Generator: "uuid"}},
+		{name: "uuidv7", txnID: TxnIDConfig{Generator: "uuidv7"}},
+		{name: "ulid with propagation", txnID: TxnIDConfig{Generator: "ulid", PropagateFrom: "traceparent"}},
+		{name: "unknown generator", txnID: TxnIDConfig{Generator: "snowflake"}, wantErr: "unknown txn_id generator"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txnID := tt.txnID
+			cfg := Config{
+				TrustDomain: "example.com",
+				Issuers: []IssuerConfig{{
+					TokenType: string(service.TokenTypeTransactionToken),
+					Type:      "transaction_token",
+					IssuerURL: "https://parsec.example.com",
+					SignerID:  "txn",
+					TxnID:     &txnID,
+				}},
+			}
+			_, err := NewIssuerRegistryWithSigners(cfg, signers, nil, nil, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_JTI(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	signers := keys.NewSignerRegistry()
+	if err := signers.Register("txn", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+	identity := &instance.Identity{ID: "instance-1"}
+
+	tests := []struct {
+		name          string
+		jti           JTIConfig
+		notBeforeSkew string
+		identity      *instance.Identity
+		wantErr       string
+	}{
+		{name: "uuidv7 with instance prefix", jti: JTIConfig{Generator: "uuidv7", InstancePrefix: true}, notBeforeSkew: "30s", identity: identity},
+		{name: "ulid", jti: JTIConfig{Generator: "ulid"}},
+		{name: "unknown generator", jti: JTIConfig{Generator: "snowflake"}, wantErr: "unknown jti generator"},
+		{name: "instance prefix without identity", jti: JTIConfig{InstancePrefix: true}, wantErr: "requires an instance identity"},
+		{name: "negative skew", notBeforeSkew: "-30s", wantErr: "must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jti := tt.jti
+			cfg := Config{
+				TrustDomain: "example.com",
+				Issuers: []IssuerConfig{{
+					TokenType:     string(service.TokenTypeTransactionToken),
+					Type:          "transaction_token",
+					IssuerURL:     "https://parsec.example.com",
+					SignerID:      "txn",
+					JTI:           &jti,
+					NotBeforeSkew: tt.notBeforeSkew,
+				}},
+			}
+			_, err := NewIssuerRegistryWithSigners(cfg, signers, nil, tt.identity, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewTrustDomain(t *testing.T) {
+	unsigned := IssuerConfig{TokenType: "urn:example:unsigned", Type: "unsigned"}
+
+	tests := []struct {
+		name    string
+		domain  TrustDomainConfig
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			domain: TrustDomainConfig{Name: "partners.example.com", Audiences: []string{"*.partners.example.com"}, Issuers: []IssuerConfig{unsigned}},
+		},
+		{
+			name:    "missing name",
+			domain:  TrustDomainConfig{Issuers: []IssuerConfig{unsigned}},
+			wantErr: "trust domain name is required",
+		},
+		{
+			name:    "same as the trust domain",
+			domain:  TrustDomainConfig{Name: "example.com", Issuers: []IssuerConfig{unsigned}},
+			wantErr: "is already the trust_domain",
+		},
+		{
+			name:    "invalid wildcard",
+			domain:  TrustDomainConfig{Name: "partners.example.com", Audiences: []string{"api.*.example.com"}, Issuers: []IssuerConfig{unsigned}},
+			wantErr: "invalid audience",
+		},
+		{
+			name:    "no issuers",
+			domain:  TrustDomainConfig{Name: "partners.example.com"},
+			wantErr: "at least one issuer is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{TrustDomain: "example.com", TrustDomains: []TrustDomainConfig{tt.domain}}
+			domain, err := NewTrustDomain(cfg, tt.domain, keys.NewSignerRegistry(), nil, nil, false)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := domain.Issuers.GetIssuer("urn:example:unsigned"); err != nil {
+				t.Errorf("issuer not registered: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_Audiences(t *testing.T) {
+	const tokenType = "urn:example:unsigned"
+
+	tests := []struct {
+		name    string
+		issuers []IssuerConfig
+		wantErr string
+	}{
+		{
+			name: "issuers for some audiences and for any",
+			issuers: []IssuerConfig{
+				{TokenType: tokenType, Type: "unsigned", Audiences: []string{"billing.example.com"}},
+				{TokenType: tokenType, Type: "unsigned"},
+			},
+		},
+		{
+			name: "overlapping audiences",
+			issuers: []IssuerConfig{
+				{TokenType: tokenType, Type: "unsigned", Audiences: []string{"billing.example.com"}},
+				{TokenType: tokenType, Type: "unsigned", Audiences: []string{"orders.example.com", "billing.example.com"}},
+			},
+			wantErr: "more than one issuer for token type urn:example:unsigned and audience billing.example.com",
+		},
+		{
+			name: "two issuers for any audience",
+			issuers: []IssuerConfig{
+				{TokenType: tokenType, Type: "unsigned"},
+				{TokenType: tokenType, Type: "unsigned"},
+			},
+			wantErr: "more than one issuer for token type urn:example:unsigned without audiences",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := NewIssuerRegistry(Config{TrustDomain: "example.com", Issuers: tt.issuers}, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			billing, err := registry.GetIssuerFor(tokenType, []string{"billing.example.com"})
+			if err != nil {
+				t.Fatalf("no issuer for billing.example.com: %v", err)
+			}
+			general, err := registry.GetIssuerFor(tokenType, []string{"orders.example.com"})
+			if err != nil {
+				t.Fatalf("no issuer for orders.example.com: %v", err)
+			}
+			if billing == general {
+				t.Error("expected billing.example.com to have its own issuer")
+			}
+		})
+	}
+}
+
+func TestNewIssuerRegistry_TTLPolicy(t *testing.T) {
+	const accessToken = "urn:ietf:params:oauth:token-type:access_token"
+	expression := `"admin" in scopes ? duration("1m") : null`
+
+	tests := []struct {
+		name        string
+		ttl         string
+		policy      *TTLPolicyConfig
+		tokenPolicy *TokenPolicyConfig
+		wantErr     string
+	}{
+		{
+			name:   "valid",
+			policy: &TTLPolicyConfig{Expression: expression, MinTTL: "30s", MaxTTL: "1h"},
+		},
+		{
+			name:    "missing max_ttl",
+			policy:  &TTLPolicyConfig{Expression: expression},
+			wantErr: "ttl_policy requires max_ttl",
+		},
+		{
+			name:    "ttl out of bounds",
+			ttl:     "2h",
+			policy:  &TTLPolicyConfig{Expression: expression, MaxTTL: "1h"},
+			wantErr: "ttl 2h0m0s must be between ttl_policy min_ttl 0s and max_ttl 1h0m0s",
+		},
+		{
+			name:    "invalid expression",
+			policy:  &TTLPolicyConfig{Expression: `"1m"`, MaxTTL: "1h"},
+			wantErr: "invalid ttl_policy",
+		},
+		{
+			name:        "max_ttl exceeds token policy",
+			policy:      &TTLPolicyConfig{Expression: expression, MaxTTL: "1h"},
+			tokenPolicy: &TokenPolicyConfig{MaxTTLs: []MaxTTLConfig{{TokenType: accessToken, MaxTTL: "30m"}}},
+			wantErr:     "exceeds token_policy max_ttl",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				TrustDomain: "example.com",
+				Issuers: []IssuerConfig{{
+					TokenType: accessToken,
+					Type:      "opaque",
+					IssuerURL: "https://parsec.example.com",
+					TTL:       tt.ttl,
+					TTLPolicy: tt.policy,
+				}},
+				TokenPolicy: tt.tokenPolicy,
+			}
+			_, err := NewIssuerRegistry(cfg, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
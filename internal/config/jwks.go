@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/alechenninger/parsec/internal/jwkspub"
+)
+
+// NewJWKSPublisher creates a JWKS publisher from configuration
+// Returns nil if no publishers are configured
+func NewJWKSPublisher(cfg *JWKSConfig, transport http.RoundTripper) (jwkspub.Publisher, error) {
+	if cfg == nil || len(cfg.Publishers) == 0 {
+		return nil, nil
+	}
+
+	var publishers []jwkspub.Publisher
+	for i, pubCfg := range cfg.Publishers {
+		publisher, err := newJWKSPublisher(pubCfg, transport)
+		if err != nil {
+			return nil, fmt.Errorf("jwks publisher %d: %w", i, err)
+		}
+		publishers = append(publishers, publisher)
+	}
+
+	if len(publishers) == 1 {
+		return publishers[0], nil
+	}
+	return jwkspub.NewMultiPublisher(publishers...), nil
+}
+
+func newJWKSPublisher(cfg JWKSPublisherConfig, transport http.RoundTripper) (jwkspub.Publisher, error) {
+	switch cfg.Type {
+	case "s3":
+		return jwkspub.NewS3Publisher(context.Background(), jwkspub.S3PublisherConfig{
+			Bucket:       cfg.Bucket,
+			Key:          cfg.Key,
+			Region:       cfg.Region,
+			Endpoint:     cfg.Endpoint,
+			UsePathStyle: cfg.UsePathStyle,
+			CacheControl: cfg.CacheControl,
+		})
+
+	case "gcs":
+		// GCS is published through its S3-compatible XML API using HMAC keys,
+		// supplied through the usual AWS credential chain
+		region := cfg.Region
+		if region == "" {
+			region = "auto"
+		}
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = jwkspub.GCSEndpoint
+		}
+		return jwkspub.NewS3Publisher(context.Background(), jwkspub.S3PublisherConfig{
+			Bucket:       cfg.Bucket,
+			Key:          cfg.Key,
+			Region:       region,
+			Endpoint:     endpoint,
+			UsePathStyle: cfg.UsePathStyle,
+			CacheControl: cfg.CacheControl,
+		})
+
+	case "webhook":
+		var client *http.Client
+		if transport != nil {
+			client = &http.Client{Transport: transport}
+		}
+		return jwkspub.NewWebhookPublisher(jwkspub.WebhookPublisherConfig{
+			URL:          cfg.URL,
+			Method:       cfg.Method,
+			Headers:      cfg.Headers,
+			CacheControl: cfg.CacheControl,
+			HTTPClient:   client,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown jwks publisher type: %s (supported: s3, gcs, webhook)", cfg.Type)
+	}
+}
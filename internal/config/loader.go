@@ -3,15 +3,18 @@ package config
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/knadh/koanf/parsers/json"
 	"github.com/knadh/koanf/parsers/toml/v2"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/confmap"
 	"github.com/knadh/koanf/providers/env"
-	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/posflag"
 	"github.com/knadh/koanf/v2"
 	"github.com/spf13/pflag"
@@ -21,7 +24,12 @@ import (
 // from files and environment variables
 type Loader struct {
 	k          *koanf.Koanf
+	fileKeys   map[string]any
+	files      []string
 	configPath string
+	flags      *pflag.FlagSet
+
+	pollInterval time.Duration
 }
 
 // NewLoader creates a new configuration loader that reads from a file
@@ -63,6 +71,35 @@ func getDefaults() map[string]interface{} {
 
 // newLoader is the internal loader implementation
 func newLoader(configPath string, flags *pflag.FlagSet) (*Loader, error) {
+	loaded, err := load(configPath, flags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Loader{
+		k:          loaded.k,
+		fileKeys:   loaded.fileKeys,
+		files:      loaded.files,
+		configPath: configPath,
+		flags:      flags,
+
+		pollInterval: DefaultPollInterval,
+	}, nil
+}
+
+// loaded is configuration loaded from every source
+type loaded struct {
+	k *koanf.Koanf
+
+	// fileKeys are the keys of the config files alone, to check for unknown keys
+	fileKeys map[string]any
+
+	// files are the config file and every file it includes
+	files []string
+}
+
+// load loads the configuration from every source, in order of precedence
+func load(configPath string, flags *pflag.FlagSet) (*loaded, error) {
 	k := koanf.New(".")
 
 	// Load defaults (lowest precedence)
@@ -70,25 +107,106 @@ func newLoader(configPath string, flags *pflag.FlagSet) (*Loader, error) {
 		return nil, fmt.Errorf("failed to load defaults: %w", err)
 	}
 
-	// Load from file if provided
+	// Load from file, and the files it includes, if provided
+	var fk *koanf.Koanf
+	var files []string
 	if configPath != "" {
-		// Auto-detect parser based on file extension
-		parser, err := getParserForFile(configPath)
+		var err error
+		fk, files, err = loadFile(configPath, nil)
 		if err != nil {
 			return nil, err
 		}
-
-		// Load from file
-		if err := k.Load(file.Provider(configPath), parser); err != nil {
+		if err := k.Merge(fk); err != nil {
 			return nil, fmt.Errorf("failed to load config file %s: %w", configPath, err)
 		}
 	}
 
+	if err := loadOverrides(k, flags); err != nil {
+		return nil, err
+	}
+
+	// The overlay may be selected by any source, but patches only the config file:
+	// environment variables and flags still take precedence over it
+	if overlay := k.String("overlay"); overlay != "" {
+		if fk == nil || !fk.Exists("overlays."+overlay) {
+			return nil, fmt.Errorf("unknown config overlay: %s", overlay)
+		}
+		if err := k.Merge(fk.Cut("overlays." + overlay)); err != nil {
+			return nil, fmt.Errorf("failed to apply config overlay %s: %w", overlay, err)
+		}
+		if err := loadOverrides(k, flags); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &loaded{k: k, files: files}
+	if fk != nil {
+		result.fileKeys = fk.Raw()
+	}
+	return result, nil
+}
+
+// loadFile loads the config file at location over the files it includes, in order, so
+// the file's own options take precedence. Included locations are relative to the
+// including file. including are the files that include this one, to detect cycles.
+func loadFile(location string, including []string) (*koanf.Koanf, []string, error) {
+	id := location
+	if !isRemote(location) {
+		absPath, err := filepath.Abs(location)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve config file %s: %w", location, err)
+		}
+		id = absPath
+	}
+	if slices.Contains(including, id) {
+		return nil, nil, fmt.Errorf("config file %s includes itself", location)
+	}
+
+	// Auto-detect parser based on file extension
+	parser, err := getParserForFile(locationPath(location))
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := context.Background()
+	source, err := openSource(ctx, location)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := source.read(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config file %s: %w", location, err)
+	}
+	fk := koanf.New(".")
+	if err := fk.Load(bytesProvider(data), parser); err != nil {
+		return nil, nil, fmt.Errorf("failed to load config file %s: %w", location, err)
+	}
+
+	k := koanf.New(".")
+	files := []string{location}
+	for _, include := range fk.Strings("include") {
+		include = resolveInclude(location, include)
+		ik, includedFiles, err := loadFile(include, append(including, id))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := k.Merge(ik); err != nil {
+			return nil, nil, fmt.Errorf("failed to include config file %s: %w", include, err)
+		}
+		files = append(files, includedFiles...)
+	}
+	if err := k.Merge(fk); err != nil {
+		return nil, nil, fmt.Errorf("failed to load config file %s: %w", location, err)
+	}
+	return k, files, nil
+}
+
+// loadOverrides loads environment variables and command-line flags over k
+func loadOverrides(k *koanf.Koanf, flags *pflag.FlagSet) error {
 	// Load environment variable overrides with PARSEC_ prefix
 	// Use double underscore (__) for nesting: PARSEC_SERVER__GRPC_PORT -> server.grpc_port
 	// Single underscore is part of the field name: PARSEC_TRUST_DOMAIN -> trust_domain
 	if err := k.Load(env.Provider("PARSEC_", ".", envTransform), nil); err != nil {
-		return nil, fmt.Errorf("failed to load environment variables: %w", err)
+		return fmt.Errorf("failed to load environment variables: %w", err)
 	}
 
 	// Load command-line flags (highest precedence)
@@ -111,27 +229,53 @@ func newLoader(configPath string, flags *pflag.FlagSet) (*Loader, error) {
 
 			return configKey, posflag.FlagVal(flags, f)
 		}), nil); err != nil {
-			return nil, fmt.Errorf("failed to load command-line flags: %w", err)
+			return fmt.Errorf("failed to load command-line flags: %w", err)
 		}
 	}
 
-	return &Loader{
-		k:          k,
-		configPath: configPath,
-	}, nil
+	return nil
+}
+
+// SetPollInterval sets how often Watch checks remote config files without change
+// notifications, S3 objects and HTTP URLs, for changes (default: DefaultPollInterval)
+func (l *Loader) SetPollInterval(interval time.Duration) {
+	l.pollInterval = interval
+}
+
+// Path returns the path or URL of the config file, or "" if none is loaded
+func (l *Loader) Path() string {
+	return l.configPath
 }
 
-// Get unmarshals the configuration into a Config struct
+// Get unmarshals the configuration into a Config struct, validates it, and resolves
+// its secret references. If the configuration is invalid, the error is a
+// *ValidationError listing every problem, including keys in the config file that are
+// not configuration options and secrets that cannot be read.
 func (l *Loader) Get() (*Config, error) {
+	return parse(l.k, l.fileKeys)
+}
+
+// parse unmarshals, validates, and resolves the secrets of the configuration in k
+func parse(k *koanf.Koanf, fileKeys map[string]any) (*Config, error) {
 	var cfg Config
-	if err := l.k.Unmarshal("", &cfg); err != nil {
+	if err := k.Unmarshal("", &cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if err := validate(&cfg, fileKeys); err != nil {
+		return nil, err
+	}
+	if err := resolveSecrets(context.Background(), &cfg); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
 
 // Watch watches the config file for changes and calls onChange with the new config.
-// This runs until the context is cancelled or an error occurs.
+// Local files are watched for changes and ConfigMaps through the Kubernetes API; S3
+// objects and HTTP URLs are polled.
+// The new config is loaded from every source, so defaults, environment variables, and
+// flags apply as they did at startup. This runs until the context is cancelled or an
+// error occurs.
 //
 // Note: Not all components can be safely hot-reloaded. Use with caution in production.
 // If no config file is configured, this will block until context is cancelled.
@@ -142,53 +286,53 @@ func (l *Loader) Watch(ctx context.Context, onChange func(*Config) error) error
 		return ctx.Err()
 	}
 
-	// Use file provider with watch enabled
-	fp := file.Provider(l.configPath)
-
-	// Set up file watcher
-	if err := fp.Watch(func(event interface{}, err error) {
+	// Reloads are serialized: several files may change at once
+	var mu sync.Mutex
+	reload := func(err error) {
 		if err != nil {
 			// Log error but continue watching
-			fmt.Printf("config watch error: %v\n", err)
+			slog.Warn("Failed to watch config file", "error", err)
 			return
 		}
 
+		mu.Lock()
+		defer mu.Unlock()
+
 		// Reload the config
-		parser, err := getParserForFile(l.configPath)
+		loaded, err := load(l.configPath, l.flags)
 		if err != nil {
-			fmt.Printf("config parser error: %v\n", err)
-			return
-		}
-
-		// Create new koanf instance for reload
-		k := koanf.New(".")
-		if err := k.Load(fp, parser); err != nil {
-			fmt.Printf("config reload error: %v\n", err)
-			return
-		}
-
-		// Reload env vars
-		if err := k.Load(env.Provider("PARSEC_", ".", envTransform), nil); err != nil {
-			fmt.Printf("env reload error: %v\n", err)
+			slog.Error("Failed to reload config", "error", err)
 			return
 		}
 
 		// Unmarshal new config
-		var cfg Config
-		if err := k.Unmarshal("", &cfg); err != nil {
-			fmt.Printf("config unmarshal error: %v\n", err)
+		cfg, err := parse(loaded.k, loaded.fileKeys)
+		if err != nil {
+			slog.Error("Failed to reload config", "error", err)
 			return
 		}
 
 		// Update loader's koanf instance
-		l.k = k
+		l.k = loaded.k
+		l.fileKeys = loaded.fileKeys
 
 		// Call onChange callback
-		if err := onChange(&cfg); err != nil {
-			fmt.Printf("config onChange error: %v\n", err)
+		if err := onChange(cfg); err != nil {
+			slog.Error("Failed to apply reloaded config", "error", err)
+		}
+	}
+
+	// Watch the config file and the files it included at startup
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for _, location := range l.files {
+		source, err := openSource(ctx, location)
+		if err != nil {
+			return err
+		}
+		if err := source.watch(ctx, l.pollInterval, reload); err != nil {
+			return fmt.Errorf("failed to watch config file %s: %w", location, err)
 		}
-	}); err != nil {
-		return fmt.Errorf("failed to watch config file: %w", err)
 	}
 
 	// Block until context is cancelled
@@ -7,12 +7,15 @@ import (
 	"os"
 	"strings"
 
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/logging"
 	"github.com/alechenninger/parsec/internal/probe"
 	"github.com/alechenninger/parsec/internal/service"
 )
 
 // NewObserver creates an application observer from configuration
-func NewObserver(cfg *ObservabilityConfig) (service.ApplicationObserver, error) {
+// If identity is set, logged events identify the instance that produced them
+func NewObserver(cfg *ObservabilityConfig, identity *instance.Identity) (service.ApplicationObserver, error) {
 	if cfg == nil {
 		// Default to no-op observer if not configured
 		return &service.NoOpApplicationObserver{}, nil
@@ -20,41 +23,53 @@ func NewObserver(cfg *ObservabilityConfig) (service.ApplicationObserver, error)
 
 	switch cfg.Type {
 	case "logging":
-		return newLoggingObserver(cfg)
+		return newLoggingObserver(cfg, identity)
 	case "noop", "":
 		return &service.NoOpApplicationObserver{}, nil
 	case "composite":
-		return newCompositeObserver(cfg)
+		return newCompositeObserver(cfg, identity)
 	default:
 		return nil, fmt.Errorf("unknown observability type: %s (supported: logging, noop, composite)", cfg.Type)
 	}
 }
 
 // newLoggingObserver creates a logging observer
-func newLoggingObserver(cfg *ObservabilityConfig) (service.ApplicationObserver, error) {
+func newLoggingObserver(cfg *ObservabilityConfig, identity *instance.Identity) (service.ApplicationObserver, error) {
 	// Parse default log level
 	defaultLevel := parseLogLevel(cfg.LogLevel)
 
 	// Create handler with event-based filtering
 	handler := createEventFilteringHandler(cfg, defaultLevel)
 
-	// Create logger
-	logger := slog.New(handler)
+	// Create logger, logging the fields of the request each event is for
+	logger := slog.New(logging.NewContextHandler(handler))
 
 	return probe.NewLoggingObserverWithConfig(probe.LoggingObserverConfig{
-		Logger: logger,
+		Logger:   logger,
+		Instance: identity,
 	}), nil
 }
 
+// NewLogger creates the logger for components' own messages, like failed background
+// refreshes, at the configured level and format. Records logged while serving a request
+// carry its fields (see package logging). Without configuration, messages at info and
+// above are logged as text.
+func NewLogger(cfg *ObservabilityConfig) *slog.Logger {
+	if cfg == nil {
+		return slog.New(logging.NewContextHandler(createHandler("text", slog.LevelInfo)))
+	}
+	return slog.New(logging.NewContextHandler(createHandler(cfg.LogFormat, parseLogLevel(cfg.LogLevel))))
+}
+
 // newCompositeObserver creates a composite observer that delegates to multiple observers
-func newCompositeObserver(cfg *ObservabilityConfig) (service.ApplicationObserver, error) {
+func newCompositeObserver(cfg *ObservabilityConfig, identity *instance.Identity) (service.ApplicationObserver, error) {
 	if len(cfg.Observers) == 0 {
 		return nil, fmt.Errorf("composite observer requires at least one sub-observer")
 	}
 
 	var observers []service.ApplicationObserver
 	for i, subCfg := range cfg.Observers {
-		observer, err := NewObserver(&subCfg)
+		observer, err := NewObserver(&subCfg, identity)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create observer %d: %w", i, err)
 		}
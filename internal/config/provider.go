@@ -1,12 +1,33 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/alechenninger/parsec/internal/audit"
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/datasource"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/events"
 	"github.com/alechenninger/parsec/internal/httpfixture"
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/probe"
+	"github.com/alechenninger/parsec/internal/reexchange"
+	"github.com/alechenninger/parsec/internal/scope"
 	"github.com/alechenninger/parsec/internal/server"
 	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
 	"github.com/alechenninger/parsec/internal/trust"
 )
 
@@ -15,15 +36,34 @@ import (
 type Provider struct {
 	config *Config
 
+	// offline builds components without contacting external systems
+	offline bool
+
 	// Lazily constructed components (cached after first call)
-	trustStore           trust.Store
+	trustStore           *trust.ReloadableStore
 	dataSourceRegistry   *service.DataSourceRegistry
-	issuerRegistry       service.Registry
+	signerRegistry       *keys.SignerRegistry
+	trustDomainSigners   []*keys.SignerRegistry
+	trustDomains         []*service.TrustDomain
+	trustDomainIssuers   []*service.ReloadableRegistry
+	trustDomainsBuilt    bool
+	tokenStore           tokenstore.Store
+	denylist             denylist.Denylist
+	issuerRegistry       *service.ReloadableRegistry
+	validationCache      *server.ValidationCache
 	claimsFilterRegistry server.ClaimsFilterRegistry
 	tokenService         *service.TokenService
 	httpFixtureProvider  httpfixture.FixtureProvider
 	httpFixtureBuilt     bool
 	observer             service.ApplicationObserver
+	tracerProvider       *sdktrace.TracerProvider
+	eventPublisher       *events.Publisher
+	auditLogger          *audit.Logger
+	instance             *instance.Identity
+	cachePeerPool        *datasource.PeerPool
+
+	// reloadMu serializes Reload
+	reloadMu sync.Mutex
 }
 
 // NewProvider creates a new provider from configuration
@@ -33,36 +73,163 @@ func NewProvider(config *Config) *Provider {
 	}
 }
 
+// NewOfflineProvider creates a provider that builds components without contacting
+// external systems, to check configuration where they are unreachable, like in CI.
+// Signers are not started, so no keys are generated or loaded; JWKS are not fetched
+// until needed; and trust bundles, IdP metadata, and stores and key sources in a
+// Kubernetes cluster are not read. Components it builds are not fit to serve requests.
+func NewOfflineProvider(config *Config) *Provider {
+	return &Provider{
+		config:  config,
+		offline: true,
+	}
+}
+
+// errOffline fails what cannot be used when components are built offline
+var errOffline = errors.New("built offline, without contacting external systems")
+
+// offlineKeySource stands in for an external key source when building offline
+type offlineKeySource struct{}
+
+// Fetch implements keys.ExternalKeySource
+func (offlineKeySource) Fetch(ctx context.Context) (map[string][]byte, error) {
+	return nil, errOffline
+}
+
+// Instance returns the identity of this instance, generating and persisting it on first start
+func (p *Provider) Instance() (*instance.Identity, error) {
+	if p.instance != nil {
+		return p.instance, nil
+	}
+
+	var cfg instance.Config
+	if p.config.Instance != nil {
+		cfg.ID = p.config.Instance.ID
+		cfg.IDFile = p.config.Instance.IDFile
+	}
+
+	identity, err := instance.Load(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load instance identity: %w", err)
+	}
+
+	p.instance = &identity
+	return p.instance, nil
+}
+
+// Logger returns the logger for components' own messages
+func (p *Provider) Logger() *slog.Logger {
+	return NewLogger(p.config.Observability)
+}
+
 // Observer returns the configured application observer
 func (p *Provider) Observer() (service.ApplicationObserver, error) {
 	if p.observer != nil {
 		return p.observer, nil
 	}
 
+	identity, err := p.Instance()
+	if err != nil {
+		return nil, err
+	}
+
 	// Build from config
-	observer, err := NewObserver(p.config.Observability)
+	observer, err := NewObserver(p.config.Observability, identity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create observer: %w", err)
 	}
 
+	tracerProvider, err := p.TracerProvider()
+	if err != nil {
+		return nil, err
+	}
+	if tracerProvider != nil {
+		observer = service.NewCompositeObserver(observer, probe.NewTracingObserver(tracerProvider))
+	}
+
+	eventPublisher, err := p.EventPublisher()
+	if err != nil {
+		return nil, err
+	}
+	if eventPublisher != nil {
+		observer = service.NewCompositeObserver(observer, probe.NewEventObserver(probe.EventObserverConfig{
+			Publisher: eventPublisher,
+			Instance:  identity,
+		}))
+	}
+
 	p.observer = observer
 	return observer, nil
 }
 
+// TracerProvider returns the tracer provider spans are exported with, or nil if
+// tracing is not configured
+func (p *Provider) TracerProvider() (*sdktrace.TracerProvider, error) {
+	if p.tracerProvider != nil {
+		return p.tracerProvider, nil
+	}
+	if p.config.Observability == nil || p.config.Observability.Tracing == nil {
+		return nil, nil
+	}
+
+	identity, err := p.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	tracerProvider, err := NewTracerProvider(p.config.Observability.Tracing, identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracer provider: %w", err)
+	}
+
+	p.tracerProvider = tracerProvider
+	return tracerProvider, nil
+}
+
+// EventPublisher returns the publisher of issuance and denial events, or nil if
+// events are not configured
+func (p *Provider) EventPublisher() (*events.Publisher, error) {
+	if p.eventPublisher != nil {
+		return p.eventPublisher, nil
+	}
+	if p.config.Observability == nil || p.config.Observability.Events == nil {
+		return nil, nil
+	}
+
+	identity, err := p.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	publisher, err := NewEventPublisher(p.config.Observability.Events, identity, p.HTTPTransport())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event publisher: %w", err)
+	}
+
+	p.eventPublisher = publisher
+	return publisher, nil
+}
+
 // TrustStore returns the configured trust store
 func (p *Provider) TrustStore() (trust.Store, error) {
 	if p.trustStore != nil {
 		return p.trustStore, nil
 	}
 
+	observer, err := p.Observer()
+	if err != nil {
+		return nil, err
+	}
+
 	transport := p.HTTPTransport()
-	store, err := NewTrustStore(p.config.TrustStore, transport)
+	store, err := NewTrustStore(p.config.TrustStore, transport, observer, p.offline)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create trust store: %w", err)
 	}
 
-	p.trustStore = store
-	return store, nil
+	// Reloadable, so validators can be reconfigured without a restart
+	p.trustStore = trust.NewReloadableStore(store)
+	return p.trustStore, nil
 }
 
 // DataSourceRegistry returns the configured data source registry
@@ -71,8 +238,13 @@ func (p *Provider) DataSourceRegistry() (*service.DataSourceRegistry, error) {
 		return p.dataSourceRegistry, nil
 	}
 
+	observer, err := p.Observer()
+	if err != nil {
+		return nil, err
+	}
+
 	transport := p.HTTPTransport()
-	registry, err := NewDataSourceRegistry(p.config.DataSources, transport)
+	registry, err := NewDataSourceRegistry(p.config.DataSources, transport, observer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create data source registry: %w", err)
 	}
@@ -81,19 +253,219 @@ func (p *Provider) DataSourceRegistry() (*service.DataSourceRegistry, error) {
 	return registry, nil
 }
 
+// CachePeerPool returns the pool distributed data source caches share with their
+// peers, or nil if cache peers are not configured
+// Only one pool may exist per process; it is created once and cached.
+func (p *Provider) CachePeerPool() (*datasource.PeerPool, error) {
+	if p.cachePeerPool != nil || p.config.CachePeers == nil {
+		return p.cachePeerPool, nil
+	}
+
+	pool, err := NewCachePeerPool(*p.config.CachePeers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache peer pool: %w", err)
+	}
+
+	p.cachePeerPool = pool
+	return pool, nil
+}
+
+// CachePeerDiscovery returns discovery of the cache peer pool's peers from
+// Kubernetes, not yet started, or nil if peers are not discovered from Kubernetes
+func (p *Provider) CachePeerDiscovery() (*datasource.KubernetesPeerDiscovery, error) {
+	if p.config.CachePeers == nil || p.config.CachePeers.Kubernetes == nil {
+		return nil, nil
+	}
+
+	pool, err := p.CachePeerPool()
+	if err != nil {
+		return nil, err
+	}
+
+	discovery, err := NewKubernetesPeerDiscovery(*p.config.CachePeers, pool)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache peer discovery: %w", err)
+	}
+	return discovery, nil
+}
+
+// SignerRegistry returns the configured signers, started
+func (p *Provider) SignerRegistry() (*keys.SignerRegistry, error) {
+	if p.signerRegistry != nil {
+		return p.signerRegistry, nil
+	}
+
+	// Each trust domain has its own signers, so its own keys
+	trustDomains := []string{p.config.TrustDomain}
+	for _, domain := range p.config.TrustDomains {
+		trustDomains = append(trustDomains, domain.Name)
+	}
+	registries, err := NewSignerRegistries(*p.config, p.offline, trustDomains...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer registry: %w", err)
+	}
+
+	p.signerRegistry = registries[0]
+	p.trustDomainSigners = registries[1:]
+	return p.signerRegistry, nil
+}
+
+// StopSigners stops the key rotation of the signers of every trust domain
+func (p *Provider) StopSigners() {
+	if p.signerRegistry == nil {
+		return
+	}
+	p.signerRegistry.Stop()
+	for _, registry := range p.trustDomainSigners {
+		registry.Stop()
+	}
+}
+
+// TokenStore returns the configured store of opaque token claims
+func (p *Provider) TokenStore() (tokenstore.Store, error) {
+	if p.tokenStore != nil {
+		return p.tokenStore, nil
+	}
+
+	store, err := NewTokenStore(p.config.TokenStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token store: %w", err)
+	}
+
+	p.tokenStore = store
+	return store, nil
+}
+
+// Denylist returns the configured denylist of revoked tokens
+func (p *Provider) Denylist() (denylist.Denylist, error) {
+	if p.denylist != nil {
+		return p.denylist, nil
+	}
+
+	list, err := NewDenylist(p.config.Denylist)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create denylist: %w", err)
+	}
+
+	p.denylist = list
+	return list, nil
+}
+
+// AuditLogger returns the audit logger, or nil if auditing is not configured
+func (p *Provider) AuditLogger() (*audit.Logger, error) {
+	if p.auditLogger != nil {
+		return p.auditLogger, nil
+	}
+	if p.config.Audit == nil {
+		return nil, nil
+	}
+
+	identity, err := p.Instance()
+	if err != nil {
+		return nil, err
+	}
+	var signerRegistry *keys.SignerRegistry
+	if p.config.Audit.SignerID != "" {
+		signerRegistry, err = p.SignerRegistry()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logger, err := NewAuditLogger(*p.config.Audit, identity, signerRegistry, p.HTTPTransport())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit logger: %w", err)
+	}
+
+	p.auditLogger = logger
+	return logger, nil
+}
+
 // IssuerRegistry returns the configured issuer registry
 func (p *Provider) IssuerRegistry() (service.Registry, error) {
 	if p.issuerRegistry != nil {
 		return p.issuerRegistry, nil
 	}
 
-	registry, err := NewIssuerRegistry(*p.config)
+	identity, err := p.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	signerRegistry, err := p.SignerRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	tokenStore, err := p.TokenStore()
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := NewIssuerRegistryWithSigners(*p.config, signerRegistry, tokenStore, identity, p.offline)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create issuer registry: %w", err)
 	}
 
-	p.issuerRegistry = registry
-	return registry, nil
+	// Reloadable, so claim mappers can be reconfigured without a restart
+	p.issuerRegistry = service.NewReloadableRegistry(registry)
+	return p.issuerRegistry, nil
+}
+
+// TrustDomains returns the configured additional trust domains, with their issuers
+func (p *Provider) TrustDomains() ([]*service.TrustDomain, error) {
+	if p.trustDomainsBuilt {
+		return p.trustDomains, nil
+	}
+
+	identity, err := p.Instance()
+	if err != nil {
+		return nil, err
+	}
+
+	// Built with the signer registry, so the trust domains' signers are too
+	if _, err := p.SignerRegistry(); err != nil {
+		return nil, err
+	}
+
+	tokenStore, err := p.TokenStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []*service.TrustDomain
+	for i, domainCfg := range p.config.TrustDomains {
+		domain, err := NewTrustDomain(*p.config, domainCfg, p.trustDomainSigners[i], tokenStore, identity, p.offline)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create trust domain: %w", err)
+		}
+		// Issuers are swapped on reload, like those of the instance's own trust domain
+		issuers := service.NewReloadableRegistry(domain.Issuers)
+		domain.Issuers = issuers
+		p.trustDomainIssuers = append(p.trustDomainIssuers, issuers)
+		domains = append(domains, domain)
+	}
+
+	p.trustDomains = domains
+	p.trustDomainsBuilt = true
+	return domains, nil
+}
+
+// publicKeyRegistry returns the issuer registry, with the public keys of every trust domain
+// Per-issuer key sets are those of the instance's own trust domain.
+func (p *Provider) publicKeyRegistry() (service.Registry, error) {
+	issuerRegistry, err := p.IssuerRegistry()
+	if err != nil {
+		return nil, err
+	}
+	trustDomains, err := p.TrustDomains()
+	if err != nil {
+		return nil, err
+	}
+	if len(trustDomains) == 0 {
+		return issuerRegistry, nil
+	}
+	return service.NewTrustDomainsRegistry(issuerRegistry, trustDomains), nil
 }
 
 // ExchangeServerClaimsFilterRegistry returns the claims filter registry for the exchange server
@@ -140,24 +512,308 @@ func (p *Provider) TokenService() (*service.TokenService, error) {
 		return nil, fmt.Errorf("failed to get observer: %w", err)
 	}
 
+	// Issuers were checked against the token policy when the issuer registry was built
+	maxTTLs, err := parseMaxTTLs(p.config.TokenPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	trustDomains, err := p.TrustDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []service.TokenServiceOption{service.WithMaxTTLs(maxTTLs), service.WithTrustDomains(trustDomains...)}
+	policies, err := NewAuthorizationPolicies(p.config.TokenPolicy, dataSourceRegistry)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range policies {
+		opts = append(opts, service.WithAuthorizationPolicy(policy))
+	}
+	if p.config.TokenPolicy != nil && p.config.TokenPolicy.ReplayDetection {
+		opts = append(opts, service.WithReplayDetector(service.NewMemoryReplayDetector(nil, 0)))
+	}
+
 	// Create token service
 	tokenService := service.NewTokenService(
 		p.config.TrustDomain,
 		dataSourceRegistry,
 		issuerRegistry,
 		observer, // Application observer for observability
+		opts...,
 	)
 
 	p.tokenService = tokenService
 	return tokenService, nil
 }
 
+// JWKSServerConfig returns the JWKS server configuration, including any external publishers
+func (p *Provider) JWKSServerConfig() (server.JWKSServerConfig, error) {
+	issuerRegistry, err := p.publicKeyRegistry()
+	if err != nil {
+		return server.JWKSServerConfig{}, err
+	}
+
+	cfg := server.JWKSServerConfig{
+		IssuerRegistry: issuerRegistry,
+	}
+
+	if p.config.JWKS == nil {
+		return cfg, nil
+	}
+
+	if p.config.JWKS.RefreshInterval != "" {
+		refreshInterval, err := time.ParseDuration(p.config.JWKS.RefreshInterval)
+		if err != nil {
+			return server.JWKSServerConfig{}, fmt.Errorf("invalid jwks refresh_interval: %w", err)
+		}
+		cfg.RefreshInterval = refreshInterval
+	}
+
+	switch publication := server.JWKSPublication(p.config.JWKS.Publication); publication {
+	case "", server.JWKSPublicationAggregated, server.JWKSPublicationPerIssuer, server.JWKSPublicationBoth:
+		cfg.Publication = publication
+	default:
+		return server.JWKSServerConfig{}, fmt.Errorf("unknown jwks publication: %s", publication)
+	}
+	if len(p.config.JWKS.Publishers) > 0 && cfg.Publication == server.JWKSPublicationPerIssuer {
+		return server.JWKSServerConfig{}, fmt.Errorf("jwks publishers require aggregated publication")
+	}
+
+	publisher, err := NewJWKSPublisher(p.config.JWKS, p.HTTPTransport())
+	if err != nil {
+		return server.JWKSServerConfig{}, fmt.Errorf("failed to create jwks publisher: %w", err)
+	}
+	cfg.Publisher = publisher
+
+	return cfg, nil
+}
+
+// AdminServerConfig returns the admin server configuration, or nil if the admin API is disabled
+func (p *Provider) AdminServerConfig() (*server.AdminServerConfig, error) {
+	if p.config.AdminServer == nil {
+		return nil, nil
+	}
+
+	tokens, err := readTokens(p.config.AdminServer.Tokens, p.config.AdminServer.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin token file: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("admin_server requires tokens or token_file")
+	}
+
+	issuerRegistry, err := p.IssuerRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	return &server.AdminServerConfig{
+		IssuerRegistry: issuerRegistry,
+		Tokens:         tokens,
+	}, nil
+}
+
+// DebugServerConfig returns the debug server configuration, or nil if the debug
+// listener is disabled
+// The caller sets the ValidationCache ext_authz uses, if any.
+func (p *Provider) DebugServerConfig() (*server.DebugServerConfig, error) {
+	if p.config.DebugServer == nil {
+		return nil, nil
+	}
+
+	tokens, err := readTokens(p.config.DebugServer.Tokens, p.config.DebugServer.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read debug token file: %w", err)
+	}
+
+	signerRegistry, err := p.SignerRegistry()
+	if err != nil {
+		return nil, err
+	}
+	dataSourceRegistry, err := p.DataSourceRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	address := p.config.DebugServer.Address
+	if address == "" {
+		address = "localhost:6060"
+	}
+
+	return &server.DebugServerConfig{
+		Address:            address,
+		Tokens:             tokens,
+		Config:             Sanitize(p.config),
+		SignerRegistry:     signerRegistry,
+		DataSourceRegistry: dataSourceRegistry,
+	}, nil
+}
+
+// readTokens returns tokens and the tokens in file, one per line, if file is set
+func readTokens(tokens []string, file string) ([]string, error) {
+	tokens = slices.Clone(tokens)
+	if file == "" {
+		return tokens, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	for line := range strings.Lines(string(content)) {
+		if token := strings.TrimSpace(line); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// IntrospectionServerConfig returns the introspection server configuration, or nil if
+// token introspection is disabled
+func (p *Provider) IntrospectionServerConfig() (*server.IntrospectionServerConfig, error) {
+	if p.config.IntrospectionServer == nil {
+		return nil, nil
+	}
+	if len(p.config.IntrospectionServer.ClientAuthentication.Clients) == 0 {
+		return nil, fmt.Errorf("introspection_server requires client_authentication clients")
+	}
+
+	tokenStore, err := p.TokenStore()
+	if err != nil {
+		return nil, err
+	}
+
+	denylist, err := p.Denylist()
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator, err := NewClientAuthenticator(p.config.IntrospectionServer.ClientAuthentication, p.HTTPTransport(), p.offline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection client authenticator: %w", err)
+	}
+
+	return &server.IntrospectionServerConfig{
+		Store:               tokenStore,
+		Denylist:            denylist,
+		ClientAuthenticator: authenticator,
+	}, nil
+}
+
+// ClaimsServerConfig returns the claims retrieval server configuration, or nil if
+// referenced claims are not served
+func (p *Provider) ClaimsServerConfig() (*server.ClaimsServerConfig, error) {
+	if p.config.ClaimsServer == nil {
+		return nil, nil
+	}
+	if len(p.config.ClaimsServer.ClientAuthentication.Clients) == 0 {
+		return nil, fmt.Errorf("claims_server requires client_authentication clients")
+	}
+
+	tokenStore, err := p.TokenStore()
+	if err != nil {
+		return nil, err
+	}
+
+	denylist, err := p.Denylist()
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator, err := NewClientAuthenticator(p.config.ClaimsServer.ClientAuthentication, p.HTTPTransport(), p.offline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create claims client authenticator: %w", err)
+	}
+
+	return &server.ClaimsServerConfig{
+		Store:               tokenStore,
+		Denylist:            denylist,
+		ClientAuthenticator: authenticator,
+	}, nil
+}
+
+// RevocationServerConfig returns the revocation server configuration, or nil if
+// token revocation is disabled
+func (p *Provider) RevocationServerConfig() (*server.RevocationServerConfig, error) {
+	if p.config.RevocationServer == nil {
+		return nil, nil
+	}
+	if len(p.config.RevocationServer.ClientAuthentication.Clients) == 0 {
+		return nil, fmt.Errorf("revocation_server requires client_authentication clients")
+	}
+
+	tokenStore, err := p.TokenStore()
+	if err != nil {
+		return nil, err
+	}
+
+	denylist, err := p.Denylist()
+	if err != nil {
+		return nil, err
+	}
+
+	issuerRegistry, err := p.IssuerRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator, err := NewClientAuthenticator(p.config.RevocationServer.ClientAuthentication, p.HTTPTransport(), p.offline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revocation client authenticator: %w", err)
+	}
+
+	return &server.RevocationServerConfig{
+		Denylist:            denylist,
+		Store:               tokenStore,
+		IssuerRegistry:      issuerRegistry,
+		ClientAuthenticator: authenticator,
+	}, nil
+}
+
+// VerifyServerConfig returns the token verification endpoint configuration
+func (p *Provider) VerifyServerConfig() (server.VerifyServerConfig, error) {
+	issuerRegistry, err := p.IssuerRegistry()
+	if err != nil {
+		return server.VerifyServerConfig{}, err
+	}
+
+	denylist, err := p.Denylist()
+	if err != nil {
+		return server.VerifyServerConfig{}, err
+	}
+
+	return server.VerifyServerConfig{
+		IssuerRegistry: issuerRegistry,
+		TrustDomain:    p.config.TrustDomain,
+		Denylist:       denylist,
+	}, nil
+}
+
 // ServerConfig returns the server configuration
-func (p *Provider) ServerConfig() server.Config {
-	return server.Config{
+func (p *Provider) ServerConfig() (server.Config, error) {
+	cfg := server.Config{
 		GRPCPort: p.config.Server.GRPCPort,
 		HTTPPort: p.config.Server.HTTPPort,
 	}
+	if timeout := p.config.Server.ShutdownTimeout; timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return server.Config{}, fmt.Errorf("invalid server shutdown_timeout: %w", err)
+		}
+		cfg.ShutdownTimeout = d
+	}
+	if tlsCfg := p.config.Server.TLS; tlsCfg != nil {
+		cfg.TLS = &server.TLSConfig{
+			CertFile:     tlsCfg.CertFile,
+			KeyFile:      tlsCfg.KeyFile,
+			ClientCAFile: tlsCfg.ClientCAFile,
+		}
+		if err := cfg.TLS.Validate(); err != nil {
+			return server.Config{}, fmt.Errorf("invalid server TLS config: %w", err)
+		}
+	}
+	return cfg, nil
 }
 
 // TrustDomain returns the configured trust domain
@@ -197,6 +853,225 @@ func (p *Provider) HTTPFixtureProvider() httpfixture.FixtureProvider {
 	return p.httpFixtureProvider
 }
 
+// AuthzServerTrustsForwardedClientCert reports whether ext_authz reads workload
+// certificates from the x-forwarded-client-cert header
+func (p *Provider) AuthzServerTrustsForwardedClientCert() bool {
+	return p.config.AuthzServer != nil && p.config.AuthzServer.TrustForwardedClientCert
+}
+
+// AuthzServerCertificateBoundTokens reports whether ext_authz binds issued tokens to
+// the requesting workload's client certificate
+func (p *Provider) AuthzServerCertificateBoundTokens() bool {
+	return p.config.AuthzServer != nil && p.config.AuthzServer.CertificateBoundTokens
+}
+
+// AuthzServerTransactionIDHeader returns the header ext_authz adds the issued txn ID to, if any
+func (p *Provider) AuthzServerTransactionIDHeader() string {
+	if p.config.AuthzServer == nil {
+		return ""
+	}
+	return p.config.AuthzServer.TransactionIDHeader
+}
+
+// AuthzServerDenial returns how ext_authz responds to requests it denies
+func (p *Provider) AuthzServerDenial() (server.DenialSpec, error) {
+	if p.config.AuthzServer == nil || p.config.AuthzServer.Deny == nil {
+		return server.DenialSpec{}, nil
+	}
+	deny := p.config.AuthzServer.Deny
+	spec := server.DenialSpec{
+		UnauthenticatedStatus: deny.UnauthenticatedStatus,
+		WWWAuthenticate:       deny.WWWAuthenticate,
+		Realm:                 deny.Realm,
+		JSONBody:              deny.JSONBody,
+	}
+	if err := spec.Validate(); err != nil {
+		return server.DenialSpec{}, err
+	}
+	return spec, nil
+}
+
+// AuthzServerHeaderPolicy returns how ext_authz changes the headers of allowed requests
+func (p *Provider) AuthzServerHeaderPolicy() (server.HeaderPolicy, error) {
+	if p.config.AuthzServer == nil || p.config.AuthzServer.Headers == nil {
+		return server.HeaderPolicy{}, nil
+	}
+	headers := p.config.AuthzServer.Headers
+	policy := server.HeaderPolicy{
+		StripHeaders:        headers.Strip,
+		AppendHeaders:       headers.Append,
+		MaxHeaderSize:       headers.MaxSize,
+		ForwardedUserHeader: headers.ForwardedUserHeader,
+	}
+	if err := policy.Validate(); err != nil {
+		return server.HeaderPolicy{}, err
+	}
+	return policy, nil
+}
+
+// AuthzServerBodyCredentialRules returns the rules ext_authz reads bearer tokens from request bodies with
+func (p *Provider) AuthzServerBodyCredentialRules() ([]server.BodyCredentialRule, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	var rules []server.BodyCredentialRule
+	for i, cfg := range p.config.AuthzServer.BodyCredentials {
+		rule := server.BodyCredentialRule{
+			ContentType: cfg.ContentType,
+			JSONPath:    cfg.JSONPath,
+			XMLPath:     cfg.XMLPath,
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("body credential rule %d: %w", i, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// AuthzServerAuthzRules returns the CEL rules that deny ext_authz requests, or nil if none
+func (p *Provider) AuthzServerAuthzRules() (service.AuthorizationPolicy, error) {
+	if p.config.AuthzServer == nil {
+		return nil, nil
+	}
+	return p.authzRules(p.config.AuthzServer.AuthzRules)
+}
+
+// ExchangeServerAuthzRules returns the CEL rules that deny token exchanges, or nil if none
+func (p *Provider) ExchangeServerAuthzRules() (service.AuthorizationPolicy, error) {
+	if p.config.ExchangeServer == nil {
+		return nil, nil
+	}
+	return p.authzRules(p.config.ExchangeServer.AuthzRules)
+}
+
+// authzRules creates CEL rules reading the configured data sources
+func (p *Provider) authzRules(cfg []AuthzRuleConfig) (service.AuthorizationPolicy, error) {
+	dataSourceRegistry, err := p.DataSourceRegistry()
+	if err != nil {
+		return nil, err
+	}
+	rules, err := NewAuthzRules(cfg, dataSourceRegistry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authz rules: %w", err)
+	}
+	return rules, nil
+}
+
+// AuthzServerValidationCache returns the cache ext_authz reuses subject validations from,
+// or nil if it is not configured
+func (p *Provider) AuthzServerValidationCache() (*server.ValidationCache, error) {
+	if p.validationCache != nil {
+		return p.validationCache, nil
+	}
+	if p.config.AuthzServer == nil || p.config.AuthzServer.ValidationCache == nil {
+		return nil, nil
+	}
+	cfg := p.config.AuthzServer.ValidationCache
+	if cfg.TTL == "" {
+		return nil, fmt.Errorf("validation cache ttl is required")
+	}
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation cache ttl: %w", err)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("validation cache ttl must be positive")
+	}
+	p.validationCache = server.NewValidationCache(server.ValidationCacheConfig{
+		TTL:        ttl,
+		MaxEntries: cfg.MaxEntries,
+	})
+	return p.validationCache, nil
+}
+
+// ExchangeServerCertificateBoundTokens reports whether token exchange binds issued tokens
+// to the caller's client certificate
+func (p *Provider) ExchangeServerCertificateBoundTokens() bool {
+	return p.config.ExchangeServer != nil && p.config.ExchangeServer.CertificateBoundTokens
+}
+
+// ExchangeServerPreview reports whether token exchanges can be previewed
+func (p *Provider) ExchangeServerPreview() bool {
+	return p.config.ExchangeServer != nil && p.config.ExchangeServer.Preview
+}
+
+// ExchangeServerAllowedAudiences returns the audiences token exchange clients may request
+// besides the trust domain
+func (p *Provider) ExchangeServerAllowedAudiences() []string {
+	if p.config.ExchangeServer == nil {
+		return nil
+	}
+	return p.config.ExchangeServer.AllowedAudiences
+}
+
+// ExchangeServerScopePolicy returns the scope policy of the exchange server, and whether
+// it rejects exchanges requesting denied scopes rather than downgrading them
+func (p *Provider) ExchangeServerScopePolicy() (scope.Policy, bool, error) {
+	var cfg ScopePolicyConfig
+	if p.config.ExchangeServer != nil {
+		cfg = p.config.ExchangeServer.ScopePolicy
+	}
+	return NewScopePolicy(cfg)
+}
+
+// ExchangeServerClientAuthenticator returns the client authenticator of the exchange server,
+// or nil if no clients are registered and clients need not authenticate
+func (p *Provider) ExchangeServerClientAuthenticator() (*clientauth.Authenticator, error) {
+	if p.config.ExchangeServer == nil || len(p.config.ExchangeServer.ClientAuthentication.Clients) == 0 {
+		return nil, nil
+	}
+	return NewClientAuthenticator(p.config.ExchangeServer.ClientAuthentication, p.HTTPTransport(), p.offline)
+}
+
+// ExchangeServerReexchangeTokens returns the issuer of the exchange server's re-exchange
+// tokens, or nil if they are not enabled
+func (p *Provider) ExchangeServerReexchangeTokens() (*reexchange.Issuer, error) {
+	if p.config.ExchangeServer == nil || p.config.ExchangeServer.ReexchangeTokens == nil {
+		return nil, nil
+	}
+	signerRegistry, err := p.SignerRegistry()
+	if err != nil {
+		return nil, err
+	}
+	return NewReexchangeIssuer(*p.config.ExchangeServer.ReexchangeTokens, p.config.TrustDomain, signerRegistry)
+}
+
+// ExchangeServerRateLimits returns the exchange server's global and per-client rate
+// limiters, each nil if not configured
+func (p *Provider) ExchangeServerRateLimits() (global, perClient *server.RateLimiter, err error) {
+	if p.config.ExchangeServer == nil || p.config.ExchangeServer.RateLimit == nil {
+		return nil, nil, nil
+	}
+	cfg := p.config.ExchangeServer.RateLimit
+	if global, err = NewRateLimiter(cfg.Global); err != nil {
+		return nil, nil, fmt.Errorf("global rate limit: %w", err)
+	}
+	if perClient, err = NewRateLimiter(cfg.PerClient); err != nil {
+		return nil, nil, fmt.Errorf("per_client rate limit: %w", err)
+	}
+	return global, perClient, nil
+}
+
+// ExchangeServerSizeLimits returns the largest token and HTTP request body the exchange
+// server accepts, each 0 if unlimited
+func (p *Provider) ExchangeServerSizeLimits() (maxTokenBytes int, maxRequestBytes int64, err error) {
+	if p.config.ExchangeServer == nil {
+		return 0, 0, nil
+	}
+	cfg := p.config.ExchangeServer
+	if cfg.MaxTokenBytes < 0 || cfg.MaxRequestBytes < 0 {
+		return 0, 0, fmt.Errorf("max_token_bytes and max_request_bytes must not be negative")
+	}
+	return cfg.MaxTokenBytes, cfg.MaxRequestBytes, nil
+}
+
+// AuthzServerAPIKeyHeaders returns the request headers ext_authz reads API keys from,
+// those of the configured API key validators
+func (p *Provider) AuthzServerAPIKeyHeaders() []string {
+	return APIKeyHeaders(p.config.TrustStore)
+}
+
 // AuthzServerTokenTypes returns the configured token types for ext_authz
 func (p *Provider) AuthzServerTokenTypes() ([]server.TokenTypeSpec, error) {
 	// If no authz server config, return nil (will use defaults)
@@ -210,15 +1085,33 @@ func (p *Provider) AuthzServerTokenTypes() ([]server.TokenTypeSpec, error) {
 			return nil, fmt.Errorf("token type is required")
 		}
 
-		if ttCfg.HeaderName == "" {
-			return nil, fmt.Errorf("header_name is required for token type %s", ttCfg.Type)
+		if ttCfg.HeaderName == "" && ttCfg.Cookie == nil {
+			return nil, fmt.Errorf("header_name or cookie is required for token type %s", ttCfg.Type)
 		}
 
 		// Use token type directly as service.TokenType (it's already a URN string)
-		tokenTypes = append(tokenTypes, server.TokenTypeSpec{
+		spec := server.TokenTypeSpec{
 			Type:       service.TokenType(ttCfg.Type),
 			HeaderName: ttCfg.HeaderName,
-		})
+		}
+
+		if ttCfg.Cookie != nil {
+			sameSite, err := server.ParseSameSite(ttCfg.Cookie.SameSite)
+			if err != nil {
+				return nil, fmt.Errorf("token type %s: %w", ttCfg.Type, err)
+			}
+			spec.Cookie = &server.CookieSpec{
+				Name:     ttCfg.Cookie.Name,
+				Domain:   ttCfg.Cookie.Domain,
+				Path:     ttCfg.Cookie.Path,
+				SameSite: sameSite,
+			}
+			if err := spec.Cookie.Validate(); err != nil {
+				return nil, fmt.Errorf("token type %s: %w", ttCfg.Type, err)
+			}
+		}
+
+		tokenTypes = append(tokenTypes, spec)
 	}
 
 	return tokenTypes, nil
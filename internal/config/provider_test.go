@@ -0,0 +1,99 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestProvider_TokenService(t *testing.T) {
+	newConfig := func(policy *TokenPolicyConfig) *Config {
+		return &Config{
+			TrustDomain: "example.com",
+			Instance:    &InstanceConfig{ID: "parsec-0"},
+			TrustStore:  TrustStoreConfig{Type: "stub_store"},
+			Issuers: []IssuerConfig{{
+				TokenType: "urn:ietf:params:oauth:token-type:txn_token",
+				Type:      "stub",
+				IssuerURL: "https://parsec.example.com",
+			}},
+			TokenPolicy: policy,
+		}
+	}
+
+	t.Run("builds without a token policy", func(t *testing.T) {
+		tokenService, err := NewProvider(newConfig(nil)).TokenService()
+		if err != nil {
+			t.Fatalf("TokenService failed: %v", err)
+		}
+		if tokenService == nil {
+			t.Fatal("expected a token service")
+		}
+	})
+
+	t.Run("builds with replay detection", func(t *testing.T) {
+		if _, err := NewProvider(newConfig(&TokenPolicyConfig{ReplayDetection: true})).TokenService(); err != nil {
+			t.Fatalf("TokenService failed: %v", err)
+		}
+	})
+}
+
+func TestNewOfflineProvider(t *testing.T) {
+	t.Run("builds the key managers example outside a cluster", func(t *testing.T) {
+		t.Setenv("KUBERNETES_SERVICE_HOST", "")
+		loader, err := NewLoader("../../configs/examples/parsec-keymanagers.yaml")
+		if err != nil {
+			t.Fatalf("failed to load config: %v", err)
+		}
+		cfg, err := loader.Get()
+		if err != nil {
+			t.Fatalf("failed to parse config: %v", err)
+		}
+
+		provider := NewOfflineProvider(cfg)
+		defer provider.StopSigners()
+		if _, err := provider.TokenService(); err != nil {
+			t.Fatalf("TokenService failed: %v", err)
+		}
+		if _, err := provider.JWKSServerConfig(); err != nil {
+			t.Fatalf("JWKSServerConfig failed: %v", err)
+		}
+	})
+
+	t.Run("does not fetch JWKS", func(t *testing.T) {
+		var requests atomic.Int32
+		idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer idp.Close()
+
+		cfg := &Config{
+			TrustDomain: "example.com",
+			TrustStore: TrustStoreConfig{
+				Type: "stub_store",
+				Validators: []NamedValidatorConfig{{
+					Name: "idp",
+					ValidatorConfig: ValidatorConfig{
+						Type:        "jwt_validator",
+						Issuer:      "https://idp.example.com",
+						JWKSURL:     idp.URL,
+						TrustDomain: "idp.example.com",
+					},
+				}},
+			},
+		}
+
+		if _, err := NewOfflineProvider(cfg).TrustStore(); err != nil {
+			t.Fatalf("TrustStore failed: %v", err)
+		}
+		if n := requests.Load(); n != 0 {
+			t.Errorf("expected no JWKS requests offline, got %d", n)
+		}
+
+		if _, err := NewProvider(cfg).TrustStore(); err == nil {
+			t.Error("expected an error fetching JWKS when online")
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/alechenninger/parsec/internal/server"
+)
+
+// NewRateLimiter creates a rate limiter from configuration, or returns nil if cfg is nil
+func NewRateLimiter(cfg *RateLimitConfig) (*server.RateLimiter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.RequestsPerSecond <= 0 {
+		return nil, fmt.Errorf("requests_per_second must be positive")
+	}
+	if cfg.Burst < 0 || cfg.MaxClients < 0 {
+		return nil, fmt.Errorf("burst and max_clients must not be negative")
+	}
+	return server.NewRateLimiter(server.RateLimiterConfig{
+		Rate:    cfg.RequestsPerSecond,
+		Burst:   cfg.Burst,
+		MaxKeys: cfg.MaxClients,
+	}), nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/reexchange"
+)
+
+// NewReexchangeIssuer creates the issuer of re-exchange tokens for a trust domain from
+// configuration, signing with a signer from signerRegistry
+func NewReexchangeIssuer(cfg ReexchangeTokensConfig, trustDomain string, signerRegistry *keys.SignerRegistry) (*reexchange.Issuer, error) {
+	if cfg.SignerID == "" {
+		return nil, fmt.Errorf("reexchange_tokens requires signer_id")
+	}
+	signer, err := signerRegistry.Get(cfg.SignerID)
+	if err != nil {
+		return nil, fmt.Errorf("signer not found: %s", cfg.SignerID)
+	}
+
+	var ttl time.Duration
+	if cfg.TTL != "" {
+		ttl, err = time.ParseDuration(cfg.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reexchange_tokens ttl: %w", err)
+		}
+		if ttl <= 0 {
+			return nil, fmt.Errorf("reexchange_tokens ttl must be positive")
+		}
+	}
+
+	return reexchange.NewIssuer(reexchange.IssuerConfig{
+		TrustDomain: trustDomain,
+		TTL:         ttl,
+		Signer:      signer,
+	}), nil
+}
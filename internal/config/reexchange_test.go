@@ -0,0 +1,64 @@
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/reexchange"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestNewReexchangeIssuer(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	signerRegistry := keys.NewSignerRegistry()
+	if err := signerRegistry.Register("reexchange", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+
+	t.Run("signs with the configured signer", func(t *testing.T) {
+		issuer, err := NewReexchangeIssuer(ReexchangeTokensConfig{SignerID: "reexchange", TTL: "2h"}, "parsec.example.com", signerRegistry)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ctx := context.Background()
+		token, _, err := issuer.Issue(ctx, &reexchange.Grant{Subject: &trust.Result{Subject: "user@example.com"}})
+		if err != nil {
+			t.Fatalf("failed to issue: %v", err)
+		}
+		if _, err := issuer.Redeem(ctx, token); err != nil {
+			t.Errorf("failed to redeem: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		cfg     ReexchangeTokensConfig
+		wantErr string
+	}{
+		{name: "missing signer_id", cfg: ReexchangeTokensConfig{}, wantErr: "requires signer_id"},
+		{name: "unknown signer", cfg: ReexchangeTokensConfig{SignerID: "missing"}, wantErr: "signer not found"},
+		{name: "invalid ttl", cfg: ReexchangeTokensConfig{SignerID: "reexchange", TTL: "soon"}, wantErr: "invalid reexchange_tokens ttl"},
+		{name: "negative ttl", cfg: ReexchangeTokensConfig{SignerID: "reexchange", TTL: "-1h"}, wantErr: "must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReexchangeIssuer(tt.cfg, "parsec.example.com", signerRegistry)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
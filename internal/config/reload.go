@@ -0,0 +1,153 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// reloadableOptions are the top-level options Reload applies to a running instance
+var reloadableOptions = map[string]bool{
+	"trust_store": true,
+	"issuers":     true,
+}
+
+// Reload applies cfg to the components already built: the trust store (validators
+// and their filter) and the issuers (including their claim mappers), of the instance's
+// trust domain and of each of trust_domains, are rebuilt and swapped in together, or
+// not at all if any cannot be built. Trust domains themselves, their names, audiences,
+// and validators, are not: if they change, the running trust domains and their issuers
+// are kept. Other changed options take effect only on restart; they are reported to
+// the observer.
+func (p *Provider) Reload(ctx context.Context, cfg *Config) error {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	observer, err := p.Observer()
+	if err != nil {
+		return err
+	}
+	ctx, probe := observer.ConfigReloadStarted(ctx)
+	defer probe.End()
+
+	if changed := changedOptions(p.config, cfg); len(changed) > 0 {
+		probe.RestartRequired(changed)
+	}
+
+	// Rebuild from the running configuration, so options that need a restart (such
+	// as the trust domain) keep the values the rest of the instance uses
+	next := *p.config
+	next.TrustStore = cfg.TrustStore
+	next.Issuers = cfg.Issuers
+	if sameTrustDomains(p.config.TrustDomains, cfg.TrustDomains) {
+		next.TrustDomains = cfg.TrustDomains
+	}
+
+	var store trust.Store
+	if p.trustStore != nil {
+		store, err = NewTrustStore(next.TrustStore, p.HTTPTransport(), observer, p.offline)
+		if err != nil {
+			err = fmt.Errorf("failed to create trust store: %w", err)
+			probe.ConfigReloadFailed(err)
+			return err
+		}
+	}
+
+	var registry service.Registry
+	if p.issuerRegistry != nil {
+		registry, err = NewIssuerRegistryWithSigners(next, p.signerRegistry, p.tokenStore, p.instance, p.offline)
+		if err != nil {
+			err = fmt.Errorf("failed to create issuer registry: %w", err)
+			probe.ConfigReloadFailed(err)
+			closeTrustStore(store)
+			return err
+		}
+	}
+
+	var domainRegistries []service.Registry
+	if p.trustDomainsBuilt {
+		for i, domainCfg := range next.TrustDomains {
+			domain, err := NewTrustDomain(next, domainCfg, p.trustDomainSigners[i], p.tokenStore, p.instance, p.offline)
+			if err != nil {
+				err = fmt.Errorf("failed to create trust domain: %w", err)
+				probe.ConfigReloadFailed(err)
+				closeTrustStore(store)
+				return err
+			}
+			domainRegistries = append(domainRegistries, domain.Issuers)
+		}
+	}
+
+	p.config = &next
+	if store != nil {
+		old := p.trustStore.Swap(store)
+		// Results validated by the old validators are not kept
+		if p.validationCache != nil {
+			p.validationCache.Purge()
+		}
+		closeTrustStore(old)
+		probe.ComponentReloaded("trust_store")
+	}
+	if registry != nil {
+		p.issuerRegistry.Swap(registry)
+		probe.ComponentReloaded("issuers")
+	}
+	if len(domainRegistries) > 0 {
+		for i, registry := range domainRegistries {
+			p.trustDomainIssuers[i].Swap(registry)
+		}
+		probe.ComponentReloaded("trust_domains")
+	}
+	return nil
+}
+
+// closeTrustStore stops the background work of store's validators, such as JWKS refreshes
+// Validations already using them still complete.
+func closeTrustStore(store trust.Store) {
+	closer, ok := store.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		slog.Warn("Failed to close replaced validators", "error", err)
+	}
+}
+
+// changedOptions returns the top-level options, by name, that differ between
+// running and cfg and that Reload does not apply
+func changedOptions(running, cfg *Config) []string {
+	var changed []string
+	before, after := reflect.ValueOf(running).Elem(), reflect.ValueOf(cfg).Elem()
+	for i := 0; i < before.NumField(); i++ {
+		name, _, _ := strings.Cut(before.Type().Field(i).Tag.Get("koanf"), ",")
+		if name == "" || reloadableOptions[name] {
+			continue
+		}
+		// Only the issuers of trust domains are reloaded
+		if name == "trust_domains" {
+			if !sameTrustDomains(running.TrustDomains, cfg.TrustDomains) {
+				changed = append(changed, name)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// sameTrustDomains returns whether running and cfg configure the same trust domains,
+// in the same order, differing at most in their issuers
+func sameTrustDomains(running, cfg []TrustDomainConfig) bool {
+	return slices.EqualFunc(running, cfg, func(a, b TrustDomainConfig) bool {
+		return a.Name == b.Name && slices.Equal(a.Audiences, b.Audiences) && slices.Equal(a.Validators, b.Validators)
+	})
+}
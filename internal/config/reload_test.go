@@ -0,0 +1,151 @@
+package config
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestProviderReload(t *testing.T) {
+	const txnToken = "urn:ietf:params:oauth:token-type:txn_token"
+	const accessToken = "urn:ietf:params:oauth:token-type:access_token"
+	ctx := context.Background()
+
+	newConfig := func(credentialType string, tokenTypes ...string) *Config {
+		cfg := &Config{
+			TrustDomain: "example.com",
+			Instance:    &InstanceConfig{ID: "parsec-0"},
+			TrustStore: TrustStoreConfig{
+				Type: "stub_store",
+				Validators: []NamedValidatorConfig{{
+					Name:            "stub",
+					ValidatorConfig: ValidatorConfig{Type: "stub_validator", CredentialTypes: []string{credentialType}},
+				}},
+			},
+		}
+		for _, tokenType := range tokenTypes {
+			cfg.Issuers = append(cfg.Issuers, IssuerConfig{TokenType: tokenType, Type: "stub", IssuerURL: "https://parsec.example.com"})
+		}
+		return cfg
+	}
+
+	provider := NewProvider(newConfig("bearer", txnToken))
+	store, err := provider.TrustStore()
+	if err != nil {
+		t.Fatalf("TrustStore failed: %v", err)
+	}
+	issuers, err := provider.IssuerRegistry()
+	if err != nil {
+		t.Fatalf("IssuerRegistry failed: %v", err)
+	}
+	bearer := &trust.BearerCredential{Token: "token"}
+	if _, err := store.Validate(ctx, bearer); err != nil {
+		t.Fatalf("expected bearer credential to validate, got %v", err)
+	}
+
+	// Components already handed out see the new validators and issuers
+	if err := provider.Reload(ctx, newConfig("json", txnToken, accessToken)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if _, err := store.Validate(ctx, bearer); err == nil || !strings.Contains(err.Error(), "no validator found") {
+		t.Errorf("expected bearer validator to be removed, got %v", err)
+	}
+	if types := issuers.ListTokenTypes(); !slices.Contains(types, service.TokenType(accessToken)) {
+		t.Errorf("expected access token issuer to be added, got %v", types)
+	}
+
+	// A configuration that cannot be built changes nothing
+	invalid := newConfig("bearer", txnToken)
+	invalid.Issuers[0].Type = "unknown"
+	if err := provider.Reload(ctx, invalid); err == nil {
+		t.Fatal("expected invalid issuer to fail the reload")
+	}
+	if _, err := store.Validate(ctx, bearer); err == nil {
+		t.Error("expected trust store to be kept when issuers fail to build")
+	}
+	if types := issuers.ListTokenTypes(); len(types) != 2 {
+		t.Errorf("expected issuers to be kept, got %v", types)
+	}
+}
+
+func TestProviderReload_TrustDomains(t *testing.T) {
+	const txnToken = "urn:ietf:params:oauth:token-type:txn_token"
+	const accessToken = "urn:ietf:params:oauth:token-type:access_token"
+	ctx := context.Background()
+
+	newConfig := func(audiences []string, tokenTypes ...string) *Config {
+		domain := TrustDomainConfig{Name: "partners.example.com", Audiences: audiences}
+		for _, tokenType := range tokenTypes {
+			domain.Issuers = append(domain.Issuers, IssuerConfig{TokenType: tokenType, Type: "stub", IssuerURL: "https://partners.example.com"})
+		}
+		return &Config{
+			TrustDomain:  "example.com",
+			Instance:     &InstanceConfig{ID: "parsec-0"},
+			TrustStore:   TrustStoreConfig{Type: "stub_store"},
+			Issuers:      []IssuerConfig{{TokenType: txnToken, Type: "stub", IssuerURL: "https://parsec.example.com"}},
+			TrustDomains: []TrustDomainConfig{domain},
+		}
+	}
+
+	provider := NewProvider(newConfig(nil, txnToken))
+	domains, err := provider.TrustDomains()
+	if err != nil {
+		t.Fatalf("TrustDomains failed: %v", err)
+	}
+	issuers := domains[0].Issuers
+
+	// Trust domains already handed out see the new issuers
+	if err := provider.Reload(ctx, newConfig(nil, txnToken, accessToken)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if types := issuers.ListTokenTypes(); !slices.Contains(types, service.TokenType(accessToken)) {
+		t.Errorf("expected access token issuer to be added, got %v", types)
+	}
+
+	// Issuers that cannot be built change nothing
+	invalid := newConfig(nil, txnToken)
+	invalid.TrustDomains[0].Issuers[0].Type = "unknown"
+	if err := provider.Reload(ctx, invalid); err == nil {
+		t.Fatal("expected invalid trust domain issuer to fail the reload")
+	}
+	if types := issuers.ListTokenTypes(); len(types) != 2 {
+		t.Errorf("expected issuers to be kept, got %v", types)
+	}
+
+	// Changed trust domains need a restart, so their issuers are kept too
+	if err := provider.Reload(ctx, newConfig([]string{"*.partners.example.com"}, txnToken)); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if types := issuers.ListTokenTypes(); len(types) != 2 {
+		t.Errorf("expected issuers of changed trust domains to be kept, got %v", types)
+	}
+}
+
+func TestChangedOptions(t *testing.T) {
+	running := &Config{TrustDomain: "example.com", Server: ServerConfig{GRPCPort: 9090}}
+	cfg := &Config{
+		TrustDomain: "example.org",
+		Server:      ServerConfig{GRPCPort: 9090},
+		TrustStore:  TrustStoreConfig{Type: "filtered_store"},
+		Issuers:     []IssuerConfig{{Type: "stub"}},
+	}
+
+	if changed := changedOptions(running, cfg); !slices.Equal(changed, []string{"trust_domain"}) {
+		t.Errorf("expected only trust_domain to require a restart, got %v", changed)
+	}
+
+	running.TrustDomains = []TrustDomainConfig{{Name: "partners.example.com"}}
+	cfg = &Config{TrustDomain: "example.com", Server: ServerConfig{GRPCPort: 9090}}
+	cfg.TrustDomains = []TrustDomainConfig{{Name: "partners.example.com", Issuers: []IssuerConfig{{Type: "stub"}}}}
+	if changed := changedOptions(running, cfg); len(changed) != 0 {
+		t.Errorf("expected trust domain issuers to be reloaded, got %v", changed)
+	}
+	cfg.TrustDomains[0].Audiences = []string{"*.partners.example.com"}
+	if changed := changedOptions(running, cfg); !slices.Equal(changed, []string{"trust_domains"}) {
+		t.Errorf("expected changed trust domains to require a restart, got %v", changed)
+	}
+}
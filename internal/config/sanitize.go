@@ -0,0 +1,135 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// redacted replaces sensitive values in a sanitized configuration
+const redacted = "REDACTED"
+
+// sensitiveKeys are the options whose values are secrets
+var sensitiveKeys = map[string]bool{
+	"secret":        true,
+	"client_secret": true,
+	"secret_id":     true,
+	"password":      true,
+	"token":         true,
+	"tokens":        true,
+	"dsn":           true,
+	"hash_key":      true,
+}
+
+// sensitiveMaps are the options whose entries may be secrets, such as header
+// credentials or data source script configuration
+var sensitiveMaps = map[string]bool{
+	"headers": true,
+	"config":  true,
+}
+
+// Sanitize returns cfg as it would be written in a config file, without secrets
+// Options left unset are omitted. Secrets and credentials in URLs are replaced, so
+// the result can be shared while troubleshooting.
+func Sanitize(cfg *Config) map[string]any {
+	sanitized, _ := sanitizeValue(reflect.ValueOf(cfg), "").(map[string]any)
+	return sanitized
+}
+
+// sanitizeValue converts v, the value of the option named key, to plain maps,
+// slices, and scalars, or returns nil if it is unset
+func sanitizeValue(v reflect.Value, key string) any {
+	// Options behind pointers are set even if zero, such as a sample ratio of 0
+	set := false
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v, set = v.Elem(), true
+	}
+	if v.IsZero() && (!set || v.Kind() == reflect.Struct) {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("koanf"), ",")
+			if !field.IsExported() {
+				continue
+			}
+			// Squashed structs' options are the parent's own
+			if strings.Contains(opts, "squash") {
+				if inline, ok := sanitizeValue(v.Field(i), key).(map[string]any); ok {
+					for k, value := range inline {
+						out[k] = value
+					}
+				}
+				continue
+			}
+			if name == "" || name == "-" {
+				continue
+			}
+			if value := sanitizeValue(v.Field(i), name); value != nil {
+				out[name] = value
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+
+	case reflect.Map:
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			name := iter.Key().String()
+			if sensitiveMaps[key] {
+				out[name] = redacted
+			} else if value := sanitizeValue(iter.Value(), name); value != nil {
+				out[name] = value
+			}
+		}
+		return out
+
+	case reflect.Slice, reflect.Array:
+		if sensitiveKeys[key] {
+			return redacted
+		}
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = sanitizeValue(v.Index(i), key)
+		}
+		return out
+
+	case reflect.String:
+		if sensitiveKeys[key] {
+			return redacted
+		}
+		return sanitizeURL(v.String())
+
+	default:
+		if sensitiveKeys[key] {
+			return redacted
+		}
+		return v.Interface()
+	}
+}
+
+// sanitizeURL replaces the password of a URL, such as a connection string, and
+// returns any other string unchanged
+func sanitizeURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/alechenninger/parsec/internal/scope"
+)
+
+// NewScopePolicy creates a scope policy from configuration, and reports whether
+// exchanges requesting denied scopes are rejected rather than downgraded
+func NewScopePolicy(cfg ScopePolicyConfig) (scope.Policy, bool, error) {
+	var reject bool
+	switch cfg.OnDenied {
+	case "downgrade", "":
+	case "reject":
+		reject = true
+	default:
+		return nil, false, fmt.Errorf("unknown scope policy on_denied: %s (supported: downgrade, reject)", cfg.OnDenied)
+	}
+
+	switch cfg.Type {
+	case "passthrough", "":
+		return scope.PassthroughPolicy{}, reject, nil
+	case "allowlist":
+		if len(cfg.Subjects) == 0 {
+			return nil, false, fmt.Errorf("allowlist scope policy requires subjects")
+		}
+		policyCfg := scope.AllowlistPolicyConfig{SubjectScopes: allowlistScopes(cfg.Subjects)}
+		if len(cfg.Actors) > 0 {
+			policyCfg.ActorScopes = allowlistScopes(cfg.Actors)
+		}
+		return scope.NewAllowlistPolicy(policyCfg), reject, nil
+	case "cel":
+		policy, err := scope.NewCELPolicy(cfg.Script)
+		if err != nil {
+			return nil, false, err
+		}
+		return policy, reject, nil
+	default:
+		return nil, false, fmt.Errorf("unknown scope policy type: %s (supported: passthrough, allowlist, cel)", cfg.Type)
+	}
+}
+
+// allowlistScopes indexes allowlists by trust domain
+func allowlistScopes(allowlists []ScopeAllowlistConfig) map[string][]string {
+	scopes := make(map[string][]string, len(allowlists))
+	for _, allowlist := range allowlists {
+		scopes[allowlist.TrustDomain] = append(scopes[allowlist.TrustDomain], allowlist.Scopes...)
+	}
+	return scopes
+}
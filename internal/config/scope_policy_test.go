@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/scope"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestNewScopePolicy(t *testing.T) {
+	t.Run("defaults to passthrough and downgrade", func(t *testing.T) {
+		policy, reject, err := NewScopePolicy(ScopePolicyConfig{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := policy.(scope.PassthroughPolicy); !ok {
+			t.Errorf("expected passthrough policy, got %T", policy)
+		}
+		if reject {
+			t.Error("expected denied scopes to be downgraded")
+		}
+	})
+
+	t.Run("allowlist", func(t *testing.T) {
+		policy, reject, err := NewScopePolicy(ScopePolicyConfig{
+			Type:     "allowlist",
+			OnDenied: "reject",
+			Subjects: []ScopeAllowlistConfig{
+				{TrustDomain: "users.example.com", Scopes: []string{"read"}},
+				{TrustDomain: "users.example.com", Scopes: []string{"write"}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reject {
+			t.Error("expected denied scopes to be rejected")
+		}
+
+		granted, err := policy.Grant(context.Background(), &scope.Request{
+			Subject: &trust.Result{Subject: "alice", TrustDomain: "users.example.com"},
+			Actor:   trust.AnonymousResult(),
+			Scopes:  []string{"read", "write", "admin"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(granted, []string{"read", "write"}) {
+			t.Errorf("expected [read write], got %v", granted)
+		}
+	})
+
+	for name, cfg := range map[string]ScopePolicyConfig{
+		"unknown type":            {Type: "opa"},
+		"unknown on_denied":       {OnDenied: "ignore"},
+		"allowlist without rules": {Type: "allowlist"},
+		"cel without script":      {Type: "cel"},
+		"cel with invalid script": {Type: "cel", Script: "scope +"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := NewScopePolicy(cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/keys"
+)
+
+// Schemes of secret references
+const (
+	envSecretScheme   = "env://"
+	fileSecretScheme  = "file://"
+	vaultSecretScheme = "vault://"
+)
+
+// secretReference is a vault:// reference found in cfg, to be read once all are known
+type secretReference struct {
+	path  string // Option path, like "token_store.password"
+	mount string
+	key   string // Secret path within the mount
+	field string
+	set   func(string)
+}
+
+// resolveSecrets replaces the secret references in the sensitive options of cfg, the
+// options Sanitize redacts, with the secrets they refer to. Other options are never
+// resolved, so URLs like file:///etc/parsec/jwks.json keep their meaning. Every
+// reference that cannot be resolved is reported, in a ValidationError.
+func resolveSecrets(ctx context.Context, cfg *Config) error {
+	r := &secretResolver{}
+	r.walk(reflect.ValueOf(cfg).Elem(), "", false)
+	if len(r.vault) > 0 {
+		r.resolveVault(ctx, cfg.Secrets)
+	}
+
+	if len(r.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: r.errs}
+}
+
+// secretResolver resolves the secret references of a configuration
+type secretResolver struct {
+	validation
+	vault []secretReference
+}
+
+// walk resolves the references in v, the value of the option at path
+func (r *secretResolver) walk(v reflect.Value, path string, sensitive bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			r.walk(v.Elem(), path, sensitive)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("koanf"), ",")
+			// Squashed structs' options are the parent's own
+			if opts == "squash" {
+				r.walk(v.Field(i), path, false)
+				continue
+			}
+			if name == "" || name == "-" {
+				continue
+			}
+			r.walk(v.Field(i), joinPath(path, name), sensitiveKeys[name] || sensitiveMaps[name])
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			r.walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i), sensitive)
+		}
+	case reflect.Map:
+		if !sensitive || v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key)
+			if value.Kind() == reflect.Interface {
+				value = value.Elem()
+			}
+			if value.Kind() != reflect.String {
+				continue
+			}
+			r.resolve(joinPath(path, key.String()), value.String(), func(secret string) {
+				v.SetMapIndex(key, reflect.ValueOf(secret).Convert(v.Type().Elem()))
+			})
+		}
+	case reflect.String:
+		if sensitive && v.CanSet() {
+			r.resolve(path, v.String(), v.SetString)
+		}
+	}
+}
+
+// resolve replaces value, if it is a secret reference, with set
+func (r *secretResolver) resolve(path, value string, set func(string)) {
+	switch {
+	case strings.HasPrefix(value, envSecretScheme):
+		name := strings.TrimPrefix(value, envSecretScheme)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			r.addf(path, "environment variable %s is not set", name)
+			return
+		}
+		set(secret)
+
+	case strings.HasPrefix(value, fileSecretScheme):
+		file := strings.TrimPrefix(value, fileSecretScheme)
+		content, err := os.ReadFile(file)
+		if err != nil {
+			r.addf(path, "failed to read secret file %s: %v", file, err)
+			return
+		}
+		set(strings.TrimSpace(string(content)))
+
+	case strings.HasPrefix(value, vaultSecretScheme):
+		// Vault is configured by the secrets section, so it cannot refer to Vault
+		if path == "secrets" || strings.HasPrefix(path, "secrets.") {
+			r.addf(path, "vault credentials cannot be read from vault")
+			return
+		}
+		location, field, _ := strings.Cut(strings.TrimPrefix(value, vaultSecretScheme), "#")
+		mount, key, _ := strings.Cut(location, "/")
+		if mount == "" || key == "" || field == "" {
+			r.addf(path, "invalid vault reference %q (expected vault://mount/path#field)", value)
+			return
+		}
+		r.vault = append(r.vault, secretReference{path: path, mount: mount, key: key, field: field, set: set})
+	}
+}
+
+// resolveVault reads the vault:// references, reading each secret once
+func (r *secretResolver) resolveVault(ctx context.Context, cfg *SecretsConfig) {
+	var vaultCfg VaultSecretsConfig
+	if cfg != nil && cfg.Vault != nil {
+		vaultCfg = *cfg.Vault
+	}
+	fail := func(format string, args ...any) {
+		for _, ref := range r.vault {
+			r.addf(ref.path, format, args...)
+		}
+	}
+
+	address := vaultCfg.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		fail("secrets.vault.address is required to read vault secrets (or set VAULT_ADDR)")
+		return
+	}
+	auth, err := buildVaultAuth(vaultCfg.Auth)
+	if err != nil {
+		fail("invalid secrets.vault.auth: %v", err)
+		return
+	}
+
+	secrets := make(map[string]map[string][]byte)
+	failed := make(map[string]error)
+	for _, ref := range r.vault {
+		location := ref.mount + "/" + ref.key
+		if err, ok := failed[location]; ok {
+			r.addf(ref.path, "%v", err)
+			continue
+		}
+		fields, ok := secrets[location]
+		if !ok {
+			fields, err = readVaultSecret(ctx, keys.VaultKVKeySourceConfig{
+				Address:   address,
+				MountPath: ref.mount,
+				Path:      ref.key,
+				KVVersion: vaultCfg.KVVersion,
+				Namespace: vaultCfg.VaultNamespace,
+				Auth:      auth,
+			})
+			if err != nil {
+				failed[location] = err
+				r.addf(ref.path, "%v", err)
+				continue
+			}
+			secrets[location] = fields
+		}
+
+		secret, ok := fields[ref.field]
+		if !ok {
+			r.addf(ref.path, "vault secret %s has no field %s", location, ref.field)
+			continue
+		}
+		ref.set(string(secret))
+	}
+}
+
+// readVaultSecret reads the fields of a Vault KV secret
+func readVaultSecret(ctx context.Context, cfg keys.VaultKVKeySourceConfig) (map[string][]byte, error) {
+	source, err := keys.NewVaultKVKeySource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return source.Fetch(ctx)
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoader_SecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "client-secret")
+	if err := os.WriteFile(secretFile, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	vaultReads := 0
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" || r.URL.Path != "/v1/secret/data/parsec/redis" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		vaultReads++
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{
+				"data": map[string]any{"password": "vault-password", "username": "parsec"},
+			},
+		})
+	}))
+	t.Cleanup(vault.Close)
+
+	t.Setenv("TEST_DATA_SOURCE_API_KEY", "env-secret")
+	t.Setenv("VAULT_TOKEN", "root")
+
+	load := func(t *testing.T, config string) (*Config, error) {
+		configPath := filepath.Join(t.TempDir(), "parsec.yaml")
+		if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		loader, err := NewLoader(configPath)
+		if err != nil {
+			t.Fatalf("NewLoader failed: %v", err)
+		}
+		return loader.Get()
+	}
+
+	t.Run("resolves references in sensitive options", func(t *testing.T) {
+		cfg, err := load(t, `
+secrets:
+  vault:
+    address: `+vault.URL+`
+trust_store:
+  validators:
+    - type: jwt_validator
+      jwks_url: file://`+secretFile+`
+exchange_server:
+  client_authentication:
+    clients:
+      - client_id: platform
+        secret: file://`+secretFile+`
+data_sources:
+  - name: users
+    type: lua
+    config:
+      api_key: env://TEST_DATA_SOURCE_API_KEY
+token_store:
+  type: redis
+  password: vault://secret/parsec/redis#password
+denylist:
+  type: redis
+  password: vault://secret/parsec/redis#password
+`)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+
+		if got := cfg.ExchangeServer.ClientAuthentication.Clients[0].Secret; got != "file-secret" {
+			t.Errorf("expected secret from file, got %q", got)
+		}
+		if got := cfg.DataSources[0].Config["api_key"]; got != "env-secret" {
+			t.Errorf("expected api key from environment, got %q", got)
+		}
+		if cfg.TokenStore.Password != "vault-password" || cfg.Denylist.Password != "vault-password" {
+			t.Errorf("expected passwords from vault, got %q and %q", cfg.TokenStore.Password, cfg.Denylist.Password)
+		}
+		if vaultReads != 1 {
+			t.Errorf("expected the vault secret to be read once, got %d reads", vaultReads)
+		}
+		if got := cfg.TrustStore.Validators[0].JWKSURL; got != "file://"+secretFile {
+			t.Errorf("expected options that are not sensitive to be left alone, got %q", got)
+		}
+	})
+
+	t.Run("reports every reference that cannot be resolved", func(t *testing.T) {
+		_, err := load(t, `
+secrets:
+  vault:
+    address: `+vault.URL+`
+exchange_server:
+  client_authentication:
+    clients:
+      - client_id: platform
+        secret: env://TEST_UNSET_SECRET
+token_store:
+  password: vault://secret/parsec/redis#missing
+denylist:
+  password: vault://secret
+`)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("expected a ValidationError, got %v", err)
+		}
+		for _, want := range []string{
+			"exchange_server.client_authentication.clients[0].secret: environment variable TEST_UNSET_SECRET is not set",
+			"token_store.password: vault secret secret/parsec/redis has no field missing",
+			`denylist.password: invalid vault reference "vault://secret"`,
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q in:\n%v", want, err)
+			}
+		}
+	})
+}
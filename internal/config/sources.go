@@ -0,0 +1,396 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/knadh/koanf/providers/file"
+
+	"github.com/alechenninger/parsec/internal/kubernetes"
+)
+
+// DefaultPollInterval is how often remote config files without change notifications
+// (S3 objects and HTTP URLs) are checked for changes
+const DefaultPollInterval = 30 * time.Second
+
+// configMapWatchTimeout bounds ConfigMap watches, beneath the Kubernetes client's timeout
+const configMapWatchTimeout = 25
+
+// configSource is where a config file is stored: the local filesystem, a Kubernetes
+// ConfigMap, an S3 object, or an HTTP URL
+type configSource interface {
+	// read reads the config file
+	read(ctx context.Context) ([]byte, error)
+
+	// watch starts calling onChange, until ctx is done, when the config file changes.
+	// Errors watching are passed to onChange too. Sources without change notifications
+	// are polled every interval.
+	watch(ctx context.Context, interval time.Duration, onChange func(error)) error
+}
+
+// isRemote reports whether location is a URL rather than a local path
+func isRemote(location string) bool {
+	return strings.Contains(location, "://")
+}
+
+// openSource returns the source of the config file at location: a local path, or a
+// configmap://namespace/name/key, s3://bucket/key, or http(s):// URL
+func openSource(ctx context.Context, location string) (configSource, error) {
+	if !isRemote(location) {
+		return &fileSource{path: location}, nil
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config location %s: %w", location, err)
+	}
+	switch u.Scheme {
+	case "configmap":
+		name, key, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+		if u.Host == "" || name == "" || key == "" {
+			return nil, fmt.Errorf("invalid config location %s (expected configmap://namespace/name/key)", location)
+		}
+		client, err := kubernetes.NewClient(kubernetes.ClientConfig{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+		}
+		return &configMapSource{client: client, namespace: u.Host, name: name, key: key}, nil
+
+	case "s3":
+		key := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || key == "" {
+			return nil, fmt.Errorf("invalid config location %s (expected s3://bucket/key)", location)
+		}
+		// Region and endpoint come from the environment, like AWS_REGION and AWS_ENDPOINT_URL_S3
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		return &s3Source{client: s3.NewFromConfig(awsCfg), bucket: u.Host, key: key}, nil
+
+	case "http", "https":
+		return &httpSource{url: location, client: &http.Client{Timeout: 30 * time.Second}}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported config location %s (supported: file paths, configmap://, s3://, http://, https://)", location)
+	}
+}
+
+// locationPath returns the path of location, whose extension is the config format
+func locationPath(location string) string {
+	if !isRemote(location) {
+		return location
+	}
+	if u, err := url.Parse(location); err == nil {
+		return u.Path
+	}
+	return location
+}
+
+// resolveInclude resolves include, a file included by the file at location
+func resolveInclude(location, include string) string {
+	if isRemote(include) {
+		return include
+	}
+	if !isRemote(location) {
+		if filepath.IsAbs(include) {
+			return include
+		}
+		return filepath.Join(filepath.Dir(location), include)
+	}
+
+	// Remote files include files relative to their URL
+	base, err := url.Parse(location)
+	if err != nil {
+		return include
+	}
+	ref, err := url.Parse(include)
+	if err != nil {
+		return include
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// bytesProvider provides a config file's content to koanf
+type bytesProvider []byte
+
+// ReadBytes implements koanf.Provider
+func (b bytesProvider) ReadBytes() ([]byte, error) {
+	return b, nil
+}
+
+// Read implements koanf.Provider
+func (b bytesProvider) Read() (map[string]any, error) {
+	return nil, errors.New("config file must be parsed")
+}
+
+// pollUntilDone calls check every interval until ctx is done, calling onChange when
+// check reports a change, or fails
+func pollUntilDone(ctx context.Context, interval time.Duration, check func(context.Context) (bool, error), onChange func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := check(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				onChange(err)
+			}
+			continue
+		}
+		if changed {
+			onChange(nil)
+		}
+	}
+}
+
+// sleep waits for d, returning false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// fileSource is a config file on the local filesystem
+type fileSource struct {
+	path string
+}
+
+func (s *fileSource) read(ctx context.Context) ([]byte, error) {
+	return file.Provider(s.path).ReadBytes()
+}
+
+func (s *fileSource) watch(ctx context.Context, interval time.Duration, onChange func(error)) error {
+	fp := file.Provider(s.path)
+	if err := fp.Watch(func(event interface{}, err error) {
+		onChange(err)
+	}); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		fp.Unwatch()
+	}()
+	return nil
+}
+
+// configMapSource is a key of a Kubernetes ConfigMap, watched through the API
+type configMapSource struct {
+	client    *kubernetes.Client
+	namespace string
+	name      string
+	key       string
+}
+
+// configMap is the part of a ConfigMap read
+type configMap struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+}
+
+func (s *configMapSource) get(ctx context.Context) (*configMap, error) {
+	var cm configMap
+	path := fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", s.namespace, s.name)
+	if err := s.client.Send(ctx, http.MethodGet, path, nil, &cm); err != nil {
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %w", s.namespace, s.name, err)
+	}
+	return &cm, nil
+}
+
+func (s *configMapSource) read(ctx context.Context) ([]byte, error) {
+	cm, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := cm.Data[s.key]
+	if !ok {
+		return nil, fmt.Errorf("configmap %s/%s has no key %s", s.namespace, s.name, s.key)
+	}
+	return []byte(data), nil
+}
+
+func (s *configMapSource) watch(ctx context.Context, interval time.Duration, onChange func(error)) error {
+	go func() {
+		var data string
+		known := false
+		for ctx.Err() == nil {
+			// Each watch starts from the current version, so none are missed between watches
+			cm, err := s.get(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					onChange(err)
+				}
+				sleep(ctx, interval)
+				continue
+			}
+			if known && cm.Data[s.key] != data {
+				onChange(nil)
+			}
+			data, known = cm.Data[s.key], true
+
+			path := fmt.Sprintf("/api/v1/namespaces/%s/configmaps?watch=true&fieldSelector=%s&resourceVersion=%s&timeoutSeconds=%d",
+				s.namespace, url.QueryEscape("metadata.name="+s.name), url.QueryEscape(cm.Metadata.ResourceVersion), configMapWatchTimeout)
+			err = s.client.Watch(ctx, path, func(event kubernetes.WatchEvent) error {
+				switch event.Type {
+				case "ADDED", "MODIFIED":
+				case "DELETED":
+					return fmt.Errorf("configmap %s/%s was deleted", s.namespace, s.name)
+				default:
+					return nil
+				}
+				var changed configMap
+				if err := json.Unmarshal(event.Object, &changed); err != nil {
+					return fmt.Errorf("failed to decode configmap: %w", err)
+				}
+				if changed.Data[s.key] != data {
+					data = changed.Data[s.key]
+					onChange(nil)
+				}
+				return nil
+			})
+			if err != nil && ctx.Err() == nil {
+				onChange(fmt.Errorf("failed to watch configmap %s/%s: %w", s.namespace, s.name, err))
+				sleep(ctx, interval)
+			}
+		}
+	}()
+	return nil
+}
+
+// s3ObjectAPI is the subset of the S3 client used to read config files
+type s3ObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// s3Source is an S3 object, polled for a new ETag
+type s3Source struct {
+	client s3ObjectAPI
+	bucket string
+	key    string
+}
+
+func (s *s3Source) read(ctx context.Context) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	return data, nil
+}
+
+func (s *s3Source) etag(ctx context.Context) (string, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to head s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+func (s *s3Source) watch(ctx context.Context, interval time.Duration, onChange func(error)) error {
+	last, err := s.etag(ctx)
+	if err != nil {
+		return err
+	}
+	go pollUntilDone(ctx, interval, func(ctx context.Context) (bool, error) {
+		etag, err := s.etag(ctx)
+		if err != nil {
+			return false, err
+		}
+		changed := etag != last
+		last = etag
+		return changed, nil
+	}, onChange)
+	return nil
+}
+
+// httpSource is an HTTP URL, polled with If-None-Match requests
+type httpSource struct {
+	url    string
+	client *http.Client
+
+	// etag and body are the last response
+	etag string
+	body []byte
+}
+
+// fetch gets the URL, reporting whether it changed since the last fetch
+func (s *httpSource) fetch(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request for %s: %w", s.url, err)
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to get %s: status %d", s.url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", s.url, err)
+	}
+
+	// Servers without ETags are compared by content
+	changed := s.body == nil || !bytes.Equal(body, s.body)
+	s.etag = resp.Header.Get("ETag")
+	s.body = body
+	return changed, nil
+}
+
+func (s *httpSource) read(ctx context.Context) ([]byte, error) {
+	if _, err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+	return s.body, nil
+}
+
+func (s *httpSource) watch(ctx context.Context, interval time.Duration, onChange func(error)) error {
+	if _, err := s.fetch(ctx); err != nil {
+		return err
+	}
+	go pollUntilDone(ctx, interval, s.fetch, onChange)
+	return nil
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/alechenninger/parsec/internal/kubernetes"
+)
+
+func TestLoader_HTTPSource(t *testing.T) {
+	var mu sync.Mutex
+	files := map[string]string{
+		"/configs/parsec.yaml": "include: [base.yaml]\ntrust_domain: v1.example.com\n",
+		"/configs/base.yaml":   "server:\n  grpc_port: 9443\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		etag := fmt.Sprintf(`"%x"`, len(content))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		io.WriteString(w, content)
+	}))
+	t.Cleanup(server.Close)
+
+	loader, err := NewLoader(server.URL + "/configs/parsec.yaml")
+	if err != nil {
+		t.Fatalf("NewLoader failed: %v", err)
+	}
+	cfg, err := loader.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if cfg.TrustDomain != "v1.example.com" || cfg.Server.GRPCPort != 9443 {
+		t.Errorf("expected the config and its include, got trust domain %s and grpc port %d", cfg.TrustDomain, cfg.Server.GRPCPort)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	changes := make(chan *Config, 1)
+	loader.SetPollInterval(10 * time.Millisecond)
+	go loader.Watch(ctx, func(cfg *Config) error {
+		changes <- cfg
+		return nil
+	})
+
+	// Unchanged files are not reloaded
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-changes:
+		t.Fatal("expected no reload before the config changes")
+	default:
+	}
+
+	mu.Lock()
+	files["/configs/parsec.yaml"] = "include: [base.yaml]\ntrust_domain: v2.prod.example.com\n"
+	mu.Unlock()
+	select {
+	case cfg := <-changes:
+		if cfg.TrustDomain != "v2.prod.example.com" || cfg.Server.GRPCPort != 9443 {
+			t.Errorf("expected the changed config, got trust domain %s and grpc port %d", cfg.TrustDomain, cfg.Server.GRPCPort)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the changed config to be reloaded")
+	}
+}
+
+func TestConfigMapSource(t *testing.T) {
+	events := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sa-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/api/v1/namespaces/parsec/configmaps/parsec-config":
+			io.WriteString(w, `{"metadata": {"resourceVersion": "1"}, "data": {"parsec.yaml": "trust_domain: v1.example.com"}}`)
+		case r.URL.Path == "/api/v1/namespaces/parsec/configmaps" && r.URL.Query().Get("watch") == "true":
+			if r.URL.Query().Get("fieldSelector") != "metadata.name=parsec-config" || r.URL.Query().Get("resourceVersion") != "1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case event := <-events:
+					io.WriteString(w, event+"\n")
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("sa-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+	client, err := kubernetes.NewClient(kubernetes.ClientConfig{
+		APIServer:  server.URL,
+		TokenFile:  tokenFile,
+		HTTPClient: server.Client(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	source := &configMapSource{client: client, namespace: "parsec", name: "parsec-config", key: "parsec.yaml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	data, err := source.read(ctx)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != "trust_domain: v1.example.com" {
+		t.Errorf("expected the configmap key, got %q", data)
+	}
+
+	changes := make(chan error, 1)
+	if err := source.watch(ctx, time.Hour, func(err error) { changes <- err }); err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+
+	// Changes to other keys are not changes to the config file
+	events <- `{"type": "MODIFIED", "object": {"metadata": {"resourceVersion": "2"}, "data": {"parsec.yaml": "trust_domain: v1.example.com", "other": "x"}}}`
+	select {
+	case err := <-changes:
+		t.Fatalf("expected no change, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	events <- `{"type": "MODIFIED", "object": {"metadata": {"resourceVersion": "3"}, "data": {"parsec.yaml": "trust_domain: v2.example.com"}}}`
+	select {
+	case err := <-changes:
+		if err != nil {
+			t.Errorf("expected a change, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a change to the config file")
+	}
+}
+
+// stubS3 is an S3 client serving one object
+type stubS3 struct {
+	mu      sync.Mutex
+	content string
+	etag    string
+}
+
+func (s *stubS3) set(content, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.content, s.etag = content, etag
+}
+
+func (s *stubS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if aws.ToString(params.Bucket) != "configs" || aws.ToString(params.Key) != "parsec/prod.yaml" {
+		return nil, fmt.Errorf("no such key")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(s.content)), ETag: aws.String(s.etag)}, nil
+}
+
+func (s *stubS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &s3.HeadObjectOutput{ETag: aws.String(s.etag)}, nil
+}
+
+func TestS3Source(t *testing.T) {
+	client := &stubS3{}
+	client.set("trust_domain: v1.example.com", `"v1"`)
+	source := &s3Source{client: client, bucket: "configs", key: "parsec/prod.yaml"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	data, err := source.read(ctx)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != "trust_domain: v1.example.com" {
+		t.Errorf("expected the object, got %q", data)
+	}
+
+	changes := make(chan error, 1)
+	if err := source.watch(ctx, 10*time.Millisecond, func(err error) { changes <- err }); err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	select {
+	case err := <-changes:
+		t.Fatalf("expected no change, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	client.set("trust_domain: v2.example.com", `"v2"`)
+	select {
+	case err := <-changes:
+		if err != nil {
+			t.Errorf("expected a change, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a change to the object")
+	}
+}
+
+func TestResolveInclude(t *testing.T) {
+	tests := []struct {
+		location, include, want string
+	}{
+		{"configs/parsec.yaml", "base.yaml", "configs/base.yaml"},
+		{"configs/parsec.yaml", "/etc/parsec/base.yaml", "/etc/parsec/base.yaml"},
+		{"https://config.example.com/parsec/prod.yaml", "base.yaml", "https://config.example.com/parsec/base.yaml"},
+		{"configmap://parsec/parsec-config/prod.yaml", "base.yaml", "configmap://parsec/parsec-config/base.yaml"},
+		{"s3://configs/parsec/prod.yaml", "../shared/base.yaml", "s3://configs/shared/base.yaml"},
+		{"configs/parsec.yaml", "s3://configs/base.yaml", "s3://configs/base.yaml"},
+	}
+	for _, tt := range tests {
+		if got := resolveInclude(tt.location, tt.include); got != tt.want {
+			t.Errorf("resolveInclude(%q, %q) = %q, want %q", tt.location, tt.include, got, tt.want)
+		}
+	}
+}
+
+func TestOpenSource_InvalidLocations(t *testing.T) {
+	for _, location := range []string{"configmap://parsec/parsec-config", "s3://configs", "ftp://example.com/parsec.yaml"} {
+		if _, err := openSource(context.Background(), location); err == nil {
+			t.Errorf("expected %s to be invalid", location)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/tokenstore"
+)
+
+// NewTokenStore creates the store of opaque token claims from configuration
+func NewTokenStore(cfg *TokenStoreConfig) (tokenstore.Store, error) {
+	if cfg == nil {
+		return tokenstore.NewMemoryStore(nil), nil
+	}
+
+	switch cfg.Type {
+	case "", "memory":
+		return tokenstore.NewMemoryStore(nil), nil
+
+	case "redis":
+		if cfg.Address == "" {
+			return nil, fmt.Errorf("redis token store requires address")
+		}
+		return tokenstore.NewRedisStore(tokenstore.RedisStoreConfig{
+			Client: redis.NewClient(&redis.Options{
+				Addr:     cfg.Address,
+				Username: cfg.Username,
+				Password: cfg.Password,
+				DB:       cfg.RedisDB,
+			}),
+			KeyPrefix: cfg.KeyPrefix,
+		})
+
+	default:
+		return nil, fmt.Errorf("unknown token store type: %s (supported: memory, redis)", cfg.Type)
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/alechenninger/parsec/internal/instance"
+)
+
+// NewTracerProvider creates a tracer provider that exports spans with OTLP/gRPC
+// If identity is set, spans identify the instance that recorded them.
+// The caller must shut the provider down to flush buffered spans.
+func NewTracerProvider(cfg *TracingConfig, identity *instance.Identity) (*sdktrace.TracerProvider, error) {
+	sampleRatio := 1.0
+	if cfg.SampleRatio != nil {
+		sampleRatio = *cfg.SampleRatio
+	}
+	if sampleRatio < 0 || sampleRatio > 1 {
+		return nil, fmt.Errorf("tracing sample_ratio must be between 0 and 1, got %v", sampleRatio)
+	}
+
+	var opts []otlptracegrpc.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+	}
+	// The exporter connects lazily, so a collector that is down does not fail startup
+	exporter, err := otlptracegrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "parsec"
+	}
+	attrs := []attribute.KeyValue{attribute.String("service.name", serviceName)}
+	if identity != nil {
+		attrs = append(attrs,
+			attribute.String("service.instance.id", identity.ID),
+			attribute.String("service.version", identity.Version),
+		)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	), nil
+}
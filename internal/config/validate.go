@@ -0,0 +1,326 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/keys"
+)
+
+// FieldError is a problem with one configuration field
+type FieldError struct {
+	// Path locates the field, like "issuers[0].ttl"
+	Path string
+
+	// Message describes the problem
+	Message string
+}
+
+// Error implements error
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Errors))
+	for _, err := range e.Errors {
+		b.WriteString("\n  ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Validate checks the configuration as a whole, before any component is built: that
+// required fields are set, durations parse, and references between sections, like
+// issuers to signers and signers to key providers, resolve. Every problem is reported
+// at once, in a ValidationError.
+//
+// Options only a component understands, like CEL expressions, are still checked when
+// the component is built.
+func (c *Config) Validate() error {
+	return validate(c, nil)
+}
+
+// validate validates cfg, and that fileKeys, the keys of the config file, are all
+// known. Keys from environment variables and flags are not checked: the environment
+// may hold PARSEC_ variables that are not configuration, like PARSEC_CONFIG.
+func validate(cfg *Config, fileKeys map[string]any) error {
+	v := &validation{}
+	if fileKeys != nil {
+		v.unknownKeys("", fileKeys, reflect.TypeOf(Config{}))
+	}
+	cfg.validate(v)
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+// validation collects the problems of a configuration
+type validation struct {
+	errs []FieldError
+}
+
+func (v *validation) addf(path, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// required reports value if it is empty
+func (v *validation) required(path, value string) {
+	if value == "" {
+		v.addf(path, "is required")
+	}
+}
+
+// duration reports value if it is set but is not a duration
+func (v *validation) duration(path, value string) {
+	if value == "" {
+		return
+	}
+	if _, err := time.ParseDuration(value); err != nil {
+		v.addf(path, "invalid duration %q", value)
+	}
+}
+
+// unique reports id if it is already in ids, then adds it
+func (v *validation) unique(path, id string, ids map[string]bool) {
+	if id == "" {
+		return
+	}
+	if ids[id] {
+		v.addf(path, "duplicate id %q", id)
+	}
+	ids[id] = true
+}
+
+// reference reports id if it is set but is not in ids, the IDs of kind
+func (v *validation) reference(path, id string, ids map[string]bool, kind string) {
+	if id != "" && !ids[id] {
+		v.addf(path, "no %s %q", kind, id)
+	}
+}
+
+// unknownKeys reports the keys of value, a node of a config file, that are not fields of t
+func (v *validation) unknownKeys(path string, value any, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Values of the wrong type are reported by unmarshalling
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := koanfFields(t)
+		for _, key := range sortedKeys(m) {
+			field, ok := fields[key]
+			if !ok {
+				v.addf(joinPath(path, key), "unknown key")
+				continue
+			}
+			v.unknownKeys(joinPath(path, key), m[key], field)
+		}
+	case reflect.Map:
+		m, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		for _, key := range sortedKeys(m) {
+			v.unknownKeys(joinPath(path, key), m[key], t.Elem())
+		}
+	case reflect.Slice:
+		items := reflect.ValueOf(value)
+		if items.Kind() != reflect.Slice {
+			return
+		}
+		for i := range items.Len() {
+			v.unknownKeys(fmt.Sprintf("%s[%d]", path, i), items.Index(i).Interface(), t.Elem())
+		}
+	}
+}
+
+// koanfFields returns the types of the fields of t by key, including squashed fields
+func koanfFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("koanf"), ",")
+		if name == "-" {
+			continue
+		}
+		if opts == "squash" {
+			for key, squashed := range koanfFields(field.Type) {
+				fields[key] = squashed
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// validate checks c, reporting problems to v
+func (c *Config) validate(v *validation) {
+	v.required("trust_domain", c.TrustDomain)
+	v.duration("server.shutdown_timeout", c.Server.ShutdownTimeout)
+
+	for i, validator := range c.TrustStore.Validators {
+		path := fmt.Sprintf("trust_store.validators[%d]", i)
+		v.required(path+".type", validator.Type)
+		v.duration(path+".refresh_interval", validator.RefreshInterval)
+		v.duration(path+".min_refresh_interval", validator.MinRefreshInterval)
+		v.duration(path+".clock_skew", validator.ClockSkew)
+		v.duration(path+".max_token_age", validator.MaxTokenAge)
+	}
+
+	dataSources := make(map[string]bool)
+	for i, dataSource := range c.DataSources {
+		path := fmt.Sprintf("data_sources[%d]", i)
+		v.required(path+".name", dataSource.Name)
+		v.required(path+".type", dataSource.Type)
+		v.unique(path+".name", dataSource.Name, dataSources)
+	}
+
+	keyProviders := make(map[string]bool)
+	for i, provider := range c.KeyProviders {
+		path := fmt.Sprintf("key_providers[%d]", i)
+		v.required(path+".id", provider.ID)
+		v.unique(path+".id", provider.ID, keyProviders)
+		v.required(path+".key_type", provider.KeyType)
+		switch keys.KeyType(provider.KeyType) {
+		case "", keys.KeyTypeECP256, keys.KeyTypeECP384, keys.KeyTypeRSA2048, keys.KeyTypeRSA4096:
+		default:
+			v.addf(path+".key_type", "unknown key type %q (supported: EC-P256, EC-P384, RSA-2048, RSA-4096)", provider.KeyType)
+		}
+	}
+
+	// Signers may refer to signers defined after them, so all IDs are collected first
+	signers := make(map[string]bool)
+	for i, signer := range c.Signers {
+		path := fmt.Sprintf("signers[%d]", i)
+		v.required(path+".id", signer.ID)
+		v.unique(path+".id", signer.ID, signers)
+	}
+	for i, signer := range c.Signers {
+		path := fmt.Sprintf("signers[%d]", i)
+		v.duration(path+".key_ttl", signer.KeyTTL)
+		v.duration(path+".rotation_threshold", signer.RotationThreshold)
+		v.duration(path+".grace_period", signer.GracePeriod)
+		v.duration(path+".check_interval", signer.CheckInterval)
+		v.duration(path+".prepare_timeout", signer.PrepareTimeout)
+
+		switch signer.Type {
+		case "", "dual_slot":
+			v.required(path+".key_provider_id", signer.KeyProviderID)
+			v.reference(path+".key_provider_id", signer.KeyProviderID, keyProviders, "key provider")
+		case "external":
+			if signer.Source == nil {
+				v.addf(path+".source", "is required")
+			}
+		case "algorithm_migration":
+			v.required(path+".from_signer_id", signer.FromSignerID)
+			v.reference(path+".from_signer_id", signer.FromSignerID, signers, "signer")
+			v.required(path+".to_signer_id", signer.ToSignerID)
+			v.reference(path+".to_signer_id", signer.ToSignerID, signers, "signer")
+			v.required(path+".cutover_at", signer.CutoverAt)
+			if signer.CutoverAt != "" {
+				if _, err := time.Parse(time.RFC3339, signer.CutoverAt); err != nil {
+					v.addf(path+".cutover_at", "invalid RFC 3339 time %q", signer.CutoverAt)
+				}
+			}
+			v.duration(path+".retire_after", signer.RetireAfter)
+		default:
+			v.addf(path+".type", "unknown signer type %q (supported: dual_slot, external, algorithm_migration)", signer.Type)
+		}
+	}
+
+	validateIssuers(v, "issuers", c.Issuers, signers)
+
+	trustDomains := map[string]bool{c.TrustDomain: true}
+	for i, domain := range c.TrustDomains {
+		path := fmt.Sprintf("trust_domains[%d]", i)
+		v.required(path+".name", domain.Name)
+		if domain.Name != "" && trustDomains[domain.Name] {
+			v.addf(path+".name", "duplicate trust domain %q", domain.Name)
+		}
+		trustDomains[domain.Name] = true
+		validateIssuers(v, path+".issuers", domain.Issuers, signers)
+	}
+
+	if c.ExchangeServer != nil && c.ExchangeServer.ReexchangeTokens != nil {
+		reexchange := c.ExchangeServer.ReexchangeTokens
+		v.required("exchange_server.reexchange_tokens.signer_id", reexchange.SignerID)
+		v.reference("exchange_server.reexchange_tokens.signer_id", reexchange.SignerID, signers, "signer")
+		v.duration("exchange_server.reexchange_tokens.ttl", reexchange.TTL)
+	}
+
+	if c.Audit != nil {
+		v.reference("audit.signer_id", c.Audit.SignerID, signers, "signer")
+	}
+
+	if c.TokenPolicy != nil {
+		for i, maxTTL := range c.TokenPolicy.MaxTTLs {
+			path := fmt.Sprintf("token_policy.max_ttls[%d]", i)
+			v.required(path+".token_type", maxTTL.TokenType)
+			v.required(path+".max_ttl", maxTTL.MaxTTL)
+			v.duration(path+".max_ttl", maxTTL.MaxTTL)
+		}
+		v.reference("token_policy.decision_data_source", c.TokenPolicy.DecisionDataSource, dataSources, "data source")
+	}
+
+	if c.JWKS != nil {
+		v.duration("jwks.refresh_interval", c.JWKS.RefreshInterval)
+	}
+}
+
+// validateIssuers checks the issuers at path, which sign with signers
+func validateIssuers(v *validation, path string, issuers []IssuerConfig, signers map[string]bool) {
+	for i, issuer := range issuers {
+		issuerPath := fmt.Sprintf("%s[%d]", path, i)
+		v.required(issuerPath+".token_type", issuer.TokenType)
+		v.required(issuerPath+".type", issuer.Type)
+		v.reference(issuerPath+".signer_id", issuer.SignerID, signers, "signer")
+		v.duration(issuerPath+".ttl", issuer.TTL)
+		v.duration(issuerPath+".not_before_skew", issuer.NotBeforeSkew)
+		if issuer.TTLPolicy != nil {
+			v.duration(issuerPath+".ttl_policy.min_ttl", issuer.TTLPolicy.MinTTL)
+			v.required(issuerPath+".ttl_policy.max_ttl", issuer.TTLPolicy.MaxTTL)
+			v.duration(issuerPath+".ttl_policy.max_ttl", issuer.TTLPolicy.MaxTTL)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoader_Validation(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "parsec.yaml")
+	if err := os.WriteFile(configPath, []byte(`
+trust_domain: parsec.test
+server:
+  grcp_port: 9091
+trust_store:
+  validators:
+    - name: idp
+      type: jwt_validator
+      clock_skew: 30
+key_providers:
+  - id: memory
+    key_type: EC-P256
+signers:
+  - id: txn-signer
+    key_provider_id: memroy
+  - id: migration
+    type: algorithm_migration
+    from_signer_id: txn-signer
+    to_signer_id: missing
+    cutover_at: "2025-06-01T00:00:00Z"
+issuers:
+  - token_type: urn:ietf:params:oauth:token-type:txn_token
+    type: transaction_token
+    signer_id: txn-signer
+    ttl: 5 minutes
+    claim_mapers: []
+`), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	// Environment variables that are not configuration are not unknown keys
+	t.Setenv("PARSEC_CONFIG", configPath)
+
+	loader, err := NewLoader(configPath)
+	if err != nil {
+		t.Fatalf("NewLoader failed: %v", err)
+	}
+	_, err = loader.Get()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	want := []string{
+		`issuers[0].claim_mapers: unknown key`,
+		`server.grcp_port: unknown key`,
+		`trust_store.validators[0].clock_skew: invalid duration "30"`,
+		`signers[0].key_provider_id: no key provider "memroy"`,
+		`signers[1].to_signer_id: no signer "missing"`,
+		`issuers[0].ttl: invalid duration "5 minutes"`,
+	}
+	var got []string
+	for _, fieldErr := range validationErr.Errors {
+		got = append(got, fieldErr.Error())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected problems:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			TrustDomain:  "parsec.test",
+			KeyProviders: []KeyProviderConfig{{ID: "memory", KeyType: "EC-P256"}},
+			Signers:      []SignerConfig{{ID: "txn-signer", KeyProviderID: "memory"}},
+			Issuers: []IssuerConfig{{
+				TokenType: "urn:ietf:params:oauth:token-type:txn_token",
+				Type:      "transaction_token",
+				SignerID:  "txn-signer",
+				TTL:       "5m",
+			}},
+		}
+	}
+
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{
+			name:   "issuer without type",
+			modify: func(c *Config) { c.Issuers[0].Type = "" },
+			want:   "issuers[0].type: is required",
+		},
+		{
+			name:   "duplicate key provider",
+			modify: func(c *Config) { c.KeyProviders = append(c.KeyProviders, c.KeyProviders[0]) },
+			want:   `key_providers[1].id: duplicate id "memory"`,
+		},
+		{
+			name:   "unknown key type",
+			modify: func(c *Config) { c.KeyProviders[0].KeyType = "EC-P521" },
+			want:   `key_providers[0].key_type: unknown key type "EC-P521"`,
+		},
+		{
+			name: "trust domain issuer with unknown signer",
+			modify: func(c *Config) {
+				c.TrustDomains = []TrustDomainConfig{{Name: "partners.test", Issuers: []IssuerConfig{{
+					TokenType: "urn:ietf:params:oauth:token-type:txn_token",
+					Type:      "transaction_token",
+					SignerID:  "partners-signer",
+				}}}}
+			},
+			want: `trust_domains[0].issuers[0].signer_id: no signer "partners-signer"`,
+		},
+		{
+			name:   "decision data source that does not exist",
+			modify: func(c *Config) { c.TokenPolicy = &TokenPolicyConfig{DecisionDataSource: "opa"} },
+			want:   `token_policy.decision_data_source: no data source "opa"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -1,44 +1,62 @@
 package config
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+
+	"github.com/alechenninger/parsec/internal/probe"
 	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
 	"github.com/alechenninger/parsec/internal/trust"
 )
 
-// NewTrustStore creates a trust store from configuration
-func NewTrustStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.Store, error) {
+// NewTrustStore creates a trust store from configuration, whose validators report each
+// attempt to observer
+// If offline, validators do not contact their IdPs or trust bundle sources as they are
+// built (see NewOfflineProvider).
+func NewTrustStore(cfg TrustStoreConfig, transport http.RoundTripper, observer service.ValidatorObserver, offline bool) (trust.Store, error) {
 	switch cfg.Type {
 	case "stub_store":
-		return newStubStore(cfg, transport)
+		return newStubStore(cfg, transport, observer, offline)
 	case "filtered_store":
-		return newFilteredStore(cfg, transport)
+		return newFilteredStore(cfg, transport, observer, offline)
 	default:
 		return nil, fmt.Errorf("unknown trust store type: %s (supported: stub_store, filtered_store)", cfg.Type)
 	}
 }
 
 // newStubStore creates a stub trust store (no filtering)
-func newStubStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.Store, error) {
+func newStubStore(cfg TrustStoreConfig, transport http.RoundTripper, observer service.ValidatorObserver, offline bool) (trust.Store, error) {
 	store := trust.NewStubStore()
 
 	// Add validators
 	for _, validatorCfg := range cfg.Validators {
-		validator, err := newValidator(validatorCfg.ValidatorConfig, transport)
+		validator, err := newValidator(withJWKSCacheFile(validatorCfg.ValidatorConfig, cfg.JWKSCacheDir), transport, offline)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create validator: %w", err)
 		}
-		store.AddValidator(validator)
+		store.AddValidator(probe.NewObservedValidator(validatorName(validatorCfg), validator, observer))
 	}
 
 	return store, nil
 }
 
 // newFilteredStore creates a filtered trust store with validator filtering
-func newFilteredStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.Store, error) {
+func newFilteredStore(cfg TrustStoreConfig, transport http.RoundTripper, observer service.ValidatorObserver, offline bool) (trust.Store, error) {
 	var opts []trust.FilteredStoreOption
 
 	// Add validator filter if configured
@@ -61,33 +79,68 @@ func newFilteredStore(cfg TrustStoreConfig, transport http.RoundTripper) (trust.
 			return nil, fmt.Errorf("validator name is required for filtered store")
 		}
 
-		validator, err := newValidator(validatorCfg.ValidatorConfig, transport)
+		validator, err := newValidator(withJWKSCacheFile(validatorCfg.ValidatorConfig, cfg.JWKSCacheDir), transport, offline)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create validator %s: %w", validatorCfg.Name, err)
 		}
 
-		store.AddValidator(validatorCfg.Name, validator)
+		store.AddValidator(validatorCfg.Name, probe.NewObservedValidator(validatorCfg.Name, validator, observer))
 	}
 
 	return store, nil
 }
 
+// validatorName names a validator in logs and traces: its configured name, else its type
+func validatorName(cfg NamedValidatorConfig) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Type
+}
+
+// withJWKSCacheFile defaults a JWT validator's JWKS cache file to a file in dir
+// named after its issuer, unless it sets one explicitly or does not fetch its JWKS
+func withJWKSCacheFile(cfg ValidatorConfig, dir string) ValidatorConfig {
+	if cfg.Type != "jwt_validator" || cfg.JWKSCacheFile != "" || cfg.JWKS != "" || cfg.JWKSFile != "" || dir == "" {
+		return cfg
+	}
+	key := cfg.Issuer + "|" + cfg.JWKSURL
+	sum := sha256.Sum256([]byte(key))
+	cfg.JWKSCacheFile = filepath.Join(dir, hex.EncodeToString(sum[:8])+".jwks.json")
+	return cfg
+}
+
 // newValidator creates a validator from configuration
-func newValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Validator, error) {
+func newValidator(cfg ValidatorConfig, transport http.RoundTripper, offline bool) (trust.Validator, error) {
 	switch cfg.Type {
 	case "jwt_validator":
-		return newJWTValidator(cfg, transport)
+		return newJWTValidator(cfg, transport, offline)
 	case "json_validator":
 		return newJSONValidator(cfg)
+	case "x509_validator":
+		return newX509Validator(cfg)
+	case "spiffe_validator":
+		return newSPIFFEValidator(cfg, offline)
+	case "introspection":
+		return newIntrospectionValidator(cfg, transport)
+	case "api_key_validator":
+		return newAPIKeyValidator(cfg, transport)
+	case "aws_sigv4_validator":
+		return newAWSSigV4Validator(cfg, transport)
+	case "saml_validator":
+		return newSAMLValidator(cfg, transport, offline)
+	case "wasm":
+		return newWASMValidator(cfg)
 	case "stub_validator":
 		return newStubValidator(cfg)
 	default:
-		return nil, fmt.Errorf("unknown validator type: %s (supported: jwt_validator, json_validator, stub_validator)", cfg.Type)
+		return nil, fmt.Errorf("unknown validator type: %s (supported: jwt_validator, json_validator, x509_validator, spiffe_validator, introspection, api_key_validator, aws_sigv4_validator, saml_validator, wasm, stub_validator)", cfg.Type)
 	}
 }
 
 // newJWTValidator creates a JWT validator
-func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Validator, error) {
+// If offline, its JWKS is not fetched until a token is validated.
+func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper, offline bool) (trust.Validator, error) {
 	if cfg.Issuer == "" {
 		return nil, fmt.Errorf("jwt_validator requires issuer")
 	}
@@ -96,9 +149,16 @@ func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Va
 	}
 
 	validatorCfg := trust.JWTValidatorConfig{
-		Issuer:      cfg.Issuer,
-		JWKSURL:     cfg.JWKSURL,
-		TrustDomain: cfg.TrustDomain,
+		Issuer:            cfg.Issuer,
+		JWKSURL:           cfg.JWKSURL,
+		JWKS:              []byte(cfg.JWKS),
+		JWKSFile:          cfg.JWKSFile,
+		TrustDomain:       cfg.TrustDomain,
+		CacheFile:         cfg.JWKSCacheFile,
+		RequiredAudiences: cfg.RequiredAudiences,
+		AllowedAudiences:  cfg.AllowedAudiences,
+		AuthorizedParties: cfg.AuthorizedParties,
+		LazyJWKS:          offline,
 	}
 
 	// Parse refresh interval if provided
@@ -109,6 +169,27 @@ func newJWTValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Va
 		}
 		validatorCfg.RefreshInterval = duration
 	}
+	if cfg.MinRefreshInterval != "" {
+		duration, err := time.ParseDuration(cfg.MinRefreshInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_refresh_interval: %w", err)
+		}
+		validatorCfg.MinRefreshInterval = duration
+	}
+	if cfg.ClockSkew != "" {
+		duration, err := time.ParseDuration(cfg.ClockSkew)
+		if err != nil {
+			return nil, fmt.Errorf("invalid clock_skew: %w", err)
+		}
+		validatorCfg.ClockSkew = duration
+	}
+	if cfg.MaxTokenAge != "" {
+		duration, err := time.ParseDuration(cfg.MaxTokenAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_token_age: %w", err)
+		}
+		validatorCfg.MaxTokenAge = duration
+	}
 
 	// Use provided transport if available
 	if transport != nil {
@@ -131,6 +212,315 @@ func newJSONValidator(cfg ValidatorConfig) (trust.Validator, error) {
 	), nil
 }
 
+// newX509Validator creates an X.509 client certificate validator
+func newX509Validator(cfg ValidatorConfig) (trust.Validator, error) {
+	if cfg.CAFile == "" && len(cfg.CAFiles) == 0 && cfg.CAPEM == "" {
+		return nil, fmt.Errorf("x509_validator requires ca_file, ca_files, or ca_pem")
+	}
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("x509_validator requires trust_domain")
+	}
+
+	roots := x509.NewCertPool()
+	caFiles := cfg.CAFiles
+	if cfg.CAFile != "" {
+		caFiles = append([]string{cfg.CAFile}, caFiles...)
+	}
+	for _, caFile := range caFiles {
+		pemData, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		if !roots.AppendCertsFromPEM(pemData) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+	}
+	if cfg.CAPEM != "" && !roots.AppendCertsFromPEM([]byte(cfg.CAPEM)) {
+		return nil, fmt.Errorf("no certificates found in ca_pem")
+	}
+
+	var extKeyUsages []x509.ExtKeyUsage
+	for _, usage := range cfg.ExtKeyUsages {
+		switch usage {
+		case "client_auth":
+			extKeyUsages = append(extKeyUsages, x509.ExtKeyUsageClientAuth)
+		case "server_auth":
+			extKeyUsages = append(extKeyUsages, x509.ExtKeyUsageServerAuth)
+		case "any":
+			extKeyUsages = append(extKeyUsages, x509.ExtKeyUsageAny)
+		default:
+			return nil, fmt.Errorf("unknown extended key usage: %s (supported: client_auth, server_auth, any)", usage)
+		}
+	}
+
+	return trust.NewX509Validator(trust.X509ValidatorConfig{
+		Roots:         roots,
+		TrustDomain:   cfg.TrustDomain,
+		ExtKeyUsages:  extKeyUsages,
+		AllowedSANs:   cfg.AllowedSANs,
+		SubjectSource: trust.X509SubjectSource(cfg.SubjectSource),
+	})
+}
+
+// newSPIFFEValidator creates a SPIFFE SVID validator
+// The trust bundle is watched via the Workload API or fetched from the bundle endpoint.
+// If offline, the validator has no trust bundle, so it rejects every SVID.
+func newSPIFFEValidator(cfg ValidatorConfig, offline bool) (trust.Validator, error) {
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("spiffe_validator requires trust_domain")
+	}
+	if (cfg.WorkloadAPIAddr == "") == (cfg.BundleEndpointURL == "") {
+		return nil, fmt.Errorf("spiffe_validator requires exactly one of workload_api_addr or bundle_endpoint_url")
+	}
+
+	var refreshInterval time.Duration
+	if cfg.RefreshInterval != "" {
+		duration, err := time.ParseDuration(cfg.RefreshInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid refresh_interval: %w", err)
+		}
+		refreshInterval = duration
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var bundles spiffebundle.Source
+	if offline {
+		bundles = spiffebundle.NewSet()
+	} else if cfg.WorkloadAPIAddr != "" {
+		// The source keeps watching for bundle updates after the initial one
+		source, err := workloadapi.NewBundleSource(ctx, workloadapi.WithClientOptions(workloadapi.WithAddr(cfg.WorkloadAPIAddr)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to watch trust bundle from workload API: %w", err)
+		}
+		bundles = source
+	} else {
+		source, err := trust.NewSPIFFEBundleEndpointSource(ctx, trust.SPIFFEBundleEndpointSourceConfig{
+			TrustDomain:     cfg.TrustDomain,
+			URL:             cfg.BundleEndpointURL,
+			RefreshInterval: refreshInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		bundles = source
+	}
+
+	return trust.NewSPIFFEValidator(trust.SPIFFEValidatorConfig{
+		TrustDomain: cfg.TrustDomain,
+		Bundles:     bundles,
+		Audiences:   cfg.Audiences,
+	})
+}
+
+// newIntrospectionValidator creates an OAuth 2.0 token introspection validator
+func newIntrospectionValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Validator, error) {
+	if cfg.IntrospectionURL == "" {
+		return nil, fmt.Errorf("introspection validator requires introspection_url")
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("introspection validator requires client_id")
+	}
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("introspection validator requires trust_domain")
+	}
+
+	clientSecret := cfg.ClientSecret
+	if cfg.ClientSecretFile != "" {
+		content, err := os.ReadFile(cfg.ClientSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client secret file %s: %w", cfg.ClientSecretFile, err)
+		}
+		clientSecret = strings.TrimSpace(string(content))
+	}
+
+	validatorCfg := trust.IntrospectionValidatorConfig{
+		Endpoint:     cfg.IntrospectionURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: clientSecret,
+		Issuer:       cfg.Issuer,
+		TrustDomain:  cfg.TrustDomain,
+	}
+
+	// Use provided transport if available
+	if transport != nil {
+		validatorCfg.HTTPClient = &http.Client{
+			Transport: transport,
+		}
+	}
+
+	return trust.NewIntrospectionValidator(validatorCfg)
+}
+
+// newAPIKeyValidator creates an API key validator and its key store
+func newAPIKeyValidator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Validator, error) {
+	if cfg.Header == "" {
+		return nil, fmt.Errorf("api_key_validator requires header")
+	}
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("api_key_validator requires trust_domain")
+	}
+	if cfg.KeyStore == nil {
+		return nil, fmt.Errorf("api_key_validator requires key_store")
+	}
+
+	store, err := newAPIKeyStore(*cfg.KeyStore, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key store: %w", err)
+	}
+
+	return trust.NewAPIKeyValidator(trust.APIKeyValidatorConfig{
+		Header:      cfg.Header,
+		Store:       store,
+		TrustDomain: cfg.TrustDomain,
+	})
+}
+
+// newAPIKeyStore creates an API key store from configuration
+func newAPIKeyStore(cfg APIKeyStoreConfig, transport http.RoundTripper) (trust.APIKeyStore, error) {
+	switch cfg.Type {
+	case "static":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("static api key store requires file")
+		}
+		return trust.LoadStaticAPIKeyStore(cfg.File)
+	case "sql":
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("sql api key store requires dsn")
+		}
+		var driverName string
+		switch cfg.Driver {
+		case "postgres":
+			driverName = "pgx"
+		case "mysql":
+			driverName = "mysql"
+		default:
+			return nil, fmt.Errorf("unknown sql api key store driver: %s (supported: postgres, mysql)", cfg.Driver)
+		}
+		db, err := sql.Open(driverName, cfg.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		store, err := trust.NewSQLAPIKeyStore(trust.SQLAPIKeyStoreConfig{
+			DB:    db,
+			Query: cfg.Query,
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		return store, nil
+	case "http":
+		storeCfg := trust.HTTPAPIKeyStoreConfig{URL: cfg.URL}
+		if transport != nil {
+			storeCfg.HTTPClient = &http.Client{
+				Transport: transport,
+			}
+		}
+		return trust.NewHTTPAPIKeyStore(storeCfg)
+	default:
+		return nil, fmt.Errorf("unknown api key store type: %s (supported: static, sql, http)", cfg.Type)
+	}
+}
+
+// newAWSSigV4Validator creates a validator for presigned STS GetCallerIdentity requests
+func newAWSSigV4Validator(cfg ValidatorConfig, transport http.RoundTripper) (trust.Validator, error) {
+	if cfg.Audience == "" {
+		return nil, fmt.Errorf("aws_sigv4_validator requires audience")
+	}
+
+	validatorCfg := trust.AWSSigV4ValidatorConfig{
+		TrustDomain:     cfg.TrustDomain,
+		Audience:        cfg.Audience,
+		AllowedAccounts: cfg.AllowedAccounts,
+		STSHosts:        cfg.STSHosts,
+	}
+
+	// Use provided transport if available
+	if transport != nil {
+		validatorCfg.HTTPClient = &http.Client{
+			Transport: transport,
+		}
+	}
+
+	return trust.NewAWSSigV4Validator(validatorCfg)
+}
+
+// newSAMLValidator creates a SAML assertion validator from the IdP's metadata
+// If offline, metadata is not fetched from idp_metadata_url; the validator stands in
+// for one, rejecting every assertion.
+func newSAMLValidator(cfg ValidatorConfig, transport http.RoundTripper, offline bool) (trust.Validator, error) {
+	if cfg.Audience == "" {
+		return nil, fmt.Errorf("saml_validator requires audience")
+	}
+	if (cfg.IdPMetadataFile == "") == (cfg.IdPMetadataURL == "") {
+		return nil, fmt.Errorf("saml_validator requires exactly one of idp_metadata_file or idp_metadata_url")
+	}
+
+	var data []byte
+	if cfg.IdPMetadataFile != "" {
+		content, err := os.ReadFile(cfg.IdPMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IdP metadata file %s: %w", cfg.IdPMetadataFile, err)
+		}
+		data = content
+	} else if offline {
+		return trust.NewStubValidator(trust.CredentialTypeSAML).WithError(errOffline), nil
+	} else {
+		content, err := fetchSAMLMetadata(cfg.IdPMetadataURL, transport)
+		if err != nil {
+			return nil, err
+		}
+		data = content
+	}
+
+	metadata, err := trust.ParseSAMLIdPMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return trust.NewSAMLValidator(trust.SAMLValidatorConfig{
+		IdPEntityID:     metadata.EntityID,
+		IdPCertificates: metadata.Certificates,
+		Audience:        cfg.Audience,
+		Recipient:       cfg.Recipient,
+		TrustDomain:     cfg.TrustDomain,
+	})
+}
+
+// fetchSAMLMetadata fetches SAML metadata from url
+func fetchSAMLMetadata(url string, transport http.RoundTripper) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	if transport != nil {
+		client.Transport = transport
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IdP metadata: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch IdP metadata from %s: status %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IdP metadata: %w", err)
+	}
+	return data, nil
+}
+
+// APIKeyHeaders returns the request headers API key validators read keys from
+func APIKeyHeaders(cfg TrustStoreConfig) []string {
+	var headers []string
+	for _, validatorCfg := range cfg.Validators {
+		if validatorCfg.Type == "api_key_validator" && validatorCfg.Header != "" && !slices.Contains(headers, strings.ToLower(validatorCfg.Header)) {
+			headers = append(headers, strings.ToLower(validatorCfg.Header))
+		}
+	}
+	return headers
+}
+
 // newStubValidator creates a stub validator
 func newStubValidator(cfg ValidatorConfig) (trust.Validator, error) {
 	// Convert credential type strings to CredentialType
@@ -202,7 +592,13 @@ func parseCredentialType(s string) (trust.CredentialType, error) {
 		return trust.CredentialTypeJSON, nil
 	case "mtls":
 		return trust.CredentialTypeMTLS, nil
+	case "x509":
+		return trust.CredentialTypeX509, nil
+	case "api_key":
+		return trust.CredentialTypeAPIKey, nil
+	case "saml":
+		return trust.CredentialTypeSAML, nil
 	default:
-		return "", fmt.Errorf("unknown credential type: %s (supported: bearer, jwt, json, mtls)", s)
+		return "", fmt.Errorf("unknown credential type: %s (supported: bearer, jwt, json, mtls, x509, api_key, saml)", s)
 	}
 }
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+	"github.com/alechenninger/parsec/internal/wasm"
+)
+
+// loadWASMModule loads and compiles the configured plugin
+func loadWASMModule(cfg *WASMConfig) (*wasm.Module, error) {
+	if cfg == nil || cfg.Path == "" {
+		return nil, fmt.Errorf("wasm requires wasm.path")
+	}
+
+	var timeout time.Duration
+	if cfg.Timeout != "" {
+		duration, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wasm timeout: %w", err)
+		}
+		timeout = duration
+	}
+
+	return wasm.Load(context.Background(), wasm.Config{
+		Path:             cfg.Path,
+		Config:           cfg.Config,
+		MemoryLimitPages: cfg.MemoryLimitPages,
+		Timeout:          timeout,
+	})
+}
+
+// newWASMDataSource creates a data source implemented by a plugin, with optional caching
+func newWASMDataSource(cfg DataSourceConfig) (service.DataSource, error) {
+	module, err := loadWASMModule(cfg.WASM)
+	if err != nil {
+		return nil, err
+	}
+
+	var cacheTTL time.Duration
+	if cfg.Caching != nil && cfg.Caching.TTL != "" {
+		cacheTTL, err = time.ParseDuration(cfg.Caching.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid caching ttl: %w", err)
+		}
+	}
+
+	ds, err := wasm.NewDataSource(cfg.Name, module, cacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Caching != nil {
+		return wrapWithCaching(ds, *cfg.Caching)
+	}
+	return ds, nil
+}
+
+// newWASMMapper creates a claim mapper implemented by a plugin
+func newWASMMapper(cfg ClaimMapperConfig) (service.ClaimMapper, error) {
+	module, err := loadWASMModule(cfg.WASM)
+	if err != nil {
+		return nil, err
+	}
+	return wasm.NewClaimMapper(module, cfg.DataSources)
+}
+
+// newWASMValidator creates a validator implemented by a plugin
+func newWASMValidator(cfg ValidatorConfig) (trust.Validator, error) {
+	module, err := loadWASMModule(cfg.WASM)
+	if err != nil {
+		return nil, err
+	}
+
+	var credTypes []trust.CredentialType
+	for _, typeStr := range cfg.CredentialTypes {
+		credType, err := parseCredentialType(typeStr)
+		if err != nil {
+			return nil, err
+		}
+		credTypes = append(credTypes, credType)
+	}
+
+	return wasm.NewValidator(module, credTypes, cfg.TrustDomain)
+}
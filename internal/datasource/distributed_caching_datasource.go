@@ -54,6 +54,10 @@ func NewDistributedCachingDataSource(source service.DataSource, config Distribut
 	// Create the getter function that will be called on cache miss
 	// This may be called on a different server in the groupcache peer pool
 	getter := groupcache.GetterFunc(func(ctx context.Context, key string, dest groupcache.Sink) error {
+		if loaded, ok := ctx.Value(loadedKey{}).(*bool); ok {
+			*loaded = true
+		}
+
 		// Strip TTL timestamp suffix if present (format: "...json...:ttl:timestamp")
 		// The cache key may include a TTL-based timestamp for expiration
 		inputJSON := stripTTLSuffix(key)
@@ -103,6 +107,9 @@ func NewDistributedCachingDataSource(source service.DataSource, config Distribut
 	}
 }
 
+// loadedKey marks the context of a fetch with whether this process loaded it from the source
+type loadedKey struct{}
+
 // cachedEntry wraps the data and content type for storage in cache
 type cachedEntry struct {
 	Data        []byte                        `json:"data"`
@@ -138,11 +145,20 @@ func (c *DistributedCachingDataSource) Fetch(ctx context.Context, input *service
 
 	// Fetch from groupcache (will hit cache or call getter)
 	var cachedBytes []byte
-	err = c.group.Get(ctx, cacheKeyStr, groupcache.AllocatingByteSliceSink(&cachedBytes))
+	var loaded bool
+	err = c.group.Get(context.WithValue(ctx, loadedKey{}, &loaded), cacheKeyStr, groupcache.AllocatingByteSliceSink(&cachedBytes))
 	if err != nil {
 		return nil, fmt.Errorf("groupcache fetch failed: %w", err)
 	}
 
+	// Fetches the getter did not load here were answered by a cache: this process's, or
+	// that of the peer that owns the key
+	if loaded {
+		service.DataSourceFetchProbeFromContext(ctx).CacheMissed()
+	} else {
+		service.DataSourceFetchProbeFromContext(ctx).CacheHit()
+	}
+
 	// Deserialize the cached entry
 	var entry cachedEntry
 	if err := json.Unmarshal(cachedBytes, &entry); err != nil {
@@ -155,6 +171,21 @@ func (c *DistributedCachingDataSource) Fetch(ctx context.Context, input *service
 	}, nil
 }
 
+// CacheStats implements service.CacheStatsReporter
+// Entries and evictions are of this process's share of the cache; a miss may still be
+// answered by the peer that owns the key.
+func (c *DistributedCachingDataSource) CacheStats() service.CacheStats {
+	main := c.group.CacheStats(groupcache.MainCache)
+	hot := c.group.CacheStats(groupcache.HotCache)
+	gets, hits := c.group.Stats.Gets.Get(), c.group.Stats.CacheHits.Get()
+	return service.CacheStats{
+		Entries:   main.Items + hot.Items,
+		Hits:      hits,
+		Misses:    gets - hits,
+		Evictions: main.Evictions + hot.Evictions,
+	}
+}
+
 // roundTimestampToInterval rounds a timestamp to the nearest interval boundary.
 // This is used to create cache keys that naturally expire as time intervals change.
 // For example, with a 5-minute TTL:
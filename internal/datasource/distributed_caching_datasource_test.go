@@ -100,6 +100,27 @@ func TestDistributedCachingDataSource(t *testing.T) {
 		}
 	})
 
+	t.Run("reports hits and misses to the fetch's probe", func(t *testing.T) {
+		source := &mockCacheableDataSource{name: "test-distributed", ttl: time.Hour}
+		cached := NewDistributedCachingDataSource(source, DistributedCachingConfig{
+			GroupName:      "test-group-probes",
+			CacheSizeBytes: 1 << 20,
+		})
+		input := &service.DataSourceInput{Subject: &trust.Result{Subject: "user@example.com"}}
+		_, miss := service.NewFakeObserver(t).DataSourceFetchStarted(ctx, "test-distributed")
+		_, hit := service.NewFakeObserver(t).DataSourceFetchStarted(ctx, "test-distributed")
+
+		if _, err := cached.Fetch(service.WithDataSourceFetchProbe(ctx, miss), input); err != nil {
+			t.Fatalf("first fetch failed: %v", err)
+		}
+		if _, err := cached.Fetch(service.WithDataSourceFetchProbe(ctx, hit), input); err != nil {
+			t.Fatalf("second fetch failed: %v", err)
+		}
+
+		miss.(*service.FakeProbe).AssertProbeSequence("CacheMissed")
+		hit.(*service.FakeProbe).AssertProbeSequence("CacheHit")
+	})
+
 	t.Run("returns non-cacheable source as-is", func(t *testing.T) {
 		source := &mockNonCacheableDataSource{
 			name: "non-cacheable",
@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
 	"github.com/alechenninger/parsec/internal/clock"
 	"github.com/alechenninger/parsec/internal/service"
 )
@@ -20,6 +23,10 @@ type InMemoryCachingDataSource struct {
 	clock     clock.Clock
 	mu        sync.RWMutex
 	entries   map[string]*cacheEntry
+	fetches   singleflight.Group
+
+	hits   atomic.Int64
+	misses atomic.Int64
 }
 
 // cacheEntry stores cached data with expiration
@@ -87,6 +94,8 @@ func (c *InMemoryCachingDataSource) Fetch(ctx context.Context, input *service.Da
 	if found {
 		// Check if entry has expired
 		if entry.expiresAt.IsZero() || c.clock.Now().Before(entry.expiresAt) {
+			c.hits.Add(1)
+			service.DataSourceFetchProbeFromContext(ctx).CacheHit()
 			return entry.result, nil
 		}
 		// Entry expired, remove it
@@ -95,29 +104,34 @@ func (c *InMemoryCachingDataSource) Fetch(ctx context.Context, input *service.Da
 		c.mu.Unlock()
 	}
 
-	// Cache miss - fetch from source using the original (full) input
-	result, err := c.source.Fetch(ctx, input)
-	if err != nil {
-		return nil, err
-	}
-
-	// Store in cache if result is not nil
-	if result != nil {
-		ttl := c.cacheable.CacheTTL()
-		var expiresAt time.Time
-		if ttl > 0 {
-			expiresAt = c.clock.Now().Add(ttl)
+	// Cache miss - fetch from source using the original (full) input, once for
+	// every concurrent miss of the same key
+	c.misses.Add(1)
+	service.DataSourceFetchProbeFromContext(ctx).CacheMissed()
+	return fetchOnce(ctx, &c.fetches, cacheKeyStr, func(ctx context.Context) (*service.DataSourceResult, error) {
+		result, err := c.source.Fetch(ctx, input)
+		if err != nil {
+			return nil, err
 		}
 
-		c.mu.Lock()
-		c.entries[cacheKeyStr] = &cacheEntry{
-			result:    result,
-			expiresAt: expiresAt,
+		// Store in cache if result is not nil
+		if result != nil {
+			ttl := c.cacheable.CacheTTL()
+			var expiresAt time.Time
+			if ttl > 0 {
+				expiresAt = c.clock.Now().Add(ttl)
+			}
+
+			c.mu.Lock()
+			c.entries[cacheKeyStr] = &cacheEntry{
+				result:    result,
+				expiresAt: expiresAt,
+			}
+			c.mu.Unlock()
 		}
-		c.mu.Unlock()
-	}
 
-	return result, nil
+		return result, nil
+	})
 }
 
 // Cleanup removes expired entries from the cache
@@ -141,6 +155,15 @@ func (c *InMemoryCachingDataSource) Size() int {
 	return len(c.entries)
 }
 
+// CacheStats implements service.CacheStatsReporter
+func (c *InMemoryCachingDataSource) CacheStats() service.CacheStats {
+	return service.CacheStats{
+		Entries: int64(c.Size()),
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+	}
+}
+
 // serializeInput serializes a masked DataSourceInput into a cache key string
 // This creates a deterministic string representation of the input
 func serializeInput(input *service.DataSourceInput) (string, error) {
@@ -154,3 +177,23 @@ func serializeInput(input *service.DataSourceInput) (string, error) {
 	hash := sha256.Sum256(keyBytes)
 	return fmt.Sprintf("%x", hash), nil
 }
+
+// fetchOnce calls fetch, sharing its result with concurrent calls for the same key
+// instead of calling it again
+// fetch runs with the first caller's context, without its cancellation, so the
+// others do not fail when it gives up; each caller still stops waiting when its
+// own context is done.
+func fetchOnce(ctx context.Context, group *singleflight.Group, key string, fetch func(context.Context) (*service.DataSourceResult, error)) (*service.DataSourceResult, error) {
+	flight := group.DoChan(key, func() (any, error) {
+		return fetch(context.WithoutCancel(ctx))
+	})
+	select {
+	case res := <-flight:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*service.DataSourceResult), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -3,6 +3,7 @@ package datasource
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -182,6 +183,23 @@ func TestInMemoryCachingDataSource(t *testing.T) {
 		}
 	})
 
+	t.Run("reports hits and misses to the fetch's probe", func(t *testing.T) {
+		cached := NewInMemoryCachingDataSource(&mockCacheableDataSource{name: "test-source", ttl: time.Hour})
+		input := &service.DataSourceInput{Subject: &trust.Result{Subject: "user@example.com"}}
+		_, miss := service.NewFakeObserver(t).DataSourceFetchStarted(ctx, "test-source")
+		_, hit := service.NewFakeObserver(t).DataSourceFetchStarted(ctx, "test-source")
+
+		if _, err := cached.Fetch(service.WithDataSourceFetchProbe(ctx, miss), input); err != nil {
+			t.Fatalf("first fetch failed: %v", err)
+		}
+		if _, err := cached.Fetch(service.WithDataSourceFetchProbe(ctx, hit), input); err != nil {
+			t.Fatalf("second fetch failed: %v", err)
+		}
+
+		miss.(*service.FakeProbe).AssertProbeSequence("CacheMissed")
+		hit.(*service.FakeProbe).AssertProbeSequence("CacheHit")
+	})
+
 	t.Run("returns non-cacheable source as-is", func(t *testing.T) {
 		source := &mockNonCacheableDataSource{
 			name: "non-cacheable",
@@ -229,4 +247,68 @@ func TestInMemoryCachingDataSource(t *testing.T) {
 			t.Errorf("expected cache size 0 after cleanup, got %d", cached.Size())
 		}
 	})
+
+	t.Run("concurrent misses of the same key fetch once", func(t *testing.T) {
+		source := &gatedCacheableDataSource{
+			mockCacheableDataSource: mockCacheableDataSource{name: "test-source", ttl: time.Hour},
+			started:                 make(chan struct{}, 1),
+			release:                 make(chan struct{}),
+		}
+		cached := NewInMemoryCachingDataSource(source)
+
+		input := &service.DataSourceInput{
+			Subject: &trust.Result{
+				Subject: "user@example.com",
+			},
+		}
+
+		const callers = 10
+		var wg sync.WaitGroup
+		results := make(chan *service.DataSourceResult, callers)
+		for range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result, err := cached.Fetch(ctx, input)
+				if err != nil {
+					t.Errorf("fetch failed: %v", err)
+				}
+				results <- result
+			}()
+		}
+
+		// Hold the first fetch until every caller has missed
+		<-source.started
+		for cached.(*InMemoryCachingDataSource).CacheStats().Misses < callers {
+			time.Sleep(time.Millisecond)
+		}
+		close(source.release)
+		wg.Wait()
+		close(results)
+
+		if source.fetchCount != 1 {
+			t.Errorf("expected 1 fetch, got %d", source.fetchCount)
+		}
+		for result := range results {
+			if result == nil || string(result.Data) != `{"fetch_count":1}` {
+				t.Errorf("expected every caller to get the shared result, got %v", result)
+			}
+		}
+	})
+}
+
+// gatedCacheableDataSource is a cacheable data source whose fetches wait for release
+type gatedCacheableDataSource struct {
+	mockCacheableDataSource
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedCacheableDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	select {
+	case g.started <- struct{}{}:
+	default:
+	}
+	<-g.release
+	return g.mockCacheableDataSource.Fetch(ctx, input)
 }
@@ -0,0 +1,174 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/kubernetes"
+)
+
+// KubernetesPeerDiscovery finds the peers of the distributed cache in the
+// EndpointSlices of a Kubernetes Service, usually a headless Service selecting the
+// parsec pods, and updates the peer pool as pods come and go
+// Only ready endpoints are peers.
+type KubernetesPeerDiscovery struct {
+	service   string
+	namespace string
+	scheme    string
+	port      int
+	interval  time.Duration
+	client    *kubernetes.Client
+	peers     PeerUpdater
+	clock     clock.Clock
+	ticker    clock.Ticker
+}
+
+// KubernetesPeerDiscoveryConfig configures Kubernetes peer discovery.
+// Defaults assume parsec runs in-cluster with a mounted service account, which may
+// list EndpointSlices in the namespace.
+type KubernetesPeerDiscoveryConfig struct {
+	// Service is the name of the Service whose endpoints are the peers
+	Service string
+
+	// Namespace is the Service's namespace (default: the pod's namespace)
+	Namespace string
+
+	// Scheme is the scheme of peer URLs (default: "http")
+	Scheme string
+
+	// Port is the port peers serve each other on
+	Port int
+
+	// RefreshInterval is how often the endpoints are listed (default: 10 seconds)
+	RefreshInterval time.Duration
+
+	// Peers is told the peers found
+	Peers PeerUpdater
+
+	// Client is the Kubernetes API client (default: the in-cluster service account)
+	Client *kubernetes.Client
+
+	// Clock drives the refresh schedule (default: system clock)
+	Clock clock.Clock
+}
+
+// NewKubernetesPeerDiscovery creates Kubernetes peer discovery
+func NewKubernetesPeerDiscovery(cfg KubernetesPeerDiscoveryConfig) (*KubernetesPeerDiscovery, error) {
+	if cfg.Service == "" {
+		return nil, fmt.Errorf("kubernetes peer discovery requires a service")
+	}
+	if cfg.Port <= 0 {
+		return nil, fmt.Errorf("kubernetes peer discovery requires a port")
+	}
+	if cfg.Peers == nil {
+		return nil, fmt.Errorf("kubernetes peer discovery requires peers to update")
+	}
+
+	namespace, err := kubernetes.Namespace(cfg.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	client := cfg.Client
+	if client == nil {
+		if client, err = kubernetes.NewClient(kubernetes.ClientConfig{}); err != nil {
+			return nil, err
+		}
+	}
+
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	interval := cfg.RefreshInterval
+	if interval == 0 {
+		interval = 10 * time.Second
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &KubernetesPeerDiscovery{
+		service:   cfg.Service,
+		namespace: namespace,
+		scheme:    scheme,
+		port:      cfg.Port,
+		interval:  interval,
+		client:    client,
+		peers:     cfg.Peers,
+		clock:     clk,
+	}, nil
+}
+
+// Start finds the peers, failing if the endpoints cannot be listed, and begins
+// periodic refreshes
+func (d *KubernetesPeerDiscovery) Start(ctx context.Context) error {
+	if err := d.Refresh(ctx); err != nil {
+		return fmt.Errorf("failed to discover cache peers: %w", err)
+	}
+
+	d.ticker = d.clock.Ticker(d.interval)
+	return d.ticker.Start(func(ctx context.Context) {
+		// Keep the last known peers until the endpoints can be listed again
+		if err := d.Refresh(ctx); err != nil {
+			slog.WarnContext(ctx, "Failed to refresh cache peers", "error", err)
+		}
+	})
+}
+
+// Stop stops periodic refreshes
+func (d *KubernetesPeerDiscovery) Stop() {
+	if d.ticker != nil {
+		d.ticker.Stop()
+	}
+}
+
+// endpointSliceList is the part of a discovery.k8s.io/v1 EndpointSliceList read
+type endpointSliceList struct {
+	Items []struct {
+		Endpoints []struct {
+			Addresses  []string `json:"addresses"`
+			Conditions struct {
+				Ready *bool `json:"ready"`
+			} `json:"conditions"`
+		} `json:"endpoints"`
+	} `json:"items"`
+}
+
+// Refresh lists the Service's endpoints and updates the peers
+func (d *KubernetesPeerDiscovery) Refresh(ctx context.Context) error {
+	path := fmt.Sprintf("/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?labelSelector=%s",
+		d.namespace, url.QueryEscape("kubernetes.io/service-name="+d.service))
+	var list endpointSliceList
+	if err := d.client.Send(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return err
+	}
+
+	var peers []string
+	for _, slice := range list.Items {
+		for _, endpoint := range slice.Endpoints {
+			// A nil ready condition means ready (it is unknown)
+			if ready := endpoint.Conditions.Ready; ready != nil && !*ready {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				peers = append(peers, peerURL(d.scheme, address, d.port))
+			}
+		}
+	}
+	d.peers.UpdatePeers(sortedUnique(peers)...)
+	return nil
+}
+
+// sortedUnique sorts s and removes duplicates
+func sortedUnique(s []string) []string {
+	slices.Sort(s)
+	return slices.Compact(s)
+}
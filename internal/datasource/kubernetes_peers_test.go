@@ -0,0 +1,117 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/kubernetes"
+)
+
+// recordingPeers records the peers it is told
+type recordingPeers struct {
+	mu    sync.Mutex
+	peers []string
+}
+
+func (r *recordingPeers) UpdatePeers(peers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.peers = peers
+}
+
+func (r *recordingPeers) current() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.peers
+}
+
+func TestKubernetesPeerDiscovery(t *testing.T) {
+	var mu sync.Mutex
+	endpoints := []map[string]any{
+		{"addresses": []string{"10.0.0.2"}, "conditions": map[string]any{"ready": true}},
+		{"addresses": []string{"10.0.0.1"}},
+		{"addresses": []string{"10.0.0.3"}, "conditions": map[string]any{"ready": false}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sa-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/apis/discovery.k8s.io/v1/namespaces/parsec/endpointslices" ||
+			r.URL.Query().Get("labelSelector") != "kubernetes.io/service-name=parsec-peers" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": []map[string]any{{"endpoints": endpoints}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("sa-token\n"), 0o600))
+	client, err := kubernetes.NewClient(kubernetes.ClientConfig{
+		APIServer:  server.URL,
+		TokenFile:  tokenFile,
+		HTTPClient: http.DefaultClient,
+	})
+	require.NoError(t, err)
+
+	clk := clock.NewFixtureClock(time.Now())
+	peers := &recordingPeers{}
+	discovery, err := NewKubernetesPeerDiscovery(KubernetesPeerDiscoveryConfig{
+		Service:   "parsec-peers",
+		Namespace: "parsec",
+		Port:      8081,
+		Peers:     peers,
+		Client:    client,
+		Clock:     clk,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, discovery.Start(context.Background()))
+	t.Cleanup(discovery.Stop)
+	assert.Equal(t, []string{"http://10.0.0.1:8081", "http://10.0.0.2:8081"}, peers.current(),
+		"expected ready endpoints, sorted")
+
+	mu.Lock()
+	endpoints = endpoints[:1]
+	mu.Unlock()
+	clk.Advance(10 * time.Second)
+	assert.Equal(t, []string{"http://10.0.0.2:8081"}, peers.current(), "expected removed endpoint to be dropped")
+
+	t.Run("requires a service", func(t *testing.T) {
+		_, err := NewKubernetesPeerDiscovery(KubernetesPeerDiscoveryConfig{
+			Namespace: "parsec",
+			Port:      8081,
+			Peers:     peers,
+			Client:    client,
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestDetectSelfPeerURL(t *testing.T) {
+	t.Setenv("POD_IP", "10.1.2.3")
+	self, err := DetectSelfPeerURL("http", 8081)
+	require.NoError(t, err)
+	assert.Equal(t, "http://10.1.2.3:8081", self)
+
+	t.Setenv("POD_IP", "fd00::1")
+	self, err = DetectSelfPeerURL("http", 8081)
+	require.NoError(t, err)
+	assert.Equal(t, "http://[fd00::1]:8081", self)
+}
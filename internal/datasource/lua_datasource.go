@@ -1,12 +1,15 @@
 package datasource
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	lua "github.com/yuin/gopher-lua"
 
+	"github.com/alechenninger/parsec/internal/limits"
 	luaservices "github.com/alechenninger/parsec/internal/lua"
 	"github.com/alechenninger/parsec/internal/request"
 	"github.com/alechenninger/parsec/internal/service"
@@ -20,6 +23,7 @@ type LuaDataSource struct {
 	script       string
 	configSource luaservices.ConfigSource
 	httpConfig   luaservices.HTTPServiceConfig
+	jsonLimits   limits.JSON
 }
 
 // LuaDataSourceConfig configures a Lua data source
@@ -48,6 +52,12 @@ type LuaDataSourceConfig struct {
 	// HTTPConfig provides HTTP service configuration including timeout, fixtures, etc.
 	// If nil, default HTTP config (30s timeout, no fixtures) will be used
 	HTTPConfig *luaservices.HTTPServiceConfig
+
+	// JSONLimits bounds JSON decoding, both in the script (json.decode, json.each)
+	// and of JSON data the script returns. Returned data is re-encoded after
+	// decoding, so arrays truncated under limits.PolicyTruncate stay truncated
+	// downstream. If zero, JSON is decoded without limits.
+	JSONLimits limits.JSON
 }
 
 // NewLuaDataSource creates a new Lua data source
@@ -92,6 +102,7 @@ func NewLuaDataSource(config LuaDataSourceConfig) (*LuaDataSource, error) {
 		script:       config.Script,
 		configSource: config.ConfigSource,
 		httpConfig:   httpConfig,
+		jsonLimits:   config.JSONLimits,
 	}, nil
 }
 
@@ -113,7 +124,9 @@ func (ds *LuaDataSource) Fetch(ctx context.Context, input *service.DataSourceInp
 	configService := luaservices.NewConfigService(ds.configSource)
 	configService.Register(L)
 
-	jsonService := luaservices.NewJSONService()
+	jsonService := luaservices.NewJSONServiceWithConfig(luaservices.JSONServiceConfig{
+		Limits: ds.jsonLimits,
+	})
 	jsonService.Register(L)
 
 	// Load the script
@@ -202,13 +215,37 @@ func (ds *LuaDataSource) inputToLuaTable(L *lua.LState, input *service.DataSourc
 		if input.RequestAttributes.UserAgent != "" {
 			L.SetField(reqTbl, "user_agent", lua.LString(input.RequestAttributes.UserAgent))
 		}
+		if input.RequestAttributes.Protocol != "" {
+			L.SetField(reqTbl, "protocol", lua.LString(input.RequestAttributes.Protocol))
+		}
+		if input.RequestAttributes.Authority != "" {
+			L.SetField(reqTbl, "authority", lua.LString(input.RequestAttributes.Authority))
+		}
+		if input.RequestAttributes.ContentType != "" {
+			L.SetField(reqTbl, "content_type", lua.LString(input.RequestAttributes.ContentType))
+		}
+		if grpc := input.RequestAttributes.GRPC; grpc != nil {
+			grpcTbl := L.NewTable()
+			L.SetField(grpcTbl, "service", lua.LString(grpc.Service))
+			L.SetField(grpcTbl, "method", lua.LString(grpc.Method))
+			L.SetField(grpcTbl, "authority", lua.LString(grpc.Authority))
+			L.SetField(reqTbl, "grpc", grpcTbl)
+		}
 
 		if len(input.RequestAttributes.Headers) > 0 {
+			// headers holds combined values; header_values holds every value of repeated headers
 			headersTbl := L.NewTable()
-			for key, value := range input.RequestAttributes.Headers {
-				headersTbl.RawSetString(key, lua.LString(value))
+			headerValuesTbl := L.NewTable()
+			for key, values := range input.RequestAttributes.Headers {
+				headersTbl.RawSetString(key, lua.LString(input.RequestAttributes.Headers.Get(key)))
+				valuesTbl := L.NewTable()
+				for _, value := range values {
+					valuesTbl.Append(lua.LString(value))
+				}
+				headerValuesTbl.RawSetString(key, valuesTbl)
 			}
 			L.SetField(reqTbl, "headers", headersTbl)
+			L.SetField(reqTbl, "header_values", headerValuesTbl)
 		}
 
 		if len(input.RequestAttributes.Additional) > 0 {
@@ -246,12 +283,37 @@ func (ds *LuaDataSource) luaTableToResult(tbl *lua.LTable) (*service.DataSourceR
 		contentType = service.DataSourceContentType(lua.LVAsString(contentTypeField))
 	}
 
+	if contentType == service.ContentTypeJSON && !ds.jsonLimits.IsZero() {
+		bounded, err := ds.boundJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		data = bounded
+	}
+
 	return &service.DataSourceResult{
 		Data:        data,
 		ContentType: contentType,
 	}, nil
 }
 
+// boundJSON enforces JSON limits on data returned by the script, so downstream
+// consumers (such as CEL mappers) never parse more than the limits allow
+func (ds *LuaDataSource) boundJSON(data []byte) ([]byte, error) {
+	value, truncated, err := limits.DecodeJSON(bytes.NewReader(data), ds.jsonLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data from data source %s: %w", ds.name, err)
+	}
+	if !truncated {
+		return data, nil
+	}
+	bounded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encode truncated JSON: %w", err)
+	}
+	return bounded, nil
+}
+
 // luaTableToInput converts a Lua table to a DataSourceInput
 func (ds *LuaDataSource) luaTableToInput(tbl *lua.LTable) service.DataSourceInput {
 	input := service.DataSourceInput{}
@@ -290,17 +352,41 @@ func (ds *LuaDataSource) luaTableToInput(tbl *lua.LTable) service.DataSourceInpu
 	if reqLV := tbl.RawGetString("request_attributes"); reqLV.Type() == lua.LTTable {
 		reqTbl := reqLV.(*lua.LTable)
 		reqAttrs := &request.RequestAttributes{
-			Method:    lua.LVAsString(reqTbl.RawGetString("method")),
-			Path:      lua.LVAsString(reqTbl.RawGetString("path")),
-			IPAddress: lua.LVAsString(reqTbl.RawGetString("ip_address")),
-			UserAgent: lua.LVAsString(reqTbl.RawGetString("user_agent")),
+			Method:      lua.LVAsString(reqTbl.RawGetString("method")),
+			Path:        lua.LVAsString(reqTbl.RawGetString("path")),
+			IPAddress:   lua.LVAsString(reqTbl.RawGetString("ip_address")),
+			UserAgent:   lua.LVAsString(reqTbl.RawGetString("user_agent")),
+			Protocol:    lua.LVAsString(reqTbl.RawGetString("protocol")),
+			Authority:   lua.LVAsString(reqTbl.RawGetString("authority")),
+			ContentType: lua.LVAsString(reqTbl.RawGetString("content_type")),
 		}
 
-		if headersLV := reqTbl.RawGetString("headers"); headersLV.Type() == lua.LTTable {
-			headers := make(map[string]string)
+		if grpcLV := reqTbl.RawGetString("grpc"); grpcLV.Type() == lua.LTTable {
+			reqAttrs.GRPC = &request.GRPCAttributes{
+				Service:   lua.LVAsString(grpcLV.(*lua.LTable).RawGetString("service")),
+				Method:    lua.LVAsString(grpcLV.(*lua.LTable).RawGetString("method")),
+				Authority: lua.LVAsString(grpcLV.(*lua.LTable).RawGetString("authority")),
+			}
+		}
+
+		if valuesLV := reqTbl.RawGetString("header_values"); valuesLV.Type() == lua.LTTable {
+			headers := make(request.Headers)
+			valuesLV.(*lua.LTable).ForEach(func(k, v lua.LValue) {
+				if k.Type() != lua.LTString || v.Type() != lua.LTTable {
+					return
+				}
+				v.(*lua.LTable).ForEach(func(_, item lua.LValue) {
+					if item.Type() == lua.LTString {
+						headers.Add(k.String(), item.String())
+					}
+				})
+			})
+			reqAttrs.Headers = headers
+		} else if headersLV := reqTbl.RawGetString("headers"); headersLV.Type() == lua.LTTable {
+			headers := make(request.Headers)
 			headersLV.(*lua.LTable).ForEach(func(k, v lua.LValue) {
 				if k.Type() == lua.LTString && v.Type() == lua.LTString {
-					headers[k.String()] = v.String()
+					headers.Add(k.String(), v.String())
 				}
 			})
 			reqAttrs.Headers = headers
@@ -3,11 +3,13 @@ package datasource
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/alechenninger/parsec/internal/limits"
 	luaservices "github.com/alechenninger/parsec/internal/lua"
 	"github.com/alechenninger/parsec/internal/request"
 	"github.com/alechenninger/parsec/internal/service"
@@ -140,6 +142,74 @@ end
 	}
 }
 
+func TestLuaDataSource_Fetch_JSONLimits(t *testing.T) {
+	script := `
+function fetch(input)
+	return {
+		data = '{"groups": ["a", "b", "c", "d"], "name": "alice"}',
+		content_type = "application/json"
+	}
+end
+`
+
+	t.Run("truncates returned arrays", func(t *testing.T) {
+		ds, err := NewLuaDataSource(LuaDataSourceConfig{
+			Name:       "test",
+			Script:     script,
+			JSONLimits: limits.JSON{MaxArrayItems: 2, Policy: limits.PolicyTruncate},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result, err := ds.Fetch(context.Background(), &service.DataSourceInput{})
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+
+		want := `{"groups":["a","b"],"name":"alice"}`
+		if string(result.Data) != want {
+			t.Errorf("result.Data = %q, want %q", string(result.Data), want)
+		}
+	})
+
+	t.Run("rejects returned data over limits", func(t *testing.T) {
+		ds, err := NewLuaDataSource(LuaDataSourceConfig{
+			Name:       "test",
+			Script:     script,
+			JSONLimits: limits.JSON{MaxArrayItems: 2},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := ds.Fetch(context.Background(), &service.DataSourceInput{}); !errors.Is(err, limits.ErrLimitExceeded) {
+			t.Errorf("expected ErrLimitExceeded, got %v", err)
+		}
+	})
+
+	t.Run("leaves data within limits untouched", func(t *testing.T) {
+		ds, err := NewLuaDataSource(LuaDataSourceConfig{
+			Name:       "test",
+			Script:     script,
+			JSONLimits: limits.JSON{MaxArrayItems: 10, MaxDepth: 5},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		result, err := ds.Fetch(context.Background(), &service.DataSourceInput{})
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+
+		want := `{"groups": ["a", "b", "c", "d"], "name": "alice"}`
+		if string(result.Data) != want {
+			t.Errorf("result.Data = %q, want %q", string(result.Data), want)
+		}
+	})
+}
+
 func TestLuaDataSource_Fetch_NilReturn(t *testing.T) {
 	script := `
 function fetch(input)
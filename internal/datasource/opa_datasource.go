@@ -0,0 +1,137 @@
+package datasource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// maxOPAResponseBytes caps the size of OPA responses read
+const maxOPAResponseBytes = 10 << 20
+
+// OPADataSource queries a document of an Open Policy Agent server's Data API with
+// the subject, actor, and request attributes as input
+// The result is the document, such as a policy decision, or nothing if it is undefined.
+type OPADataSource struct {
+	name     string
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// OPADataSourceConfig configures an OPA data source
+type OPADataSourceConfig struct {
+	// Name identifies the data source
+	Name string
+
+	// URL is the OPA server's base URL (e.g., "http://localhost:8181")
+	URL string
+
+	// Path is the path of the document to query, like "parsec/authz"
+	Path string
+
+	// Token, if set, is sent as a bearer token
+	Token string
+
+	// Timeout bounds each query (default: 5 seconds)
+	Timeout time.Duration
+
+	// Transport sends queries (default: http.DefaultTransport)
+	Transport http.RoundTripper
+}
+
+// NewOPADataSource creates an OPA data source
+func NewOPADataSource(cfg OPADataSourceConfig) (*OPADataSource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("opa data source requires url")
+	}
+	path := strings.Trim(cfg.Path, "/")
+	if path == "" {
+		return nil, fmt.Errorf("opa data source requires path")
+	}
+	endpoint, err := url.JoinPath(cfg.URL, "v1", "data", path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid opa url: %w", err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &OPADataSource{
+		name:     cfg.Name,
+		endpoint: endpoint,
+		token:    cfg.Token,
+		client:   &http.Client{Transport: transport, Timeout: timeout},
+	}, nil
+}
+
+// Name implements service.DataSource
+func (o *OPADataSource) Name() string {
+	return o.name
+}
+
+// Fetch implements service.DataSource
+func (o *OPADataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	body, err := json.Marshal(struct {
+		Input *service.DataSourceInput `json:"input"`
+	}{input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal opa input: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create opa request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.token)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query opa: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxOPAResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read opa response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("opa query failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+
+	var decoded struct {
+		// Result is absent if the document is undefined
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to parse opa response: %w", err)
+	}
+	if decoded.Result == nil {
+		return nil, nil
+	}
+
+	return &service.DataSourceResult{
+		Data:        decoded.Result,
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
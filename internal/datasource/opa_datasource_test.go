@@ -0,0 +1,76 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestOPADataSource(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer opa-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Input service.DataSourceInput `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/data/parsec/authz":
+			json.NewEncoder(w).Encode(map[string]any{
+				"result": map[string]any{"allow": body.Input.Subject.Subject == "alice"},
+			})
+		case "/v1/data/parsec/undefined":
+			json.NewEncoder(w).Encode(map[string]any{})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	newSource := func(t *testing.T, path string) *OPADataSource {
+		t.Helper()
+		ds, err := NewOPADataSource(OPADataSourceConfig{Name: "policy", URL: server.URL, Path: path, Token: "opa-token"})
+		if err != nil {
+			t.Fatalf("NewOPADataSource failed: %v", err)
+		}
+		return ds
+	}
+
+	t.Run("returns the document for the input", func(t *testing.T) {
+		ds := newSource(t, "/parsec/authz")
+		for subject, want := range map[string]string{"alice": `{"allow":true}`, "bob": `{"allow":false}`} {
+			result, err := ds.Fetch(ctx, &service.DataSourceInput{Subject: &trust.Result{Subject: subject}})
+			if err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			if result == nil || string(result.Data) != want || result.ContentType != service.ContentTypeJSON {
+				t.Errorf("expected %s for %s, got %+v", want, subject, result)
+			}
+		}
+	})
+
+	t.Run("undefined documents are nothing", func(t *testing.T) {
+		result, err := newSource(t, "parsec/undefined").Fetch(ctx, &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}})
+		if err != nil || result != nil {
+			t.Errorf("expected nothing, got %+v, %v", result, err)
+		}
+	})
+
+	t.Run("errors fail the fetch", func(t *testing.T) {
+		if _, err := newSource(t, "parsec/missing").Fetch(ctx, &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}}); err == nil {
+			t.Error("expected an error status to fail the fetch")
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache"
+)
+
+// PeerUpdater is told the current peers of the distributed cache
+type PeerUpdater interface {
+	// UpdatePeers replaces the peers with peers, given as base URLs
+	UpdatePeers(peers ...string)
+}
+
+// PeerPool shares the groups of every DistributedCachingDataSource with its peers:
+// each key is owned by one peer, which fetches and caches it for the others
+// Peers serve each other over HTTP on a dedicated address.
+type PeerPool struct {
+	self    string
+	address string
+	pool    *groupcache.HTTPPool
+
+	mu         sync.Mutex
+	peers      []string
+	httpServer *http.Server
+}
+
+// PeerPoolConfig configures the peer pool
+type PeerPoolConfig struct {
+	// SelfURL is this instance's base URL, as its peers reach it (e.g., "http://10.0.0.1:8081")
+	SelfURL string
+
+	// Address is the address peer requests are served on (e.g., ":8081")
+	Address string
+}
+
+// NewPeerPool creates the peer pool of this process's distributed caches
+// groupcache allows only one pool per process, and distributed caches pick it up on
+// their first fetch, so create it once, before serving requests.
+func NewPeerPool(cfg PeerPoolConfig) (*PeerPool, error) {
+	if cfg.SelfURL == "" {
+		return nil, fmt.Errorf("peer pool requires its own url")
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("peer pool requires an address to serve peers on")
+	}
+	p := &PeerPool{
+		self:    cfg.SelfURL,
+		address: cfg.Address,
+		pool:    groupcache.NewHTTPPoolOpts(cfg.SelfURL, nil),
+	}
+	p.UpdatePeers()
+	return p, nil
+}
+
+// UpdatePeers implements PeerUpdater
+// This instance is always a peer, whether or not peers includes it.
+func (p *PeerPool) UpdatePeers(peers ...string) {
+	peers = append([]string{p.self}, peers...)
+	slices.Sort(peers)
+	peers = slices.Compact(peers)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if slices.Equal(peers, p.peers) {
+		return
+	}
+	p.peers = peers
+	p.pool.Set(peers...)
+}
+
+// Self returns this instance's base URL, as its peers reach it
+func (p *PeerPool) Self() string {
+	return p.self
+}
+
+// Peers returns the current peers, including this instance, sorted
+func (p *PeerPool) Peers() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.peers)
+}
+
+// Start serves peer requests in the background
+func (p *PeerPool) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", p.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on cache peer address %s: %w", p.address, err)
+	}
+
+	p.mu.Lock()
+	p.httpServer = &http.Server{
+		Handler:           p.pool,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	httpServer := p.httpServer
+	p.mu.Unlock()
+
+	go func() {
+		slog.Info("Cache peer server listening", "address", listener.Addr().String())
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Error("Cache peer server failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Stop gracefully stops serving peer requests
+func (p *PeerPool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	httpServer := p.httpServer
+	p.mu.Unlock()
+	if httpServer == nil {
+		return nil
+	}
+	return httpServer.Shutdown(ctx)
+}
+
+// podIPEnv is the environment variable a pod's IP is usually exposed in, with the
+// downward API (fieldRef: status.podIP)
+const podIPEnv = "POD_IP"
+
+// DetectSelfPeerURL returns the base URL peers reach this instance at on port: the
+// pod IP in $POD_IP, or else the first non-loopback address of this host
+func DetectSelfPeerURL(scheme string, port int) (string, error) {
+	ip := os.Getenv(podIPEnv)
+	if ip == "" {
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return "", fmt.Errorf("failed to list interface addresses: %w", err)
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+				ip = ipNet.IP.String()
+				break
+			}
+		}
+	}
+	if ip == "" {
+		return "", fmt.Errorf("cannot detect own address: set $%s or the self url", podIPEnv)
+	}
+	return peerURL(scheme, ip, port), nil
+}
+
+// peerURL returns the base URL of a peer at ip and port
+func peerURL(scheme, ip string, port int) string {
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(ip, fmt.Sprint(port)))
+}
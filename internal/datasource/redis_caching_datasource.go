@@ -0,0 +1,155 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// DefaultRedisCacheKeyPrefix is the default prefix of the Redis keys of cached results
+const DefaultRedisCacheKeyPrefix = "parsec:datasource:"
+
+// RedisCachingDataSource wraps a cacheable data source with a cache in Redis, shared
+// by every replica using the same Redis server
+// Each result is a key that Redis expires after the TTL. Redis failures do not fail
+// fetches: they fall back to the data source.
+type RedisCachingDataSource struct {
+	source    service.DataSource
+	cacheable service.Cacheable
+	client    redis.UniversalClient
+	prefix    string
+	ttl       time.Duration
+	fetches   singleflight.Group
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// RedisCachingConfig configures the Redis caching data source
+type RedisCachingConfig struct {
+	// Client is the Redis client. The caller owns it.
+	Client redis.UniversalClient
+
+	// KeyPrefix prefixes the Redis keys of cached results (default: DefaultRedisCacheKeyPrefix)
+	// The data source's name follows it.
+	KeyPrefix string
+
+	// TTL is how long results are cached (default: the data source's CacheTTL)
+	TTL time.Duration
+}
+
+// NewRedisCachingDataSource wraps a data source with caching in Redis
+// Returns the original source if it doesn't implement Cacheable
+func NewRedisCachingDataSource(source service.DataSource, cfg RedisCachingConfig) (service.DataSource, error) {
+	cacheable, ok := source.(service.Cacheable)
+	if !ok {
+		// Source is not cacheable, return as-is
+		return source, nil
+	}
+
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redis caching requires a client")
+	}
+
+	// Redis keeps entries without an expiry forever, so a TTL is required
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = cacheable.CacheTTL()
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("redis caching of data source %s requires a positive ttl", source.Name())
+	}
+
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultRedisCacheKeyPrefix
+	}
+
+	return &RedisCachingDataSource{
+		source:    source,
+		cacheable: cacheable,
+		client:    cfg.Client,
+		prefix:    prefix + source.Name() + ":",
+		ttl:       ttl,
+	}, nil
+}
+
+// Name forwards to the underlying data source
+func (c *RedisCachingDataSource) Name() string {
+	return c.source.Name()
+}
+
+// Fetch checks Redis first, then fetches from source on miss and stores the result
+func (c *RedisCachingDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	// Get the cache key (which is the masked input with only relevant fields)
+	maskedInput := c.cacheable.CacheKey(input)
+
+	cacheKeyStr, err := serializeInput(&maskedInput)
+	if err != nil {
+		// If serialization fails, skip caching and fetch directly
+		return c.source.Fetch(ctx, input)
+	}
+	key := c.prefix + cacheKeyStr
+
+	cachedBytes, err := c.client.Get(ctx, key).Bytes()
+	switch {
+	case err == nil:
+		var entry cachedEntry
+		if err := json.Unmarshal(cachedBytes, &entry); err == nil {
+			c.hits.Add(1)
+			service.DataSourceFetchProbeFromContext(ctx).CacheHit()
+			return &service.DataSourceResult{
+				Data:        entry.Data,
+				ContentType: entry.ContentType,
+			}, nil
+		}
+		slog.WarnContext(ctx, "Discarding unreadable cache entry", "data_source", c.source.Name(), "error", err)
+	case !errors.Is(err, redis.Nil):
+		slog.WarnContext(ctx, "Failed to read data source cache", "data_source", c.source.Name(), "error", err)
+	}
+
+	// Cache miss - fetch from source using the original (full) input, once for
+	// every concurrent miss of the same key in this process
+	c.misses.Add(1)
+	service.DataSourceFetchProbeFromContext(ctx).CacheMissed()
+	return fetchOnce(ctx, &c.fetches, key, func(ctx context.Context) (*service.DataSourceResult, error) {
+		result, err := c.source.Fetch(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			return nil, nil
+		}
+
+		entryBytes, err := json.Marshal(cachedEntry{
+			Data:        result.Data,
+			ContentType: result.ContentType,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal cache entry: %w", err)
+		}
+		if err := c.client.Set(ctx, key, entryBytes, c.ttl).Err(); err != nil {
+			slog.WarnContext(ctx, "Failed to write data source cache", "data_source", c.source.Name(), "error", err)
+		}
+
+		return result, nil
+	})
+}
+
+// CacheStats implements service.CacheStatsReporter
+// Hits and misses are of this process's fetches; entries are shared in Redis and not counted.
+func (c *RedisCachingDataSource) CacheStats() service.CacheStats {
+	return service.CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
@@ -0,0 +1,89 @@
+package datasource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestRedisCachingDataSource(t *testing.T) {
+	ctx := context.Background()
+	alice := &service.DataSourceInput{Subject: &trust.Result{Subject: "alice", Issuer: "https://idp.example.com"}}
+
+	newCache := func(t *testing.T, server *miniredis.Miniredis, source *mockCacheableDataSource) service.DataSource {
+		t.Helper()
+		ds, err := NewRedisCachingDataSource(source, RedisCachingConfig{
+			Client: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+		})
+		if err != nil {
+			t.Fatalf("NewRedisCachingDataSource failed: %v", err)
+		}
+		return ds
+	}
+
+	t.Run("replicas share cached results until they expire", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		source := &mockCacheableDataSource{name: "roles", ttl: time.Minute}
+		replica1, replica2 := newCache(t, server, source), newCache(t, server, source)
+
+		first, err := replica1.Fetch(ctx, alice)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		second, err := replica2.Fetch(ctx, alice)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if source.fetchCount != 1 || string(second.Data) != string(first.Data) || second.ContentType != service.ContentTypeJSON {
+			t.Errorf("expected the second replica to get the cached result, got %d fetches and %q", source.fetchCount, second.Data)
+		}
+
+		keys := server.Keys()
+		if len(keys) != 1 || server.TTL(keys[0]) != time.Minute {
+			t.Errorf("expected one key expiring with the TTL, got %v", keys)
+		}
+
+		server.FastForward(time.Minute)
+		if _, err := replica1.Fetch(ctx, alice); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if source.fetchCount != 2 {
+			t.Errorf("expected an expired result to be fetched again, got %d fetches", source.fetchCount)
+		}
+
+		stats := replica1.(service.CacheStatsReporter).CacheStats()
+		if stats.Hits != 0 || stats.Misses != 2 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("fetches from the source when redis is down", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		source := &mockCacheableDataSource{name: "roles", ttl: time.Minute}
+		cache := newCache(t, server, source)
+		server.Close()
+
+		if _, err := cache.Fetch(ctx, alice); err != nil {
+			t.Fatalf("expected fetch to fall back to the source, got %v", err)
+		}
+		if source.fetchCount != 1 {
+			t.Errorf("expected 1 fetch, got %d", source.fetchCount)
+		}
+	})
+
+	t.Run("requires a ttl", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		_, err := NewRedisCachingDataSource(&mockCacheableDataSource{name: "roles"}, RedisCachingConfig{
+			Client: redis.NewClient(&redis.Options{Addr: server.Addr()}),
+		})
+		if err == nil {
+			t.Error("expected an error without a ttl")
+		}
+	})
+}
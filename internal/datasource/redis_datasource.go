@@ -0,0 +1,212 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// RedisDataSource looks up JSON values in Redis under keys built from the subject
+// and actor, such as "entitlements:{sub}", without a script or HTTP call
+// With one key, the result is its value. With several, all are fetched in one
+// pipeline and the result is an object of their values by name.
+type RedisDataSource struct {
+	name   string
+	client redis.UniversalClient
+	key    keyTemplate
+	keys   map[string]keyTemplate
+}
+
+// RedisDataSourceConfig configures a Redis data source
+type RedisDataSourceConfig struct {
+	// Name identifies the data source
+	Name string
+
+	// Client is the Redis client. The caller owns it.
+	Client redis.UniversalClient
+
+	// Key is the template of the one key to look up (alternative to Keys)
+	Key string
+
+	// Keys are the templates of several keys to look up, by the name of their value
+	// in the result
+	Keys map[string]string
+}
+
+// keyPlaceholders are what key templates may refer to, with how to get each from input
+var keyPlaceholders = map[string]func(*service.DataSourceInput) string{
+	"sub": func(in *service.DataSourceInput) string {
+		if in.Subject == nil {
+			return ""
+		}
+		return in.Subject.Subject
+	},
+	"iss": func(in *service.DataSourceInput) string {
+		if in.Subject == nil {
+			return ""
+		}
+		return in.Subject.Issuer
+	},
+	"trust_domain": func(in *service.DataSourceInput) string {
+		if in.Subject == nil {
+			return ""
+		}
+		return in.Subject.TrustDomain
+	},
+	"actor_sub": func(in *service.DataSourceInput) string {
+		if in.Actor == nil {
+			return ""
+		}
+		return in.Actor.Subject
+	},
+	"actor_iss": func(in *service.DataSourceInput) string {
+		if in.Actor == nil {
+			return ""
+		}
+		return in.Actor.Issuer
+	},
+}
+
+// placeholderPattern matches a {placeholder} in a key template
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]*)\}`)
+
+// keyTemplate is a parsed key template
+type keyTemplate string
+
+// parseKeyTemplate checks that template refers only to known placeholders
+func parseKeyTemplate(template string) (keyTemplate, error) {
+	if template == "" {
+		return "", fmt.Errorf("key template must not be empty")
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if _, ok := keyPlaceholders[match[1]]; !ok {
+			return "", fmt.Errorf("unknown placeholder %s in key template %q (supported: %v)",
+				match[0], template, slices.Sorted(maps.Keys(keyPlaceholders)))
+		}
+	}
+	return keyTemplate(template), nil
+}
+
+// expand returns the key for input, or false if a placeholder has no value
+func (t keyTemplate) expand(input *service.DataSourceInput) (string, bool) {
+	complete := true
+	key := placeholderPattern.ReplaceAllStringFunc(string(t), func(placeholder string) string {
+		value := keyPlaceholders[placeholder[1:len(placeholder)-1]](input)
+		if value == "" {
+			complete = false
+		}
+		return value
+	})
+	return key, complete
+}
+
+// NewRedisDataSource creates a Redis data source
+func NewRedisDataSource(cfg RedisDataSourceConfig) (*RedisDataSource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redis data source requires a client")
+	}
+	if (cfg.Key == "") == (len(cfg.Keys) == 0) {
+		return nil, fmt.Errorf("redis data source requires exactly one of key or keys")
+	}
+
+	ds := &RedisDataSource{
+		name:   cfg.Name,
+		client: cfg.Client,
+	}
+	if cfg.Key != "" {
+		key, err := parseKeyTemplate(cfg.Key)
+		if err != nil {
+			return nil, err
+		}
+		ds.key = key
+		return ds, nil
+	}
+
+	ds.keys = make(map[string]keyTemplate, len(cfg.Keys))
+	for name, template := range cfg.Keys {
+		key, err := parseKeyTemplate(template)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", name, err)
+		}
+		ds.keys[name] = key
+	}
+	return ds, nil
+}
+
+// Name implements service.DataSource
+func (r *RedisDataSource) Name() string {
+	return r.name
+}
+
+// Fetch implements service.DataSource
+// Keys that do not exist, or refer to a placeholder the input has no value for,
+// are null. A value that is not JSON, or a Redis failure, fails the fetch.
+func (r *RedisDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	if r.keys == nil {
+		key, ok := r.key.expand(input)
+		if !ok {
+			return nil, nil
+		}
+		value, err := r.client.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s from redis: %w", key, err)
+		}
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("value of %s in redis is not JSON", key)
+		}
+		return &service.DataSourceResult{Data: value, ContentType: service.ContentTypeJSON}, nil
+	}
+
+	gets := make(map[string]*redis.StringCmd, len(r.keys))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for name, template := range r.keys {
+			if key, ok := template.expand(input); ok {
+				gets[name] = pipe.Get(ctx, key)
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get keys from redis: %w", err)
+	}
+
+	values := make(map[string]json.RawMessage, len(r.keys))
+	for name := range r.keys {
+		values[name] = json.RawMessage("null")
+		get, ok := gets[name]
+		if !ok {
+			continue
+		}
+		value, err := get.Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s from redis: %w", get.Args()[1], err)
+		}
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("value of %s in redis is not JSON", get.Args()[1])
+		}
+		values[name] = value
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal values: %w", err)
+	}
+	return &service.DataSourceResult{Data: data, ContentType: service.ContentTypeJSON}, nil
+}
@@ -0,0 +1,84 @@
+package datasource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestRedisDataSource(t *testing.T) {
+	ctx := context.Background()
+	alice := &service.DataSourceInput{Subject: &trust.Result{Subject: "alice", Issuer: "https://idp.example.com"}}
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	server.Set("entitlements:alice", `["read","write"]`)
+	server.Set("groups:https://idp.example.com:alice", `{"groups":["admins"]}`)
+
+	t.Run("one key is the result", func(t *testing.T) {
+		ds, err := NewRedisDataSource(RedisDataSourceConfig{Name: "entitlements", Client: client, Key: "entitlements:{sub}"})
+		if err != nil {
+			t.Fatalf("NewRedisDataSource failed: %v", err)
+		}
+
+		result, err := ds.Fetch(ctx, alice)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if result == nil || string(result.Data) != `["read","write"]` || result.ContentType != service.ContentTypeJSON {
+			t.Errorf("expected the key's value, got %+v", result)
+		}
+
+		bob := &service.DataSourceInput{Subject: &trust.Result{Subject: "bob"}}
+		if result, err := ds.Fetch(ctx, bob); err != nil || result != nil {
+			t.Errorf("expected nothing for a missing key, got %+v, %v", result, err)
+		}
+	})
+
+	t.Run("several keys are an object", func(t *testing.T) {
+		ds, err := NewRedisDataSource(RedisDataSourceConfig{
+			Name:   "profile",
+			Client: client,
+			Keys: map[string]string{
+				"entitlements": "entitlements:{sub}",
+				"groups":       "groups:{iss}:{sub}",
+				"missing":      "missing:{sub}",
+				"delegated":    "delegations:{actor_sub}",
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewRedisDataSource failed: %v", err)
+		}
+
+		result, err := ds.Fetch(ctx, alice)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		want := `{"delegated":null,"entitlements":["read","write"],"groups":{"groups":["admins"]},"missing":null}`
+		if result == nil || string(result.Data) != want {
+			t.Errorf("expected %s, got %+v", want, result)
+		}
+	})
+
+	t.Run("values must be JSON", func(t *testing.T) {
+		server.Set("entitlements:mallory", "not json")
+		ds, err := NewRedisDataSource(RedisDataSourceConfig{Name: "entitlements", Client: client, Key: "entitlements:{sub}"})
+		if err != nil {
+			t.Fatalf("NewRedisDataSource failed: %v", err)
+		}
+		if _, err := ds.Fetch(ctx, &service.DataSourceInput{Subject: &trust.Result{Subject: "mallory"}}); err == nil {
+			t.Error("expected a value that is not JSON to fail the fetch")
+		}
+	})
+
+	t.Run("rejects unknown placeholders", func(t *testing.T) {
+		if _, err := NewRedisDataSource(RedisDataSourceConfig{Name: "x", Client: client, Key: "entitlements:{email}"}); err == nil {
+			t.Error("expected an unknown placeholder to be rejected")
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package denylist
+
+import (
+	"context"
+	"time"
+)
+
+// Denylist records revoked tokens by their token ID (jti claim) until they expire
+// Tokens are denied until the time they would have expired anyway, so entries
+// never outlive the tokens they deny.
+type Denylist interface {
+	// Deny denies the token with the given ID until expiresAt
+	Deny(ctx context.Context, tokenID string, expiresAt time.Time) error
+
+	// IsDenied reports whether the token with the given ID has been denied
+	IsDenied(ctx context.Context, tokenID string) (bool, error)
+}
@@ -0,0 +1,83 @@
+package denylist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+func testDenylist(t *testing.T, newDenylist func(t *testing.T, clk clock.Clock) (Denylist, func(time.Duration))) {
+	ctx := context.Background()
+
+	isDenied := func(t *testing.T, d Denylist, tokenID string) bool {
+		t.Helper()
+		denied, err := d.IsDenied(ctx, tokenID)
+		if err != nil {
+			t.Fatalf("failed to check denylist: %v", err)
+		}
+		return denied
+	}
+
+	t.Run("denies until expiry", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		d, advance := newDenylist(t, clk)
+
+		if err := d.Deny(ctx, "token-1", clk.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("failed to deny: %v", err)
+		}
+		if !isDenied(t, d, "token-1") {
+			t.Error("expected token-1 to be denied")
+		}
+		if isDenied(t, d, "token-2") {
+			t.Error("expected token-2 not to be denied")
+		}
+
+		advance(time.Minute)
+		if isDenied(t, d, "token-1") {
+			t.Error("expected token-1 to be forgotten once expired")
+		}
+	})
+
+	t.Run("ignores expired tokens", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		d, _ := newDenylist(t, clk)
+
+		if err := d.Deny(ctx, "expired", clk.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("failed to deny: %v", err)
+		}
+		if isDenied(t, d, "expired") {
+			t.Error("expected an expired token not to be recorded")
+		}
+	})
+}
+
+func TestMemoryDenylist(t *testing.T) {
+	testDenylist(t, func(t *testing.T, clk clock.Clock) (Denylist, func(time.Duration)) {
+		fixture := clk.(*clock.FixtureClock)
+		return NewMemoryDenylist(clk), fixture.Advance
+	})
+}
+
+func TestRedisDenylist(t *testing.T) {
+	testDenylist(t, func(t *testing.T, clk clock.Clock) (Denylist, func(time.Duration)) {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		d, err := NewRedisDenylist(RedisDenylistConfig{Client: client, Clock: clk})
+		if err != nil {
+			t.Fatalf("failed to create denylist: %v", err)
+		}
+		// Redis expires keys on its own clock
+		fixture := clk.(*clock.FixtureClock)
+		return d, func(d time.Duration) {
+			fixture.Advance(d)
+			server.FastForward(d)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package denylist
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// MemoryDenylist keeps denied token IDs in memory
+// Entries are not shared between replicas, so it suits single-replica deployments and tests.
+type MemoryDenylist struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	denied map[string]time.Time
+}
+
+// NewMemoryDenylist creates a new in-memory denylist
+// clk is optional (defaults to system clock)
+func NewMemoryDenylist(clk clock.Clock) *MemoryDenylist {
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &MemoryDenylist{
+		clock:  clk,
+		denied: make(map[string]time.Time),
+	}
+}
+
+// Deny implements Denylist
+// Expired entries are removed as new ones are added.
+func (d *MemoryDenylist) Deny(_ context.Context, tokenID string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	maps.DeleteFunc(d.denied, func(_ string, until time.Time) bool {
+		return !now.Before(until)
+	})
+	if now.Before(expiresAt) {
+		d.denied[tokenID] = expiresAt
+	}
+	return nil
+}
+
+// IsDenied implements Denylist
+func (d *MemoryDenylist) IsDenied(_ context.Context, tokenID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	until, ok := d.denied[tokenID]
+	return ok && d.clock.Now().Before(until), nil
+}
@@ -0,0 +1,76 @@
+package denylist
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// DefaultRedisKeyPrefix is the default prefix of the Redis keys of denied token IDs
+const DefaultRedisKeyPrefix = "parsec:denylist:"
+
+// RedisDenylist keeps denied token IDs in Redis, so a token revoked on any replica is
+// denied by every replica and verifier sharing the Redis server
+// Each denied token ID is a key that Redis expires with the token.
+type RedisDenylist struct {
+	client redis.UniversalClient
+	prefix string
+	clock  clock.Clock
+}
+
+// RedisDenylistConfig configures the Redis denylist
+type RedisDenylistConfig struct {
+	// Client is the Redis client. The caller owns it.
+	Client redis.UniversalClient
+
+	// KeyPrefix prefixes the Redis keys of denied token IDs (default: DefaultRedisKeyPrefix)
+	KeyPrefix string
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// NewRedisDenylist creates a new Redis-backed denylist
+func NewRedisDenylist(cfg RedisDenylistConfig) (*RedisDenylist, error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redis denylist requires a client")
+	}
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultRedisKeyPrefix
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &RedisDenylist{
+		client: cfg.Client,
+		prefix: prefix,
+		clock:  clk,
+	}, nil
+}
+
+// Deny implements Denylist
+func (d *RedisDenylist) Deny(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := expiresAt.Sub(d.clock.Now())
+	if ttl <= 0 {
+		return nil
+	}
+	if err := d.client.Set(ctx, d.prefix+tokenID, expiresAt.Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to deny token: %w", err)
+	}
+	return nil
+}
+
+// IsDenied implements Denylist
+func (d *RedisDenylist) IsDenied(ctx context.Context, tokenID string) (bool, error) {
+	n, err := d.client.Exists(ctx, d.prefix+tokenID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check denylist: %w", err)
+	}
+	return n > 0, nil
+}
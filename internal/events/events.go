@@ -0,0 +1,84 @@
+// Package events publishes token issuance and denial events to external systems,
+// such as a SIEM, in batches.
+//
+// Events are queued and sent in the background, so publishing never waits on the
+// sink unless the publisher is configured to block when its queue is full.
+package events
+
+import (
+	"time"
+
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// Event types
+const (
+	// TypeTokenIssued is published for each token issued
+	TypeTokenIssued = "token.issued"
+
+	// TypeTokenIssuanceFailed is published when a token could not be issued
+	TypeTokenIssuanceFailed = "token.issuance_failed"
+
+	// TypeAuthzCheckDenied is published when an authorization check is denied
+	// before any token is issued (e.g., the credential is missing or invalid)
+	TypeAuthzCheckDenied = "authz_check.denied"
+
+	// TypeTokenExchangeDenied is published when a token exchange is denied
+	// before any token is issued
+	TypeTokenExchangeDenied = "token_exchange.denied"
+)
+
+// Event is an issuance or denial event
+type Event struct {
+	// ID uniquely identifies the event, so consumers can discard events that
+	// were delivered more than once
+	ID string `json:"id"`
+
+	Type     string             `json:"type"`
+	Time     time.Time          `json:"time"`
+	Instance *instance.Identity `json:"instance,omitempty"`
+
+	Subject *Identity `json:"subject,omitempty"`
+	Actor   *Identity `json:"actor,omitempty"`
+
+	// Request is the request being authorized, for authorization checks
+	Request *Request `json:"request,omitempty"`
+
+	// GrantType and Audiences are what was requested, for token exchanges
+	GrantType string   `json:"grant_type,omitempty"`
+	Audiences []string `json:"audiences,omitempty"`
+
+	Scope         string     `json:"scope,omitempty"`
+	TokenType     string     `json:"token_type,omitempty"`
+	TransactionID string     `json:"txn,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+
+	// Reason is why the token was not issued
+	Reason string `json:"reason,omitempty"`
+}
+
+// Identity identifies a subject or actor
+type Identity struct {
+	Subject     string `json:"sub,omitempty"`
+	Issuer      string `json:"iss,omitempty"`
+	TrustDomain string `json:"trust_domain,omitempty"`
+}
+
+// IdentityOf returns the identity of a validation result, or nil if it has no subject
+func IdentityOf(result *trust.Result) *Identity {
+	if result == nil || result.Subject == "" {
+		return nil
+	}
+	return &Identity{
+		Subject:     result.Subject,
+		Issuer:      result.Issuer,
+		TrustDomain: result.TrustDomain,
+	}
+}
+
+// Request describes the request being authorized
+type Request struct {
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+}
@@ -0,0 +1,269 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults for PublisherConfig
+const (
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+	DefaultQueueSize     = 10000
+	DefaultMaxRetries    = 5
+	DefaultRetryBackoff  = 500 * time.Millisecond
+
+	// maxRetryBackoff caps the exponential backoff between attempts
+	maxRetryBackoff = 30 * time.Second
+)
+
+// Sink delivers batches of events
+type Sink interface {
+	// Send delivers events, all or none
+	// Errors that a retry cannot fix should wrap ErrNotRetryable.
+	Send(ctx context.Context, events []Event) error
+}
+
+// ErrNotRetryable marks a Send error that retrying would not fix
+var ErrNotRetryable = errors.New("not retryable")
+
+// PublisherConfig configures a Publisher
+type PublisherConfig struct {
+	// Sink receives batches of events
+	Sink Sink
+
+	// BatchSize is the most events sent at once
+	// Default: DefaultBatchSize
+	BatchSize int
+
+	// FlushInterval is the longest an event waits for its batch to fill
+	// Default: DefaultFlushInterval
+	FlushInterval time.Duration
+
+	// QueueSize is the most events waiting to be sent
+	// Default: DefaultQueueSize
+	QueueSize int
+
+	// MaxRetries is how many times a failed batch is retried before it is dropped
+	// Default: DefaultMaxRetries; negative disables retries
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry, doubling with each attempt
+	// Default: DefaultRetryBackoff
+	RetryBackoff time.Duration
+
+	// Block makes Publish wait for room when the queue is full, slowing the
+	// requests that publish events until the sink catches up
+	// Otherwise, events published to a full queue are dropped.
+	Block bool
+}
+
+// Publisher queues events and sends them to a sink in batches, in the background
+//
+// A batch that fails is retried with exponential backoff while new events queue
+// up behind it; once the queue is full, events are dropped or Publish blocks,
+// depending on PublisherConfig.Block.
+type Publisher struct {
+	sink          Sink
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	retryBackoff  time.Duration
+	block         bool
+
+	queue   chan Event
+	dropped atomic.Int64
+
+	// reported is how many dropped events have been logged, by run
+	reported int64
+
+	// ctx bounds sends and retries; it is canceled if Close gives up waiting
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewPublisher creates a publisher and starts sending events in the background
+func NewPublisher(cfg PublisherConfig) (*Publisher, error) {
+	if cfg.Sink == nil {
+		return nil, fmt.Errorf("publisher requires a sink")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	} else if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Publisher{
+		sink:          cfg.Sink,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		maxRetries:    cfg.MaxRetries,
+		retryBackoff:  cfg.RetryBackoff,
+		block:         cfg.Block,
+		queue:         make(chan Event, cfg.QueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go p.run()
+	return p, nil
+}
+
+// Publish queues event to be sent
+// If the queue is full, event is dropped, or with Block, Publish waits for room
+// until ctx is done.
+func (p *Publisher) Publish(ctx context.Context, event Event) {
+	if p.closed.Load() {
+		p.dropped.Add(1)
+		return
+	}
+
+	if p.block {
+		select {
+		case p.queue <- event:
+		case <-ctx.Done():
+			p.dropped.Add(1)
+		case <-p.stop:
+			p.dropped.Add(1)
+		}
+		return
+	}
+
+	select {
+	case p.queue <- event:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many events have been dropped, because the queue was full
+// or their batch could not be sent
+func (p *Publisher) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Close sends the events already queued and stops the publisher
+// If ctx is done first, the remaining events are dropped.
+func (p *Publisher) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() {
+		p.closed.Store(true)
+		close(p.stop)
+	})
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-p.done
+		return ctx.Err()
+	}
+}
+
+// run collects events into batches and sends them until the publisher is closed
+func (p *Publisher) run() {
+	defer close(p.done)
+	defer p.cancel()
+
+	batch := make([]Event, 0, p.batchSize)
+	timer := time.NewTimer(p.flushInterval)
+	defer timer.Stop()
+
+	flush := func() {
+		if len(batch) > 0 {
+			p.send(batch)
+			batch = make([]Event, 0, p.batchSize)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(p.flushInterval)
+	}
+
+	for {
+		select {
+		case event := <-p.queue:
+			batch = append(batch, event)
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+
+		case <-timer.C:
+			flush()
+
+		case <-p.stop:
+			for {
+				select {
+				case event := <-p.queue:
+					batch = append(batch, event)
+					if len(batch) >= p.batchSize {
+						p.send(batch)
+						batch = make([]Event, 0, p.batchSize)
+					}
+				default:
+					if len(batch) > 0 {
+						p.send(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// send sends batch, retrying with exponential backoff
+// A batch that still fails is dropped and reported, so one bad batch cannot stop
+// later events from being sent.
+func (p *Publisher) send(batch []Event) {
+	if dropped := p.dropped.Load(); dropped > p.reported {
+		slog.Warn("Dropped events since the last batch", "count", dropped-p.reported)
+		p.reported = dropped
+	}
+
+	backoff := p.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := p.sink.Send(p.ctx, batch)
+		if err == nil {
+			return
+		}
+		if attempt >= p.maxRetries || errors.Is(err, ErrNotRetryable) || p.ctx.Err() != nil {
+			slog.Warn("Failed to send events", "count", len(batch), "attempts", attempt+1, "error", err)
+			p.dropped.Add(int64(len(batch)))
+			p.reported += int64(len(batch))
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-p.ctx.Done():
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
@@ -0,0 +1,238 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink records batches, failing the first failures sends with err
+type recordingSink struct {
+	mu       sync.Mutex
+	batches  [][]Event
+	attempts int
+	failures int
+	err      error
+
+	// release, if set, is waited on by each send
+	release chan struct{}
+}
+
+func (s *recordingSink) Send(ctx context.Context, events []Event) error {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.attempts <= s.failures {
+		return s.err
+	}
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *recordingSink) sent() (batches [][]Event, attempts int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches, s.attempts
+}
+
+func publish(p *Publisher, n int) {
+	for i := 0; i < n; i++ {
+		p.Publish(context.Background(), Event{ID: fmt.Sprint(i), Type: TypeTokenIssued})
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	t.Run("sends full batches and flushes the rest on close", func(t *testing.T) {
+		sink := &recordingSink{}
+		p, err := NewPublisher(PublisherConfig{Sink: sink, BatchSize: 2, FlushInterval: time.Hour})
+		if err != nil {
+			t.Fatalf("failed to create publisher: %v", err)
+		}
+		publish(p, 5)
+		if err := p.Close(context.Background()); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		batches, _ := sink.sent()
+		if len(batches) != 3 || len(batches[0]) != 2 || len(batches[2]) != 1 {
+			t.Errorf("expected batches of 2, 2, and 1, got %v", batches)
+		}
+		if batches[0][0].ID != "0" || batches[2][0].ID != "4" {
+			t.Errorf("expected events in order, got %v", batches)
+		}
+	})
+
+	t.Run("flushes partial batches on interval", func(t *testing.T) {
+		sink := &recordingSink{}
+		p, _ := NewPublisher(PublisherConfig{Sink: sink, BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+		defer p.Close(context.Background())
+		publish(p, 1)
+
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) {
+			if batches, _ := sink.sent(); len(batches) == 1 {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Error("expected batch to be sent before the batch filled")
+	})
+
+	t.Run("retries failed batches", func(t *testing.T) {
+		sink := &recordingSink{failures: 2, err: errors.New("unavailable")}
+		p, _ := NewPublisher(PublisherConfig{Sink: sink, MaxRetries: 2, RetryBackoff: time.Millisecond})
+		publish(p, 1)
+		p.Close(context.Background())
+
+		batches, attempts := sink.sent()
+		if len(batches) != 1 || attempts != 3 {
+			t.Errorf("expected batch sent on third attempt, got %d batches in %d attempts", len(batches), attempts)
+		}
+		if p.Dropped() != 0 {
+			t.Errorf("expected nothing dropped, got %d", p.Dropped())
+		}
+	})
+
+	t.Run("drops batches that cannot be sent", func(t *testing.T) {
+		sink := &recordingSink{failures: 1, err: fmt.Errorf("bad request: %w", ErrNotRetryable)}
+		p, _ := NewPublisher(PublisherConfig{Sink: sink, BatchSize: 2, RetryBackoff: time.Millisecond})
+		publish(p, 3)
+		p.Close(context.Background())
+
+		batches, attempts := sink.sent()
+		if len(batches) != 1 || attempts != 2 {
+			t.Errorf("expected first batch dropped without retry, got %d batches in %d attempts", len(batches), attempts)
+		}
+		if p.Dropped() != 2 {
+			t.Errorf("expected 2 dropped, got %d", p.Dropped())
+		}
+	})
+
+	t.Run("drops events when the queue is full", func(t *testing.T) {
+		sink := &recordingSink{release: make(chan struct{})}
+		p, _ := NewPublisher(PublisherConfig{Sink: sink, BatchSize: 1, QueueSize: 2})
+
+		// The first event is taken from the queue and held by the sink, the next
+		// two fill the queue, and the rest are dropped
+		publish(p, 1)
+		deadline := time.Now().Add(time.Second)
+		for len(p.queue) > 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		publish(p, 4)
+		if p.Dropped() != 2 {
+			t.Errorf("expected 2 dropped, got %d", p.Dropped())
+		}
+
+		close(sink.release)
+		p.Close(context.Background())
+		if batches, _ := sink.sent(); len(batches) != 3 {
+			t.Errorf("expected 3 events sent, got %d", len(batches))
+		}
+	})
+
+	t.Run("blocks when the queue is full", func(t *testing.T) {
+		sink := &recordingSink{release: make(chan struct{})}
+		p, _ := NewPublisher(PublisherConfig{Sink: sink, BatchSize: 1, QueueSize: 1, Block: true})
+		publish(p, 2)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		p.Publish(ctx, Event{Type: TypeTokenIssued})
+		if ctx.Err() == nil {
+			t.Error("expected Publish to wait for room in the queue")
+		}
+		if p.Dropped() != 1 {
+			t.Errorf("expected event dropped once ctx was done, got %d", p.Dropped())
+		}
+
+		close(sink.release)
+		p.Close(context.Background())
+	})
+
+	t.Run("close gives up when ctx is done", func(t *testing.T) {
+		sink := &recordingSink{failures: 100, err: errors.New("unavailable")}
+		p, _ := NewPublisher(PublisherConfig{Sink: sink, RetryBackoff: time.Hour})
+		publish(p, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := p.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+		if p.Dropped() != 1 {
+			t.Errorf("expected 1 dropped, got %d", p.Dropped())
+		}
+	})
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode events: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewWebhookSink(WebhookSinkConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	if err := sink.Send(context.Background(), []Event{{ID: "1"}, {ID: "2"}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(received) != 2 || received[1].ID != "2" {
+		t.Errorf("unexpected events %+v", received)
+	}
+
+	unauthorized, _ := NewWebhookSink(WebhookSinkConfig{URL: server.URL})
+	if err := unauthorized.Send(context.Background(), []Event{{ID: "1"}}); !errors.Is(err, ErrNotRetryable) {
+		t.Errorf("expected client error not to be retryable, got %v", err)
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	var produced kafkaProduceRequest
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/topics/parsec-events" || r.Header.Get("Content-Type") != "application/vnd.kafka.json.v2+json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&produced); err != nil {
+			t.Errorf("failed to decode produce request: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := NewKafkaSink(KafkaSinkConfig{URL: server.URL, Topic: "parsec-events", Key: "parsec-0"})
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	if err := sink.Send(context.Background(), []Event{{ID: "1"}, {ID: "2"}}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if len(produced.Records) != 2 || produced.Records[0].Key != "parsec-0" || produced.Records[1].Value.ID != "2" {
+		t.Errorf("unexpected produce request %+v", produced)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := sink.Send(context.Background(), []Event{{ID: "3"}}); err == nil || errors.Is(err, ErrNotRetryable) {
+		t.Errorf("expected retryable error, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WebhookSink posts batches of events to an HTTP endpoint as a JSON array
+type WebhookSink struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// WebhookSinkConfig configures a WebhookSink
+type WebhookSinkConfig struct {
+	// URL is the endpoint events are posted to
+	URL string
+
+	// Headers are sent with every request (e.g., an authorization header)
+	Headers map[string]string
+
+	// HTTPClient is an optional HTTP client
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+}
+
+// NewWebhookSink creates a sink that posts events to a webhook
+func NewWebhookSink(cfg WebhookSinkConfig) (*WebhookSink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook event sink requires url")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &WebhookSink{
+		url:        cfg.URL,
+		headers:    cfg.Headers,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Send implements Sink
+func (s *WebhookSink) Send(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+	return post(ctx, s.httpClient, s.url, "application/json", s.headers, body)
+}
+
+// KafkaSink produces events to a Kafka topic through an HTTP bridge that speaks the
+// Kafka REST proxy v2 API (Confluent REST Proxy or the Strimzi Kafka Bridge)
+type KafkaSink struct {
+	endpoint   string
+	key        string
+	httpClient *http.Client
+}
+
+// KafkaSinkConfig configures a KafkaSink
+type KafkaSinkConfig struct {
+	// URL is the base URL of the REST proxy (e.g., "http://kafka-bridge:8080")
+	URL string
+
+	// Topic is the topic events are produced to
+	Topic string
+
+	// Key is the key of every record
+	// Keying by instance keeps an instance's events on one partition, in order.
+	Key string
+
+	// HTTPClient is an optional HTTP client
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+}
+
+// NewKafkaSink creates a sink that produces events to a Kafka topic
+func NewKafkaSink(cfg KafkaSinkConfig) (*KafkaSink, error) {
+	if cfg.URL == "" || cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka event sink requires url and topic")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &KafkaSink{
+		endpoint:   strings.TrimSuffix(cfg.URL, "/") + "/topics/" + url.PathEscape(cfg.Topic),
+		key:        cfg.Key,
+		httpClient: httpClient,
+	}, nil
+}
+
+// kafkaProduceRequest is a Kafka REST proxy v2 produce request
+type kafkaProduceRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value Event  `json:"value"`
+}
+
+// Send implements Sink
+// The whole batch is one produce request.
+func (s *KafkaSink) Send(ctx context.Context, events []Event) error {
+	records := make([]kafkaRecord, len(events))
+	for i, event := range events {
+		records[i] = kafkaRecord{Key: s.key, Value: event}
+	}
+	body, err := json.Marshal(kafkaProduceRequest{Records: records})
+	if err != nil {
+		return fmt.Errorf("failed to encode Kafka produce request: %w", err)
+	}
+	return post(ctx, s.httpClient, s.endpoint, "application/vnd.kafka.json.v2+json", nil, body)
+}
+
+// post sends body to url, failing unless the response is successful
+// Client errors other than timeouts and rate limiting are not retryable.
+func post(ctx context.Context, httpClient *http.Client, url, contentType string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send events: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("failed to send events: HTTP %d: %w", resp.StatusCode, ErrNotRetryable)
+	default:
+		return fmt.Errorf("failed to send events: HTTP %d", resp.StatusCode)
+	}
+}
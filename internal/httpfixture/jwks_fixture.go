@@ -10,6 +10,7 @@ import (
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 
 	"github.com/alechenninger/parsec/internal/clock"
@@ -24,6 +25,7 @@ type JWKSFixture struct {
 	publicKey  jwk.Key
 	keyID      string
 	algorithm  jwa.SignatureAlgorithm
+	tokenType  string
 	jwks       jwk.Set
 	clock      clock.Clock
 }
@@ -44,6 +46,10 @@ type JWKSFixtureConfig struct {
 	// If zero value, defaults to RS256
 	Algorithm jwa.SignatureAlgorithm
 
+	// TokenType is the typ header of signed tokens
+	// If empty, defaults to JWT
+	TokenType string
+
 	// Clock is the time source for token timestamps
 	// If nil, uses system clock
 	Clock clock.Clock
@@ -110,6 +116,7 @@ func NewJWKSFixture(cfg JWKSFixtureConfig) (*JWKSFixture, error) {
 		publicKey:  publicKey,
 		keyID:      keyID,
 		algorithm:  algorithm,
+		tokenType:  cfg.TokenType,
 		jwks:       jwks,
 		clock:      clk,
 	}, nil
@@ -178,8 +185,15 @@ func (f *JWKSFixture) SignToken(token jwt.Token) (string, error) {
 		return "", fmt.Errorf("failed to set algorithm: %w", err)
 	}
 
+	headers := jws.NewHeaders()
+	if f.tokenType != "" {
+		if err := headers.Set(jws.TypeKey, f.tokenType); err != nil {
+			return "", fmt.Errorf("failed to set type header: %w", err)
+		}
+	}
+
 	// Sign the token
-	signed, err := jwt.Sign(token, jwt.WithKey(f.algorithm, key))
+	signed, err := jwt.Sign(token, jwt.WithKey(f.algorithm, key, jws.WithProtectedHeaders(headers)))
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -0,0 +1,140 @@
+// Package idgen provides an abstraction over unique identifier generation.
+//
+// Like the clock package, this exists so components that mint identifiers
+// (e.g. txn and jti claims) can be made deterministic in tests.
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// Generator generates unique identifiers
+type Generator interface {
+	// NewID returns a new unique identifier
+	NewID() string
+}
+
+// UUIDGenerator generates random (version 4) UUIDs
+type UUIDGenerator struct{}
+
+// NewUUIDGenerator creates a new random UUID generator
+func NewUUIDGenerator() *UUIDGenerator {
+	return &UUIDGenerator{}
+}
+
+// NewID returns a new random UUID
+func (g *UUIDGenerator) NewID() string {
+	return uuid.NewString()
+}
+
+// UUIDv7Generator generates time-ordered (version 7) UUIDs
+type UUIDv7Generator struct{}
+
+// NewUUIDv7Generator creates a new time-ordered UUID generator
+func NewUUIDv7Generator() *UUIDv7Generator {
+	return &UUIDv7Generator{}
+}
+
+// NewID returns a new time-ordered UUID
+func (g *UUIDv7Generator) NewID() string {
+	// NewV7 only fails if the system's random source does
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// ulidEncoding is Crockford's base32 alphabet, used by ULIDs
+const ulidEncoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator generates ULIDs (https://github.com/ulid/spec):
+// a 48-bit millisecond timestamp and 80 random bits in 26 base32 characters,
+// which sort lexically by time
+type ULIDGenerator struct {
+	clock clock.Clock
+}
+
+// NewULIDGenerator creates a new ULID generator
+// clk supplies timestamps (defaults to the system clock)
+func NewULIDGenerator(clk clock.Clock) *ULIDGenerator {
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &ULIDGenerator{clock: clk}
+}
+
+// NewID returns a new ULID
+func (g *ULIDGenerator) NewID() string {
+	var id [16]byte
+	ms := uint64(g.clock.Now().UnixMilli())
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	// rand.Read never returns an error
+	_, _ = rand.Read(id[6:])
+
+	// 128 bits as 26 base32 characters, the first holding only 3 bits
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(id[0:8])
+	lo := binary.BigEndian.Uint64(id[8:16])
+	for i := 25; i >= 0; i-- {
+		out[i] = ulidEncoding[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// PrefixedGenerator prefixes the identifiers of another generator, such as with an
+// instance ID, so identifiers minted by different instances cannot collide
+type PrefixedGenerator struct {
+	prefix string
+	next   Generator
+}
+
+// NewPrefixedGenerator creates a generator of next's identifiers, prefixed with prefix
+func NewPrefixedGenerator(prefix string, next Generator) *PrefixedGenerator {
+	return &PrefixedGenerator{prefix: prefix, next: next}
+}
+
+// NewID returns a new prefixed identifier
+func (g *PrefixedGenerator) NewID() string {
+	return g.prefix + g.next.NewID()
+}
+
+// FixtureGenerator generates a deterministic sequence of UUIDs for testing.
+// The same seed always produces the same sequence.
+type FixtureGenerator struct {
+	mu        sync.Mutex
+	namespace uuid.UUID
+	counter   int
+}
+
+// NewFixtureGenerator creates a deterministic generator.
+// IDs are name-based (version 5) UUIDs derived from the seed and a counter,
+// so they look like real identifiers but are stable across runs.
+func NewFixtureGenerator(seed string) *FixtureGenerator {
+	return &FixtureGenerator{
+		namespace: uuid.NewSHA1(uuid.NameSpaceOID, []byte(seed)),
+	}
+}
+
+// NewID returns the next UUID in the sequence
+func (g *FixtureGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.counter++
+	return uuid.NewSHA1(g.namespace, []byte(fmt.Sprintf("%d", g.counter))).String()
+}
+
+// Reset restarts the sequence from the beginning
+func (g *FixtureGenerator) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.counter = 0
+}
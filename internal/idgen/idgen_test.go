@@ -0,0 +1,102 @@
+package idgen
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+func TestUUIDGenerator_NewID(t *testing.T) {
+	g := NewUUIDGenerator()
+
+	a, b := g.NewID(), g.NewID()
+	if a == b {
+		t.Errorf("expected unique IDs, got %s twice", a)
+	}
+	if _, err := uuid.Parse(a); err != nil {
+		t.Errorf("expected a valid UUID, got %s: %v", a, err)
+	}
+}
+
+func TestUUIDv7Generator_NewID(t *testing.T) {
+	g := NewUUIDv7Generator()
+	a, b := g.NewID(), g.NewID()
+	if a == b {
+		t.Errorf("expected unique IDs, got %s twice", a)
+	}
+	parsed, err := uuid.Parse(a)
+	if err != nil {
+		t.Fatalf("expected a valid UUID, got %s: %v", a, err)
+	}
+	if parsed.Version() != 7 {
+		t.Errorf("expected a version 7 UUID, got version %d", parsed.Version())
+	}
+}
+
+func TestULIDGenerator_NewID(t *testing.T) {
+	// 1469918176385 ms is encoded as 01ARYZ6S41, as in the ULID spec example
+	clk := clock.NewFixtureClock(time.UnixMilli(1469918176385))
+	g := NewULIDGenerator(clk)
+
+	a, b := g.NewID(), g.NewID()
+	if a == b {
+		t.Errorf("expected unique IDs, got %s twice", a)
+	}
+	if len(a) != 26 {
+		t.Errorf("expected 26 characters, got %d in %s", len(a), a)
+	}
+	if !strings.HasPrefix(a, "01ARYZ6S41") {
+		t.Errorf("expected timestamp 01ARYZ6S41, got %s", a[:10])
+	}
+	for _, c := range a {
+		if !strings.ContainsRune(ulidEncoding, c) {
+			t.Errorf("unexpected character %q in %s", c, a)
+		}
+	}
+
+	clk.Advance(time.Millisecond)
+	if later := g.NewID(); later <= a {
+		t.Errorf("expected %s to sort after %s", later, a)
+	}
+}
+
+func TestPrefixedGenerator_NewID(t *testing.T) {
+	g := NewPrefixedGenerator("instance-1.", NewUUIDv7Generator())
+	a, b := g.NewID(), g.NewID()
+	if a == b {
+		t.Errorf("expected unique IDs, got %s twice", a)
+	}
+	id, ok := strings.CutPrefix(a, "instance-1.")
+	if !ok {
+		t.Fatalf("expected the prefix, got %s", a)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		t.Errorf("expected a valid UUID after the prefix, got %s: %v", id, err)
+	}
+}
+
+func TestFixtureGenerator_IsDeterministic(t *testing.T) {
+	g1 := NewFixtureGenerator("seed")
+	g2 := NewFixtureGenerator("seed")
+
+	for i := 0; i < 3; i++ {
+		a, b := g1.NewID(), g2.NewID()
+		if a != b {
+			t.Errorf("expected same sequence for same seed, got %s and %s", a, b)
+		}
+	}
+
+	first := NewFixtureGenerator("seed").NewID()
+	g1.Reset()
+	if got := g1.NewID(); got != first {
+		t.Errorf("expected Reset to restart the sequence, got %s want %s", got, first)
+	}
+
+	if other := NewFixtureGenerator("other").NewID(); other == first {
+		t.Error("expected different seeds to produce different sequences")
+	}
+}
@@ -0,0 +1,158 @@
+// Package instance identifies the running parsec replica and binary version.
+//
+// Operators use this to trace a token back to the replica that minted it:
+// the identity is included in audit logs and, optionally, in issued tokens.
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/idgen"
+)
+
+// Version is the parsec release version.
+// It is set at build time with -ldflags "-X github.com/alechenninger/parsec/internal/instance.Version=v1.2.3".
+// If unset, BuildVersion derives a version from the Go build info.
+var Version string
+
+// Identity identifies a parsec instance
+type Identity struct {
+	// ID is a stable identifier for this instance
+	ID string `json:"id"`
+
+	// Version is the version of the parsec binary
+	Version string `json:"version"`
+}
+
+// Claims returns the identity as a JWT claim value
+func (i Identity) Claims() map[string]any {
+	return map[string]any{
+		"id":      i.ID,
+		"version": i.Version,
+	}
+}
+
+// Config configures how the instance identity is determined
+type Config struct {
+	// ID is an explicit instance ID (e.g., a StatefulSet pod name).
+	// Takes precedence over IDFile.
+	ID string
+
+	// IDFile persists a generated ID across restarts.
+	// If the file does not exist, a new ID is generated and written to it.
+	// If empty and ID is unset, a new ID is generated on every start.
+	IDFile string
+
+	// IDGenerator generates new instance IDs (defaults to random UUIDs)
+	IDGenerator idgen.Generator
+}
+
+// Load determines the identity of this instance, generating and persisting
+// a new ID on first start if configured to
+func Load(cfg Config) (Identity, error) {
+	identity := Identity{ID: cfg.ID, Version: BuildVersion()}
+	if identity.ID != "" {
+		return identity, nil
+	}
+
+	gen := cfg.IDGenerator
+	if gen == nil {
+		gen = idgen.NewUUIDGenerator()
+	}
+
+	if cfg.IDFile == "" {
+		identity.ID = gen.NewID()
+		return identity, nil
+	}
+
+	id, err := loadOrCreateID(cfg.IDFile, gen)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity.ID = id
+	return identity, nil
+}
+
+// loadOrCreateID reads the instance ID from path, creating it if it does not exist
+func loadOrCreateID(path string, gen idgen.Generator) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		id := strings.TrimSpace(string(data))
+		if id == "" {
+			return "", fmt.Errorf("instance id file %s is empty", path)
+		}
+		return id, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read instance id file: %w", err)
+	}
+
+	id := gen.NewID()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create instance id directory: %w", err)
+	}
+
+	// Write to a temp file and rename so a crash never leaves a partial ID behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".instance-id-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create instance id file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(id + "\n"); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write instance id file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write instance id file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write instance id file: %w", err)
+	}
+
+	return id, nil
+}
+
+// BuildVersion returns the version of the running binary.
+// It prefers Version, then the main module version, then the VCS revision.
+func BuildVersion() string {
+	if Version != "" {
+		return Version
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "devel"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return "devel+" + revision
+}
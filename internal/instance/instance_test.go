@@ -0,0 +1,85 @@
+package instance
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/idgen"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("explicit id takes precedence", func(t *testing.T) {
+		identity, err := Load(Config{ID: "parsec-0", IDFile: filepath.Join(t.TempDir(), "id")})
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if identity.ID != "parsec-0" {
+			t.Errorf("expected id parsec-0, got %s", identity.ID)
+		}
+		if identity.Version == "" {
+			t.Error("expected a version")
+		}
+	})
+
+	t.Run("id is persisted on first start", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state", "instance-id")
+
+		first, err := Load(Config{IDFile: path, IDGenerator: idgen.NewFixtureGenerator("first")})
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("expected id file to be written: %v", err)
+		}
+		if strings.TrimSpace(string(data)) != first.ID {
+			t.Errorf("expected file to contain %s, got %q", first.ID, data)
+		}
+
+		second, err := Load(Config{IDFile: path, IDGenerator: idgen.NewFixtureGenerator("second")})
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if second.ID != first.ID {
+			t.Errorf("expected id to be stable across restarts, got %s then %s", first.ID, second.ID)
+		}
+	})
+
+	t.Run("ephemeral id without a file", func(t *testing.T) {
+		gen := idgen.NewFixtureGenerator("ephemeral")
+		identity, err := Load(Config{IDGenerator: gen})
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if identity.ID != idgen.NewFixtureGenerator("ephemeral").NewID() {
+			t.Errorf("expected generated id, got %s", identity.ID)
+		}
+	})
+
+	t.Run("empty id file is an error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "instance-id")
+		if err := os.WriteFile(path, []byte("\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(Config{IDFile: path}); err == nil {
+			t.Error("expected error for empty id file")
+		}
+	})
+}
+
+func TestBuildVersion(t *testing.T) {
+	original := Version
+	t.Cleanup(func() { Version = original })
+
+	Version = "v1.2.3"
+	if got := BuildVersion(); got != "v1.2.3" {
+		t.Errorf("expected v1.2.3, got %s", got)
+	}
+
+	Version = ""
+	if got := BuildVersion(); got == "" {
+		t.Error("expected a derived version")
+	}
+}
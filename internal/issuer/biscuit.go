@@ -0,0 +1,270 @@
+package issuer
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/alechenninger/parsec/internal/keys"
+)
+
+// This file encodes Biscuit v3 tokens (https://doc.biscuitsec.org/reference/specifications)
+// directly in their protobuf wire format. Only what issuing an authority block needs is
+// implemented: facts, checks comparing a variable to a value, and signing.
+
+// biscuitBlockVersion is the Datalog version of issued blocks
+const biscuitBlockVersion = 3
+
+// biscuitSymbolOffset is the index of the first symbol a block defines;
+// lower indices refer to biscuitDefaultSymbols
+const biscuitSymbolOffset = 1024
+
+// biscuitDefaultSymbols is the symbol table every block starts with
+var biscuitDefaultSymbols = []string{
+	"read", "write", "resource", "operation", "right", "time", "role", "owner",
+	"tenant", "namespace", "user", "team", "service", "admin", "email", "group",
+	"member", "ip_address", "client", "client_ip", "domain", "path", "version",
+	"cluster", "node", "hostname", "nonce", "query",
+}
+
+// biscuitEd25519 is the PublicKey.Algorithm of Ed25519 keys
+const biscuitEd25519 = 0
+
+// biscuitLessOrEqual is the OpBinary.Kind of the <= operator
+const biscuitLessOrEqual = 2
+
+// biscuitVariable is a Datalog variable term, like $time
+type biscuitVariable string
+
+// biscuitBlock builds the Datalog of one block, interning its symbols
+type biscuitBlock struct {
+	symbols []string
+	index   map[string]uint64
+	facts   [][]byte
+	checks  [][]byte
+}
+
+func newBiscuitBlock() *biscuitBlock {
+	index := make(map[string]uint64, len(biscuitDefaultSymbols))
+	for i, symbol := range biscuitDefaultSymbols {
+		index[symbol] = uint64(i)
+	}
+	return &biscuitBlock{index: index}
+}
+
+// symbol returns the index of s, adding it to the block's symbols if needed
+func (b *biscuitBlock) symbol(s string) uint64 {
+	if i, ok := b.index[s]; ok {
+		return i
+	}
+	i := uint64(biscuitSymbolOffset + len(b.symbols))
+	b.symbols = append(b.symbols, s)
+	b.index[s] = i
+	return i
+}
+
+// addFact adds the fact name(terms...)
+// Terms may be strings, integers, booleans, times, or biscuitVariables.
+func (b *biscuitBlock) addFact(name string, terms ...any) error {
+	predicate, err := b.predicate(name, terms)
+	if err != nil {
+		return err
+	}
+	var fact []byte
+	fact = protowire.AppendTag(fact, 1, protowire.BytesType) // FactV2.predicate
+	fact = protowire.AppendBytes(fact, predicate)
+	b.facts = append(b.facts, fact)
+	return nil
+}
+
+// addLessOrEqualCheck adds "check if name($v), $v <= limit"
+func (b *biscuitBlock) addLessOrEqualCheck(name string, limit any) error {
+	variable := biscuitVariable(name)
+	body, err := b.predicate(name, []any{variable})
+	if err != nil {
+		return err
+	}
+	head, err := b.predicate("query", nil)
+	if err != nil {
+		return err
+	}
+	left, err := b.term(variable)
+	if err != nil {
+		return err
+	}
+	right, err := b.term(limit)
+	if err != nil {
+		return err
+	}
+
+	// ExpressionV2 in reverse Polish notation: $v, limit, <=
+	var expression []byte
+	for _, value := range [][]byte{left, right} {
+		var op []byte
+		op = protowire.AppendTag(op, 1, protowire.BytesType) // Op.value
+		op = protowire.AppendBytes(op, value)
+		expression = protowire.AppendTag(expression, 1, protowire.BytesType) // ExpressionV2.ops
+		expression = protowire.AppendBytes(expression, op)
+	}
+	var binary []byte
+	binary = protowire.AppendTag(binary, 1, protowire.VarintType) // OpBinary.kind
+	binary = protowire.AppendVarint(binary, biscuitLessOrEqual)
+	var op []byte
+	op = protowire.AppendTag(op, 3, protowire.BytesType) // Op.Binary
+	op = protowire.AppendBytes(op, binary)
+	expression = protowire.AppendTag(expression, 1, protowire.BytesType)
+	expression = protowire.AppendBytes(expression, op)
+
+	var rule []byte
+	rule = protowire.AppendTag(rule, 1, protowire.BytesType) // RuleV2.head
+	rule = protowire.AppendBytes(rule, head)
+	rule = protowire.AppendTag(rule, 2, protowire.BytesType) // RuleV2.body
+	rule = protowire.AppendBytes(rule, body)
+	rule = protowire.AppendTag(rule, 3, protowire.BytesType) // RuleV2.expressions
+	rule = protowire.AppendBytes(rule, expression)
+
+	var check []byte
+	check = protowire.AppendTag(check, 1, protowire.BytesType) // CheckV2.queries
+	check = protowire.AppendBytes(check, rule)
+	b.checks = append(b.checks, check)
+	return nil
+}
+
+// predicate encodes a PredicateV2
+func (b *biscuitBlock) predicate(name string, terms []any) ([]byte, error) {
+	var predicate []byte
+	predicate = protowire.AppendTag(predicate, 1, protowire.VarintType) // PredicateV2.name
+	predicate = protowire.AppendVarint(predicate, b.symbol(name))
+	for _, t := range terms {
+		term, err := b.term(t)
+		if err != nil {
+			return nil, fmt.Errorf("fact %s: %w", name, err)
+		}
+		predicate = protowire.AppendTag(predicate, 2, protowire.BytesType) // PredicateV2.terms
+		predicate = protowire.AppendBytes(predicate, term)
+	}
+	return predicate, nil
+}
+
+// term encodes a TermV2
+func (b *biscuitBlock) term(value any) ([]byte, error) {
+	var term []byte
+	switch v := value.(type) {
+	case biscuitVariable:
+		term = protowire.AppendTag(term, 1, protowire.VarintType)
+		term = protowire.AppendVarint(term, b.symbol(string(v)))
+	case string:
+		term = protowire.AppendTag(term, 3, protowire.VarintType)
+		term = protowire.AppendVarint(term, b.symbol(v))
+	case int:
+		term = protowire.AppendTag(term, 2, protowire.VarintType)
+		term = protowire.AppendVarint(term, uint64(v))
+	case int64:
+		term = protowire.AppendTag(term, 2, protowire.VarintType)
+		term = protowire.AppendVarint(term, uint64(v))
+	case float64:
+		if v != math.Trunc(v) || v > math.MaxInt64 || v < math.MinInt64 {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+		term = protowire.AppendTag(term, 2, protowire.VarintType)
+		term = protowire.AppendVarint(term, uint64(int64(v)))
+	case bool:
+		term = protowire.AppendTag(term, 6, protowire.VarintType)
+		term = protowire.AppendVarint(term, protowire.EncodeBool(v))
+	case time.Time:
+		term = protowire.AppendTag(term, 4, protowire.VarintType)
+		term = protowire.AppendVarint(term, uint64(v.Unix()))
+	default:
+		return nil, fmt.Errorf("unsupported term type %T", value)
+	}
+	return term, nil
+}
+
+// marshal encodes the Block
+func (b *biscuitBlock) marshal() []byte {
+	var block []byte
+	for _, symbol := range b.symbols {
+		block = protowire.AppendTag(block, 1, protowire.BytesType) // Block.symbols
+		block = protowire.AppendString(block, symbol)
+	}
+	block = protowire.AppendTag(block, 3, protowire.VarintType) // Block.version
+	block = protowire.AppendVarint(block, biscuitBlockVersion)
+	for _, fact := range b.facts {
+		block = protowire.AppendTag(block, 4, protowire.BytesType) // Block.facts_v2
+		block = protowire.AppendBytes(block, fact)
+	}
+	for _, check := range b.checks {
+		block = protowire.AppendTag(block, 6, protowire.BytesType) // Block.checks_v2
+		block = protowire.AppendBytes(block, check)
+	}
+	return block
+}
+
+// signBiscuit signs an authority block with a root key and returns the base64url-encoded token
+// The token carries the private key of its next block, so holders can attenuate it by
+// appending blocks without contacting parsec.
+func signBiscuit(authority *biscuitBlock, signer crypto.Signer, algorithm keys.Algorithm) (string, error) {
+	nextPublic, nextPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate next key: %w", err)
+	}
+
+	block := authority.marshal()
+
+	// Signed data: block || next key algorithm (little-endian u32) || next public key
+	toBeSigned := append([]byte{}, block...)
+	toBeSigned = binary.LittleEndian.AppendUint32(toBeSigned, biscuitEd25519)
+	toBeSigned = append(toBeSigned, nextPublic...)
+
+	var signature []byte
+	switch algorithm {
+	case "EdDSA":
+		signature, err = signer.Sign(rand.Reader, toBeSigned, crypto.Hash(0))
+	case "ES256":
+		digest := sha256.Sum256(toBeSigned)
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return "", fmt.Errorf("algorithm %s cannot sign biscuits (supported: EdDSA, ES256)", algorithm)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	var signedBlock []byte
+	signedBlock = protowire.AppendTag(signedBlock, 1, protowire.BytesType) // SignedBlock.block
+	signedBlock = protowire.AppendBytes(signedBlock, block)
+	signedBlock = protowire.AppendTag(signedBlock, 2, protowire.BytesType) // SignedBlock.nextKey
+	signedBlock = protowire.AppendBytes(signedBlock, biscuitPublicKey(biscuitEd25519, nextPublic))
+	signedBlock = protowire.AppendTag(signedBlock, 3, protowire.BytesType) // SignedBlock.signature
+	signedBlock = protowire.AppendBytes(signedBlock, signature)
+
+	var proof []byte
+	proof = protowire.AppendTag(proof, 1, protowire.BytesType) // Proof.nextSecret
+	proof = protowire.AppendBytes(proof, nextPrivate.Seed())
+
+	var token []byte
+	token = protowire.AppendTag(token, 2, protowire.BytesType) // Biscuit.authority
+	token = protowire.AppendBytes(token, signedBlock)
+	token = protowire.AppendTag(token, 4, protowire.BytesType) // Biscuit.proof
+	token = protowire.AppendBytes(token, proof)
+
+	return base64.URLEncoding.EncodeToString(token), nil
+}
+
+// biscuitPublicKey encodes a PublicKey
+func biscuitPublicKey(algorithm uint64, key []byte) []byte {
+	var publicKey []byte
+	publicKey = protowire.AppendTag(publicKey, 1, protowire.VarintType) // PublicKey.algorithm
+	publicKey = protowire.AppendVarint(publicKey, algorithm)
+	publicKey = protowire.AppendTag(publicKey, 2, protowire.BytesType) // PublicKey.key
+	publicKey = protowire.AppendBytes(publicKey, key)
+	return publicKey
+}
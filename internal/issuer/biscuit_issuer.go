@@ -0,0 +1,236 @@
+package issuer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/idgen"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// BiscuitIssuerConfig is the configuration for creating a Biscuit issuer
+type BiscuitIssuerConfig struct {
+	// IssuerURL, if set, is added to tokens as an issuer fact
+	IssuerURL string
+
+	// TokenType is the token type to issue
+	TokenType string
+
+	// TTL is the time-to-live for tokens
+	TTL time.Duration
+
+	// Signer provides the root key (EdDSA or ES256) tokens are signed with
+	Signer keys.RotatingSigner
+
+	// ClaimMappers are the mappers to apply to generate additional facts
+	ClaimMappers []service.ClaimMapper
+
+	// Redaction, if set, strips or hashes sensitive mapped claims before issuance
+	Redaction *Redaction
+
+	// Profiles, if set, shape the claims of tokens for some audiences
+	Profiles []AudienceProfile
+
+	// DynamicTTL, if set, decides the lifetime of each token, within its bounds;
+	// TTL is used where its policy decides none
+	DynamicTTL *DynamicTTL
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+
+	// IDGenerator is an optional generator for token_id facts (defaults to random UUIDs)
+	IDGenerator idgen.Generator
+}
+
+// Facts the authority block of every Biscuit has
+const (
+	BiscuitUserFact     = "user"
+	BiscuitIssuerFact   = "issuer"
+	BiscuitAudienceFact = "audience"
+	BiscuitScopeFact    = "scope"
+	BiscuitTokenIDFact  = "token_id"
+	BiscuitClientIDFact = "client_id"
+)
+
+// BiscuitIssuer issues Biscuit tokens (https://www.biscuitsec.org)
+// Its authority block holds the token's claims as Datalog facts, like user("alice") and
+// scope("orders:read"), and a check that the token has not expired. Holders can
+// attenuate a token offline by appending blocks with further checks.
+type BiscuitIssuer struct {
+	issuerURL    string
+	tokenType    string
+	ttl          time.Duration
+	dynamicTTL   *DynamicTTL
+	signer       keys.RotatingSigner
+	claimMappers []service.ClaimMapper
+	redaction    *Redaction
+	profiles     []AudienceProfile
+	clock        clock.Clock
+	idGenerator  idgen.Generator
+}
+
+// NewBiscuitIssuer creates a new Biscuit issuer
+func NewBiscuitIssuer(cfg BiscuitIssuerConfig) *BiscuitIssuer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	idGenerator := cfg.IDGenerator
+	if idGenerator == nil {
+		idGenerator = idgen.NewUUIDGenerator()
+	}
+
+	return &BiscuitIssuer{
+		issuerURL:    cfg.IssuerURL,
+		tokenType:    cfg.TokenType,
+		ttl:          cfg.TTL,
+		dynamicTTL:   cfg.DynamicTTL,
+		signer:       cfg.Signer,
+		claimMappers: cfg.ClaimMappers,
+		redaction:    cfg.Redaction,
+		profiles:     cfg.Profiles,
+		clock:        clk,
+		idGenerator:  idGenerator,
+	}
+}
+
+// Issue implements the Issuer interface
+// Mapped claims become facts named after the claim, with one fact per element of arrays.
+func (i *BiscuitIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	claimMappers, redaction := i.claimMappers, i.redaction
+	if profile := profileFor(i.profiles, issueCtx.Audiences); profile != nil {
+		claimMappers, redaction = profile.ClaimMappers, profile.redactionOr(i.redaction)
+	}
+
+	mappedClaims, err := issueCtx.ToClaims(ctx, claimMappers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map claims: %w", err)
+	}
+	mappedClaims, redacted, err := redaction.Redact("", mappedClaims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redact claims: %w", err)
+	}
+
+	ttl, err := i.dynamicTTL.ttlFor(ctx, issueCtx, i.ttl)
+	if err != nil {
+		return nil, err
+	}
+	now := i.clock.Now()
+	expiresAt := now.Add(ttl)
+
+	authority := newBiscuitBlock()
+	if err := authority.addFact(BiscuitUserFact, issueCtx.Subject.Subject); err != nil {
+		return nil, err
+	}
+	if i.issuerURL != "" {
+		if err := authority.addFact(BiscuitIssuerFact, i.issuerURL); err != nil {
+			return nil, err
+		}
+	}
+	for _, aud := range issueCtx.Audiences {
+		if err := authority.addFact(BiscuitAudienceFact, aud); err != nil {
+			return nil, err
+		}
+	}
+	for _, scope := range strings.Fields(issueCtx.Scope) {
+		if err := authority.addFact(BiscuitScopeFact, scope); err != nil {
+			return nil, err
+		}
+	}
+	tokenID := i.idGenerator.NewID()
+	if err := authority.addFact(BiscuitTokenIDFact, tokenID); err != nil {
+		return nil, err
+	}
+	if issueCtx.ClientID != "" {
+		if err := authority.addFact(BiscuitClientIDFact, issueCtx.ClientID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sort mapped claims so tokens with the same claims encode the same way
+	names := make([]string, 0, len(mappedClaims))
+	for name := range mappedClaims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch name {
+		case BiscuitUserFact, BiscuitIssuerFact, BiscuitAudienceFact, BiscuitScopeFact,
+			BiscuitTokenIDFact, BiscuitClientIDFact, "time", "query":
+			return nil, fmt.Errorf("claim %s conflicts with a fact parsec sets", name)
+		}
+		values, ok := mappedClaims[name].([]any)
+		if !ok {
+			values = []any{mappedClaims[name]}
+		}
+		for _, value := range values {
+			if err := authority.addFact(name, value); err != nil {
+				return nil, fmt.Errorf("failed to add claim: %w", err)
+			}
+		}
+	}
+
+	// check if time($time), $time <= expiration
+	if err := authority.addLessOrEqualCheck("time", expiresAt); err != nil {
+		return nil, err
+	}
+
+	signer, _, algorithm, err := i.signer.GetCurrentSigner(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current signer: %w", err)
+	}
+	value, err := signBiscuit(authority, signer, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.Token{
+		Value:          value,
+		Type:           i.tokenType,
+		ExpiresAt:      expiresAt,
+		IssuedAt:       now,
+		ID:             tokenID,
+		RedactedClaims: redacted,
+	}, nil
+}
+
+// PublicKeys implements the Issuer interface
+// Returns the root public keys Biscuits are verified with
+func (i *BiscuitIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return i.signer.PublicKeys(ctx)
+}
+
+// RotateKey implements service.KeyRotatingIssuer
+func (i *BiscuitIssuer) RotateKey(ctx context.Context) error {
+	rotator, ok := i.signer.(keys.ManualRotator)
+	if !ok {
+		return service.ErrKeyRotationNotSupported
+	}
+	return rotator.RotateNow(ctx)
+}
+
+// RevokeKey implements service.KeyRevokingIssuer
+func (i *BiscuitIssuer) RevokeKey(ctx context.Context, keyID string) error {
+	revoker, ok := i.signer.(keys.KeyRevoker)
+	if !ok {
+		return service.ErrKeyRevocationNotSupported
+	}
+	return revoker.RevokeKey(ctx, keys.KeyID(keyID))
+}
+
+// Describe implements service.DescribableIssuer
+func (i *BiscuitIssuer) Describe() service.IssuerDescription {
+	minTTL, maxTTL := i.dynamicTTL.bounds(i.ttl)
+	return service.IssuerDescription{
+		IssuerURL: i.issuerURL,
+		Format:    service.TokenFormatBiscuit,
+		MinTTL:    minTTL,
+		MaxTTL:    maxTTL,
+	}
+}
@@ -0,0 +1,287 @@
+package issuer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// protoFields decodes a protobuf message into its fields' values by field number
+// Varints are returned as uint64 and length-delimited fields as []byte.
+func protoFields(t *testing.T, message []byte) map[protowire.Number][]any {
+	t.Helper()
+	fields := make(map[protowire.Number][]any)
+	for len(message) > 0 {
+		num, typ, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+		message = message[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(message)
+			if n < 0 {
+				t.Fatalf("invalid varint: %v", protowire.ParseError(n))
+			}
+			fields[num] = append(fields[num], v)
+			message = message[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(message)
+			if n < 0 {
+				t.Fatalf("invalid bytes: %v", protowire.ParseError(n))
+			}
+			fields[num] = append(fields[num], v)
+			message = message[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+	return fields
+}
+
+// biscuitFacts decodes the facts of a block as Datalog, like user("alice")
+func biscuitFacts(t *testing.T, block map[protowire.Number][]any) []string {
+	t.Helper()
+	symbols := slices.Clone(biscuitDefaultSymbols)
+	for len(symbols) < biscuitSymbolOffset {
+		symbols = append(symbols, "")
+	}
+	for _, s := range block[1] {
+		symbols = append(symbols, string(s.([]byte)))
+	}
+
+	var facts []string
+	for _, f := range block[4] {
+		predicate := protoFields(t, protoFields(t, f.([]byte))[1][0].([]byte))
+		var terms []string
+		for _, termBytes := range predicate[2] {
+			term := protoFields(t, termBytes.([]byte))
+			switch {
+			case term[3] != nil:
+				terms = append(terms, fmt.Sprintf("%q", symbols[term[3][0].(uint64)]))
+			case term[2] != nil:
+				terms = append(terms, fmt.Sprint(int64(term[2][0].(uint64))))
+			case term[6] != nil:
+				terms = append(terms, fmt.Sprint(term[6][0].(uint64) == 1))
+			default:
+				t.Fatalf("unexpected term %v", term)
+			}
+		}
+		facts = append(facts, fmt.Sprintf("%s(%s)", symbols[predicate[1][0].(uint64)], strings.Join(terms, ", ")))
+	}
+	return facts
+}
+
+func TestBiscuitIssuer(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		key       crypto.Signer
+		algorithm string
+		verify    func(message, signature []byte) bool
+	}{
+		{
+			name:      "EdDSA",
+			key:       edPrivate,
+			algorithm: "EdDSA",
+			verify: func(message, signature []byte) bool {
+				return ed25519.Verify(edPublic, message, signature)
+			},
+		},
+		{
+			name:      "ES256",
+			key:       ecPrivate,
+			algorithm: "ES256",
+			verify: func(message, signature []byte) bool {
+				digest := sha256.Sum256(message)
+				return ecdsa.VerifyASN1(&ecPrivate.PublicKey, digest[:], signature)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := keys.NewStaticSigner(tt.key, keys.Algorithm(tt.algorithm))
+			if err != nil {
+				t.Fatalf("failed to create signer: %v", err)
+			}
+			issuer := NewBiscuitIssuer(BiscuitIssuerConfig{
+				IssuerURL: "https://parsec.example.com",
+				TokenType: string(service.TokenTypeBiscuit),
+				TTL:       5 * time.Minute,
+				Signer:    signer,
+				ClaimMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{
+					"role":  []any{"admin", "auditor"},
+					"level": float64(3),
+				})},
+				Clock: clk,
+			})
+
+			token, err := issuer.Issue(ctx, &service.IssueContext{
+				Subject:   &trust.Result{Subject: "alice"},
+				Audiences: []string{"orders.example.com"},
+				Scope:     "orders:read orders:write",
+				ClientID:  "batch",
+				// Request attributes may be set by callers, so never name the client
+				RequestAttributes: &request.RequestAttributes{
+					Additional: map[string]any{"client_id": "spoofed"},
+				},
+				DataSourceRegistry: service.NewDataSourceRegistry(),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !token.ExpiresAt.Equal(clk.Now().Add(5 * time.Minute)) {
+				t.Errorf("expected expiry %v, got %v", clk.Now().Add(5*time.Minute), token.ExpiresAt)
+			}
+
+			data, err := base64.URLEncoding.DecodeString(token.Value)
+			if err != nil {
+				t.Fatalf("token is not base64url: %v", err)
+			}
+			biscuit := protoFields(t, data)
+			authority := protoFields(t, biscuit[2][0].([]byte))
+			proof := protoFields(t, biscuit[4][0].([]byte))
+
+			blockBytes := authority[1][0].([]byte)
+			nextKey := protoFields(t, authority[2][0].([]byte))
+			if alg := nextKey[1][0].(uint64); alg != biscuitEd25519 {
+				t.Fatalf("expected Ed25519 next key, got algorithm %d", alg)
+			}
+			nextPublic := nextKey[2][0].([]byte)
+
+			toBeSigned := binary.LittleEndian.AppendUint32(slices.Clone(blockBytes), biscuitEd25519)
+			toBeSigned = append(toBeSigned, nextPublic...)
+			if !tt.verify(toBeSigned, authority[3][0].([]byte)) {
+				t.Error("authority block signature does not verify")
+			}
+
+			nextSecret := ed25519.NewKeyFromSeed(proof[1][0].([]byte))
+			if !nextSecret.Public().(ed25519.PublicKey).Equal(ed25519.PublicKey(nextPublic)) {
+				t.Error("proof does not hold the next block's private key")
+			}
+
+			block := protoFields(t, blockBytes)
+			if version := block[3][0].(uint64); version != biscuitBlockVersion {
+				t.Errorf("expected block version %d, got %d", biscuitBlockVersion, version)
+			}
+			facts := biscuitFacts(t, block)
+			for _, want := range []string{
+				`user("alice")`,
+				`issuer("https://parsec.example.com")`,
+				`audience("orders.example.com")`,
+				`scope("orders:read")`,
+				`scope("orders:write")`,
+				`client_id("batch")`,
+				`level(3)`,
+				`role("admin")`,
+				`role("auditor")`,
+			} {
+				if !slices.Contains(facts, want) {
+					t.Errorf("expected fact %s in %v", want, facts)
+				}
+			}
+			if slices.Contains(facts, `client_id("spoofed")`) {
+				t.Errorf("expected client_id only of the authenticated client, got %v", facts)
+			}
+			if len(block[6]) != 1 {
+				t.Errorf("expected one expiration check, got %d checks", len(block[6]))
+			}
+		})
+	}
+}
+
+func TestBiscuitIssuer_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		algorithm string
+		claims    claims.Claims
+		wantErr   string
+	}{
+		{
+			name:      "unsupported algorithm",
+			algorithm: "ES384",
+			wantErr:   "cannot sign biscuits",
+		},
+		{
+			name:      "claim conflicts with fact",
+			algorithm: "ES256",
+			claims:    claims.Claims{"user": "mallory"},
+			wantErr:   "conflicts with a fact",
+		},
+		{
+			name:      "unsupported claim value",
+			algorithm: "ES256",
+			claims:    claims.Claims{"profile": map[string]any{"name": "alice"}},
+			wantErr:   "unsupported term type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curve := elliptic.P256()
+			if tt.algorithm == "ES384" {
+				curve = elliptic.P384()
+			}
+			privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			if err != nil {
+				t.Fatalf("failed to generate key: %v", err)
+			}
+			signer, err := keys.NewStaticSigner(privateKey, keys.Algorithm(tt.algorithm))
+			if err != nil {
+				t.Fatalf("failed to create signer: %v", err)
+			}
+			var mappers []service.ClaimMapper
+			if tt.claims != nil {
+				mappers = append(mappers, service.NewStubClaimMapper(tt.claims))
+			}
+			issuer := NewBiscuitIssuer(BiscuitIssuerConfig{
+				TokenType:    string(service.TokenTypeBiscuit),
+				TTL:          5 * time.Minute,
+				Signer:       signer,
+				ClaimMappers: mappers,
+			})
+
+			_, err = issuer.Issue(ctx, &service.IssueContext{
+				Subject:            &trust.Result{Subject: "alice"},
+				DataSourceRegistry: service.NewDataSourceRegistry(),
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
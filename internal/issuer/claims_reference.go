@@ -0,0 +1,67 @@
+package issuer
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+)
+
+// ClaimsReferenceClaim references the context claims moved out of a token, as an
+// object with the URI they are retrieved from ("uri") and the base64url SHA-256 hash
+// of the retrieved document ("sha256"). The document holds the moved claims under
+// their context, like {"jti": ..., "tctx": {"groups": [...]}}.
+const ClaimsReferenceClaim = "claims_ref"
+
+// ClaimsReferenceTokenType is the token type of the records of claims references
+const ClaimsReferenceTokenType = "urn:parsec:token-type:claims_reference"
+
+// ClaimsReferences keeps context claims out of transaction tokens, in a token store
+// they are retrieved from by reference, so tokens stay small while consumers that
+// need rich context can still get it
+type ClaimsReferences struct {
+	// Store keeps the moved claims until their token expires
+	Store tokenstore.Store
+
+	// URL is the retrieval endpoint references are resolved against, like
+	// "https://parsec.example.com/v1/claims/"
+	URL string
+
+	// Claims are the context claims always moved, like "tctx.entitlements"
+	Claims []string
+}
+
+// referenceDocument encodes the claims moved out of the token with ID tokenID as
+// served by the retrieval endpoint, returning the JSON and the generic values it
+// decodes to, which is what is stored
+//
+// Values are round tripped through JSON so the stored claims encode to the same
+// bytes however the store keeps them, and the hash in the token can be checked.
+func referenceDocument(tokenID string, moved map[string]claims.Claims) ([]byte, map[string]any, error) {
+	document := map[string]any{"jti": tokenID}
+	for name, contextClaims := range moved {
+		document[name] = contextClaims
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode referenced claims: %w", err)
+	}
+	var generic map[string]any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode referenced claims: %w", err)
+	}
+	data, err = json.Marshal(generic)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode referenced claims: %w", err)
+	}
+	return data, generic, nil
+}
+
+// hashReferenceDocument is the base64url SHA-256 hash of a reference document
+func hashReferenceDocument(document []byte) string {
+	sum := sha256.Sum256(document)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
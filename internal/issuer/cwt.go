@@ -0,0 +1,163 @@
+package issuer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/alechenninger/parsec/internal/keys"
+)
+
+// COSE header parameters (RFC 9052 section 3.1)
+const (
+	coseHeaderAlgorithm = 1
+	coseHeaderKeyID     = 4
+)
+
+// coseSign1Tag is the CBOR tag of a COSE_Sign1 message (RFC 9052 section 4.2)
+const coseSign1Tag = 18
+
+// cwtClaimKeys maps JWT claim names to their CWT claim keys (RFC 8392 section 3.1, RFC 8693)
+// Claims not listed keep their names as text string keys.
+var cwtClaimKeys = map[string]int{
+	"iss":   1,
+	"sub":   2,
+	"aud":   3,
+	"exp":   4,
+	"nbf":   5,
+	"iat":   6,
+	"jti":   7, // cti
+	"scope": 9,
+}
+
+// coseAlgorithm describes how to sign with a COSE algorithm
+type coseAlgorithm struct {
+	id   int
+	hash crypto.Hash
+	// ecdsaSize is the size of each of r and s in ECDSA signatures, 0 for other algorithms
+	ecdsaSize int
+	pss       bool
+}
+
+// coseAlgorithms maps the JWS algorithms keys sign with to COSE algorithms (RFC 9053, RFC 8812)
+var coseAlgorithms = map[keys.Algorithm]coseAlgorithm{
+	"ES256": {id: -7, hash: crypto.SHA256, ecdsaSize: 32},
+	"ES384": {id: -35, hash: crypto.SHA384, ecdsaSize: 48},
+	"ES512": {id: -36, hash: crypto.SHA512, ecdsaSize: 66},
+	"EdDSA": {id: -8},
+	"PS256": {id: -37, hash: crypto.SHA256, pss: true},
+	"PS384": {id: -38, hash: crypto.SHA384, pss: true},
+	"PS512": {id: -39, hash: crypto.SHA512, pss: true},
+	"RS256": {id: -257, hash: crypto.SHA256},
+	"RS384": {id: -258, hash: crypto.SHA384},
+	"RS512": {id: -259, hash: crypto.SHA512},
+}
+
+// cwtEncMode encodes CWTs deterministically (RFC 8949 section 4.2.1)
+var cwtEncMode, _ = cbor.CoreDetEncOptions().EncMode()
+
+// signCWT encodes claims as a CWT (RFC 8392) in a COSE_Sign1 message and returns
+// it base64url-encoded, so it can be carried wherever a JWT can
+// Registered claims use their integer keys, times are NumericDates, and jti becomes
+// the cti byte string.
+func signCWT(claims map[string]any, signer crypto.Signer, keyID keys.KeyID, algorithm keys.Algorithm) (string, error) {
+	alg, ok := coseAlgorithms[algorithm]
+	if !ok {
+		return "", fmt.Errorf("algorithm %s has no COSE equivalent", algorithm)
+	}
+
+	cwtClaims := make(map[any]any, len(claims))
+	for name, value := range claims {
+		switch v := value.(type) {
+		case time.Time:
+			value = v.Unix()
+		case []string:
+			if name == "aud" && len(v) == 1 {
+				value = v[0]
+			}
+		}
+		key, ok := cwtClaimKeys[name]
+		if !ok {
+			cwtClaims[name] = value
+			continue
+		}
+		if name == "jti" {
+			value = []byte(fmt.Sprint(value))
+		}
+		cwtClaims[key] = value
+	}
+
+	payload, err := cwtEncMode.Marshal(cwtClaims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+	protected, err := cwtEncMode.Marshal(map[int]any{coseHeaderAlgorithm: alg.id})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode protected header: %w", err)
+	}
+
+	// Sig_structure (RFC 9052 section 4.4)
+	toBeSigned, err := cwtEncMode.Marshal([]any{"Signature1", protected, []byte{}, payload})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode signature input: %w", err)
+	}
+	signature, err := coseSign(signer, alg, toBeSigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	message, err := cwtEncMode.Marshal(cbor.Tag{
+		Number: coseSign1Tag,
+		Content: []any{
+			protected,
+			map[int]any{coseHeaderKeyID: []byte(keyID)},
+			payload,
+			signature,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode COSE_Sign1 message: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(message), nil
+}
+
+// coseSign signs a message, returning the signature in its COSE encoding
+func coseSign(signer crypto.Signer, alg coseAlgorithm, message []byte) ([]byte, error) {
+	// EdDSA signs the message itself
+	if alg.hash == 0 {
+		return signer.Sign(rand.Reader, message, crypto.Hash(0))
+	}
+
+	h := alg.hash.New()
+	h.Write(message)
+	digest := h.Sum(nil)
+
+	var opts crypto.SignerOpts = alg.hash
+	if alg.pss {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: alg.hash}
+	}
+	signature, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+	if alg.ecdsaSize == 0 {
+		return signature, nil
+	}
+
+	// crypto.Signers return ASN.1 ECDSA signatures; COSE uses fixed-size r || s
+	var parsed struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(signature, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse ECDSA signature: %w", err)
+	}
+	raw := make([]byte, 2*alg.ecdsaSize)
+	parsed.R.FillBytes(raw[:alg.ecdsaSize])
+	parsed.S.FillBytes(raw[alg.ecdsaSize:])
+	return raw, nil
+}
@@ -0,0 +1,163 @@
+package issuer
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestTransactionTokenIssuer_CWT(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		key       crypto.Signer
+		algorithm keys.Algorithm
+		coseAlg   int64
+		verify    func(digest, signature []byte) bool
+	}{
+		{
+			name:      "ES256",
+			key:       ecKey,
+			algorithm: "ES256",
+			coseAlg:   -7,
+			verify: func(digest, signature []byte) bool {
+				if len(signature) != 64 {
+					return false
+				}
+				r := new(big.Int).SetBytes(signature[:32])
+				s := new(big.Int).SetBytes(signature[32:])
+				return ecdsa.Verify(&ecKey.PublicKey, digest, r, s)
+			},
+		},
+		{
+			name:      "PS256",
+			key:       rsaKey,
+			algorithm: "PS256",
+			coseAlg:   -37,
+			verify: func(digest, signature []byte) bool {
+				return rsa.VerifyPSS(&rsaKey.PublicKey, crypto.SHA256, digest, signature, nil) == nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := keys.NewStaticSigner(tt.key, tt.algorithm)
+			if err != nil {
+				t.Fatalf("failed to create signer: %v", err)
+			}
+			issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+				IssuerURL: "https://parsec.example.com",
+				TTL:       5 * time.Minute,
+				Signer:    signer,
+				Clock:     clk,
+				Format:    service.TokenFormatCWT,
+			})
+			if format := issuer.Describe().Format; format != service.TokenFormatCWT {
+				t.Errorf("expected format cwt, got %s", format)
+			}
+
+			token, err := issuer.Issue(ctx, &service.IssueContext{
+				Subject:            &trust.Result{Subject: "user@example.com"},
+				Audiences:          []string{"example.com"},
+				Scope:              "orders:read",
+				DataSourceRegistry: service.NewDataSourceRegistry(),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			raw, err := base64.RawURLEncoding.DecodeString(token.Value)
+			if err != nil {
+				t.Fatalf("expected a base64url token: %v", err)
+			}
+			var message cbor.Tag
+			if err := cbor.Unmarshal(raw, &message); err != nil {
+				t.Fatalf("failed to decode COSE message: %v", err)
+			}
+			if message.Number != 18 {
+				t.Fatalf("expected COSE_Sign1 tag 18, got %d", message.Number)
+			}
+			parts, ok := message.Content.([]any)
+			if !ok || len(parts) != 4 {
+				t.Fatalf("expected a 4-element COSE_Sign1 array, got %v", message.Content)
+			}
+			protected, _ := parts[0].([]byte)
+			unprotected, _ := parts[1].(map[any]any)
+			payload, _ := parts[2].([]byte)
+			signature, _ := parts[3].([]byte)
+
+			var header map[int64]any
+			if err := cbor.Unmarshal(protected, &header); err != nil {
+				t.Fatalf("failed to decode protected header: %v", err)
+			}
+			if header[1] != tt.coseAlg {
+				t.Errorf("expected alg %d, got %v", tt.coseAlg, header[1])
+			}
+			_, wantKeyID, _, _ := signer.GetCurrentSigner(ctx)
+			if kid, _ := unprotected[uint64(4)].([]byte); string(kid) != string(wantKeyID) {
+				t.Errorf("expected kid %s, got %v", wantKeyID, unprotected[uint64(4)])
+			}
+
+			toBeSigned, err := cbor.Marshal([]any{"Signature1", protected, []byte{}, payload})
+			if err != nil {
+				t.Fatalf("failed to encode signature input: %v", err)
+			}
+			digest := sha256.Sum256(toBeSigned)
+			if !tt.verify(digest[:], signature) {
+				t.Error("signature does not verify")
+			}
+
+			var claims map[any]any
+			if err := cbor.Unmarshal(payload, &claims); err != nil {
+				t.Fatalf("failed to decode claims: %v", err)
+			}
+			want := map[any]any{
+				uint64(1): "https://parsec.example.com",
+				uint64(2): "user@example.com",
+				uint64(3): "example.com",
+				uint64(4): uint64(clk.Now().Add(5 * time.Minute).Unix()),
+				uint64(5): uint64(clk.Now().Unix()),
+				uint64(6): uint64(clk.Now().Unix()),
+				uint64(9): "orders:read",
+			}
+			for key, value := range want {
+				if claims[key] != value {
+					t.Errorf("expected claim %v=%v, got %v", key, value, claims[key])
+				}
+			}
+			if cti, _ := claims[uint64(7)].([]byte); len(cti) == 0 {
+				t.Errorf("expected cti byte string, got %v", claims[uint64(7)])
+			}
+			if txn, _ := claims["txn"].(string); txn == "" {
+				t.Errorf("expected txn claim, got %v", claims["txn"])
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package issuer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// DynamicTTL computes the lifetime of each token with a policy, such as a shorter
+// lifetime for admin scopes, bounded by MinTTL and MaxTTL
+type DynamicTTL struct {
+	// Policy decides the lifetime of each token; where it has none, the issuer's TTL is used
+	Policy service.TTLPolicy
+
+	// MinTTL is the shortest lifetime a token may have (zero: no bound)
+	MinTTL time.Duration
+
+	// MaxTTL is the longest lifetime a token may have
+	MaxTTL time.Duration
+}
+
+// ttlFor returns the lifetime of the token for issueCtx: the policy's, clamped to the
+// bounds, or fallback if the policy has none. A nil DynamicTTL always returns fallback.
+func (d *DynamicTTL) ttlFor(ctx context.Context, issueCtx *service.IssueContext, fallback time.Duration) (time.Duration, error) {
+	if d == nil {
+		return fallback, nil
+	}
+	ttl, err := d.Policy.TTL(ctx, issueCtx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate ttl policy: %w", err)
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("ttl policy returned a negative ttl %s", ttl)
+	}
+	if ttl == 0 {
+		return fallback, nil
+	}
+	return min(max(ttl, d.MinTTL), d.MaxTTL), nil
+}
+
+// bounds returns the shortest and longest lifetimes of tokens, given the issuer's TTL
+func (d *DynamicTTL) bounds(fallback time.Duration) (time.Duration, time.Duration) {
+	if d == nil {
+		return fallback, fallback
+	}
+	return min(d.MinTTL, fallback), max(d.MaxTTL, fallback)
+}
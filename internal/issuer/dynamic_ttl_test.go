@@ -0,0 +1,84 @@
+package issuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// ttlByScope is a TTL policy that looks up the TTL of the token's scope
+type ttlByScope map[string]time.Duration
+
+func (p ttlByScope) TTL(ctx context.Context, issueCtx *service.IssueContext) (time.Duration, error) {
+	return p[issueCtx.Scope], nil
+}
+
+func TestTransactionTokenIssuer_DynamicTTL(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+		Clock:     clk,
+		DynamicTTL: &DynamicTTL{
+			Policy: ttlByScope{
+				"admin":   2 * time.Minute,
+				"batch":   time.Hour,
+				"instant": time.Second,
+			},
+			MinTTL: 30 * time.Second,
+			MaxTTL: 30 * time.Minute,
+		},
+	})
+
+	tests := []struct {
+		scope string
+		want  time.Duration
+	}{
+		{scope: "admin", want: 2 * time.Minute},
+		{scope: "batch", want: 30 * time.Minute},
+		{scope: "instant", want: 30 * time.Second},
+		{scope: "orders:read", want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			token, err := issuer.Issue(ctx, &service.IssueContext{
+				Subject:            &trust.Result{Subject: "alice"},
+				Scope:              tt.scope,
+				DataSourceRegistry: service.NewDataSourceRegistry(),
+			})
+			if err != nil {
+				t.Fatalf("Issue failed: %v", err)
+			}
+			if ttl := token.ExpiresAt.Sub(token.IssuedAt); ttl != tt.want {
+				t.Errorf("expected ttl %s, got %s", tt.want, ttl)
+			}
+		})
+	}
+
+	t.Run("describes its bounds", func(t *testing.T) {
+		description := issuer.Describe()
+		if description.MinTTL != 30*time.Second || description.MaxTTL != 30*time.Minute {
+			t.Errorf("expected ttl bounds 30s to 30m, got %s to %s", description.MinTTL, description.MaxTTL)
+		}
+	})
+}
@@ -30,7 +30,7 @@ func TestStubIssuer(t *testing.T) {
 				Issuer:      "https://idp.example.com",
 				TrustDomain: "example-domain",
 			},
-			Audience:           "test-audience",
+			Audiences:          []string{"test-audience"},
 			DataSourceRegistry: service.NewDataSourceRegistry(),
 		}
 
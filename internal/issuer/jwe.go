@@ -0,0 +1,128 @@
+package issuer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// TokenEncryption wraps signed JWTs in a JWE (RFC 7516) encrypted to the token's audience,
+// so intermediaries that forward a token cannot read its claims
+type TokenEncryption struct {
+	// KeyAlgorithm encrypts the content encryption key (defaults to RSA-OAEP-256)
+	KeyAlgorithm jwa.KeyEncryptionAlgorithm
+
+	// ContentEncryption encrypts the signed JWT (defaults to A256GCM)
+	ContentEncryption jwa.ContentEncryptionAlgorithm
+
+	// Recipients are the audiences tokens are encrypted to.
+	// Tokens for other audiences are only signed.
+	Recipients []EncryptionRecipient
+}
+
+// EncryptionRecipient is an audience whose tokens are encrypted to its public keys
+type EncryptionRecipient struct {
+	// Audience is the aud claim value identifying the recipient
+	Audience string
+
+	// Keys is the recipient's key set; the first key suitable for KeyAlgorithm is used
+	Keys trust.JWKSSource
+}
+
+// Close releases the recipients' key sources
+func (e *TokenEncryption) Close() {
+	for _, recipient := range e.Recipients {
+		recipient.Keys.Close()
+	}
+}
+
+// encrypt wraps a signed JWT in a JWE for the recipient among audiences, if there is one
+// A compact JWE has a single recipient, so tokens with several audiences cannot be
+// encrypted if any of them is a recipient.
+func (e *TokenEncryption) encrypt(ctx context.Context, signedToken []byte, audiences []string) ([]byte, error) {
+	var recipient *EncryptionRecipient
+	for i := range e.Recipients {
+		for _, aud := range audiences {
+			if e.Recipients[i].Audience == aud {
+				recipient = &e.Recipients[i]
+			}
+		}
+	}
+	if recipient == nil {
+		return signedToken, nil
+	}
+	if len(audiences) > 1 {
+		return nil, fmt.Errorf("cannot encrypt token for multiple audiences %v", audiences)
+	}
+
+	keyAlgorithm := e.KeyAlgorithm
+	if keyAlgorithm == "" {
+		keyAlgorithm = jwa.RSA_OAEP_256
+	}
+	contentEncryption := e.ContentEncryption
+	if contentEncryption == "" {
+		contentEncryption = jwa.A256GCM
+	}
+
+	key, err := encryptionKey(recipient.Keys.Keys(ctx, ""), keyAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("no encryption key for audience %s: %w", recipient.Audience, err)
+	}
+
+	// Nested JWT (RFC 7519 section 5.2)
+	headers := jwe.NewHeaders()
+	if err := headers.Set(jwe.ContentTypeKey, "JWT"); err != nil {
+		return nil, fmt.Errorf("failed to set content type header: %w", err)
+	}
+	if kid := key.KeyID(); kid != "" {
+		if err := headers.Set(jwe.KeyIDKey, kid); err != nil {
+			return nil, fmt.Errorf("failed to set key ID header: %w", err)
+		}
+	}
+
+	encrypted, err := jwe.Encrypt(signedToken,
+		jwe.WithKey(keyAlgorithm, key),
+		jwe.WithContentEncryption(contentEncryption),
+		jwe.WithProtectedHeaders(headers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt token: %w", err)
+	}
+	return encrypted, nil
+}
+
+// encryptionKey selects the first public key in set that can be used with algorithm
+func encryptionKey(set jwk.Set, algorithm jwa.KeyEncryptionAlgorithm) (jwk.Key, error) {
+	if set == nil {
+		return nil, fmt.Errorf("key set is unavailable")
+	}
+
+	var keyType jwa.KeyType
+	switch algorithm {
+	case jwa.RSA_OAEP, jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512:
+		keyType = jwa.RSA
+	case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW:
+		keyType = jwa.EC
+	default:
+		return nil, fmt.Errorf("unsupported key encryption algorithm %s", algorithm)
+	}
+
+	for i := 0; i < set.Len(); i++ {
+		key, _ := set.Key(i)
+		if key.KeyType() != keyType {
+			continue
+		}
+		if use := key.KeyUsage(); use != "" && use != string(jwk.ForEncryption) {
+			continue
+		}
+		if alg := key.Algorithm(); alg != nil && alg.String() != "" && alg.String() != algorithm.String() {
+			continue
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("no %s key for %s", keyType, algorithm)
+}
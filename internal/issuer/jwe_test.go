@@ -0,0 +1,182 @@
+package issuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// recipientKeys returns a JWKS source publishing the public half of privateKey
+func recipientKeys(t *testing.T, privateKey any) trust.JWKSSource {
+	t.Helper()
+	key, err := jwk.FromRaw(privateKey)
+	if err != nil {
+		t.Fatalf("failed to create JWK: %v", err)
+	}
+	if err := key.Set(jwk.KeyIDKey, "orders-enc"); err != nil {
+		t.Fatalf("failed to set key ID: %v", err)
+	}
+	if err := key.Set(jwk.KeyUsageKey, jwk.ForEncryption); err != nil {
+		t.Fatalf("failed to set key usage: %v", err)
+	}
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to get public key: %v", err)
+	}
+	set := jwk.NewSet()
+	if err := set.AddKey(publicKey); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to marshal JWKS: %v", err)
+	}
+	source, err := trust.NewStaticJWKSSource(data)
+	if err != nil {
+		t.Fatalf("failed to create JWKS source: %v", err)
+	}
+	return source
+}
+
+func TestTransactionTokenIssuer_Encryption(t *testing.T) {
+	ctx := context.Background()
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(signingKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	issue := func(t *testing.T, encryption *TokenEncryption, audiences ...string) (*service.Token, error) {
+		t.Helper()
+		issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:  "https://parsec.example.com",
+			TTL:        5 * time.Minute,
+			Signer:     signer,
+			Encryption: encryption,
+		})
+		return issuer.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "user@example.com"},
+			Audiences:          audiences,
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+	}
+
+	tests := []struct {
+		name          string
+		keyAlgorithm  jwa.KeyEncryptionAlgorithm
+		decryptionKey any
+	}{
+		{name: "RSA-OAEP-256 by default", decryptionKey: rsaKey},
+		{name: "ECDH-ES", keyAlgorithm: jwa.ECDH_ES, decryptionKey: ecKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryption := &TokenEncryption{
+				KeyAlgorithm: tt.keyAlgorithm,
+				Recipients: []EncryptionRecipient{
+					{Audience: "orders.example.com", Keys: recipientKeys(t, tt.decryptionKey)},
+				},
+			}
+			token, err := issue(t, encryption, "orders.example.com")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if parts := strings.Split(token.Value, "."); len(parts) != 5 {
+				t.Fatalf("expected compact JWE with 5 parts, got %d", len(parts))
+			}
+
+			keyAlgorithm := tt.keyAlgorithm
+			if keyAlgorithm == "" {
+				keyAlgorithm = jwa.RSA_OAEP_256
+			}
+			message := jwe.NewMessage()
+			signedToken, err := jwe.Decrypt([]byte(token.Value),
+				jwe.WithKey(keyAlgorithm, tt.decryptionKey), jwe.WithMessage(message))
+			if err != nil {
+				t.Fatalf("failed to decrypt token: %v", err)
+			}
+			headers := message.ProtectedHeaders()
+			if headers.ContentType() != "JWT" {
+				t.Errorf("expected cty JWT, got %q", headers.ContentType())
+			}
+			if headers.KeyID() != "orders-enc" {
+				t.Errorf("expected kid orders-enc, got %q", headers.KeyID())
+			}
+			if headers.ContentEncryption() != jwa.A256GCM {
+				t.Errorf("expected enc A256GCM, got %s", headers.ContentEncryption())
+			}
+
+			parsed, err := jwt.Parse(signedToken, jwt.WithKey(jwa.ES256, &signingKey.PublicKey))
+			if err != nil {
+				t.Fatalf("failed to verify inner token: %v", err)
+			}
+			if parsed.Subject() != "user@example.com" {
+				t.Errorf("expected subject user@example.com, got %s", parsed.Subject())
+			}
+		})
+	}
+
+	encryption := &TokenEncryption{
+		Recipients: []EncryptionRecipient{
+			{Audience: "orders.example.com", Keys: recipientKeys(t, rsaKey)},
+		},
+	}
+
+	t.Run("other audiences are only signed", func(t *testing.T) {
+		token, err := issue(t, encryption, "billing.example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := jwt.Parse([]byte(token.Value), jwt.WithKey(jwa.ES256, &signingKey.PublicKey)); err != nil {
+			t.Errorf("expected signed JWT: %v", err)
+		}
+	})
+
+	t.Run("multiple audiences with a recipient", func(t *testing.T) {
+		_, err := issue(t, encryption, "orders.example.com", "billing.example.com")
+		if err == nil || !strings.Contains(err.Error(), "multiple audiences") {
+			t.Errorf("expected multiple audiences error, got %v", err)
+		}
+	})
+
+	t.Run("no suitable key", func(t *testing.T) {
+		ecOnly := &TokenEncryption{
+			Recipients: []EncryptionRecipient{
+				{Audience: "orders.example.com", Keys: recipientKeys(t, ecKey)},
+			},
+		}
+		_, err := issue(t, ecOnly, "orders.example.com")
+		if err == nil || !strings.Contains(err.Error(), "no encryption key") {
+			t.Errorf("expected no encryption key error, got %v", err)
+		}
+	})
+}
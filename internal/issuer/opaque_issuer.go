@@ -0,0 +1,231 @@
+package issuer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/idgen"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// opaqueTokenBytes is the number of random bytes in an opaque token
+const opaqueTokenBytes = 32
+
+// OpaqueIssuerConfig is the configuration for creating an opaque issuer
+type OpaqueIssuerConfig struct {
+	// IssuerURL is the issuer URL (iss claim returned by introspection)
+	IssuerURL string
+
+	// TokenType is the token type to issue
+	TokenType string
+
+	// TTL is the time-to-live for tokens
+	TTL time.Duration
+
+	// ClaimMappers are the mappers to apply to generate claims
+	ClaimMappers []service.ClaimMapper
+
+	// Redaction, if set, strips or hashes sensitive mapped claims before issuance
+	Redaction *Redaction
+
+	// Profiles, if set, shape the claims of tokens for some audiences
+	Profiles []AudienceProfile
+
+	// DynamicTTL, if set, decides the lifetime of each token, within its bounds;
+	// TTL is used where its policy decides none
+	DynamicTTL *DynamicTTL
+
+	// Store keeps the claims of issued tokens for introspection
+	Store tokenstore.Store
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+
+	// IDGenerator is an optional generator for jti claims (defaults to random UUIDs)
+	IDGenerator idgen.Generator
+
+	// NotBeforeSkew backdates the nbf claim, for consumers whose clocks run behind
+	// (zero: nbf equals iat)
+	NotBeforeSkew time.Duration
+}
+
+// OpaqueIssuer issues opaque reference tokens
+// A token is a random string that carries no claims; its claims are kept in a
+// tokenstore.Store, where resource servers look them up by introspecting the token.
+type OpaqueIssuer struct {
+	issuerURL     string
+	tokenType     string
+	ttl           time.Duration
+	dynamicTTL    *DynamicTTL
+	claimMappers  []service.ClaimMapper
+	redaction     *Redaction
+	profiles      []AudienceProfile
+	store         tokenstore.Store
+	clock         clock.Clock
+	idGenerator   idgen.Generator
+	notBeforeSkew time.Duration
+}
+
+// NewOpaqueIssuer creates a new opaque issuer
+func NewOpaqueIssuer(cfg OpaqueIssuerConfig) *OpaqueIssuer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	idGenerator := cfg.IDGenerator
+	if idGenerator == nil {
+		idGenerator = idgen.NewUUIDGenerator()
+	}
+
+	return &OpaqueIssuer{
+		issuerURL:     cfg.IssuerURL,
+		tokenType:     cfg.TokenType,
+		ttl:           cfg.TTL,
+		dynamicTTL:    cfg.DynamicTTL,
+		claimMappers:  cfg.ClaimMappers,
+		redaction:     cfg.Redaction,
+		profiles:      cfg.Profiles,
+		store:         cfg.Store,
+		clock:         clk,
+		idGenerator:   idGenerator,
+		notBeforeSkew: cfg.NotBeforeSkew,
+	}
+}
+
+// Issue implements the Issuer interface
+// Stores the token's claims and returns a random reference to them
+func (i *OpaqueIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	return i.IssueChecked(ctx, issueCtx, nil)
+}
+
+// IssueChecked implements service.ReplayCheckingIssuer
+// The token's claims are stored only if check accepts the token.
+func (i *OpaqueIssuer) IssueChecked(ctx context.Context, issueCtx *service.IssueContext, check func(context.Context, *service.Token) error) (*service.Token, error) {
+	token, claims, err := i.newToken(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+	if check != nil {
+		if err := check(ctx, token); err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &tokenstore.Record{
+		TokenType: i.tokenType,
+		Claims:    claims,
+		ExpiresAt: token.ExpiresAt,
+	}
+	if err := i.store.Put(ctx, tokenstore.Key(value), record); err != nil {
+		return nil, fmt.Errorf("failed to store token: %w", err)
+	}
+
+	token.Value = value
+	return token, nil
+}
+
+// Preview implements service.PreviewingIssuer
+// Its claims are those introspection would return; nothing is stored.
+func (i *OpaqueIssuer) Preview(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	token, claims, err := i.newToken(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+	token.Claims = claims
+	return token, nil
+}
+
+// newToken returns the token for issueCtx, without a value, and the claims kept for it
+func (i *OpaqueIssuer) newToken(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, map[string]any, error) {
+	claimMappers, redaction := i.claimMappers, i.redaction
+	if profile := profileFor(i.profiles, issueCtx.Audiences); profile != nil {
+		claimMappers, redaction = profile.ClaimMappers, profile.redactionOr(i.redaction)
+	}
+
+	mappedClaims, err := issueCtx.ToClaims(ctx, claimMappers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map claims: %w", err)
+	}
+	mappedClaims, redacted, err := redaction.Redact("", mappedClaims)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to redact claims: %w", err)
+	}
+
+	ttl, err := i.dynamicTTL.ttlFor(ctx, issueCtx, i.ttl)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := i.clock.Now()
+	expiresAt := now.Add(ttl)
+
+	// Mapped claims come first so they cannot override the registered claims
+	claims := map[string]any(mappedClaims)
+	claims["iss"] = i.issuerURL
+	claims["sub"] = issueCtx.Subject.Subject
+	claims["aud"] = issueCtx.Audiences
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Add(-i.notBeforeSkew).Unix()
+	claims["exp"] = expiresAt.Unix()
+	tokenID := i.idGenerator.NewID()
+	claims["jti"] = tokenID
+	if issueCtx.Scope != "" {
+		claims["scope"] = issueCtx.Scope
+	}
+	if issueCtx.ClientID != "" {
+		claims["client_id"] = issueCtx.ClientID
+	}
+	if issueCtx.Delegation != nil {
+		claims[trust.ActClaim] = issueCtx.Delegation.Claim()
+	}
+	if issueCtx.CertificateThumbprint != "" {
+		claims[trust.ConfirmationClaim] = map[string]any{
+			trust.X509ThumbprintConfirmation: issueCtx.CertificateThumbprint,
+		}
+	}
+
+	return &service.Token{
+		Type:           i.tokenType,
+		ExpiresAt:      expiresAt,
+		IssuedAt:       now,
+		ID:             tokenID,
+		RedactedClaims: redacted,
+	}, claims, nil
+}
+
+// newOpaqueToken returns a new random, base64url-encoded token
+func newOpaqueToken() (string, error) {
+	b := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PublicKeys implements the Issuer interface
+// Opaque issuer returns an empty slice since tokens are not signed
+func (i *OpaqueIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return []service.PublicKey{}, nil
+}
+
+// Describe implements service.DescribableIssuer
+func (i *OpaqueIssuer) Describe() service.IssuerDescription {
+	minTTL, maxTTL := i.dynamicTTL.bounds(i.ttl)
+	return service.IssuerDescription{
+		IssuerURL: i.issuerURL,
+		Format:    service.TokenFormatOpaque,
+		MinTTL:    minTTL,
+		MaxTTL:    maxTTL,
+	}
+}
@@ -0,0 +1,139 @@
+package issuer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestOpaqueIssuer_Issue(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := tokenstore.NewMemoryStore(clk)
+	tokenType := "urn:ietf:params:oauth:token-type:access_token"
+
+	issuer := NewOpaqueIssuer(OpaqueIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TokenType: tokenType,
+		TTL:       5 * time.Minute,
+		ClaimMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{
+			"department": "engineering",
+			"iss":        "https://mapper.example.com",
+		})},
+		Store: store,
+		Clock: clk,
+	})
+
+	// client_id comes from the authenticated client, not the request's attributes
+	attrs := &request.RequestAttributes{Additional: map[string]any{"client_id": "spoofed"}}
+	issueCtx := &service.IssueContext{
+		Subject:               &trust.Result{Subject: "user@example.com"},
+		RequestAttributes:     attrs,
+		Delegation:            &trust.Delegation{Subject: "spiffe://example.org/frontend"},
+		Audiences:             []string{"orders.example.com"},
+		CertificateThumbprint: "thumbprint",
+		Scope:                 "orders:read",
+		ClientID:              "orders",
+		DataSourceRegistry:    service.NewDataSourceRegistry(),
+	}
+
+	token, err := issuer.Issue(ctx, issueCtx)
+	if err != nil {
+		t.Fatalf("Issue() failed: %v", err)
+	}
+	if token.Type != tokenType {
+		t.Errorf("expected token type %q, got %q", tokenType, token.Type)
+	}
+	if want := clk.Now().Add(5 * time.Minute); !token.ExpiresAt.Equal(want) {
+		t.Errorf("expected expiry %v, got %v", want, token.ExpiresAt)
+	}
+
+	record, err := store.Get(ctx, tokenstore.Key(token.Value))
+	if err != nil {
+		t.Fatalf("expected the token to be stored: %v", err)
+	}
+	if record.TokenType != tokenType || !record.ExpiresAt.Equal(token.ExpiresAt) {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	want := map[string]any{
+		"iss":        "https://parsec.example.com",
+		"sub":        "user@example.com",
+		"exp":        token.ExpiresAt.Unix(),
+		"scope":      "orders:read",
+		"client_id":  "orders",
+		"department": "engineering",
+	}
+	for name, value := range want {
+		if record.Claims[name] != value {
+			t.Errorf("expected %s=%v, got %v", name, value, record.Claims[name])
+		}
+	}
+	if aud, _ := record.Claims["aud"].([]string); !slices.Equal(aud, issueCtx.Audiences) {
+		t.Errorf("expected aud %v, got %v", issueCtx.Audiences, record.Claims["aud"])
+	}
+	if act, _ := record.Claims[trust.ActClaim].(map[string]any); act["sub"] != "spiffe://example.org/frontend" {
+		t.Errorf("expected act claim, got %v", record.Claims[trust.ActClaim])
+	}
+	if cnf, _ := record.Claims[trust.ConfirmationClaim].(map[string]any); cnf[trust.X509ThumbprintConfirmation] != "thumbprint" {
+		t.Errorf("expected cnf claim, got %v", record.Claims[trust.ConfirmationClaim])
+	}
+
+	t.Run("tokens are unique", func(t *testing.T) {
+		other, err := issuer.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("Issue() failed: %v", err)
+		}
+		if other.Value == token.Value {
+			t.Error("expected a new token value")
+		}
+	})
+
+	t.Run("tokens rejected by check are not stored", func(t *testing.T) {
+		puts := &countingStore{Store: store}
+		checked := NewOpaqueIssuer(OpaqueIssuerConfig{TokenType: tokenType, TTL: time.Minute, Store: puts, Clock: clk})
+
+		rejected := errors.New("rejected")
+		var checkedID string
+		_, err := checked.IssueChecked(ctx, issueCtx, func(ctx context.Context, token *service.Token) error {
+			checkedID = token.ID
+			return rejected
+		})
+		if !errors.Is(err, rejected) {
+			t.Fatalf("expected the check's error, got %v", err)
+		}
+		if checkedID == "" {
+			t.Error("expected the token's ID to be checked")
+		}
+		if puts.puts != 0 {
+			t.Errorf("expected nothing to be stored, got %d records", puts.puts)
+		}
+	})
+
+	t.Run("not introspectable after expiry", func(t *testing.T) {
+		clk.Advance(5 * time.Minute)
+		if _, err := store.Get(ctx, tokenstore.Key(token.Value)); err == nil {
+			t.Error("expected the record to have expired")
+		}
+	})
+}
+
+// countingStore counts the records put in its Store
+type countingStore struct {
+	tokenstore.Store
+	puts int
+}
+
+func (s *countingStore) Put(ctx context.Context, key string, record *tokenstore.Record) error {
+	s.puts++
+	return s.Store.Put(ctx, key, record)
+}
@@ -0,0 +1,59 @@
+package issuer
+
+import (
+	"slices"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// AudienceProfile shapes the claims of tokens for some audiences, so the same
+// subject can get a minimal token for one service and a rich one for another
+//
+// A profile's mappers replace the issuer's, so a profile without mappers issues
+// tokens without mapped claims.
+type AudienceProfile struct {
+	// Audiences are the audiences the profile applies to. A token uses the first
+	// profile that lists every one of its audiences, or the issuer's mappers if none do.
+	Audiences []string
+
+	// TransactionContextMappers build the "tctx" claim (transaction tokens only)
+	TransactionContextMappers []service.ClaimMapper
+
+	// RequestContextMappers build the "req_ctx" claim (transaction tokens only)
+	RequestContextMappers []service.ClaimMapper
+
+	// ClaimMappers build the claims of other tokens
+	ClaimMappers []service.ClaimMapper
+
+	// Redaction, if set, replaces the issuer's redaction
+	Redaction *Redaction
+}
+
+// profileFor returns the first profile for all of audiences, or nil if there is none
+func profileFor(profiles []AudienceProfile, audiences []string) *AudienceProfile {
+	if len(audiences) == 0 {
+		return nil
+	}
+	for i := range profiles {
+		profile := &profiles[i]
+		matches := true
+		for _, aud := range audiences {
+			if !slices.Contains(profile.Audiences, aud) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return profile
+		}
+	}
+	return nil
+}
+
+// redactionOr returns the profile's redaction, or fallback if it has none
+func (p *AudienceProfile) redactionOr(fallback *Redaction) *Redaction {
+	if p.Redaction != nil {
+		return p.Redaction
+	}
+	return fallback
+}
@@ -0,0 +1,92 @@
+package issuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestTransactionTokenIssuer_Profiles(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+		TransactionContextMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{
+			"user":   "alice",
+			"email":  "alice@example.com",
+			"groups": []any{"admins"},
+		})},
+		RequestContextMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{"method": "GET"})},
+		Profiles: []AudienceProfile{{
+			Audiences:                 []string{"billing.example.com", "invoices.example.com"},
+			TransactionContextMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{"user": "alice"})},
+		}},
+	})
+
+	issue := func(t *testing.T, audiences ...string) jwt.Token {
+		t.Helper()
+		token, err := issuer.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "alice"},
+			Audiences:          audiences,
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+		if err != nil {
+			t.Fatalf("Issue failed: %v", err)
+		}
+		parsed, err := jwt.ParseInsecure([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		return parsed
+	}
+
+	t.Run("uses the profile of the audience", func(t *testing.T) {
+		token := issue(t, "billing.example.com")
+		tctx, _ := token.Get("tctx")
+		if !reflect.DeepEqual(tctx, map[string]any{"user": "alice"}) {
+			t.Errorf("expected a minimal tctx, got %v", tctx)
+		}
+		if reqCtx, ok := token.Get("req_ctx"); ok {
+			t.Errorf("expected no req_ctx, got %v", reqCtx)
+		}
+	})
+
+	t.Run("uses the issuer's mappers for other audiences", func(t *testing.T) {
+		token := issue(t, "internal-api.example.com")
+		tctx, _ := token.Get("tctx")
+		if len(tctx.(map[string]any)) != 3 {
+			t.Errorf("expected a rich tctx, got %v", tctx)
+		}
+	})
+
+	t.Run("uses a profile only if it lists every audience", func(t *testing.T) {
+		if profile := profileFor(issuer.profiles, []string{"billing.example.com", "invoices.example.com"}); profile == nil {
+			t.Error("expected the profile for both audiences")
+		}
+		if profile := profileFor(issuer.profiles, []string{"billing.example.com", "internal-api.example.com"}); profile != nil {
+			t.Errorf("expected no profile, got %v", profile.Audiences)
+		}
+	})
+}
@@ -0,0 +1,320 @@
+package issuer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/alechenninger/parsec/internal/claims"
+)
+
+// RedactionAction is what is done with a sensitive claim
+type RedactionAction string
+
+const (
+	// RedactionRemove leaves the claim out of the token (the default)
+	RedactionRemove RedactionAction = "remove"
+
+	// RedactionHash replaces each value in the claim with the base64url SHA-256
+	// hash of its string form (HMAC-SHA256, with a hash key), so it still
+	// correlates without revealing the value
+	RedactionHash RedactionAction = "hash"
+)
+
+// RedactionRule selects sensitive claims by path, value pattern, or CEL condition
+// A claim matching any of a rule's selectors is redacted.
+type RedactionRule struct {
+	// Claims are claim paths, like "tctx.email" or "req_ctx.headers.*", where "*"
+	// matches any one key
+	Claims []string
+
+	// Pattern matches sensitive string values anywhere in the claims, like emails
+	Pattern string
+
+	// When is a CEL condition on each claim's path and value, like
+	// `path.endsWith("_ip")`
+	When string
+
+	// Action is what is done with matching claims (default: RedactionRemove)
+	Action RedactionAction
+}
+
+// RedactionConfig configures a Redaction
+type RedactionConfig struct {
+	// Allow, if set, lists the only claim paths tokens may carry; others are removed.
+	// Allowing a path allows everything under it.
+	Allow []string
+
+	// Rules redact sensitive claims, after the allowlist
+	Rules []RedactionRule
+
+	// HashKey, if set, keys the hashes of RedactionHash, so values cannot be
+	// recovered by hashing guesses
+	HashKey []byte
+}
+
+// Redaction strips or hashes sensitive mapped claims before tokens are signed, for
+// data minimization. Registered claims like sub and exp are not redacted.
+type Redaction struct {
+	allow   [][]string
+	rules   []redactionRule
+	hashKey []byte
+}
+
+type redactionRule struct {
+	claims  [][]string
+	pattern *regexp.Regexp
+	when    cel.Program
+	action  RedactionAction
+}
+
+// NewRedaction creates a Redaction, compiling its patterns and conditions
+func NewRedaction(cfg RedactionConfig) (*Redaction, error) {
+	r := &Redaction{hashKey: cfg.HashKey}
+	for _, path := range cfg.Allow {
+		r.allow = append(r.allow, strings.Split(path, "."))
+	}
+
+	var env *cel.Env
+	for i, rule := range cfg.Rules {
+		compiled := redactionRule{action: rule.Action}
+		switch rule.Action {
+		case "":
+			compiled.action = RedactionRemove
+		case RedactionRemove, RedactionHash:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action %s (supported: remove, hash)", i, rule.Action)
+		}
+		if len(rule.Claims) == 0 && rule.Pattern == "" && rule.When == "" {
+			return nil, fmt.Errorf("rule %d: claims, pattern, or when is required", i)
+		}
+		for _, path := range rule.Claims {
+			compiled.claims = append(compiled.claims, strings.Split(path, "."))
+		}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: invalid pattern: %w", i, err)
+			}
+			compiled.pattern = pattern
+		}
+		if rule.When != "" {
+			if env == nil {
+				var err error
+				env, err = cel.NewEnv(
+					cel.Variable("path", cel.StringType),
+					cel.Variable("value", cel.DynType),
+				)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+				}
+			}
+			ast, issues := env.Compile(rule.When)
+			if issues != nil && issues.Err() != nil {
+				return nil, fmt.Errorf("rule %d: failed to compile when condition: %w", i, issues.Err())
+			}
+			if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+				return nil, fmt.Errorf("rule %d: when condition must evaluate to a bool, got %s", i, ast.OutputType())
+			}
+			program, err := env.Program(ast)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: failed to create CEL program: %w", i, err)
+			}
+			compiled.when = program
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// Redact returns mapped claims with sensitive claims removed or hashed, and the
+// sorted paths of the claims redacted. Paths start with prefix, like "tctx.", which
+// is how claims are named in the allowlist and rules. A nil Redaction redacts
+// nothing. The claims are not modified.
+func (r *Redaction) Redact(prefix string, mapped claims.Claims) (claims.Claims, []string, error) {
+	if r == nil || mapped == nil {
+		return mapped, nil, nil
+	}
+	var parent []string
+	if prefix != "" {
+		parent = strings.Split(strings.TrimSuffix(prefix, "."), ".")
+	}
+	redactor := &redactor{Redaction: r}
+	result, err := redactor.object(parent, mapped)
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.Sort(redactor.redacted)
+	return claims.Claims(result), slices.Compact(redactor.redacted), nil
+}
+
+// redactor redacts the claims of one token
+type redactor struct {
+	*Redaction
+	redacted []string
+}
+
+// object redacts the claims of an object at path
+func (r *redactor) object(path []string, object map[string]any) (map[string]any, error) {
+	result := make(map[string]any, len(object))
+	for key, value := range object {
+		claimPath := append(slices.Clip(path), key)
+		kept, keep, err := r.value(claimPath, value)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			result[key] = kept
+		}
+	}
+	return result, nil
+}
+
+// value redacts the claim at path, returning false if it is removed
+func (r *redactor) value(path []string, value any) (any, bool, error) {
+	if c, ok := value.(claims.Claims); ok {
+		value = map[string]any(c)
+	}
+	allowed, under := r.allowed(path)
+	if !allowed && !under {
+		r.redacted = append(r.redacted, strings.Join(path, "."))
+		return nil, false, nil
+	}
+
+	for _, rule := range r.rules {
+		matched, err := r.matches(rule, path, value)
+		if err != nil {
+			return nil, false, err
+		}
+		if !matched {
+			continue
+		}
+		r.redacted = append(r.redacted, strings.Join(path, "."))
+		if rule.action == RedactionHash {
+			return r.hash(value), true, nil
+		}
+		return nil, false, nil
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		result, err := r.object(path, v)
+		return result, err == nil, err
+	case []any:
+		result := make([]any, 0, len(v))
+		for _, element := range v {
+			kept, keep, err := r.value(path, element)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				result = append(result, kept)
+			}
+		}
+		// Arrays only partly allowed are removed if none of their elements are
+		return result, allowed || len(result) > 0 || len(v) == 0, nil
+	}
+	if !allowed {
+		// Only paths under this one are allowed, and it has none
+		r.redacted = append(r.redacted, strings.Join(path, "."))
+		return nil, false, nil
+	}
+	return value, true, nil
+}
+
+// allowed reports whether the allowlist allows path and everything under it, or
+// only some paths under it. Everything is allowed without an allowlist.
+func (r *redactor) allowed(path []string) (allowed, under bool) {
+	if len(r.allow) == 0 {
+		return true, false
+	}
+	for _, pattern := range r.allow {
+		if len(pattern) <= len(path) && matchPath(pattern, path[:len(pattern)]) {
+			return true, false
+		}
+		if len(pattern) > len(path) && matchPath(pattern[:len(path)], path) {
+			under = true
+		}
+	}
+	return false, under
+}
+
+// matches reports whether a rule selects the claim at path
+func (r *redactor) matches(rule redactionRule, path []string, value any) (bool, error) {
+	for _, pattern := range rule.claims {
+		if len(pattern) == len(path) && matchPath(pattern, path) {
+			return true, nil
+		}
+	}
+	if s, ok := value.(string); ok && rule.pattern != nil && rule.pattern.MatchString(s) {
+		return true, nil
+	}
+	if rule.when != nil {
+		result, _, err := rule.when.Eval(map[string]any{
+			"path":  strings.Join(path, "."),
+			"value": value,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to evaluate redaction condition for %s: %w", strings.Join(path, "."), err)
+		}
+		matched, ok := result.(types.Bool)
+		if !ok {
+			return false, fmt.Errorf("redaction condition must evaluate to a bool, got %s", result.Type())
+		}
+		return bool(matched), nil
+	}
+	return false, nil
+}
+
+// hash replaces every leaf of value with the hash of its string form
+func (r *redactor) hash(value any) any {
+	switch v := value.(type) {
+	case string:
+		var sum []byte
+		if len(r.hashKey) > 0 {
+			mac := hmac.New(sha256.New, r.hashKey)
+			mac.Write([]byte(v))
+			sum = mac.Sum(nil)
+		} else {
+			digest := sha256.Sum256([]byte(v))
+			sum = digest[:]
+		}
+		return base64.RawURLEncoding.EncodeToString(sum)
+	case []any:
+		result := make([]any, len(v))
+		for i, element := range v {
+			result[i] = r.hash(element)
+		}
+		return result
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, element := range v {
+			result[key] = r.hash(element)
+		}
+		return result
+	case nil:
+		return nil
+	default:
+		return r.hash(fmt.Sprint(v))
+	}
+}
+
+// matchPath reports whether path matches pattern, segment by segment
+func matchPath(pattern, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package issuer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestRedaction(t *testing.T) {
+	mapped := claims.Claims{
+		"user":  "alice",
+		"email": "alice@example.com",
+		"org": map[string]any{
+			"id":      "acme",
+			"contact": "ops@acme.example.com",
+			"plan":    "gold",
+		},
+		"client_ip": "203.0.113.7",
+		"groups":    []any{"admins", "bob@example.com"},
+	}
+
+	redact := func(t *testing.T, cfg RedactionConfig) (claims.Claims, []string) {
+		t.Helper()
+		redaction, err := NewRedaction(cfg)
+		if err != nil {
+			t.Fatalf("NewRedaction failed: %v", err)
+		}
+		got, redacted, err := redaction.Redact("tctx.", mapped)
+		if err != nil {
+			t.Fatalf("Redact failed: %v", err)
+		}
+		return got, redacted
+	}
+
+	t.Run("removes claims by path, pattern, and condition", func(t *testing.T) {
+		got, redacted := redact(t, RedactionConfig{Rules: []RedactionRule{
+			{Claims: []string{"tctx.org.plan"}},
+			{Pattern: `^[^@\s]+@[^@\s]+$`},
+			{When: `path.endsWith("_ip")`},
+		}})
+		want := claims.Claims{
+			"user":   "alice",
+			"org":    map[string]any{"id": "acme"},
+			"groups": []any{"admins"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		wantRedacted := []string{"tctx.client_ip", "tctx.email", "tctx.groups", "tctx.org.contact", "tctx.org.plan"}
+		if !reflect.DeepEqual(redacted, wantRedacted) {
+			t.Errorf("expected redacted %v, got %v", wantRedacted, redacted)
+		}
+		if _, ok := mapped["email"]; !ok {
+			t.Error("expected the mapped claims not to be modified")
+		}
+	})
+
+	t.Run("hashes claims", func(t *testing.T) {
+		got, _ := redact(t, RedactionConfig{Rules: []RedactionRule{
+			{Claims: []string{"tctx.email"}, Action: RedactionHash},
+		}})
+		sum := sha256.Sum256([]byte("alice@example.com"))
+		if want := base64.RawURLEncoding.EncodeToString(sum[:]); got["email"] != want {
+			t.Errorf("expected hash %s, got %v", want, got["email"])
+		}
+
+		keyed, _ := redact(t, RedactionConfig{
+			Rules:   []RedactionRule{{Claims: []string{"tctx.email"}, Action: RedactionHash}},
+			HashKey: []byte("secret"),
+		})
+		if keyed["email"] == got["email"] {
+			t.Error("expected a keyed hash to differ from an unkeyed one")
+		}
+	})
+
+	t.Run("keeps only allowed claims", func(t *testing.T) {
+		got, redacted := redact(t, RedactionConfig{Allow: []string{"tctx.user", "tctx.org.id", "tctx.groups"}})
+		want := claims.Claims{
+			"user":   "alice",
+			"org":    map[string]any{"id": "acme"},
+			"groups": []any{"admins", "bob@example.com"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		wantRedacted := []string{"tctx.client_ip", "tctx.email", "tctx.org.contact", "tctx.org.plan"}
+		if !reflect.DeepEqual(redacted, wantRedacted) {
+			t.Errorf("expected redacted %v, got %v", wantRedacted, redacted)
+		}
+	})
+
+	t.Run("matches wildcards", func(t *testing.T) {
+		got, _ := redact(t, RedactionConfig{Allow: []string{"tctx.*.id", "tctx.user"}})
+		if !reflect.DeepEqual(got, claims.Claims{"user": "alice", "org": map[string]any{"id": "acme"}}) {
+			t.Errorf("unexpected claims: %v", got)
+		}
+	})
+
+	t.Run("rejects invalid rules", func(t *testing.T) {
+		for _, rule := range []RedactionRule{
+			{},
+			{Pattern: "("},
+			{When: `"yes"`},
+			{Claims: []string{"tctx.email"}, Action: "encrypt"},
+		} {
+			if _, err := NewRedaction(RedactionConfig{Rules: []RedactionRule{rule}}); err == nil {
+				t.Errorf("expected error for rule %+v", rule)
+			}
+		}
+	})
+}
+
+func TestUnsignedIssuer_Redaction(t *testing.T) {
+	redaction, err := NewRedaction(RedactionConfig{Rules: []RedactionRule{{Claims: []string{"email"}}}})
+	if err != nil {
+		t.Fatalf("NewRedaction failed: %v", err)
+	}
+	issuer := NewUnsignedIssuer(UnsignedIssuerConfig{
+		TokenType:    "urn:example:unsigned",
+		ClaimMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{"user": "alice", "email": "alice@example.com"})},
+		Redaction:    redaction,
+	})
+
+	token, err := issuer.Issue(context.Background(), &service.IssueContext{
+		Subject:            &trust.Result{Subject: "alice"},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("Issue failed: %v", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(token.Value)
+	if err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed to decode claims: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]any{"user": "alice"}) {
+		t.Errorf("expected email to be redacted, got %v", got)
+	}
+	if !reflect.DeepEqual(token.RedactedClaims, []string{"email"}) {
+		t.Errorf("expected redacted claims to be reported, got %v", token.RedactedClaims)
+	}
+}
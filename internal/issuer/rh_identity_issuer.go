@@ -19,6 +19,12 @@ type RHIdentityIssuerConfig struct {
 	// ClaimMappers are the mappers to apply to generate claims
 	ClaimMappers []service.ClaimMapper
 
+	// Redaction, if set, strips or hashes sensitive mapped claims before issuance
+	Redaction *Redaction
+
+	// Profiles, if set, shape the claims of tokens for some audiences
+	Profiles []AudienceProfile
+
 	// Clock is the time source for token timestamps
 	// If nil, uses system clock
 	Clock clock.Clock
@@ -29,6 +35,8 @@ type RHIdentityIssuerConfig struct {
 type RHIdentityIssuer struct {
 	tokenType    string
 	claimMappers []service.ClaimMapper
+	redaction    *Redaction
+	profiles     []AudienceProfile
 	clock        clock.Clock
 }
 
@@ -42,6 +50,8 @@ func NewRHIdentityIssuer(cfg RHIdentityIssuerConfig) *RHIdentityIssuer {
 	return &RHIdentityIssuer{
 		tokenType:    cfg.TokenType,
 		claimMappers: cfg.ClaimMappers,
+		redaction:    cfg.Redaction,
+		profiles:     cfg.Profiles,
 		clock:        clk,
 	}
 }
@@ -50,10 +60,19 @@ func NewRHIdentityIssuer(cfg RHIdentityIssuerConfig) *RHIdentityIssuer {
 // Returns a token in the x-rh-identity format: base64(JSON({"identity": {...}}))
 func (i *RHIdentityIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
 	// Apply claim mappers
-	mappedClaims, err := issueCtx.ToClaims(ctx, i.claimMappers)
+	claimMappers, redaction := i.claimMappers, i.redaction
+	if profile := profileFor(i.profiles, issueCtx.Audiences); profile != nil {
+		claimMappers, redaction = profile.ClaimMappers, profile.redactionOr(i.redaction)
+	}
+
+	mappedClaims, err := issueCtx.ToClaims(ctx, claimMappers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to map claims: %w", err)
 	}
+	mappedClaims, redacted, err := redaction.Redact("", mappedClaims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redact claims: %w", err)
+	}
 
 	// Wrap mapped claims in "identity" wrapper
 	// This matches the format expected by Red Hat services
@@ -75,15 +94,35 @@ func (i *RHIdentityIssuer) Issue(ctx context.Context, issueCtx *service.IssueCon
 	neverExpires := time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
 
 	return &service.Token{
-		Value:     encodedToken,
-		Type:      i.tokenType,
-		ExpiresAt: neverExpires,
-		IssuedAt:  i.clock.Now(),
+		Value:          encodedToken,
+		Type:           i.tokenType,
+		ExpiresAt:      neverExpires,
+		IssuedAt:       i.clock.Now(),
+		RedactedClaims: redacted,
 	}, nil
 }
 
+// Preview implements service.PreviewingIssuer
+// Issuing RH identity tokens has no side effects, so the preview is an issued token's
+// claims, including the "identity" wrapper, without its value.
+func (i *RHIdentityIssuer) Preview(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	token, err := i.Issue(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+	return previewBase64JSON(token)
+}
+
 // PublicKeys implements the Issuer interface
 // RH identity issuer returns an empty slice since tokens are not signed
 func (i *RHIdentityIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
 	return []service.PublicKey{}, nil
 }
+
+// Describe implements service.DescribableIssuer
+// Identity tokens carry no issuer and never expire
+func (i *RHIdentityIssuer) Describe() service.IssuerDescription {
+	return service.IssuerDescription{
+		Format: service.TokenFormatRHIdentity,
+	}
+}
@@ -0,0 +1,234 @@
+package issuer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+)
+
+// ErrTokenTooLarge is returned when a token cannot be issued within its size budget
+var ErrTokenTooLarge = errors.New("token exceeds size budget")
+
+// TruncatedClaimsClaim lists the context claims left out of a token to fit its
+// size budget, like "tctx.groups"
+const TruncatedClaimsClaim = "truncated_claims"
+
+// SizeBudgetAction is what is done with low-priority claims when a token is over
+// its size budget
+type SizeBudgetAction string
+
+const (
+	// SizeBudgetFail fails issuance (the default)
+	SizeBudgetFail SizeBudgetAction = "fail"
+
+	// SizeBudgetTruncate leaves low-priority claims out, listing them in
+	// TruncatedClaimsClaim
+	SizeBudgetTruncate SizeBudgetAction = "truncate"
+
+	// SizeBudgetReference moves low-priority claims to the issuer's ClaimsReferences,
+	// referenced by ClaimsReferenceClaim
+	SizeBudgetReference SizeBudgetAction = "reference"
+)
+
+// SizeBudget bounds the size of transaction tokens, so they fit in the headers of
+// the proxies they pass through
+type SizeBudget struct {
+	// MaxTokenBytes bounds the length of encoded tokens (0 for no limit)
+	MaxTokenBytes int
+
+	// MaxClaimBytes bounds the JSON size of each tctx and req_ctx claim (0 for no limit)
+	MaxClaimBytes int
+
+	// LowPriorityClaims are the context claims that may be shed to fit the budget,
+	// like "tctx.groups" or "req_ctx.headers", in the order they are shed
+	LowPriorityClaims []string
+
+	// OnExceeded is what is done with low-priority claims when the budget is
+	// exceeded (default: SizeBudgetFail). Issuance fails if shedding them all is
+	// not enough, or a claim over MaxClaimBytes is not low priority.
+	OnExceeded SizeBudgetAction
+}
+
+// claimShedder sheds the low-priority claims of one token to fit its budget, and
+// moves the claims always kept by reference
+type claimShedder struct {
+	budget     *SizeBudget              // nil without a budget
+	references *ClaimsReferences        // nil if claims cannot be moved
+	contexts   map[string]claims.Claims // Context claims by the name of their claim in the token
+
+	next      int                      // Index of the next low-priority claim to shed
+	shed      []string                 // Paths of the low-priority claims shed
+	truncated []string                 // Paths of the claims left out
+	moved     map[string]claims.Claims // Claims moved to the reference, by context
+	handle    string                   // Opaque handle of the reference
+}
+
+func newClaimShedder(budget *SizeBudget, references *ClaimsReferences, contexts map[string]claims.Claims) *claimShedder {
+	return &claimShedder{budget: budget, references: references, contexts: contexts}
+}
+
+// moveReferenced moves the claims always kept by reference
+func (s *claimShedder) moveReferenced() error {
+	if s.references == nil {
+		return nil
+	}
+	for _, path := range s.references.Claims {
+		if !s.has(path) {
+			continue
+		}
+		if err := s.moveClaim(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enforceClaimLimit sheds low-priority claims over MaxClaimBytes, and fails if any
+// other claim is over it
+func (s *claimShedder) enforceClaimLimit() error {
+	if s.budget == nil || s.budget.MaxClaimBytes <= 0 {
+		return nil
+	}
+	for _, name := range []string{"tctx", "req_ctx"} {
+		for key, value := range s.contexts[name] {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to encode claim %s.%s: %w", name, key, err)
+			}
+			if len(data) <= s.budget.MaxClaimBytes {
+				continue
+			}
+			path := name + "." + key
+			if s.budget.OnExceeded == SizeBudgetFail || s.budget.OnExceeded == "" ||
+				!slices.Contains(s.budget.LowPriorityClaims, path) {
+				return fmt.Errorf("%w: claim %s is %d bytes, over the limit of %d", ErrTokenTooLarge, path, len(data), s.budget.MaxClaimBytes)
+			}
+			if err := s.shedClaim(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fits reports whether an encoded token is within MaxTokenBytes
+func (s *claimShedder) fits(token string) bool {
+	return s.budget == nil || s.budget.MaxTokenBytes <= 0 || len(token) <= s.budget.MaxTokenBytes
+}
+
+// shedNext sheds the next low-priority claim the token has, for a token of size bytes
+func (s *claimShedder) shedNext(size int) error {
+	if s.budget.OnExceeded != SizeBudgetFail && s.budget.OnExceeded != "" {
+		for s.next < len(s.budget.LowPriorityClaims) {
+			path := s.budget.LowPriorityClaims[s.next]
+			s.next++
+			if s.has(path) {
+				return s.shedClaim(path)
+			}
+		}
+	}
+	if len(s.shed) > 0 {
+		return fmt.Errorf("%w: token is %d bytes after shedding %s, over the limit of %d",
+			ErrTokenTooLarge, size, strings.Join(s.shed, ", "), s.budget.MaxTokenBytes)
+	}
+	return fmt.Errorf("%w: token is %d bytes, over the limit of %d", ErrTokenTooLarge, size, s.budget.MaxTokenBytes)
+}
+
+// has reports whether the context claim at path is in the token
+func (s *claimShedder) has(path string) bool {
+	name, key, ok := strings.Cut(path, ".")
+	if !ok {
+		return false
+	}
+	_, has := s.contexts[name][key]
+	return has
+}
+
+// shedClaim removes the low-priority claim at path from the token, moving it to the
+// reference if the budget says to
+func (s *claimShedder) shedClaim(path string) error {
+	s.shed = append(s.shed, path)
+	if s.budget.OnExceeded == SizeBudgetReference {
+		return s.moveClaim(path)
+	}
+	name, key, _ := strings.Cut(path, ".")
+	delete(s.contexts[name], key)
+	s.truncated = append(s.truncated, path)
+	return nil
+}
+
+// moveClaim moves the context claim at path from the token to the reference
+func (s *claimShedder) moveClaim(path string) error {
+	if s.references == nil || s.references.Store == nil {
+		return fmt.Errorf("cannot move claim %s: no claims reference store", path)
+	}
+	if s.moved == nil {
+		handle, err := newOpaqueToken()
+		if err != nil {
+			return err
+		}
+		s.handle = handle
+		s.moved = make(map[string]claims.Claims)
+	}
+	name, key, _ := strings.Cut(path, ".")
+	if s.moved[name] == nil {
+		s.moved[name] = make(claims.Claims)
+	}
+	s.moved[name][key] = s.contexts[name][key]
+	delete(s.contexts[name], key)
+	return nil
+}
+
+// setClaims sets the claims that tell consumers which claims were truncated or
+// moved out of the token with ID tokenID
+func (s *claimShedder) setClaims(token jwt.Token, tokenID string) error {
+	if s.handle != "" {
+		document, _, err := referenceDocument(tokenID, s.moved)
+		if err != nil {
+			return err
+		}
+		reference := map[string]any{
+			"uri":    s.references.URL + s.handle,
+			"sha256": hashReferenceDocument(document),
+		}
+		if err := token.Set(ClaimsReferenceClaim, reference); err != nil {
+			return fmt.Errorf("failed to set claims reference: %w", err)
+		}
+	}
+	if len(s.truncated) > 0 {
+		if err := token.Set(TruncatedClaimsClaim, s.truncated); err != nil {
+			return fmt.Errorf("failed to set truncated claims: %w", err)
+		}
+	}
+	return nil
+}
+
+// storeReference stores the claims moved out of the token with ID tokenID, if any,
+// until it expires
+func (s *claimShedder) storeReference(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if s.handle == "" {
+		return nil
+	}
+	_, document, err := referenceDocument(tokenID, s.moved)
+	if err != nil {
+		return err
+	}
+	record := &tokenstore.Record{
+		TokenType: ClaimsReferenceTokenType,
+		Claims:    document,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.references.Store.Put(ctx, tokenstore.Key(s.handle), record); err != nil {
+		return fmt.Errorf("failed to store claims reference: %w", err)
+	}
+	return nil
+}
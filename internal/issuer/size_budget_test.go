@@ -0,0 +1,194 @@
+package issuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestTransactionTokenIssuer_SizeBudget(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	groups := make([]any, 200)
+	for i := range groups {
+		groups[i] = "group-with-a-long-name-" + strings.Repeat("x", 10)
+	}
+	mappers := []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{
+		"user":   "alice",
+		"groups": groups,
+	})}
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "user@example.com"},
+		Audiences:          []string{"example.com"},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	issue := func(t *testing.T, budget *SizeBudget, references *ClaimsReferences) (*service.Token, jwt.Token, error) {
+		t.Helper()
+		issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL:                 "https://parsec.example.com",
+			TTL:                       5 * time.Minute,
+			Signer:                    signer,
+			TransactionContextMappers: mappers,
+			SizeBudget:                budget,
+			ClaimsReferences:          references,
+		})
+		token, err := issuer.Issue(ctx, issueCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+		parsed, err := jwt.ParseInsecure([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		return token, parsed, nil
+	}
+
+	tctx := func(t *testing.T, token jwt.Token) map[string]any {
+		t.Helper()
+		value, _ := token.Get("tctx")
+		m, _ := value.(map[string]any)
+		return m
+	}
+
+	// retrieve resolves a claims reference as the claims endpoint does, checking its hash
+	retrieve := func(t *testing.T, store tokenstore.Store, token jwt.Token) map[string]any {
+		t.Helper()
+		value, ok := token.Get(ClaimsReferenceClaim)
+		if !ok {
+			t.Fatal("expected a claims reference")
+		}
+		reference, _ := value.(map[string]any)
+		uri, _ := reference["uri"].(string)
+		handle, ok := strings.CutPrefix(uri, "https://parsec.example.com/v1/claims/")
+		if !ok {
+			t.Fatalf("unexpected reference URI: %v", reference["uri"])
+		}
+		record, err := store.Get(ctx, tokenstore.Key(handle))
+		if err != nil {
+			t.Fatalf("expected the moved claims to be stored: %v", err)
+		}
+		document, err := json.Marshal(record.Claims)
+		if err != nil {
+			t.Fatalf("failed to encode stored claims: %v", err)
+		}
+		if hash := hashReferenceDocument(document); reference["sha256"] != hash {
+			t.Errorf("expected hash %s, got %v", hash, reference["sha256"])
+		}
+		if record.Claims["jti"] != token.JwtID() {
+			t.Errorf("expected the record to name the token, got %v", record.Claims["jti"])
+		}
+		return record.Claims
+	}
+
+	t.Run("fails by default", func(t *testing.T) {
+		_, _, err := issue(t, &SizeBudget{MaxTokenBytes: 2048}, nil)
+		if !errors.Is(err, ErrTokenTooLarge) {
+			t.Errorf("expected ErrTokenTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("truncates low-priority claims", func(t *testing.T) {
+		token, parsed, err := issue(t, &SizeBudget{
+			MaxTokenBytes:     2048,
+			LowPriorityClaims: []string{"tctx.groups"},
+			OnExceeded:        SizeBudgetTruncate,
+		}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(token.Value) > 2048 {
+			t.Errorf("expected token within budget, got %d bytes", len(token.Value))
+		}
+		if claims := tctx(t, parsed); claims["user"] != "alice" || claims["groups"] != nil {
+			t.Errorf("expected groups to be truncated, got %v", claims)
+		}
+		truncated, _ := parsed.Get(TruncatedClaimsClaim)
+		if list, ok := truncated.([]any); !ok || len(list) != 1 || list[0] != "tctx.groups" {
+			t.Errorf("expected truncated claims to be listed, got %v", truncated)
+		}
+	})
+
+	t.Run("moves low-priority claims to a reference", func(t *testing.T) {
+		store := tokenstore.NewMemoryStore(nil)
+		_, parsed, err := issue(t, &SizeBudget{
+			MaxClaimBytes:     1024,
+			LowPriorityClaims: []string{"tctx.groups"},
+			OnExceeded:        SizeBudgetReference,
+		}, &ClaimsReferences{Store: store, URL: "https://parsec.example.com/v1/claims/"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if claims := tctx(t, parsed); claims["groups"] != nil {
+			t.Errorf("expected groups to be moved, got %v", claims)
+		}
+		moved := retrieve(t, store, parsed)
+		if groups, _ := moved["tctx"].(map[string]any)["groups"].([]any); len(groups) != 200 {
+			t.Errorf("unexpected reference record: %v", moved)
+		}
+	})
+
+	t.Run("moves claims by reference without a budget", func(t *testing.T) {
+		store := tokenstore.NewMemoryStore(nil)
+		_, parsed, err := issue(t, nil, &ClaimsReferences{
+			Store:  store,
+			URL:    "https://parsec.example.com/v1/claims/",
+			Claims: []string{"tctx.groups", "req_ctx.missing"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if claims := tctx(t, parsed); claims["user"] != "alice" || claims["groups"] != nil {
+			t.Errorf("expected only groups to be moved, got %v", claims)
+		}
+		moved := retrieve(t, store, parsed)
+		if _, ok := moved["req_ctx"]; ok {
+			t.Errorf("expected only claims the token has to be moved, got %v", moved)
+		}
+	})
+
+	t.Run("fails to reference claims without a store", func(t *testing.T) {
+		_, _, err := issue(t, &SizeBudget{
+			MaxClaimBytes:     1024,
+			LowPriorityClaims: []string{"tctx.groups"},
+			OnExceeded:        SizeBudgetReference,
+		}, nil)
+		if err == nil {
+			t.Error("expected error without a claims reference store")
+		}
+	})
+
+	t.Run("fails for claims over the limit that are not low priority", func(t *testing.T) {
+		_, _, err := issue(t, &SizeBudget{
+			MaxClaimBytes:     1024,
+			LowPriorityClaims: []string{"tctx.user"},
+			OnExceeded:        SizeBudgetTruncate,
+		}, nil)
+		if !errors.Is(err, ErrTokenTooLarge) || !strings.Contains(err.Error(), "tctx.groups") {
+			t.Errorf("expected an error naming the claim, got %v", err)
+		}
+	})
+}
@@ -88,10 +88,15 @@ func (i *StubIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext)
 	tokenValue := fmt.Sprintf("stub-txn-token.%s.%s.%s", subject, txnID, string(requestContextJSON))
 
 	return &service.Token{
-		Value:     tokenValue,
-		Type:      "urn:ietf:params:oauth:token-type:txn_token",
-		ExpiresAt: expiresAt,
-		IssuedAt:  now,
+		Value:         tokenValue,
+		Type:          "urn:ietf:params:oauth:token-type:txn_token",
+		ExpiresAt:     expiresAt,
+		IssuedAt:      now,
+		TransactionID: txnID,
+		Claims: map[string]any{
+			"sub": subject,
+			"txn": txnID,
+		},
 	}, nil
 }
 
@@ -101,3 +106,13 @@ func (i *StubIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error
 	// Return empty slice for unsigned stub tokens
 	return []service.PublicKey{}, nil
 }
+
+// Describe implements service.DescribableIssuer
+func (i *StubIssuer) Describe() service.IssuerDescription {
+	return service.IssuerDescription{
+		IssuerURL: i.issuerURL,
+		Format:    service.TokenFormatStub,
+		MinTTL:    i.ttl,
+		MaxTTL:    i.ttl,
+	}
+}
@@ -0,0 +1,39 @@
+package issuer
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/request"
+)
+
+// TraceparentHeader is the W3C Trace Context header; a txn propagated from it is the trace ID
+const TraceparentHeader = "traceparent"
+
+// traceparentPattern matches a version 00 traceparent: version-traceid-parentid-flags
+var traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// maxPropagatedTxnIDLength bounds txn IDs taken from request headers, which clients control
+const maxPropagatedTxnIDLength = 128
+
+// propagatedTxnID returns the txn ID carried by header in attrs, or "" if there is none
+// A traceparent header yields its trace ID, unless the trace ID is invalid (all zeros).
+func propagatedTxnID(attrs *request.RequestAttributes, header string) string {
+	if attrs == nil || header == "" {
+		return ""
+	}
+	value := strings.TrimSpace(attrs.Headers.First(header))
+
+	if strings.EqualFold(header, TraceparentHeader) {
+		match := traceparentPattern.FindStringSubmatch(strings.ToLower(value))
+		if match == nil || strings.Trim(match[1], "0") == "" {
+			return ""
+		}
+		return match[1]
+	}
+
+	if len(value) > maxPropagatedTxnIDLength {
+		return ""
+	}
+	return value
+}
@@ -2,17 +2,21 @@ package issuer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 
+	"github.com/alechenninger/parsec/internal/claims"
 	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/idgen"
+	"github.com/alechenninger/parsec/internal/instance"
 	"github.com/alechenninger/parsec/internal/keys"
 	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
 )
 
 // TransactionTokenIssuerConfig is the configuration for creating a transaction token issuer
@@ -34,17 +38,83 @@ type TransactionTokenIssuerConfig struct {
 
 	// Clock is an optional clock for testing (defaults to system clock)
 	Clock clock.Clock
+
+	// IDGenerator is an optional generator for txn and jti claims
+	// (defaults to random UUIDs)
+	IDGenerator idgen.Generator
+
+	// TxnIDGenerator is an optional generator for the txn claim (defaults to IDGenerator)
+	TxnIDGenerator idgen.Generator
+
+	// NotBeforeSkew backdates the nbf claim, for consumers whose clocks run behind
+	// (zero: nbf equals iat)
+	NotBeforeSkew time.Duration
+
+	// TxnIDHeader, if set, propagates the txn claim from this request header, such as a
+	// request ID, so tokens correlate with logs and traces. For TraceparentHeader, the
+	// trace ID is used. Requests without the header get a generated txn.
+	TxnIDHeader string
+
+	// Instance, if set, is added to tokens as the InstanceClaim claim
+	// so tokens can be traced back to the replica and version that issued them
+	Instance *instance.Identity
+
+	// Format is the encoding of issued tokens: service.TokenFormatJWT (default)
+	// or service.TokenFormatCWT for constrained consumers
+	Format service.TokenFormat
+
+	// Encryption, if set, encrypts JWTs for some audiences to their public keys
+	// (JWT format only)
+	Encryption *TokenEncryption
+
+	// SizeBudget, if set, bounds the size of tokens and their context claims
+	SizeBudget *SizeBudget
+
+	// Redaction, if set, strips or hashes sensitive tctx and req_ctx claims before
+	// signing. Its claim paths start with "tctx." or "req_ctx.".
+	Redaction *Redaction
+
+	// Profiles, if set, shape the tctx and req_ctx claims of tokens for some audiences
+	Profiles []AudienceProfile
+
+	// DynamicTTL, if set, decides the lifetime of each token, within its bounds;
+	// TTL is used where its policy decides none
+	DynamicTTL *DynamicTTL
+
+	// ClaimsReferences, if set, keeps some context claims out of tokens, referenced
+	// by ClaimsReferenceClaim (required for SizeBudgetReference)
+	ClaimsReferences *ClaimsReferences
 }
 
+// InstanceClaim is the claim identifying the parsec instance that issued a token
+const InstanceClaim = "parsec_instance"
+
+// txnTokenJWTType is the typ header of transaction tokens in JWT format
+// Verifiers check it, so other JWTs signed with the same keys are not accepted as
+// transaction tokens.
+const txnTokenJWTType = "txntoken+jwt"
+
 // TransactionTokenIssuer issues signed transaction tokens per draft-ietf-oauth-transaction-tokens.
 // It uses a RotatingSigner for key rotation and signing operations.
 type TransactionTokenIssuer struct {
 	issuerURL                 string
 	ttl                       time.Duration
+	dynamicTTL                *DynamicTTL
 	signer                    keys.RotatingSigner
 	transactionContextMappers []service.ClaimMapper
 	requestContextMappers     []service.ClaimMapper
 	clock                     clock.Clock
+	idGenerator               idgen.Generator
+	txnIDGenerator            idgen.Generator
+	notBeforeSkew             time.Duration
+	txnIDHeader               string
+	instance                  *instance.Identity
+	format                    service.TokenFormat
+	encryption                *TokenEncryption
+	sizeBudget                *SizeBudget
+	redaction                 *Redaction
+	profiles                  []AudienceProfile
+	claimsReferences          *ClaimsReferences
 }
 
 // NewTransactionTokenIssuer creates a new transaction token issuer
@@ -54,37 +124,205 @@ func NewTransactionTokenIssuer(cfg TransactionTokenIssuerConfig) *TransactionTok
 		clk = clock.NewSystemClock()
 	}
 
+	idGenerator := cfg.IDGenerator
+	if idGenerator == nil {
+		idGenerator = idgen.NewUUIDGenerator()
+	}
+
+	txnIDGenerator := cfg.TxnIDGenerator
+	if txnIDGenerator == nil {
+		txnIDGenerator = idGenerator
+	}
+
+	format := cfg.Format
+	if format == "" {
+		format = service.TokenFormatJWT
+	}
+
 	return &TransactionTokenIssuer{
 		issuerURL:                 cfg.IssuerURL,
 		ttl:                       cfg.TTL,
+		dynamicTTL:                cfg.DynamicTTL,
 		signer:                    cfg.Signer,
 		transactionContextMappers: cfg.TransactionContextMappers,
 		requestContextMappers:     cfg.RequestContextMappers,
 		clock:                     clk,
+		idGenerator:               idGenerator,
+		txnIDGenerator:            txnIDGenerator,
+		notBeforeSkew:             cfg.NotBeforeSkew,
+		txnIDHeader:               cfg.TxnIDHeader,
+		instance:                  cfg.Instance,
+		format:                    format,
+		encryption:                cfg.Encryption,
+		sizeBudget:                cfg.SizeBudget,
+		redaction:                 cfg.Redaction,
+		profiles:                  cfg.Profiles,
+		claimsReferences:          cfg.ClaimsReferences,
 	}
 }
 
 // Issue implements the Issuer interface
-// Issues a signed JWT transaction token per draft-ietf-oauth-transaction-tokens
+// Issues a signed JWT transaction token per draft-ietf-oauth-transaction-tokens,
+// or a CWT with the same claims if the issuer's format is CWT.
+// JWTs for audiences with encryption recipients are wrapped in a JWE.
 func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	transactionContext, requestContext, redacted, err := i.contextClaims(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := i.dynamicTTL.ttlFor(ctx, issueCtx, i.ttl)
+	if err != nil {
+		return nil, err
+	}
+	now := i.clock.Now()
+	expiresAt := now.Add(ttl)
+
+	// Propagate or generate the transaction ID
+	txnID := i.txnID(issueCtx)
+	tokenID := i.idGenerator.NewID()
+
+	var shedder *claimShedder
+	if i.sizeBudget != nil || i.claimsReferences != nil {
+		shedder = newClaimShedder(i.sizeBudget, i.claimsReferences, map[string]claims.Claims{
+			"tctx":    transactionContext,
+			"req_ctx": requestContext,
+		})
+		if err := shedder.moveReferenced(); err != nil {
+			return nil, err
+		}
+		if err := shedder.enforceClaimLimit(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sign, shedding low-priority claims until the token fits its budget
+	var value string
+	var tokenClaims map[string]any
+	for {
+		token, err := i.newToken(issueCtx, transactionContext, requestContext, now, expiresAt, txnID, tokenID)
+		if err != nil {
+			return nil, err
+		}
+		if shedder != nil {
+			if err := shedder.setClaims(token, tokenID); err != nil {
+				return nil, err
+			}
+		}
+		value, tokenClaims, err = i.sign(ctx, token, issueCtx.Audiences)
+		if err != nil {
+			return nil, err
+		}
+		if shedder == nil || shedder.fits(value) {
+			break
+		}
+		if err := shedder.shedNext(len(value)); err != nil {
+			return nil, err
+		}
+	}
+	if shedder != nil {
+		if err := shedder.storeReference(ctx, tokenID, expiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return &service.Token{
+		Value:          value,
+		Type:           "urn:ietf:params:oauth:token-type:txn_token",
+		ExpiresAt:      expiresAt,
+		IssuedAt:       now,
+		ID:             tokenID,
+		TransactionID:  txnID,
+		Claims:         tokenClaims,
+		RedactedClaims: redacted,
+	}, nil
+}
+
+// contextClaims maps and redacts the tctx and req_ctx claims of the token for issueCtx,
+// also returning the paths of the redacted claims
+func (i *TransactionTokenIssuer) contextClaims(ctx context.Context, issueCtx *service.IssueContext) (claims.Claims, claims.Claims, []string, error) {
+	transactionContextMappers, requestContextMappers, redaction := i.transactionContextMappers, i.requestContextMappers, i.redaction
+	if profile := profileFor(i.profiles, issueCtx.Audiences); profile != nil {
+		transactionContextMappers = profile.TransactionContextMappers
+		requestContextMappers = profile.RequestContextMappers
+		redaction = profile.redactionOr(i.redaction)
+	}
+
 	// Apply transaction context mappers
-	transactionContext, err := issueCtx.ToClaims(ctx, i.transactionContextMappers)
+	transactionContext, err := issueCtx.ToClaims(ctx, transactionContextMappers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to map transaction context: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to map transaction context: %w", err)
 	}
 
 	// Apply request context mappers
-	requestContext, err := issueCtx.ToClaims(ctx, i.requestContextMappers)
+	requestContext, err := issueCtx.ToClaims(ctx, requestContextMappers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to map request context: %w", err)
+		return nil, nil, nil, fmt.Errorf("failed to map request context: %w", err)
 	}
 
+	// Redact sensitive claims before they are signed or stored by reference
+	transactionContext, redacted, err := redaction.Redact("tctx.", transactionContext)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to redact transaction context: %w", err)
+	}
+	requestContext, redactedRequest, err := redaction.Redact("req_ctx.", requestContext)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to redact request context: %w", err)
+	}
+	redacted = append(redacted, redactedRequest...)
+
+	return transactionContext, requestContext, redacted, nil
+}
+
+// txnID returns the transaction ID propagated with issueCtx's request, or a new one
+func (i *TransactionTokenIssuer) txnID(issueCtx *service.IssueContext) string {
+	if txnID := propagatedTxnID(issueCtx.RequestAttributes, i.txnIDHeader); txnID != "" {
+		return txnID
+	}
+	return i.txnIDGenerator.NewID()
+}
+
+// Preview implements service.PreviewingIssuer
+// Its claims are the token's before any are shed to fit the size budget or moved to a
+// claims reference, and nothing is stored by reference.
+func (i *TransactionTokenIssuer) Preview(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	transactionContext, requestContext, redacted, err := i.contextClaims(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := i.dynamicTTL.ttlFor(ctx, issueCtx, i.ttl)
+	if err != nil {
+		return nil, err
+	}
 	now := i.clock.Now()
-	expiresAt := now.Add(i.ttl)
+	expiresAt := now.Add(ttl)
+	txnID := i.txnID(issueCtx)
+	tokenID := i.idGenerator.NewID()
 
-	// Generate UUIDv7 for transaction ID (provides temporal ordering)
-	txnID := uuid.NewString()
+	token, err := i.newToken(issueCtx, transactionContext, requestContext, now, expiresAt, txnID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	tokenClaims, err := claimsOf(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &service.Token{
+		Type:           "urn:ietf:params:oauth:token-type:txn_token",
+		ExpiresAt:      expiresAt,
+		IssuedAt:       now,
+		ID:             tokenID,
+		TransactionID:  txnID,
+		Claims:         tokenClaims,
+		RedactedClaims: redacted,
+	}, nil
+}
 
+// newToken builds the claims of a transaction token
+func (i *TransactionTokenIssuer) newToken(issueCtx *service.IssueContext, transactionContext, requestContext claims.Claims,
+	now, expiresAt time.Time, txnID, tokenID string) (jwt.Token, error) {
 	// Build JWT token per draft-ietf-oauth-transaction-tokens
 	token := jwt.New()
 
@@ -95,7 +333,7 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 	if err := token.Set(jwt.SubjectKey, issueCtx.Subject.Subject); err != nil {
 		return nil, fmt.Errorf("failed to set subject: %w", err)
 	}
-	if err := token.Set(jwt.AudienceKey, []string{issueCtx.Audience}); err != nil {
+	if err := token.Set(jwt.AudienceKey, issueCtx.Audiences); err != nil {
 		return nil, fmt.Errorf("failed to set audience: %w", err)
 	}
 	if err := token.Set(jwt.IssuedAtKey, now.Unix()); err != nil {
@@ -104,10 +342,10 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 	if err := token.Set(jwt.ExpirationKey, expiresAt.Unix()); err != nil {
 		return nil, fmt.Errorf("failed to set expiration: %w", err)
 	}
-	if err := token.Set(jwt.NotBeforeKey, now.Unix()); err != nil {
+	if err := token.Set(jwt.NotBeforeKey, now.Add(-i.notBeforeSkew).Unix()); err != nil {
 		return nil, fmt.Errorf("failed to set not before: %w", err)
 	}
-	if err := token.Set(jwt.JwtIDKey, uuid.NewString()); err != nil {
+	if err := token.Set(jwt.JwtIDKey, tokenID); err != nil {
 		return nil, fmt.Errorf("failed to set JWT ID: %w", err)
 	}
 
@@ -130,6 +368,28 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 		}
 	}
 
+	// Requesting workload (req_wl) - the workload that requested the token, if authenticated
+	if issueCtx.Workload != nil && issueCtx.Workload.Subject != "" {
+		if err := token.Set("req_wl", issueCtx.Workload.Subject); err != nil {
+			return nil, fmt.Errorf("failed to set requesting workload: %w", err)
+		}
+	}
+
+	// Actor (act) - the delegation chain of actors acting on behalf of the subject
+	if issueCtx.Delegation != nil {
+		if err := token.Set(trust.ActClaim, issueCtx.Delegation.Claim()); err != nil {
+			return nil, fmt.Errorf("failed to set actor: %w", err)
+		}
+	}
+
+	// Confirmation (cnf) - the client certificate the token is bound to (RFC 8705)
+	if issueCtx.CertificateThumbprint != "" {
+		confirmation := map[string]any{trust.X509ThumbprintConfirmation: issueCtx.CertificateThumbprint}
+		if err := token.Set(trust.ConfirmationClaim, confirmation); err != nil {
+			return nil, fmt.Errorf("failed to set confirmation: %w", err)
+		}
+	}
+
 	// Scope (if provided)
 	if issueCtx.Scope != "" {
 		if err := token.Set("scope", issueCtx.Scope); err != nil {
@@ -137,31 +397,84 @@ func (i *TransactionTokenIssuer) Issue(ctx context.Context, issueCtx *service.Is
 		}
 	}
 
+	// Client ID (RFC 8693 section 4.3) - the client the token was issued to, if known
+	if issueCtx.ClientID != "" {
+		if err := token.Set("client_id", issueCtx.ClientID); err != nil {
+			return nil, fmt.Errorf("failed to set client_id: %w", err)
+		}
+	}
+
+	if i.instance != nil {
+		if err := token.Set(InstanceClaim, i.instance.Claims()); err != nil {
+			return nil, fmt.Errorf("failed to set instance: %w", err)
+		}
+	}
+
+	return token, nil
+}
+
+// sign encodes and signs token, returning the token and its claims as they appear in it
+func (i *TransactionTokenIssuer) sign(ctx context.Context, token jwt.Token, audiences []string) (string, map[string]any, error) {
+	tokenClaims, err := claimsOf(token)
+	if err != nil {
+		return "", nil, err
+	}
+
 	// Get the current signer, key ID, and algorithm from the signer
 	signer, keyID, algorithm, err := i.signer.GetCurrentSigner(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current signer: %w", err)
+		return "", nil, fmt.Errorf("failed to get current signer: %w", err)
 	}
 
-	// Build JWS headers with the key ID
-	headers := jws.NewHeaders()
-	if err := headers.Set(jws.KeyIDKey, string(keyID)); err != nil {
-		return nil, fmt.Errorf("failed to set key ID header: %w", err)
+	var value string
+	if i.format == service.TokenFormatCWT {
+		cwtClaims, err := token.AsMap(ctx)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get claims: %w", err)
+		}
+		value, err = signCWT(cwtClaims, signer, keyID, algorithm)
+		if err != nil {
+			return "", nil, err
+		}
+	} else {
+		// Build JWS headers with the key ID
+		headers := jws.NewHeaders()
+		if err := headers.Set(jws.KeyIDKey, string(keyID)); err != nil {
+			return "", nil, fmt.Errorf("failed to set key ID header: %w", err)
+		}
+		if err := headers.Set(jws.TypeKey, txnTokenJWTType); err != nil {
+			return "", nil, fmt.Errorf("failed to set type header: %w", err)
+		}
+
+		// Sign the token with the current key
+		signedToken, err := jwt.Sign(token,
+			jwt.WithKey(jwa.SignatureAlgorithm(string(algorithm)), signer, jws.WithProtectedHeaders(headers)))
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to sign token: %w", err)
+		}
+		if i.encryption != nil {
+			signedToken, err = i.encryption.encrypt(ctx, signedToken, audiences)
+			if err != nil {
+				return "", nil, err
+			}
+		}
+		value = string(signedToken)
 	}
 
-	// Sign the token with the current key
-	signedToken, err := jwt.Sign(token,
-		jwt.WithKey(jwa.SignatureAlgorithm(string(algorithm)), signer, jws.WithProtectedHeaders(headers)))
+	return value, tokenClaims, nil
+}
+
+// claimsOf returns the claims of token as they appear in it, round-tripped through JSON
+func claimsOf(token jwt.Token) (map[string]any, error) {
+	claimsJSON, err := json.Marshal(token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign token: %w", err)
+		return nil, fmt.Errorf("failed to encode claims: %w", err)
 	}
-
-	return &service.Token{
-		Value:     string(signedToken),
-		Type:      "urn:ietf:params:oauth:token-type:txn_token",
-		ExpiresAt: expiresAt,
-		IssuedAt:  now,
-	}, nil
+	var tokenClaims map[string]any
+	if err := json.Unmarshal(claimsJSON, &tokenClaims); err != nil {
+		return nil, fmt.Errorf("failed to decode claims: %w", err)
+	}
+	return tokenClaims, nil
 }
 
 // PublicKeys implements the Issuer interface
@@ -170,3 +483,61 @@ func (i *TransactionTokenIssuer) PublicKeys(ctx context.Context) ([]service.Publ
 	// Get all public keys from the rotating signer (already in service.PublicKey format)
 	return i.signer.PublicKeys(ctx)
 }
+
+// RotateKey implements service.KeyRotatingIssuer
+func (i *TransactionTokenIssuer) RotateKey(ctx context.Context) error {
+	rotator, ok := i.signer.(keys.ManualRotator)
+	if !ok {
+		return service.ErrKeyRotationNotSupported
+	}
+	return rotator.RotateNow(ctx)
+}
+
+// RevokeKey implements service.KeyRevokingIssuer
+func (i *TransactionTokenIssuer) RevokeKey(ctx context.Context, keyID string) error {
+	revoker, ok := i.signer.(keys.KeyRevoker)
+	if !ok {
+		return service.ErrKeyRevocationNotSupported
+	}
+	return revoker.RevokeKey(ctx, keys.KeyID(keyID))
+}
+
+// SigningStatus implements service.SigningStatusIssuer
+func (i *TransactionTokenIssuer) SigningStatus(ctx context.Context) (service.SigningStatus, error) {
+	_, keyID, algorithm, err := i.signer.GetCurrentSigner(ctx)
+	if err != nil {
+		return service.SigningStatus{}, fmt.Errorf("failed to get current signer: %w", err)
+	}
+
+	status := service.SigningStatus{
+		KeyID:     string(keyID),
+		Algorithm: string(algorithm),
+	}
+
+	if migrator, ok := i.signer.(keys.AlgorithmMigrator); ok {
+		migration, err := migrator.MigrationStatus(ctx)
+		if err != nil {
+			return service.SigningStatus{}, fmt.Errorf("failed to get algorithm migration status: %w", err)
+		}
+		status.Migration = &service.AlgorithmMigrationStatus{
+			Phase:         string(migration.Phase),
+			FromAlgorithm: string(migration.FromAlgorithm),
+			ToAlgorithm:   string(migration.ToAlgorithm),
+			CutoverAt:     migration.CutoverAt,
+			RetireAt:      migration.RetireAt,
+		}
+	}
+
+	return status, nil
+}
+
+// Describe implements service.DescribableIssuer
+func (i *TransactionTokenIssuer) Describe() service.IssuerDescription {
+	minTTL, maxTTL := i.dynamicTTL.bounds(i.ttl)
+	return service.IssuerDescription{
+		IssuerURL: i.issuerURL,
+		Format:    i.format,
+		MinTTL:    minTTL,
+		MaxTTL:    maxTTL,
+	}
+}
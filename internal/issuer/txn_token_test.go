@@ -0,0 +1,471 @@
+package issuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/idgen"
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestTransactionTokenIssuer_InstanceClaim(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	issueCtx := &service.IssueContext{
+		Subject:            &trust.Result{Subject: "user@example.com"},
+		Audiences:          []string{"example.com"},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	}
+
+	issue := func(t *testing.T, identity *instance.Identity) jwt.Token {
+		t.Helper()
+		issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+			IssuerURL: "https://parsec.example.com",
+			TTL:       5 * time.Minute,
+			Signer:    signer,
+			Instance:  identity,
+		})
+		token, err := issuer.Issue(ctx, issueCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := jwt.ParseInsecure([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		return parsed
+	}
+
+	t.Run("included when configured", func(t *testing.T) {
+		token := issue(t, &instance.Identity{ID: "parsec-0", Version: "v1.2.3"})
+
+		value, ok := token.Get(InstanceClaim)
+		if !ok {
+			t.Fatalf("expected %s claim", InstanceClaim)
+		}
+		claim, ok := value.(map[string]any)
+		if !ok {
+			t.Fatalf("expected %s to be an object, got %T", InstanceClaim, value)
+		}
+		if claim["id"] != "parsec-0" || claim["version"] != "v1.2.3" {
+			t.Errorf("unexpected instance claim: %v", claim)
+		}
+	})
+
+	t.Run("omitted by default", func(t *testing.T) {
+		token := issue(t, nil)
+
+		if _, ok := token.Get(InstanceClaim); ok {
+			t.Errorf("expected no %s claim", InstanceClaim)
+		}
+	})
+}
+
+func TestTransactionTokenIssuer_TypeHeader(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	})
+	token, err := issuer.Issue(ctx, &service.IssueContext{
+		Subject:            &trust.Result{Subject: "user@example.com"},
+		Audiences:          []string{"example.com"},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg, err := jws.Parse([]byte(token.Value))
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	if typ := msg.Signatures()[0].ProtectedHeaders().Type(); typ != "txntoken+jwt" {
+		t.Errorf("expected typ txntoken+jwt, got %q", typ)
+	}
+}
+
+func TestTransactionTokenIssuer_PSSSigning(t *testing.T) {
+	ctx := context.Background()
+
+	for _, alg := range []string{"PS256", "PS384", "PS512"} {
+		t.Run(alg, func(t *testing.T) {
+			signer := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+				Namespace:     "urn:ietf:params:oauth:token-type:txn_token",
+				KeyProviderID: "rsa",
+				KeyProviderRegistry: map[string]keys.KeyProvider{
+					"rsa": keys.NewInMemoryKeyProvider(keys.KeyTypeRSA2048, alg),
+				},
+				SlotStore: keys.NewInMemoryKeySlotStore(),
+			})
+			if err := signer.Start(ctx); err != nil {
+				t.Fatalf("failed to start signer: %v", err)
+			}
+			defer signer.Stop()
+
+			issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+				IssuerURL: "https://parsec.example.com",
+				TTL:       5 * time.Minute,
+				Signer:    signer,
+			})
+			token, err := issuer.Issue(ctx, &service.IssueContext{
+				Subject:            &trust.Result{Subject: "user@example.com"},
+				Audiences:          []string{"example.com"},
+				DataSourceRegistry: service.NewDataSourceRegistry(),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			publicKeys, err := issuer.PublicKeys(ctx)
+			if err != nil {
+				t.Fatalf("failed to get public keys: %v", err)
+			}
+			set := jwk.NewSet()
+			for _, pk := range publicKeys {
+				if pk.Algorithm != alg {
+					t.Errorf("expected published algorithm %s, got %s", alg, pk.Algorithm)
+				}
+				key, err := jwk.FromRaw(pk.Key)
+				if err != nil {
+					t.Fatalf("failed to create JWK: %v", err)
+				}
+				_ = key.Set(jwk.KeyIDKey, pk.KeyID)
+				_ = key.Set(jwk.AlgorithmKey, jwa.SignatureAlgorithm(pk.Algorithm))
+				_ = set.AddKey(key)
+			}
+
+			if _, err := jwt.Parse([]byte(token.Value), jwt.WithKeySet(set)); err != nil {
+				t.Errorf("failed to verify %s token: %v", alg, err)
+			}
+		})
+	}
+}
+
+func TestTransactionTokenIssuer_CertificateBinding(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	})
+
+	issue := func(t *testing.T, thumbprint string) jwt.Token {
+		t.Helper()
+		token, err := issuer.Issue(ctx, &service.IssueContext{
+			Subject:               &trust.Result{Subject: "user@example.com"},
+			Audiences:             []string{"example.com"},
+			CertificateThumbprint: thumbprint,
+			DataSourceRegistry:    service.NewDataSourceRegistry(),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := jwt.ParseInsecure([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		return parsed
+	}
+
+	t.Run("bound to the certificate thumbprint", func(t *testing.T) {
+		thumbprint := trust.CertificateThumbprint([]byte("certificate"))
+		token := issue(t, thumbprint)
+
+		value, ok := token.Get(trust.ConfirmationClaim)
+		if !ok {
+			t.Fatalf("expected %s claim", trust.ConfirmationClaim)
+		}
+		cnf, ok := value.(map[string]any)
+		if !ok {
+			t.Fatalf("expected %s to be an object, got %T", trust.ConfirmationClaim, value)
+		}
+		if cnf["x5t#S256"] != thumbprint {
+			t.Errorf("expected x5t#S256 %s, got %v", thumbprint, cnf["x5t#S256"])
+		}
+	})
+
+	t.Run("bearer by default", func(t *testing.T) {
+		token := issue(t, "")
+
+		if _, ok := token.Get(trust.ConfirmationClaim); ok {
+			t.Errorf("expected no %s claim", trust.ConfirmationClaim)
+		}
+	})
+}
+
+func TestTransactionTokenIssuer_ClientID(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	})
+
+	issue := func(t *testing.T, clientID string) jwt.Token {
+		t.Helper()
+		token, err := issuer.Issue(ctx, &service.IssueContext{
+			Subject: &trust.Result{Subject: "user@example.com"},
+			// Request attributes may be set by callers, so never name the client
+			RequestAttributes:  &request.RequestAttributes{Additional: map[string]any{"client_id": "spoofed"}},
+			Audiences:          []string{"example.com"},
+			ClientID:           clientID,
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsed, err := jwt.ParseInsecure([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		return parsed
+	}
+
+	t.Run("the authenticated client", func(t *testing.T) {
+		if value, _ := issue(t, "orders").Get("client_id"); value != "orders" {
+			t.Errorf("expected client_id orders, got %v", value)
+		}
+	})
+
+	t.Run("none without an authenticated client", func(t *testing.T) {
+		if value, ok := issue(t, "").Get("client_id"); ok {
+			t.Errorf("expected no client_id, got %v", value)
+		}
+	})
+}
+
+func TestTransactionTokenIssuer_TxnID(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	generated := idgen.NewFixtureGenerator("txn").NewID()
+
+	tests := []struct {
+		name    string
+		header  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "generated by default",
+			headers: map[string]string{"x-request-id": "req-123"},
+			want:    generated,
+		},
+		{
+			name:    "propagated from header",
+			header:  "X-Request-Id",
+			headers: map[string]string{"x-request-id": "req-123"},
+			want:    "req-123",
+		},
+		{
+			name:   "generated without header",
+			header: "x-request-id",
+			want:   generated,
+		},
+		{
+			name:    "trace ID from traceparent",
+			header:  TraceparentHeader,
+			headers: map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			want:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:    "generated for invalid traceparent",
+			header:  TraceparentHeader,
+			headers: map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+			want:    generated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+				IssuerURL:      "https://parsec.example.com",
+				TTL:            5 * time.Minute,
+				Signer:         signer,
+				TxnIDGenerator: idgen.NewFixtureGenerator("txn"),
+				TxnIDHeader:    tt.header,
+			})
+			token, err := issuer.Issue(ctx, &service.IssueContext{
+				Subject:            &trust.Result{Subject: "user@example.com"},
+				Audiences:          []string{"example.com"},
+				RequestAttributes:  &request.RequestAttributes{Headers: request.NewHeaders(tt.headers)},
+				DataSourceRegistry: service.NewDataSourceRegistry(),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			parsed, err := jwt.ParseInsecure([]byte(token.Value))
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+			txn, _ := parsed.Get("txn")
+			if txn != tt.want {
+				t.Errorf("expected txn %s, got %v", tt.want, txn)
+			}
+			if token.TransactionID != tt.want {
+				t.Errorf("expected TransactionID %s, got %s", tt.want, token.TransactionID)
+			}
+			if token.Claims["txn"] != tt.want || token.Claims["jti"] != parsed.JwtID() {
+				t.Errorf("expected Claims to match the token's claims, got %v", token.Claims)
+			}
+			if parsed.JwtID() == tt.want {
+				t.Error("expected jti to differ from txn")
+			}
+		})
+	}
+}
+
+func TestTransactionTokenIssuer_NotBeforeSkew(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL:      "https://parsec.example.com",
+		TTL:            5 * time.Minute,
+		Signer:         signer,
+		Clock:          clk,
+		IDGenerator:    idgen.NewPrefixedGenerator("instance-1.", idgen.NewFixtureGenerator("jti")),
+		TxnIDGenerator: idgen.NewFixtureGenerator("txn"),
+		NotBeforeSkew:  30 * time.Second,
+	})
+	token, err := issuer.Issue(ctx, &service.IssueContext{
+		Subject:            &trust.Result{Subject: "user@example.com"},
+		Audiences:          []string{"example.com"},
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parsed, err := jwt.ParseInsecure([]byte(token.Value))
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	if want := clk.Now().Add(-30 * time.Second); !parsed.NotBefore().Equal(want) {
+		t.Errorf("expected nbf %s, got %s", want, parsed.NotBefore())
+	}
+	if !parsed.IssuedAt().Equal(clk.Now()) {
+		t.Errorf("expected iat %s, got %s", clk.Now(), parsed.IssuedAt())
+	}
+	if want := "instance-1." + idgen.NewFixtureGenerator("jti").NewID(); parsed.JwtID() != want || token.ID != want {
+		t.Errorf("expected jti %s, got %s (token ID %s)", want, parsed.JwtID(), token.ID)
+	}
+}
+
+func TestTransactionTokenIssuer_Preview(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	issuer := NewTransactionTokenIssuer(TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+		Clock:     clk,
+		TransactionContextMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{
+			"department": "engineering",
+		})},
+	})
+	token, err := issuer.Preview(ctx, &service.IssueContext{
+		Subject:            &trust.Result{Subject: "user@example.com"},
+		Audiences:          []string{"example.com"},
+		Scope:              "orders:read",
+		DataSourceRegistry: service.NewDataSourceRegistry(),
+	})
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	if token.Value != "" {
+		t.Errorf("expected no token value, got %s", token.Value)
+	}
+	if token.Claims["sub"] != "user@example.com" || token.Claims["scope"] != "orders:read" || token.Claims["jti"] != token.ID {
+		t.Errorf("expected the token's claims, got %v", token.Claims)
+	}
+	if tctx, _ := token.Claims["tctx"].(map[string]any); tctx["department"] != "engineering" {
+		t.Errorf("expected mapped tctx, got %v", token.Claims["tctx"])
+	}
+	if want := clk.Now().Add(5 * time.Minute); !token.ExpiresAt.Equal(want) {
+		t.Errorf("expected expiry %s, got %s", want, token.ExpiresAt)
+	}
+}
@@ -21,6 +21,12 @@ type UnsignedIssuerConfig struct {
 	// ClaimMappers are the mappers to apply to generate claims
 	ClaimMappers []service.ClaimMapper
 
+	// Redaction, if set, strips or hashes sensitive mapped claims before issuance
+	Redaction *Redaction
+
+	// Profiles, if set, shape the claims of tokens for some audiences
+	Profiles []AudienceProfile
+
 	// Clock is the time source for token timestamps
 	// If nil, uses system clock
 	Clock clock.Clock
@@ -31,6 +37,8 @@ type UnsignedIssuerConfig struct {
 type UnsignedIssuer struct {
 	tokenType    string
 	claimMappers []service.ClaimMapper
+	redaction    *Redaction
+	profiles     []AudienceProfile
 	clock        clock.Clock
 }
 
@@ -44,6 +52,8 @@ func NewUnsignedIssuer(cfg UnsignedIssuerConfig) *UnsignedIssuer {
 	return &UnsignedIssuer{
 		tokenType:    cfg.TokenType,
 		claimMappers: cfg.ClaimMappers,
+		redaction:    cfg.Redaction,
+		profiles:     cfg.Profiles,
 		clock:        clk,
 	}
 }
@@ -52,10 +62,19 @@ func NewUnsignedIssuer(cfg UnsignedIssuerConfig) *UnsignedIssuer {
 // Returns a token containing base64-encoded JSON of the mapped claims
 func (i *UnsignedIssuer) Issue(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
 	// Apply claim mappers
-	mappedClaims, err := issueCtx.ToClaims(ctx, i.claimMappers)
+	claimMappers, redaction := i.claimMappers, i.redaction
+	if profile := profileFor(i.profiles, issueCtx.Audiences); profile != nil {
+		claimMappers, redaction = profile.ClaimMappers, profile.redactionOr(i.redaction)
+	}
+
+	mappedClaims, err := issueCtx.ToClaims(ctx, claimMappers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to map claims: %w", err)
 	}
+	mappedClaims, redacted, err := redaction.Redact("", mappedClaims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redact claims: %w", err)
+	}
 
 	// Serialize mapped claims to JSON
 	claimsJSON, err := json.Marshal(mappedClaims)
@@ -70,15 +89,49 @@ func (i *UnsignedIssuer) Issue(ctx context.Context, issueCtx *service.IssueConte
 	neverExpires := never
 
 	return &service.Token{
-		Value:     encodedToken,
-		Type:      i.tokenType,
-		ExpiresAt: neverExpires,
-		IssuedAt:  i.clock.Now(),
+		Value:          encodedToken,
+		Type:           i.tokenType,
+		ExpiresAt:      neverExpires,
+		IssuedAt:       i.clock.Now(),
+		RedactedClaims: redacted,
 	}, nil
 }
 
+// Preview implements service.PreviewingIssuer
+// Issuing unsigned tokens has no side effects, so the preview is an issued token's
+// claims, without its value.
+func (i *UnsignedIssuer) Preview(ctx context.Context, issueCtx *service.IssueContext) (*service.Token, error) {
+	token, err := i.Issue(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+	return previewBase64JSON(token)
+}
+
+// previewBase64JSON returns token, a base64-encoded JSON object, with its claims
+// decoded and without its value
+func previewBase64JSON(token *service.Token) (*service.Token, error) {
+	claimsJSON, err := base64.StdEncoding.DecodeString(token.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token: %w", err)
+	}
+	if err := json.Unmarshal(claimsJSON, &token.Claims); err != nil {
+		return nil, fmt.Errorf("failed to decode claims: %w", err)
+	}
+	token.Value = ""
+	return token, nil
+}
+
 // PublicKeys implements the Issuer interface
 // Unsigned issuer returns an empty slice since tokens are not signed
 func (i *UnsignedIssuer) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
 	return []service.PublicKey{}, nil
 }
+
+// Describe implements service.DescribableIssuer
+// Unsigned tokens carry no issuer and never expire
+func (i *UnsignedIssuer) Describe() service.IssuerDescription {
+	return service.IssuerDescription{
+		Format: service.TokenFormatBase64JSON,
+	}
+}
@@ -31,7 +31,7 @@ func TestUnsignedIssuer_Issue(t *testing.T) {
 		Subject: &trust.Result{
 			Subject: "test-subject",
 		},
-		Audience:           "test-audience",
+		Audiences:          []string{"test-audience"},
 		Scope:              "test-scope",
 		DataSourceRegistry: service.NewDataSourceRegistry(),
 	}
@@ -111,7 +111,7 @@ func TestUnsignedIssuer_Issue_EmptyTransactionContext(t *testing.T) {
 		Subject: &trust.Result{
 			Subject: "test-subject",
 		},
-		Audience:           "test-audience",
+		Audiences:          []string{"test-audience"},
 		DataSourceRegistry: service.NewDataSourceRegistry(),
 	}
 
@@ -150,7 +150,7 @@ func TestUnsignedIssuer_Issue_NilTransactionContext(t *testing.T) {
 		Subject: &trust.Result{
 			Subject: "test-subject",
 		},
-		Audience:           "test-audience",
+		Audiences:          []string{"test-audience"},
 		DataSourceRegistry: service.NewDataSourceRegistry(),
 	}
 
@@ -0,0 +1,53 @@
+// Package jwkspub publishes parsec's JWKS document to external locations.
+//
+// Verifiers often fetch keys from a CDN or object store rather than from parsec
+// directly. Publishers push the current JWKS document there every time the key set
+// changes, so new keys reach verifiers during the signer's grace period (the window
+// between a key being published and being used to sign).
+//
+// Each publisher replaces the whole document in a single write, so readers always
+// see either the previous or the new key set, never a partial one. Documents are
+// written with Cache-Control metadata; keep max-age well below the signer's grace
+// period so CDN caches expire before a new key starts signing.
+package jwkspub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultCacheControl is the Cache-Control metadata written with published documents.
+// Five minutes is well within the default two hour rotation grace period.
+const DefaultCacheControl = "public, max-age=300"
+
+// ContentType is the media type of published JWKS documents (RFC 7517 section 8.5.1)
+const ContentType = "application/jwk-set+json"
+
+// Publisher writes a JWKS document to an external location
+type Publisher interface {
+	// Publish atomically replaces the published document with doc
+	Publish(ctx context.Context, doc []byte) error
+}
+
+// MultiPublisher publishes to several publishers
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+// NewMultiPublisher creates a publisher that publishes to all of the given publishers
+func NewMultiPublisher(publishers ...Publisher) *MultiPublisher {
+	return &MultiPublisher{publishers: publishers}
+}
+
+// Publish publishes to every publisher, even if some fail.
+// Returns the joined errors of all publishers that failed.
+func (m *MultiPublisher) Publish(ctx context.Context, doc []byte) error {
+	var errs []error
+	for i, p := range m.publishers {
+		if err := p.Publish(ctx, doc); err != nil {
+			errs = append(errs, fmt.Errorf("publisher %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,137 @@
+package jwkspub
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type fakeS3 struct {
+	input *s3.PutObjectInput
+	body  []byte
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.input = params
+	f.body, _ = io.ReadAll(params.Body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3Publisher_Publish(t *testing.T) {
+	client := &fakeS3{}
+	p, err := NewS3Publisher(context.Background(), S3PublisherConfig{
+		Bucket: "keys",
+		Key:    ".well-known/jwks.json",
+		Client: client,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Publisher failed: %v", err)
+	}
+
+	if err := p.Publish(context.Background(), []byte(`{"keys":[]}`)); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	if *client.input.Bucket != "keys" || *client.input.Key != ".well-known/jwks.json" {
+		t.Errorf("unexpected destination s3://%s/%s", *client.input.Bucket, *client.input.Key)
+	}
+	if *client.input.CacheControl != DefaultCacheControl {
+		t.Errorf("expected default cache control, got %q", *client.input.CacheControl)
+	}
+	if *client.input.ContentType != ContentType {
+		t.Errorf("expected content type %q, got %q", ContentType, *client.input.ContentType)
+	}
+	if string(client.body) != `{"keys":[]}` {
+		t.Errorf("unexpected body %s", client.body)
+	}
+}
+
+func TestS3Publisher_RequiresBucketAndKey(t *testing.T) {
+	if _, err := NewS3Publisher(context.Background(), S3PublisherConfig{Key: "k", Client: &fakeS3{}}); err == nil {
+		t.Error("expected error for missing bucket")
+	}
+	if _, err := NewS3Publisher(context.Background(), S3PublisherConfig{Bucket: "b", Client: &fakeS3{}}); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
+
+func TestWebhookPublisher_Publish(t *testing.T) {
+	var gotMethod, gotCacheControl, gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotCacheControl = r.Header.Get("Cache-Control")
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	p, err := NewWebhookPublisher(WebhookPublisherConfig{
+		URL:          srv.URL,
+		Headers:      map[string]string{"Authorization": "Bearer secret"},
+		CacheControl: "public, max-age=60",
+	})
+	if err != nil {
+		t.Fatalf("NewWebhookPublisher failed: %v", err)
+	}
+
+	if err := p.Publish(context.Background(), []byte(`{"keys":[]}`)); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	if gotMethod != http.MethodPut {
+		t.Errorf("expected PUT, got %s", gotMethod)
+	}
+	if gotCacheControl != "public, max-age=60" {
+		t.Errorf("unexpected cache control %q", gotCacheControl)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("expected configured headers to be sent, got %q", gotAuth)
+	}
+	if gotBody != `{"keys":[]}` {
+		t.Errorf("unexpected body %s", gotBody)
+	}
+}
+
+func TestWebhookPublisher_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	p, _ := NewWebhookPublisher(WebhookPublisherConfig{URL: srv.URL})
+	if err := p.Publish(context.Background(), []byte(`{}`)); err == nil {
+		t.Error("expected error for non-2xx status")
+	}
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, doc []byte) error {
+	return errors.New("boom")
+}
+
+type countingPublisher struct{ count int }
+
+func (c *countingPublisher) Publish(ctx context.Context, doc []byte) error {
+	c.count++
+	return nil
+}
+
+func TestMultiPublisher_PublishesToAll(t *testing.T) {
+	counter := &countingPublisher{}
+	m := NewMultiPublisher(failingPublisher{}, counter)
+
+	if err := m.Publish(context.Background(), []byte(`{}`)); err == nil {
+		t.Error("expected error from failing publisher")
+	}
+	if counter.count != 1 {
+		t.Errorf("expected remaining publishers to run despite failure, got %d", counter.count)
+	}
+}
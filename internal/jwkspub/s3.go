@@ -0,0 +1,106 @@
+package jwkspub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// GCSEndpoint is the S3-compatible XML API endpoint of Google Cloud Storage.
+// Publishing to GCS uses the S3 publisher with HMAC keys for a GCS service account.
+const GCSEndpoint = "https://storage.googleapis.com"
+
+// S3PutObjectAPI is the subset of the S3 client used by S3Publisher
+type S3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3Publisher publishes the JWKS document as an object in an S3 bucket,
+// or any S3-compatible store such as GCS or MinIO.
+// S3 PutObject replaces objects atomically.
+type S3Publisher struct {
+	client       S3PutObjectAPI
+	bucket       string
+	key          string
+	cacheControl string
+}
+
+// S3PublisherConfig configures an S3 publisher
+type S3PublisherConfig struct {
+	// Bucket is the destination bucket
+	Bucket string
+
+	// Key is the object key (e.g., ".well-known/jwks.json")
+	Key string
+
+	// Region is the bucket region
+	Region string
+
+	// Endpoint optionally overrides the S3 endpoint (e.g., GCSEndpoint)
+	Endpoint string
+
+	// UsePathStyle addresses the bucket in the path rather than the host name
+	UsePathStyle bool
+
+	// CacheControl is the Cache-Control metadata (default: DefaultCacheControl)
+	CacheControl string
+
+	// Client is an optional preconfigured client
+	// If nil, a client is created from the default AWS credential chain
+	Client S3PutObjectAPI
+}
+
+// NewS3Publisher creates a new S3 publisher
+func NewS3Publisher(ctx context.Context, cfg S3PublisherConfig) (*S3Publisher, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required")
+	}
+	if cfg.Key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+
+	cacheControl := cfg.CacheControl
+	if cacheControl == "" {
+		cacheControl = DefaultCacheControl
+	}
+
+	client := cfg.Client
+	if client == nil {
+		awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		client = s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+			if cfg.Endpoint != "" {
+				o.BaseEndpoint = aws.String(cfg.Endpoint)
+			}
+			o.UsePathStyle = cfg.UsePathStyle
+		})
+	}
+
+	return &S3Publisher{
+		client:       client,
+		bucket:       cfg.Bucket,
+		key:          cfg.Key,
+		cacheControl: cacheControl,
+	}, nil
+}
+
+// Publish writes the document to the configured object
+func (p *S3Publisher) Publish(ctx context.Context, doc []byte) error {
+	_, err := p.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String(p.bucket),
+		Key:          aws.String(p.key),
+		Body:         bytes.NewReader(doc),
+		ContentType:  aws.String(ContentType),
+		CacheControl: aws.String(p.cacheControl),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put s3://%s/%s: %w", p.bucket, p.key, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package jwkspub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookPublisher publishes the JWKS document by sending it to an HTTP endpoint.
+// The receiver is expected to replace its copy of the document in full.
+type WebhookPublisher struct {
+	url          string
+	method       string
+	headers      map[string]string
+	cacheControl string
+	client       *http.Client
+}
+
+// WebhookPublisherConfig configures a webhook publisher
+type WebhookPublisherConfig struct {
+	// URL is the endpoint to send the document to
+	URL string
+
+	// Method is the HTTP method (default: PUT)
+	Method string
+
+	// Headers are additional request headers (e.g., Authorization)
+	Headers map[string]string
+
+	// CacheControl is sent as the Cache-Control header (default: DefaultCacheControl)
+	CacheControl string
+
+	// HTTPClient is an optional HTTP client
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+}
+
+// NewWebhookPublisher creates a new webhook publisher
+func NewWebhookPublisher(cfg WebhookPublisherConfig) (*WebhookPublisher, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPut
+	}
+
+	cacheControl := cfg.CacheControl
+	if cacheControl == "" {
+		cacheControl = DefaultCacheControl
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &WebhookPublisher{
+		url:          cfg.URL,
+		method:       method,
+		headers:      cfg.Headers,
+		cacheControl: cacheControl,
+		client:       client,
+	}, nil
+}
+
+// Publish sends the document to the webhook.
+// Any 2xx response is considered success.
+func (p *WebhookPublisher) Publish(ctx context.Context, doc []byte) error {
+	req, err := http.NewRequestWithContext(ctx, p.method, p.url, bytes.NewReader(doc))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("Cache-Control", p.cacheControl)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send JWKS to %s: %w", p.url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned status %d", p.url, resp.StatusCode)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"encoding/asn1"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"strings"
 
@@ -47,6 +48,12 @@ func NewAWSKMSKeyProvider(ctx context.Context, cfg AWSKMSConfig) (*AWSKMSKeyProv
 			return nil, err
 		}
 	}
+	if err := checkAlgorithmForKeyType(algorithm, cfg.KeyType); err != nil {
+		return nil, err
+	}
+	if _, err := kmsSigningAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
 
 	var client *kms.Client
 
@@ -138,7 +145,7 @@ func (m *AWSKMSKeyProvider) rotateKey(ctx context.Context, trustDomain, namespac
 			PendingWindowInDays: aws.Int32(7),
 		})
 		if err != nil {
-			fmt.Printf("Warning: failed to schedule old key %s for deletion: %v\n", oldKeyID, err)
+			slog.WarnContext(ctx, "Failed to schedule old key for deletion", "key_id", oldKeyID, "error", err)
 		}
 	}
 
@@ -181,17 +188,9 @@ type awsKeyHandle struct {
 func (h *awsKeyHandle) Sign(ctx context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, string, error) {
 	aliasName := h.manager.aliasName(h.trustDomain, h.namespace, h.keyName)
 
-	// Determine signing algorithm
-	var signingAlg types.SigningAlgorithmSpec
-	switch h.manager.algorithm {
-	case "ES256":
-		signingAlg = types.SigningAlgorithmSpecEcdsaSha256
-	case "ES384":
-		signingAlg = types.SigningAlgorithmSpecEcdsaSha384
-	case "RS256":
-		signingAlg = types.SigningAlgorithmSpecRsassaPkcs1V15Sha256
-	default:
-		return nil, "", fmt.Errorf("unsupported algorithm: %s", h.manager.algorithm)
+	signingAlg, err := kmsSigningAlgorithm(h.manager.algorithm)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// Call KMS Sign using ALIAS
@@ -209,7 +208,7 @@ func (h *awsKeyHandle) Sign(ctx context.Context, digest []byte, opts crypto.Sign
 	usedKeyID := aws.ToString(resp.KeyId)
 
 	var signature []byte
-	if h.manager.algorithm == "ES256" || h.manager.algorithm == "ES384" {
+	if strings.HasPrefix(h.manager.algorithm, "ES") {
 		signature, err = convertDERToRawECDSA(resp.Signature)
 		if err != nil {
 			return nil, "", err
@@ -282,6 +281,31 @@ func algorithmFromKeyType(keyType KeyType) (string, error) {
 	}
 }
 
+// kmsSigningAlgorithm maps a JWS algorithm to the KMS signing algorithm.
+// KMS RSASSA_PSS uses a salt as long as the hash, as JWS PS* algorithms require.
+func kmsSigningAlgorithm(algorithm string) (types.SigningAlgorithmSpec, error) {
+	switch algorithm {
+	case "ES256":
+		return types.SigningAlgorithmSpecEcdsaSha256, nil
+	case "ES384":
+		return types.SigningAlgorithmSpecEcdsaSha384, nil
+	case "RS256":
+		return types.SigningAlgorithmSpecRsassaPkcs1V15Sha256, nil
+	case "RS384":
+		return types.SigningAlgorithmSpecRsassaPkcs1V15Sha384, nil
+	case "RS512":
+		return types.SigningAlgorithmSpecRsassaPkcs1V15Sha512, nil
+	case "PS256":
+		return types.SigningAlgorithmSpecRsassaPssSha256, nil
+	case "PS384":
+		return types.SigningAlgorithmSpecRsassaPssSha384, nil
+	case "PS512":
+		return types.SigningAlgorithmSpecRsassaPssSha512, nil
+	default:
+		return "", fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
+
 // convertDERToRawECDSA converts DER-encoded ECDSA signature to raw (r || s) format
 func convertDERToRawECDSA(derSig []byte) ([]byte, error) {
 	var sig struct {
@@ -0,0 +1,30 @@
+package keys
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKMSSigningAlgorithm(t *testing.T) {
+	tests := map[string]types.SigningAlgorithmSpec{
+		"ES256": types.SigningAlgorithmSpecEcdsaSha256,
+		"ES384": types.SigningAlgorithmSpecEcdsaSha384,
+		"RS256": types.SigningAlgorithmSpecRsassaPkcs1V15Sha256,
+		"RS384": types.SigningAlgorithmSpecRsassaPkcs1V15Sha384,
+		"RS512": types.SigningAlgorithmSpecRsassaPkcs1V15Sha512,
+		"PS256": types.SigningAlgorithmSpecRsassaPssSha256,
+		"PS384": types.SigningAlgorithmSpecRsassaPssSha384,
+		"PS512": types.SigningAlgorithmSpecRsassaPssSha512,
+	}
+	for alg, want := range tests {
+		got, err := kmsSigningAlgorithm(alg)
+		require.NoError(t, err, alg)
+		assert.Equal(t, want, got, alg)
+	}
+
+	_, err := kmsSigningAlgorithm("EdDSA")
+	assert.Error(t, err)
+}
@@ -3,10 +3,7 @@ package keys
 import (
 	"context"
 	"crypto"
-	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
@@ -84,6 +81,9 @@ func NewDiskKeyProvider(cfg DiskKeyProviderConfig) (*DiskKeyProvider, error) {
 			algorithm = "RS256"
 		}
 	}
+	if err := checkAlgorithmForKeyType(algorithm, cfg.KeyType); err != nil {
+		return nil, err
+	}
 
 	// Default to OS filesystem if not provided
 	filesystem := cfg.FileSystem
@@ -118,23 +118,9 @@ func (m *DiskKeyProvider) rotateKey(trustDomain, namespace, keyName string) erro
 	defer m.mu.Unlock()
 
 	// Generate new key based on configured keyType
-	var signer crypto.Signer
-	var err error
-
-	switch m.keyType {
-	case KeyTypeECP256:
-		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	case KeyTypeECP384:
-		signer, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
-	case KeyTypeRSA2048:
-		signer, err = rsa.GenerateKey(rand.Reader, 2048)
-	case KeyTypeRSA4096:
-		signer, err = rsa.GenerateKey(rand.Reader, 4096)
-	default:
-		return fmt.Errorf("unsupported key type: %s", m.keyType)
-	}
+	signer, err := GenerateKey(m.keyType)
 	if err != nil {
-		return fmt.Errorf("failed to generate key: %w", err)
+		return err
 	}
 
 	// Generate a unique kid using UUID
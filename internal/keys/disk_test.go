@@ -347,3 +347,24 @@ func TestDiskKeyProvider_ExplicitAlgorithm(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "RS512", alg)
 }
+
+func TestDiskKeyProvider_AlgorithmKeyTypeMismatch(t *testing.T) {
+	memFS := fs.NewMemFileSystem()
+
+	_, err := NewDiskKeyProvider(DiskKeyProviderConfig{
+		KeyType:    KeyTypeECP256,
+		Algorithm:  "PS256",
+		KeysPath:   "/keys",
+		FileSystem: memFS,
+	})
+	assert.ErrorContains(t, err, "algorithm PS256 cannot be used with EC-P256 keys")
+
+	kp, err := NewDiskKeyProvider(DiskKeyProviderConfig{
+		KeyType:    KeyTypeRSA2048,
+		Algorithm:  "PS384",
+		KeysPath:   "/keys",
+		FileSystem: memFS,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "PS384", kp.algorithm)
+}
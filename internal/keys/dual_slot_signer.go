@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -58,9 +58,12 @@ type DualSlotRotatingSigner struct {
 	activeThumbprint KeyID               // Public key ID (JWK Thumbprint)
 	activeAlg        Algorithm           // JWT Algorithm
 	publicKeys       []service.PublicKey // All non-expired public keys
+	revokedKeyIDs    map[KeyID]struct{}  // Keys revoked in the slot store, never used to sign
 
 	clock  clock.Clock
 	ticker clock.Ticker
+
+	limiter *SigningLimiter // Optional bound on concurrent signatures
 }
 
 // DualSlotRotatingSignerConfig configures the DualSlotRotatingSigner
@@ -78,6 +81,9 @@ type DualSlotRotatingSignerConfig struct {
 	GracePeriod       time.Duration
 	CheckInterval     time.Duration
 	PrepareTimeout    time.Duration // How long to wait before retrying a stuck "preparing" state (default: 1 minute)
+
+	// Limiter, if set, bounds the signatures in progress at once
+	Limiter *SigningLimiter
 }
 
 // NewDualSlotRotatingSigner creates a new dual-slot rotating signer
@@ -124,6 +130,7 @@ func NewDualSlotRotatingSigner(cfg DualSlotRotatingSignerConfig) *DualSlotRotati
 		checkInterval:       checkInterval,
 		prepareTimeout:      prepareTimeout,
 		clock:               clk,
+		limiter:             cfg.Limiter,
 	}
 }
 
@@ -166,11 +173,11 @@ func (r *DualSlotRotatingSigner) Stop() {
 // doRotationCheck is called periodically by the ticker to check for rotation needs
 func (r *DualSlotRotatingSigner) doRotationCheck(ctx context.Context) {
 	if err := r.checkAndRotate(ctx); err != nil {
-		log.Printf("Error during key rotation check: %v", err)
+		slog.ErrorContext(ctx, "Key rotation check failed", "namespace", r.namespace, "error", err)
 	}
 	// Update active key cache after each check (whether rotation happened or not)
 	if err := r.updateActiveKeyCache(ctx); err != nil {
-		log.Printf("Error updating active key cache: %v", err)
+		slog.ErrorContext(ctx, "Failed to update active key cache", "namespace", r.namespace, "error", err)
 	}
 }
 
@@ -209,26 +216,49 @@ func (r *DualSlotRotatingSigner) GetCurrentSigner(ctx context.Context) (crypto.S
 	if handle == nil {
 		return nil, "", "", fmt.Errorf("no active key available")
 	}
+	if r.isRevoked(thumbprint) {
+		return nil, "", "", fmt.Errorf("%w: %s", ErrKeyRevoked, thumbprint)
+	}
 
-	signer := &contextSigner{
+	var signer crypto.Signer = &contextSigner{
 		handle:     handle,
 		ctx:        ctx,
 		expectedID: internalID,
 	}
+	if r.limiter != nil {
+		signer = r.limiter.Wrap(ctx, signer)
+	}
 
 	return signer, thumbprint, alg, nil
 }
 
+// SigningLimiter returns the limiter bounding the signer's concurrent signatures, if any
+func (r *DualSlotRotatingSigner) SigningLimiter() *SigningLimiter {
+	return r.limiter
+}
+
 // PublicKeys returns all non-expired public keys from cache
 func (r *DualSlotRotatingSigner) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
 	r.mu.RLock()
-	keys := make([]service.PublicKey, len(r.publicKeys))
-	copy(keys, r.publicKeys)
-	r.mu.RUnlock()
+	defer r.mu.RUnlock()
 
+	keys := make([]service.PublicKey, 0, len(r.publicKeys))
+	for _, key := range r.publicKeys {
+		if _, revoked := r.revokedKeyIDs[KeyID(key.KeyID)]; !revoked {
+			keys = append(keys, key)
+		}
+	}
 	return keys, nil
 }
 
+// isRevoked reports whether the key has been revoked
+func (r *DualSlotRotatingSigner) isRevoked(keyID KeyID) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, revoked := r.revokedKeyIDs[keyID]
+	return revoked
+}
+
 // ensureInitialKey ensures at least one key exists, generating key-a if needed
 func (r *DualSlotRotatingSigner) ensureInitialKey(ctx context.Context) error {
 	slots, version, err := r.slotStore.ListSlots(ctx)
@@ -291,8 +321,230 @@ func (r *DualSlotRotatingSigner) checkAndRotate(ctx context.Context) error {
 		return fmt.Errorf("failed to list slots: %w", err)
 	}
 
-	// Filter slots to find slotA and slotB for this token type
-	var slotA, slotB *KeySlot
+	slotA, slotB, err := r.findSlots(slots)
+	if err != nil {
+		return err
+	}
+
+	// A revoked key must be replaced right away if no other key can sign
+	if revokedSlot := r.selectRevokedSlotForReplacement(slotA, slotB); revokedSlot != nil {
+		_, err = r.rotateSlot(ctx, revokedSlot, storeVersion)
+		return err
+	}
+
+	// 2. Determine which slot needs rotation and which slot to rotate TO
+	sourceSlot, targetSlot := r.selectSlotsForRotation(slotA, slotB)
+	if sourceSlot == nil || targetSlot == nil {
+		return nil // No rotation needed
+	}
+
+	_, err = r.rotateSlot(ctx, targetSlot, storeVersion)
+	return err
+}
+
+// RotateNow generates a new key immediately, regardless of the current key's age.
+//
+// The new key goes into the slot that is not signing, so the active key keeps signing
+// until the new key is past its grace period, giving clients time to fetch it.
+// Returns ErrRotationInProgress if another process is rotating concurrently.
+func (r *DualSlotRotatingSigner) RotateNow(ctx context.Context) error {
+	slots, storeVersion, err := r.slotStore.ListSlots(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list slots: %w", err)
+	}
+
+	slotA, slotB, err := r.findSlots(slots)
+	if err != nil {
+		return err
+	}
+
+	rotated, err := r.rotateSlot(ctx, r.selectSlotForForcedRotation(slotA, slotB), storeVersion)
+	if err != nil {
+		return err
+	}
+	if !rotated {
+		return ErrRotationInProgress
+	}
+
+	if err := r.updateActiveKeyCache(ctx); err != nil {
+		return fmt.Errorf("failed to update active key cache: %w", err)
+	}
+	return nil
+}
+
+// RevokeKey marks the key with the given ID as revoked in the slot store, so every
+// replica stops publishing it and refuses to sign with it.
+//
+// If no other key can sign, a replacement is generated in the revoked key's slot
+// immediately, skipping the grace period. Otherwise the revoked slot is reused by
+// the next rotation. Returns ErrRotationInProgress if the key was revoked but its
+// replacement is being generated by another process.
+func (r *DualSlotRotatingSigner) RevokeKey(ctx context.Context, keyID KeyID) error {
+	slots, storeVersion, err := r.slotStore.ListSlots(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list slots: %w", err)
+	}
+
+	slotA, slotB, err := r.findSlots(slots)
+	if err != nil {
+		return err
+	}
+
+	var target *KeySlot
+	for _, slot := range []*KeySlot{slotA, slotB} {
+		if slot == nil || slot.RotationCompletedAt == nil {
+			continue
+		}
+		slotKeyID, err := r.slotKeyID(ctx, slot)
+		if err != nil {
+			return err
+		}
+		if slotKeyID == keyID {
+			target = slot
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownKeyID, keyID)
+	}
+
+	if target.RevokedAt == nil {
+		now := r.clock.Now()
+		target.RevokedAt = &now
+		storeVersion, err = r.slotStore.SaveSlot(ctx, target, storeVersion)
+		if err != nil {
+			return fmt.Errorf("failed to save revoked slot %s: %w", target.Position, err)
+		}
+		slog.InfoContext(ctx, "Revoked key", "namespace", r.namespace, "key_id", keyID, "slot", target.Position)
+	}
+
+	replaced := true
+	if r.selectRevokedSlotForReplacement(slotA, slotB) != nil {
+		replaced, err = r.rotateSlot(ctx, target, storeVersion)
+		if err != nil {
+			return fmt.Errorf("key %s revoked, but failed to generate a replacement: %w", keyID, err)
+		}
+	}
+
+	// Refresh even if there is no replacement yet, so this process stops using the key
+	cacheErr := r.updateActiveKeyCache(ctx)
+	if !replaced {
+		return fmt.Errorf("key %s revoked, but its replacement is not ready: %w", keyID, ErrRotationInProgress)
+	}
+	if cacheErr != nil {
+		return fmt.Errorf("failed to update active key cache: %w", cacheErr)
+	}
+	return nil
+}
+
+// slotKeyID returns the key ID (JWK thumbprint) of the key currently in a slot
+func (r *DualSlotRotatingSigner) slotKeyID(ctx context.Context, slot *KeySlot) (KeyID, error) {
+	provider, ok := r.keyProviderRegistry[slot.KeyProviderID]
+	if !ok {
+		return "", fmt.Errorf("key provider %s not found for slot %s", slot.KeyProviderID, slot.Position)
+	}
+
+	handle, err := provider.GetKeyHandle(ctx, r.trustDomain, r.namespace, r.keyName(slot.Position))
+	if err != nil {
+		return "", fmt.Errorf("failed to get handle %s: %w", slot.Position, err)
+	}
+
+	pubKey, err := handle.Public(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get public key for %s: %w", slot.Position, err)
+	}
+
+	thumbprint, err := ComputeThumbprint(pubKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute thumbprint for key %s: %w", slot.Position, err)
+	}
+	return KeyID(thumbprint), nil
+}
+
+// SlotStates implements SlotReporter
+func (r *DualSlotRotatingSigner) SlotStates(ctx context.Context) ([]SlotState, error) {
+	slots, _, err := r.slotStore.ListSlots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slots: %w", err)
+	}
+	slotA, slotB, err := r.findSlots(slots)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	activeKeyID := r.activeThumbprint
+	r.mu.RUnlock()
+
+	var states []SlotState
+	for _, slot := range []*KeySlot{slotA, slotB} {
+		if slot == nil {
+			continue
+		}
+		state := SlotState{
+			Position:            slot.Position,
+			KeyProviderID:       slot.KeyProviderID,
+			PreparingAt:         slot.PreparingAt,
+			RotationCompletedAt: slot.RotationCompletedAt,
+			RevokedAt:           slot.RevokedAt,
+		}
+		if slot.RotationCompletedAt != nil {
+			usableAt := slot.RotationCompletedAt.Add(r.gracePeriod)
+			expiresAt := slot.RotationCompletedAt.Add(r.keyTTL)
+			state.UsableAt, state.ExpiresAt = &usableAt, &expiresAt
+		}
+
+		if keyID, alg, err := r.slotKey(ctx, slot); err != nil {
+			state.Error = err.Error()
+		} else {
+			state.KeyID, state.Algorithm = keyID, alg
+			state.Active = keyID == activeKeyID && slot.RevokedAt == nil
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// slotKey returns the key ID and algorithm of the key currently in a slot
+func (r *DualSlotRotatingSigner) slotKey(ctx context.Context, slot *KeySlot) (KeyID, Algorithm, error) {
+	keyID, err := r.slotKeyID(ctx, slot)
+	if err != nil {
+		return "", "", err
+	}
+
+	handle, err := r.keyProviderRegistry[slot.KeyProviderID].GetKeyHandle(ctx, r.trustDomain, r.namespace, r.keyName(slot.Position))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get handle %s: %w", slot.Position, err)
+	}
+	_, alg, err := handle.Metadata(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get metadata for %s: %w", slot.Position, err)
+	}
+	return keyID, Algorithm(alg), nil
+}
+
+// selectRevokedSlotForReplacement returns a revoked slot that needs a new key because
+// no other key can sign, or nil if there is none
+func (r *DualSlotRotatingSigner) selectRevokedSlotForReplacement(slotA, slotB *KeySlot) *KeySlot {
+	now := r.clock.Now()
+	usable := func(slot *KeySlot) bool {
+		return slot != nil && slot.RevokedAt == nil && slot.RotationCompletedAt != nil &&
+			now.Before(slot.RotationCompletedAt.Add(r.keyTTL))
+	}
+	if usable(slotA) || usable(slotB) {
+		return nil
+	}
+
+	for _, slot := range []*KeySlot{slotA, slotB} {
+		if slot != nil && slot.RevokedAt != nil {
+			return slot
+		}
+	}
+	return nil
+}
+
+// findSlots returns this signer's slots A and B, either of which may be nil
+func (r *DualSlotRotatingSigner) findSlots(slots []*KeySlot) (slotA, slotB *KeySlot, err error) {
 	for _, slot := range slots {
 		if slot.Namespace != r.namespace || slot.KeyProviderID != r.keyProviderID {
 			continue
@@ -303,23 +555,23 @@ func (r *DualSlotRotatingSigner) checkAndRotate(ctx context.Context) error {
 		case SlotPositionB:
 			slotB = slot
 		default:
-			return fmt.Errorf("unexpected slot position for namespace %s: %s", r.namespace, slot.Position)
+			return nil, nil, fmt.Errorf("unexpected slot position for namespace %s: %s", r.namespace, slot.Position)
 		}
 	}
+	return slotA, slotB, nil
+}
 
-	// 2. Determine which slot needs rotation and which slot to rotate TO
-	sourceSlot, targetSlot := r.selectSlotsForRotation(slotA, slotB)
-	if sourceSlot == nil || targetSlot == nil {
-		return nil // No rotation needed
-	}
-
+// rotateSlot generates a new key in the target slot using two-phase rotation:
+// the slot is first marked as preparing, then completed once the key exists.
+// Returns false if another process is already preparing the slot or changed the store first.
+func (r *DualSlotRotatingSigner) rotateSlot(ctx context.Context, targetSlot *KeySlot, storeVersion StoreVersion) (bool, error) {
 	now := r.clock.Now()
 
-	// 3. Check if target slot is NOT in "preparing" state - if so, mark it as preparing
+	// Check if target slot is NOT in "preparing" state - if so, mark it as preparing
 	if targetSlot.PreparingAt != nil {
 		if now.Sub(*targetSlot.PreparingAt) < r.prepareTimeout {
 			// Already preparing and not timed out, wait for the other process
-			return nil
+			return false, nil
 		}
 		// else: timed out, proceed to generate key
 	}
@@ -327,46 +579,101 @@ func (r *DualSlotRotatingSigner) checkAndRotate(ctx context.Context) error {
 	targetSlot.PreparingAt = &now
 	// Use current KeyProvider for new key
 	targetSlot.KeyProviderID = r.keyProviderID
-	storeVersion, err = r.slotStore.SaveSlot(ctx, targetSlot, storeVersion)
+	storeVersion, err := r.slotStore.SaveSlot(ctx, targetSlot, storeVersion)
 	if errors.Is(err, ErrVersionMismatch) {
-		return nil // Another process won, that's fine
+		return false, nil // Another process won, that's fine
 	}
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// 4. Generate key and complete rotation using current KeyProvider
+	// Generate key and complete rotation using current KeyProvider
 	provider, ok := r.keyProviderRegistry[r.keyProviderID]
 	if !ok {
-		return fmt.Errorf("key provider not found: %s", r.keyProviderID)
+		return false, fmt.Errorf("key provider not found: %s", r.keyProviderID)
 	}
 
 	keyName := r.keyName(targetSlot.Position)
 	handle, err := provider.GetKeyHandle(ctx, r.trustDomain, r.namespace, keyName)
 	if err != nil {
-		return fmt.Errorf("failed to get key handle: %w", err)
+		return false, fmt.Errorf("failed to get key handle: %w", err)
 	}
 
 	if err := handle.Rotate(ctx); err != nil {
-		return fmt.Errorf("failed to rotate key: %w", err)
+		return false, fmt.Errorf("failed to rotate key: %w", err)
 	}
 
-	// 5. Update slot with rotation completed, clear preparing state
+	// Update slot with rotation completed, clear preparing state
 	targetSlot.PreparingAt = nil
 	targetSlot.RotationCompletedAt = &now
+	targetSlot.RevokedAt = nil
 
 	_, err = r.slotStore.SaveSlot(ctx, targetSlot, storeVersion)
 	if errors.Is(err, ErrVersionMismatch) {
-		log.Printf("Another process completed rotation for slot %s, skipping", targetSlot.Position)
-		return nil
+		slog.InfoContext(ctx, "Another process completed rotation, skipping", "namespace", r.namespace, "slot", targetSlot.Position)
+		return false, nil
 	}
 	if err != nil {
-		return fmt.Errorf("failed to save slot: %w", err)
+		return false, fmt.Errorf("failed to save slot: %w", err)
 	}
 
-	log.Printf("Completed rotation for slot %s", targetSlot.Position)
+	slog.InfoContext(ctx, "Completed key rotation", "namespace", r.namespace, "slot", targetSlot.Position)
 
-	return nil
+	return true, nil
+}
+
+// selectSlotForForcedRotation returns the slot a forced rotation should generate a new key in:
+// a missing slot if there is one, otherwise the slot that is not signing.
+func (r *DualSlotRotatingSigner) selectSlotForForcedRotation(slotA, slotB *KeySlot) *KeySlot {
+	if slotA == nil {
+		return &KeySlot{Position: SlotPositionA, Namespace: r.namespace, KeyProviderID: r.keyProviderID}
+	}
+	if slotB == nil {
+		return &KeySlot{Position: SlotPositionB, Namespace: r.namespace, KeyProviderID: r.keyProviderID}
+	}
+
+	now := r.clock.Now()
+	// Revoked keys never sign, so they are as good as expired
+	expired := func(slot *KeySlot) bool {
+		return slot.RevokedAt != nil || slot.RotationCompletedAt == nil || !now.Before(slot.RotationCompletedAt.Add(r.keyTTL))
+	}
+	pastGracePeriod := func(slot *KeySlot) bool {
+		return !now.Before(slot.RotationCompletedAt.Add(r.gracePeriod))
+	}
+
+	// Mirror updateActiveKeyCache: the active key is the newest key past its grace period,
+	// or else the oldest key still in its grace period
+	var preferred, fallback []*KeySlot
+	for _, slot := range []*KeySlot{slotA, slotB} {
+		if expired(slot) {
+			continue
+		}
+		if pastGracePeriod(slot) {
+			preferred = append(preferred, slot)
+		} else {
+			fallback = append(fallback, slot)
+		}
+	}
+
+	active := findNewestSlot(preferred)
+	if active == nil {
+		active = findOldestSlot(fallback)
+	}
+	if active == slotA {
+		return slotB
+	}
+	if active == slotB {
+		return slotA
+	}
+
+	// Neither key is usable; replace the older one
+	if slotA.RotationCompletedAt == nil {
+		return slotA
+	}
+	if slotB.RotationCompletedAt == nil || slotA.RotationCompletedAt.After(*slotB.RotationCompletedAt) {
+		return slotB
+	}
+	return slotA
 }
 
 // selectSlotsForRotation determines which slot needs rotation and which slot to rotate to
@@ -381,6 +688,11 @@ func (r *DualSlotRotatingSigner) selectSlotsForRotation(slotA, slotB *KeySlot) (
 			return false
 		}
 
+		// Revoked keys are replaced by selectRevokedSlotForReplacement or the other slot's rotation
+		if slot.RevokedAt != nil {
+			return false
+		}
+
 		// Check if key is expired - expired keys don't need rotation
 		if slot.RotationCompletedAt != nil {
 			expiresAt := slot.RotationCompletedAt.Add(r.keyTTL)
@@ -423,7 +735,7 @@ func (r *DualSlotRotatingSigner) selectSlotsForRotation(slotA, slotB *KeySlot) (
 		}
 		// Don't rotate if target slot (B) already has a recent key
 		// This prevents re-rotating A to B when B was just created
-		if slotB.RotationCompletedAt != nil {
+		if slotB.RotationCompletedAt != nil && slotB.RevokedAt == nil {
 			// If B is newer than A, don't rotate A again
 			if slotA.RotationCompletedAt != nil && slotB.RotationCompletedAt.After(*slotA.RotationCompletedAt) {
 				return nil, nil // B is already the newer key
@@ -444,7 +756,7 @@ func (r *DualSlotRotatingSigner) selectSlotsForRotation(slotA, slotB *KeySlot) (
 		}
 		// Don't rotate if target slot (A) already has a recent key
 		// This prevents re-rotating A to B when B was just created
-		if slotA.RotationCompletedAt != nil {
+		if slotA.RotationCompletedAt != nil && slotA.RevokedAt == nil {
 			// If A is newer than B, don't rotate B again
 			if slotB.RotationCompletedAt != nil && slotA.RotationCompletedAt.After(*slotB.RotationCompletedAt) {
 				return nil, nil // A is already the newer key
@@ -484,6 +796,7 @@ func (r *DualSlotRotatingSigner) updateActiveKeyCache(ctx context.Context) error
 	var preferredSlots []*KeySlot           // Keys past grace period
 	var fallbackSlots []*KeySlot            // Keys still in grace period
 	thumbprints := make(map[*KeySlot]KeyID) // Cache computed thumbprints
+	revokedKeyIDs := make(map[KeyID]struct{})
 
 	for _, slot := range mySlots {
 		// Check if key is expired
@@ -502,34 +815,40 @@ func (r *DualSlotRotatingSigner) updateActiveKeyCache(ctx context.Context) error
 		// Get the KeyProvider that created this key
 		provider, ok := r.keyProviderRegistry[slot.KeyProviderID]
 		if !ok {
-			log.Printf("Warning: key provider %s not found for slot %s, skipping", slot.KeyProviderID, slot.Position)
+			slog.WarnContext(ctx, "Key provider not found, skipping slot", "namespace", r.namespace, "key_provider_id", slot.KeyProviderID, "slot", slot.Position)
 			continue
 		}
 
 		keyName := r.keyName(slot.Position)
 		handle, err := provider.GetKeyHandle(ctx, r.trustDomain, r.namespace, keyName)
 		if err != nil {
-			log.Printf("Warning: failed to get handle %s from key provider: %v", slot.Position, err)
+			slog.WarnContext(ctx, "Failed to get key handle", "namespace", r.namespace, "slot", slot.Position, "error", err)
 			continue
 		}
 
 		pubKey, err := handle.Public(ctx)
 		if err != nil {
-			log.Printf("Warning: failed to get public key for %s: %v", slot.Position, err)
+			slog.WarnContext(ctx, "Failed to get public key", "namespace", r.namespace, "slot", slot.Position, "error", err)
 			continue
 		}
 
 		thumbprintStr, err := ComputeThumbprint(pubKey)
 		if err != nil {
-			log.Printf("Warning: failed to compute thumbprint for key %s: %v", slot.Position, err)
+			slog.WarnContext(ctx, "Failed to compute key thumbprint", "namespace", r.namespace, "slot", slot.Position, "error", err)
 			continue
 		}
 		thumbprint := KeyID(thumbprintStr)
 		thumbprints[slot] = thumbprint
 
+		// Revoked keys are neither published nor used
+		if slot.RevokedAt != nil {
+			revokedKeyIDs[thumbprint] = struct{}{}
+			continue
+		}
+
 		_, algStr, err := handle.Metadata(ctx)
 		if err != nil {
-			log.Printf("Warning: failed to get metadata for %s: %v", slot.Position, err)
+			slog.WarnContext(ctx, "Failed to get key metadata", "namespace", r.namespace, "slot", slot.Position, "error", err)
 			continue
 		}
 		alg := Algorithm(algStr)
@@ -568,6 +887,11 @@ func (r *DualSlotRotatingSigner) updateActiveKeyCache(ctx context.Context) error
 		activeSlot = findOldestSlot(fallbackSlots)
 	}
 
+	// Record revocations even if there is no key to switch to, so the cached key stops signing
+	r.mu.Lock()
+	r.revokedKeyIDs = revokedKeyIDs
+	r.mu.Unlock()
+
 	if activeSlot == nil {
 		return errors.New("no keys available")
 	}
@@ -652,3 +652,180 @@ func TestDualSlotRotatingSigner_Namespacing(t *testing.T) {
 	_, _, err = handleBad.Metadata(ctx)
 	assert.Error(t, err)
 }
+
+func TestDualSlotRotatingSigner_RotateNow(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+	ctx := context.Background()
+
+	require.NoError(t, rs.Start(ctx))
+	defer rs.Stop()
+
+	// Well before the rotation threshold
+	clk.Advance(5 * time.Minute)
+	_, keyID1, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, rs.RotateNow(ctx))
+
+	// The new key is published immediately, but the old key keeps signing during the grace period
+	publicKeys, err := rs.PublicKeys(ctx)
+	require.NoError(t, err)
+	assert.Len(t, publicKeys, 2)
+
+	_, keyID2, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, keyID1, keyID2)
+
+	// Rotating again replaces the pending key rather than the active one
+	require.NoError(t, rs.RotateNow(ctx))
+	_, keyID3, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, keyID1, keyID3)
+
+	// After the grace period, the new key is used
+	clk.Advance(3 * time.Minute)
+	_, keyID4, _, err := rs.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, keyID1, keyID4)
+}
+
+func TestDualSlotRotatingSigner_RotateNowWhileRotationInProgress(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Time{})
+	slotStore := NewInMemoryKeySlotStore()
+	rs, _ := newTestDualSlotRotatingSigner(t, clk, slotStore, nil)
+	ctx := context.Background()
+
+	require.NoError(t, rs.Start(ctx))
+	defer rs.Stop()
+
+	clk.Advance(5 * time.Minute)
+
+	// Another process has started preparing the other slot
+	_, version, err := slotStore.ListSlots(ctx)
+	require.NoError(t, err)
+	preparingAt := clk.Now()
+	_, err = slotStore.SaveSlot(ctx, &KeySlot{
+		Position:      SlotPositionB,
+		Namespace:     testTokenType,
+		KeyProviderID: "test-provider",
+		PreparingAt:   &preparingAt,
+	}, version)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, rs.RotateNow(ctx), ErrRotationInProgress)
+}
+
+func TestDualSlotRotatingSigner_RevokeKey(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("revoking the only key generates a replacement", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+		require.NoError(t, rs.Start(ctx))
+		defer rs.Stop()
+
+		clk.Advance(5 * time.Minute)
+		_, revokedID, _, err := rs.GetCurrentSigner(ctx)
+		require.NoError(t, err)
+
+		require.NoError(t, rs.RevokeKey(ctx, revokedID))
+
+		// The replacement signs immediately, since nothing else can
+		_, keyID, _, err := rs.GetCurrentSigner(ctx)
+		require.NoError(t, err)
+		assert.NotEqual(t, revokedID, keyID)
+
+		publicKeys, err := rs.PublicKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, publicKeys, 1)
+		assert.Equal(t, string(keyID), publicKeys[0].KeyID)
+	})
+
+	t.Run("revoking the active key switches to the other key", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		slotStore := NewInMemoryKeySlotStore()
+		rs, _ := newTestDualSlotRotatingSigner(t, clk, slotStore, nil)
+		require.NoError(t, rs.Start(ctx))
+		defer rs.Stop()
+
+		clk.Advance(5 * time.Minute)
+		_, revokedID, _, err := rs.GetCurrentSigner(ctx)
+		require.NoError(t, err)
+		require.NoError(t, rs.RotateNow(ctx))
+
+		require.NoError(t, rs.RevokeKey(ctx, revokedID))
+
+		// The other key signs even though it is still in its grace period
+		_, keyID, _, err := rs.GetCurrentSigner(ctx)
+		require.NoError(t, err)
+		assert.NotEqual(t, revokedID, keyID)
+
+		publicKeys, err := rs.PublicKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, publicKeys, 1)
+		assert.Equal(t, string(keyID), publicKeys[0].KeyID)
+
+		// The revocation is persisted, so other replicas stop using the key too
+		slots, _, err := slotStore.ListSlots(ctx)
+		require.NoError(t, err)
+		revoked := 0
+		for _, slot := range slots {
+			if slot.RevokedAt != nil {
+				revoked++
+			}
+		}
+		assert.Equal(t, 1, revoked)
+
+		// Revoking again is a no-op
+		require.NoError(t, rs.RevokeKey(ctx, revokedID))
+
+		// The next rotation (8m before the other key expires) reuses the revoked slot
+		clk.Advance(23 * time.Minute)
+		publicKeys, err = rs.PublicKeys(ctx)
+		require.NoError(t, err)
+		assert.Len(t, publicKeys, 2)
+		for _, key := range publicKeys {
+			assert.NotEqual(t, string(revokedID), key.KeyID)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		rs, _ := newTestDualSlotRotatingSigner(t, clk, nil, nil)
+		require.NoError(t, rs.Start(ctx))
+		defer rs.Stop()
+
+		assert.ErrorIs(t, rs.RevokeKey(ctx, "not-a-key"), ErrUnknownKeyID)
+	})
+
+	t.Run("refuses to sign with a key revoked by another process", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		slotStore := NewInMemoryKeySlotStore()
+		rs, _ := newTestDualSlotRotatingSigner(t, clk, slotStore, nil)
+		require.NoError(t, rs.Start(ctx))
+		defer rs.Stop()
+
+		clk.Advance(5 * time.Minute)
+
+		// Another process revoked the key but has not generated a replacement yet
+		slots, version, err := slotStore.ListSlots(ctx)
+		require.NoError(t, err)
+		require.Len(t, slots, 1)
+		revokedAt := clk.Now()
+		slots[0].RevokedAt = &revokedAt
+		preparingAt := clk.Now()
+		slots[0].PreparingAt = &preparingAt
+		_, err = slotStore.SaveSlot(ctx, slots[0], version)
+		require.NoError(t, err)
+
+		assert.Error(t, rs.updateActiveKeyCache(ctx))
+
+		_, _, _, err = rs.GetCurrentSigner(ctx)
+		assert.ErrorIs(t, err, ErrKeyRevoked)
+
+		publicKeys, err := rs.PublicKeys(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, publicKeys)
+	})
+}
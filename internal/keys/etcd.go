@@ -0,0 +1,324 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultEtcdKeySlotKey is the default etcd key holding the key slots
+const DefaultEtcdKeySlotKey = "/parsec/key-slots"
+
+// EtcdKeySlotStore persists key slots under a single etcd key, so replicas sharing
+// the etcd cluster coordinate rotation.
+//
+// The key's mod_revision is the store version. Saves are etcd transactions that
+// compare it first, so a concurrent save by another replica surfaces as
+// ErrVersionMismatch. It uses etcd's JSON gateway (/v3/...) over HTTP.
+type EtcdKeySlotStore struct {
+	endpoints  []string
+	key        string
+	username   string
+	password   string
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// EtcdKeySlotStoreConfig configures the etcd key slot store
+type EtcdKeySlotStoreConfig struct {
+	// Endpoints are etcd client URLs (e.g., "https://etcd-0.etcd:2379"), tried in order
+	Endpoints []string
+
+	// Key is the etcd key holding the slots (default: DefaultEtcdKeySlotKey)
+	Key string
+
+	// Username and Password authenticate with etcd, if auth is enabled
+	Username string
+	Password string
+
+	// HTTPClient is used for requests to etcd (default: http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// NewEtcdKeySlotStore creates a new etcd-backed key slot store
+func NewEtcdKeySlotStore(cfg EtcdKeySlotStoreConfig) (*EtcdKeySlotStore, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, fmt.Errorf("etcd slot store requires at least one endpoint")
+	}
+
+	key := cfg.Key
+	if key == "" {
+		key = DefaultEtcdKeySlotKey
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	endpoints := make([]string, len(cfg.Endpoints))
+	for i, endpoint := range cfg.Endpoints {
+		endpoints[i] = strings.TrimSuffix(endpoint, "/")
+	}
+
+	return &EtcdKeySlotStore{
+		endpoints:  endpoints,
+		key:        key,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		httpClient: httpClient,
+	}, nil
+}
+
+// etcdKeyValue is a key-value pair in etcd's JSON API. Bytes are base64 and int64s are strings.
+type etcdKeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	ModRevision string `json:"mod_revision"`
+}
+
+// ListSlots returns all slots and the key's mod_revision.
+// If the key does not exist yet, there are no slots and the version is empty.
+func (s *EtcdKeySlotStore) ListSlots(ctx context.Context) ([]*KeySlot, StoreVersion, error) {
+	kv, err := s.get(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if kv == nil {
+		return nil, "", nil
+	}
+
+	stored, err := s.decodeSlots(kv)
+	if err != nil {
+		return nil, "", err
+	}
+	return fromStoredSlots(stored), StoreVersion(kv.ModRevision), nil
+}
+
+// SaveSlot saves a slot if the key is still at expectedVersion
+func (s *EtcdKeySlotStore) SaveSlot(ctx context.Context, slot *KeySlot, expectedVersion StoreVersion) (StoreVersion, error) {
+	kv, err := s.get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var stored []storedSlot
+	if kv == nil {
+		if expectedVersion != "" {
+			return "", ErrVersionMismatch
+		}
+	} else {
+		if StoreVersion(kv.ModRevision) != expectedVersion {
+			return "", ErrVersionMismatch
+		}
+		if stored, err = s.decodeSlots(kv); err != nil {
+			return "", err
+		}
+	}
+
+	content, err := json.Marshal(putStoredSlot(stored, slot))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal slots: %w", err)
+	}
+
+	key := base64.StdEncoding.EncodeToString([]byte(s.key))
+
+	// Creating requires the key to still not exist; updating requires it unmodified
+	compare := map[string]any{"key": key, "result": "EQUAL"}
+	if expectedVersion == "" {
+		compare["target"] = "CREATE"
+		compare["create_revision"] = "0"
+	} else {
+		compare["target"] = "MOD"
+		compare["mod_revision"] = string(expectedVersion)
+	}
+
+	txn := map[string]any{
+		"compare": []any{compare},
+		"success": []any{map[string]any{
+			"request_put": map[string]any{
+				"key":   key,
+				"value": base64.StdEncoding.EncodeToString(content),
+			},
+		}},
+	}
+
+	var resp struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := s.call(ctx, "/v3/kv/txn", txn, &resp); err != nil {
+		return "", fmt.Errorf("failed to save key slots to etcd: %w", err)
+	}
+	if !resp.Succeeded {
+		return "", ErrVersionMismatch
+	}
+
+	// The put is the only write in the transaction, so the key's new mod_revision
+	// is the revision the transaction created
+	return StoreVersion(resp.Header.Revision), nil
+}
+
+// get reads the key, returning nil if it does not exist
+func (s *EtcdKeySlotStore) get(ctx context.Context) (*etcdKeyValue, error) {
+	var resp struct {
+		Kvs []etcdKeyValue `json:"kvs"`
+	}
+	req := map[string]any{"key": base64.StdEncoding.EncodeToString([]byte(s.key))}
+	if err := s.call(ctx, "/v3/kv/range", req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to read key slots from etcd: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+	return &resp.Kvs[0], nil
+}
+
+func (s *EtcdKeySlotStore) decodeSlots(kv *etcdKeyValue) ([]storedSlot, error) {
+	content, err := base64.StdEncoding.DecodeString(kv.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode etcd key %s: %w", s.key, err)
+	}
+	if len(content) == 0 {
+		return nil, nil
+	}
+
+	var stored []storedSlot
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse slots in etcd key %s: %w", s.key, err)
+	}
+	return stored, nil
+}
+
+// call posts a JSON request, trying each endpoint in turn until one responds.
+// If auth is configured, it authenticates first and again once if the token is rejected.
+func (s *EtcdKeySlotStore) call(ctx context.Context, path string, body, out any) error {
+	token, err := s.currentToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.post(ctx, path, token, body, out)
+
+	var eErr *etcdError
+	if token != "" && errors.As(err, &eErr) && eErr.isAuthError() {
+		s.invalidate(token)
+		if token, err = s.currentToken(ctx); err != nil {
+			return err
+		}
+		err = s.post(ctx, path, token, body, out)
+	}
+	return err
+}
+
+func (s *EtcdKeySlotStore) currentToken(ctx context.Context) (string, error) {
+	if s.username == "" {
+		return "", nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" {
+		return s.token, nil
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := s.post(ctx, "/v3/auth/authenticate", "", map[string]any{"name": s.username, "password": s.password}, &resp); err != nil {
+		return "", fmt.Errorf("etcd authentication failed: %w", err)
+	}
+	s.token = resp.Token
+	return s.token, nil
+}
+
+func (s *EtcdKeySlotStore) invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+// post sends the request to the first endpoint that responds
+func (s *EtcdKeySlotStore) post(ctx context.Context, path, token string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal etcd request: %w", err)
+	}
+
+	var lastErr error
+	for _, endpoint := range s.endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+path, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create etcd request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			// Unreachable member; try the next one
+			lastErr = fmt.Errorf("etcd request to %s failed: %w", endpoint, err)
+			continue
+		}
+		return decodeEtcdResponse(resp, out)
+	}
+	return lastErr
+}
+
+func decodeEtcdResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		eErr := &etcdError{StatusCode: resp.StatusCode}
+		body, _ := io.ReadAll(resp.Body)
+		var status struct {
+			Message string `json:"message"`
+			Error   string `json:"error"`
+		}
+		if json.Unmarshal(body, &status) == nil {
+			eErr.Message = status.Message
+			if eErr.Message == "" {
+				eErr.Message = status.Error
+			}
+		}
+		return eErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+	return nil
+}
+
+// etcdError is an error response from etcd's JSON gateway
+type etcdError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *etcdError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("etcd returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("etcd returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// isAuthError reports whether etcd rejected the auth token (e.g., it expired)
+func (e *etcdError) isAuthError() bool {
+	return e.StatusCode == http.StatusUnauthorized || strings.Contains(e.Message, "invalid auth token")
+}
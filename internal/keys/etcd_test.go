@@ -0,0 +1,152 @@
+package keys
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEtcd implements the subset of etcd's JSON gateway used by EtcdKeySlotStore
+type fakeEtcd struct {
+	mu       sync.Mutex
+	revision int64
+	kvs      map[string]fakeEtcdValue // by base64 key
+	tokens   []string
+}
+
+type fakeEtcdValue struct {
+	value          string
+	createRevision int64
+	modRevision    int64
+}
+
+func newFakeEtcd(t *testing.T) (*fakeEtcd, *httptest.Server) {
+	f := &fakeEtcd{kvs: make(map[string]fakeEtcdValue)}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeEtcd) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch r.URL.Path {
+	case "/v3/auth/authenticate":
+		if body["name"] != "parsec" || body["password"] != "s3cret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"message": "authentication failed"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"token": "token-1"})
+		return
+
+	case "/v3/kv/range":
+		f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+		resp := map[string]any{"header": f.header()}
+		if kv, ok := f.kvs[body["key"].(string)]; ok {
+			resp["kvs"] = []any{map[string]any{
+				"key":             body["key"],
+				"value":           kv.value,
+				"create_revision": strconv.FormatInt(kv.createRevision, 10),
+				"mod_revision":    strconv.FormatInt(kv.modRevision, 10),
+			}}
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	case "/v3/kv/txn":
+		f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+		succeeded := true
+		for _, c := range body["compare"].([]any) {
+			compare := c.(map[string]any)
+			kv := f.kvs[compare["key"].(string)]
+			var actual int64
+			var expected string
+			switch compare["target"] {
+			case "CREATE":
+				actual, expected = kv.createRevision, compare["create_revision"].(string)
+			case "MOD":
+				actual, expected = kv.modRevision, compare["mod_revision"].(string)
+			}
+			if strconv.FormatInt(actual, 10) != expected {
+				succeeded = false
+			}
+		}
+		if succeeded {
+			for _, op := range body["success"].([]any) {
+				put := op.(map[string]any)["request_put"].(map[string]any)
+				f.put(put["key"].(string), put["value"].(string))
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"header": f.header(), "succeeded": succeeded})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeEtcd) put(key, value string) {
+	f.revision++
+	kv, ok := f.kvs[key]
+	if !ok {
+		kv.createRevision = f.revision
+	}
+	kv.value = value
+	kv.modRevision = f.revision
+	f.kvs[key] = kv
+}
+
+func (f *fakeEtcd) header() map[string]any {
+	return map[string]any{"revision": strconv.FormatInt(f.revision, 10)}
+}
+
+func TestEtcdKeySlotStore(t *testing.T) {
+	testSharedKeySlotStore(t, func(t *testing.T) func() KeySlotStore {
+		_, server := newFakeEtcd(t)
+		return func() KeySlotStore {
+			store, err := NewEtcdKeySlotStore(EtcdKeySlotStoreConfig{Endpoints: []string{server.URL}})
+			require.NoError(t, err)
+			return store
+		}
+	})
+}
+
+func TestEtcdKeySlotStore_FailoverAndAuth(t *testing.T) {
+	ctx := t.Context()
+	api, server := newFakeEtcd(t)
+
+	// The first endpoint is unreachable
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	store, err := NewEtcdKeySlotStore(EtcdKeySlotStoreConfig{
+		Endpoints: []string{down.URL, server.URL},
+		Username:  "parsec",
+		Password:  "s3cret",
+	})
+	require.NoError(t, err)
+
+	version, err := store.SaveSlot(ctx, &KeySlot{Position: SlotPositionA, Namespace: "txn", KeyProviderID: "kms"}, "")
+	require.NoError(t, err)
+
+	slots, listed, err := store.ListSlots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, version, listed)
+	assert.Len(t, slots, 1)
+
+	for _, token := range api.tokens {
+		assert.Equal(t, "token-1", token)
+	}
+	_, stored := api.kvs[base64.StdEncoding.EncodeToString([]byte(DefaultEtcdKeySlotKey))]
+	assert.True(t, stored)
+}
@@ -0,0 +1,321 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// Default field names of externally managed keys
+const (
+	DefaultExternalKeyField         = "key.pem"
+	DefaultExternalPreviousKeyField = "previous.pem"
+)
+
+// ExternalKeySource fetches key material whose lifecycle is managed outside parsec,
+// such as a Kubernetes Secret or a Vault KV secret
+type ExternalKeySource interface {
+	// Fetch returns the fields of the secret (e.g., PEM-encoded private keys by name)
+	Fetch(ctx context.Context) (map[string][]byte, error)
+}
+
+// ExternalKeySigner is a RotatingSigner for keys rotated by an external system.
+// It signs in-process with a PEM private key loaded from an ExternalKeySource and
+// reloads it on a schedule, so the JWKS follows when the key is replaced.
+//
+// To roll keys without invalidating outstanding tokens, the external system should
+// move the old key to the previous key field when writing a new key: the previous
+// key is published for verification but never used to sign.
+type ExternalKeySigner struct {
+	source           ExternalKeySource
+	keyField         string
+	previousKeyField string
+	algorithm        Algorithm
+	refreshInterval  time.Duration
+	clock            clock.Clock
+	ticker           clock.Ticker
+	limiter          *SigningLimiter
+
+	mu       sync.RWMutex
+	active   *externalKey
+	previous *externalKey
+}
+
+// ExternalKeySignerConfig configures an ExternalKeySigner
+type ExternalKeySignerConfig struct {
+	// Source provides the key material
+	Source ExternalKeySource
+
+	// KeyField is the field holding the signing key (default: DefaultExternalKeyField)
+	KeyField string
+
+	// PreviousKeyField is an optional field holding the previous key, published for
+	// verification only (default: DefaultExternalPreviousKeyField)
+	PreviousKeyField string
+
+	// Algorithm is the signing algorithm (default based on the key type,
+	// e.g., ES256 for P-256 keys and RS256 for RSA keys)
+	Algorithm Algorithm
+
+	// RefreshInterval is how often the key is reloaded (default: 1 minute)
+	RefreshInterval time.Duration
+
+	// Clock drives the refresh schedule (default: system clock)
+	Clock clock.Clock
+
+	// Limiter, if set, bounds the signatures in progress at once
+	Limiter *SigningLimiter
+}
+
+// externalKey is a parsed key and its identifiers
+type externalKey struct {
+	signer    crypto.Signer
+	keyID     KeyID
+	algorithm Algorithm
+}
+
+// NewExternalKeySigner creates a signer for externally managed keys
+func NewExternalKeySigner(cfg ExternalKeySignerConfig) (*ExternalKeySigner, error) {
+	if cfg.Source == nil {
+		return nil, fmt.Errorf("external key source is required")
+	}
+
+	keyField := cfg.KeyField
+	if keyField == "" {
+		keyField = DefaultExternalKeyField
+	}
+	previousKeyField := cfg.PreviousKeyField
+	if previousKeyField == "" {
+		previousKeyField = DefaultExternalPreviousKeyField
+	}
+	refreshInterval := cfg.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = time.Minute
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &ExternalKeySigner{
+		source:           cfg.Source,
+		keyField:         keyField,
+		previousKeyField: previousKeyField,
+		algorithm:        cfg.Algorithm,
+		refreshInterval:  refreshInterval,
+		clock:            clk,
+		limiter:          cfg.Limiter,
+	}, nil
+}
+
+// Start loads the key, failing if it cannot be loaded, and begins periodic reloads
+func (s *ExternalKeySigner) Start(ctx context.Context) error {
+	if err := s.Reload(ctx); err != nil {
+		return fmt.Errorf("failed to load external key: %w", err)
+	}
+
+	s.ticker = s.clock.Ticker(s.refreshInterval)
+	if err := s.ticker.Start(func(ctx context.Context) {
+		// Keep signing with the last good key if the source is unavailable or invalid
+		if err := s.Reload(ctx); err != nil {
+			slog.ErrorContext(ctx, "Failed to reload external key", "error", err)
+		}
+	}); err != nil {
+		return fmt.Errorf("failed to start refresh ticker: %w", err)
+	}
+
+	return nil
+}
+
+// Stop stops periodic reloads
+func (s *ExternalKeySigner) Stop() {
+	if s.ticker != nil {
+		s.ticker.Stop()
+	}
+}
+
+// Reload fetches and parses the keys from the source.
+// The current keys are kept if fetching or parsing fails.
+func (s *ExternalKeySigner) Reload(ctx context.Context) error {
+	fields, err := s.source.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	keyPEM, ok := fields[s.keyField]
+	if !ok || len(keyPEM) == 0 {
+		return fmt.Errorf("external key field %q not found", s.keyField)
+	}
+	active, err := parseExternalKey(keyPEM, s.algorithm)
+	if err != nil {
+		return fmt.Errorf("invalid key in field %q: %w", s.keyField, err)
+	}
+
+	var previous *externalKey
+	if previousPEM := fields[s.previousKeyField]; len(previousPEM) > 0 {
+		if previous, err = parseExternalKey(previousPEM, s.algorithm); err != nil {
+			return fmt.Errorf("invalid key in field %q: %w", s.previousKeyField, err)
+		}
+		if previous.keyID == active.keyID {
+			previous = nil
+		}
+	}
+
+	s.mu.Lock()
+	changed := s.active == nil || s.active.keyID != active.keyID
+	s.active = active
+	s.previous = previous
+	s.mu.Unlock()
+
+	if changed {
+		slog.Info("Loaded external signing key", "key_id", active.keyID)
+	}
+
+	return nil
+}
+
+// GetCurrentSigner returns the active key
+func (s *ExternalKeySigner) GetCurrentSigner(ctx context.Context) (crypto.Signer, KeyID, Algorithm, error) {
+	s.mu.RLock()
+	active := s.active
+	s.mu.RUnlock()
+
+	if active == nil {
+		return nil, "", "", fmt.Errorf("no active key available")
+	}
+	if s.limiter != nil {
+		return s.limiter.Wrap(ctx, active.signer), active.keyID, active.algorithm, nil
+	}
+	return active.signer, active.keyID, active.algorithm, nil
+}
+
+// SigningLimiter returns the limiter bounding the signer's concurrent signatures, if any
+func (s *ExternalKeySigner) SigningLimiter() *SigningLimiter {
+	return s.limiter
+}
+
+// PublicKeys returns the active key and, if present, the previous key
+func (s *ExternalKeySigner) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []service.PublicKey
+	for _, key := range []*externalKey{s.active, s.previous} {
+		if key == nil {
+			continue
+		}
+		keys = append(keys, service.PublicKey{
+			KeyID:     string(key.keyID),
+			Algorithm: string(key.algorithm),
+			Key:       key.signer.Public(),
+			Use:       "sig",
+		})
+	}
+	return keys, nil
+}
+
+// parseExternalKey parses a PEM private key (PKCS#8, SEC 1, or PKCS#1).
+// If algorithm is empty, it is chosen based on the key type.
+func parseExternalKey(data []byte, algorithm Algorithm) (*externalKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	if algorithm == "" {
+		if algorithm, err = defaultAlgorithmForKey(signer.Public()); err != nil {
+			return nil, err
+		}
+	} else if err := checkAlgorithmForKey(algorithm, signer.Public()); err != nil {
+		return nil, err
+	}
+
+	thumbprint, err := ComputeThumbprint(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute key ID: %w", err)
+	}
+
+	return &externalKey{
+		signer:    signer,
+		keyID:     KeyID(thumbprint),
+		algorithm: algorithm,
+	}, nil
+}
+
+// defaultAlgorithmForKey returns the conventional JWS algorithm for a public key
+func defaultAlgorithmForKey(pub crypto.PublicKey) (Algorithm, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "ES256", nil
+		case elliptic.P384():
+			return "ES384", nil
+		case elliptic.P521():
+			return "ES512", nil
+		}
+		return "", fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", pub)
+	}
+}
+
+// checkAlgorithmForKey verifies algorithm can be used with pub
+func checkAlgorithmForKey(algorithm Algorithm, pub crypto.PublicKey) error {
+	var ok bool
+	switch pub.(type) {
+	case *ecdsa.PublicKey:
+		// The curve must also match, so compare against the key's only valid algorithm
+		want, err := defaultAlgorithmForKey(pub)
+		if err != nil {
+			return err
+		}
+		ok = algorithm == want
+	case *rsa.PublicKey:
+		ok = strings.HasPrefix(string(algorithm), "RS") || strings.HasPrefix(string(algorithm), "PS")
+	case ed25519.PublicKey:
+		ok = algorithm == "EdDSA"
+	}
+	if !ok {
+		return fmt.Errorf("algorithm %s cannot be used with %T keys", algorithm, pub)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKeySource is an ExternalKeySource whose fields can be changed by tests
+type fakeKeySource struct {
+	mu     sync.Mutex
+	fields map[string][]byte
+	err    error
+}
+
+func (s *fakeKeySource) set(fields map[string][]byte, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fields, s.err = fields, err
+}
+
+func (s *fakeKeySource) Fetch(ctx context.Context) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fields, s.err
+}
+
+func newECKeyPEM(t *testing.T, curve elliptic.Curve) ([]byte, crypto.Signer) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), key
+}
+
+func newRSAKeyPEM(t *testing.T) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func keyIDs(t *testing.T, signer *ExternalKeySigner) []string {
+	t.Helper()
+	keys, err := signer.PublicKeys(context.Background())
+	require.NoError(t, err)
+	var ids []string
+	for _, key := range keys {
+		ids = append(ids, key.KeyID)
+	}
+	return ids
+}
+
+func TestExternalKeySigner_ReloadsOnSchedule(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Time{})
+
+	firstPEM, firstKey := newECKeyPEM(t, elliptic.P256())
+	source := &fakeKeySource{fields: map[string][]byte{"key.pem": firstPEM}}
+
+	signer, err := NewExternalKeySigner(ExternalKeySignerConfig{
+		Source:          source,
+		RefreshInterval: time.Minute,
+		Clock:           clk,
+	})
+	require.NoError(t, err)
+	require.NoError(t, signer.Start(ctx))
+	t.Cleanup(signer.Stop)
+
+	current, firstID, alg, err := signer.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Algorithm("ES256"), alg)
+	assert.Equal(t, firstKey.Public(), current.Public())
+	assert.Equal(t, []string{string(firstID)}, keyIDs(t, signer))
+
+	digest := sha256.Sum256([]byte("payload"))
+	sig, err := current.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	assert.True(t, ecdsa.VerifyASN1(firstKey.Public().(*ecdsa.PublicKey), digest[:], sig))
+
+	// The external system rotates, keeping the old key as previous
+	secondPEM, secondKey := newECKeyPEM(t, elliptic.P256())
+	source.set(map[string][]byte{"key.pem": secondPEM, "previous.pem": firstPEM}, nil)
+
+	clk.Advance(30 * time.Second)
+	_, keyID, _, err := signer.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, firstID, keyID, "should not reload before the refresh interval")
+
+	clk.Advance(30 * time.Second)
+	current, secondID, _, err := signer.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, firstID, secondID)
+	assert.Equal(t, secondKey.Public(), current.Public())
+	assert.Equal(t, []string{string(secondID), string(firstID)}, keyIDs(t, signer))
+
+	// The previous key is eventually removed
+	source.set(map[string][]byte{"key.pem": secondPEM}, nil)
+	clk.Advance(time.Minute)
+	assert.Equal(t, []string{string(secondID)}, keyIDs(t, signer))
+}
+
+func TestExternalKeySigner_KeepsKeyWhenReloadFails(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Time{})
+
+	keyPEM, _ := newECKeyPEM(t, elliptic.P256())
+	source := &fakeKeySource{fields: map[string][]byte{"key.pem": keyPEM}}
+
+	signer, err := NewExternalKeySigner(ExternalKeySignerConfig{Source: source, Clock: clk})
+	require.NoError(t, err)
+	require.NoError(t, signer.Start(ctx))
+	t.Cleanup(signer.Stop)
+
+	_, keyID, _, err := signer.GetCurrentSigner(ctx)
+	require.NoError(t, err)
+
+	for name, update := range map[string]func(){
+		"source unavailable": func() { source.set(nil, errors.New("connection refused")) },
+		"field missing":      func() { source.set(map[string][]byte{"other": keyPEM}, nil) },
+		"invalid PEM":        func() { source.set(map[string][]byte{"key.pem": []byte("garbage")}, nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			update()
+			assert.Error(t, signer.Reload(ctx))
+
+			clk.Advance(time.Minute)
+			_, current, _, err := signer.GetCurrentSigner(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, keyID, current)
+		})
+	}
+}
+
+func TestExternalKeySigner_StartFailsWithoutKey(t *testing.T) {
+	signer, err := NewExternalKeySigner(ExternalKeySignerConfig{
+		Source: &fakeKeySource{fields: map[string][]byte{}},
+		Clock:  clock.NewFixtureClock(time.Time{}),
+	})
+	require.NoError(t, err)
+	assert.Error(t, signer.Start(context.Background()))
+}
+
+func TestParseExternalKey_Algorithm(t *testing.T) {
+	p384PEM, _ := newECKeyPEM(t, elliptic.P384())
+	rsaPEM := newRSAKeyPEM(t)
+
+	key, err := parseExternalKey(p384PEM, "")
+	require.NoError(t, err)
+	assert.Equal(t, Algorithm("ES384"), key.algorithm)
+
+	key, err = parseExternalKey(rsaPEM, "")
+	require.NoError(t, err)
+	assert.Equal(t, Algorithm("RS256"), key.algorithm)
+
+	key, err = parseExternalKey(rsaPEM, "PS256")
+	require.NoError(t, err)
+	assert.Equal(t, Algorithm("PS256"), key.algorithm)
+
+	_, err = parseExternalKey(p384PEM, "ES256")
+	assert.Error(t, err, "curve must match algorithm")
+
+	_, err = parseExternalKey(rsaPEM, "ES256")
+	assert.Error(t, err)
+}
+
+func TestKubernetesSecretKeySource(t *testing.T) {
+	api, server := newFakeKubernetesAPI(t)
+	keyPEM, _ := newECKeyPEM(t, elliptic.P256())
+	api.objects["/api/v1/namespaces/parsec/secrets/signing-key"] = map[string]any{
+		"kind":     "Secret",
+		"metadata": map[string]any{"name": "signing-key", "resourceVersion": "1"},
+		"data":     map[string]any{"key.pem": base64.StdEncoding.EncodeToString(keyPEM)},
+	}
+
+	source, err := NewKubernetesSecretKeySource(KubernetesSecretKeySourceConfig{
+		Name:       "signing-key",
+		Namespace:  "parsec",
+		APIServer:  server.URL,
+		TokenFile:  newTestTokenFile(t),
+		HTTPClient: http.DefaultClient,
+	})
+	require.NoError(t, err)
+
+	fields, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, keyPEM, fields["key.pem"])
+
+	missing, err := NewKubernetesSecretKeySource(KubernetesSecretKeySourceConfig{
+		Name:       "missing",
+		Namespace:  "parsec",
+		APIServer:  server.URL,
+		TokenFile:  newTestTokenFile(t),
+		HTTPClient: http.DefaultClient,
+	})
+	require.NoError(t, err)
+	_, err = missing.Fetch(context.Background())
+	assert.True(t, kubernetes.IsStatus(err, http.StatusNotFound))
+}
+
+func TestVaultKVKeySource(t *testing.T) {
+	keyPEM, _ := newECKeyPEM(t, elliptic.P256())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			writeVaultError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/parsec/signing":
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{
+					"data":     map[string]any{"key.pem": string(keyPEM)},
+					"metadata": map[string]any{"version": 3},
+				},
+			})
+		case "/v1/kv/parsec/signing":
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{"key.pem": string(keyPEM)},
+			})
+		default:
+			writeVaultError(w, http.StatusNotFound, "")
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	for _, tc := range []struct {
+		name      string
+		mountPath string
+		kvVersion int
+	}{
+		{"kv v2", "", 0},
+		{"kv v1", "kv", 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			source, err := NewVaultKVKeySource(VaultKVKeySourceConfig{
+				Address:   server.URL,
+				MountPath: tc.mountPath,
+				Path:      "parsec/signing",
+				KVVersion: tc.kvVersion,
+				Auth:      VaultTokenAuth{Token: "root"},
+			})
+			require.NoError(t, err)
+
+			fields, err := source.Fetch(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, keyPEM, fields["key.pem"])
+			assert.NotContains(t, fields, "metadata")
+		})
+	}
+}
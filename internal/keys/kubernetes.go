@@ -0,0 +1,335 @@
+package keys
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/alechenninger/parsec/internal/kubernetes"
+)
+
+// kubernetesSlotsDataKey is the data key of the Secret or ConfigMap holding the slots
+const kubernetesSlotsDataKey = "slots.json"
+
+// KubernetesResourceKind is the kind of Kubernetes object the slot store persists to
+type KubernetesResourceKind string
+
+const (
+	KubernetesSecret    KubernetesResourceKind = "Secret"
+	KubernetesConfigMap KubernetesResourceKind = "ConfigMap"
+)
+
+// KubernetesKeySlotStore persists key slots in a single Kubernetes Secret or ConfigMap,
+// so replicas sharing the object coordinate rotation.
+//
+// The object's resourceVersion is the store version. Updates are conditional on it,
+// so a concurrent write by another replica surfaces as ErrVersionMismatch.
+// The object is created on the first save.
+type KubernetesKeySlotStore struct {
+	kind      KubernetesResourceKind
+	name      string
+	namespace string
+	client    *kubernetes.Client
+}
+
+// KubernetesKeySlotStoreConfig configures the Kubernetes key slot store.
+// Defaults assume parsec runs in-cluster with a mounted service account.
+type KubernetesKeySlotStoreConfig struct {
+	// Kind is the object kind to store slots in (default: KubernetesSecret)
+	Kind KubernetesResourceKind
+
+	// Name is the name of the Secret or ConfigMap
+	Name string
+
+	// Namespace is the object's namespace (default: the pod's namespace)
+	Namespace string
+
+	// APIServer is the Kubernetes API server URL
+	// (default: https://$KUBERNETES_SERVICE_HOST:$KUBERNETES_SERVICE_PORT)
+	APIServer string
+
+	// TokenFile is the bearer token file, re-read on every request so
+	// projected token rotation is picked up (default: the service account token)
+	TokenFile string
+
+	// CAFile is the API server CA bundle (default: the service account CA).
+	// Ignored if HTTPClient is set.
+	CAFile string
+
+	// HTTPClient is used for API requests (default: a client trusting CAFile)
+	HTTPClient *http.Client
+}
+
+// NewKubernetesKeySlotStore creates a new Kubernetes-backed key slot store
+func NewKubernetesKeySlotStore(cfg KubernetesKeySlotStoreConfig) (*KubernetesKeySlotStore, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("kubernetes slot store requires a name")
+	}
+
+	kind := cfg.Kind
+	if kind == "" {
+		kind = KubernetesSecret
+	}
+	if kind != KubernetesSecret && kind != KubernetesConfigMap {
+		return nil, fmt.Errorf("unsupported kubernetes slot store kind: %s (supported: Secret, ConfigMap)", kind)
+	}
+
+	namespace, err := kubernetes.Namespace(cfg.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubernetes.NewClient(kubernetes.ClientConfig{
+		APIServer:  cfg.APIServer,
+		TokenFile:  cfg.TokenFile,
+		CAFile:     cfg.CAFile,
+		HTTPClient: cfg.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &KubernetesKeySlotStore{
+		kind:      kind,
+		name:      cfg.Name,
+		namespace: namespace,
+		client:    client,
+	}, nil
+}
+
+// ListSlots returns all slots and the object's resourceVersion.
+// If the object does not exist yet, there are no slots and the version is empty.
+func (s *KubernetesKeySlotStore) ListSlots(ctx context.Context) ([]*KeySlot, StoreVersion, error) {
+	obj, err := s.get(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if obj == nil {
+		return nil, "", nil
+	}
+
+	stored, err := s.decodeSlots(obj)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return fromStoredSlots(stored), resourceVersion(obj), nil
+}
+
+// SaveSlot saves a slot if the object is still at expectedVersion
+func (s *KubernetesKeySlotStore) SaveSlot(ctx context.Context, slot *KeySlot, expectedVersion StoreVersion) (StoreVersion, error) {
+	obj, err := s.get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if obj == nil {
+		if expectedVersion != "" {
+			return "", ErrVersionMismatch
+		}
+		obj = s.newObject()
+	} else if resourceVersion(obj) != expectedVersion {
+		return "", ErrVersionMismatch
+	}
+
+	stored, err := s.decodeSlots(obj)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.encodeSlots(obj, putStoredSlot(stored, slot)); err != nil {
+		return "", err
+	}
+
+	// The write is conditional: the API server rejects it with 409 Conflict if
+	// resourceVersion changed (update) or the object now exists (create)
+	var saved map[string]any
+	if expectedVersion == "" {
+		err = s.client.Send(ctx, http.MethodPost, s.collectionPath(), obj, &saved)
+	} else {
+		err = s.client.Send(ctx, http.MethodPut, s.objectPath(), obj, &saved)
+	}
+	if err != nil {
+		if kubernetes.IsStatus(err, http.StatusConflict) {
+			return "", ErrVersionMismatch
+		}
+		return "", err
+	}
+
+	return resourceVersion(saved), nil
+}
+
+// get fetches the object, returning nil if it does not exist
+func (s *KubernetesKeySlotStore) get(ctx context.Context) (map[string]any, error) {
+	var obj map[string]any
+	if err := s.client.Send(ctx, http.MethodGet, s.objectPath(), nil, &obj); err != nil {
+		if kubernetes.IsStatus(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *KubernetesKeySlotStore) newObject() map[string]any {
+	obj := map[string]any{
+		"apiVersion": "v1",
+		"kind":       string(s.kind),
+		"metadata": map[string]any{
+			"name":      s.name,
+			"namespace": s.namespace,
+			"labels": map[string]any{
+				"app.kubernetes.io/managed-by": "parsec",
+			},
+		},
+	}
+	if s.kind == KubernetesSecret {
+		obj["type"] = "Opaque"
+	}
+	return obj
+}
+
+// decodeSlots reads the slots from the object's data
+func (s *KubernetesKeySlotStore) decodeSlots(obj map[string]any) ([]storedSlot, error) {
+	data, _ := obj["data"].(map[string]any)
+	raw, _ := data[kubernetesSlotsDataKey].(string)
+	if raw == "" {
+		return nil, nil
+	}
+
+	content := []byte(raw)
+	if s.kind == KubernetesSecret {
+		var err error
+		if content, err = base64.StdEncoding.DecodeString(raw); err != nil {
+			return nil, fmt.Errorf("failed to decode %s %s: %w", s.kind, s.name, err)
+		}
+	}
+
+	var stored []storedSlot
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse slots in %s %s: %w", s.kind, s.name, err)
+	}
+	return stored, nil
+}
+
+// encodeSlots writes the slots into the object's data, leaving other keys intact
+func (s *KubernetesKeySlotStore) encodeSlots(obj map[string]any, stored []storedSlot) error {
+	content, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slots: %w", err)
+	}
+
+	data, _ := obj["data"].(map[string]any)
+	if data == nil {
+		data = make(map[string]any)
+		obj["data"] = data
+	}
+
+	if s.kind == KubernetesSecret {
+		data[kubernetesSlotsDataKey] = base64.StdEncoding.EncodeToString(content)
+	} else {
+		data[kubernetesSlotsDataKey] = string(content)
+	}
+	return nil
+}
+
+func (s *KubernetesKeySlotStore) collectionPath() string {
+	resource := "secrets"
+	if s.kind == KubernetesConfigMap {
+		resource = "configmaps"
+	}
+	return fmt.Sprintf("/api/v1/namespaces/%s/%s", s.namespace, resource)
+}
+
+func (s *KubernetesKeySlotStore) objectPath() string {
+	return s.collectionPath() + "/" + s.name
+}
+
+// resourceVersion returns the object's metadata.resourceVersion
+func resourceVersion(obj map[string]any) StoreVersion {
+	metadata, _ := obj["metadata"].(map[string]any)
+	version, _ := metadata["resourceVersion"].(string)
+	return StoreVersion(version)
+}
+
+// KubernetesSecretKeySource is an ExternalKeySource reading the data of a Kubernetes Secret
+type KubernetesSecretKeySource struct {
+	name      string
+	namespace string
+	client    *kubernetes.Client
+}
+
+// KubernetesSecretKeySourceConfig configures a KubernetesSecretKeySource.
+// Defaults assume parsec runs in-cluster with a mounted service account.
+type KubernetesSecretKeySourceConfig struct {
+	// Name is the name of the Secret
+	Name string
+
+	// Namespace is the Secret's namespace (default: the pod's namespace)
+	Namespace string
+
+	// APIServer is the Kubernetes API server URL
+	// (default: https://$KUBERNETES_SERVICE_HOST:$KUBERNETES_SERVICE_PORT)
+	APIServer string
+
+	// TokenFile is the bearer token file (default: the service account token)
+	TokenFile string
+
+	// CAFile is the API server CA bundle (default: the service account CA).
+	// Ignored if HTTPClient is set.
+	CAFile string
+
+	// HTTPClient is used for API requests (default: a client trusting CAFile)
+	HTTPClient *http.Client
+}
+
+// NewKubernetesSecretKeySource creates a new Kubernetes Secret key source
+func NewKubernetesSecretKeySource(cfg KubernetesSecretKeySourceConfig) (*KubernetesSecretKeySource, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("kubernetes secret key source requires a name")
+	}
+
+	namespace, err := kubernetes.Namespace(cfg.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubernetes.NewClient(kubernetes.ClientConfig{
+		APIServer:  cfg.APIServer,
+		TokenFile:  cfg.TokenFile,
+		CAFile:     cfg.CAFile,
+		HTTPClient: cfg.HTTPClient,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &KubernetesSecretKeySource{
+		name:      cfg.Name,
+		namespace: namespace,
+		client:    client,
+	}, nil
+}
+
+// Fetch returns the decoded data of the Secret
+func (s *KubernetesSecretKeySource) Fetch(ctx context.Context) (map[string][]byte, error) {
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	path := "/api/v1/namespaces/" + s.namespace + "/secrets/" + s.name
+	if err := s.client.Send(ctx, http.MethodGet, path, nil, &secret); err != nil {
+		return nil, fmt.Errorf("failed to read secret %s/%s: %w", s.namespace, s.name, err)
+	}
+
+	fields := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode secret %s/%s key %s: %w", s.namespace, s.name, key, err)
+		}
+		fields[key] = decoded
+	}
+	return fields, nil
+}
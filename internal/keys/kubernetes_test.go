@@ -0,0 +1,254 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKubernetesAPI stores objects and enforces resourceVersion preconditions like the API server
+type fakeKubernetesAPI struct {
+	mu      sync.Mutex
+	objects map[string]map[string]any // by request path
+	version int
+	tokens  []string
+}
+
+func newFakeKubernetesAPI(t *testing.T) (*fakeKubernetesAPI, *httptest.Server) {
+	f := &fakeKubernetesAPI{objects: make(map[string]map[string]any)}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeKubernetesAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+
+	var body map[string]any
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		obj, ok := f.objects[r.URL.Path]
+		if !ok {
+			writeKubernetesStatus(w, http.StatusNotFound, "not found")
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+
+	case http.MethodPost:
+		name := body["metadata"].(map[string]any)["name"].(string)
+		path := r.URL.Path + "/" + name
+		if _, exists := f.objects[path]; exists {
+			writeKubernetesStatus(w, http.StatusConflict, "already exists")
+			return
+		}
+		f.store(path, body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(body)
+
+	case http.MethodPut:
+		current, ok := f.objects[r.URL.Path]
+		if !ok {
+			writeKubernetesStatus(w, http.StatusNotFound, "not found")
+			return
+		}
+		if resourceVersion(body) != resourceVersion(current) {
+			writeKubernetesStatus(w, http.StatusConflict, "the object has been modified")
+			return
+		}
+		f.store(r.URL.Path, body)
+		json.NewEncoder(w).Encode(body)
+
+	default:
+		writeKubernetesStatus(w, http.StatusMethodNotAllowed, "unsupported")
+	}
+}
+
+func (f *fakeKubernetesAPI) store(path string, obj map[string]any) {
+	f.version++
+	obj["metadata"].(map[string]any)["resourceVersion"] = strconv.Itoa(f.version)
+	f.objects[path] = obj
+}
+
+// bump simulates another writer modifying the object
+func (f *fakeKubernetesAPI) bump(path string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.store(path, f.objects[path])
+}
+
+func writeKubernetesStatus(w http.ResponseWriter, code int, message string) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{"kind": "Status", "code": code, "message": message})
+}
+
+func newTestTokenFile(t *testing.T) string {
+	t.Helper()
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("sa-token\n"), 0o600))
+	return tokenFile
+}
+
+func newTestKubernetesSlotStore(t *testing.T, apiServer string, kind KubernetesResourceKind) *KubernetesKeySlotStore {
+	t.Helper()
+	store, err := NewKubernetesKeySlotStore(KubernetesKeySlotStoreConfig{
+		Kind:       kind,
+		Name:       "parsec-key-slots",
+		Namespace:  "parsec",
+		APIServer:  apiServer,
+		TokenFile:  newTestTokenFile(t),
+		HTTPClient: http.DefaultClient,
+	})
+	require.NoError(t, err)
+	return store
+}
+
+func TestKubernetesKeySlotStore(t *testing.T) {
+	for _, tc := range []struct {
+		kind KubernetesResourceKind
+		path string
+	}{
+		{KubernetesSecret, "/api/v1/namespaces/parsec/secrets/parsec-key-slots"},
+		{KubernetesConfigMap, "/api/v1/namespaces/parsec/configmaps/parsec-key-slots"},
+	} {
+		t.Run(string(tc.kind), func(t *testing.T) {
+			ctx := context.Background()
+			api, server := newFakeKubernetesAPI(t)
+			store := newTestKubernetesSlotStore(t, server.URL, tc.kind)
+
+			slots, version, err := store.ListSlots(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, slots)
+			assert.Equal(t, StoreVersion(""), version)
+
+			preparingAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+			version, err = store.SaveSlot(ctx, &KeySlot{
+				Position:      SlotPositionA,
+				Namespace:     "txn",
+				KeyProviderID: "kms",
+				PreparingAt:   &preparingAt,
+			}, version)
+			require.NoError(t, err)
+			assert.NotEmpty(t, version)
+
+			obj := api.objects[tc.path]
+			require.NotNil(t, obj, "object should be created")
+			assert.Equal(t, string(tc.kind), obj["kind"])
+
+			version, err = store.SaveSlot(ctx, &KeySlot{
+				Position:      SlotPositionB,
+				Namespace:     "txn",
+				KeyProviderID: "kms",
+			}, version)
+			require.NoError(t, err)
+
+			// Updating an existing slot replaces it
+			completedAt := preparingAt.Add(time.Minute)
+			version, err = store.SaveSlot(ctx, &KeySlot{
+				Position:            SlotPositionA,
+				Namespace:           "txn",
+				KeyProviderID:       "kms",
+				RotationCompletedAt: &completedAt,
+			}, version)
+			require.NoError(t, err)
+
+			slots, listedVersion, err := store.ListSlots(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, version, listedVersion)
+			require.Len(t, slots, 2)
+
+			byPosition := map[SlotPosition]*KeySlot{}
+			for _, slot := range slots {
+				byPosition[slot.Position] = slot
+			}
+			require.NotNil(t, byPosition[SlotPositionA].RotationCompletedAt)
+			assert.True(t, completedAt.Equal(*byPosition[SlotPositionA].RotationCompletedAt))
+			assert.Nil(t, byPosition[SlotPositionA].PreparingAt)
+			assert.Equal(t, "kms", byPosition[SlotPositionB].KeyProviderID)
+
+			assert.Equal(t, "Bearer sa-token", api.tokens[0])
+		})
+	}
+}
+
+func TestKubernetesKeySlotStore_VersionMismatch(t *testing.T) {
+	ctx := context.Background()
+	api, server := newFakeKubernetesAPI(t)
+	store := newTestKubernetesSlotStore(t, server.URL, KubernetesSecret)
+	other := newTestKubernetesSlotStore(t, server.URL, KubernetesSecret)
+	slot := &KeySlot{Position: SlotPositionA, Namespace: "txn", KeyProviderID: "kms"}
+
+	t.Run("concurrent create", func(t *testing.T) {
+		_, err := other.SaveSlot(ctx, slot, "")
+		require.NoError(t, err)
+
+		_, err = store.SaveSlot(ctx, slot, "")
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		_, version, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+
+		_, err = other.SaveSlot(ctx, slot, version)
+		require.NoError(t, err)
+
+		_, err = store.SaveSlot(ctx, slot, version)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+
+	t.Run("modified between read and write", func(t *testing.T) {
+		_, version, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+
+		// Another replica writes after we listed
+		api.bump("/api/v1/namespaces/parsec/secrets/parsec-key-slots")
+
+		_, err = store.SaveSlot(ctx, slot, version)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+}
+
+func TestKubernetesKeySlotStore_WithDualSlotSigner(t *testing.T) {
+	ctx := context.Background()
+	_, server := newFakeKubernetesAPI(t)
+
+	// Two replicas sharing the same Secret agree on the active key
+	providers := map[string]KeyProvider{"memory": NewInMemoryKeyProvider(KeyTypeECP256, "ES256")}
+	var signers []*DualSlotRotatingSigner
+	for range 2 {
+		signer := NewDualSlotRotatingSigner(DualSlotRotatingSignerConfig{
+			Namespace:           "txn",
+			TrustDomain:         "example.com",
+			KeyProviderID:       "memory",
+			KeyProviderRegistry: providers,
+			SlotStore:           newTestKubernetesSlotStore(t, server.URL, KubernetesSecret),
+		})
+		require.NoError(t, signer.Start(ctx))
+		t.Cleanup(signer.Stop)
+		signers = append(signers, signer)
+	}
+
+	_, keyID1, _, err := signers[0].GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	_, keyID2, _, err := signers[1].GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, keyID1, keyID2)
+	assert.NotEmpty(t, keyID1)
+}
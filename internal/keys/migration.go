@@ -0,0 +1,209 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// DefaultMigrationRetireAfter is how long after cutover the old signer's keys stay published by default
+const DefaultMigrationRetireAfter = 24 * time.Hour
+
+// MigrationPhase is the stage of an algorithm migration
+type MigrationPhase string
+
+const (
+	// MigrationPhaseDualPublish signs with the old signer and publishes both signers' keys,
+	// so verifiers learn the new keys before any token uses them
+	MigrationPhaseDualPublish MigrationPhase = "dual_publish"
+
+	// MigrationPhaseCutover signs with the new signer and still publishes both signers' keys,
+	// so tokens signed before cutover keep verifying until they expire
+	MigrationPhaseCutover MigrationPhase = "cutover"
+
+	// MigrationPhaseComplete signs with and publishes only the new signer's keys
+	MigrationPhaseComplete MigrationPhase = "complete"
+)
+
+// MigrationStatus describes the progress of an algorithm migration
+type MigrationStatus struct {
+	Phase         MigrationPhase
+	FromAlgorithm Algorithm
+	ToAlgorithm   Algorithm
+	CutoverAt     time.Time
+	RetireAt      time.Time
+}
+
+// AlgorithmMigrator is implemented by RotatingSigners that migrate between signing algorithms
+type AlgorithmMigrator interface {
+	// MigrationStatus returns the current phase of the migration and the algorithms involved
+	MigrationStatus(ctx context.Context) (MigrationStatus, error)
+}
+
+// AlgorithmMigrationSigner moves an issuer from one signer to another, typically
+// to change signing algorithms (e.g., RS256 to ES256), without breaking verification.
+//
+// Until CutoverAt it signs with From while publishing the keys of both signers.
+// From CutoverAt it signs with To. At CutoverAt + RetireAfter it stops publishing
+// From's keys, once tokens signed before cutover have expired.
+//
+// It does not start or stop From and To; they are owned by whoever created them
+// (typically a SignerRegistry they are also registered in).
+type AlgorithmMigrationSigner struct {
+	from      RotatingSigner
+	to        RotatingSigner
+	cutoverAt time.Time
+	retireAt  time.Time
+	clock     clock.Clock
+}
+
+// AlgorithmMigrationSignerConfig configures an AlgorithmMigrationSigner
+type AlgorithmMigrationSignerConfig struct {
+	// From is the signer being migrated away from
+	From RotatingSigner
+
+	// To is the signer being migrated to
+	To RotatingSigner
+
+	// CutoverAt is when signing switches from From to To.
+	// It should leave verifiers enough time to fetch To's keys.
+	CutoverAt time.Time
+
+	// RetireAfter is how long after cutover From's keys stay published (default: DefaultMigrationRetireAfter).
+	// It must exceed the lifetime of tokens signed by From.
+	RetireAfter time.Duration
+
+	// Clock determines the current phase (default: system clock)
+	Clock clock.Clock
+}
+
+// NewAlgorithmMigrationSigner creates a new algorithm migration signer
+func NewAlgorithmMigrationSigner(cfg AlgorithmMigrationSignerConfig) (*AlgorithmMigrationSigner, error) {
+	if cfg.From == nil || cfg.To == nil {
+		return nil, fmt.Errorf("algorithm migration requires from and to signers")
+	}
+	if cfg.CutoverAt.IsZero() {
+		return nil, fmt.Errorf("algorithm migration requires a cutover time")
+	}
+	if cfg.RetireAfter < 0 {
+		return nil, fmt.Errorf("algorithm migration retire after must not be negative")
+	}
+
+	retireAfter := cfg.RetireAfter
+	if retireAfter == 0 {
+		retireAfter = DefaultMigrationRetireAfter
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &AlgorithmMigrationSigner{
+		from:      cfg.From,
+		to:        cfg.To,
+		cutoverAt: cfg.CutoverAt,
+		retireAt:  cfg.CutoverAt.Add(retireAfter),
+		clock:     clk,
+	}, nil
+}
+
+// phase returns the migration phase at the current time
+func (s *AlgorithmMigrationSigner) phase() MigrationPhase {
+	now := s.clock.Now()
+	switch {
+	case now.Before(s.cutoverAt):
+		return MigrationPhaseDualPublish
+	case now.Before(s.retireAt):
+		return MigrationPhaseCutover
+	default:
+		return MigrationPhaseComplete
+	}
+}
+
+// signing returns the signer for the current phase
+func (s *AlgorithmMigrationSigner) signing() RotatingSigner {
+	if s.phase() == MigrationPhaseDualPublish {
+		return s.from
+	}
+	return s.to
+}
+
+// GetCurrentSigner returns From's current signer before cutover, and To's after
+func (s *AlgorithmMigrationSigner) GetCurrentSigner(ctx context.Context) (crypto.Signer, KeyID, Algorithm, error) {
+	return s.signing().GetCurrentSigner(ctx)
+}
+
+// PublicKeys returns the keys of both signers until From's keys are retired
+func (s *AlgorithmMigrationSigner) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	toKeys, err := s.to.PublicKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public keys of migration target: %w", err)
+	}
+	if s.phase() == MigrationPhaseComplete {
+		return toKeys, nil
+	}
+
+	fromKeys, err := s.from.PublicKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public keys of migration source: %w", err)
+	}
+	return slices.Concat(fromKeys, toKeys), nil
+}
+
+// Start does nothing; From and To are started by their owner
+func (s *AlgorithmMigrationSigner) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop does nothing; From and To are stopped by their owner
+func (s *AlgorithmMigrationSigner) Stop() {}
+
+// RotateNow rotates the key of the signer currently signing, if it supports rotation
+func (s *AlgorithmMigrationSigner) RotateNow(ctx context.Context) error {
+	rotator, ok := s.signing().(ManualRotator)
+	if !ok {
+		return fmt.Errorf("current signer of algorithm migration cannot rotate: %w", service.ErrKeyRotationNotSupported)
+	}
+	return rotator.RotateNow(ctx)
+}
+
+// RevokeKey revokes the key in whichever of From and To has it
+func (s *AlgorithmMigrationSigner) RevokeKey(ctx context.Context, keyID KeyID) error {
+	for _, signer := range []RotatingSigner{s.from, s.to} {
+		revoker, ok := signer.(KeyRevoker)
+		if !ok {
+			continue
+		}
+		err := revoker.RevokeKey(ctx, keyID)
+		if !errors.Is(err, ErrUnknownKeyID) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownKeyID, keyID)
+}
+
+// MigrationStatus returns the current phase of the migration and the algorithms involved
+func (s *AlgorithmMigrationSigner) MigrationStatus(ctx context.Context) (MigrationStatus, error) {
+	_, _, fromAlg, err := s.from.GetCurrentSigner(ctx)
+	if err != nil {
+		return MigrationStatus{}, fmt.Errorf("failed to get algorithm of migration source: %w", err)
+	}
+	_, _, toAlg, err := s.to.GetCurrentSigner(ctx)
+	if err != nil {
+		return MigrationStatus{}, fmt.Errorf("failed to get algorithm of migration target: %w", err)
+	}
+
+	return MigrationStatus{
+		Phase:         s.phase(),
+		FromAlgorithm: fromAlg,
+		ToAlgorithm:   toAlg,
+		CutoverAt:     s.cutoverAt,
+		RetireAt:      s.retireAt,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package keys
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+func newTestMigrationSigners(t *testing.T) (from, to RotatingSigner) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	from, err = NewStaticSigner(rsaKey, "RS256")
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	to, err = NewStaticSigner(ecKey, "ES256")
+	require.NoError(t, err)
+
+	return from, to
+}
+
+func TestAlgorithmMigrationSigner_Phases(t *testing.T) {
+	ctx := context.Background()
+	from, to := newTestMigrationSigners(t)
+
+	cutoverAt := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFixtureClock(cutoverAt.Add(-time.Hour))
+
+	signer, err := NewAlgorithmMigrationSigner(AlgorithmMigrationSignerConfig{
+		From:        from,
+		To:          to,
+		CutoverAt:   cutoverAt,
+		RetireAfter: 2 * time.Hour,
+		Clock:       clk,
+	})
+	require.NoError(t, err)
+
+	assertPhase := func(t *testing.T, phase MigrationPhase, alg Algorithm, publishedAlgs ...string) {
+		t.Helper()
+
+		_, _, signingAlg, err := signer.GetCurrentSigner(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, alg, signingAlg)
+
+		publicKeys, err := signer.PublicKeys(ctx)
+		require.NoError(t, err)
+		var algs []string
+		for _, key := range publicKeys {
+			algs = append(algs, key.Algorithm)
+		}
+		assert.ElementsMatch(t, publishedAlgs, algs)
+
+		status, err := signer.MigrationStatus(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, phase, status.Phase)
+		assert.Equal(t, Algorithm("RS256"), status.FromAlgorithm)
+		assert.Equal(t, Algorithm("ES256"), status.ToAlgorithm)
+		assert.Equal(t, cutoverAt, status.CutoverAt)
+		assert.Equal(t, cutoverAt.Add(2*time.Hour), status.RetireAt)
+	}
+
+	t.Run("before cutover signs with the old algorithm and publishes both", func(t *testing.T) {
+		assertPhase(t, MigrationPhaseDualPublish, "RS256", "RS256", "ES256")
+	})
+
+	t.Run("after cutover signs with the new algorithm and still publishes both", func(t *testing.T) {
+		clk.Advance(time.Hour)
+		assertPhase(t, MigrationPhaseCutover, "ES256", "RS256", "ES256")
+	})
+
+	t.Run("after retirement publishes only the new algorithm", func(t *testing.T) {
+		clk.Advance(2 * time.Hour)
+		assertPhase(t, MigrationPhaseComplete, "ES256", "ES256")
+	})
+}
+
+func TestAlgorithmMigrationSigner_RotateNow(t *testing.T) {
+	ctx := context.Background()
+
+	from, to := newTestMigrationSigners(t)
+	signer, err := NewAlgorithmMigrationSigner(AlgorithmMigrationSignerConfig{From: from, To: to, CutoverAt: time.Now()})
+	require.NoError(t, err)
+	assert.ErrorIs(t, signer.RotateNow(ctx), service.ErrKeyRotationNotSupported)
+
+	// Rotates whichever signer is currently signing
+	rotating, _ := newTestDualSlotRotatingSigner(t, clock.NewFixtureClock(time.Time{}), nil, nil)
+	require.NoError(t, rotating.Start(ctx))
+	defer rotating.Stop()
+
+	signer, err = NewAlgorithmMigrationSigner(AlgorithmMigrationSignerConfig{From: from, To: rotating, CutoverAt: time.Time{}.Add(-time.Hour)})
+	require.NoError(t, err)
+	require.NoError(t, signer.RotateNow(ctx))
+
+	publicKeys, err := rotating.PublicKeys(ctx)
+	require.NoError(t, err)
+	assert.Len(t, publicKeys, 2)
+}
+
+func TestNewAlgorithmMigrationSigner_Validation(t *testing.T) {
+	from, to := newTestMigrationSigners(t)
+
+	_, err := NewAlgorithmMigrationSigner(AlgorithmMigrationSignerConfig{From: from, CutoverAt: time.Now()})
+	assert.Error(t, err)
+
+	_, err = NewAlgorithmMigrationSigner(AlgorithmMigrationSignerConfig{From: from, To: to})
+	assert.Error(t, err)
+
+	_, err = NewAlgorithmMigrationSigner(AlgorithmMigrationSignerConfig{From: from, To: to, CutoverAt: time.Now(), RetireAfter: -time.Hour})
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package keys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisKeySlotKey is the default Redis key holding the key slots
+const DefaultRedisKeySlotKey = "parsec:key-slots"
+
+// redisSaveSlotsScript replaces the slots only if the version is unchanged.
+// Scripts run atomically, so this is a compare-and-swap on the version field.
+var redisSaveSlotsScript = redis.NewScript(`
+local version = redis.call('HGET', KEYS[1], 'version') or '0'
+if version ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'version', ARGV[2], 'slots', ARGV[3])
+return 1
+`)
+
+// RedisKeySlotStore persists key slots in a single Redis hash, so replicas sharing
+// the Redis server coordinate rotation.
+//
+// The hash holds the slots as JSON and a version counter. Saves are applied by a
+// script that checks the version first, so a concurrent save by another replica
+// surfaces as ErrVersionMismatch.
+type RedisKeySlotStore struct {
+	client redis.UniversalClient
+	key    string
+}
+
+// RedisKeySlotStoreConfig configures the Redis key slot store
+type RedisKeySlotStoreConfig struct {
+	// Client is the Redis client. The caller owns it.
+	Client redis.UniversalClient
+
+	// Key is the Redis key of the hash holding the slots (default: DefaultRedisKeySlotKey)
+	Key string
+}
+
+// NewRedisKeySlotStore creates a new Redis-backed key slot store
+func NewRedisKeySlotStore(cfg RedisKeySlotStoreConfig) (*RedisKeySlotStore, error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redis slot store requires a client")
+	}
+
+	key := cfg.Key
+	if key == "" {
+		key = DefaultRedisKeySlotKey
+	}
+
+	return &RedisKeySlotStore{
+		client: cfg.Client,
+		key:    key,
+	}, nil
+}
+
+// ListSlots returns all slots and the current store version.
+// The version is "0" before the first save.
+func (s *RedisKeySlotStore) ListSlots(ctx context.Context) ([]*KeySlot, StoreVersion, error) {
+	stored, version, err := s.read(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return fromStoredSlots(stored), version, nil
+}
+
+// SaveSlot saves a slot if the store is still at expectedVersion
+func (s *RedisKeySlotStore) SaveSlot(ctx context.Context, slot *KeySlot, expectedVersion StoreVersion) (StoreVersion, error) {
+	if expectedVersion == "" {
+		expectedVersion = "0"
+	}
+	expected, err := strconv.ParseInt(string(expectedVersion), 10, 64)
+	if err != nil {
+		return "", ErrVersionMismatch
+	}
+
+	stored, version, err := s.read(ctx)
+	if err != nil {
+		return "", err
+	}
+	if version != expectedVersion {
+		return "", ErrVersionMismatch
+	}
+
+	content, err := json.Marshal(putStoredSlot(stored, slot))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal slots: %w", err)
+	}
+
+	newVersion := strconv.FormatInt(expected+1, 10)
+	saved, err := redisSaveSlotsScript.Run(ctx, s.client, []string{s.key}, string(expectedVersion), newVersion, content).Int()
+	if err != nil {
+		return "", fmt.Errorf("failed to save key slots to redis: %w", err)
+	}
+	if saved == 0 {
+		return "", ErrVersionMismatch
+	}
+
+	return StoreVersion(newVersion), nil
+}
+
+// read fetches the slots and version from the hash
+func (s *RedisKeySlotStore) read(ctx context.Context) ([]storedSlot, StoreVersion, error) {
+	values, err := s.client.HMGet(ctx, s.key, "version", "slots").Result()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read key slots from redis: %w", err)
+	}
+
+	version, _ := values[0].(string)
+	if version == "" {
+		version = "0"
+	}
+
+	var stored []storedSlot
+	if raw, _ := values[1].(string); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+			return nil, "", fmt.Errorf("failed to parse slots in redis key %s: %w", s.key, err)
+		}
+	}
+
+	return stored, StoreVersion(version), nil
+}
@@ -0,0 +1,49 @@
+package keys
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisBackend(t *testing.T) (*miniredis.Miniredis, func() KeySlotStore) {
+	t.Helper()
+	server := miniredis.RunT(t)
+
+	return server, func() KeySlotStore {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		store, err := NewRedisKeySlotStore(RedisKeySlotStoreConfig{Client: client})
+		require.NoError(t, err)
+		return store
+	}
+}
+
+func TestRedisKeySlotStore(t *testing.T) {
+	testSharedKeySlotStore(t, func(t *testing.T) func() KeySlotStore {
+		_, newStore := newTestRedisBackend(t)
+		return newStore
+	})
+}
+
+func TestRedisSaveSlotsScript(t *testing.T) {
+	// The script is what makes saves atomic: it must refuse to write over a newer version
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	saved, err := redisSaveSlotsScript.Run(ctx, client, []string{"slots"}, "0", "1", "[]").Int()
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved)
+
+	saved, err = redisSaveSlotsScript.Run(ctx, client, []string{"slots"}, "0", "1", `[{"position":"A"}]`).Int()
+	require.NoError(t, err)
+	assert.Equal(t, 0, saved)
+	assert.Equal(t, "[]", server.HGet("slots", "slots"))
+}
@@ -3,6 +3,7 @@ package keys
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -45,6 +46,19 @@ func (r *SignerRegistry) Get(id string) (RotatingSigner, error) {
 	return signer, nil
 }
 
+// IDs returns the IDs of all registered signers, sorted
+func (r *SignerRegistry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.signers))
+	for id := range r.signers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // Start starts all registered signers
 func (r *SignerRegistry) Start(ctx context.Context) error {
 	r.mu.RLock()
@@ -3,7 +3,12 @@ package keys
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"errors"
+	"fmt"
 
 	"github.com/alechenninger/parsec/internal/service"
 )
@@ -11,6 +16,15 @@ import (
 var (
 	// ErrKeyMismatch is returned when the key used for signing does not match the expected key ID
 	ErrKeyMismatch = errors.New("key mismatch during signing")
+
+	// ErrRotationInProgress is returned when a rotation cannot start because another is in progress
+	ErrRotationInProgress = errors.New("key rotation already in progress")
+
+	// ErrKeyRevoked is returned when the only key available to sign has been revoked
+	ErrKeyRevoked = errors.New("signing key revoked")
+
+	// ErrUnknownKeyID is returned when revoking a key ID the signer does not have
+	ErrUnknownKeyID = errors.New("unknown key id")
 )
 
 // KeyID is a unique identifier for a cryptographic key
@@ -61,6 +75,21 @@ type RotatingSigner interface {
 	Stop()
 }
 
+// ManualRotator is implemented by RotatingSigners that can rotate on demand,
+// such as in response to a key compromise.
+type ManualRotator interface {
+	// RotateNow generates a new key immediately, regardless of the current key's age.
+	RotateNow(ctx context.Context) error
+}
+
+// KeyRevoker is implemented by RotatingSigners that can revoke a key before it expires,
+// such as when it has been exposed.
+type KeyRevoker interface {
+	// RevokeKey removes the key from PublicKeys and stops signing with it.
+	// Returns ErrUnknownKeyID if the signer has no key with that ID.
+	RevokeKey(ctx context.Context, keyID KeyID) error
+}
+
 // KeyProvider manages creating/retrieving KeyHandles.
 type KeyProvider interface {
 	// GetKeyHandle returns a handle for a specific trust domain, namespace, and key name.
@@ -78,3 +107,48 @@ const (
 	KeyTypeRSA2048 KeyType = "RSA-2048"
 	KeyTypeRSA4096 KeyType = "RSA-4096"
 )
+
+// checkAlgorithmForKeyType verifies algorithm can be used with keys of keyType.
+// RSA keys support both PKCS#1 v1.5 (RS*) and RSASSA-PSS (PS*) algorithms.
+func checkAlgorithmForKeyType(algorithm string, keyType KeyType) error {
+	var ok bool
+	switch keyType {
+	case KeyTypeECP256:
+		ok = algorithm == "ES256"
+	case KeyTypeECP384:
+		ok = algorithm == "ES384"
+	case KeyTypeRSA2048, KeyTypeRSA4096:
+		switch algorithm {
+		case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+			ok = true
+		}
+	default:
+		return fmt.Errorf("unsupported key type: %s", keyType)
+	}
+	if !ok {
+		return fmt.Errorf("algorithm %s cannot be used with %s keys", algorithm, keyType)
+	}
+	return nil
+}
+
+// GenerateKey generates a private key of keyType
+func GenerateKey(keyType KeyType) (crypto.Signer, error) {
+	var signer crypto.Signer
+	var err error
+	switch keyType {
+	case KeyTypeECP256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyTypeECP384:
+		signer, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyTypeRSA2048:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case KeyTypeRSA4096:
+		signer, err = rsa.GenerateKey(rand.Reader, 4096)
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return signer, nil
+}
@@ -0,0 +1,42 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKey(t *testing.T) {
+	tests := []struct {
+		keyType KeyType
+		check   func(t *testing.T, key any)
+	}{
+		{KeyTypeECP256, func(t *testing.T, key any) {
+			require.IsType(t, &ecdsa.PrivateKey{}, key)
+			assert.Equal(t, "P-256", key.(*ecdsa.PrivateKey).Curve.Params().Name)
+		}},
+		{KeyTypeECP384, func(t *testing.T, key any) {
+			require.IsType(t, &ecdsa.PrivateKey{}, key)
+			assert.Equal(t, "P-384", key.(*ecdsa.PrivateKey).Curve.Params().Name)
+		}},
+		{KeyTypeRSA2048, func(t *testing.T, key any) {
+			require.IsType(t, &rsa.PrivateKey{}, key)
+			assert.Equal(t, 2048, key.(*rsa.PrivateKey).N.BitLen())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.keyType), func(t *testing.T) {
+			key, err := GenerateKey(tt.keyType)
+			require.NoError(t, err)
+			tt.check(t, key)
+		})
+	}
+
+	t.Run("rejects unknown key types", func(t *testing.T) {
+		_, err := GenerateKey("EC-P521")
+		assert.Error(t, err)
+	})
+}
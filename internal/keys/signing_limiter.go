@@ -0,0 +1,168 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrSigningSaturated is returned (wrapped) by signers limited by a SigningLimiter when
+// every slot is busy and the queue is full, or a signature waited in the queue too long
+var ErrSigningSaturated = errors.New("signing saturated")
+
+// SigningLimiterConfig configures a SigningLimiter
+type SigningLimiterConfig struct {
+	// MaxConcurrent is how many signatures may be in progress at once
+	MaxConcurrent int
+
+	// MaxQueued is how many signatures may wait for a free slot; once as many wait,
+	// further signatures fail immediately. Zero fails signatures as soon as every slot is busy.
+	MaxQueued int
+
+	// QueueTimeout, if positive, is how long a signature waits for a free slot before
+	// failing. Otherwise it waits as long as the signer's context allows.
+	QueueTimeout time.Duration
+}
+
+// SigningLimiter bounds the signatures in progress at once, such as to stay within
+// a KMS's request quota, queuing a limited number beyond that
+type SigningLimiter struct {
+	slots        chan struct{}
+	maxQueued    int
+	queueTimeout time.Duration
+
+	mu       sync.Mutex
+	queued   int
+	signed   int64
+	rejected int64
+	timedOut int64
+}
+
+// SigningLimiterStats describes a SigningLimiter's load
+type SigningLimiterStats struct {
+	MaxConcurrent int
+	MaxQueued     int
+
+	// InFlight and Queued are the signatures in progress and waiting now
+	InFlight int
+	Queued   int
+
+	// Signed counts the signatures that got a slot
+	Signed int64
+
+	// Rejected counts the signatures that failed because the queue was full
+	Rejected int64
+
+	// TimedOut counts the signatures that failed while waiting in the queue
+	TimedOut int64
+}
+
+// SigningLimitReporter is implemented by RotatingSigners whose signatures may be limited
+type SigningLimitReporter interface {
+	// SigningLimiter returns the signer's limiter, or nil if its signatures are not limited
+	SigningLimiter() *SigningLimiter
+}
+
+// NewSigningLimiter creates a new signing limiter
+func NewSigningLimiter(cfg SigningLimiterConfig) (*SigningLimiter, error) {
+	if cfg.MaxConcurrent <= 0 {
+		return nil, fmt.Errorf("max concurrent signatures must be positive")
+	}
+	if cfg.MaxQueued < 0 {
+		return nil, fmt.Errorf("max queued signatures must not be negative")
+	}
+	return &SigningLimiter{
+		slots:        make(chan struct{}, cfg.MaxConcurrent),
+		maxQueued:    cfg.MaxQueued,
+		queueTimeout: cfg.QueueTimeout,
+	}, nil
+}
+
+// Wrap returns a signer whose signatures each take a slot of the limiter
+// Waiting for a slot ends when ctx, the context the signer is bound to, is done.
+func (l *SigningLimiter) Wrap(ctx context.Context, signer crypto.Signer) crypto.Signer {
+	return &limitedSigner{Signer: signer, ctx: ctx, limiter: l}
+}
+
+// Stats returns the limiter's current load and counts
+func (l *SigningLimiter) Stats() SigningLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return SigningLimiterStats{
+		MaxConcurrent: cap(l.slots),
+		MaxQueued:     l.maxQueued,
+		InFlight:      len(l.slots),
+		Queued:        l.queued,
+		Signed:        l.signed,
+		Rejected:      l.rejected,
+		TimedOut:      l.timedOut,
+	}
+}
+
+// acquire takes a slot, waiting in the queue if there is room, and returns a func
+// to release it
+func (l *SigningLimiter) acquire(ctx context.Context) (func(), error) {
+	release := func() { <-l.slots }
+
+	l.mu.Lock()
+	select {
+	case l.slots <- struct{}{}:
+		l.signed++
+		l.mu.Unlock()
+		return release, nil
+	default:
+	}
+	if l.queued >= l.maxQueued {
+		l.rejected++
+		l.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d signatures in progress and %d queued", ErrSigningSaturated, cap(l.slots), l.queued)
+	}
+	l.queued++
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case l.slots <- struct{}{}:
+	case <-timeout:
+		err = fmt.Errorf("%w: no signing slot free after %s", ErrSigningSaturated, l.queueTimeout)
+	case <-ctx.Done():
+		err = fmt.Errorf("%w: %w", ErrSigningSaturated, ctx.Err())
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queued--
+	if err != nil {
+		l.timedOut++
+		return nil, err
+	}
+	l.signed++
+	return release, nil
+}
+
+// limitedSigner is a crypto.Signer that signs only with a slot of its limiter
+type limitedSigner struct {
+	crypto.Signer
+	ctx     context.Context
+	limiter *SigningLimiter
+}
+
+func (s *limitedSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	release, err := s.limiter.acquire(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return s.Signer.Sign(rand, digest, opts)
+}
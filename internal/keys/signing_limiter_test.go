@@ -0,0 +1,105 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingSigner signs once release is closed, reporting each signature started
+type blockingSigner struct {
+	crypto.Signer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+func TestSigningLimiter(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("payload"))
+
+	// occupy starts a signature that holds a slot until release is closed
+	occupy := func(t *testing.T, limiter *SigningLimiter) (release chan struct{}, done chan error) {
+		t.Helper()
+		blocking := &blockingSigner{Signer: key, started: make(chan struct{}), release: make(chan struct{})}
+		done = make(chan error, 1)
+		go func() {
+			_, err := limiter.Wrap(context.Background(), blocking).Sign(rand.Reader, digest[:], crypto.SHA256)
+			done <- err
+		}()
+		<-blocking.started
+		return blocking.release, done
+	}
+
+	t.Run("fails fast once slots and queue are full", func(t *testing.T) {
+		limiter, err := NewSigningLimiter(SigningLimiterConfig{MaxConcurrent: 1})
+		require.NoError(t, err)
+		release, done := occupy(t, limiter)
+
+		_, err = limiter.Wrap(context.Background(), key).Sign(rand.Reader, digest[:], crypto.SHA256)
+		assert.True(t, errors.Is(err, ErrSigningSaturated), "expected ErrSigningSaturated, got %v", err)
+
+		close(release)
+		require.NoError(t, <-done)
+		_, err = limiter.Wrap(context.Background(), key).Sign(rand.Reader, digest[:], crypto.SHA256)
+		assert.NoError(t, err, "expected the freed slot to be used")
+
+		stats := limiter.Stats()
+		assert.Equal(t, int64(2), stats.Signed)
+		assert.Equal(t, int64(1), stats.Rejected)
+		assert.Equal(t, 0, stats.InFlight)
+	})
+
+	t.Run("queued signatures wait for a free slot", func(t *testing.T) {
+		limiter, err := NewSigningLimiter(SigningLimiterConfig{MaxConcurrent: 1, MaxQueued: 1})
+		require.NoError(t, err)
+		release, done := occupy(t, limiter)
+
+		queued := make(chan error, 1)
+		go func() {
+			_, err := limiter.Wrap(context.Background(), key).Sign(rand.Reader, digest[:], crypto.SHA256)
+			queued <- err
+		}()
+		require.Eventually(t, func() bool { return limiter.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+		close(release)
+		require.NoError(t, <-done)
+		assert.NoError(t, <-queued)
+		assert.Equal(t, 0, limiter.Stats().Queued)
+	})
+
+	t.Run("queued signatures time out", func(t *testing.T) {
+		limiter, err := NewSigningLimiter(SigningLimiterConfig{MaxConcurrent: 1, MaxQueued: 1, QueueTimeout: 10 * time.Millisecond})
+		require.NoError(t, err)
+		release, done := occupy(t, limiter)
+		defer func() {
+			close(release)
+			<-done
+		}()
+
+		_, err = limiter.Wrap(context.Background(), key).Sign(rand.Reader, digest[:], crypto.SHA256)
+		assert.True(t, errors.Is(err, ErrSigningSaturated), "expected ErrSigningSaturated, got %v", err)
+		assert.Equal(t, int64(1), limiter.Stats().TimedOut)
+	})
+
+	t.Run("requires a positive limit", func(t *testing.T) {
+		_, err := NewSigningLimiter(SigningLimiterConfig{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,251 @@
+package keys
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLDialect identifies the SQL flavor spoken by a database
+type SQLDialect string
+
+const (
+	SQLDialectPostgres SQLDialect = "postgres"
+	SQLDialectMySQL    SQLDialect = "mysql"
+	SQLDialectSQLite   SQLDialect = "sqlite"
+)
+
+// DefaultSQLKeySlotTable is the default name of the key slots table
+const DefaultSQLKeySlotTable = "parsec_key_slots"
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLKeySlotStore persists key slots in a SQL database, so replicas sharing
+// the database coordinate rotation.
+//
+// Slots are rows of one table. The store version is a counter in a single-row
+// companion table (<table>_version): each save increments it only if it still
+// holds the expected value, within the same transaction as the slot write,
+// so a concurrent save by another replica surfaces as ErrVersionMismatch.
+type SQLKeySlotStore struct {
+	db           *sql.DB
+	dialect      SQLDialect
+	table        string
+	versionTable string
+}
+
+// SQLKeySlotStoreConfig configures the SQL key slot store
+type SQLKeySlotStoreConfig struct {
+	// DB is the database handle. The caller owns it and must register the driver.
+	DB *sql.DB
+
+	// Dialect is the database's SQL flavor
+	Dialect SQLDialect
+
+	// Table is the slots table name (default: DefaultSQLKeySlotTable).
+	// The version table is named by appending "_version".
+	Table string
+}
+
+// NewSQLKeySlotStore creates a new SQL-backed key slot store
+func NewSQLKeySlotStore(cfg SQLKeySlotStoreConfig) (*SQLKeySlotStore, error) {
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("sql slot store requires a database")
+	}
+
+	switch cfg.Dialect {
+	case SQLDialectPostgres, SQLDialectMySQL, SQLDialectSQLite:
+	default:
+		return nil, fmt.Errorf("unsupported sql dialect: %s (supported: postgres, mysql, sqlite)", cfg.Dialect)
+	}
+
+	table := cfg.Table
+	if table == "" {
+		table = DefaultSQLKeySlotTable
+	}
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid sql slot store table name: %q", table)
+	}
+
+	return &SQLKeySlotStore{
+		db:           cfg.DB,
+		dialect:      cfg.Dialect,
+		table:        table,
+		versionTable: table + "_version",
+	}, nil
+}
+
+// CreateTables creates the slot and version tables if they do not exist
+func (s *SQLKeySlotStore) CreateTables(ctx context.Context) error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+			namespace VARCHAR(255) NOT NULL,
+			key_provider_id VARCHAR(255) NOT NULL,
+			slot_position VARCHAR(8) NOT NULL,
+			preparing_at BIGINT NULL,
+			rotation_completed_at BIGINT NULL,
+			revoked_at BIGINT NULL,
+			PRIMARY KEY (namespace, key_provider_id, slot_position)
+		)`,
+		`CREATE TABLE IF NOT EXISTS ` + s.versionTable + ` (
+			id INTEGER NOT NULL PRIMARY KEY,
+			version BIGINT NOT NULL
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create key slot tables: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListSlots returns all slots and the current store version.
+// The version is "0" before the first save.
+func (s *SQLKeySlotStore) ListSlots(ctx context.Context) ([]*KeySlot, StoreVersion, error) {
+	// Read the version before the slots. A save committed in between makes the
+	// version stale rather than the slots, so the next save fails safely.
+	version, err := s.currentVersion(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT namespace, key_provider_id, slot_position, preparing_at, rotation_completed_at, revoked_at FROM `+s.table)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list key slots: %w", err)
+	}
+	defer rows.Close()
+
+	var slots []*KeySlot
+	for rows.Next() {
+		var slot KeySlot
+		var position string
+		var preparingAt, completedAt, revokedAt sql.NullInt64
+		if err := rows.Scan(&slot.Namespace, &slot.KeyProviderID, &position, &preparingAt, &completedAt, &revokedAt); err != nil {
+			return nil, "", fmt.Errorf("failed to read key slot: %w", err)
+		}
+		slot.Position = SlotPosition(position)
+		slot.PreparingAt = timeFromSQL(preparingAt)
+		slot.RotationCompletedAt = timeFromSQL(completedAt)
+		slot.RevokedAt = timeFromSQL(revokedAt)
+		slots = append(slots, &slot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to list key slots: %w", err)
+	}
+
+	return slots, StoreVersion(strconv.FormatInt(version, 10)), nil
+}
+
+// SaveSlot saves a slot if the store is still at expectedVersion
+func (s *SQLKeySlotStore) SaveSlot(ctx context.Context, slot *KeySlot, expectedVersion StoreVersion) (StoreVersion, error) {
+	expected, err := strconv.ParseInt(string(expectedVersion), 10, 64)
+	if expectedVersion == "" {
+		expected, err = 0, nil
+	}
+	if err != nil {
+		return "", ErrVersionMismatch
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Claim the next version first: the conditional update locks the version row,
+	// so concurrent saves serialize here and all but one see a mismatch
+	result, err := tx.ExecContext(ctx,
+		s.bind(`UPDATE `+s.versionTable+` SET version = ? WHERE id = 1 AND version = ?`),
+		expected+1, expected)
+	if err != nil {
+		return "", fmt.Errorf("failed to update key slot store version: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("failed to update key slot store version: %w", err)
+	}
+	if updated == 0 {
+		if expected != 0 {
+			return "", ErrVersionMismatch
+		}
+		// First save: the version row does not exist yet. If another replica
+		// inserts it concurrently, the primary key rejects one of the inserts.
+		if _, err := tx.ExecContext(ctx,
+			s.bind(`INSERT INTO `+s.versionTable+` (id, version) VALUES (1, ?)`), expected+1); err != nil {
+			if version, vErr := s.currentVersion(ctx); vErr == nil && version != 0 {
+				return "", ErrVersionMismatch
+			}
+			return "", fmt.Errorf("failed to initialize key slot store version: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		s.bind(`DELETE FROM `+s.table+` WHERE namespace = ? AND key_provider_id = ? AND slot_position = ?`),
+		slot.Namespace, slot.KeyProviderID, string(slot.Position)); err != nil {
+		return "", fmt.Errorf("failed to save key slot: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		s.bind(`INSERT INTO `+s.table+` (namespace, key_provider_id, slot_position, preparing_at, rotation_completed_at, revoked_at) VALUES (?, ?, ?, ?, ?, ?)`),
+		slot.Namespace, slot.KeyProviderID, string(slot.Position), timeToSQL(slot.PreparingAt), timeToSQL(slot.RotationCompletedAt), timeToSQL(slot.RevokedAt)); err != nil {
+		return "", fmt.Errorf("failed to save key slot: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit key slot: %w", err)
+	}
+
+	return StoreVersion(strconv.FormatInt(expected+1, 10)), nil
+}
+
+// currentVersion reads the store version, which is 0 if the version row does not exist
+func (s *SQLKeySlotStore) currentVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := s.db.QueryRowContext(ctx, `SELECT version FROM `+s.versionTable+` WHERE id = 1`).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read key slot store version: %w", err)
+	}
+	return version, nil
+}
+
+// bind rewrites "?" placeholders for the store's dialect
+func (s *SQLKeySlotStore) bind(query string) string {
+	if s.dialect != SQLDialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func timeToSQL(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+func timeFromSQL(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(0, v.Int64).UTC()
+	return &t
+}
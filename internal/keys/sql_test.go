@@ -0,0 +1,162 @@
+package keys
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func newTestSQLDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "parsec.db")+"?_pragma=busy_timeout(5000)")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestSQLSlotStore(t *testing.T, db *sql.DB) *SQLKeySlotStore {
+	t.Helper()
+	store, err := NewSQLKeySlotStore(SQLKeySlotStoreConfig{DB: db, Dialect: SQLDialectSQLite})
+	require.NoError(t, err)
+	require.NoError(t, store.CreateTables(context.Background()))
+	return store
+}
+
+func TestSQLKeySlotStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLSlotStore(t, newTestSQLDB(t))
+
+	slots, version, err := store.ListSlots(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, slots)
+	assert.Equal(t, StoreVersion("0"), version)
+
+	preparingAt := time.Date(2025, 1, 1, 12, 0, 0, 123, time.UTC)
+	version, err = store.SaveSlot(ctx, &KeySlot{
+		Position:      SlotPositionA,
+		Namespace:     "txn",
+		KeyProviderID: "kms",
+		PreparingAt:   &preparingAt,
+	}, version)
+	require.NoError(t, err)
+	assert.Equal(t, StoreVersion("1"), version)
+
+	version, err = store.SaveSlot(ctx, &KeySlot{
+		Position:      SlotPositionB,
+		Namespace:     "txn",
+		KeyProviderID: "kms",
+	}, version)
+	require.NoError(t, err)
+
+	// Updating an existing slot replaces it
+	completedAt := preparingAt.Add(time.Minute)
+	revokedAt := completedAt.Add(time.Minute)
+	version, err = store.SaveSlot(ctx, &KeySlot{
+		Position:            SlotPositionA,
+		Namespace:           "txn",
+		KeyProviderID:       "kms",
+		RotationCompletedAt: &completedAt,
+		RevokedAt:           &revokedAt,
+	}, version)
+	require.NoError(t, err)
+
+	slots, listedVersion, err := store.ListSlots(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, version, listedVersion)
+	require.Len(t, slots, 2)
+
+	byPosition := map[SlotPosition]*KeySlot{}
+	for _, slot := range slots {
+		byPosition[slot.Position] = slot
+	}
+	require.NotNil(t, byPosition[SlotPositionA].RotationCompletedAt)
+	assert.True(t, completedAt.Equal(*byPosition[SlotPositionA].RotationCompletedAt))
+	assert.Nil(t, byPosition[SlotPositionA].PreparingAt)
+	require.NotNil(t, byPosition[SlotPositionA].RevokedAt)
+	assert.True(t, revokedAt.Equal(*byPosition[SlotPositionA].RevokedAt))
+	assert.Equal(t, "kms", byPosition[SlotPositionB].KeyProviderID)
+	assert.Nil(t, byPosition[SlotPositionB].RevokedAt)
+}
+
+func TestSQLKeySlotStore_VersionMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLDB(t)
+	store := newTestSQLSlotStore(t, db)
+	other := newTestSQLSlotStore(t, db)
+	slot := &KeySlot{Position: SlotPositionA, Namespace: "txn", KeyProviderID: "kms"}
+
+	t.Run("concurrent first save", func(t *testing.T) {
+		_, err := other.SaveSlot(ctx, slot, "0")
+		require.NoError(t, err)
+
+		_, err = store.SaveSlot(ctx, slot, "0")
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		_, version, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+
+		_, err = other.SaveSlot(ctx, slot, version)
+		require.NoError(t, err)
+
+		_, err = store.SaveSlot(ctx, slot, version)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+
+	t.Run("failed save leaves slots unchanged", func(t *testing.T) {
+		before, version, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+
+		_, err = store.SaveSlot(ctx, &KeySlot{Position: SlotPositionB, Namespace: "txn", KeyProviderID: "kms"}, "1")
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+
+		after, versionAfter, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, version, versionAfter)
+		assert.Len(t, after, len(before))
+	})
+}
+
+func TestSQLKeySlotStore_WithDualSlotSigner(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLDB(t)
+
+	// Two replicas sharing the same database agree on the active key
+	providers := map[string]KeyProvider{"memory": NewInMemoryKeyProvider(KeyTypeECP256, "ES256")}
+	var signers []*DualSlotRotatingSigner
+	for range 2 {
+		signer := NewDualSlotRotatingSigner(DualSlotRotatingSignerConfig{
+			Namespace:           "txn",
+			TrustDomain:         "example.com",
+			KeyProviderID:       "memory",
+			KeyProviderRegistry: providers,
+			SlotStore:           newTestSQLSlotStore(t, db),
+		})
+		require.NoError(t, signer.Start(ctx))
+		t.Cleanup(signer.Stop)
+		signers = append(signers, signer)
+	}
+
+	_, keyID1, _, err := signers[0].GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	_, keyID2, _, err := signers[1].GetCurrentSigner(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, keyID1, keyID2)
+	assert.NotEmpty(t, keyID1)
+}
+
+func TestSQLKeySlotStore_Bind(t *testing.T) {
+	store, err := NewSQLKeySlotStore(SQLKeySlotStoreConfig{DB: &sql.DB{}, Dialect: SQLDialectPostgres})
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE t SET v = $1 WHERE v = $2", store.bind("UPDATE t SET v = ? WHERE v = ?"))
+
+	_, err = NewSQLKeySlotStore(SQLKeySlotStoreConfig{DB: &sql.DB{}, Dialect: SQLDialectPostgres, Table: "slots; DROP TABLE x"})
+	assert.Error(t, err)
+}
@@ -31,6 +31,38 @@ type KeySlot struct {
 	KeyProviderID       string       // Which KeyProvider created this key
 	PreparingAt         *time.Time   // When "preparing" state started (nil = not preparing)
 	RotationCompletedAt *time.Time   // When rotation completed (for grace period)
+	RevokedAt           *time.Time   // When the slot's key was revoked (nil = not revoked; cleared by the next rotation)
+}
+
+// SlotState describes a signer's key slot, for troubleshooting
+type SlotState struct {
+	Position      SlotPosition
+	KeyProviderID string
+
+	// KeyID and Algorithm are of the slot's current key; empty if it could not be read
+	KeyID     KeyID
+	Algorithm Algorithm
+
+	// Active is whether the slot's key is the one signing
+	Active bool
+
+	PreparingAt         *time.Time
+	RotationCompletedAt *time.Time
+	RevokedAt           *time.Time
+
+	// UsableAt is when the key's grace period ends, and ExpiresAt when it is no longer
+	// published; both are nil if the slot has never completed a rotation
+	UsableAt  *time.Time
+	ExpiresAt *time.Time
+
+	// Error is why the slot's key could not be read
+	Error string
+}
+
+// SlotReporter is implemented by RotatingSigners that keep their keys in slots
+type SlotReporter interface {
+	// SlotStates returns the state of each of the signer's slots, as stored
+	SlotStates(ctx context.Context) ([]SlotState, error)
 }
 
 // KeySlotStore is an interface for persisting key slots with concurrency control
@@ -117,5 +149,56 @@ func (s *InMemoryKeySlotStore) copySlot(slot *KeySlot) *KeySlot {
 		copy.RotationCompletedAt = &t
 	}
 
+	if slot.RevokedAt != nil {
+		t := *slot.RevokedAt
+		copy.RevokedAt = &t
+	}
+
 	return copy
 }
+
+// storedSlot is the JSON form of a KeySlot, used by stores that persist all slots as one document
+type storedSlot struct {
+	Position            SlotPosition `json:"position"`
+	Namespace           string       `json:"namespace"`
+	KeyProviderID       string       `json:"key_provider_id"`
+	PreparingAt         *time.Time   `json:"preparing_at,omitempty"`
+	RotationCompletedAt *time.Time   `json:"rotation_completed_at,omitempty"`
+	RevokedAt           *time.Time   `json:"revoked_at,omitempty"`
+}
+
+// fromStoredSlots converts stored slots to KeySlots
+func fromStoredSlots(stored []storedSlot) []*KeySlot {
+	slots := make([]*KeySlot, 0, len(stored))
+	for _, st := range stored {
+		slots = append(slots, &KeySlot{
+			Position:            st.Position,
+			Namespace:           st.Namespace,
+			KeyProviderID:       st.KeyProviderID,
+			PreparingAt:         st.PreparingAt,
+			RotationCompletedAt: st.RotationCompletedAt,
+			RevokedAt:           st.RevokedAt,
+		})
+	}
+	return slots
+}
+
+// putStoredSlot replaces the stored slot with the same position, namespace, and
+// key provider as slot, or appends it if there is none
+func putStoredSlot(stored []storedSlot, slot *KeySlot) []storedSlot {
+	updated := storedSlot{
+		Position:            slot.Position,
+		Namespace:           slot.Namespace,
+		KeyProviderID:       slot.KeyProviderID,
+		PreparingAt:         slot.PreparingAt,
+		RotationCompletedAt: slot.RotationCompletedAt,
+		RevokedAt:           slot.RevokedAt,
+	}
+	for i, st := range stored {
+		if st.Position == slot.Position && st.Namespace == slot.Namespace && st.KeyProviderID == slot.KeyProviderID {
+			stored[i] = updated
+			return stored
+		}
+	}
+	return append(stored, updated)
+}
@@ -0,0 +1,115 @@
+package keys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSharedKeySlotStore checks a KeySlotStore implementation's round-tripping and
+// compare-and-swap behavior. newBackend starts an empty backend and returns a
+// constructor for stores sharing it, like replicas do.
+func testSharedKeySlotStore(t *testing.T, newBackend func(t *testing.T) func() KeySlotStore) {
+	t.Run("round trip", func(t *testing.T) {
+		ctx := context.Background()
+		store := newBackend(t)()
+
+		slots, version, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, slots)
+
+		preparingAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		version, err = store.SaveSlot(ctx, &KeySlot{
+			Position:      SlotPositionA,
+			Namespace:     "txn",
+			KeyProviderID: "kms",
+			PreparingAt:   &preparingAt,
+		}, version)
+		require.NoError(t, err)
+
+		version, err = store.SaveSlot(ctx, &KeySlot{
+			Position:      SlotPositionB,
+			Namespace:     "txn",
+			KeyProviderID: "kms",
+		}, version)
+		require.NoError(t, err)
+
+		// Updating an existing slot replaces it
+		completedAt := preparingAt.Add(time.Minute)
+		version, err = store.SaveSlot(ctx, &KeySlot{
+			Position:            SlotPositionA,
+			Namespace:           "txn",
+			KeyProviderID:       "kms",
+			RotationCompletedAt: &completedAt,
+		}, version)
+		require.NoError(t, err)
+
+		slots, listedVersion, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, version, listedVersion)
+		require.Len(t, slots, 2)
+
+		byPosition := map[SlotPosition]*KeySlot{}
+		for _, slot := range slots {
+			byPosition[slot.Position] = slot
+		}
+		require.NotNil(t, byPosition[SlotPositionA].RotationCompletedAt)
+		assert.True(t, completedAt.Equal(*byPosition[SlotPositionA].RotationCompletedAt))
+		assert.Nil(t, byPosition[SlotPositionA].PreparingAt)
+		assert.Equal(t, "kms", byPosition[SlotPositionB].KeyProviderID)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		ctx := context.Background()
+		newStore := newBackend(t)
+		store, other := newStore(), newStore()
+		slot := &KeySlot{Position: SlotPositionA, Namespace: "txn", KeyProviderID: "kms"}
+
+		_, initial, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+
+		// Concurrent first save
+		_, err = other.SaveSlot(ctx, slot, initial)
+		require.NoError(t, err)
+		_, err = store.SaveSlot(ctx, slot, initial)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+
+		// Stale version
+		_, version, err := store.ListSlots(ctx)
+		require.NoError(t, err)
+		_, err = other.SaveSlot(ctx, slot, version)
+		require.NoError(t, err)
+		_, err = store.SaveSlot(ctx, slot, version)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+
+	t.Run("with dual slot signers", func(t *testing.T) {
+		ctx := context.Background()
+		newStore := newBackend(t)
+
+		// Two replicas sharing the backend agree on the active key
+		providers := map[string]KeyProvider{"memory": NewInMemoryKeyProvider(KeyTypeECP256, "ES256")}
+		var keyIDs []KeyID
+		for range 2 {
+			signer := NewDualSlotRotatingSigner(DualSlotRotatingSignerConfig{
+				Namespace:           "txn",
+				TrustDomain:         "example.com",
+				KeyProviderID:       "memory",
+				KeyProviderRegistry: providers,
+				SlotStore:           newStore(),
+			})
+			require.NoError(t, signer.Start(ctx))
+			t.Cleanup(signer.Stop)
+
+			_, keyID, _, err := signer.GetCurrentSigner(ctx)
+			require.NoError(t, err)
+			keyIDs = append(keyIDs, keyID)
+		}
+
+		assert.NotEmpty(t, keyIDs[0])
+		assert.Equal(t, keyIDs[0], keyIDs[1])
+	})
+}
@@ -0,0 +1,64 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// StaticSigner is a RotatingSigner that always signs with a single, fixed key.
+// It never rotates. It is intended for tests and replay harnesses where issued
+// tokens must be reproducible: with a deterministic algorithm (RS256/384/512)
+// the same claims always produce byte-identical tokens.
+type StaticSigner struct {
+	signer    crypto.Signer
+	keyID     KeyID
+	algorithm Algorithm
+}
+
+// NewStaticSigner creates a signer for the given key.
+// The key ID is the RFC 7638 thumbprint of the public key, as with rotating signers.
+func NewStaticSigner(signer crypto.Signer, algorithm Algorithm) (*StaticSigner, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("signer is required")
+	}
+	if algorithm == "" {
+		return nil, fmt.Errorf("algorithm is required")
+	}
+
+	thumbprint, err := ComputeThumbprint(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute key ID: %w", err)
+	}
+
+	return &StaticSigner{
+		signer:    signer,
+		keyID:     KeyID(thumbprint),
+		algorithm: algorithm,
+	}, nil
+}
+
+// GetCurrentSigner returns the static key
+func (s *StaticSigner) GetCurrentSigner(ctx context.Context) (crypto.Signer, KeyID, Algorithm, error) {
+	return s.signer, s.keyID, s.algorithm, nil
+}
+
+// PublicKeys returns the static key's public key
+func (s *StaticSigner) PublicKeys(ctx context.Context) ([]service.PublicKey, error) {
+	return []service.PublicKey{{
+		KeyID:     string(s.keyID),
+		Algorithm: string(s.algorithm),
+		Key:       s.signer.Public(),
+		Use:       "sig",
+	}}, nil
+}
+
+// Start is a no-op; a static signer has nothing to rotate
+func (s *StaticSigner) Start(ctx context.Context) error {
+	return nil
+}
+
+// Stop is a no-op
+func (s *StaticSigner) Stop() {}
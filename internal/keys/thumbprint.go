@@ -3,6 +3,7 @@ package keys
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/base64"
@@ -39,6 +40,8 @@ func publicKeyToJWK(publicKey crypto.PublicKey) (map[string]interface{}, error)
 		return ecdsaToJWK(key)
 	case *rsa.PublicKey:
 		return rsaToJWK(key)
+	case ed25519.PublicKey:
+		return ed25519ToJWK(key), nil
 	default:
 		return nil, fmt.Errorf("unsupported key type: %T", publicKey)
 	}
@@ -90,6 +93,15 @@ func rsaToJWK(key *rsa.PublicKey) (map[string]interface{}, error) {
 	}, nil
 }
 
+// ed25519ToJWK converts an Ed25519 public key to JWK format (RFC 8037)
+func ed25519ToJWK(key ed25519.PublicKey) map[string]interface{} {
+	return map[string]interface{}{
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"x":   base64.RawURLEncoding.EncodeToString(key),
+	}
+}
+
 // canonicalizeJWK creates the canonical JSON representation for RFC 7638
 func canonicalizeJWK(jwk map[string]interface{}) (string, error) {
 	// Get required members based on key type
@@ -104,6 +116,8 @@ func canonicalizeJWK(jwk map[string]interface{}) (string, error) {
 		requiredMembers = []string{"crv", "kty", "x", "y"}
 	case "RSA":
 		requiredMembers = []string{"e", "kty", "n"}
+	case "OKP":
+		requiredMembers = []string{"crv", "kty", "x"}
 	default:
 		return "", fmt.Errorf("unsupported key type: %s", kty)
 	}
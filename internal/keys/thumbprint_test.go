@@ -2,9 +2,11 @@ package keys
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -59,6 +61,17 @@ func TestComputeThumbprint_RSA(t *testing.T) {
 	assert.NotContains(t, thumbprint, "=", "base64url should not contain padding")
 }
 
+func TestComputeThumbprint_Ed25519(t *testing.T) {
+	// Test vector from RFC 8037 appendix A.3
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	require.NoError(t, err)
+
+	thumbprint, err := ComputeThumbprint(ed25519.PublicKey(x))
+	require.NoError(t, err)
+
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", thumbprint)
+}
+
 func TestComputeThumbprint_Deterministic(t *testing.T) {
 	// Generate an EC P-256 key
 	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -0,0 +1,484 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// VaultTransitKeyProvider is a KeyProvider backed by HashiCorp Vault's Transit secrets engine.
+// Private keys never leave Vault: signing is done by Vault, and only public keys are read.
+//
+// Each handle maps to one Transit key. Rotating a handle creates a new version of
+// that key (or the key itself, the first time); signing always uses the latest version.
+type VaultTransitKeyProvider struct {
+	client    *vaultClient
+	keyType   KeyType
+	algorithm string
+	mountPath string
+	keyPrefix string
+}
+
+// VaultTransitConfig configures the Vault Transit key provider
+type VaultTransitConfig struct {
+	// KeyType is the type of keys this provider creates
+	KeyType KeyType
+
+	// Algorithm is the signing algorithm to use (default based on KeyType)
+	Algorithm string
+
+	// Address is the Vault server address (e.g., "https://vault.example.com:8200")
+	Address string
+
+	// MountPath is where the Transit secrets engine is mounted (default: "transit")
+	MountPath string
+
+	// KeyPrefix is prepended to Transit key names (default: "parsec-")
+	KeyPrefix string
+
+	// Namespace is the Vault Enterprise namespace, if any
+	Namespace string
+
+	// Auth obtains the Vault token used for Transit requests
+	Auth VaultAuthMethod
+
+	// HTTPClient is used for requests to Vault (default: http.DefaultClient)
+	HTTPClient *http.Client
+
+	// Clock is used to track token expiry (default: system clock)
+	Clock clock.Clock
+}
+
+// NewVaultTransitKeyProvider creates a new Vault Transit key provider
+func NewVaultTransitKeyProvider(cfg VaultTransitConfig) (*VaultTransitKeyProvider, error) {
+	if cfg.KeyType == "" {
+		return nil, fmt.Errorf("key_type is required")
+	}
+	if _, err := transitKeyType(cfg.KeyType); err != nil {
+		return nil, err
+	}
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	if cfg.Auth == nil {
+		return nil, fmt.Errorf("vault auth method is required")
+	}
+
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		var err error
+		algorithm, err = algorithmFromKeyType(cfg.KeyType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := checkAlgorithmForKeyType(algorithm, cfg.KeyType); err != nil {
+		return nil, err
+	}
+	if _, _, err := transitSignParams(algorithm); err != nil {
+		return nil, err
+	}
+
+	if cfg.MountPath == "" {
+		cfg.MountPath = "transit"
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "parsec-"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.NewSystemClock()
+	}
+
+	return &VaultTransitKeyProvider{
+		client: &vaultClient{
+			address:    strings.TrimSuffix(cfg.Address, "/"),
+			namespace:  cfg.Namespace,
+			httpClient: cfg.HTTPClient,
+			auth:       cfg.Auth,
+			clock:      cfg.Clock,
+		},
+		keyType:   cfg.KeyType,
+		algorithm: algorithm,
+		mountPath: strings.Trim(cfg.MountPath, "/"),
+		keyPrefix: cfg.KeyPrefix,
+	}, nil
+}
+
+// GetKeyHandle returns a handle for a specific trust domain, namespace, and key name.
+func (m *VaultTransitKeyProvider) GetKeyHandle(ctx context.Context, trustDomain, namespace, keyName string) (KeyHandle, error) {
+	return &vaultKeyHandle{
+		manager: m,
+		name:    m.transitKeyName(trustDomain, namespace, keyName),
+	}, nil
+}
+
+// transitKeyName builds the Transit key name for a trust domain, namespace, and key name.
+// Transit key names are a single path segment, so components are joined with "-".
+func (m *VaultTransitKeyProvider) transitKeyName(trustDomain, namespace, keyName string) string {
+	var parts []string
+	if trustDomain != "" {
+		parts = append(parts, m.sanitize(trustDomain))
+	}
+	if namespace != "" {
+		parts = append(parts, m.sanitize(namespace))
+	}
+	parts = append(parts, m.sanitize(keyName))
+
+	return m.keyPrefix + strings.Join(parts, "-")
+}
+
+// sanitize replaces characters that are not valid in a Transit key name with underscores
+func (m *VaultTransitKeyProvider) sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// transitKey is the subset of a Transit key read response parsec uses
+type transitKey struct {
+	Type          string `json:"type"`
+	LatestVersion int    `json:"latest_version"`
+	Keys          map[string]struct {
+		PublicKey string `json:"public_key"`
+	} `json:"keys"`
+}
+
+func (m *VaultTransitKeyProvider) readKey(ctx context.Context, name string) (*transitKey, error) {
+	var resp struct {
+		Data transitKey `json:"data"`
+	}
+	if err := m.client.do(ctx, http.MethodGet, m.mountPath+"/keys/"+name, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+func (m *VaultTransitKeyProvider) rotateKey(ctx context.Context, name string) error {
+	_, err := m.readKey(ctx, name)
+	if isVaultNotFound(err) {
+		// First rotation creates the key
+		keyType, err := transitKeyType(m.keyType)
+		if err != nil {
+			return err
+		}
+		if err := m.client.do(ctx, http.MethodPost, m.mountPath+"/keys/"+name, map[string]any{"type": keyType}, nil); err != nil {
+			return fmt.Errorf("failed to create transit key %s: %w", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read transit key %s: %w", name, err)
+	}
+
+	if err := m.client.do(ctx, http.MethodPost, m.mountPath+"/keys/"+name+"/rotate", nil, nil); err != nil {
+		return fmt.Errorf("failed to rotate transit key %s: %w", name, err)
+	}
+	return nil
+}
+
+// vaultKeyHandle implements KeyHandle
+type vaultKeyHandle struct {
+	manager *VaultTransitKeyProvider
+	name    string
+}
+
+func (h *vaultKeyHandle) Sign(ctx context.Context, digest []byte, opts crypto.SignerOpts) ([]byte, string, error) {
+	hashAlgorithm, signatureAlgorithm, err := transitSignParams(h.manager.algorithm)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req := map[string]any{
+		"input":     base64.StdEncoding.EncodeToString(digest),
+		"prehashed": true,
+		// JWS marshaling returns raw r||s for ECDSA, as JWS requires
+		"marshaling_algorithm": "jws",
+	}
+	if signatureAlgorithm != "" {
+		req["signature_algorithm"] = signatureAlgorithm
+	}
+	if signatureAlgorithm == "pss" {
+		// JWS PS* algorithms require the salt length to equal the hash length
+		req["salt_length"] = "hash"
+	}
+
+	var resp struct {
+		Data struct {
+			Signature  string `json:"signature"`
+			KeyVersion int    `json:"key_version"`
+		} `json:"data"`
+	}
+	path := fmt.Sprintf("%s/sign/%s/%s", h.manager.mountPath, h.name, hashAlgorithm)
+	if err := h.manager.client.do(ctx, http.MethodPost, path, req, &resp); err != nil {
+		return nil, "", fmt.Errorf("vault transit sign failed: %w", err)
+	}
+
+	// Signatures are formatted "vault:v<version>:<signature>"
+	parts := strings.SplitN(resp.Data.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, "", fmt.Errorf("unexpected vault signature format")
+	}
+	version := resp.Data.KeyVersion
+	if version == 0 {
+		version, err = strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+		if err != nil {
+			return nil, "", fmt.Errorf("unexpected vault signature version %q", parts[1])
+		}
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode vault signature: %w", err)
+	}
+
+	return signature, h.versionID(version), nil
+}
+
+func (h *vaultKeyHandle) Metadata(ctx context.Context) (string, string, error) {
+	key, err := h.manager.readKey(ctx, h.name)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read transit key %s: %w", h.name, err)
+	}
+	return h.versionID(key.LatestVersion), h.manager.algorithm, nil
+}
+
+func (h *vaultKeyHandle) Public(ctx context.Context) (crypto.PublicKey, error) {
+	key, err := h.manager.readKey(ctx, h.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transit key %s: %w", h.name, err)
+	}
+
+	version, ok := key.Keys[strconv.Itoa(key.LatestVersion)]
+	if !ok || version.PublicKey == "" {
+		return nil, fmt.Errorf("transit key %s has no public key for version %d", h.name, key.LatestVersion)
+	}
+
+	block, _ := pem.Decode([]byte(version.PublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode public key PEM for transit key %s", h.name)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func (h *vaultKeyHandle) Rotate(ctx context.Context) error {
+	return h.manager.rotateKey(ctx, h.name)
+}
+
+// versionID identifies a specific version of this handle's Transit key
+func (h *vaultKeyHandle) versionID(version int) string {
+	return fmt.Sprintf("%s:v%d", h.name, version)
+}
+
+// transitKeyType maps a KeyType to a Transit key type
+func transitKeyType(keyType KeyType) (string, error) {
+	switch keyType {
+	case KeyTypeECP256:
+		return "ecdsa-p256", nil
+	case KeyTypeECP384:
+		return "ecdsa-p384", nil
+	case KeyTypeRSA2048:
+		return "rsa-2048", nil
+	case KeyTypeRSA4096:
+		return "rsa-4096", nil
+	default:
+		return "", fmt.Errorf("unsupported key type: %s", keyType)
+	}
+}
+
+// transitSignParams maps a JWS algorithm to Transit's hash and signature algorithms.
+// signatureAlgorithm is empty for ECDSA, which has only one.
+func transitSignParams(algorithm string) (hashAlgorithm, signatureAlgorithm string, err error) {
+	switch algorithm {
+	case "ES256":
+		return "sha2-256", "", nil
+	case "ES384":
+		return "sha2-384", "", nil
+	case "RS256":
+		return "sha2-256", "pkcs1v15", nil
+	case "RS384":
+		return "sha2-384", "pkcs1v15", nil
+	case "RS512":
+		return "sha2-512", "pkcs1v15", nil
+	case "PS256":
+		return "sha2-256", "pss", nil
+	case "PS384":
+		return "sha2-384", "pss", nil
+	case "PS512":
+		return "sha2-512", "pss", nil
+	default:
+		return "", "", fmt.Errorf("unsupported algorithm: %s", algorithm)
+	}
+}
+
+// vaultClient is a minimal client for the Vault HTTP API.
+// It logs in lazily, caches the token until it nears expiry, and logs in again
+// once if Vault rejects a cached token.
+type vaultClient struct {
+	address    string
+	namespace  string
+	httpClient *http.Client
+	auth       VaultAuthMethod
+	clock      clock.Clock
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// vaultError is an error response from Vault
+type vaultError struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *vaultError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("vault returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("vault returned status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+func isVaultNotFound(err error) bool {
+	var vErr *vaultError
+	return errors.As(err, &vErr) && vErr.StatusCode == http.StatusNotFound
+}
+
+// do sends an authenticated request to path (relative to /v1/) and decodes the response data into out
+func (c *vaultClient) do(ctx context.Context, method, path string, body, out any) error {
+	token, err := c.currentToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.send(ctx, method, path, token, body, out)
+
+	var vErr *vaultError
+	if errors.As(err, &vErr) && vErr.StatusCode == http.StatusForbidden {
+		// The token may have been revoked or expired early; log in again once
+		c.invalidate(token)
+		if token, err = c.currentToken(ctx); err != nil {
+			return err
+		}
+		err = c.send(ctx, method, path, token, body, out)
+	}
+	return err
+}
+
+// login performs a login request against an auth method mounted at mountPath
+func (c *vaultClient) login(ctx context.Context, mountPath string, payload map[string]any) (string, time.Duration, error) {
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	if err := c.send(ctx, http.MethodPost, "auth/"+strings.Trim(mountPath, "/")+"/login", "", payload, &resp); err != nil {
+		return "", 0, fmt.Errorf("vault login failed: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", 0, fmt.Errorf("vault login returned no client token")
+	}
+	return resp.Auth.ClientToken, time.Duration(resp.Auth.LeaseDuration) * time.Second, nil
+}
+
+func (c *vaultClient) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.tokenExpiry.IsZero() || c.clock.Now().Before(c.tokenExpiry)) {
+		return c.token, nil
+	}
+
+	token, ttl, err := c.auth.login(ctx, c)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = token
+	c.tokenExpiry = time.Time{}
+	if ttl > 0 {
+		// Renew by logging in again before the token actually expires
+		c.tokenExpiry = c.clock.Now().Add(ttl - ttl/5)
+	}
+	return token, nil
+}
+
+func (c *vaultClient) invalidate(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == token {
+		c.token = ""
+	}
+}
+
+func (c *vaultClient) send(ctx context.Context, method, path, token string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal vault request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/v1/"+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create vault request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		vErr := &vaultError{StatusCode: resp.StatusCode}
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil {
+			vErr.Errors = errResp.Errors
+		}
+		return vErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return nil
+}
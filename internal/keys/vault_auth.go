@@ -0,0 +1,120 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultKubernetesServiceAccountTokenPath is where Kubernetes mounts the pod's service account token
+const DefaultKubernetesServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultAuthMethod obtains a Vault client token.
+// Implementations are VaultTokenAuth, VaultKubernetesAuth, and VaultAppRoleAuth.
+type VaultAuthMethod interface {
+	// login returns a client token and how long it is valid.
+	// A zero TTL means the token does not expire (or expiry is unknown); it is
+	// then reused until Vault rejects it.
+	login(ctx context.Context, client *vaultClient) (token string, ttl time.Duration, err error)
+}
+
+// VaultTokenAuth authenticates with a pre-issued Vault token.
+type VaultTokenAuth struct {
+	// Token is the Vault token. If empty, TokenFile is read instead.
+	Token string
+
+	// TokenFile is a file containing the Vault token (e.g., written by Vault Agent).
+	// It is re-read on every login, so rotated tokens are picked up.
+	TokenFile string
+}
+
+func (a VaultTokenAuth) login(ctx context.Context, client *vaultClient) (string, time.Duration, error) {
+	if a.Token != "" {
+		return a.Token, 0, nil
+	}
+	if a.TokenFile == "" {
+		return "", 0, fmt.Errorf("vault token auth requires token or token_file")
+	}
+	data, err := os.ReadFile(a.TokenFile)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read vault token file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), 0, nil
+}
+
+// VaultKubernetesAuth authenticates with the Kubernetes auth method using the pod's service account token.
+type VaultKubernetesAuth struct {
+	// Role is the Vault role to log in as
+	Role string
+
+	// MountPath is where the Kubernetes auth method is mounted (default: "kubernetes")
+	MountPath string
+
+	// JWTPath is the service account token file (default: DefaultKubernetesServiceAccountTokenPath)
+	JWTPath string
+}
+
+func (a VaultKubernetesAuth) login(ctx context.Context, client *vaultClient) (string, time.Duration, error) {
+	if a.Role == "" {
+		return "", 0, fmt.Errorf("vault kubernetes auth requires role")
+	}
+	mountPath := a.MountPath
+	if mountPath == "" {
+		mountPath = "kubernetes"
+	}
+	jwtPath := a.JWTPath
+	if jwtPath == "" {
+		jwtPath = DefaultKubernetesServiceAccountTokenPath
+	}
+
+	jwt, err := os.ReadFile(jwtPath)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	return client.login(ctx, mountPath, map[string]any{
+		"role": a.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+}
+
+// VaultAppRoleAuth authenticates with the AppRole auth method.
+type VaultAppRoleAuth struct {
+	// RoleID identifies the AppRole
+	RoleID string
+
+	// SecretID is the AppRole secret. If empty, SecretIDFile is read instead.
+	SecretID string
+
+	// SecretIDFile is a file containing the AppRole secret ID
+	SecretIDFile string
+
+	// MountPath is where the AppRole auth method is mounted (default: "approle")
+	MountPath string
+}
+
+func (a VaultAppRoleAuth) login(ctx context.Context, client *vaultClient) (string, time.Duration, error) {
+	if a.RoleID == "" {
+		return "", 0, fmt.Errorf("vault approle auth requires role_id")
+	}
+	mountPath := a.MountPath
+	if mountPath == "" {
+		mountPath = "approle"
+	}
+
+	secretID := a.SecretID
+	if secretID == "" && a.SecretIDFile != "" {
+		data, err := os.ReadFile(a.SecretIDFile)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to read approle secret_id file: %w", err)
+		}
+		secretID = strings.TrimSpace(string(data))
+	}
+
+	return client.login(ctx, mountPath, map[string]any{
+		"role_id":   a.RoleID,
+		"secret_id": secretID,
+	})
+}
@@ -0,0 +1,125 @@
+package keys
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// VaultKVKeySource is an ExternalKeySource reading a secret from Vault's KV secrets engine.
+// Unlike VaultTransitKeyProvider, the private key is read from Vault and used in-process.
+type VaultKVKeySource struct {
+	client    *vaultClient
+	kvVersion int
+	path      string
+	display   string
+}
+
+// VaultKVKeySourceConfig configures a VaultKVKeySource
+type VaultKVKeySourceConfig struct {
+	// Address is the Vault server address (e.g., "https://vault.example.com:8200")
+	Address string
+
+	// MountPath is where the KV secrets engine is mounted (default: "secret")
+	MountPath string
+
+	// Path is the secret's path within the mount
+	Path string
+
+	// KVVersion is the KV secrets engine version, 1 or 2 (default: 2)
+	KVVersion int
+
+	// Namespace is the Vault Enterprise namespace, if any
+	Namespace string
+
+	// Auth obtains the Vault token used for reads
+	Auth VaultAuthMethod
+
+	// HTTPClient is used for requests to Vault (default: http.DefaultClient)
+	HTTPClient *http.Client
+
+	// Clock is used to track token expiry (default: system clock)
+	Clock clock.Clock
+}
+
+// NewVaultKVKeySource creates a new Vault KV key source
+func NewVaultKVKeySource(cfg VaultKVKeySourceConfig) (*VaultKVKeySource, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("vault kv path is required")
+	}
+	if cfg.Auth == nil {
+		return nil, fmt.Errorf("vault auth method is required")
+	}
+	if cfg.MountPath == "" {
+		cfg.MountPath = "secret"
+	}
+	if cfg.KVVersion == 0 {
+		cfg.KVVersion = 2
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.NewSystemClock()
+	}
+
+	mount := strings.Trim(cfg.MountPath, "/")
+	secretPath := strings.Trim(cfg.Path, "/")
+
+	var path string
+	switch cfg.KVVersion {
+	case 1:
+		path = mount + "/" + secretPath
+	case 2:
+		path = mount + "/data/" + secretPath
+	default:
+		return nil, fmt.Errorf("unsupported vault kv version: %d (supported: 1, 2)", cfg.KVVersion)
+	}
+
+	return &VaultKVKeySource{
+		client: &vaultClient{
+			address:    strings.TrimSuffix(cfg.Address, "/"),
+			namespace:  cfg.Namespace,
+			httpClient: cfg.HTTPClient,
+			auth:       cfg.Auth,
+			clock:      cfg.Clock,
+		},
+		kvVersion: cfg.KVVersion,
+		path:      path,
+		display:   mount + "/" + secretPath,
+	}, nil
+}
+
+// Fetch returns the string fields of the latest version of the secret
+func (s *VaultKVKeySource) Fetch(ctx context.Context) (map[string][]byte, error) {
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := s.client.do(ctx, http.MethodGet, s.path, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %w", s.display, err)
+	}
+
+	data := resp.Data
+	if s.kvVersion == 2 {
+		// KV v2 nests the secret's fields alongside version metadata
+		nested, _ := data["data"].(map[string]any)
+		if nested == nil {
+			return nil, fmt.Errorf("vault secret %s has no data (deleted?)", s.display)
+		}
+		data = nested
+	}
+
+	fields := make(map[string][]byte, len(data))
+	for key, value := range data {
+		if str, ok := value.(string); ok {
+			fields[key] = []byte(str)
+		}
+	}
+	return fields, nil
+}
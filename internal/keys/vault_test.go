@@ -0,0 +1,344 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransit is an in-memory stand-in for Vault's Transit secrets engine and AppRole login
+type fakeTransit struct {
+	t *testing.T
+
+	mu         sync.Mutex
+	keys       map[string][]crypto.Signer // versions, oldest first
+	validToken string
+	logins     int
+	namespaces []string
+}
+
+func newFakeTransit(t *testing.T) (*fakeTransit, *httptest.Server) {
+	f := &fakeTransit{t: t, keys: make(map[string][]crypto.Signer)}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeTransit) revokeToken() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.validToken = ""
+}
+
+func (f *fakeTransit) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.namespaces = append(f.namespaces, r.Header.Get("X-Vault-Namespace"))
+
+	var body map[string]any
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if path == "auth/approle/login" {
+		if body["role_id"] != "parsec" || body["secret_id"] != "s3cret" {
+			writeVaultError(w, http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		f.logins++
+		f.validToken = "token-" + strconv.Itoa(f.logins)
+		json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{"client_token": f.validToken, "lease_duration": 3600},
+		})
+		return
+	}
+
+	if f.validToken == "" || r.Header.Get("X-Vault-Token") != f.validToken {
+		writeVaultError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	switch {
+	case len(parts) == 3 && parts[1] == "keys" && r.Method == http.MethodGet:
+		f.readKey(w, parts[2])
+	case len(parts) == 3 && parts[1] == "keys" && r.Method == http.MethodPost:
+		f.createKey(w, parts[2], body["type"].(string))
+	case len(parts) == 4 && parts[1] == "keys" && parts[3] == "rotate":
+		versions, ok := f.keys[parts[2]]
+		if !ok {
+			writeVaultError(w, http.StatusNotFound, "key not found")
+			return
+		}
+		f.keys[parts[2]] = append(versions, f.generate(versions[0]))
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 4 && parts[1] == "sign":
+		f.sign(w, parts[2], parts[3], body)
+	default:
+		writeVaultError(w, http.StatusNotFound, "unsupported path "+path)
+	}
+}
+
+func (f *fakeTransit) readKey(w http.ResponseWriter, name string) {
+	versions, ok := f.keys[name]
+	if !ok {
+		writeVaultError(w, http.StatusNotFound, "key not found")
+		return
+	}
+
+	keys := make(map[string]any)
+	for i, signer := range versions {
+		der, err := x509.MarshalPKIXPublicKey(signer.Public())
+		require.NoError(f.t, err)
+		keys[strconv.Itoa(i+1)] = map[string]any{
+			"public_key": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"data": map[string]any{"latest_version": len(versions), "keys": keys},
+	})
+}
+
+func (f *fakeTransit) createKey(w http.ResponseWriter, name, keyType string) {
+	var signer crypto.Signer
+	var err error
+	switch keyType {
+	case "ecdsa-p256":
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa-2048":
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		writeVaultError(w, http.StatusBadRequest, "unsupported key type "+keyType)
+		return
+	}
+	require.NoError(f.t, err)
+	f.keys[name] = []crypto.Signer{signer}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// generate creates a new key of the same type as template
+func (f *fakeTransit) generate(template crypto.Signer) crypto.Signer {
+	var signer crypto.Signer
+	var err error
+	switch template.(type) {
+	case *ecdsa.PrivateKey:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	require.NoError(f.t, err)
+	return signer
+}
+
+func (f *fakeTransit) sign(w http.ResponseWriter, name, hashAlgorithm string, body map[string]any) {
+	versions, ok := f.keys[name]
+	if !ok {
+		writeVaultError(w, http.StatusNotFound, "key not found")
+		return
+	}
+	assert.Equal(f.t, "sha2-256", hashAlgorithm)
+	assert.Equal(f.t, true, body["prehashed"])
+	assert.Equal(f.t, "jws", body["marshaling_algorithm"])
+
+	digest, err := base64.StdEncoding.DecodeString(body["input"].(string))
+	require.NoError(f.t, err)
+
+	version := len(versions)
+	var signature []byte
+	switch key := versions[version-1].(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		require.NoError(f.t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case *rsa.PrivateKey:
+		if body["signature_algorithm"] == "pss" {
+			assert.Equal(f.t, "hash", body["salt_length"])
+			signature, err = rsa.SignPSS(rand.Reader, key, crypto.SHA256, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			assert.Equal(f.t, "pkcs1v15", body["signature_algorithm"])
+			signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest)
+		}
+		require.NoError(f.t, err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"data": map[string]any{
+			"signature":   "vault:v" + strconv.Itoa(version) + ":" + base64.RawURLEncoding.EncodeToString(signature),
+			"key_version": version,
+		},
+	})
+}
+
+func writeVaultError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"errors": []string{message}})
+}
+
+func newTestVaultProvider(t *testing.T, address string, keyType KeyType, algorithm string) *VaultTransitKeyProvider {
+	t.Helper()
+	provider, err := NewVaultTransitKeyProvider(VaultTransitConfig{
+		KeyType:   keyType,
+		Algorithm: algorithm,
+		Address:   address,
+		Namespace: "team-a",
+		Auth:      VaultAppRoleAuth{RoleID: "parsec", SecretID: "s3cret"},
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestVaultTransitKeyProvider_SignAndVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyType   KeyType
+		algorithm string
+		verify    func(t *testing.T, pub crypto.PublicKey, digest, sig []byte)
+	}{
+		{
+			name:    "ES256",
+			keyType: KeyTypeECP256,
+			verify: func(t *testing.T, pub crypto.PublicKey, digest, sig []byte) {
+				require.Len(t, sig, 64, "JWS ECDSA signatures are raw r||s")
+				r := new(big.Int).SetBytes(sig[:32])
+				s := new(big.Int).SetBytes(sig[32:])
+				assert.True(t, ecdsa.Verify(pub.(*ecdsa.PublicKey), digest, r, s))
+			},
+		},
+		{
+			name:    "RS256",
+			keyType: KeyTypeRSA2048,
+			verify: func(t *testing.T, pub crypto.PublicKey, digest, sig []byte) {
+				assert.NoError(t, rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), crypto.SHA256, digest, sig))
+			},
+		},
+		{
+			name:      "PS256",
+			keyType:   KeyTypeRSA2048,
+			algorithm: "PS256",
+			verify: func(t *testing.T, pub crypto.PublicKey, digest, sig []byte) {
+				assert.NoError(t, rsa.VerifyPSS(pub.(*rsa.PublicKey), crypto.SHA256, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			_, server := newFakeTransit(t)
+			provider := newTestVaultProvider(t, server.URL, tt.keyType, tt.algorithm)
+
+			handle, err := provider.GetKeyHandle(ctx, "example.com", "txn", "key-a")
+			require.NoError(t, err)
+			require.NoError(t, handle.Rotate(ctx))
+
+			keyID, alg, err := handle.Metadata(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, "parsec-example.com-txn-key-a:v1", keyID)
+			assert.Equal(t, tt.name, alg)
+
+			pub, err := handle.Public(ctx)
+			require.NoError(t, err)
+
+			digest := sha256.Sum256([]byte("payload"))
+			sig, usedKeyID, err := handle.Sign(ctx, digest[:], crypto.SHA256)
+			require.NoError(t, err)
+			assert.Equal(t, keyID, usedKeyID)
+
+			tt.verify(t, pub, digest[:], sig)
+		})
+	}
+}
+
+func TestVaultTransitKeyProvider_Rotate(t *testing.T) {
+	ctx := context.Background()
+	fake, server := newFakeTransit(t)
+	provider := newTestVaultProvider(t, server.URL, KeyTypeECP256, "")
+
+	handle, err := provider.GetKeyHandle(ctx, "example.com", "txn", "key-a")
+	require.NoError(t, err)
+
+	_, _, err = handle.Metadata(ctx)
+	assert.Error(t, err, "metadata before the key exists")
+
+	require.NoError(t, handle.Rotate(ctx))
+	pub1, err := handle.Public(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, handle.Rotate(ctx))
+	keyID, _, err := handle.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "parsec-example.com-txn-key-a:v2", keyID)
+
+	pub2, err := handle.Public(ctx)
+	require.NoError(t, err)
+	assert.False(t, pub1.(*ecdsa.PublicKey).Equal(pub2), "rotation should produce a new public key")
+
+	digest := sha256.Sum256([]byte("payload"))
+	_, usedKeyID, err := handle.Sign(ctx, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	assert.Equal(t, keyID, usedKeyID)
+
+	assert.Len(t, fake.keys, 1, "both rotations should use the same transit key")
+}
+
+func TestVaultTransitKeyProvider_ReauthenticatesWhenTokenRejected(t *testing.T) {
+	ctx := context.Background()
+	fake, server := newFakeTransit(t)
+	provider := newTestVaultProvider(t, server.URL, KeyTypeECP256, "")
+
+	handle, err := provider.GetKeyHandle(ctx, "example.com", "txn", "key-a")
+	require.NoError(t, err)
+	require.NoError(t, handle.Rotate(ctx))
+	assert.Equal(t, 1, fake.logins)
+
+	fake.revokeToken()
+
+	_, _, err = handle.Metadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, fake.logins)
+
+	for _, ns := range fake.namespaces {
+		assert.Equal(t, "team-a", ns)
+	}
+}
+
+func TestNewVaultTransitKeyProvider_Validation(t *testing.T) {
+	auth := VaultTokenAuth{Token: "root"}
+
+	_, err := NewVaultTransitKeyProvider(VaultTransitConfig{Address: "http://vault", Auth: auth})
+	assert.Error(t, err, "missing key type")
+
+	_, err = NewVaultTransitKeyProvider(VaultTransitConfig{KeyType: KeyTypeECP256, Auth: auth})
+	assert.Error(t, err, "missing address")
+
+	_, err = NewVaultTransitKeyProvider(VaultTransitConfig{KeyType: KeyTypeECP256, Address: "http://vault"})
+	assert.Error(t, err, "missing auth")
+
+	_, err = NewVaultTransitKeyProvider(VaultTransitConfig{KeyType: KeyTypeECP256, Algorithm: "HS256", Address: "http://vault", Auth: auth})
+	assert.Error(t, err, "unsupported algorithm")
+
+	_, err = NewVaultTransitKeyProvider(VaultTransitConfig{KeyType: KeyTypeECP256, Address: "http://vault", Auth: auth})
+	assert.NoError(t, err)
+}
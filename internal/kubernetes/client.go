@@ -0,0 +1,240 @@
+// Package kubernetes is a minimal client for the Kubernetes API, authenticating with
+// the pod's service account, for the few objects parsec reads and writes
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ServiceAccountDir is where the pod's service account is mounted
+const ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Client sends requests to the Kubernetes API with a service account token
+type Client struct {
+	apiServer  string
+	tokenFile  string
+	httpClient *http.Client
+}
+
+// ClientConfig configures a Client.
+// Defaults assume parsec runs in-cluster with a mounted service account.
+type ClientConfig struct {
+	// APIServer is the Kubernetes API server URL
+	// (default: https://$KUBERNETES_SERVICE_HOST:$KUBERNETES_SERVICE_PORT)
+	APIServer string
+
+	// TokenFile is the bearer token file, re-read on every request so
+	// projected token rotation is picked up (default: the service account token)
+	TokenFile string
+
+	// CAFile is the API server CA bundle (default: the service account CA).
+	// Ignored if HTTPClient is set.
+	CAFile string
+
+	// HTTPClient is used for API requests (default: a client trusting CAFile)
+	HTTPClient *http.Client
+}
+
+// NewClient creates a client, defaulting empty settings to the in-cluster service account
+func NewClient(cfg ClientConfig) (*Client, error) {
+	apiServer := cfg.APIServer
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("kubernetes api server not set and not running in-cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+
+	tokenFile := cfg.TokenFile
+	if tokenFile == "" {
+		tokenFile = ServiceAccountDir + "/token"
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		caFile := cfg.CAFile
+		if caFile == "" {
+			caFile = ServiceAccountDir + "/ca.crt"
+		}
+		var err error
+		httpClient, err = httpClientTrusting(caFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Client{
+		apiServer:  strings.TrimSuffix(apiServer, "/"),
+		tokenFile:  tokenFile,
+		httpClient: httpClient,
+	}, nil
+}
+
+// httpClientTrusting creates an HTTP client trusting the CA bundle at caFile
+func httpClientTrusting(caFile string) (*http.Client, error) {
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubernetes CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in kubernetes CA %s", caFile)
+	}
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}, nil
+}
+
+// Namespace returns namespace, or the pod's namespace if empty
+func Namespace(namespace string) (string, error) {
+	if namespace != "" {
+		return namespace, nil
+	}
+	data, err := os.ReadFile(ServiceAccountDir + "/namespace")
+	if err != nil {
+		return "", fmt.Errorf("kubernetes namespace not set and not running in-cluster: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Send sends an authenticated request to path and decodes the JSON response into out
+func (c *Client) Send(ctx context.Context, method, path string, body, out any) error {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode kubernetes response: %w", err)
+	}
+	return nil
+}
+
+// WatchEvent is an event of a watch: a change to an object
+type WatchEvent struct {
+	// Type is ADDED, MODIFIED, DELETED, BOOKMARK, or ERROR
+	Type string `json:"type"`
+
+	// Object is the object after the change, or a Status for ERROR events
+	Object json.RawMessage `json:"object"`
+}
+
+// Watch watches path, a collection with watch=true in its query, calling handle with each
+// event until the server ends the watch, handle returns an error, or ctx is done.
+// The server ends watches after their timeoutSeconds, or the client's timeout.
+func (c *Client) Watch(ctx context.Context, path string, handle func(WatchEvent) error) error {
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event WatchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to decode kubernetes watch event: %w", err)
+		}
+		if event.Type == "ERROR" {
+			var status struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			json.Unmarshal(event.Object, &status)
+			return &StatusError{StatusCode: status.Code, Message: status.Message}
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+}
+
+// do sends an authenticated request to path, returning successful responses
+func (c *Client) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal kubernetes request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.apiServer+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	token, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubernetes token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes request failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		kErr := &StatusError{StatusCode: resp.StatusCode}
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&status) == nil {
+			kErr.Message = status.Message
+		}
+		return nil, kErr
+	}
+	return resp, nil
+}
+
+// StatusError is an error response from the Kubernetes API
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("kubernetes api error (status %d)", e.StatusCode)
+	}
+	return fmt.Sprintf("kubernetes api error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// IsStatus reports whether err is a StatusError with statusCode
+func IsStatus(err error, statusCode int) bool {
+	var kErr *StatusError
+	return errors.As(err, &kErr) && kErr.StatusCode == statusCode
+}
@@ -0,0 +1,308 @@
+// Package limits bounds how much memory untrusted upstream data can consume.
+//
+// Data sources call enrichment APIs on the issuance path. A misbehaving API
+// that returns a huge body or a very large JSON document would otherwise be
+// buffered and parsed in full for every token issued. The helpers here read
+// and decode such data incrementally, enforcing size, depth, and array length
+// limits as they go rather than after everything is in memory.
+package limits
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrLimitExceeded is returned (wrapped) when data exceeds a configured limit
+// and the policy is PolicyError
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Policy decides what happens when data exceeds a limit
+type Policy string
+
+const (
+	// PolicyError fails the read or decode (default)
+	PolicyError Policy = "error"
+
+	// PolicyTruncate keeps the data within the limit and discards the rest
+	PolicyTruncate Policy = "truncate"
+)
+
+// ParsePolicy parses a policy name. The empty string selects PolicyError.
+func ParsePolicy(s string) (Policy, error) {
+	switch Policy(s) {
+	case "", PolicyError:
+		return PolicyError, nil
+	case PolicyTruncate:
+		return PolicyTruncate, nil
+	default:
+		return "", fmt.Errorf("unknown truncation policy %q (expected %q or %q)", s, PolicyError, PolicyTruncate)
+	}
+}
+
+// ReadAll reads r until EOF or until more than max bytes have been read.
+// A max of zero or less means unlimited.
+//
+// When r holds more than max bytes, PolicyTruncate returns the first max
+// bytes with truncated set; any other policy returns an error wrapping
+// ErrLimitExceeded. Either way, no more than max+1 bytes are buffered.
+func ReadAll(r io.Reader, max int64, policy Policy) (data []byte, truncated bool, err error) {
+	if max <= 0 {
+		data, err = io.ReadAll(r)
+		return data, false, err
+	}
+
+	data, err = io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) <= max {
+		return data, false, nil
+	}
+
+	if policy == PolicyTruncate {
+		return data[:max], true, nil
+	}
+	return nil, false, fmt.Errorf("%w: body exceeds %d bytes", ErrLimitExceeded, max)
+}
+
+// JSON bounds JSON decoding. Zero values mean unlimited.
+type JSON struct {
+	// MaxBytes caps the size of the encoded document.
+	// Exceeding it is always an error: a truncated document is not valid JSON.
+	MaxBytes int64
+
+	// MaxDepth caps how deeply objects and arrays may nest.
+	// Exceeding it is always an error.
+	MaxDepth int
+
+	// MaxArrayItems caps the number of elements kept from any one array.
+	// With PolicyTruncate, excess elements are skipped without being decoded
+	// into memory; otherwise they are an error.
+	MaxArrayItems int
+
+	// Policy decides what happens when an array exceeds MaxArrayItems
+	Policy Policy
+}
+
+// IsZero reports whether no limits are configured
+func (l JSON) IsZero() bool {
+	return l.MaxBytes <= 0 && l.MaxDepth <= 0 && l.MaxArrayItems <= 0
+}
+
+// DecodeJSON decodes a single JSON value from r into the same generic shape
+// json.Unmarshal produces for an interface{} (map[string]any, []any, string,
+// float64, bool, nil), enforcing limits while the document is streamed.
+// truncated reports whether any array was cut short under PolicyTruncate.
+func DecodeJSON(r io.Reader, limits JSON) (value any, truncated bool, err error) {
+	d := newDecoder(r, limits)
+
+	value, err = d.value(0)
+	if err != nil {
+		return nil, false, d.wrap(err)
+	}
+	if err := d.end(); err != nil {
+		return nil, false, err
+	}
+	return value, d.truncated, nil
+}
+
+// EachJSONArrayItem streams a top-level JSON array from r, decoding one
+// element at a time and calling fn with its index and value. Only the current
+// element is held in memory, so arbitrarily long arrays can be processed in
+// bounded memory. fn returns false to stop early; the rest of the input is
+// then left unread.
+//
+// MaxArrayItems applies to the top-level array as it does for DecodeJSON:
+// with PolicyTruncate iteration simply stops at the limit.
+// MaxDepth and nested array limits apply to each element.
+func EachJSONArrayItem(r io.Reader, limits JSON, fn func(index int, value any) (bool, error)) (truncated bool, err error) {
+	d := newDecoder(r, limits)
+
+	tok, err := d.dec.Token()
+	if err != nil {
+		return false, d.wrap(err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return false, fmt.Errorf("expected JSON array, got %v", tok)
+	}
+
+	for i := 0; d.dec.More(); i++ {
+		if limits.MaxArrayItems > 0 && i >= limits.MaxArrayItems {
+			if limits.Policy != PolicyTruncate {
+				return false, fmt.Errorf("%w: array exceeds %d items", ErrLimitExceeded, limits.MaxArrayItems)
+			}
+			return true, nil
+		}
+
+		value, err := d.value(1)
+		if err != nil {
+			return false, d.wrap(err)
+		}
+		more, err := fn(i, value)
+		if err != nil {
+			return false, err
+		}
+		if !more {
+			return d.truncated, nil
+		}
+	}
+
+	if _, err := d.dec.Token(); err != nil {
+		return false, d.wrap(err)
+	}
+	if err := d.end(); err != nil {
+		return false, err
+	}
+	return d.truncated, nil
+}
+
+// decoder builds generic values from a json.Decoder token stream
+type decoder struct {
+	dec       *json.Decoder
+	limits    JSON
+	truncated bool
+}
+
+func newDecoder(r io.Reader, limits JSON) *decoder {
+	if limits.MaxBytes > 0 {
+		r = &limitedReader{r: r, remaining: limits.MaxBytes, max: limits.MaxBytes}
+	}
+	return &decoder{dec: json.NewDecoder(r), limits: limits}
+}
+
+// value decodes the next value. depth is the nesting depth of that value's container.
+func (d *decoder) value(depth int) (any, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		// string, float64, bool, or nil
+		return tok, nil
+	}
+
+	if d.limits.MaxDepth > 0 && depth >= d.limits.MaxDepth {
+		return nil, fmt.Errorf("%w: nesting exceeds depth %d", ErrLimitExceeded, d.limits.MaxDepth)
+	}
+
+	switch delim {
+	case '{':
+		obj := make(map[string]any)
+		for d.dec.More() {
+			keyTok, err := d.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyTok.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected object key, got %v", keyTok)
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = v
+		}
+		if _, err := d.dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+
+	case '[':
+		arr := make([]any, 0)
+		for d.dec.More() {
+			if d.limits.MaxArrayItems > 0 && len(arr) >= d.limits.MaxArrayItems {
+				if d.limits.Policy != PolicyTruncate {
+					return nil, fmt.Errorf("%w: array exceeds %d items", ErrLimitExceeded, d.limits.MaxArrayItems)
+				}
+				d.truncated = true
+				if err := d.skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		if _, err := d.dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected delimiter %v", delim)
+	}
+}
+
+// skip consumes the next value without retaining it
+func (d *decoder) skip() error {
+	nesting := 0
+	for {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				nesting++
+			case '}', ']':
+				nesting--
+			}
+		}
+		if nesting == 0 {
+			return nil
+		}
+	}
+}
+
+// end verifies nothing but whitespace follows the decoded value, as json.Unmarshal does
+func (d *decoder) end() error {
+	if _, err := d.dec.Token(); err != io.EOF {
+		if err != nil {
+			return d.wrap(err)
+		}
+		return fmt.Errorf("invalid JSON: unexpected data after top-level value")
+	}
+	return nil
+}
+
+// wrap annotates syntax errors; limit errors are returned as-is
+func (d *decoder) wrap(err error) error {
+	if errors.Is(err, ErrLimitExceeded) {
+		return err
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("invalid JSON: %w", err)
+}
+
+// limitedReader fails with ErrLimitExceeded once more than remaining bytes are read
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	max       int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, fmt.Errorf("%w: document exceeds %d bytes", ErrLimitExceeded, l.max)
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n - 1, fmt.Errorf("%w: document exceeds %d bytes", ErrLimitExceeded, l.max)
+	}
+	return n, err
+}
@@ -0,0 +1,296 @@
+package limits
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		max           int64
+		policy        Policy
+		want          string
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{name: "unlimited", input: "hello world", max: 0, want: "hello world"},
+		{name: "within limit", input: "hello", max: 5, want: "hello"},
+		{name: "over limit errors", input: "hello world", max: 5, policy: PolicyError, wantErr: true},
+		{name: "over limit defaults to error", input: "hello world", max: 5, wantErr: true},
+		{name: "over limit truncates", input: "hello world", max: 5, policy: PolicyTruncate, want: "hello", wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := ReadAll(strings.NewReader(tt.input), tt.max, tt.policy)
+			if tt.wantErr {
+				if !errors.Is(err, ErrLimitExceeded) {
+					t.Fatalf("expected ErrLimitExceeded, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if truncated != tt.wantTruncated {
+				t.Errorf("expected truncated=%v, got %v", tt.wantTruncated, truncated)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON_MatchesUnmarshal(t *testing.T) {
+	inputs := []string{
+		`{"name":"alice","age":30,"admin":true,"manager":null}`,
+		`[1,"two",[3,4],{"five":5}]`,
+		`"just a string"`,
+		`42.5`,
+		`{"nested":{"deeper":{"deepest":[]}}}`,
+		`  {"padded": true}  `,
+	}
+
+	for _, input := range inputs {
+		var want any
+		if err := json.Unmarshal([]byte(input), &want); err != nil {
+			t.Fatalf("json.Unmarshal(%s) failed: %v", input, err)
+		}
+
+		got, truncated, err := DecodeJSON(strings.NewReader(input), JSON{})
+		if err != nil {
+			t.Fatalf("DecodeJSON(%s) failed: %v", input, err)
+		}
+		if truncated {
+			t.Errorf("DecodeJSON(%s) unexpectedly truncated", input)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DecodeJSON(%s) = %#v, want %#v", input, got, want)
+		}
+	}
+}
+
+func TestDecodeJSON_InvalidInput(t *testing.T) {
+	for _, input := range []string{``, `{"a":`, `[1,2`, `{"a":1}{"b":2}`, `{"a" 1}`} {
+		_, _, err := DecodeJSON(strings.NewReader(input), JSON{})
+		if err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+		if errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("syntax error for %q should not be ErrLimitExceeded: %v", input, err)
+		}
+	}
+}
+
+func TestDecodeJSON_Limits(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		limits        JSON
+		want          string
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{
+			name:    "max bytes exceeded",
+			input:   `{"key":"a value that is too long"}`,
+			limits:  JSON{MaxBytes: 10},
+			wantErr: true,
+		},
+		{
+			name:    "max bytes exceeded even when truncating",
+			input:   `{"key":"a value that is too long"}`,
+			limits:  JSON{MaxBytes: 10, Policy: PolicyTruncate},
+			wantErr: true,
+		},
+		{
+			name:   "max bytes exact",
+			input:  `{"a":1}`,
+			limits: JSON{MaxBytes: 7},
+			want:   `{"a":1}`,
+		},
+		{
+			name:    "max depth exceeded",
+			input:   `{"a":{"b":{"c":1}}}`,
+			limits:  JSON{MaxDepth: 2},
+			wantErr: true,
+		},
+		{
+			name:   "max depth exact",
+			input:  `{"a":{"b":1}}`,
+			limits: JSON{MaxDepth: 2},
+			want:   `{"a":{"b":1}}`,
+		},
+		{
+			name:    "max array items exceeded",
+			input:   `[1,2,3,4]`,
+			limits:  JSON{MaxArrayItems: 2},
+			wantErr: true,
+		},
+		{
+			name:          "max array items truncated",
+			input:         `[1,2,{"skipped":[5,6]},[7],8]`,
+			limits:        JSON{MaxArrayItems: 2, Policy: PolicyTruncate},
+			want:          `[1,2]`,
+			wantTruncated: true,
+		},
+		{
+			name:          "nested arrays truncated",
+			input:         `{"groups":["a","b","c"],"roles":["x"]}`,
+			limits:        JSON{MaxArrayItems: 2, Policy: PolicyTruncate},
+			want:          `{"groups":["a","b"],"roles":["x"]}`,
+			wantTruncated: true,
+		},
+		{
+			name:    "truncated arrays are still validated",
+			input:   `[1,2,{"bad"}]`,
+			limits:  JSON{MaxArrayItems: 2, Policy: PolicyTruncate},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := DecodeJSON(strings.NewReader(tt.input), tt.limits)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeJSON failed: %v", err)
+			}
+
+			gotJSON, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("failed to marshal result: %v", err)
+			}
+			if string(gotJSON) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, gotJSON)
+			}
+			if truncated != tt.wantTruncated {
+				t.Errorf("expected truncated=%v, got %v", tt.wantTruncated, truncated)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON_LimitErrors(t *testing.T) {
+	_, _, err := DecodeJSON(strings.NewReader(`[1,2,3]`), JSON{MaxArrayItems: 1})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected ErrLimitExceeded for array items, got %v", err)
+	}
+
+	_, _, err = DecodeJSON(strings.NewReader(`[[[1]]]`), JSON{MaxDepth: 1})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected ErrLimitExceeded for depth, got %v", err)
+	}
+
+	_, _, err = DecodeJSON(strings.NewReader(`"`+strings.Repeat("x", 100)+`"`), JSON{MaxBytes: 50})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected ErrLimitExceeded for bytes, got %v", err)
+	}
+}
+
+func TestEachJSONArrayItem(t *testing.T) {
+	t.Run("visits every item", func(t *testing.T) {
+		var got []any
+		truncated, err := EachJSONArrayItem(strings.NewReader(`[1,"two",{"three":3}]`), JSON{}, func(i int, v any) (bool, error) {
+			if i != len(got) {
+				t.Errorf("expected index %d, got %d", len(got), i)
+			}
+			got = append(got, v)
+			return true, nil
+		})
+		if err != nil {
+			t.Fatalf("EachJSONArrayItem failed: %v", err)
+		}
+		if truncated {
+			t.Error("unexpected truncation")
+		}
+		want := []any{float64(1), "two", map[string]any{"three": float64(3)}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %#v, got %#v", want, got)
+		}
+	})
+
+	t.Run("stops early", func(t *testing.T) {
+		count := 0
+		// Everything after the stop point is never read, so even invalid trailing input is fine
+		_, err := EachJSONArrayItem(strings.NewReader(`[1,2,3,garbage`), JSON{}, func(i int, v any) (bool, error) {
+			count++
+			return i < 1, nil
+		})
+		if err != nil {
+			t.Fatalf("EachJSONArrayItem failed: %v", err)
+		}
+		if count != 2 {
+			t.Errorf("expected 2 items visited, got %d", count)
+		}
+	})
+
+	t.Run("truncates at max items", func(t *testing.T) {
+		count := 0
+		truncated, err := EachJSONArrayItem(strings.NewReader(`[1,2,3,4,5]`), JSON{MaxArrayItems: 3, Policy: PolicyTruncate}, func(i int, v any) (bool, error) {
+			count++
+			return true, nil
+		})
+		if err != nil {
+			t.Fatalf("EachJSONArrayItem failed: %v", err)
+		}
+		if !truncated || count != 3 {
+			t.Errorf("expected truncation after 3 items, got truncated=%v count=%d", truncated, count)
+		}
+	})
+
+	t.Run("errors at max items", func(t *testing.T) {
+		_, err := EachJSONArrayItem(strings.NewReader(`[1,2,3,4,5]`), JSON{MaxArrayItems: 3}, func(i int, v any) (bool, error) {
+			return true, nil
+		})
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("expected ErrLimitExceeded, got %v", err)
+		}
+	})
+
+	t.Run("rejects non-array", func(t *testing.T) {
+		_, err := EachJSONArrayItem(strings.NewReader(`{"a":1}`), JSON{}, func(i int, v any) (bool, error) {
+			return true, nil
+		})
+		if err == nil {
+			t.Error("expected error for non-array input")
+		}
+	})
+
+	t.Run("propagates callback errors", func(t *testing.T) {
+		boom := errors.New("boom")
+		_, err := EachJSONArrayItem(strings.NewReader(`[1]`), JSON{}, func(i int, v any) (bool, error) {
+			return false, boom
+		})
+		if !errors.Is(err, boom) {
+			t.Errorf("expected callback error, got %v", err)
+		}
+	})
+}
+
+func TestParsePolicy(t *testing.T) {
+	for input, want := range map[string]Policy{"": PolicyError, "error": PolicyError, "truncate": PolicyTruncate} {
+		got, err := ParsePolicy(input)
+		if err != nil {
+			t.Errorf("ParsePolicy(%q) failed: %v", input, err)
+		}
+		if got != want {
+			t.Errorf("ParsePolicy(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	if _, err := ParsePolicy("drop"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
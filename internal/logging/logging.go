@@ -0,0 +1,106 @@
+// Package logging carries request-scoped log fields in contexts, so every record logged
+// while serving a request, by any component, identifies the request: its transaction
+// ID, a hash of its subject, and the validator that validated it.
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+)
+
+// Keys of request-scoped fields
+const (
+	TransactionIDKey = "txn"
+	SubjectHashKey   = "subject_hash"
+	ValidatorKey     = "validator"
+)
+
+type requestKey struct{}
+
+// request holds the fields of a request, added to as it is served
+type request struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// WithRequest starts the scope of a request: fields added to the returned context, or
+// contexts derived from it, are logged with every record logged with them
+func WithRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{})
+}
+
+// AddAttrs adds fields to the request of ctx, replacing fields with the same keys.
+// Without a request, the fields are dropped.
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	req, ok := ctx.Value(requestKey{}).(*request)
+	if !ok {
+		return
+	}
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	for _, attr := range attrs {
+		replaced := false
+		for i := range req.attrs {
+			if req.attrs[i].Key == attr.Key {
+				req.attrs[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			req.attrs = append(req.attrs, attr)
+		}
+	}
+}
+
+// Attrs returns the fields of the request of ctx
+func Attrs(ctx context.Context) []slog.Attr {
+	req, ok := ctx.Value(requestKey{}).(*request)
+	if !ok {
+		return nil
+	}
+	req.mu.Lock()
+	defer req.mu.Unlock()
+	return append([]slog.Attr(nil), req.attrs...)
+}
+
+// Subject returns the field identifying subject without logging it: a truncated SHA-256
+// hash, enough to correlate a subject's requests
+func Subject(subject string) slog.Attr {
+	sum := sha256.Sum256([]byte(subject))
+	return slog.String(SubjectHashKey, hex.EncodeToString(sum[:8]))
+}
+
+// contextHandler adds the fields of requests to the records logged with them
+type contextHandler struct {
+	next slog.Handler
+}
+
+// NewContextHandler returns a handler that adds the fields of the request of each
+// record's context to the record, then passes it to next
+func NewContextHandler(next slog.Handler) slog.Handler {
+	return &contextHandler{next: next}
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs := Attrs(ctx); len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
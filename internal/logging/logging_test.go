@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewContextHandler(slog.NewJSONHandler(&buf, nil)))
+
+	logged := func(t *testing.T) map[string]any {
+		t.Helper()
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("failed to decode record: %v", err)
+		}
+		buf.Reset()
+		return record
+	}
+
+	t.Run("logs the fields of the request", func(t *testing.T) {
+		ctx := WithRequest(context.Background())
+		AddAttrs(ctx, slog.String(ValidatorKey, "corp-idp"), Subject("alice"))
+		AddAttrs(ctx, slog.String(TransactionIDKey, "txn-1"))
+
+		// Fields added after a context is derived are still the request's
+		derived, cancel := context.WithCancel(ctx)
+		defer cancel()
+		AddAttrs(ctx, slog.String(ValidatorKey, "partner-idp"))
+
+		logger.InfoContext(derived, "Token issued", "token_type", "txn_token")
+		record := logged(t)
+		if record["validator"] != "partner-idp" || record["txn"] != "txn-1" || record["token_type"] != "txn_token" {
+			t.Errorf("expected the request's fields, got %v", record)
+		}
+		if hash, _ := record["subject_hash"].(string); len(hash) != 16 || hash == "alice" {
+			t.Errorf("expected a subject hash, got %v", record["subject_hash"])
+		}
+	})
+
+	t.Run("logs records without a request unchanged", func(t *testing.T) {
+		ctx := context.Background()
+		AddAttrs(ctx, slog.String(ValidatorKey, "corp-idp"))
+
+		logger.InfoContext(ctx, "Reloaded JWKS")
+		if record := logged(t); record["validator"] != nil {
+			t.Errorf("expected no request fields, got %v", record)
+		}
+	})
+
+	t.Run("hashes subjects consistently", func(t *testing.T) {
+		if Subject("alice").Value.String() != Subject("alice").Value.String() {
+			t.Error("expected the same hash for the same subject")
+		}
+		if Subject("alice").Value.String() == Subject("bob").Value.String() {
+			t.Error("expected different hashes for different subjects")
+		}
+	})
+}
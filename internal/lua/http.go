@@ -3,23 +3,31 @@ package lua
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
 	lua "github.com/yuin/gopher-lua"
+
+	"github.com/alechenninger/parsec/internal/limits"
 )
 
+// DefaultMaxResponseBytes is the default cap on response bodies read by the HTTP service
+const DefaultMaxResponseBytes = 10 << 20 // 10 MiB
+
 // RequestOptions is a function that can modify a request before it is sent
 // This can be used to add authentication headers, modify URLs, etc.
 type RequestOptions func(*http.Request) error
 
 // HTTPService provides HTTP client functionality to Lua scripts
 type HTTPService struct {
-	client         *http.Client
-	timeout        time.Duration
-	requestOptions RequestOptions
+	client           *http.Client
+	timeout          time.Duration
+	requestOptions   RequestOptions
+	maxResponseBytes int64
+	truncationPolicy limits.Policy
 }
 
 // HTTPServiceConfig configures the HTTP service
@@ -34,6 +42,15 @@ type HTTPServiceConfig struct {
 	// Transport is the HTTP transport to use for requests
 	// If nil, uses http.DefaultTransport
 	Transport http.RoundTripper
+
+	// MaxResponseBytes caps how much of a response body is read into memory
+	// (default: DefaultMaxResponseBytes). A negative value disables the limit.
+	MaxResponseBytes int64
+
+	// TruncationPolicy decides what happens when a body exceeds MaxResponseBytes
+	// limits.PolicyError (default): the request fails, returning (nil, error)
+	// limits.PolicyTruncate: the body is cut at MaxResponseBytes and response.truncated is true
+	TruncationPolicy limits.Policy
 }
 
 // NewHTTPService creates a new HTTP service with configurable timeout
@@ -48,6 +65,12 @@ func NewHTTPServiceWithConfig(config HTTPServiceConfig) *HTTPService {
 	if config.Timeout == 0 {
 		config.Timeout = 30 * time.Second
 	}
+	if config.MaxResponseBytes == 0 {
+		config.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+	if config.TruncationPolicy == "" {
+		config.TruncationPolicy = limits.PolicyError
+	}
 
 	// Use provided transport or default
 	transport := config.Transport
@@ -60,8 +83,10 @@ func NewHTTPServiceWithConfig(config HTTPServiceConfig) *HTTPService {
 			Timeout:   config.Timeout,
 			Transport: transport,
 		},
-		timeout:        config.Timeout,
-		requestOptions: config.RequestOptions,
+		timeout:          config.Timeout,
+		requestOptions:   config.RequestOptions,
+		maxResponseBytes: config.MaxResponseBytes,
+		truncationPolicy: config.TruncationPolicy,
 	}
 }
 
@@ -85,7 +110,7 @@ func (s *HTTPService) Register(L *lua.LState) {
 
 // luaHTTPGet implements HTTP GET
 // Args: url (string), [headers (table)]
-// Returns: response table {status=int, body=string, headers=table} or (nil, error)
+// Returns: response table {status=int, body=string, headers=table, truncated=bool} or (nil, error)
 func (s *HTTPService) luaHTTPGet(L *lua.LState) int {
 	url := L.CheckString(1)
 	headers := s.parseHeaders(L, 2)
@@ -119,13 +144,19 @@ func (s *HTTPService) luaHTTPGet(L *lua.LState) int {
 	}
 	defer resp.Body.Close()
 
-	L.Push(s.responseToLua(L, resp))
+	tbl, err := s.responseToLua(L, resp)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(tbl)
 	return 1
 }
 
 // luaHTTPPost implements HTTP POST
 // Args: url (string), body (string), [headers (table)]
-// Returns: response table {status=int, body=string, headers=table} or (nil, error)
+// Returns: response table {status=int, body=string, headers=table, truncated=bool} or (nil, error)
 func (s *HTTPService) luaHTTPPost(L *lua.LState) int {
 	url := L.CheckString(1)
 	body := L.CheckString(2)
@@ -159,13 +190,19 @@ func (s *HTTPService) luaHTTPPost(L *lua.LState) int {
 	}
 	defer resp.Body.Close()
 
-	L.Push(s.responseToLua(L, resp))
+	tbl, err := s.responseToLua(L, resp)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(tbl)
 	return 1
 }
 
 // luaHTTPRequest implements a generic HTTP request
 // Args: method (string), url (string), [body (string)], [headers (table)]
-// Returns: response table {status=int, body=string, headers=table} or (nil, error)
+// Returns: response table {status=int, body=string, headers=table, truncated=bool} or (nil, error)
 func (s *HTTPService) luaHTTPRequest(L *lua.LState) int {
 	method := L.CheckString(1)
 	url := L.CheckString(2)
@@ -206,7 +243,13 @@ func (s *HTTPService) luaHTTPRequest(L *lua.LState) int {
 	}
 	defer resp.Body.Close()
 
-	L.Push(s.responseToLua(L, resp))
+	tbl, err := s.responseToLua(L, resp)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(tbl)
 	return 1
 }
 
@@ -234,20 +277,30 @@ func (s *HTTPService) parseHeaders(L *lua.LState, arg int) map[string]string {
 }
 
 // responseToLua converts an HTTP response to a Lua table
-func (s *HTTPService) responseToLua(L *lua.LState, resp *http.Response) *lua.LTable {
+// Returns an error if the body exceeds the configured size limit under the error policy
+func (s *HTTPService) responseToLua(L *lua.LState, resp *http.Response) (*lua.LTable, error) {
+	// Reject oversized bodies up front when the server declares their length
+	if s.maxResponseBytes > 0 && resp.ContentLength > s.maxResponseBytes && s.truncationPolicy != limits.PolicyTruncate {
+		return nil, fmt.Errorf("response body of %d bytes exceeds limit of %d bytes", resp.ContentLength, s.maxResponseBytes)
+	}
+
 	tbl := L.NewTable()
 
 	// Status code
 	L.SetField(tbl, "status", lua.LNumber(resp.StatusCode))
 
 	// Body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	bodyBytes, truncated, err := limits.ReadAll(resp.Body, s.maxResponseBytes, s.truncationPolicy)
+	if errors.Is(err, limits.ErrLimitExceeded) {
+		return nil, fmt.Errorf("response body exceeds limit of %d bytes", s.maxResponseBytes)
+	}
 	if err != nil {
 		L.SetField(tbl, "body", lua.LString(""))
 		L.SetField(tbl, "error", lua.LString(fmt.Sprintf("failed to read body: %v", err)))
 	} else {
 		L.SetField(tbl, "body", lua.LString(string(bodyBytes)))
 	}
+	L.SetField(tbl, "truncated", lua.LBool(truncated))
 
 	// Headers
 	headersTbl := L.NewTable()
@@ -258,7 +311,7 @@ func (s *HTTPService) responseToLua(L *lua.LState, resp *http.Response) *lua.LTa
 	}
 	L.SetField(tbl, "headers", headersTbl)
 
-	return tbl
+	return tbl, nil
 }
 
 // WithContext allows setting a context for requests (useful for cancellation)
@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	lua "github.com/yuin/gopher-lua"
+
+	"github.com/alechenninger/parsec/internal/limits"
 )
 
 func TestHTTPService_Get(t *testing.T) {
@@ -291,3 +294,66 @@ func TestHTTPService_ResponseHeaders(t *testing.T) {
 		t.Errorf("headers = %q, want %q", lua.LVAsString(result), expected)
 	}
 }
+
+func TestHTTPService_MaxResponseBytes(t *testing.T) {
+	body := strings.Repeat("x", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Chunked on /chunked so the limit is enforced while reading rather than from Content-Length
+		if r.URL.Path == "/chunked" {
+			w.Write([]byte(body[:50]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(body[50:]))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		max    int64
+		policy limits.Policy
+		want   string
+	}{
+		{name: "within limit", path: "/", max: 100, want: "ok:100:false"},
+		{name: "unlimited", path: "/", max: -1, want: "ok:100:false"},
+		{name: "declared length over limit", path: "/", max: 10, want: "error:response body of 100 bytes exceeds limit of 10 bytes"},
+		{name: "streamed body over limit", path: "/chunked", max: 10, want: "error:response body exceeds limit of 10 bytes"},
+		{name: "truncated", path: "/", max: 10, policy: limits.PolicyTruncate, want: "ok:10:true"},
+		{name: "streamed body truncated", path: "/chunked", max: 10, policy: limits.PolicyTruncate, want: "ok:10:true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			L := lua.NewState()
+			defer L.Close()
+
+			service := NewHTTPServiceWithConfig(HTTPServiceConfig{
+				Timeout:          5 * time.Second,
+				MaxResponseBytes: tt.max,
+				TruncationPolicy: tt.policy,
+			})
+			service.Register(L)
+
+			script := `
+				local response, err = http.get("` + server.URL + tt.path + `")
+				if response == nil then
+					return "error:" .. err
+				end
+				return "ok:" .. #response.body .. ":" .. tostring(response.truncated)
+			`
+
+			if err := L.DoString(script); err != nil {
+				t.Fatalf("script execution failed: %v", err)
+			}
+
+			result := L.Get(-1)
+			L.Pop(1)
+
+			if got := lua.LVAsString(result); got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,23 +3,45 @@ package lua
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	lua "github.com/yuin/gopher-lua"
+
+	"github.com/alechenninger/parsec/internal/limits"
 )
 
 // JSONService provides JSON encoding/decoding functionality to Lua scripts
-type JSONService struct{}
+type JSONService struct {
+	limits limits.JSON
+}
+
+// JSONServiceConfig configures the JSON service
+type JSONServiceConfig struct {
+	// Limits bound json.decode and json.each (default: unlimited)
+	// Scripts may tighten or override them per call with an options table.
+	Limits limits.JSON
+}
 
 // NewJSONService creates a new JSON service
 func NewJSONService() *JSONService {
-	return &JSONService{}
+	return NewJSONServiceWithConfig(JSONServiceConfig{})
+}
+
+// NewJSONServiceWithConfig creates a new JSON service with decoding limits
+func NewJSONServiceWithConfig(config JSONServiceConfig) *JSONService {
+	if config.Limits.Policy == "" {
+		config.Limits.Policy = limits.PolicyError
+	}
+	return &JSONService{limits: config.Limits}
 }
 
 // Register adds the JSON service to the Lua state
 // Usage in Lua:
 //
 //	local obj = json.decode('{"key": "value"}')
+//	local obj = json.decode(body, {max_array_items = 100, truncation = "truncate"})
 //	local str = json.encode({key = "value"})
+//	json.each('[1, 2, 3]', function(i, item) ... end)
 func (s *JSONService) Register(L *lua.LState) {
 	// Create JSON module table
 	mod := L.NewTable()
@@ -27,6 +49,7 @@ func (s *JSONService) Register(L *lua.LState) {
 	// Register functions
 	L.SetField(mod, "encode", L.NewFunction(s.luaJSONEncode))
 	L.SetField(mod, "decode", L.NewFunction(s.luaJSONDecode))
+	L.SetField(mod, "each", L.NewFunction(s.luaJSONEach))
 
 	// Set the module as a global
 	L.SetGlobal("json", mod)
@@ -51,13 +74,18 @@ func (s *JSONService) luaJSONEncode(L *lua.LState) int {
 }
 
 // luaJSONDecode decodes a JSON string to a Lua value
-// Args: json_string (string)
-// Returns: value or (nil, error)
+// Args: json_string (string), [options (table)]
+// Returns: value, nil, truncated (bool) or (nil, error)
 func (s *JSONService) luaJSONDecode(L *lua.LState) int {
 	jsonStr := L.CheckString(1)
+	opts, err := s.decodeLimits(L, 2)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
 
-	var goValue interface{}
-	err := json.Unmarshal([]byte(jsonStr), &goValue)
+	goValue, truncated, err := limits.DecodeJSON(strings.NewReader(jsonStr), opts)
 	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(fmt.Sprintf("failed to decode JSON: %v", err)))
@@ -65,9 +93,89 @@ func (s *JSONService) luaJSONDecode(L *lua.LState) int {
 	}
 
 	L.Push(GoToLua(L, goValue))
+	if truncated {
+		L.Push(lua.LNil)
+		L.Push(lua.LTrue)
+		return 3
+	}
+	return 1
+}
+
+// luaJSONEach iterates a JSON array, decoding one element at a time
+// so large arrays never need to be materialized as a whole Lua table.
+// The callback receives a 1-based index and the element; returning false stops iteration.
+// Args: json_string (string), callback (function), [options (table)]
+// Returns: true, nil, truncated (bool) or (nil, error)
+func (s *JSONService) luaJSONEach(L *lua.LState) int {
+	jsonStr := L.CheckString(1)
+	fn := L.CheckFunction(2)
+	opts, err := s.decodeLimits(L, 3)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	var callbackErr error
+	truncated, err := limits.EachJSONArrayItem(strings.NewReader(jsonStr), opts, func(index int, value any) (bool, error) {
+		if err := L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, lua.LNumber(index+1), GoToLua(L, value)); err != nil {
+			callbackErr = err
+			return false, err
+		}
+		ret := L.Get(-1)
+		L.Pop(1)
+		return ret != lua.LFalse, nil
+	})
+	if err != nil {
+		L.Push(lua.LNil)
+		if callbackErr != nil {
+			L.Push(lua.LString(callbackErr.Error()))
+		} else {
+			L.Push(lua.LString(fmt.Sprintf("failed to decode JSON: %v", err)))
+		}
+		return 2
+	}
+
+	L.Push(lua.LTrue)
+	if truncated {
+		L.Push(lua.LNil)
+		L.Push(lua.LTrue)
+		return 3
+	}
 	return 1
 }
 
+// decodeLimits returns the service limits, overridden by an optional options table at arg
+// Recognized options: max_bytes, max_depth, max_array_items (numbers), truncation ("error" or "truncate")
+func (s *JSONService) decodeLimits(L *lua.LState, arg int) (limits.JSON, error) {
+	opts := s.limits
+	if L.GetTop() < arg {
+		return opts, nil
+	}
+	tbl, ok := L.Get(arg).(*lua.LTable)
+	if !ok {
+		return opts, nil
+	}
+
+	if v, ok := tbl.RawGetString("max_bytes").(lua.LNumber); ok {
+		opts.MaxBytes = int64(v)
+	}
+	if v, ok := tbl.RawGetString("max_depth").(lua.LNumber); ok {
+		opts.MaxDepth = int(v)
+	}
+	if v, ok := tbl.RawGetString("max_array_items").(lua.LNumber); ok {
+		opts.MaxArrayItems = int(v)
+	}
+	if v, ok := tbl.RawGetString("truncation").(lua.LString); ok {
+		policy, err := limits.ParsePolicy(string(v))
+		if err != nil {
+			return limits.JSON{}, err
+		}
+		opts.Policy = policy
+	}
+	return opts, nil
+}
+
 // LuaToGo converts a Lua value to a Go value
 func LuaToGo(lv lua.LValue) interface{} {
 	switch v := lv.(type) {
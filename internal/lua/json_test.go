@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	lua "github.com/yuin/gopher-lua"
+
+	"github.com/alechenninger/parsec/internal/limits"
 )
 
 func TestJSONService_Encode(t *testing.T) {
@@ -202,3 +204,160 @@ func TestJSONService_RoundTrip(t *testing.T) {
 		t.Errorf("round trip result = %q, want %q", got, expected)
 	}
 }
+
+func TestJSONService_DecodeLimits(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	service := NewJSONServiceWithConfig(JSONServiceConfig{
+		Limits: limits.JSON{MaxArrayItems: 2},
+	})
+	service.Register(L)
+
+	tests := []struct {
+		name   string
+		script string
+		expect string
+	}{
+		{
+			name: "service limit errors",
+			script: `
+				local arr, err = json.decode('[1,2,3]')
+				if arr == nil then return "error" end
+				return "ok"
+			`,
+			expect: "error",
+		},
+		{
+			name: "truncate per call",
+			script: `
+				local arr, err, truncated = json.decode('[1,2,3]', {truncation = "truncate"})
+				return #arr .. ":" .. tostring(truncated)
+			`,
+			expect: "2:true",
+		},
+		{
+			name: "override limit per call",
+			script: `
+				local arr = json.decode('[1,2,3]', {max_array_items = 5})
+				return tostring(#arr)
+			`,
+			expect: "3",
+		},
+		{
+			name: "max depth",
+			script: `
+				local obj, err = json.decode('{"a":{"b":{}}}', {max_depth = 2})
+				if obj == nil then return "error" end
+				return "ok"
+			`,
+			expect: "error",
+		},
+		{
+			name: "invalid truncation option",
+			script: `
+				local obj, err = json.decode('[]', {truncation = "drop"})
+				if obj == nil then return "error" end
+				return "ok"
+			`,
+			expect: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := L.DoString(tt.script); err != nil {
+				t.Fatalf("script execution failed: %v", err)
+			}
+
+			result := L.Get(-1)
+			L.Pop(1)
+
+			if got := lua.LVAsString(result); got != tt.expect {
+				t.Errorf("result = %q, want %q", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestJSONService_Each(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	service := NewJSONService()
+	service.Register(L)
+
+	tests := []struct {
+		name   string
+		script string
+		expect string
+	}{
+		{
+			name: "visits every item",
+			script: `
+				local ids = {}
+				local ok = json.each('[{"id":"a"},{"id":"b"},{"id":"c"}]', function(i, item)
+					table.insert(ids, i .. "=" .. item.id)
+				end)
+				return tostring(ok) .. ":" .. table.concat(ids, ",")
+			`,
+			expect: "true:1=a,2=b,3=c",
+		},
+		{
+			name: "stops when callback returns false",
+			script: `
+				local count = 0
+				json.each('[1,2,3,4]', function(i, item)
+					count = count + 1
+					return i < 2
+				end)
+				return tostring(count)
+			`,
+			expect: "2",
+		},
+		{
+			name: "truncates at max items",
+			script: `
+				local count = 0
+				local ok, err, truncated = json.each('[1,2,3,4]', function(i, item)
+					count = count + 1
+				end, {max_array_items = 3, truncation = "truncate"})
+				return count .. ":" .. tostring(truncated)
+			`,
+			expect: "3:true",
+		},
+		{
+			name: "rejects non-array",
+			script: `
+				local ok, err = json.each('{"a":1}', function(i, item) end)
+				if ok == nil then return "error" end
+				return "ok"
+			`,
+			expect: "error",
+		},
+		{
+			name: "reports callback errors",
+			script: `
+				local ok, err = json.each('[1]', function(i, item) error("boom") end)
+				if ok == nil and string.find(err, "boom") then return "error" end
+				return "ok"
+			`,
+			expect: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := L.DoString(tt.script); err != nil {
+				t.Fatalf("script execution failed: %v", err)
+			}
+
+			result := L.Get(-1)
+			L.Pop(1)
+
+			if got := lua.LVAsString(result); got != tt.expect {
+				t.Errorf("result = %q, want %q", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package mapper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	celhelpers "github.com/alechenninger/parsec/internal/cel"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// CELAuthzRules is a service.AuthorizationPolicy of CEL rules that deny requests,
+// deciding whether to issue tokens at all rather than what claims they carry
+// Rules see the same variables and functions as CEL claim mappers. The first rule
+// whose expression is true denies the request with its reason.
+//
+// Example rules:
+//
+//	// Suspended accounts get no tokens
+//	datasource("account").suspended
+//
+//	// Only the payments service may act for subjects on /payments
+//	request.path.startsWith("/payments") && (actor == null || actor.subject != "spiffe://example.org/payments")
+type CELAuthzRules struct {
+	rules       []compiledAuthzRule
+	dataSources *service.DataSourceRegistry
+}
+
+// CELAuthzRule denies requests for which Deny is true
+type CELAuthzRule struct {
+	// Name identifies the rule in denials
+	Name string
+
+	// Deny is a CEL expression that evaluates to true to deny a request
+	Deny string
+
+	// Reason explains denials (default: the rule's name)
+	Reason string
+}
+
+type compiledAuthzRule struct {
+	name   string
+	reason string
+	ast    *cel.Ast
+}
+
+// NewCELAuthzRules compiles rules that read data sources from dataSources
+func NewCELAuthzRules(rules []CELAuthzRule, dataSources *service.DataSourceRegistry) (*CELAuthzRules, error) {
+	env, err := cel.NewEnv(
+		celhelpers.MapperInputLibrary(context.Background(), nil, nil),
+		celhelpers.RedHatHelpersLibrary(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	compiled := make([]compiledAuthzRule, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("authz rule %d: name is required", i)
+		}
+		if rule.Deny == "" {
+			return nil, fmt.Errorf("authz rule %s: deny is required", rule.Name)
+		}
+		ast, issues := env.Compile(rule.Deny)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("authz rule %s: failed to compile CEL expression: %w", rule.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("authz rule %s: deny must evaluate to a bool, got %s", rule.Name, ast.OutputType())
+		}
+		reason := rule.Reason
+		if reason == "" {
+			reason = rule.Name
+		}
+		compiled[i] = compiledAuthzRule{name: rule.Name, reason: reason, ast: ast}
+	}
+
+	return &CELAuthzRules{rules: compiled, dataSources: dataSources}, nil
+}
+
+// Authorize implements service.AuthorizationPolicy
+func (r *CELAuthzRules) Authorize(ctx context.Context, req *service.IssueRequest) error {
+	input := &service.MapperInput{
+		Subject:            req.Subject,
+		Actor:              req.Actor,
+		Workload:           req.Workload,
+		RequestAttributes:  req.RequestAttributes,
+		DataSourceRegistry: r.dataSources,
+		DataSourceInput: &service.DataSourceInput{
+			Subject:           req.Subject,
+			Actor:             req.Actor,
+			RequestAttributes: req.RequestAttributes,
+		},
+	}
+
+	// One environment for all rules, so each data source is fetched at most once
+	env, err := cel.NewEnv(
+		celhelpers.MapperInputLibrary(ctx, input.DataSourceRegistry, input.DataSourceInput),
+		celhelpers.RedHatHelpersLibrary(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	activation := celActivation(input)
+
+	for _, rule := range r.rules {
+		program, err := env.Program(rule.ast)
+		if err != nil {
+			return fmt.Errorf("authz rule %s: failed to create CEL program: %w", rule.name, err)
+		}
+		result, _, err := program.Eval(activation)
+		if err != nil {
+			return fmt.Errorf("authz rule %s: failed to evaluate CEL expression: %w", rule.name, err)
+		}
+		deny, ok := result.(types.Bool)
+		if !ok {
+			return fmt.Errorf("authz rule %s: deny must evaluate to a bool, got %s", rule.name, result.Type())
+		}
+		if deny {
+			return fmt.Errorf("%w: %s", service.ErrIssuanceDenied, rule.reason)
+		}
+	}
+	return nil
+}
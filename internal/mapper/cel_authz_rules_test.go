@@ -0,0 +1,64 @@
+package mapper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestCELAuthzRules(t *testing.T) {
+	ctx := context.Background()
+
+	registry := service.NewDataSourceRegistry()
+	registry.Register(&mockDataSource{
+		name: "account",
+		data: map[string]any{"suspended": true},
+	})
+
+	rules, err := NewCELAuthzRules([]CELAuthzRule{
+		{Name: "admin-only", Deny: `request.path.startsWith("/admin") && subject.subject != "admin"`, Reason: "admin only"},
+		{Name: "suspended", Deny: `subject.subject == "mallory" && datasource("account").suspended`},
+	}, registry)
+	if err != nil {
+		t.Fatalf("NewCELAuthzRules failed: %v", err)
+	}
+
+	authorize := func(subject, path string) error {
+		return rules.Authorize(ctx, &service.IssueRequest{
+			Subject:           &trust.Result{Subject: subject},
+			RequestAttributes: &request.RequestAttributes{Path: path},
+		})
+	}
+
+	t.Run("allows requests no rule denies", func(t *testing.T) {
+		if err := authorize("alice", "/orders"); err != nil {
+			t.Errorf("expected request to be allowed, got %v", err)
+		}
+		if err := authorize("admin", "/admin/users"); err != nil {
+			t.Errorf("expected request to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("denies with the first matching rule's reason", func(t *testing.T) {
+		err := authorize("alice", "/admin/users")
+		if !errors.Is(err, service.ErrIssuanceDenied) || !strings.Contains(err.Error(), "admin only") {
+			t.Errorf("expected denial with reason, got %v", err)
+		}
+		err = authorize("mallory", "/orders")
+		if !errors.Is(err, service.ErrIssuanceDenied) || !strings.Contains(err.Error(), "suspended") {
+			t.Errorf("expected denial reading the data source, got %v", err)
+		}
+	})
+
+	t.Run("rejects rules that are not boolean", func(t *testing.T) {
+		_, err := NewCELAuthzRules([]CELAuthzRule{{Name: "bad", Deny: `"yes"`}}, registry)
+		if err == nil {
+			t.Error("expected a string expression to be rejected")
+		}
+	})
+}
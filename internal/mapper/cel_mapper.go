@@ -100,7 +100,7 @@ func (m *CELMapper) Map(ctx context.Context, input *service.MapperInput) (claims
 	}
 
 	// Create activation with variables for this invocation
-	activation := m.createActivation(ctx, input)
+	activation := celActivation(input)
 
 	// Evaluate the program with the activation
 	result, _, err := program.Eval(activation)
@@ -128,11 +128,11 @@ func (m *CELMapper) Script() string {
 	return m.script
 }
 
-// createActivation creates a CEL activation with variables
-func (m *CELMapper) createActivation(ctx context.Context, input *service.MapperInput) map[string]any {
+// celActivation creates a CEL activation with variables
+func celActivation(input *service.MapperInput) map[string]any {
 	activation := map[string]any{
-		// subject, actor, and request are provided as direct values
-		// Access them in CEL as: subject.field, actor.field, request.field
+		// subject, actor, workload, and request are provided as direct values
+		// Access them in CEL as: subject.field, actor.field, workload.field, request.field
 		"subject": func() any {
 			if input.Subject == nil {
 				return nil
@@ -147,19 +147,39 @@ func (m *CELMapper) createActivation(ctx context.Context, input *service.MapperI
 			return trustResultToMap(input.Actor)
 		}(),
 
+		"workload": func() any {
+			if input.Workload == nil {
+				return nil
+			}
+			return trustResultToMap(input.Workload)
+		}(),
+
 		"request": func() any {
 			if input.RequestAttributes == nil {
 				return nil
 			}
 
-			return map[string]any{
-				"method":     input.RequestAttributes.Method,
-				"path":       input.RequestAttributes.Path,
-				"ip_address": input.RequestAttributes.IPAddress,
-				"user_agent": input.RequestAttributes.UserAgent,
-				"headers":    input.RequestAttributes.Headers,
-				"additional": input.RequestAttributes.Additional,
+			req := map[string]any{
+				"method":        input.RequestAttributes.Method,
+				"path":          input.RequestAttributes.Path,
+				"ip_address":    input.RequestAttributes.IPAddress,
+				"user_agent":    input.RequestAttributes.UserAgent,
+				"protocol":      input.RequestAttributes.Protocol,
+				"authority":     input.RequestAttributes.Authority,
+				"content_type":  input.RequestAttributes.ContentType,
+				"headers":       input.RequestAttributes.Headers.Combined(),
+				"header_values": map[string][]string(input.RequestAttributes.Headers),
+				"additional":    input.RequestAttributes.Additional,
+			}
+			// grpc is only present for gRPC calls; check with has(request.grpc)
+			if grpc := input.RequestAttributes.GRPC; grpc != nil {
+				req["grpc"] = map[string]any{
+					"service":   grpc.Service,
+					"method":    grpc.Method,
+					"authority": grpc.Authority,
+				}
 			}
+			return req
 		}(),
 	}
 
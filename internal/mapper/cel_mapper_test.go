@@ -273,6 +273,57 @@ func TestCELMapper_Map(t *testing.T) {
 		}
 	})
 
+	t.Run("access gRPC request attributes", func(t *testing.T) {
+		mapper, err := NewCELMapper(`has(request.grpc) ? {
+			"rpc": request.grpc.service + "/" + request.grpc.method,
+			"content_type": request.content_type,
+			"authority": request.authority
+		} : {"rpc": "none"}`)
+		if err != nil {
+			t.Fatalf("failed to create mapper: %v", err)
+		}
+
+		grpcInput := &service.MapperInput{
+			RequestAttributes: &request.RequestAttributes{
+				Method:      "POST",
+				Path:        "/parsec.v1.TokenExchange/Exchange",
+				Protocol:    "HTTP/2",
+				Authority:   "parsec.example.com",
+				ContentType: "application/grpc",
+				GRPC:        &request.GRPCAttributes{Service: "parsec.v1.TokenExchange", Method: "Exchange"},
+			},
+		}
+
+		result, err := mapper.Map(ctx, grpcInput)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["rpc"] != "parsec.v1.TokenExchange/Exchange" {
+			t.Errorf("expected rpc=parsec.v1.TokenExchange/Exchange, got %v", result["rpc"])
+		}
+		if result["content_type"] != "application/grpc" {
+			t.Errorf("expected content_type=application/grpc, got %v", result["content_type"])
+		}
+		if result["authority"] != "parsec.example.com" {
+			t.Errorf("expected authority=parsec.example.com, got %v", result["authority"])
+		}
+
+		httpInput := &service.MapperInput{
+			RequestAttributes: &request.RequestAttributes{
+				Method: "GET",
+				Path:   "/api/resource",
+			},
+		}
+
+		result, err = mapper.Map(ctx, httpInput)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result["rpc"] != "none" {
+			t.Errorf("expected rpc=none for plain HTTP, got %v", result["rpc"])
+		}
+	})
+
 	t.Run("access datasource", func(t *testing.T) {
 		mapper, err := NewCELMapper(`{
 			"roles": datasource("user_roles").roles,
@@ -0,0 +1,90 @@
+package mapper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	celhelpers "github.com/alechenninger/parsec/internal/cel"
+	"github.com/alechenninger/parsec/internal/scope"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// CELTTLPolicy is a service.TTLPolicy that computes the lifetime of each token with a
+// CEL expression. The expression sees the same variables and functions as CEL claim
+// mappers, plus the token's scopes and audiences, and evaluates to a duration, or
+// null for the issuer's TTL.
+//
+// Example:
+//
+//	"admin" in scopes ? duration("2m") :
+//	actor != null && actor.subject.startsWith("spiffe://example.org/batch/") ? duration("1h") :
+//	null
+type CELTTLPolicy struct {
+	ast *cel.Ast
+}
+
+// NewCELTTLPolicy compiles a TTL policy expression
+func NewCELTTLPolicy(expression string) (*CELTTLPolicy, error) {
+	env, err := ttlPolicyEnv(context.Background(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile ttl expression: %w", issues.Err())
+	}
+	switch ast.OutputType() {
+	case cel.DurationType, cel.DynType, cel.NullType:
+	default:
+		return nil, fmt.Errorf("ttl expression must evaluate to a duration, got %s", ast.OutputType())
+	}
+	return &CELTTLPolicy{ast: ast}, nil
+}
+
+// TTL implements service.TTLPolicy
+func (p *CELTTLPolicy) TTL(ctx context.Context, issueCtx *service.IssueContext) (time.Duration, error) {
+	input := issueCtx.MapperInput()
+	env, err := ttlPolicyEnv(ctx, input.DataSourceRegistry, input.DataSourceInput)
+	if err != nil {
+		return 0, err
+	}
+	program, err := env.Program(p.ast)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	activation := celActivation(input)
+	activation["scopes"] = scope.Parse(issueCtx.Scope)
+	activation["audiences"] = issueCtx.Audiences
+	result, _, err := program.Eval(activation)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate ttl expression: %w", err)
+	}
+
+	switch result := result.(type) {
+	case types.Duration:
+		return result.Duration, nil
+	case types.Null:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("ttl expression must evaluate to a duration, got %s", result.Type())
+	}
+}
+
+// ttlPolicyEnv returns the CEL environment of TTL policies
+func ttlPolicyEnv(ctx context.Context, dataSources *service.DataSourceRegistry, input *service.DataSourceInput) (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		celhelpers.MapperInputLibrary(ctx, dataSources, input),
+		celhelpers.RedHatHelpersLibrary(),
+		cel.Variable("scopes", cel.ListType(cel.StringType)),
+		cel.Variable("audiences", cel.ListType(cel.StringType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	return env, nil
+}
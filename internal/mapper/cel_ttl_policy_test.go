@@ -0,0 +1,70 @@
+package mapper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestCELTTLPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	policy, err := NewCELTTLPolicy(`
+		"admin" in scopes ? duration("2m") :
+		actor != null && actor.subject.startsWith("spiffe://example.org/batch/") ? duration("1h") :
+		"reports.example.com" in audiences ? duration("15m") :
+		null`)
+	if err != nil {
+		t.Fatalf("NewCELTTLPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		issueCtx *service.IssueContext
+		want     time.Duration
+	}{
+		{
+			name:     "by scope",
+			issueCtx: &service.IssueContext{Subject: &trust.Result{Subject: "alice"}, Scope: "orders:read admin"},
+			want:     2 * time.Minute,
+		},
+		{
+			name: "by actor",
+			issueCtx: &service.IssueContext{
+				Subject: &trust.Result{Subject: "alice"},
+				Actor:   &trust.Result{Subject: "spiffe://example.org/batch/nightly"},
+			},
+			want: time.Hour,
+		},
+		{
+			name:     "by audience",
+			issueCtx: &service.IssueContext{Subject: &trust.Result{Subject: "alice"}, Audiences: []string{"reports.example.com"}},
+			want:     15 * time.Minute,
+		},
+		{
+			name:     "null for the issuer's ttl",
+			issueCtx: &service.IssueContext{Subject: &trust.Result{Subject: "alice"}},
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.TTL(ctx, tt.issueCtx)
+			if err != nil {
+				t.Fatalf("TTL failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("rejects expressions that are not durations", func(t *testing.T) {
+		if _, err := NewCELTTLPolicy(`"5m"`); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
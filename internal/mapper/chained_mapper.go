@@ -0,0 +1,95 @@
+package mapper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	celhelpers "github.com/alechenninger/parsec/internal/cel"
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// ChainedMapper is a ClaimMapper that runs another only when a CEL condition holds,
+// and merges its claims into those of the mappers before it with a strategy
+//
+// The condition sees the same variables and functions as CEL claim mappers, e.g.:
+//
+//	subject.trust_domain == "partners.example.com"
+//	has(request.headers["x-tenant"])
+type ChainedMapper struct {
+	mapper   service.ClaimMapper
+	when     *cel.Ast
+	strategy claims.MergeStrategy
+}
+
+// NewChainedMapper creates a ChainedMapper running mapper when when is true, or
+// always if it is empty, whose claims are merged with strategy
+func NewChainedMapper(mapper service.ClaimMapper, when string, strategy claims.MergeStrategy) (*ChainedMapper, error) {
+	m := &ChainedMapper{mapper: mapper, strategy: strategy}
+	if when == "" {
+		return m, nil
+	}
+
+	env, err := cel.NewEnv(
+		celhelpers.MapperInputLibrary(context.Background(), nil, nil),
+		celhelpers.RedHatHelpersLibrary(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	ast, issues := env.Compile(when)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile when condition: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("when condition must evaluate to a bool, got %s", ast.OutputType())
+	}
+	m.when = ast
+	return m, nil
+}
+
+// Map implements service.ClaimMapper, contributing nothing if the condition is false
+func (m *ChainedMapper) Map(ctx context.Context, input *service.MapperInput) (claims.Claims, error) {
+	if m.when != nil {
+		ok, err := m.evaluate(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, nil
+		}
+	}
+	return m.mapper.Map(ctx, input)
+}
+
+// MergeStrategy implements service.MergingClaimMapper
+func (m *ChainedMapper) MergeStrategy() claims.MergeStrategy {
+	return m.strategy
+}
+
+// evaluate evaluates the when condition
+func (m *ChainedMapper) evaluate(ctx context.Context, input *service.MapperInput) (bool, error) {
+	env, err := cel.NewEnv(
+		celhelpers.MapperInputLibrary(ctx, input.DataSourceRegistry, input.DataSourceInput),
+		celhelpers.RedHatHelpersLibrary(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	program, err := env.Program(m.when)
+	if err != nil {
+		return false, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+	result, _, err := program.Eval(celActivation(input))
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate when condition: %w", err)
+	}
+	ok, isBool := result.(types.Bool)
+	if !isBool {
+		return false, fmt.Errorf("when condition must evaluate to a bool, got %s", result.Type())
+	}
+	return bool(ok), nil
+}
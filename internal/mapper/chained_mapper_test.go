@@ -0,0 +1,79 @@
+package mapper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestChainedMapper(t *testing.T) {
+	ctx := context.Background()
+
+	chained := func(t *testing.T, fixed claims.Claims, when string, strategy claims.MergeStrategy) service.ClaimMapper {
+		t.Helper()
+		m, err := NewChainedMapper(service.NewStubClaimMapper(fixed), when, strategy)
+		if err != nil {
+			t.Fatalf("NewChainedMapper failed: %v", err)
+		}
+		return m
+	}
+
+	issueCtx := &service.IssueContext{
+		Subject: &trust.Result{Subject: "alice", TrustDomain: "partners.example.com"},
+	}
+
+	t.Run("runs mappers whose condition holds", func(t *testing.T) {
+		got, err := issueCtx.ToClaims(ctx, []service.ClaimMapper{
+			chained(t, claims.Claims{"partner": true}, `subject.trust_domain == "partners.example.com"`, claims.MergeOverwrite),
+			chained(t, claims.Claims{"employee": true}, `subject.trust_domain == "example.com"`, claims.MergeOverwrite),
+		})
+		if err != nil {
+			t.Fatalf("ToClaims failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, claims.Claims{"partner": true}) {
+			t.Errorf("unexpected claims: %v", got)
+		}
+	})
+
+	t.Run("deep merges objects", func(t *testing.T) {
+		got, err := issueCtx.ToClaims(ctx, []service.ClaimMapper{
+			chained(t, claims.Claims{"org": map[string]any{"id": "acme", "tier": "free"}}, "", claims.MergeOverwrite),
+			chained(t, claims.Claims{"org": map[string]any{"tier": "gold"}}, "", claims.MergeDeep),
+		})
+		if err != nil {
+			t.Fatalf("ToClaims failed: %v", err)
+		}
+		want := claims.Claims{"org": map[string]any{"id": "acme", "tier": "gold"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("fails on conflicts", func(t *testing.T) {
+		_, err := issueCtx.ToClaims(ctx, []service.ClaimMapper{
+			chained(t, claims.Claims{"role": "admin"}, "", claims.MergeOverwrite),
+			chained(t, claims.Claims{"role": "viewer"}, "", claims.MergeErrorOnConflict),
+		})
+		if err == nil {
+			t.Error("expected error for conflicting claims")
+		}
+
+		_, err = issueCtx.ToClaims(ctx, []service.ClaimMapper{
+			chained(t, claims.Claims{"role": "admin"}, "", claims.MergeOverwrite),
+			chained(t, claims.Claims{"role": "admin"}, "", claims.MergeErrorOnConflict),
+		})
+		if err != nil {
+			t.Errorf("expected equal values not to conflict, got %v", err)
+		}
+	})
+
+	t.Run("rejects non-bool conditions", func(t *testing.T) {
+		if _, err := NewChainedMapper(service.NewStubClaimMapper(nil), `"yes"`, claims.MergeOverwrite); err == nil {
+			t.Error("expected error for a non-bool condition")
+		}
+	})
+}
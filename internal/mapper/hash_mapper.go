@@ -0,0 +1,163 @@
+package mapper
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// DefaultHashClaim is the default claim holding the hashed request attributes
+const DefaultHashClaim = "hashes"
+
+// RequestAttributeHashMapper is a ClaimMapper that emits keyed hashes of selected
+// request attributes instead of their raw values, so downstream services can
+// correlate requests (e.g., for abuse detection) without receiving PII.
+//
+// Each hash is an HMAC-SHA256 of the attribute name and value, keyed with a salt
+// derived from a secret. With a rotation period, the salt changes every period,
+// so hashes only correlate within the same period; the period's epoch number is
+// included alongside the hashes. Replicas must share the secret to agree on hashes.
+//
+// The claim looks like:
+//
+//	{"hashes": {"ip_address": "q1w2...", "user_agent": "e3r4...", "epoch": 20123}}
+type RequestAttributeHashMapper struct {
+	attributes     []string
+	secret         []byte
+	rotationPeriod time.Duration
+	claim          string
+	clock          clock.Clock
+}
+
+// RequestAttributeHashMapperConfig configures a RequestAttributeHashMapper
+type RequestAttributeHashMapperConfig struct {
+	// Attributes are the request attributes to hash (default: ip_address, user_agent).
+	// Supported: ip_address, user_agent, method, path, authority, and headers.<name>.
+	Attributes []string
+
+	// Secret keys the hashes. Without it, hashes of low-entropy values like IP
+	// addresses could be reversed by brute force.
+	Secret []byte
+
+	// RotationPeriod is how often the salt changes (default: never)
+	RotationPeriod time.Duration
+
+	// Claim is the claim holding the hashes (default: DefaultHashClaim)
+	Claim string
+
+	// Clock determines the current rotation epoch (default: system clock)
+	Clock clock.Clock
+}
+
+// NewRequestAttributeHashMapper creates a new request attribute hash mapper
+func NewRequestAttributeHashMapper(cfg RequestAttributeHashMapperConfig) (*RequestAttributeHashMapper, error) {
+	if len(cfg.Secret) < 16 {
+		return nil, fmt.Errorf("hash mapper secret must be at least 16 bytes")
+	}
+	if cfg.RotationPeriod < 0 || (cfg.RotationPeriod > 0 && cfg.RotationPeriod < time.Second) {
+		return nil, fmt.Errorf("hash mapper rotation period must be at least 1s")
+	}
+
+	attributes := cfg.Attributes
+	if len(attributes) == 0 {
+		attributes = []string{"ip_address", "user_agent"}
+	}
+	for _, attribute := range attributes {
+		if !isHashableAttribute(attribute) {
+			return nil, fmt.Errorf("unsupported attribute to hash: %s (supported: ip_address, user_agent, method, path, authority, headers.<name>)", attribute)
+		}
+	}
+
+	claim := cfg.Claim
+	if claim == "" {
+		claim = DefaultHashClaim
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &RequestAttributeHashMapper{
+		attributes:     attributes,
+		secret:         cfg.Secret,
+		rotationPeriod: cfg.RotationPeriod,
+		claim:          claim,
+		clock:          clk,
+	}, nil
+}
+
+// Map implements the ClaimMapper interface
+func (m *RequestAttributeHashMapper) Map(ctx context.Context, input *service.MapperInput) (claims.Claims, error) {
+	if input.RequestAttributes == nil {
+		return nil, nil
+	}
+
+	salt := m.secret
+	var epoch int64
+	if m.rotationPeriod > 0 {
+		epoch = m.clock.Now().Unix() / int64(m.rotationPeriod/time.Second)
+		salt = m.hmac(m.secret, "epoch:"+strconv.FormatInt(epoch, 10))
+	}
+
+	hashes := make(map[string]any)
+	for _, attribute := range m.attributes {
+		value := attributeValue(input.RequestAttributes, attribute)
+		if value == "" {
+			continue
+		}
+		// The attribute name is part of the input so equal values of
+		// different attributes do not correlate
+		hashes[attribute] = base64.RawURLEncoding.EncodeToString(m.hmac(salt, attribute+"\x00"+value))
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	if m.rotationPeriod > 0 {
+		hashes["epoch"] = epoch
+	}
+
+	return claims.Claims{m.claim: hashes}, nil
+}
+
+func (m *RequestAttributeHashMapper) hmac(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func isHashableAttribute(attribute string) bool {
+	switch attribute {
+	case "ip_address", "user_agent", "method", "path", "authority":
+		return true
+	}
+	name, ok := strings.CutPrefix(attribute, "headers.")
+	return ok && name != ""
+}
+
+// attributeValue returns the value of a hashable attribute, or "" if it is absent
+func attributeValue(attrs *request.RequestAttributes, attribute string) string {
+	switch attribute {
+	case "ip_address":
+		return attrs.IPAddress
+	case "user_agent":
+		return attrs.UserAgent
+	case "method":
+		return attrs.Method
+	case "path":
+		return attrs.Path
+	case "authority":
+		return attrs.Authority
+	}
+	name, _ := strings.CutPrefix(attribute, "headers.")
+	return attrs.Headers.Get(name)
+}
@@ -0,0 +1,142 @@
+package mapper
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+var testHashSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func hashesFor(t *testing.T, m *RequestAttributeHashMapper, attrs *request.RequestAttributes) map[string]any {
+	t.Helper()
+	result, err := m.Map(context.Background(), &service.MapperInput{RequestAttributes: attrs})
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	if result == nil {
+		return nil
+	}
+	hashes, ok := result[DefaultHashClaim].(map[string]any)
+	if !ok {
+		t.Fatalf("expected %q claim to be a map, got %T", DefaultHashClaim, result[DefaultHashClaim])
+	}
+	return hashes
+}
+
+func TestRequestAttributeHashMapper(t *testing.T) {
+	attrs := &request.RequestAttributes{
+		IPAddress: "203.0.113.7",
+		UserAgent: "curl/8.0",
+		Path:      "/api",
+		Headers:   request.Headers{"x-device-id": {"device-1"}},
+	}
+
+	t.Run("hashes default attributes without raw values", func(t *testing.T) {
+		m, err := NewRequestAttributeHashMapper(RequestAttributeHashMapperConfig{Secret: testHashSecret})
+		if err != nil {
+			t.Fatalf("failed to create mapper: %v", err)
+		}
+
+		hashes := hashesFor(t, m, attrs)
+		if len(hashes) != 2 {
+			t.Fatalf("expected ip_address and user_agent hashes, got %v", hashes)
+		}
+		for name, hash := range hashes {
+			s, _ := hash.(string)
+			if s == "" || strings.Contains(s, "203.0.113.7") || strings.Contains(s, "curl") {
+				t.Errorf("unexpected hash for %s: %v", name, hash)
+			}
+		}
+		if _, ok := hashes["epoch"]; ok {
+			t.Error("expected no epoch without rotation")
+		}
+
+		// Deterministic for the same secret, so replicas agree
+		again := hashesFor(t, m, attrs)
+		if again["ip_address"] != hashes["ip_address"] {
+			t.Error("expected hashes to be stable")
+		}
+	})
+
+	t.Run("different secrets produce different hashes", func(t *testing.T) {
+		m1, _ := NewRequestAttributeHashMapper(RequestAttributeHashMapperConfig{Secret: testHashSecret})
+		m2, _ := NewRequestAttributeHashMapper(RequestAttributeHashMapperConfig{Secret: []byte("another secret of 32 bytes......")})
+		if hashesFor(t, m1, attrs)["ip_address"] == hashesFor(t, m2, attrs)["ip_address"] {
+			t.Error("expected hashes to depend on the secret")
+		}
+	})
+
+	t.Run("same value of different attributes does not correlate", func(t *testing.T) {
+		m, _ := NewRequestAttributeHashMapper(RequestAttributeHashMapperConfig{
+			Secret:     testHashSecret,
+			Attributes: []string{"path", "headers.x-path"},
+		})
+		hashes := hashesFor(t, m, &request.RequestAttributes{
+			Path:    "/same",
+			Headers: request.Headers{"x-path": {"/same"}},
+		})
+		if hashes["path"] == nil || hashes["path"] == hashes["headers.x-path"] {
+			t.Errorf("expected distinct hashes, got %v", hashes)
+		}
+	})
+
+	t.Run("rotates salt each period", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		m, err := NewRequestAttributeHashMapper(RequestAttributeHashMapperConfig{
+			Secret:         testHashSecret,
+			RotationPeriod: 24 * time.Hour,
+			Clock:          clk,
+		})
+		if err != nil {
+			t.Fatalf("failed to create mapper: %v", err)
+		}
+
+		first := hashesFor(t, m, attrs)
+		clk.Advance(time.Hour)
+		sameDay := hashesFor(t, m, attrs)
+		clk.Advance(24 * time.Hour)
+		nextDay := hashesFor(t, m, attrs)
+
+		if first["ip_address"] != sameDay["ip_address"] || first["epoch"] != sameDay["epoch"] {
+			t.Error("expected hashes to correlate within a period")
+		}
+		if first["ip_address"] == nextDay["ip_address"] {
+			t.Error("expected hashes to change after the salt rotates")
+		}
+		if nextDay["epoch"].(int64) != first["epoch"].(int64)+1 {
+			t.Errorf("expected epoch to advance by one, got %v then %v", first["epoch"], nextDay["epoch"])
+		}
+	})
+
+	t.Run("omits absent attributes", func(t *testing.T) {
+		m, _ := NewRequestAttributeHashMapper(RequestAttributeHashMapperConfig{Secret: testHashSecret})
+		if hashes := hashesFor(t, m, &request.RequestAttributes{}); hashes != nil {
+			t.Errorf("expected no claims, got %v", hashes)
+		}
+	})
+}
+
+func TestNewRequestAttributeHashMapper_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RequestAttributeHashMapperConfig
+	}{
+		{"missing secret", RequestAttributeHashMapperConfig{}},
+		{"short secret", RequestAttributeHashMapperConfig{Secret: []byte("short")}},
+		{"unknown attribute", RequestAttributeHashMapperConfig{Secret: testHashSecret, Attributes: []string{"body"}}},
+		{"sub-second rotation", RequestAttributeHashMapperConfig{Secret: testHashSecret, RotationPeriod: time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRequestAttributeHashMapper(tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
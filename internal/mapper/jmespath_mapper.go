@@ -0,0 +1,143 @@
+package mapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// JMESPathMapper is a ClaimMapper that uses a JMESPath expression to project claims
+// from the MapperInput, for mappings that are mostly reshaping data
+//
+// The expression is evaluated against a document with the same variables CEL
+// mappers see (subject, actor, workload, and request), plus datasources, an object
+// of the results of the mapper's data sources by name. It should evaluate to an
+// object, which is used as the claims.
+//
+// Example expressions:
+//
+//	// Simple claim from subject
+//	{user: subject.subject}
+//
+//	// Data source results and defaults
+//	{roles: datasources.user_roles.roles || `[]`, region: datasources.geo.region}
+//
+//	// Filtering
+//	{admin_groups: subject.claims.groups[?starts_with(@, 'admin-')]}
+type JMESPathMapper struct {
+	expression  string
+	compiled    *jmespath.JMESPath
+	dataSources []string
+}
+
+// NewJMESPathMapper creates a JMESPath claim mapper
+// dataSources name the data sources fetched into datasources for each request.
+func NewJMESPathMapper(expression string, dataSources []string) (*JMESPathMapper, error) {
+	if expression == "" {
+		return nil, fmt.Errorf("JMESPath expression cannot be empty")
+	}
+
+	compiled, err := jmespath.Compile(expression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile JMESPath expression: %w", err)
+	}
+
+	return &JMESPathMapper{
+		expression:  expression,
+		compiled:    compiled,
+		dataSources: dataSources,
+	}, nil
+}
+
+// Map evaluates the JMESPath expression and returns the resulting claims
+func (m *JMESPathMapper) Map(ctx context.Context, input *service.MapperInput) (claims.Claims, error) {
+	if input == nil {
+		return nil, fmt.Errorf("mapper input cannot be nil")
+	}
+
+	doc, err := m.document(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := m.compiled.Search(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate JMESPath expression: %w", err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+
+	resultMap, ok := result.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("JMESPath expression must evaluate to an object, got: %T", result)
+	}
+
+	return claims.Claims(resultMap), nil
+}
+
+// Expression returns the JMESPath expression used by this mapper
+func (m *JMESPathMapper) Expression() string {
+	return m.expression
+}
+
+// document builds the JSON document the expression is evaluated against
+func (m *JMESPathMapper) document(ctx context.Context, input *service.MapperInput) (any, error) {
+	activation := celActivation(input)
+
+	dataSources := map[string]any{}
+	for _, name := range m.dataSources {
+		data, err := fetchJSON(ctx, input, name)
+		if err != nil {
+			return nil, err
+		}
+		dataSources[name] = data
+	}
+	activation["datasources"] = dataSources
+
+	// JMESPath works on JSON values, so round trip the typed values (like times
+	// and headers) through JSON
+	raw, err := json.Marshal(activation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal mapper input: %w", err)
+	}
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mapper input: %w", err)
+	}
+	return doc, nil
+}
+
+// fetchJSON fetches and decodes the result of the named data source, or returns
+// nil if it is not registered or has nothing to contribute
+func fetchJSON(ctx context.Context, input *service.MapperInput, name string) (any, error) {
+	if input.DataSourceRegistry == nil {
+		return nil, nil
+	}
+	ds := input.DataSourceRegistry.Get(name)
+	if ds == nil {
+		return nil, nil
+	}
+
+	result, err := ds.Fetch(ctx, input.DataSourceInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data source %s: %w", name, err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+	if result.ContentType != service.ContentTypeJSON {
+		return nil, fmt.Errorf("data source %s returned unsupported content type %s", name, result.ContentType)
+	}
+
+	var data any
+	if err := json.Unmarshal(result.Data, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode data source %s: %w", name, err)
+	}
+	return data, nil
+}
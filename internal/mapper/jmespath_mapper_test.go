@@ -0,0 +1,87 @@
+package mapper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestNewJMESPathMapper(t *testing.T) {
+	t.Run("fails with empty expression", func(t *testing.T) {
+		if _, err := NewJMESPathMapper("", nil); err == nil {
+			t.Error("expected error for empty expression")
+		}
+	})
+
+	t.Run("fails with invalid expression", func(t *testing.T) {
+		if _, err := NewJMESPathMapper("{user: ", nil); err == nil {
+			t.Error("expected error for invalid expression")
+		}
+	})
+}
+
+func TestJMESPathMapper_Map(t *testing.T) {
+	ctx := context.Background()
+
+	registry := service.NewDataSourceRegistry()
+	registry.Register(&mockDataSource{name: "user_roles", data: map[string]any{"roles": []any{"admin", "viewer"}}})
+	registry.Register(&mockCountingDataSource{name: "unused"})
+
+	input := &service.MapperInput{
+		Subject: &trust.Result{
+			Subject:     "alice",
+			TrustDomain: "prod",
+			Claims:      map[string]any{"groups": []any{"admin-eu", "dev"}},
+		},
+		RequestAttributes:  &request.RequestAttributes{Method: "GET", Path: "/orders"},
+		DataSourceRegistry: registry,
+		DataSourceInput:    &service.DataSourceInput{},
+	}
+
+	t.Run("projects claims from the input and data sources", func(t *testing.T) {
+		mapper, err := NewJMESPathMapper(
+			"{user: subject.subject, path: request.path, roles: datasources.user_roles.roles, "+
+				"admin_groups: subject.claims.groups[?starts_with(@, 'admin-')], region: datasources.geo.region || 'us'}",
+			[]string{"user_roles", "geo"})
+		if err != nil {
+			t.Fatalf("NewJMESPathMapper failed: %v", err)
+		}
+
+		got, err := mapper.Map(ctx, input)
+		if err != nil {
+			t.Fatalf("Map failed: %v", err)
+		}
+		want := map[string]any{
+			"user":         "alice",
+			"path":         "/orders",
+			"roles":        []any{"admin", "viewer"},
+			"admin_groups": []any{"admin-eu"},
+			"region":       "us",
+		}
+		if !reflect.DeepEqual(map[string]any(got), want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+		if unused := registry.Get("unused").(*mockCountingDataSource); unused.callCount != 0 {
+			t.Errorf("expected unlisted data sources not to be fetched, got %d fetches", unused.callCount)
+		}
+	})
+
+	t.Run("missing values produce no claims", func(t *testing.T) {
+		mapper, _ := NewJMESPathMapper("actor.claims", nil)
+		got, err := mapper.Map(ctx, input)
+		if err != nil || got != nil {
+			t.Errorf("expected no claims, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("fails if the result is not an object", func(t *testing.T) {
+		mapper, _ := NewJMESPathMapper("subject.subject", nil)
+		if _, err := mapper.Map(ctx, input); err == nil {
+			t.Error("expected error for a non-object result")
+		}
+	})
+}
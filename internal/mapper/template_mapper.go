@@ -0,0 +1,192 @@
+package mapper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// TemplateMapper is a ClaimMapper that renders claims from Go text/templates, for
+// simple claims such as deployment stamps (region, cluster) that do not need CEL
+//
+// Every string in the claims, including those nested in objects and lists, is a
+// template; other values are used as they are. Templates are executed against the
+// same variables CEL mappers see (.subject, .actor, .workload, and .request) and
+// may call:
+//   - env "NAME" - the environment variable NAME, failing if it is not set
+//   - datasource "name" - the result of a named data source, or nil
+//
+// Claims that render to the empty string are omitted, so a template can leave out
+// a claim, as in {{ with .actor }}{{ .subject }}{{ end }}.
+//
+// Example claims:
+//
+//	{
+//	  "region": "{{ env \"REGION\" }}",
+//	  "user": "{{ .subject.subject }}",
+//	  "tier": "{{ (datasource \"accounts\").tier }}"
+//	}
+type TemplateMapper struct {
+	claims map[string]any
+}
+
+// NewTemplateMapper parses the templates in claimTemplates
+func NewTemplateMapper(claimTemplates map[string]any) (*TemplateMapper, error) {
+	if len(claimTemplates) == 0 {
+		return nil, fmt.Errorf("template mapper requires claims")
+	}
+
+	parsed, err := parseTemplates("", claimTemplates)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateMapper{claims: parsed.(map[string]any)}, nil
+}
+
+// templateFuncs are the functions available to templates; datasource is rebound
+// for each request
+var templateFuncs = template.FuncMap{
+	"env": func(name string) (string, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	},
+	"datasource": func(string) (any, error) {
+		return nil, nil
+	},
+}
+
+// parseTemplates replaces the strings in value with parsed templates
+func parseTemplates(path string, value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		tmpl, err := template.New(path).Funcs(templateFuncs).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template for claim %s: %w", path, err)
+		}
+		return tmpl, nil
+	case map[string]any:
+		parsed := make(map[string]any, len(v))
+		for key, item := range v {
+			p, err := parseTemplates(joinClaimPath(path, key), item)
+			if err != nil {
+				return nil, err
+			}
+			parsed[key] = p
+		}
+		return parsed, nil
+	case []any:
+		parsed := make([]any, len(v))
+		for i, item := range v {
+			p, err := parseTemplates(fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			parsed[i] = p
+		}
+		return parsed, nil
+	default:
+		return v, nil
+	}
+}
+
+func joinClaimPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Map renders the claims
+func (m *TemplateMapper) Map(ctx context.Context, input *service.MapperInput) (claims.Claims, error) {
+	if input == nil {
+		return nil, fmt.Errorf("mapper input cannot be nil")
+	}
+
+	r := &templateRenderer{
+		data:        celActivation(input),
+		dataSources: map[string]any{},
+	}
+	r.funcs = template.FuncMap{
+		"datasource": func(name string) (any, error) {
+			if data, ok := r.dataSources[name]; ok {
+				return data, nil
+			}
+			data, err := fetchJSON(ctx, input, name)
+			if err != nil {
+				return nil, err
+			}
+			r.dataSources[name] = data
+			return data, nil
+		},
+	}
+
+	rendered, err := r.render(m.claims)
+	if err != nil {
+		return nil, err
+	}
+	if len(rendered.(map[string]any)) == 0 {
+		return nil, nil
+	}
+	return claims.Claims(rendered.(map[string]any)), nil
+}
+
+// templateRenderer renders templates for one request
+type templateRenderer struct {
+	data        map[string]any
+	funcs       template.FuncMap
+	dataSources map[string]any // Fetched data sources, so each is fetched once
+}
+
+// render executes the templates in value, omitting values that render empty
+func (r *templateRenderer) render(value any) (any, error) {
+	switch v := value.(type) {
+	case *template.Template:
+		// Clone to bind this request's data sources without racing other requests
+		tmpl, err := v.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("failed to clone template for claim %s: %w", v.Name(), err)
+		}
+		var out strings.Builder
+		if err := tmpl.Funcs(r.funcs).Execute(&out, r.data); err != nil {
+			return nil, fmt.Errorf("failed to render claim %s: %w", v.Name(), err)
+		}
+		if out.Len() == 0 {
+			return nil, nil
+		}
+		return out.String(), nil
+	case map[string]any:
+		rendered := make(map[string]any, len(v))
+		for key, item := range v {
+			value, err := r.render(item)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				rendered[key] = value
+			}
+		}
+		return rendered, nil
+	case []any:
+		rendered := make([]any, 0, len(v))
+		for _, item := range v {
+			value, err := r.render(item)
+			if err != nil {
+				return nil, err
+			}
+			if value != nil {
+				rendered = append(rendered, value)
+			}
+		}
+		return rendered, nil
+	default:
+		return v, nil
+	}
+}
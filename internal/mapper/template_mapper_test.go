@@ -0,0 +1,91 @@
+package mapper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestNewTemplateMapper(t *testing.T) {
+	t.Run("fails without claims", func(t *testing.T) {
+		if _, err := NewTemplateMapper(nil); err == nil {
+			t.Error("expected error for no claims")
+		}
+	})
+
+	t.Run("fails with invalid template", func(t *testing.T) {
+		if _, err := NewTemplateMapper(map[string]any{"user": "{{ .subject.subject"}); err == nil {
+			t.Error("expected error for invalid template")
+		}
+	})
+}
+
+func TestTemplateMapper_Map(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("PARSEC_TEST_REGION", "us-east-1")
+
+	counting := &mockCountingDataSource{name: "counter"}
+	registry := service.NewDataSourceRegistry()
+	registry.Register(&mockDataSource{name: "accounts", data: map[string]any{"tier": "gold"}})
+	registry.Register(counting)
+
+	input := &service.MapperInput{
+		Subject:            &trust.Result{Subject: "alice", TrustDomain: "prod"},
+		RequestAttributes:  &request.RequestAttributes{Method: "GET", Path: "/orders"},
+		DataSourceRegistry: registry,
+		DataSourceInput:    &service.DataSourceInput{},
+	}
+
+	t.Run("renders claims", func(t *testing.T) {
+		mapper, err := NewTemplateMapper(map[string]any{
+			"region": `{{ env "PARSEC_TEST_REGION" }}`,
+			"user":   "{{ .subject.subject }}",
+			"tier":   `{{ (datasource "accounts").tier }}`,
+			"count":  `{{ (datasource "counter").value }}/{{ (datasource "counter").value }}`,
+			"actor":  "{{ with .actor }}{{ .subject }}{{ end }}",
+			"deployment": map[string]any{
+				"stamps":  []any{"{{ .request.method }}", "static"},
+				"version": 3,
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewTemplateMapper failed: %v", err)
+		}
+
+		got, err := mapper.Map(ctx, input)
+		if err != nil {
+			t.Fatalf("Map failed: %v", err)
+		}
+		want := map[string]any{
+			"region": "us-east-1",
+			"user":   "alice",
+			"tier":   "gold",
+			"count":  "1/1",
+			"deployment": map[string]any{
+				"stamps":  []any{"GET", "static"},
+				"version": 3,
+			},
+		}
+		if !reflect.DeepEqual(map[string]any(got), want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("fails for unset environment variables", func(t *testing.T) {
+		mapper, _ := NewTemplateMapper(map[string]any{"cluster": `{{ env "PARSEC_TEST_UNSET" }}`})
+		if _, err := mapper.Map(ctx, input); err == nil {
+			t.Error("expected error for an unset environment variable")
+		}
+	})
+
+	t.Run("fails for missing keys", func(t *testing.T) {
+		mapper, _ := NewTemplateMapper(map[string]any{"user": "{{ .subject.username }}"})
+		if _, err := mapper.Map(ctx, input); err == nil {
+			t.Error("expected error for a missing key")
+		}
+	})
+}
@@ -0,0 +1,260 @@
+package probe
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/events"
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// eventObserver publishes an event for each token issued or not issued, and for
+// each authorization check or token exchange denied before issuance
+type eventObserver struct {
+	publisher *events.Publisher
+	instance  *instance.Identity
+	clock     clock.Clock
+}
+
+// EventObserverConfig configures the event observer
+type EventObserverConfig struct {
+	// Publisher sends events
+	Publisher *events.Publisher
+
+	// Instance, if set, identifies the replica that published each event
+	Instance *instance.Identity
+
+	// Clock timestamps events. If nil, uses the system clock
+	Clock clock.Clock
+}
+
+// NewEventObserver creates an application observer that publishes issuance and
+// denial events
+func NewEventObserver(cfg EventObserverConfig) service.ApplicationObserver {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &eventObserver{
+		publisher: cfg.Publisher,
+		instance:  cfg.Instance,
+		clock:     clk,
+	}
+}
+
+// publish completes event and publishes it
+// Publishing waits on ctx only if the publisher blocks when its queue is full.
+func (o *eventObserver) publish(ctx context.Context, event events.Event) {
+	event.ID = uuid.NewString()
+	event.Time = o.clock.Now().UTC()
+	event.Instance = o.instance
+	o.publisher.Publish(ctx, event)
+}
+
+func (o *eventObserver) TokenIssuanceStarted(
+	ctx context.Context,
+	subject *trust.Result,
+	actor *trust.Result,
+	scope string,
+	tokenTypes []service.TokenType,
+) (context.Context, service.TokenIssuanceProbe) {
+	return ctx, &eventTokenIssuanceProbe{
+		observer: o,
+		ctx:      ctx,
+		subject:  events.IdentityOf(subject),
+		actor:    events.IdentityOf(actor),
+		scope:    scope,
+	}
+}
+
+// eventTokenIssuanceProbe publishes an event for each token type issued or not
+type eventTokenIssuanceProbe struct {
+	service.NoOpTokenIssuanceProbe
+	observer *eventObserver
+	ctx      context.Context
+	subject  *events.Identity
+	actor    *events.Identity
+	scope    string
+}
+
+// event returns an event about tokenType, from what the issuance was started with
+func (p *eventTokenIssuanceProbe) event(eventType string, tokenType service.TokenType) events.Event {
+	return events.Event{
+		Type:      eventType,
+		Subject:   p.subject,
+		Actor:     p.actor,
+		Scope:     p.scope,
+		TokenType: string(tokenType),
+	}
+}
+
+func (p *eventTokenIssuanceProbe) TokenTypeIssuanceSucceeded(tokenType service.TokenType, token *service.Token) {
+	event := p.event(events.TypeTokenIssued, tokenType)
+	if token != nil {
+		event.TransactionID = token.TransactionID
+		if !token.ExpiresAt.IsZero() {
+			expiresAt := token.ExpiresAt.UTC()
+			event.ExpiresAt = &expiresAt
+		}
+	}
+	p.observer.publish(p.ctx, event)
+}
+
+func (p *eventTokenIssuanceProbe) TokenTypeIssuanceFailed(tokenType service.TokenType, err error) {
+	event := p.event(events.TypeTokenIssuanceFailed, tokenType)
+	event.Reason = err.Error()
+	p.observer.publish(p.ctx, event)
+}
+
+func (p *eventTokenIssuanceProbe) IssuerNotFound(tokenType service.TokenType, err error) {
+	p.TokenTypeIssuanceFailed(tokenType, err)
+}
+
+func (o *eventObserver) TokenExchangeStarted(
+	ctx context.Context,
+	grantType string,
+	requestedTokenType string,
+	audiences []string,
+	scope string,
+) (context.Context, service.TokenExchangeProbe) {
+	return ctx, &eventTokenExchangeProbe{
+		observer: o,
+		ctx:      ctx,
+		event: events.Event{
+			Type:      events.TypeTokenExchangeDenied,
+			GrantType: grantType,
+			TokenType: requestedTokenType,
+			Audiences: audiences,
+			Scope:     scope,
+		},
+	}
+}
+
+// eventTokenExchangeProbe publishes an event when a token exchange is denied
+type eventTokenExchangeProbe struct {
+	service.NoOpTokenExchangeProbe
+	observer *eventObserver
+	ctx      context.Context
+	event    events.Event
+}
+
+// deny records the first reason the exchange was denied
+func (p *eventTokenExchangeProbe) deny(reason string) {
+	if p.event.Reason == "" {
+		p.event.Reason = reason
+	}
+}
+
+func (p *eventTokenExchangeProbe) ActorValidationSucceeded(actor *trust.Result) {
+	p.event.Actor = events.IdentityOf(actor)
+}
+
+func (p *eventTokenExchangeProbe) ActorValidationFailed(err error) {
+	p.deny(err.Error())
+}
+
+func (p *eventTokenExchangeProbe) RequestContextParseFailed(err error) {
+	p.deny(err.Error())
+}
+
+func (p *eventTokenExchangeProbe) SubjectTokenValidationSucceeded(subject *trust.Result) {
+	p.event.Subject = events.IdentityOf(subject)
+}
+
+func (p *eventTokenExchangeProbe) SubjectTokenValidationFailed(err error) {
+	p.deny(err.Error())
+}
+
+// ExchangeFailed records denials after the request's credentials were validated, such
+// as by policy or scope
+func (p *eventTokenExchangeProbe) ExchangeFailed(code string, description string) {
+	p.deny(description)
+}
+
+func (p *eventTokenExchangeProbe) End() {
+	if p.event.Reason != "" {
+		p.observer.publish(p.ctx, p.event)
+	}
+}
+
+func (o *eventObserver) AuthzCheckStarted(ctx context.Context) (context.Context, service.AuthzCheckProbe) {
+	return ctx, &eventAuthzCheckProbe{
+		observer: o,
+		ctx:      ctx,
+		event:    events.Event{Type: events.TypeAuthzCheckDenied},
+	}
+}
+
+// ConfigReloadStarted implements service.ConfigReloadObserver; reloads are not published
+func (o *eventObserver) ConfigReloadStarted(ctx context.Context) (context.Context, service.ConfigReloadProbe) {
+	return ctx, &service.NoOpConfigReloadProbe{}
+}
+
+// ValidationStarted implements service.ValidatorObserver; attempts of single validators
+// are not published, only the decisions they lead to
+func (o *eventObserver) ValidationStarted(ctx context.Context, validator string, credentialType trust.CredentialType) (context.Context, service.ValidationProbe) {
+	return ctx, &service.NoOpValidationProbe{}
+}
+
+// DataSourceFetchStarted implements service.DataSourceObserver; fetches are not published
+func (o *eventObserver) DataSourceFetchStarted(ctx context.Context, dataSource string) (context.Context, service.DataSourceFetchProbe) {
+	return ctx, &service.NoOpDataSourceFetchProbe{}
+}
+
+// eventAuthzCheckProbe publishes an event when an authorization check is denied
+type eventAuthzCheckProbe struct {
+	service.NoOpAuthzCheckProbe
+	observer *eventObserver
+	ctx      context.Context
+	event    events.Event
+}
+
+// deny records the first reason the check was denied
+func (p *eventAuthzCheckProbe) deny(reason string) {
+	if p.event.Reason == "" {
+		p.event.Reason = reason
+	}
+}
+
+func (p *eventAuthzCheckProbe) RequestAttributesParsed(attrs *request.RequestAttributes) {
+	if attrs != nil {
+		p.event.Request = &events.Request{Method: attrs.Method, Path: attrs.Path}
+	}
+}
+
+func (p *eventAuthzCheckProbe) ActorValidationSucceeded(actor *trust.Result) {
+	p.event.Actor = events.IdentityOf(actor)
+}
+
+func (p *eventAuthzCheckProbe) ActorValidationFailed(err error) {
+	p.deny(err.Error())
+}
+
+func (p *eventAuthzCheckProbe) SubjectCredentialExtractionFailed(err error) {
+	p.deny(err.Error())
+}
+
+func (p *eventAuthzCheckProbe) SubjectValidationSucceeded(subject *trust.Result) {
+	p.event.Subject = events.IdentityOf(subject)
+}
+
+func (p *eventAuthzCheckProbe) SubjectValidationFailed(err error) {
+	p.deny(err.Error())
+}
+
+// CheckDenied records denials after the request's credentials were validated, such as
+// by policy
+func (p *eventAuthzCheckProbe) CheckDenied(code string, reason string) {
+	p.deny(reason)
+}
+
+func (p *eventAuthzCheckProbe) End() {
+	if p.event.Reason != "" {
+		p.observer.publish(p.ctx, p.event)
+	}
+}
@@ -0,0 +1,97 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/events"
+	"github.com/alechenninger/parsec/internal/instance"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// collectingSink collects the events it is sent
+type collectingSink struct {
+	events []events.Event
+}
+
+func (s *collectingSink) Send(ctx context.Context, batch []events.Event) error {
+	s.events = append(s.events, batch...)
+	return nil
+}
+
+func TestEventObserver(t *testing.T) {
+	ctx := context.Background()
+	sink := &collectingSink{}
+	publisher, err := events.NewPublisher(events.PublisherConfig{Sink: sink})
+	if err != nil {
+		t.Fatalf("failed to create publisher: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	observer := NewEventObserver(EventObserverConfig{
+		Publisher: publisher,
+		Instance:  &instance.Identity{ID: "parsec-0"},
+		Clock:     clock.NewFixtureClock(now),
+	})
+	alice := &trust.Result{Subject: "alice", Issuer: "https://idp.example.com"}
+
+	// An allowed check publishes only what it issues
+	_, check := observer.AuthzCheckStarted(ctx)
+	check.SubjectValidationSucceeded(alice)
+	check.CheckAllowed()
+	check.End()
+	_, issuance := observer.TokenIssuanceStarted(ctx, alice, nil, "read", []service.TokenType{service.TokenTypeTransactionToken})
+	issuance.TokenTypeIssuanceSucceeded(service.TokenTypeTransactionToken, &service.Token{TransactionID: "txn-1", ExpiresAt: now.Add(time.Minute)})
+	issuance.TokenTypeIssuanceFailed(service.TokenTypeAccessToken, errors.New("signer unavailable"))
+	issuance.End()
+
+	// A denied check publishes why
+	_, check = observer.AuthzCheckStarted(ctx)
+	check.RequestAttributesParsed(&request.RequestAttributes{Method: "GET", Path: "/orders"})
+	check.SubjectValidationFailed(errors.New("token expired"))
+	check.CheckDenied("Unauthenticated", "workload validation failed: token expired")
+	check.End()
+
+	_, exchange := observer.TokenExchangeStarted(ctx, "urn:ietf:params:oauth:grant-type:token-exchange", "", []string{"orders"}, "")
+	exchange.SubjectTokenValidationFailed(errors.New("untrusted issuer"))
+	exchange.ExchangeFailed("invalid_grant", "subject token validation failed: untrusted issuer")
+	exchange.End()
+
+	// So does an exchange denied by policy, after its subject was validated
+	_, exchange = observer.TokenExchangeStarted(ctx, "urn:ietf:params:oauth:grant-type:token-exchange", "", []string{"orders"}, "admin")
+	exchange.SubjectTokenValidationSucceeded(alice)
+	exchange.ExchangeFailed("invalid_scope", "scope admin is not allowed")
+	exchange.End()
+
+	if err := publisher.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if len(sink.events) != 5 {
+		t.Fatalf("expected 5 events, got %d: %+v", len(sink.events), sink.events)
+	}
+	issued, failed, deniedCheck, deniedExchange, deniedScope := sink.events[0], sink.events[1], sink.events[2], sink.events[3], sink.events[4]
+
+	if issued.Type != events.TypeTokenIssued || issued.TransactionID != "txn-1" || issued.Subject.Subject != "alice" || issued.Scope != "read" {
+		t.Errorf("unexpected issued event %+v", issued)
+	}
+	if issued.ID == "" || !issued.Time.Equal(now) || issued.Instance.ID != "parsec-0" {
+		t.Errorf("expected ID, time, and instance, got %+v", issued)
+	}
+	if failed.Type != events.TypeTokenIssuanceFailed || failed.Reason != "signer unavailable" || failed.TokenType != string(service.TokenTypeAccessToken) {
+		t.Errorf("unexpected issuance failed event %+v", failed)
+	}
+	if deniedCheck.Type != events.TypeAuthzCheckDenied || deniedCheck.Reason != "token expired" || deniedCheck.Request.Path != "/orders" {
+		t.Errorf("unexpected denied check event %+v", deniedCheck)
+	}
+	if deniedExchange.Type != events.TypeTokenExchangeDenied || deniedExchange.Reason != "untrusted issuer" || deniedExchange.Audiences[0] != "orders" {
+		t.Errorf("unexpected denied exchange event %+v", deniedExchange)
+	}
+	if deniedScope.Type != events.TypeTokenExchangeDenied || deniedScope.Reason != "scope admin is not allowed" || deniedScope.Subject.Subject != "alice" {
+		t.Errorf("unexpected denied scope event %+v", deniedScope)
+	}
+}
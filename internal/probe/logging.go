@@ -3,7 +3,9 @@ package probe
 import (
 	"context"
 	"log/slog"
+	"time"
 
+	"github.com/alechenninger/parsec/internal/instance"
 	"github.com/alechenninger/parsec/internal/request"
 	"github.com/alechenninger/parsec/internal/service"
 	"github.com/alechenninger/parsec/internal/trust"
@@ -19,6 +21,10 @@ type loggingObserver struct {
 type LoggingObserverConfig struct {
 	// Logger is the base logger to use. If nil, uses slog.Default()
 	Logger *slog.Logger
+
+	// Instance, if set, is attached to every event so records can be traced
+	// back to the replica and version that produced them
+	Instance *instance.Identity
 }
 
 // NewLoggingObserver creates an application observer that logs all observability events
@@ -39,6 +45,13 @@ func NewLoggingObserverWithConfig(cfg LoggingObserverConfig) service.Application
 		logger = slog.Default()
 	}
 
+	if cfg.Instance != nil {
+		logger = logger.With(slog.Group("instance",
+			slog.String("id", cfg.Instance.ID),
+			slog.String("version", cfg.Instance.Version),
+		))
+	}
+
 	return &loggingObserver{
 		logger: logger,
 	}
@@ -136,7 +149,7 @@ func (o *loggingObserver) TokenExchangeStarted(
 	ctx context.Context,
 	grantType string,
 	requestedTokenType string,
-	audience string,
+	audiences []string,
 	scope string,
 ) (context.Context, service.TokenExchangeProbe) {
 	// Create scoped logger for this probe type
@@ -146,7 +159,7 @@ func (o *loggingObserver) TokenExchangeStarted(
 		"Starting token exchange",
 		slog.String("grant_type", grantType),
 		slog.String("requested_token_type", requestedTokenType),
-		slog.String("audience", audience),
+		slog.Any("audiences", audiences),
 		slog.String("scope", scope),
 	)
 
@@ -210,6 +223,21 @@ func (p *loggingTokenExchangeProbe) SubjectTokenValidationFailed(err error) {
 	)
 }
 
+func (p *loggingTokenExchangeProbe) ExchangeSucceeded(issuedTokenType string) {
+	p.logger.LogAttrs(p.ctx, slog.LevelInfo,
+		"Token exchange succeeded",
+		slog.String("issued_token_type", issuedTokenType),
+	)
+}
+
+func (p *loggingTokenExchangeProbe) ExchangeFailed(code string, description string) {
+	p.logger.LogAttrs(p.ctx, slog.LevelInfo,
+		"Token exchange failed",
+		slog.String("code", code),
+		slog.String("reason", description),
+	)
+}
+
 func (p *loggingTokenExchangeProbe) End() {
 	p.logger.LogAttrs(p.ctx, slog.LevelDebug, "Token exchange completed")
 }
@@ -297,6 +325,173 @@ func (p *loggingAuthzCheckProbe) SubjectValidationFailed(err error) {
 	)
 }
 
+func (p *loggingAuthzCheckProbe) SubjectValidationCacheHit(subject *trust.Result) {
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug, "Subject validation cache hit")
+}
+
+func (p *loggingAuthzCheckProbe) SubjectValidationCacheMissed() {
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug, "Subject validation cache missed")
+}
+
+func (p *loggingAuthzCheckProbe) CheckAllowed() {
+	p.logger.LogAttrs(p.ctx, slog.LevelInfo, "Authorization check allowed")
+}
+
+func (p *loggingAuthzCheckProbe) CheckDenied(code string, reason string) {
+	p.logger.LogAttrs(p.ctx, slog.LevelInfo,
+		"Authorization check denied",
+		slog.String("code", code),
+		slog.String("reason", reason),
+	)
+}
+
 func (p *loggingAuthzCheckProbe) End() {
 	p.logger.LogAttrs(p.ctx, slog.LevelDebug, "Authorization check completed")
 }
+
+// ConfigReloadStarted implements service.ConfigReloadObserver
+func (o *loggingObserver) ConfigReloadStarted(ctx context.Context) (context.Context, service.ConfigReloadProbe) {
+	probeLogger := o.logger.With("event", "config_reload")
+
+	probeLogger.LogAttrs(ctx, slog.LevelDebug, "Starting configuration reload")
+
+	return ctx, &loggingConfigReloadProbe{
+		ctx:    ctx,
+		logger: probeLogger,
+	}
+}
+
+// loggingConfigReloadProbe logs the events of a configuration reload
+type loggingConfigReloadProbe struct {
+	service.NoOpConfigReloadProbe
+	ctx        context.Context
+	logger     *slog.Logger
+	components []string
+	failed     bool
+}
+
+func (p *loggingConfigReloadProbe) ComponentReloaded(component string) {
+	p.components = append(p.components, component)
+}
+
+func (p *loggingConfigReloadProbe) RestartRequired(options []string) {
+	p.logger.LogAttrs(p.ctx, slog.LevelWarn,
+		"Changed configuration takes effect only on restart",
+		slog.Any("options", options),
+	)
+}
+
+func (p *loggingConfigReloadProbe) ConfigReloadFailed(err error) {
+	p.failed = true
+	p.logger.LogAttrs(p.ctx, slog.LevelError,
+		"Configuration reload failed; keeping the running configuration",
+		slog.String("error", err.Error()),
+	)
+}
+
+func (p *loggingConfigReloadProbe) End() {
+	if !p.failed {
+		p.logger.LogAttrs(p.ctx, slog.LevelInfo,
+			"Configuration reloaded",
+			slog.Any("components", p.components),
+		)
+	}
+}
+
+// ValidationStarted implements service.ValidatorObserver
+func (o *loggingObserver) ValidationStarted(ctx context.Context, validator string, credentialType trust.CredentialType) (context.Context, service.ValidationProbe) {
+	// The validator that validates the request is a request field, so attempts are keyed apart
+	probeLogger := o.logger.With(
+		slog.String("event", "validation"),
+		slog.String("attempted_validator", validator),
+		slog.String("credential_type", string(credentialType)),
+	)
+
+	return ctx, &loggingValidationProbe{
+		ctx:     ctx,
+		logger:  probeLogger,
+		started: time.Now(),
+	}
+}
+
+// loggingValidationProbe logs the outcome and latency of a validation attempt
+type loggingValidationProbe struct {
+	service.NoOpValidationProbe
+	ctx     context.Context
+	logger  *slog.Logger
+	started time.Time
+}
+
+func (p *loggingValidationProbe) ValidationSucceeded(result *trust.Result) {
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug,
+		"Validator accepted credential",
+		slog.Duration("duration", time.Since(p.started)),
+	)
+}
+
+// ValidationFailed logs at debug: with several validators, most attempts are expected to
+// fail, and a credential no validator accepts is logged with the check or exchange
+func (p *loggingValidationProbe) ValidationFailed(err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug,
+		"Validator rejected credential",
+		slog.Duration("duration", time.Since(p.started)),
+		slog.String("error", err.Error()),
+	)
+}
+
+// DataSourceFetchStarted implements service.DataSourceObserver
+func (o *loggingObserver) DataSourceFetchStarted(ctx context.Context, dataSource string) (context.Context, service.DataSourceFetchProbe) {
+	probeLogger := o.logger.With(
+		slog.String("event", "datasource_fetch"),
+		slog.String("datasource", dataSource),
+	)
+
+	return ctx, &loggingDataSourceFetchProbe{
+		ctx:     ctx,
+		logger:  probeLogger,
+		started: time.Now(),
+	}
+}
+
+// loggingDataSourceFetchProbe logs the outcome, latency, and caching of a data source fetch
+type loggingDataSourceFetchProbe struct {
+	service.NoOpDataSourceFetchProbe
+	ctx     context.Context
+	logger  *slog.Logger
+	started time.Time
+
+	// cache is "hit" or "miss", if the data source is cached
+	cache string
+}
+
+func (p *loggingDataSourceFetchProbe) CacheHit() {
+	p.cache = "hit"
+}
+
+func (p *loggingDataSourceFetchProbe) CacheMissed() {
+	p.cache = "miss"
+}
+
+// attrs returns the fields of every record of the fetch
+func (p *loggingDataSourceFetchProbe) attrs() []slog.Attr {
+	attrs := []slog.Attr{slog.Duration("duration", time.Since(p.started))}
+	if p.cache != "" {
+		attrs = append(attrs, slog.String("cache", p.cache))
+	}
+	return attrs
+}
+
+func (p *loggingDataSourceFetchProbe) FetchSucceeded(result *service.DataSourceResult) {
+	attrs := p.attrs()
+	if result != nil {
+		attrs = append(attrs, slog.Int("bytes", len(result.Data)))
+	}
+	p.logger.LogAttrs(p.ctx, slog.LevelDebug, "Data source fetched", attrs...)
+}
+
+func (p *loggingDataSourceFetchProbe) FetchFailed(err error) {
+	p.logger.LogAttrs(p.ctx, slog.LevelError,
+		"Data source fetch failed",
+		append(p.attrs(), slog.String("error", err.Error()))...,
+	)
+}
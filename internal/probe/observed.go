@@ -0,0 +1,84 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/alechenninger/parsec/internal/logging"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// observedValidator reports each validation attempt to an observer
+type observedValidator struct {
+	name      string
+	validator trust.Validator
+	observer  service.ValidatorObserver
+}
+
+// NewObservedValidator wraps validator so each validation attempt is reported to
+// observer, under the validator's name
+func NewObservedValidator(name string, validator trust.Validator, observer service.ValidatorObserver) trust.Validator {
+	return &observedValidator{name: name, validator: validator, observer: observer}
+}
+
+func (v *observedValidator) Validate(ctx context.Context, credential trust.Credential) (*trust.Result, error) {
+	ctx, probe := v.observer.ValidationStarted(ctx, v.name, credential.Type())
+	defer probe.End()
+
+	result, err := v.validator.Validate(ctx, credential)
+	if err != nil {
+		probe.ValidationFailed(err)
+		return result, err
+	}
+	probe.ValidationSucceeded(result)
+	logging.AddAttrs(ctx, slog.String(logging.ValidatorKey, v.name))
+	return result, nil
+}
+
+func (v *observedValidator) CredentialTypes() []trust.CredentialType {
+	return v.validator.CredentialTypes()
+}
+
+// Close closes the wrapped validator, if it holds resources such as a JWKS refresh
+func (v *observedValidator) Close() error {
+	if closer, ok := v.validator.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// observedDataSource reports each fetch to an observer
+type observedDataSource struct {
+	source   service.DataSource
+	observer service.DataSourceObserver
+}
+
+// NewObservedDataSource wraps source so each fetch is reported to observer. Caching
+// data sources beneath it report whether the fetch was cached.
+func NewObservedDataSource(source service.DataSource, observer service.DataSourceObserver) service.DataSource {
+	return &observedDataSource{source: source, observer: observer}
+}
+
+func (d *observedDataSource) Name() string {
+	return d.source.Name()
+}
+
+// Unwrap implements service.DataSourceWrapper
+func (d *observedDataSource) Unwrap() service.DataSource {
+	return d.source
+}
+
+func (d *observedDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	ctx, probe := d.observer.DataSourceFetchStarted(ctx, d.source.Name())
+	defer probe.End()
+
+	result, err := d.source.Fetch(service.WithDataSourceFetchProbe(ctx, probe), input)
+	if err != nil {
+		probe.FetchFailed(err)
+		return result, err
+	}
+	probe.FetchSucceeded(result)
+	return result, nil
+}
@@ -0,0 +1,356 @@
+package probe
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// tracerName is the instrumentation scope of parsec's spans
+const tracerName = "github.com/alechenninger/parsec"
+
+// tracingObserver starts a span for each authorization check, token exchange, and
+// token issuance
+//
+// Spans are started from the context each operation is given, so they continue a
+// trace the caller propagated. Attempts of validators and fetches of data sources
+// wrapped with NewObservedValidator and NewObservedDataSource, and claim mappers
+// wrapped with NewTracingClaimMapper, add child spans to the operation's span.
+type tracingObserver struct {
+	tracer trace.Tracer
+}
+
+// NewTracingObserver creates an application observer that records spans with provider
+func NewTracingObserver(provider trace.TracerProvider) service.ApplicationObserver {
+	return &tracingObserver{
+		tracer: provider.Tracer(tracerName),
+	}
+}
+
+func (o *tracingObserver) TokenIssuanceStarted(
+	ctx context.Context,
+	subject *trust.Result,
+	actor *trust.Result,
+	scope string,
+	tokenTypes []service.TokenType,
+) (context.Context, service.TokenIssuanceProbe) {
+	types := make([]string, len(tokenTypes))
+	for i, tokenType := range tokenTypes {
+		types[i] = string(tokenType)
+	}
+	attrs := []attribute.KeyValue{
+		attribute.StringSlice("parsec.token_types", types),
+		attribute.String("parsec.scope", scope),
+	}
+	attrs = append(attrs, identityAttributes("parsec.subject", subject)...)
+	attrs = append(attrs, identityAttributes("parsec.actor", actor)...)
+
+	ctx, span := o.tracer.Start(ctx, "parsec.token.Issue", trace.WithAttributes(attrs...))
+	return ctx, &tracingTokenIssuanceProbe{span: span}
+}
+
+func (o *tracingObserver) TokenExchangeStarted(
+	ctx context.Context,
+	grantType string,
+	requestedTokenType string,
+	audiences []string,
+	scope string,
+) (context.Context, service.TokenExchangeProbe) {
+	ctx, span := o.tracer.Start(ctx, "parsec.token.Exchange",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("parsec.grant_type", grantType),
+			attribute.String("parsec.requested_token_type", requestedTokenType),
+			attribute.StringSlice("parsec.audiences", audiences),
+			attribute.String("parsec.scope", scope),
+		),
+	)
+	return ctx, &tracingTokenExchangeProbe{span: span}
+}
+
+func (o *tracingObserver) AuthzCheckStarted(ctx context.Context) (context.Context, service.AuthzCheckProbe) {
+	ctx, span := o.tracer.Start(ctx, "parsec.authz.Check", trace.WithSpanKind(trace.SpanKindServer))
+	return ctx, &tracingAuthzCheckProbe{span: span}
+}
+
+func (o *tracingObserver) ConfigReloadStarted(ctx context.Context) (context.Context, service.ConfigReloadProbe) {
+	ctx, span := o.tracer.Start(ctx, "parsec.config.Reload")
+	return ctx, &tracingConfigReloadProbe{span: span}
+}
+
+// ValidationStarted implements service.ValidatorObserver with a span under the span of
+// the operation that validates
+func (o *tracingObserver) ValidationStarted(ctx context.Context, validator string, credentialType trust.CredentialType) (context.Context, service.ValidationProbe) {
+	ctx, span := startChildSpan(ctx, "parsec.trust.Validate",
+		attribute.String("parsec.validator", validator),
+		attribute.String("parsec.credential_type", string(credentialType)),
+	)
+	return ctx, &tracingValidationProbe{span: span}
+}
+
+// DataSourceFetchStarted implements service.DataSourceObserver with a span under the span
+// of the issuance that fetches
+func (o *tracingObserver) DataSourceFetchStarted(ctx context.Context, dataSource string) (context.Context, service.DataSourceFetchProbe) {
+	ctx, span := startChildSpan(ctx, "parsec.datasource.Fetch",
+		attribute.String("parsec.datasource", dataSource),
+	)
+	return ctx, &tracingDataSourceFetchProbe{span: span}
+}
+
+// identityAttributes describes a validated identity without its subject identifier,
+// which may be personal data
+func identityAttributes(prefix string, result *trust.Result) []attribute.KeyValue {
+	if result == nil {
+		return nil
+	}
+	return []attribute.KeyValue{
+		attribute.String(prefix+".issuer", result.Issuer),
+		attribute.String(prefix+".trust_domain", result.TrustDomain),
+	}
+}
+
+// fail records err on span and marks it failed
+func fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// deny records that an operation was denied on its span
+func deny(span trace.Span, code string, reason string) {
+	span.SetAttributes(
+		attribute.String("parsec.decision", "denied"),
+		attribute.String("parsec.denial.code", code),
+	)
+	span.SetStatus(codes.Error, reason)
+}
+
+// tracingTokenIssuanceProbe records the events of a token issuance on its span
+type tracingTokenIssuanceProbe struct {
+	service.NoOpTokenIssuanceProbe
+	span trace.Span
+}
+
+func (p *tracingTokenIssuanceProbe) TokenTypeIssuanceSucceeded(tokenType service.TokenType, token *service.Token) {
+	p.span.AddEvent("token issued", trace.WithAttributes(
+		attribute.String("parsec.token_type", string(tokenType)),
+	))
+}
+
+func (p *tracingTokenIssuanceProbe) TokenTypeIssuanceFailed(tokenType service.TokenType, err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingTokenIssuanceProbe) IssuerNotFound(tokenType service.TokenType, err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingTokenIssuanceProbe) End() {
+	p.span.End()
+}
+
+// tracingTokenExchangeProbe records the events of a token exchange on its span
+type tracingTokenExchangeProbe struct {
+	service.NoOpTokenExchangeProbe
+	span trace.Span
+}
+
+func (p *tracingTokenExchangeProbe) ActorValidationSucceeded(actor *trust.Result) {
+	p.span.SetAttributes(identityAttributes("parsec.actor", actor)...)
+}
+
+func (p *tracingTokenExchangeProbe) ActorValidationFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingTokenExchangeProbe) RequestContextParseFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingTokenExchangeProbe) SubjectTokenValidationSucceeded(subject *trust.Result) {
+	p.span.SetAttributes(identityAttributes("parsec.subject", subject)...)
+}
+
+func (p *tracingTokenExchangeProbe) SubjectTokenValidationFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingTokenExchangeProbe) ExchangeSucceeded(issuedTokenType string) {
+	p.span.SetAttributes(
+		attribute.String("parsec.decision", "allowed"),
+		attribute.String("parsec.issued_token_type", issuedTokenType),
+	)
+}
+
+func (p *tracingTokenExchangeProbe) ExchangeFailed(code string, description string) {
+	deny(p.span, code, description)
+}
+
+func (p *tracingTokenExchangeProbe) End() {
+	p.span.End()
+}
+
+// tracingAuthzCheckProbe records the events of an authorization check on its span
+type tracingAuthzCheckProbe struct {
+	service.NoOpAuthzCheckProbe
+	span trace.Span
+}
+
+func (p *tracingAuthzCheckProbe) RequestAttributesParsed(attrs *request.RequestAttributes) {
+	if attrs == nil {
+		return
+	}
+	p.span.SetAttributes(
+		attribute.String("http.request.method", attrs.Method),
+		attribute.String("url.path", attrs.Path),
+		attribute.String("server.address", attrs.Authority),
+	)
+}
+
+func (p *tracingAuthzCheckProbe) ActorValidationSucceeded(actor *trust.Result) {
+	p.span.SetAttributes(identityAttributes("parsec.actor", actor)...)
+}
+
+func (p *tracingAuthzCheckProbe) ActorValidationFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingAuthzCheckProbe) SubjectCredentialExtracted(cred trust.Credential, headersUsed []string) {
+	p.span.SetAttributes(attribute.String("parsec.credential_type", string(cred.Type())))
+}
+
+func (p *tracingAuthzCheckProbe) SubjectCredentialExtractionFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingAuthzCheckProbe) SubjectValidationSucceeded(subject *trust.Result) {
+	p.span.SetAttributes(identityAttributes("parsec.subject", subject)...)
+}
+
+func (p *tracingAuthzCheckProbe) SubjectValidationFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingAuthzCheckProbe) SubjectValidationCacheHit(subject *trust.Result) {
+	p.span.SetAttributes(attribute.Bool("parsec.validation_cache.hit", true))
+}
+
+func (p *tracingAuthzCheckProbe) SubjectValidationCacheMissed() {
+	p.span.SetAttributes(attribute.Bool("parsec.validation_cache.hit", false))
+}
+
+func (p *tracingAuthzCheckProbe) CheckAllowed() {
+	p.span.SetAttributes(attribute.String("parsec.decision", "allowed"))
+}
+
+func (p *tracingAuthzCheckProbe) CheckDenied(code string, reason string) {
+	deny(p.span, code, reason)
+}
+
+func (p *tracingAuthzCheckProbe) End() {
+	p.span.End()
+}
+
+// tracingConfigReloadProbe records the events of a configuration reload on its span
+type tracingConfigReloadProbe struct {
+	service.NoOpConfigReloadProbe
+	span trace.Span
+}
+
+func (p *tracingConfigReloadProbe) ComponentReloaded(component string) {
+	p.span.AddEvent("component reloaded", trace.WithAttributes(
+		attribute.String("parsec.component", component),
+	))
+}
+
+func (p *tracingConfigReloadProbe) RestartRequired(options []string) {
+	p.span.SetAttributes(attribute.StringSlice("parsec.restart_required", options))
+}
+
+func (p *tracingConfigReloadProbe) ConfigReloadFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingConfigReloadProbe) End() {
+	p.span.End()
+}
+
+// tracingValidationProbe records the outcome of a validation attempt on its span
+type tracingValidationProbe struct {
+	service.NoOpValidationProbe
+	span trace.Span
+}
+
+func (p *tracingValidationProbe) ValidationFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingValidationProbe) End() {
+	p.span.End()
+}
+
+// tracingDataSourceFetchProbe records the events of a data source fetch on its span
+type tracingDataSourceFetchProbe struct {
+	service.NoOpDataSourceFetchProbe
+	span trace.Span
+}
+
+func (p *tracingDataSourceFetchProbe) CacheHit() {
+	p.span.SetAttributes(attribute.Bool("parsec.cache.hit", true))
+}
+
+func (p *tracingDataSourceFetchProbe) CacheMissed() {
+	p.span.SetAttributes(attribute.Bool("parsec.cache.hit", false))
+}
+
+func (p *tracingDataSourceFetchProbe) FetchSucceeded(result *service.DataSourceResult) {
+	if result != nil {
+		p.span.SetAttributes(attribute.Int("parsec.datasource.bytes", len(result.Data)))
+	}
+}
+
+func (p *tracingDataSourceFetchProbe) FetchFailed(err error) {
+	fail(p.span, err)
+}
+
+func (p *tracingDataSourceFetchProbe) End() {
+	p.span.End()
+}
+
+// startChildSpan starts a span under the span in ctx, with that span's tracer provider
+// Without a span in ctx (tracing is not configured), the span does nothing.
+func startChildSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName)
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// tracingClaimMapper records a span for each mapping
+type tracingClaimMapper struct {
+	name   string
+	mapper service.ClaimMapper
+}
+
+// NewTracingClaimMapper wraps mapper so each mapping is a span, named for the mapper,
+// under the span of the issuance that maps
+func NewTracingClaimMapper(name string, mapper service.ClaimMapper) service.ClaimMapper {
+	return &tracingClaimMapper{name: name, mapper: mapper}
+}
+
+func (m *tracingClaimMapper) Map(ctx context.Context, input *service.MapperInput) (claims.Claims, error) {
+	ctx, span := startChildSpan(ctx, "parsec.mapper.Map",
+		attribute.String("parsec.mapper", m.name),
+	)
+	defer span.End()
+
+	result, err := m.mapper.Map(ctx, input)
+	if err != nil {
+		fail(span, err)
+	}
+	return result, err
+}
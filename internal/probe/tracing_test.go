@@ -0,0 +1,157 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestTracingObserver_ChildSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	observer := NewTracingObserver(provider)
+
+	validator := trust.NewStubValidator(trust.CredentialTypeBearer)
+	validator.WithError(errors.New("untrusted issuer"))
+	traced := NewObservedValidator("corp-idp", validator, observer)
+	mapper := NewTracingClaimMapper("passthrough", service.NewPassthroughSubjectMapper())
+
+	ctx, probe := observer.AuthzCheckStarted(context.Background())
+	_, err := traced.Validate(ctx, &trust.BearerCredential{Token: "token"})
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	probe.SubjectValidationFailed(err)
+	if _, err := mapper.Map(ctx, &service.MapperInput{Subject: &trust.Result{Subject: "alice"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	probe.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	validate, mapSpan, check := spans[0], spans[1], spans[2]
+
+	if check.Name() != "parsec.authz.Check" || check.Status().Code != codes.Error {
+		t.Errorf("expected failed check span, got %s (%v)", check.Name(), check.Status())
+	}
+	for _, child := range []sdktrace.ReadOnlySpan{validate, mapSpan} {
+		if child.Parent().SpanID() != check.SpanContext().SpanID() {
+			t.Errorf("expected %s to be a child of the check span", child.Name())
+		}
+	}
+	if validate.Name() != "parsec.trust.Validate" || validate.Status().Code != codes.Error {
+		t.Errorf("expected failed validation span, got %s (%v)", validate.Name(), validate.Status())
+	}
+	if !hasAttribute(validate.Attributes(), attribute.String("parsec.validator", "corp-idp")) {
+		t.Errorf("expected validator name attribute, got %v", validate.Attributes())
+	}
+	if mapSpan.Name() != "parsec.mapper.Map" || mapSpan.Status().Code == codes.Error {
+		t.Errorf("expected successful mapper span, got %s (%v)", mapSpan.Name(), mapSpan.Status())
+	}
+}
+
+func TestTracingObserver_Decisions(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	observer := NewTracingObserver(provider)
+
+	_, check := observer.AuthzCheckStarted(context.Background())
+	check.CheckAllowed()
+	check.End()
+
+	_, exchange := observer.TokenExchangeStarted(context.Background(), "urn:ietf:params:oauth:grant-type:token-exchange", "", nil, "admin")
+	exchange.ExchangeFailed("invalid_scope", "scope admin is not allowed")
+	exchange.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	allowed, denied := spans[0], spans[1]
+
+	if !hasAttribute(allowed.Attributes(), attribute.String("parsec.decision", "allowed")) || allowed.Status().Code == codes.Error {
+		t.Errorf("expected allowed check span, got %v (%v)", allowed.Attributes(), allowed.Status())
+	}
+	if !hasAttribute(denied.Attributes(), attribute.String("parsec.decision", "denied")) ||
+		!hasAttribute(denied.Attributes(), attribute.String("parsec.denial.code", "invalid_scope")) {
+		t.Errorf("expected denied exchange attributes, got %v", denied.Attributes())
+	}
+	if denied.Status().Code != codes.Error || denied.Status().Description != "scope admin is not allowed" {
+		t.Errorf("expected denied exchange status, got %v", denied.Status())
+	}
+}
+
+func TestTracingObserver_DataSourceFetches(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	observer := NewTracingObserver(provider)
+	source := NewObservedDataSource(&stubDataSource{name: "roles"}, observer)
+	if source.Name() != "roles" {
+		t.Errorf("expected name roles, got %s", source.Name())
+	}
+
+	// Outside an operation's span, fetches are not traced
+	result, err := source.Fetch(context.Background(), &service.DataSourceInput{})
+	if err != nil || string(result.Data) != `["admin"]` {
+		t.Errorf("unexpected result %v, %v", result, err)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Fatalf("expected no spans, got %d", len(spans))
+	}
+
+	ctx, issuance := observer.TokenIssuanceStarted(context.Background(), nil, nil, "", nil)
+	if _, err := source.Fetch(ctx, &service.DataSourceInput{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	issuance.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	fetch := spans[0]
+	if fetch.Name() != "parsec.datasource.Fetch" || fetch.Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("expected a fetch span under the issuance span, got %s", fetch.Name())
+	}
+	for _, want := range []attribute.KeyValue{
+		attribute.String("parsec.datasource", "roles"),
+		attribute.Bool("parsec.cache.hit", true),
+		attribute.Int("parsec.datasource.bytes", len(`["admin"]`)),
+	} {
+		if !hasAttribute(fetch.Attributes(), want) {
+			t.Errorf("expected attribute %v, got %v", want, fetch.Attributes())
+		}
+	}
+}
+
+// stubDataSource returns fixed data
+type stubDataSource struct {
+	name string
+}
+
+func (s *stubDataSource) Name() string { return s.name }
+
+// Fetch reports a cache hit, like a caching data source answering from its cache
+func (s *stubDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	service.DataSourceFetchProbeFromContext(ctx).CacheHit()
+	return &service.DataSourceResult{Data: []byte(`["admin"]`), ContentType: service.ContentTypeJSON}, nil
+}
+
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attrs {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
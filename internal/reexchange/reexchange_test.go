@@ -0,0 +1,156 @@
+package reexchange
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func newTestSigner(t *testing.T) *keys.StaticSigner {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return signer
+}
+
+func TestIssuer_IssueAndRedeem(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	issuer := NewIssuer(IssuerConfig{
+		TrustDomain: "parsec.example.com",
+		TTL:         30 * time.Minute,
+		Signer:      newTestSigner(t),
+		Clock:       clk,
+	})
+
+	grant := &Grant{
+		Subject: &trust.Result{
+			Subject:     "user@example.com",
+			Issuer:      "https://idp.example.com",
+			TrustDomain: "users",
+			Claims:      map[string]any{"email": "user@example.com"},
+			Delegation:  &trust.Delegation{Subject: "frontend"},
+		},
+		Actor:     &trust.Result{Subject: "spiffe://example.org/batch"},
+		Caller:    "spiffe://example.org/batch",
+		ClientID:  "batch",
+		Audiences: []string{"orders.example.com"},
+		Scope:     "orders:read",
+	}
+
+	token, expiresAt, err := issuer.Issue(ctx, grant)
+	if err != nil {
+		t.Fatalf("failed to issue: %v", err)
+	}
+	if want := clk.Now().Add(30 * time.Minute); !expiresAt.Equal(want) {
+		t.Errorf("expected expiry %v, got %v", want, expiresAt)
+	}
+
+	t.Run("redeemed before expiry", func(t *testing.T) {
+		redeemed, err := issuer.Redeem(ctx, token)
+		if err != nil {
+			t.Fatalf("failed to redeem: %v", err)
+		}
+		if redeemed.Subject.Subject != "user@example.com" || redeemed.Subject.TrustDomain != "users" {
+			t.Errorf("unexpected subject: %+v", redeemed.Subject)
+		}
+		if redeemed.Subject.Claims["email"] != "user@example.com" {
+			t.Errorf("expected subject claims, got %v", redeemed.Subject.Claims)
+		}
+		if redeemed.Subject.Delegation == nil || redeemed.Subject.Delegation.Subject != "frontend" {
+			t.Errorf("expected subject delegation, got %+v", redeemed.Subject.Delegation)
+		}
+		if redeemed.Actor == nil || redeemed.Actor.Subject != "spiffe://example.org/batch" {
+			t.Errorf("unexpected actor: %+v", redeemed.Actor)
+		}
+		if redeemed.Caller != grant.Caller || redeemed.ClientID != grant.ClientID || redeemed.Scope != grant.Scope {
+			t.Errorf("unexpected grant: %+v", redeemed)
+		}
+		if !slices.Equal(redeemed.Audiences, grant.Audiences) {
+			t.Errorf("expected audiences %v, got %v", grant.Audiences, redeemed.Audiences)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expiredClk := clock.NewFixtureClock(clk.Now().Add(31 * time.Minute))
+		expiredIssuer := NewIssuer(IssuerConfig{
+			TrustDomain: "parsec.example.com",
+			Signer:      issuer.signer,
+			Clock:       expiredClk,
+		})
+		if _, err := expiredIssuer.Redeem(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		other := NewIssuer(IssuerConfig{
+			TrustDomain: "parsec.example.com",
+			Signer:      newTestSigner(t),
+			Clock:       clk,
+		})
+		if _, err := other.Redeem(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("another trust domain", func(t *testing.T) {
+		other := NewIssuer(IssuerConfig{
+			TrustDomain: "other.example.com",
+			Signer:      issuer.signer,
+			Clock:       clk,
+		})
+		if _, err := other.Redeem(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("other tokens from the same signer", func(t *testing.T) {
+		// A token signed with the same key but without the re-exchange typ header,
+		// such as a transaction token
+		jwtToken := jwt.New()
+		_ = jwtToken.Set(jwt.IssuerKey, "parsec.example.com")
+		_ = jwtToken.Set(jwt.AudienceKey, "parsec.example.com")
+		_ = jwtToken.Set(jwt.ExpirationKey, clk.Now().Add(time.Minute).Unix())
+		_ = jwtToken.Set(grantClaim, grant)
+		signer, keyID, alg, err := issuer.signer.GetCurrentSigner(ctx)
+		if err != nil {
+			t.Fatalf("failed to get signer: %v", err)
+		}
+		headers := jws.NewHeaders()
+		_ = headers.Set(jws.KeyIDKey, string(keyID))
+		signed, err := jwt.Sign(jwtToken, jwt.WithKey(jwa.SignatureAlgorithm(alg), signer, jws.WithProtectedHeaders(headers)))
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+
+		if _, err := issuer.Redeem(ctx, string(signed)); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		if _, err := issuer.Redeem(ctx, "not-a-token"); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+}
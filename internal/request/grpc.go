@@ -0,0 +1,56 @@
+package request
+
+import "strings"
+
+// GRPCAttributes identifies the RPC of a gRPC request
+type GRPCAttributes struct {
+	// Service is the fully qualified service name (e.g., "parsec.v1.TokenExchange")
+	Service string `json:"service"`
+
+	// Method is the RPC method name (e.g., "Exchange")
+	Method string `json:"method"`
+
+	// Authority is the :authority the call was made to (e.g., "orders.example.com:443")
+	Authority string `json:"authority"`
+}
+
+// NewGRPCAttributes returns the gRPC attributes of a request with the given
+// content type, path, and authority, or nil if the request is not a gRPC call.
+// gRPC-Web requests are treated as gRPC since they address RPCs the same way.
+func NewGRPCAttributes(contentType, path, authority string) *GRPCAttributes {
+	if !IsGRPCContentType(contentType) {
+		return nil
+	}
+	service, method, ok := ParseGRPCPath(path)
+	if !ok {
+		return nil
+	}
+	return &GRPCAttributes{Service: service, Method: method, Authority: authority}
+}
+
+// IsGRPCContentType reports whether contentType is a gRPC or gRPC-Web content type,
+// such as "application/grpc", "application/grpc+proto", or "application/grpc-web+json"
+func IsGRPCContentType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	for _, base := range []string{"application/grpc-web-text", "application/grpc-web", "application/grpc"} {
+		if rest, ok := strings.CutPrefix(mediaType, base); ok && (rest == "" || rest[0] == '+') {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseGRPCPath splits a gRPC request path of the form "/{service}/{method}"
+func ParseGRPCPath(path string) (service, method string, ok bool) {
+	rest, ok := strings.CutPrefix(path, "/")
+	if !ok {
+		return "", "", false
+	}
+	service, method, ok = strings.Cut(rest, "/")
+	if !ok || service == "" || method == "" || strings.ContainsAny(method, "/?") {
+		return "", "", false
+	}
+	return service, method, true
+}
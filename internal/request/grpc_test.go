@@ -0,0 +1,60 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/claims"
+)
+
+func TestNewGRPCAttributes(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		path        string
+		want        *GRPCAttributes
+	}{
+		{"grpc", "application/grpc", "/parsec.v1.TokenExchange/Exchange", &GRPCAttributes{"parsec.v1.TokenExchange", "Exchange", "svc.example.com"}},
+		{"grpc with codec", "application/grpc+proto", "/pkg.Svc/Do", &GRPCAttributes{"pkg.Svc", "Do", "svc.example.com"}},
+		{"grpc-web", "application/grpc-web-text; charset=utf-8", "/pkg.Svc/Do", &GRPCAttributes{"pkg.Svc", "Do", "svc.example.com"}},
+		{"case insensitive", "Application/GRPC", "/pkg.Svc/Do", &GRPCAttributes{"pkg.Svc", "Do", "svc.example.com"}},
+		{"json", "application/json", "/pkg.Svc/Do", nil},
+		{"lookalike content type", "application/grpcfoo", "/pkg.Svc/Do", nil},
+		{"missing method", "application/grpc", "/pkg.Svc", nil},
+		{"extra segment", "application/grpc", "/pkg.Svc/Do/more", nil},
+		{"relative path", "application/grpc", "pkg.Svc/Do", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewGRPCAttributes(tt.contentType, tt.path, "svc.example.com")
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected nil, got %+v", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFromClaims_GRPC(t *testing.T) {
+	attrs := FromClaims(claims.Claims{
+		"protocol":     "HTTP/2",
+		"authority":    "parsec.example.com",
+		"content_type": "application/grpc",
+		"grpc":         map[string]any{"service": "pkg.Svc", "method": "Do", "authority": "svc.example.com"},
+	})
+
+	if attrs.GRPC == nil || attrs.GRPC.Service != "pkg.Svc" || attrs.GRPC.Method != "Do" || attrs.GRPC.Authority != "svc.example.com" {
+		t.Errorf("expected grpc pkg.Svc/Do, got %+v", attrs.GRPC)
+	}
+	if attrs.Protocol != "HTTP/2" || attrs.Authority != "parsec.example.com" || attrs.ContentType != "application/grpc" {
+		t.Errorf("unexpected protocol attributes: %+v", attrs)
+	}
+	if len(attrs.Additional) != 0 {
+		t.Errorf("expected well-known fields not to be copied to additional, got %v", attrs.Additional)
+	}
+}
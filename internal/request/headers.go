@@ -0,0 +1,135 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Headers holds request headers, preserving every value of repeated headers.
+// Header names are stored lowercase, matching how Envoy (and HTTP/2) present them.
+// Use the accessor methods rather than indexing directly so lookups are case-insensitive.
+//
+// Headers serializes to JSON as a map of header name to the combined value
+// (see Get), so CEL expressions and request_context claims see the same
+// single-string shape regardless of how the header arrived.
+// Unmarshaling accepts either a combined string or a list of values.
+type Headers map[string][]string
+
+// NewHeaders creates Headers from single-valued headers, such as Envoy's
+// headers map, where repeated headers have already been combined
+func NewHeaders(single map[string]string) Headers {
+	if single == nil {
+		return nil
+	}
+	h := make(Headers, len(single))
+	for name, value := range single {
+		h.Add(name, value)
+	}
+	return h
+}
+
+// Add appends a value to the named header
+func (h Headers) Add(name, value string) {
+	key := strings.ToLower(name)
+	h[key] = append(h[key], value)
+}
+
+// Set replaces all values of the named header with value
+func (h Headers) Set(name, value string) {
+	h[strings.ToLower(name)] = []string{value}
+}
+
+// Del removes the named header
+func (h Headers) Del(name string) {
+	delete(h, strings.ToLower(name))
+}
+
+// Has reports whether the named header is present
+func (h Headers) Has(name string) bool {
+	_, ok := h[strings.ToLower(name)]
+	return ok
+}
+
+// Values returns all values of the named header in the order they were received
+func (h Headers) Values(name string) []string {
+	return h[strings.ToLower(name)]
+}
+
+// First returns the first value of the named header, or "" if absent
+func (h Headers) First(name string) string {
+	values := h[strings.ToLower(name)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Get returns the combined value of the named header, or "" if absent.
+// Repeated values are joined with ", " as permitted by RFC 9110 section 5.3.
+// This is the same value Envoy reports for repeated headers in its headers map.
+func (h Headers) Get(name string) string {
+	return strings.Join(h[strings.ToLower(name)], ", ")
+}
+
+// Combined returns every header as its combined value (see Get)
+func (h Headers) Combined() map[string]string {
+	if h == nil {
+		return nil
+	}
+	combined := make(map[string]string, len(h))
+	for name, values := range h {
+		combined[name] = strings.Join(values, ", ")
+	}
+	return combined
+}
+
+// MarshalJSON encodes headers as their combined values
+func (h Headers) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Combined())
+}
+
+// UnmarshalJSON decodes headers from either combined string values or lists of values
+func (h *Headers) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*h = nil
+		return nil
+	}
+
+	headers, err := headersFromMap(raw)
+	if err != nil {
+		return err
+	}
+	*h = headers
+	return nil
+}
+
+// headersFromMap builds Headers from a generic map whose values are strings or lists of strings
+func headersFromMap(raw map[string]any) (Headers, error) {
+	headers := make(Headers, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			headers.Add(name, v)
+		case []string:
+			for _, s := range v {
+				headers.Add(name, s)
+			}
+		case []any:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("header %q: expected string value, got %T", name, item)
+				}
+				headers.Add(name, s)
+			}
+		default:
+			return nil, fmt.Errorf("header %q: expected string or list of strings, got %T", name, value)
+		}
+	}
+	return headers, nil
+}
@@ -0,0 +1,91 @@
+package request
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/claims"
+)
+
+func TestHeaders_Accessors(t *testing.T) {
+	h := make(Headers)
+	h.Add("X-Forwarded-For", "10.0.0.1")
+	h.Add("x-forwarded-for", "10.0.0.2")
+
+	if !h.Has("X-FORWARDED-FOR") {
+		t.Error("expected lookup to be case-insensitive")
+	}
+	if got := h.Values("x-forwarded-for"); len(got) != 2 {
+		t.Errorf("expected 2 values, got %v", got)
+	}
+	if got := h.First("x-forwarded-for"); got != "10.0.0.1" {
+		t.Errorf("expected first value 10.0.0.1, got %q", got)
+	}
+	if got := h.Get("x-forwarded-for"); got != "10.0.0.1, 10.0.0.2" {
+		t.Errorf("expected combined value, got %q", got)
+	}
+
+	h.Set("x-forwarded-for", "10.0.0.3")
+	if got := h.Values("x-forwarded-for"); len(got) != 1 || got[0] != "10.0.0.3" {
+		t.Errorf("expected Set to replace values, got %v", got)
+	}
+
+	h.Del("X-Forwarded-For")
+	if h.Has("x-forwarded-for") {
+		t.Error("expected header to be deleted")
+	}
+	if got := h.Get("missing"); got != "" {
+		t.Errorf("expected empty value for missing header, got %q", got)
+	}
+}
+
+func TestHeaders_JSON(t *testing.T) {
+	attrs := RequestAttributes{
+		Headers: Headers{"accept": {"text/html", "application/json"}},
+	}
+
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	headers := m["headers"].(map[string]any)
+	if headers["accept"] != "text/html, application/json" {
+		t.Errorf("expected combined value in JSON, got %v", headers["accept"])
+	}
+
+	var decoded Headers
+	if err := json.Unmarshal([]byte(`{"accept":["a","b"],"Host":"example.com"}`), &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got := decoded.Values("accept"); len(got) != 2 {
+		t.Errorf("expected list values to be preserved, got %v", got)
+	}
+	if got := decoded.Get("host"); got != "example.com" {
+		t.Errorf("expected string value, got %q", got)
+	}
+
+	if err := json.Unmarshal([]byte(`{"accept":1}`), &decoded); err == nil {
+		t.Error("expected error for non-string header value")
+	}
+}
+
+func TestFromClaims_Headers(t *testing.T) {
+	attrs := FromClaims(claims.Claims{
+		"headers": map[string]any{
+			"X-Tenant": "acme",
+			"accept":   []any{"a", "b"},
+		},
+	})
+
+	if got := attrs.Headers.Get("x-tenant"); got != "acme" {
+		t.Errorf("expected x-tenant acme, got %q", got)
+	}
+	if got := attrs.Headers.Values("accept"); len(got) != 2 {
+		t.Errorf("expected 2 accept values, got %v", got)
+	}
+}
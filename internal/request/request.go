@@ -22,13 +22,26 @@ type RequestAttributes struct {
 	// UserAgent is the client user agent
 	UserAgent string `json:"user_agent,omitempty"`
 
-	// Headers contains relevant HTTP headers
-	Headers map[string]string `json:"headers,omitempty"`
+	// Protocol is the request protocol as reported by the proxy (e.g., "HTTP/1.1", "HTTP/2")
+	Protocol string `json:"protocol,omitempty"`
+
+	// Authority is the :authority pseudo-header (or Host header for HTTP/1.1)
+	Authority string `json:"authority,omitempty"`
+
+	// ContentType is the request content type
+	ContentType string `json:"content_type,omitempty"`
+
+	// GRPC identifies the RPC being called. It is nil unless the request is a gRPC call.
+	GRPC *GRPCAttributes `json:"grpc,omitempty"`
+
+	// Headers contains relevant HTTP headers, including every value of repeated headers
+	Headers Headers `json:"headers,omitempty"`
 
 	// Additional arbitrary context
 	// This can include:
 	// - "host": The HTTP host header
 	// - "context_extensions": Envoy's context extensions (map[string]string)
+	// - "source", "destination": Envoy's peer principal, service, and labels
 	// - Custom application-specific context
 	// Note: No omitempty tag to ensure this field is always present in JSON,
 	// even when empty, for CEL filter expressions to work correctly
@@ -60,14 +73,38 @@ func FromClaims(filteredClaims claims.Claims) *RequestAttributes {
 	if userAgent := filteredClaims.GetString("user_agent"); userAgent != "" {
 		attrs.UserAgent = userAgent
 	}
+	if protocol := filteredClaims.GetString("protocol"); protocol != "" {
+		attrs.Protocol = protocol
+	}
+	if authority := filteredClaims.GetString("authority"); authority != "" {
+		attrs.Authority = authority
+	}
+	if contentType := filteredClaims.GetString("content_type"); contentType != "" {
+		attrs.ContentType = contentType
+	}
+	if grpc, ok := filteredClaims["grpc"].(map[string]any); ok {
+		service, _ := grpc["service"].(string)
+		method, _ := grpc["method"].(string)
+		authority, _ := grpc["authority"].(string)
+		if service != "" && method != "" {
+			attrs.GRPC = &GRPCAttributes{Service: service, Method: method, Authority: authority}
+		}
+	}
 
 	// Handle headers if present
 	if headersRaw, ok := filteredClaims["headers"]; ok {
 		if headersMap, ok := headersRaw.(map[string]any); ok {
-			attrs.Headers = make(map[string]string)
+			attrs.Headers = make(Headers)
 			for k, v := range headersMap {
-				if str, ok := v.(string); ok {
-					attrs.Headers[k] = str
+				switch value := v.(type) {
+				case string:
+					attrs.Headers.Add(k, value)
+				case []any:
+					for _, item := range value {
+						if str, ok := item.(string); ok {
+							attrs.Headers.Add(k, str)
+						}
+					}
 				}
 			}
 		}
@@ -75,11 +112,15 @@ func FromClaims(filteredClaims claims.Claims) *RequestAttributes {
 
 	// Add all other claims to Additional
 	knownFields := map[string]bool{
-		"method":     true,
-		"path":       true,
-		"ip_address": true,
-		"user_agent": true,
-		"headers":    true,
+		"method":       true,
+		"path":         true,
+		"ip_address":   true,
+		"user_agent":   true,
+		"protocol":     true,
+		"authority":    true,
+		"content_type": true,
+		"grpc":         true,
+		"headers":      true,
 	}
 
 	for key, value := range filteredClaims {
@@ -0,0 +1,95 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// CELPolicy grants the requested scopes for which a CEL expression evaluates to true
+type CELPolicy struct {
+	program cel.Program
+}
+
+// NewCELPolicy creates a CEL scope policy
+// The script is evaluated once per requested scope and must evaluate to a boolean.
+// It has access to:
+//   - scope: the requested scope (string)
+//   - subject: the subject's Result as a map (subject, issuer, trust_domain, claims, scope, etc.)
+//   - actor: the actor's Result as a map, with empty fields if anonymous
+//   - request: the request attributes as a map (method, path, headers, additional, etc.)
+//
+// Example expressions:
+//   - scope.startsWith("read:") || actor.trust_domain == "internal"
+//   - scope in subject.claims.roles
+func NewCELPolicy(script string) (*CELPolicy, error) {
+	if script == "" {
+		return nil, fmt.Errorf("CEL scope policy script cannot be empty")
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("scope", cel.StringType),
+		cel.Variable("subject", cel.DynType),
+		cel.Variable("actor", cel.DynType),
+		cel.Variable("request", cel.DynType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	ast, issues := env.Compile(script)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile CEL scope policy script: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("CEL scope policy script must evaluate to a boolean, got %s", ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL program: %w", err)
+	}
+
+	return &CELPolicy{program: program}, nil
+}
+
+// Grant implements Policy
+func (p *CELPolicy) Grant(ctx context.Context, req *Request) ([]string, error) {
+	if len(req.Scopes) == 0 {
+		return nil, nil
+	}
+
+	subject, err := trust.ConvertResultToMap(req.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert subject: %w", err)
+	}
+	actor, err := trust.ConvertResultToMap(req.Actor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert actor: %w", err)
+	}
+	requestAttrs, err := trust.ConvertRequestAttributesToMap(req.RequestAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert request attributes: %w", err)
+	}
+
+	var granted []string
+	for _, s := range req.Scopes {
+		result, _, err := p.program.ContextEval(ctx, map[string]any{
+			"scope":   s,
+			"subject": subject,
+			"actor":   actor,
+			"request": requestAttrs,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate scope policy for scope %s: %w", s, err)
+		}
+		if result == types.True {
+			granted = append(granted, s)
+		}
+	}
+	return granted, nil
+}
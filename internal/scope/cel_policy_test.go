@@ -0,0 +1,76 @@
+package scope
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestCELPolicy(t *testing.T) {
+	ctx := context.Background()
+	req := &Request{
+		Subject: &trust.Result{
+			Subject:     "alice",
+			TrustDomain: "users",
+			Claims:      claims.Claims{"roles": []any{"orders:write"}},
+		},
+		Actor:             &trust.Result{Subject: "gateway", TrustDomain: "workloads"},
+		RequestAttributes: &request.RequestAttributes{Method: "POST"},
+		Scopes:            []string{"orders:read", "orders:write", "admin"},
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "by scope",
+			script: `scope.endsWith(":read")`,
+			want:   []string{"orders:read"},
+		},
+		{
+			name:   "by subject claims",
+			script: `scope in subject.claims.roles`,
+			want:   []string{"orders:write"},
+		},
+		{
+			name:   "by actor and request",
+			script: `scope != "admin" && actor.trust_domain == "workloads" && request.method == "POST"`,
+			want:   []string{"orders:read", "orders:write"},
+		},
+		{
+			name:   "denies everything",
+			script: `false`,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewCELPolicy(tt.script)
+			if err != nil {
+				t.Fatalf("NewCELPolicy() error = %v", err)
+			}
+			got, err := policy.Grant(ctx, req)
+			if err != nil {
+				t.Fatalf("Grant() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Grant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCELPolicy_Invalid(t *testing.T) {
+	for _, script := range []string{"", "scope +", `scope + "x"`} {
+		if _, err := NewCELPolicy(script); err == nil {
+			t.Errorf("NewCELPolicy(%q) expected error", script)
+		}
+	}
+}
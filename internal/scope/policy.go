@@ -0,0 +1,106 @@
+package scope
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// Request is a request for a token with scopes
+type Request struct {
+	// Subject is the identity the token is issued for
+	Subject *trust.Result
+
+	// Actor is the party acting on behalf of the subject, or anonymous
+	Actor *trust.Result
+
+	// RequestAttributes contains information about the request
+	RequestAttributes *request.RequestAttributes
+
+	// Scopes are the requested scopes
+	Scopes []string
+}
+
+// Policy decides which requested scopes a token may be issued with
+type Policy interface {
+	// Grant returns the requested scopes that are allowed, in the order requested
+	Grant(ctx context.Context, req *Request) ([]string, error)
+}
+
+// Parse splits a space-delimited scope parameter (RFC 6749 section 3.3) into
+// its scopes, dropping duplicates
+func Parse(scope string) []string {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// Format joins scopes into a space-delimited scope parameter
+func Format(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// PassthroughPolicy grants every requested scope
+type PassthroughPolicy struct{}
+
+// Grant implements Policy
+func (PassthroughPolicy) Grant(ctx context.Context, req *Request) ([]string, error) {
+	return req.Scopes, nil
+}
+
+// AllowlistPolicyConfig configures an AllowlistPolicy
+type AllowlistPolicyConfig struct {
+	// SubjectScopes are the scopes subjects of each trust domain may be granted
+	// Subjects of other trust domains are granted no scopes
+	SubjectScopes map[string][]string
+
+	// ActorScopes, if set, are the scopes actors of each trust domain may request
+	// on behalf of a subject, in addition to the subject being allowed them
+	// Actors of other trust domains, including anonymous actors, may request no scopes
+	ActorScopes map[string][]string
+}
+
+// AllowlistPolicy grants the requested scopes allowed for the subject's trust domain
+// and, if configured, the actor's
+type AllowlistPolicy struct {
+	subjectScopes map[string][]string
+	actorScopes   map[string][]string
+}
+
+// NewAllowlistPolicy creates an allowlist scope policy
+func NewAllowlistPolicy(cfg AllowlistPolicyConfig) *AllowlistPolicy {
+	return &AllowlistPolicy{
+		subjectScopes: cfg.SubjectScopes,
+		actorScopes:   cfg.ActorScopes,
+	}
+}
+
+// Grant implements Policy
+func (p *AllowlistPolicy) Grant(ctx context.Context, req *Request) ([]string, error) {
+	var granted []string
+	for _, s := range req.Scopes {
+		if !allowed(p.subjectScopes, req.Subject, s) {
+			continue
+		}
+		if p.actorScopes != nil && !allowed(p.actorScopes, req.Actor, s) {
+			continue
+		}
+		granted = append(granted, s)
+	}
+	return granted, nil
+}
+
+// allowed reports whether scopes allows party's trust domain the scope
+func allowed(scopes map[string][]string, party *trust.Result, scope string) bool {
+	if party == nil {
+		return false
+	}
+	return slices.Contains(scopes[party.TrustDomain], scope)
+}
@@ -0,0 +1,92 @@
+package scope
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestParse(t *testing.T) {
+	got := Parse("  read write\tread admin ")
+	want := []string{"read", "write", "admin"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+	if got := Parse(""); got != nil {
+		t.Errorf("Parse(\"\") = %v, want nil", got)
+	}
+}
+
+func TestAllowlistPolicy(t *testing.T) {
+	ctx := context.Background()
+	user := &trust.Result{Subject: "alice", TrustDomain: "users"}
+	gateway := &trust.Result{Subject: "gateway", TrustDomain: "workloads"}
+
+	tests := []struct {
+		name    string
+		cfg     AllowlistPolicyConfig
+		subject *trust.Result
+		actor   *trust.Result
+		want    []string
+	}{
+		{
+			name:    "grants scopes allowed for the subject's trust domain",
+			cfg:     AllowlistPolicyConfig{SubjectScopes: map[string][]string{"users": {"read", "write"}}},
+			subject: user,
+			actor:   trust.AnonymousResult(),
+			want:    []string{"read", "write"},
+		},
+		{
+			name:    "downgrades scopes not allowed for the subject",
+			cfg:     AllowlistPolicyConfig{SubjectScopes: map[string][]string{"users": {"read"}}},
+			subject: user,
+			actor:   trust.AnonymousResult(),
+			want:    []string{"read"},
+		},
+		{
+			name:    "grants nothing to subjects of unlisted trust domains",
+			cfg:     AllowlistPolicyConfig{SubjectScopes: map[string][]string{"admins": {"read", "write", "admin"}}},
+			subject: user,
+			actor:   trust.AnonymousResult(),
+			want:    nil,
+		},
+		{
+			name: "requires actor to be allowed scopes too",
+			cfg: AllowlistPolicyConfig{
+				SubjectScopes: map[string][]string{"users": {"read", "write", "admin"}},
+				ActorScopes:   map[string][]string{"workloads": {"read", "admin"}},
+			},
+			subject: user,
+			actor:   gateway,
+			want:    []string{"read", "admin"},
+		},
+		{
+			name: "grants nothing to anonymous actors if actor scopes are set",
+			cfg: AllowlistPolicyConfig{
+				SubjectScopes: map[string][]string{"users": {"read"}},
+				ActorScopes:   map[string][]string{"workloads": {"read"}},
+			},
+			subject: user,
+			actor:   trust.AnonymousResult(),
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewAllowlistPolicy(tt.cfg).Grant(ctx, &Request{
+				Subject: tt.subject,
+				Actor:   tt.actor,
+				Scopes:  []string{"read", "write", "admin"},
+			})
+			if err != nil {
+				t.Fatalf("Grant() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Grant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
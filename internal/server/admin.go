@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// AdminServer implements the Admin gRPC service
+// It provides operational controls, such as forcing key rotation or revoking keys, to holders of an admin token
+type AdminServer struct {
+	parsecv1.UnimplementedAdminServer
+
+	issuerRegistry service.Registry
+	tokens         [][]byte
+}
+
+// AdminServerConfig configures the admin server
+type AdminServerConfig struct {
+	// IssuerRegistry provides the issuers whose keys can be managed
+	IssuerRegistry service.Registry
+
+	// Tokens are the bearer tokens that authorize admin calls
+	// If empty, every call is rejected
+	Tokens []string
+}
+
+// NewAdminServer creates a new admin server
+func NewAdminServer(cfg AdminServerConfig) *AdminServer {
+	tokens := make([][]byte, 0, len(cfg.Tokens))
+	for _, token := range cfg.Tokens {
+		if token != "" {
+			tokens = append(tokens, []byte(token))
+		}
+	}
+
+	return &AdminServer{
+		issuerRegistry: cfg.IssuerRegistry,
+		tokens:         tokens,
+	}
+}
+
+// RotateKey implements the Admin service
+func (s *AdminServer) RotateKey(ctx context.Context, req *parsecv1.RotateKeyRequest) (*parsecv1.RotateKeyResponse, error) {
+	if err := s.authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	if req.TokenType == "" {
+		return nil, status.Error(codes.InvalidArgument, "token_type is required")
+	}
+	tokenType := service.TokenType(req.TokenType)
+
+	issuer, err := s.issuerRegistry.GetIssuer(tokenType)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "no issuer for token type %s", tokenType)
+	}
+
+	rotating, ok := issuer.(service.KeyRotatingIssuer)
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "issuer for token type %s does not support key rotation", tokenType)
+	}
+
+	if err := rotating.RotateKey(ctx); err != nil {
+		switch {
+		case errors.Is(err, service.ErrKeyRotationNotSupported):
+			return nil, status.Errorf(codes.FailedPrecondition, "issuer for token type %s does not support key rotation", tokenType)
+		case errors.Is(err, keys.ErrRotationInProgress):
+			return nil, status.Errorf(codes.Aborted, "key rotation for token type %s is already in progress", tokenType)
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to rotate key for token type %s: %v", tokenType, err)
+		}
+	}
+
+	slog.InfoContext(ctx, "Admin forced key rotation", "token_type", tokenType)
+
+	publicKeys, err := issuer.PublicKeys(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "key rotated, but failed to list keys for token type %s: %v", tokenType, err)
+	}
+
+	resp := &parsecv1.RotateKeyResponse{
+		TokenType: req.TokenType,
+		KeyIds:    make([]string, 0, len(publicKeys)),
+	}
+	for _, key := range publicKeys {
+		resp.KeyIds = append(resp.KeyIds, key.KeyID)
+	}
+
+	return resp, nil
+}
+
+// RevokeKey implements the Admin service
+func (s *AdminServer) RevokeKey(ctx context.Context, req *parsecv1.RevokeKeyRequest) (*parsecv1.RevokeKeyResponse, error) {
+	if err := s.authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	if req.TokenType == "" {
+		return nil, status.Error(codes.InvalidArgument, "token_type is required")
+	}
+	if req.KeyId == "" {
+		return nil, status.Error(codes.InvalidArgument, "key_id is required")
+	}
+	tokenType := service.TokenType(req.TokenType)
+
+	issuer, err := s.issuerRegistry.GetIssuer(tokenType)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "no issuer for token type %s", tokenType)
+	}
+
+	revoking, ok := issuer.(service.KeyRevokingIssuer)
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "issuer for token type %s does not support key revocation", tokenType)
+	}
+
+	if err := revoking.RevokeKey(ctx, req.KeyId); err != nil {
+		switch {
+		case errors.Is(err, service.ErrKeyRevocationNotSupported):
+			return nil, status.Errorf(codes.FailedPrecondition, "issuer for token type %s does not support key revocation", tokenType)
+		case errors.Is(err, keys.ErrUnknownKeyID):
+			return nil, status.Errorf(codes.NotFound, "issuer for token type %s has no key %s", tokenType, req.KeyId)
+		case errors.Is(err, keys.ErrRotationInProgress), errors.Is(err, keys.ErrVersionMismatch):
+			return nil, status.Errorf(codes.Aborted, "key %s for token type %s could not be fully revoked, retry: %v", req.KeyId, tokenType, err)
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to revoke key %s for token type %s: %v", req.KeyId, tokenType, err)
+		}
+	}
+
+	slog.InfoContext(ctx, "Admin revoked key", "key_id", req.KeyId, "token_type", tokenType)
+
+	publicKeys, err := issuer.PublicKeys(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "key revoked, but failed to list keys for token type %s: %v", tokenType, err)
+	}
+
+	resp := &parsecv1.RevokeKeyResponse{
+		TokenType: req.TokenType,
+		KeyIds:    make([]string, 0, len(publicKeys)),
+	}
+	for _, key := range publicKeys {
+		resp.KeyIds = append(resp.KeyIds, key.KeyID)
+	}
+
+	return resp, nil
+}
+
+// GetSigningStatus implements the Admin service
+func (s *AdminServer) GetSigningStatus(ctx context.Context, req *parsecv1.GetSigningStatusRequest) (*parsecv1.GetSigningStatusResponse, error) {
+	if err := s.authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	if req.TokenType == "" {
+		return nil, status.Error(codes.InvalidArgument, "token_type is required")
+	}
+	tokenType := service.TokenType(req.TokenType)
+
+	issuer, err := s.issuerRegistry.GetIssuer(tokenType)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "no issuer for token type %s", tokenType)
+	}
+
+	signing, ok := issuer.(service.SigningStatusIssuer)
+	if !ok {
+		return nil, status.Errorf(codes.FailedPrecondition, "issuer for token type %s does not sign tokens", tokenType)
+	}
+
+	signingStatus, err := signing.SigningStatus(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get signing status for token type %s: %v", tokenType, err)
+	}
+
+	publicKeys, err := issuer.PublicKeys(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list keys for token type %s: %v", tokenType, err)
+	}
+
+	resp := &parsecv1.GetSigningStatusResponse{
+		TokenType:        req.TokenType,
+		SigningKeyId:     signingStatus.KeyID,
+		SigningAlgorithm: signingStatus.Algorithm,
+		Keys:             make([]*parsecv1.PublishedKey, 0, len(publicKeys)),
+	}
+	for _, key := range publicKeys {
+		resp.Keys = append(resp.Keys, &parsecv1.PublishedKey{
+			KeyId:     key.KeyID,
+			Algorithm: key.Algorithm,
+		})
+	}
+
+	if migration := signingStatus.Migration; migration != nil {
+		resp.Migration = &parsecv1.AlgorithmMigration{
+			Phase:         migration.Phase,
+			FromAlgorithm: migration.FromAlgorithm,
+			ToAlgorithm:   migration.ToAlgorithm,
+			CutoverTime:   timestamppb.New(migration.CutoverAt),
+			RetireTime:    timestamppb.New(migration.RetireAt),
+		}
+	}
+
+	return resp, nil
+}
+
+// authenticate checks the request carries one of the admin bearer tokens
+// The gateway forwards the HTTP Authorization header as "authorization" metadata
+func (s *AdminServer) authenticate(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			continue
+		}
+		for _, expected := range s.tokens {
+			if subtle.ConstantTimeCompare([]byte(token), expected) == 1 {
+				return nil
+			}
+		}
+	}
+	return status.Error(codes.Unauthenticated, "a valid admin bearer token is required")
+}
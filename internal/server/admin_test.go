@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+func TestAdminServer_RotateKey(t *testing.T) {
+	ctx := context.Background()
+
+	rotatingSigner := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:           string(service.TokenTypeTransactionToken),
+		TrustDomain:         "example.com",
+		KeyProviderID:       "memory",
+		KeyProviderRegistry: map[string]keys.KeyProvider{"memory": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256")},
+		SlotStore:           keys.NewInMemoryKeySlotStore(),
+	})
+	if err := rotatingSigner.Start(ctx); err != nil {
+		t.Fatalf("failed to start signer: %v", err)
+	}
+	t.Cleanup(rotatingSigner.Stop)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	staticSigner, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    rotatingSigner,
+	}))
+	registry.Register(service.TokenTypeAccessToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    staticSigner,
+	}))
+	registry.Register(service.TokenTypeRHIdentity, issuer.NewRHIdentityIssuer(issuer.RHIdentityIssuerConfig{
+		TokenType: string(service.TokenTypeRHIdentity),
+	}))
+
+	adminServer := NewAdminServer(AdminServerConfig{
+		IssuerRegistry: registry,
+		Tokens:         []string{"admin-secret"},
+	})
+
+	authorized := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer admin-secret"))
+
+	t.Run("rotates the issuer's key", func(t *testing.T) {
+		resp, err := adminServer.RotateKey(authorized, &parsecv1.RotateKeyRequest{
+			TokenType: string(service.TokenTypeTransactionToken),
+		})
+		if err != nil {
+			t.Fatalf("RotateKey failed: %v", err)
+		}
+		if resp.TokenType != string(service.TokenTypeTransactionToken) {
+			t.Errorf("expected token type %s, got %s", service.TokenTypeTransactionToken, resp.TokenType)
+		}
+		if len(resp.KeyIds) != 2 {
+			t.Errorf("expected the old and new key to be published, got %v", resp.KeyIds)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		tokenType service.TokenType
+		code      codes.Code
+	}{
+		{"missing token", ctx, service.TokenTypeTransactionToken, codes.Unauthenticated},
+		{"wrong token", metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer nope")), service.TokenTypeTransactionToken, codes.Unauthenticated},
+		{"wrong scheme", metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Basic admin-secret")), service.TokenTypeTransactionToken, codes.Unauthenticated},
+		{"missing token type", authorized, "", codes.InvalidArgument},
+		{"unknown token type", authorized, "urn:example:unknown", codes.NotFound},
+		{"signer cannot rotate", authorized, service.TokenTypeAccessToken, codes.FailedPrecondition},
+		{"unsigned issuer", authorized, service.TokenTypeRHIdentity, codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := adminServer.RotateKey(tt.ctx, &parsecv1.RotateKeyRequest{TokenType: string(tt.tokenType)})
+			if got := status.Code(err); got != tt.code {
+				t.Errorf("expected code %s, got %s (%v)", tt.code, got, err)
+			}
+		})
+	}
+
+	t.Run("no tokens configured rejects every call", func(t *testing.T) {
+		closed := NewAdminServer(AdminServerConfig{IssuerRegistry: registry})
+		_, err := closed.RotateKey(metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer ")),
+			&parsecv1.RotateKeyRequest{TokenType: string(service.TokenTypeTransactionToken)})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated, got %v", err)
+		}
+	})
+}
+
+func TestAdminServer_GetSigningStatus(t *testing.T) {
+	ctx := context.Background()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	from, err := keys.NewStaticSigner(rsaKey, "RS256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	to, err := keys.NewStaticSigner(ecKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	cutoverAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	migration, err := keys.NewAlgorithmMigrationSigner(keys.AlgorithmMigrationSignerConfig{
+		From:      from,
+		To:        to,
+		CutoverAt: cutoverAt,
+	})
+	if err != nil {
+		t.Fatalf("failed to create migration signer: %v", err)
+	}
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    migration,
+	}))
+	registry.Register(service.TokenTypeRHIdentity, issuer.NewRHIdentityIssuer(issuer.RHIdentityIssuerConfig{
+		TokenType: string(service.TokenTypeRHIdentity),
+	}))
+
+	adminServer := NewAdminServer(AdminServerConfig{
+		IssuerRegistry: registry,
+		Tokens:         []string{"admin-secret"},
+	})
+
+	t.Run("over HTTP", func(t *testing.T) {
+		mux := runtime.NewServeMux()
+		if err := parsecv1.RegisterAdminHandlerServer(ctx, mux, adminServer); err != nil {
+			t.Fatalf("failed to register admin handler: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/v1/keys/"+url.PathEscape(string(service.TokenTypeTransactionToken))+"/status", nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp struct {
+			SigningAlgorithm string `json:"signingAlgorithm"`
+			Keys             []struct {
+				Algorithm string `json:"algorithm"`
+			} `json:"keys"`
+			Migration struct {
+				Phase         string `json:"phase"`
+				FromAlgorithm string `json:"fromAlgorithm"`
+				ToAlgorithm   string `json:"toAlgorithm"`
+				CutoverTime   string `json:"cutoverTime"`
+			} `json:"migration"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if resp.SigningAlgorithm != "RS256" {
+			t.Errorf("expected signing algorithm RS256 before cutover, got %s", resp.SigningAlgorithm)
+		}
+		if len(resp.Keys) != 2 {
+			t.Errorf("expected keys of both algorithms to be published, got %v", resp.Keys)
+		}
+		if resp.Migration.Phase != "dual_publish" || resp.Migration.FromAlgorithm != "RS256" || resp.Migration.ToAlgorithm != "ES256" {
+			t.Errorf("unexpected migration status: %+v", resp.Migration)
+		}
+		if resp.Migration.CutoverTime != cutoverAt.UTC().Format(time.RFC3339) {
+			t.Errorf("expected cutover time %s, got %s", cutoverAt.UTC().Format(time.RFC3339), resp.Migration.CutoverTime)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/admin/v1/keys/"+url.PathEscape(string(service.TokenTypeTransactionToken))+"/status", nil)
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401 without a token, got %d", rec.Code)
+		}
+	})
+
+	t.Run("unsigned issuer", func(t *testing.T) {
+		authorized := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer admin-secret"))
+		_, err := adminServer.GetSigningStatus(authorized, &parsecv1.GetSigningStatusRequest{
+			TokenType: string(service.TokenTypeRHIdentity),
+		})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("expected FailedPrecondition, got %v", err)
+		}
+	})
+}
+
+func TestAdminServer_RevokeKey(t *testing.T) {
+	ctx := context.Background()
+
+	rotatingSigner := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:           string(service.TokenTypeTransactionToken),
+		TrustDomain:         "example.com",
+		KeyProviderID:       "memory",
+		KeyProviderRegistry: map[string]keys.KeyProvider{"memory": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256")},
+		SlotStore:           keys.NewInMemoryKeySlotStore(),
+	})
+	if err := rotatingSigner.Start(ctx); err != nil {
+		t.Fatalf("failed to start signer: %v", err)
+	}
+	t.Cleanup(rotatingSigner.Stop)
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    rotatingSigner,
+	}))
+	registry.Register(service.TokenTypeRHIdentity, issuer.NewRHIdentityIssuer(issuer.RHIdentityIssuerConfig{
+		TokenType: string(service.TokenTypeRHIdentity),
+	}))
+
+	adminServer := NewAdminServer(AdminServerConfig{
+		IssuerRegistry: registry,
+		Tokens:         []string{"admin-secret"},
+	})
+
+	authorized := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer admin-secret"))
+
+	_, revokedID, _, err := rotatingSigner.GetCurrentSigner(ctx)
+	if err != nil {
+		t.Fatalf("failed to get current signer: %v", err)
+	}
+
+	t.Run("revokes the key", func(t *testing.T) {
+		resp, err := adminServer.RevokeKey(authorized, &parsecv1.RevokeKeyRequest{
+			TokenType: string(service.TokenTypeTransactionToken),
+			KeyId:     string(revokedID),
+		})
+		if err != nil {
+			t.Fatalf("RevokeKey failed: %v", err)
+		}
+		if len(resp.KeyIds) != 1 || resp.KeyIds[0] == string(revokedID) {
+			t.Errorf("expected only the replacement key to be published, got %v", resp.KeyIds)
+		}
+
+		_, keyID, _, err := rotatingSigner.GetCurrentSigner(ctx)
+		if err != nil {
+			t.Fatalf("failed to get current signer: %v", err)
+		}
+		if keyID == revokedID {
+			t.Errorf("expected revoked key %s to stop signing", revokedID)
+		}
+	})
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		tokenType service.TokenType
+		keyID     string
+		code      codes.Code
+	}{
+		{"missing token", ctx, service.TokenTypeTransactionToken, string(revokedID), codes.Unauthenticated},
+		{"missing token type", authorized, "", string(revokedID), codes.InvalidArgument},
+		{"missing key id", authorized, service.TokenTypeTransactionToken, "", codes.InvalidArgument},
+		{"unknown token type", authorized, "urn:example:unknown", string(revokedID), codes.NotFound},
+		{"unknown key id", authorized, service.TokenTypeTransactionToken, "not-a-key", codes.NotFound},
+		{"unsigned issuer", authorized, service.TokenTypeRHIdentity, string(revokedID), codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := adminServer.RevokeKey(tt.ctx, &parsecv1.RevokeKeyRequest{TokenType: string(tt.tokenType), KeyId: tt.keyID})
+			if got := status.Code(err); got != tt.code {
+				t.Errorf("expected code %s, got %s (%v)", tt.code, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/alechenninger/parsec/internal/audit"
+	"github.com/alechenninger/parsec/internal/logging"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// auditCheck records the decision a check responded with
+func (s *AuthzServer) auditCheck(ctx context.Context, rec *audit.Record, resp *authv3.CheckResponse) {
+	if code, reason, denied := checkDenial(resp); denied {
+		rec.Outcome = audit.OutcomeDenied
+		rec.Code = code
+		rec.Reason = reason
+	} else {
+		rec.Outcome = audit.OutcomeIssued
+	}
+	logAudit(ctx, s.Audit, rec)
+}
+
+// auditExchange records the decision of an exchange that failed with err, if not nil
+func (s *ExchangeServer) auditExchange(ctx context.Context, rec *audit.Record, err error) {
+	if err == nil {
+		rec.Outcome = audit.OutcomeIssued
+	} else {
+		rec.Outcome = audit.OutcomeDenied
+		rec.Code, rec.Reason = exchangeError(err)
+	}
+	logAudit(ctx, s.Audit, rec)
+}
+
+// logAudit writes rec, even if the request was canceled
+// A record that cannot be written does not change the decision.
+func logAudit(ctx context.Context, logger *audit.Logger, rec *audit.Record) {
+	if err := logger.Log(context.WithoutCancel(ctx), *rec); err != nil {
+		slog.WarnContext(ctx, "Failed to write audit record", "error", err)
+	}
+}
+
+// recordIssuedTokens records the transaction ID, the claims redacted, and, unless
+// already recorded, the audiences of issued tokens. The transaction ID is logged with
+// the rest of the request.
+func recordIssuedTokens(ctx context.Context, rec *audit.Record, tokens map[service.TokenType]*service.Token) {
+	var audiences []string
+	for _, token := range tokens {
+		if rec.TransactionID == "" {
+			rec.TransactionID = token.TransactionID
+		}
+		rec.RedactedClaims = append(rec.RedactedClaims, token.RedactedClaims...)
+		switch aud := token.Claims["aud"].(type) {
+		case string:
+			audiences = append(audiences, aud)
+		case []string:
+			audiences = append(audiences, aud...)
+		case []any:
+			for _, v := range aud {
+				if s, ok := v.(string); ok {
+					audiences = append(audiences, s)
+				}
+			}
+		}
+	}
+	if rec.TransactionID != "" {
+		logging.AddAttrs(ctx, slog.String(logging.TransactionIDKey, rec.TransactionID))
+	}
+	if len(rec.Audiences) == 0 {
+		slices.Sort(audiences)
+		rec.Audiences = slices.Compact(audiences)
+	}
+	slices.Sort(rec.RedactedClaims)
+	rec.RedactedClaims = slices.Compact(rec.RedactedClaims)
+}
+
+// auditPolicy returns the token types the route issues and the policies it applies
+func (r *routeConfig) auditPolicy() ([]string, map[string]string) {
+	tokenTypes := make([]string, len(r.tokenTypes))
+	for i, spec := range r.tokenTypes {
+		tokenTypes[i] = string(spec.Type)
+	}
+
+	var policy map[string]string
+	if len(r.requiredScopes) > 0 || r.validators != nil || r.trustDomain != "" {
+		policy = make(map[string]string)
+		if len(r.requiredScopes) > 0 {
+			policy["required_scopes"] = strings.Join(r.requiredScopes, " ")
+		}
+		if r.validators != nil {
+			policy["validators"] = strings.Join(r.validators, " ")
+		}
+		if r.trustDomain != "" {
+			policy["trust_domain"] = r.trustDomain
+		}
+	}
+	return tokenTypes, policy
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/audit"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL:                 "https://parsec.test",
+		TTL:                       5 * time.Minute,
+		TransactionContextMappers: []service.ClaimMapper{service.NewPassthroughSubjectMapper()},
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	var out bytes.Buffer
+	logger, err := audit.NewLogger(audit.Config{Sinks: []audit.Sink{audit.NewWriterSink(&out)}, Chain: true})
+	if err != nil {
+		t.Fatalf("failed to create audit logger: %v", err)
+	}
+	lastRecord := func(t *testing.T) audit.Record {
+		t.Helper()
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		var rec audit.Record
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &rec); err != nil {
+			t.Fatalf("failed to decode record: %v", err)
+		}
+		return rec
+	}
+
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+	authzServer.Audit = logger
+	check := func(headers map[string]string) *authv3.CheckRequest {
+		return &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{Method: "GET", Path: "/orders", Headers: headers},
+				},
+			},
+		}
+	}
+
+	t.Run("allowed check", func(t *testing.T) {
+		if _, err := authzServer.Check(ctx, check(map[string]string{"authorization": "Bearer alice-token"})); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rec := lastRecord(t)
+		if rec.Event != audit.EventAuthzCheck || rec.Outcome != audit.OutcomeIssued {
+			t.Errorf("expected issued check, got %+v", rec)
+		}
+		if rec.Subject == nil || rec.TransactionID == "" || rec.Request.Path != "/orders" {
+			t.Errorf("expected subject, txn, and request, got %+v", rec)
+		}
+	})
+
+	t.Run("denied check", func(t *testing.T) {
+		if _, err := authzServer.Check(ctx, check(nil)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rec := lastRecord(t)
+		if rec.Outcome != audit.OutcomeDenied || rec.Code != "Unauthenticated" || rec.Reason == "" {
+			t.Errorf("expected unauthenticated denial, got %+v", rec)
+		}
+	})
+
+	t.Run("denied exchange", func(t *testing.T) {
+		exchangeServer := NewExchangeServer(trustStore, tokenService, nil, nil)
+		exchangeServer.Audit = logger
+		_, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{GrantType: "client_credentials"})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		rec := lastRecord(t)
+		if rec.Event != audit.EventTokenExchange || rec.Outcome != audit.OutcomeDenied || rec.Code != oauthUnsupportedGrantType {
+			t.Errorf("expected unsupported_grant_type denial, got %+v", rec)
+		}
+		if rec.Sequence != 3 {
+			t.Errorf("expected third record, got %d", rec.Sequence)
+		}
+	})
+
+	t.Run("records redacted claims", func(t *testing.T) {
+		rec := &audit.Record{}
+		recordIssuedTokens(context.Background(), rec, map[service.TokenType]*service.Token{
+			service.TokenTypeTransactionToken: {RedactedClaims: []string{"tctx.email", "req_ctx.client_ip"}},
+			service.TokenTypeAccessToken:      {RedactedClaims: []string{"tctx.email"}},
+		})
+		if want := []string{"req_ctx.client_ip", "tctx.email"}; !slices.Equal(rec.RedactedClaims, want) {
+			t.Errorf("expected redacted claims %v, got %v", want, rec.RedactedClaims)
+		}
+	})
+}
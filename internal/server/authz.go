@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -10,6 +11,9 @@ import (
 	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/codes"
 
+	"github.com/alechenninger/parsec/internal/audit"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/logging"
 	"github.com/alechenninger/parsec/internal/request"
 	"github.com/alechenninger/parsec/internal/service"
 	"github.com/alechenninger/parsec/internal/trust"
@@ -22,7 +26,13 @@ type TokenTypeSpec struct {
 
 	// HeaderName is the HTTP header to use for this token
 	// e.g., "Transaction-Token", "Authorization", "X-Custom-Token"
+	// Optional if Cookie is set
 	HeaderName string
+
+	// Cookie, if set, also delivers the token to the client as a cookie
+	// (via Set-Cookie on the response), for browser frontends that cannot
+	// read custom response headers
+	Cookie *CookieSpec
 }
 
 // AuthzServer implements Envoy's ext_authz Authorization service
@@ -34,8 +44,47 @@ type AuthzServer struct {
 	observer     service.AuthzCheckObserver
 
 	// TokenTypesToIssue specifies which token types to issue and their headers
-	// This could come from configuration in the future
+	// A route can override them with the ContextExtensionTokenTypes context extension
 	TokenTypesToIssue []TokenTypeSpec
+
+	// TrustForwardedClientCert reads the workload certificate from the x-forwarded-client-cert
+	// header when Envoy does not send a peer certificate. Only enable this if the proxy
+	// in front of parsec sanitizes the header.
+	TrustForwardedClientCert bool
+
+	// APIKeyHeaders are request headers that carry API keys (e.g., "x-api-key")
+	// A request without an Authorization header is authenticated with the first
+	// of these headers it has
+	APIKeyHeaders []string
+
+	// BodyCredentialRules extract bearer tokens from request bodies, for requests
+	// with neither an Authorization header nor an API key header
+	BodyCredentialRules []BodyCredentialRule
+
+	// ValidationCache, if set, reuses recent subject validations of the same credential
+	// Routes can opt out with the ContextExtensionValidationCache context extension.
+	ValidationCache *ValidationCache
+
+	// CertificateBoundTokens binds issued tokens to the requesting workload's validated
+	// client certificate with a cnf claim (RFC 8705 section 3)
+	CertificateBoundTokens bool
+
+	// TransactionIDHeader, if set, names a header that carries the txn ID of the issued
+	// transaction token to both the upstream service and the client, so either can
+	// correlate the request with the token
+	TransactionIDHeader string
+
+	// Denial configures the HTTP responses denied requests get
+	Denial DenialSpec
+
+	// Headers configures how allowed requests' headers are changed
+	Headers HeaderPolicy
+
+	// Audit, if set, records every check's decision
+	Audit *audit.Logger
+
+	// AuthzRules, if set, may deny requests before any token is issued
+	AuthzRules service.AuthorizationPolicy
 }
 
 // NewAuthzServer creates a new ext_authz server
@@ -64,14 +113,42 @@ func NewAuthzServer(trustStore trust.Store, tokenService *service.TokenService,
 }
 
 // Check implements the ext_authz check endpoint
-func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
-	// Create request-scoped probe
+func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (resp *authv3.CheckResponse, err error) {
+	// Create request-scoped probe, in the trace of the request being checked
+	ctx = withTraceParent(ctx, req.GetAttributes().GetRequest().GetHttp().GetHeaders())
 	ctx, probe := s.observer.AuthzCheckStarted(ctx)
 	defer probe.End()
 
+	// Report the decision, however the check ends
+	defer func() {
+		if code, reason, denied := checkDenial(resp); denied {
+			probe.CheckDenied(code, reason)
+		} else {
+			probe.CheckAllowed()
+		}
+	}()
+
+	// Audit the decision, however the check ends
+	decision := &audit.Record{Event: audit.EventAuthzCheck}
+	if s.Audit != nil {
+		defer func() { s.auditCheck(ctx, decision, resp) }()
+	}
+
 	// 1. Build request attributes
 	reqAttrs := s.buildRequestAttributes(req)
 	probe.RequestAttributesParsed(reqAttrs)
+	decision.Request = &audit.Request{
+		Method:    reqAttrs.Method,
+		Path:      reqAttrs.Path,
+		Authority: reqAttrs.Authority,
+		IPAddress: reqAttrs.IPAddress,
+	}
+
+	route, err := s.resolveRoute(req)
+	if err != nil {
+		return s.denyResponse(codes.Internal, fmt.Sprintf("invalid route configuration: %v", err)), nil
+	}
+	decision.TokenTypes, decision.Policy = route.auditPolicy()
 
 	// 2. Extract actor credential from gRPC context
 	actorCred, err := extractActorCredential(ctx)
@@ -94,6 +171,7 @@ func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*aut
 		actor = trust.AnonymousResult()
 		probe.ActorValidationSucceeded(actor)
 	}
+	decision.Actor = audit.IdentityOf(actor)
 
 	// 3. Filter trust store based on actor permissions
 	filteredStore, err := s.trustStore.ForActor(ctx, actor, reqAttrs)
@@ -101,68 +179,208 @@ func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*aut
 		return s.denyResponse(codes.PermissionDenied,
 			fmt.Sprintf("failed to filter trust store: %v", err)), nil
 	}
+	if route.validators != nil {
+		restricting, ok := filteredStore.(trust.ValidatorRestrictingStore)
+		if !ok {
+			return s.denyResponse(codes.Internal, "trust store cannot restrict validators per route"), nil
+		}
+		filteredStore = restricting.WithValidators(route.validators...)
+	}
+	domain, err := s.tokenService.ResolveTrustDomain(route.trustDomain, nil)
+	if err != nil {
+		return s.denyResponse(codes.Internal, fmt.Sprintf("invalid route configuration: %v", err)), nil
+	}
+	if domain != nil && domain.Validators != nil {
+		restricting, ok := filteredStore.(trust.ValidatorRestrictingStore)
+		if !ok {
+			return s.denyResponse(codes.Internal, "trust store cannot restrict validators per trust domain"), nil
+		}
+		filteredStore = restricting.WithValidators(domain.Validators...)
+	}
 
 	// 4. Extract subject credentials from request
 	// The extraction layer returns both the credential and which headers were used
 	cred, headersUsed, err := s.extractCredential(req)
 	if err != nil {
 		probe.SubjectCredentialExtractionFailed(err)
+		// Per RFC 6750, the challenge to a request without a bearer credential has no error code
 		return s.denyResponse(codes.Unauthenticated, fmt.Sprintf("failed to extract credentials: %v", err)), nil
 	}
 	probe.SubjectCredentialExtracted(cred, headersUsed)
 
 	// 5. Validate subject credentials against filtered trust store
 	// The filtered store only includes validators the actor is allowed to use
-	result, err := filteredStore.Validate(ctx, cred)
-	if err != nil {
-		probe.SubjectValidationFailed(err)
-		return s.denyResponse(codes.Unauthenticated, fmt.Sprintf("validation failed: %v", err)), nil
+	// A recent validation of the same credential, for the same actor and route, and with
+	// the same validators, may be reused. Stores that cannot list the validators they
+	// were filtered to are not cached.
+	var cacheKey string
+	if s.ValidationCache != nil && route.cacheValidation {
+		if listing, ok := filteredStore.(trust.ValidatorListingStore); ok {
+			cacheKey = validationCacheKey(cred, actor, listing.ValidatorNames(), req.GetAttributes().GetContextExtensions())
+		}
+	}
+	var result *trust.Result
+	var cached bool
+	if cacheKey != "" {
+		if result, cached = s.ValidationCache.get(cacheKey); cached {
+			probe.SubjectValidationCacheHit(result)
+		} else {
+			probe.SubjectValidationCacheMissed()
+		}
+	}
+	if !cached {
+		result, err = filteredStore.Validate(ctx, cred)
+		if err != nil {
+			probe.SubjectValidationFailed(err)
+			return s.denyChallenge(codes.Unauthenticated, bearerChallenge{Error: bearerErrorInvalidToken},
+				fmt.Sprintf("validation failed: %v", err)), nil
+		}
+		if cacheKey != "" {
+			s.ValidationCache.put(cacheKey, result)
+		}
 	}
 	probe.SubjectValidationSucceeded(result)
+	decision.Subject = audit.IdentityOf(result)
+	logging.AddAttrs(ctx, logging.Subject(result.Subject))
+
+	if !hasScopes(result.Scope, route.requiredScopes) {
+		scope := strings.Join(route.requiredScopes, " ")
+		return s.denyChallenge(codes.PermissionDenied,
+			bearerChallenge{Error: bearerErrorInsufficientScope, Scope: scope},
+			fmt.Sprintf("credential lacks required scopes: %s", scope)), nil
+	}
+
+	// 6. Extract and validate the requesting workload's client certificate, if any
+	var workload *trust.Result
+	var certificateThumbprint string
+	workloadCred, err := extractWorkloadCredential(req, s.TrustForwardedClientCert)
+	if err != nil {
+		return s.denyResponse(codes.Unauthenticated, fmt.Sprintf("failed to extract workload credential: %v", err)), nil
+	}
+	if workloadCred != nil {
+		workload, err = filteredStore.Validate(ctx, workloadCred)
+		if err != nil {
+			return s.denyResponse(codes.Unauthenticated, fmt.Sprintf("workload validation failed: %v", err)), nil
+		}
+		if x509Cred, ok := workloadCred.(*trust.X509Credential); ok && s.CertificateBoundTokens {
+			certificateThumbprint = trust.CertificateThumbprint(x509Cred.Certificate.Raw)
+		}
+	}
 
-	// 6. Issue tokens via TokenService
-	tokenTypes := make([]service.TokenType, len(s.TokenTypesToIssue))
-	for i, spec := range s.TokenTypesToIssue {
+	// 7. Issue tokens via TokenService
+	tokenTypes := make([]service.TokenType, len(route.tokenTypes))
+	for i, spec := range route.tokenTypes {
 		tokenTypes[i] = spec.Type
 	}
 
-	issuedTokens, err := s.tokenService.IssueTokens(ctx, &service.IssueRequest{
+	issueRequest := &service.IssueRequest{
 		Subject:           result,
 		Actor:             actor,
+		Workload:          workload,
 		RequestAttributes: reqAttrs,
-		TokenTypes:        tokenTypes,
+		// The gateway is not acting on behalf of the subject, but any delegation
+		// the subject's credential records is carried through
+		Delegation:            result.Delegation,
+		CertificateThumbprint: certificateThumbprint,
+		TrustDomain:           route.trustDomain,
+		TokenTypes:            tokenTypes,
 		// TODO: Get scope from configuration or request
 		Scope: "",
-	})
+	}
+	if s.AuthzRules != nil {
+		if err := s.AuthzRules.Authorize(ctx, issueRequest); err != nil {
+			code := codes.Internal
+			if errors.Is(err, service.ErrIssuanceDenied) {
+				code = codes.PermissionDenied
+			}
+			return s.denyResponse(code, err.Error()), nil
+		}
+	}
+	issuedTokens, err := s.tokenService.IssueTokens(ctx, issueRequest)
 	if err != nil {
-		return s.denyResponse(codes.Internal, fmt.Sprintf("failed to issue tokens: %v", err)), nil
+		code := codes.Internal
+		switch {
+		case errors.Is(err, keys.ErrSigningSaturated):
+			code = codes.Unavailable
+		case errors.Is(err, service.ErrIssuanceDenied):
+			code = codes.PermissionDenied
+		}
+		return s.denyResponse(code, fmt.Sprintf("failed to issue tokens: %v", err)), nil
 	}
+	recordIssuedTokens(ctx, decision, issuedTokens)
 
-	// 7. Build response headers from issued tokens
+	// 8. Build upstream request headers and client cookies from issued tokens
 	responseHeaders := make([]*corev3.HeaderValueOption, 0, len(issuedTokens))
-	for _, spec := range s.TokenTypesToIssue {
-		if token, ok := issuedTokens[spec.Type]; ok {
-			responseHeaders = append(responseHeaders, &corev3.HeaderValueOption{
+	var clientHeaders []*corev3.HeaderValueOption
+	var transactionID string
+	for _, spec := range route.tokenTypes {
+		token, ok := issuedTokens[spec.Type]
+		if !ok {
+			continue
+		}
+		if transactionID == "" {
+			transactionID = token.TransactionID
+		}
+		if spec.HeaderName != "" {
+			header, err := s.Headers.upstreamHeader(spec.HeaderName, token.Value)
+			if err != nil {
+				return s.denyResponse(codes.Internal, err.Error()), nil
+			}
+			responseHeaders = append(responseHeaders, header)
+		}
+		if spec.Cookie != nil {
+			clientHeaders = append(clientHeaders, &corev3.HeaderValueOption{
 				Header: &corev3.HeaderValue{
-					Key:   spec.HeaderName,
-					Value: token.Value,
+					Key:   "Set-Cookie",
+					Value: spec.Cookie.setCookie(token),
 				},
+				// Each token is its own cookie, so keep every Set-Cookie header
+				AppendAction: corev3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD,
 			})
 		}
 	}
 
-	// 8. Return OK with issued tokens in headers
+	if s.TransactionIDHeader != "" && transactionID != "" {
+		header, err := s.Headers.upstreamHeader(s.TransactionIDHeader, transactionID)
+		if err != nil {
+			return s.denyResponse(codes.Internal, err.Error()), nil
+		}
+		responseHeaders = append(responseHeaders, header)
+		clientHeaders = append(clientHeaders, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: s.TransactionIDHeader, Value: transactionID},
+		})
+	}
+
+	if s.Headers.ForwardedUserHeader != "" {
+		header, err := s.Headers.upstreamHeader(s.Headers.ForwardedUserHeader, result.Subject)
+		if err != nil {
+			return s.denyResponse(codes.Internal, err.Error()), nil
+		}
+		responseHeaders = append(responseHeaders, header)
+	}
+
+	// 9. Describe the subject and issued tokens to later Envoy filters
+	metadata, err := buildAuthzMetadata(result, issuedTokens)
+	if err != nil {
+		return s.denyResponse(codes.Internal, err.Error()), nil
+	}
+
+	// 10. Return OK with issued tokens in headers
 	// Remove the external credential headers so they don't leak to backend
 	// This creates a security boundary - external credentials stay outside
+	// The header policy may strip further headers
 	return &authv3.CheckResponse{
 		Status: &status.Status{
 			Code: int32(codes.OK),
 		},
+		DynamicMetadata: metadata,
 		HttpResponse: &authv3.CheckResponse_OkResponse{
 			OkResponse: &authv3.OkHttpResponse{
 				Headers: responseHeaders,
 				// Remove external credential headers - security boundary
-				HeadersToRemove: headersUsed,
+				HeadersToRemove: s.Headers.headersToRemove(headersUsed),
+				// Cookies are set on the response to the client
+				ResponseHeadersToAdd: clientHeaders,
 			},
 		},
 	}, nil
@@ -172,15 +390,37 @@ func (s *AuthzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*aut
 // Returns the credential and the list of headers that were used to extract it
 func (s *AuthzServer) extractCredential(req *authv3.CheckRequest) (trust.Credential, []string, error) {
 	httpReq := req.GetAttributes().GetRequest().GetHttp()
-	// TODO: mtls e.g. cert := req.GetAttributes().GetSource().GetCertificate()
 
 	if httpReq == nil {
 		return nil, nil, fmt.Errorf("no HTTP request attributes")
 	}
 
+	headers := requestHeaders(httpReq)
+
 	// Look for Authorization header
-	authHeader := httpReq.GetHeaders()["authorization"]
+	// Use the first value; a request with several Authorization headers is ambiguous
+	// and only the first is considered
+	authHeader := headers.First("authorization")
 	if authHeader == "" {
+		// Fall back to an API key in a custom header
+		for _, header := range s.APIKeyHeaders {
+			header = strings.ToLower(header)
+			if key := headers.First(header); key != "" {
+				cred := &trust.APIKeyCredential{
+					Key:    key,
+					Header: header,
+				}
+				return cred, []string{header}, nil
+			}
+		}
+		// Fall back to a token in the body; it cannot be removed before forwarding
+		cred, err := extractBodyCredential(req, s.BodyCredentialRules)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cred != nil {
+			return cred, nil, nil
+		}
 		return nil, nil, fmt.Errorf("no authorization header")
 	}
 
@@ -198,12 +438,19 @@ func (s *AuthzServer) extractCredential(req *authv3.CheckRequest) (trust.Credent
 
 	// Future: Handle other authentication schemes
 	// - Basic auth: would use "authorization" header
-	// - API key in custom header: would track that header name
 	// - Cookie-based auth: would track cookie names
 
 	return nil, nil, fmt.Errorf("unsupported authorization scheme")
 }
 
+// checkDenial returns the status code and reason of resp, if it denies the request
+func checkDenial(resp *authv3.CheckResponse) (code string, reason string, denied bool) {
+	if c := codes.Code(resp.GetStatus().GetCode()); c != codes.OK {
+		return c.String(), resp.GetStatus().GetMessage(), true
+	}
+	return "", "", false
+}
+
 // buildRequestAttributes extracts request attributes from the Envoy request
 func (s *AuthzServer) buildRequestAttributes(req *authv3.CheckRequest) *request.RequestAttributes {
 	httpReq := req.GetAttributes().GetRequest().GetHttp()
@@ -222,27 +469,60 @@ func (s *AuthzServer) buildRequestAttributes(req *authv3.CheckRequest) *request.
 		additional["context_extensions"] = contextExtensions
 	}
 
+	// Add what Envoy knows about the client and the service being called, such as
+	// Istio workload identities and destination pod labels
+	if source := peerAttributes(req.GetAttributes().GetSource()); source != nil {
+		additional["source"] = source
+	}
+	if destination := peerAttributes(req.GetAttributes().GetDestination()); destination != nil {
+		additional["destination"] = destination
+	}
+
+	headers := requestHeaders(httpReq)
+	contentType := headers.Get("content-type")
+
+	// Envoy fills in path and host from the :path and :authority pseudo-headers,
+	// but fall back to the pseudo-headers in case only they were sent
+	path := httpReq.GetPath()
+	if path == "" {
+		path = headers.First(":path")
+	}
+	authority := httpReq.GetHost()
+	if authority == "" {
+		authority = headers.First(":authority")
+	}
+
 	return &request.RequestAttributes{
-		Method:     httpReq.GetMethod(),
-		Path:       httpReq.GetPath(),
-		IPAddress:  req.GetAttributes().GetSource().GetAddress().GetSocketAddress().GetAddress(),
-		UserAgent:  httpReq.GetHeaders()["user-agent"],
-		Headers:    httpReq.GetHeaders(),
-		Additional: additional,
+		Method:      httpReq.GetMethod(),
+		Path:        path,
+		IPAddress:   req.GetAttributes().GetSource().GetAddress().GetSocketAddress().GetAddress(),
+		UserAgent:   headers.Get("user-agent"),
+		Protocol:    httpReq.GetProtocol(),
+		Authority:   authority,
+		ContentType: contentType,
+		GRPC:        request.NewGRPCAttributes(contentType, path, authority),
+		Headers:     headers,
+		Additional:  additional,
 	}
 }
 
-// denyResponse creates a denial response
-func (s *AuthzServer) denyResponse(code codes.Code, message string) *authv3.CheckResponse {
-	return &authv3.CheckResponse{
-		Status: &status.Status{
-			Code:    int32(code),
-			Message: message,
-		},
-		HttpResponse: &authv3.CheckResponse_DeniedResponse{
-			DeniedResponse: &authv3.DeniedHttpResponse{
-				Body: message,
-			},
-		},
+// requestHeaders returns the request headers from the Envoy request.
+// When Envoy is configured with encode_raw_headers, headers arrive in header_map
+// with one entry per occurrence, so repeated headers keep each value.
+// Otherwise Envoy has already combined repeated headers into the headers map.
+func requestHeaders(httpReq *authv3.AttributeContext_HttpRequest) request.Headers {
+	headerMap := httpReq.GetHeaderMap().GetHeaders()
+	if len(headerMap) == 0 {
+		return request.NewHeaders(httpReq.GetHeaders())
+	}
+
+	headers := make(request.Headers, len(headerMap))
+	for _, header := range headerMap {
+		value := header.GetValue()
+		if raw := header.GetRawValue(); len(raw) > 0 {
+			value = string(raw)
+		}
+		headers.Add(header.GetKey(), value)
 	}
+	return headers
 }
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// authzMetadata is the dynamic metadata ext_authz returns with an allowed request
+// Envoy stores it under the ext_authz filter's namespace (envoy.filters.http.ext_authz),
+// where later filters, such as RBAC, rate limiting, and access logging, can read it.
+type authzMetadata struct {
+	// Subject is the validated subject of the request
+	Subject authzMetadataSubject `json:"subject"`
+
+	// Tokens has an entry for each issued token, keyed by token type
+	Tokens map[string]authzMetadataToken `json:"tokens,omitempty"`
+}
+
+type authzMetadataSubject struct {
+	Subject     string         `json:"subject"`
+	Issuer      string         `json:"issuer,omitempty"`
+	TrustDomain string         `json:"trust_domain,omitempty"`
+	Scope       string         `json:"scope,omitempty"`
+	Claims      map[string]any `json:"claims,omitempty"`
+}
+
+type authzMetadataToken struct {
+	// TransactionID is the token's txn claim, if it has one
+	TransactionID string `json:"txn,omitempty"`
+
+	// Claims are the claims the token carries, if its issuer reports them
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+// buildAuthzMetadata returns the dynamic metadata for a request whose subject was
+// validated and for which tokens were issued
+func buildAuthzMetadata(subject *trust.Result, tokens map[service.TokenType]*service.Token) (*structpb.Struct, error) {
+	metadata := authzMetadata{
+		Subject: authzMetadataSubject{
+			Subject:     subject.Subject,
+			Issuer:      subject.Issuer,
+			TrustDomain: subject.TrustDomain,
+			Scope:       subject.Scope,
+			Claims:      subject.Claims,
+		},
+	}
+	if len(tokens) > 0 {
+		metadata.Tokens = make(map[string]authzMetadataToken, len(tokens))
+		for tokenType, token := range tokens {
+			metadata.Tokens[string(tokenType)] = authzMetadataToken{
+				TransactionID: token.TransactionID,
+				Claims:        token.Claims,
+			}
+		}
+	}
+
+	// Claims can hold any JSON value, so convert through JSON rather than structpb.NewStruct,
+	// which only accepts a few Go types
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dynamic metadata: %w", err)
+	}
+	result := &structpb.Struct{}
+	if err := result.UnmarshalJSON(data); err != nil {
+		return nil, fmt.Errorf("failed to encode dynamic metadata: %w", err)
+	}
+	return result, nil
+}
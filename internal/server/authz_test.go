@@ -2,15 +2,18 @@ package server
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/request"
 	"github.com/alechenninger/parsec/internal/service"
 	"github.com/alechenninger/parsec/internal/trust"
 )
@@ -809,6 +812,7 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 			"ActorValidationSucceeded",
 			"SubjectCredentialExtracted",
 			"SubjectValidationSucceeded",
+			"CheckAllowed",
 			"End",
 		)
 	})
@@ -855,6 +859,7 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 			"ActorValidationSucceeded",
 			"SubjectCredentialExtracted",
 			"SubjectValidationSucceeded", // Still succeeds even for invalid token with StubValidator
+			"CheckDenied",                // No issuer is registered for the token
 			"End",
 		)
 	})
@@ -895,7 +900,578 @@ func TestAuthzServer_Check_Observability(t *testing.T) {
 			"RequestAttributesParsed",
 			"ActorValidationSucceeded",
 			"SubjectCredentialExtractionFailed",
+			service.ProbeCall("CheckDenied", "Unauthenticated", "failed to extract credentials: no authorization header"),
 			"End",
 		)
 	})
 }
+
+func TestAuthzServer_RequestHeaders(t *testing.T) {
+	authzServer := NewAuthzServer(trust.NewStubStore(), nil, nil, nil)
+
+	t.Run("combined headers map", func(t *testing.T) {
+		req := &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Headers: map[string]string{
+							"x-forwarded-for": "10.0.0.1,10.0.0.2",
+							"user-agent":      "test-agent",
+						},
+					},
+				},
+			},
+		}
+
+		attrs := authzServer.buildRequestAttributes(req)
+
+		if got := attrs.Headers.Values("X-Forwarded-For"); len(got) != 1 || got[0] != "10.0.0.1,10.0.0.2" {
+			t.Errorf("expected single combined value, got %v", got)
+		}
+		if attrs.UserAgent != "test-agent" {
+			t.Errorf("expected user agent test-agent, got %q", attrs.UserAgent)
+		}
+	})
+
+	t.Run("raw header map preserves repeated headers", func(t *testing.T) {
+		req := &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						HeaderMap: &corev3.HeaderMap{
+							Headers: []*corev3.HeaderValue{
+								{Key: "x-forwarded-for", RawValue: []byte("10.0.0.1")},
+								{Key: "x-forwarded-for", RawValue: []byte("10.0.0.2")},
+								{Key: "authorization", RawValue: []byte("Bearer first")},
+								{Key: "authorization", RawValue: []byte("Bearer second")},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		attrs := authzServer.buildRequestAttributes(req)
+
+		values := attrs.Headers.Values("x-forwarded-for")
+		if len(values) != 2 || values[0] != "10.0.0.1" || values[1] != "10.0.0.2" {
+			t.Errorf("expected both values in order, got %v", values)
+		}
+		if got := attrs.Headers.Get("x-forwarded-for"); got != "10.0.0.1, 10.0.0.2" {
+			t.Errorf("expected combined value, got %q", got)
+		}
+
+		cred, headersUsed, err := authzServer.extractCredential(req)
+		if err != nil {
+			t.Fatalf("extractCredential failed: %v", err)
+		}
+		bearer, ok := cred.(*trust.BearerCredential)
+		if !ok || bearer.Token != "first" {
+			t.Errorf("expected first authorization value to be used, got %#v", cred)
+		}
+		if len(headersUsed) != 1 || headersUsed[0] != "authorization" {
+			t.Errorf("expected authorization to be tracked, got %v", headersUsed)
+		}
+	})
+}
+
+func TestAuthzServer_GRPCRequestAttributes(t *testing.T) {
+	authzServer := NewAuthzServer(trust.NewStubStore(), nil, nil, nil)
+
+	t.Run("gRPC call", func(t *testing.T) {
+		req := &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method:   "POST",
+						Path:     "/parsec.v1.TokenExchange/Exchange",
+						Host:     "parsec.example.com",
+						Protocol: "HTTP/2",
+						Headers: map[string]string{
+							"content-type": "application/grpc+proto",
+						},
+					},
+				},
+			},
+		}
+
+		attrs := authzServer.buildRequestAttributes(req)
+
+		if attrs.GRPC == nil {
+			t.Fatal("expected gRPC attributes")
+		}
+		if attrs.GRPC.Service != "parsec.v1.TokenExchange" || attrs.GRPC.Method != "Exchange" {
+			t.Errorf("expected parsec.v1.TokenExchange/Exchange, got %s/%s", attrs.GRPC.Service, attrs.GRPC.Method)
+		}
+		if attrs.GRPC.Authority != "parsec.example.com" {
+			t.Errorf("expected gRPC authority parsec.example.com, got %q", attrs.GRPC.Authority)
+		}
+		if attrs.Protocol != "HTTP/2" {
+			t.Errorf("expected protocol HTTP/2, got %q", attrs.Protocol)
+		}
+		if attrs.Authority != "parsec.example.com" {
+			t.Errorf("expected authority parsec.example.com, got %q", attrs.Authority)
+		}
+		if attrs.ContentType != "application/grpc+proto" {
+			t.Errorf("expected content type application/grpc+proto, got %q", attrs.ContentType)
+		}
+	})
+
+	t.Run("gRPC call with only pseudo-headers", func(t *testing.T) {
+		req := &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method: "POST",
+						Headers: map[string]string{
+							":path":        "/orders.v1.Orders/Get",
+							":authority":   "orders.example.com",
+							"content-type": "application/grpc",
+						},
+					},
+				},
+			},
+		}
+
+		attrs := authzServer.buildRequestAttributes(req)
+
+		if attrs.Path != "/orders.v1.Orders/Get" || attrs.Authority != "orders.example.com" {
+			t.Errorf("expected path and authority from pseudo-headers, got %q and %q", attrs.Path, attrs.Authority)
+		}
+		want := request.GRPCAttributes{Service: "orders.v1.Orders", Method: "Get", Authority: "orders.example.com"}
+		if attrs.GRPC == nil || *attrs.GRPC != want {
+			t.Errorf("expected gRPC attributes %+v, got %+v", want, attrs.GRPC)
+		}
+	})
+
+	t.Run("plain HTTP request", func(t *testing.T) {
+		req := &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method:   "POST",
+						Path:     "/api/resource",
+						Protocol: "HTTP/1.1",
+						Headers: map[string]string{
+							"content-type": "application/json",
+						},
+					},
+				},
+			},
+		}
+
+		attrs := authzServer.buildRequestAttributes(req)
+
+		if attrs.GRPC != nil {
+			t.Errorf("expected no gRPC attributes, got %+v", attrs.GRPC)
+		}
+		if attrs.ContentType != "application/json" {
+			t.Errorf("expected content type application/json, got %q", attrs.ContentType)
+		}
+	})
+}
+
+func TestAuthzServer_CookieDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServer(trustStore, tokenService, []TokenTypeSpec{
+		{
+			Type:   service.TokenTypeTransactionToken,
+			Cookie: &CookieSpec{Name: "__Host-txn_token"},
+		},
+	}, nil)
+
+	req := &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method: "GET",
+					Path:   "/app",
+					Headers: map[string]string{
+						"authorization": "Bearer test-token-123",
+					},
+				},
+			},
+		},
+	}
+
+	resp, err := authzServer.Check(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	okResp := resp.GetOkResponse()
+	if okResp == nil {
+		t.Fatalf("expected OK response, got code %d: %s", resp.Status.Code, resp.Status.Message)
+	}
+
+	if len(okResp.Headers) != 0 {
+		t.Errorf("expected no upstream token headers in cookie-only mode, got %v", okResp.Headers)
+	}
+	if len(okResp.ResponseHeadersToAdd) != 1 {
+		t.Fatalf("expected one Set-Cookie response header, got %d", len(okResp.ResponseHeadersToAdd))
+	}
+
+	header := okResp.ResponseHeadersToAdd[0]
+	if header.Header.Key != "Set-Cookie" {
+		t.Errorf("expected Set-Cookie, got %s", header.Header.Key)
+	}
+	if header.AppendAction != corev3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD {
+		t.Errorf("expected Set-Cookie to be appended, got %v", header.AppendAction)
+	}
+	for _, attr := range []string{"__Host-txn_token=", "Path=/", "Max-Age=300", "HttpOnly", "Secure", "SameSite=Strict"} {
+		if !strings.Contains(header.Header.Value, attr) {
+			t.Errorf("expected %q in Set-Cookie %q", attr, header.Header.Value)
+		}
+	}
+}
+
+func TestAuthzServer_TransactionIDHeader(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+	authzServer.TransactionIDHeader = "x-transaction-id"
+
+	req := &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method: "GET",
+					Path:   "/app",
+					Headers: map[string]string{
+						"authorization": "Bearer test-token-123",
+					},
+				},
+			},
+		},
+	}
+
+	resp, err := authzServer.Check(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	okResp := resp.GetOkResponse()
+	if okResp == nil {
+		t.Fatalf("expected OK response, got code %d: %s", resp.Status.Code, resp.Status.Message)
+	}
+
+	headerValue := func(headers []*corev3.HeaderValueOption, name string) string {
+		for _, h := range headers {
+			if h.Header.Key == name {
+				return h.Header.Value
+			}
+		}
+		return ""
+	}
+
+	// The stub token is stub-txn-token.{subject}.{txnID}.{requestContext}
+	token := headerValue(okResp.Headers, "Transaction-Token")
+	parts := strings.SplitN(token, ".", 4)
+	if len(parts) != 4 {
+		t.Fatalf("unexpected stub token %q", token)
+	}
+	txnID := parts[2]
+
+	if got := headerValue(okResp.Headers, "x-transaction-id"); got != txnID {
+		t.Errorf("expected upstream x-transaction-id %s, got %q", txnID, got)
+	}
+	if got := headerValue(okResp.ResponseHeadersToAdd, "x-transaction-id"); got != txnID {
+		t.Errorf("expected client x-transaction-id %s, got %q", txnID, got)
+	}
+}
+
+func TestAuthzServer_DynamicMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+
+	req := &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method: "GET",
+					Path:   "/app",
+					Headers: map[string]string{
+						"authorization": "Bearer test-token-123",
+					},
+				},
+			},
+		},
+	}
+
+	resp, err := authzServer.Check(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.GetOkResponse() == nil {
+		t.Fatalf("expected OK response, got code %d: %s", resp.Status.Code, resp.Status.Message)
+	}
+
+	metadata := resp.GetDynamicMetadata().AsMap()
+
+	subject, ok := metadata["subject"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected subject in metadata, got %v", metadata)
+	}
+	for key, want := range map[string]string{
+		"subject":      "test-subject",
+		"issuer":       "https://test-issuer.example.com",
+		"trust_domain": "test-domain",
+		"scope":        "read write",
+	} {
+		if subject[key] != want {
+			t.Errorf("expected subject %s %q, got %v", key, want, subject[key])
+		}
+	}
+	if email := subject["claims"].(map[string]any)["email"]; email != "test@example.com" {
+		t.Errorf("expected subject email claim, got %v", email)
+	}
+
+	tokens, ok := metadata["tokens"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected tokens in metadata, got %v", metadata)
+	}
+	txnToken, ok := tokens[string(service.TokenTypeTransactionToken)].(map[string]any)
+	if !ok {
+		t.Fatalf("expected transaction token in metadata, got %v", tokens)
+	}
+	tokenClaims := txnToken["claims"].(map[string]any)
+	if tokenClaims["sub"] != "test-subject" {
+		t.Errorf("expected token sub claim test-subject, got %v", tokenClaims["sub"])
+	}
+	if txnToken["txn"] == "" || txnToken["txn"] != tokenClaims["txn"] {
+		t.Errorf("expected token txn %v to match its txn claim %v", txnToken["txn"], tokenClaims["txn"])
+	}
+}
+
+func TestAuthzServer_RouteContextExtensions(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore, err := trust.NewFilteredStore()
+	if err != nil {
+		t.Fatalf("failed to create trust store: %v", err)
+	}
+	trustStore.AddValidator("corporate", trust.NewStubValidator(trust.CredentialTypeBearer).
+		WithError(errors.New("not a corporate token")))
+	trustStore.AddValidator("partners", trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+
+	tests := []struct {
+		name              string
+		contextExtensions map[string]string
+		wantCode          codes.Code
+		wantHeader        string
+	}{
+		{
+			name:       "no route configuration",
+			wantCode:   codes.OK,
+			wantHeader: "Transaction-Token",
+		},
+		{
+			name: "route header name",
+			contextExtensions: map[string]string{
+				ContextExtensionTokenTypes: string(service.TokenTypeTransactionToken) + "=X-Txn-Token",
+			},
+			wantCode:   codes.OK,
+			wantHeader: "X-Txn-Token",
+		},
+		{
+			name: "route token type without header uses server delivery",
+			contextExtensions: map[string]string{
+				ContextExtensionTokenTypes: string(service.TokenTypeTransactionToken),
+			},
+			wantCode:   codes.OK,
+			wantHeader: "Transaction-Token",
+		},
+		{
+			name: "route token type without delivery",
+			contextExtensions: map[string]string{
+				ContextExtensionTokenTypes: string(service.TokenTypeAccessToken),
+			},
+			wantCode: codes.Internal,
+		},
+		{
+			name: "required scopes granted",
+			contextExtensions: map[string]string{
+				ContextExtensionRequiredScopes: "write read",
+			},
+			wantCode:   codes.OK,
+			wantHeader: "Transaction-Token",
+		},
+		{
+			name: "required scope missing",
+			contextExtensions: map[string]string{
+				ContextExtensionRequiredScopes: "read admin",
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "allowed validator",
+			contextExtensions: map[string]string{
+				ContextExtensionValidators: "corporate, partners",
+			},
+			wantCode:   codes.OK,
+			wantHeader: "Transaction-Token",
+		},
+		{
+			name: "validator not allowed",
+			contextExtensions: map[string]string{
+				ContextExtensionValidators: "corporate",
+			},
+			wantCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &authv3.CheckRequest{
+				Attributes: &authv3.AttributeContext{
+					Request: &authv3.AttributeContext_Request{
+						Http: &authv3.AttributeContext_HttpRequest{
+							Method: "GET",
+							Path:   "/app",
+							Headers: map[string]string{
+								"authorization": "Bearer test-token-123",
+							},
+						},
+					},
+					ContextExtensions: tt.contextExtensions,
+				},
+			}
+
+			resp, err := authzServer.Check(ctx, req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if codes.Code(resp.Status.Code) != tt.wantCode {
+				t.Fatalf("expected code %s, got %s: %s", tt.wantCode, codes.Code(resp.Status.Code), resp.Status.Message)
+			}
+			if tt.wantHeader == "" {
+				return
+			}
+
+			headers := resp.GetOkResponse().GetHeaders()
+			if len(headers) != 1 || headers[0].Header.Key != tt.wantHeader {
+				t.Errorf("expected only header %s, got %v", tt.wantHeader, headers)
+			}
+		})
+	}
+}
+
+func TestAuthzServer_APIKeyHeader(t *testing.T) {
+	ctx := context.Background()
+
+	keyStore, err := trust.NewStaticAPIKeyStore([]trust.StaticAPIKeyEntry{
+		{Key: "tool-key", Owner: "report-tool", Tenant: "acme"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	apiKeyValidator, err := trust.NewAPIKeyValidator(trust.APIKeyValidatorConfig{
+		Header:      "X-API-Key",
+		Store:       keyStore,
+		TrustDomain: "internal-tools",
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+	trustStore.AddValidator(apiKeyValidator)
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+	authzServer.APIKeyHeaders = []string{"X-API-Key"}
+
+	check := func(t *testing.T, headers map[string]string) *authv3.CheckResponse {
+		t.Helper()
+		resp, err := authzServer.Check(ctx, &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method:  "GET",
+						Path:    "/reports",
+						Headers: headers,
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("API key authenticates and is removed", func(t *testing.T) {
+		resp := check(t, map[string]string{"x-api-key": "tool-key"})
+		okResp := resp.GetOkResponse()
+		if okResp == nil {
+			t.Fatalf("expected OK response, got code %d: %s", resp.Status.Code, resp.Status.Message)
+		}
+		if len(okResp.HeadersToRemove) != 1 || okResp.HeadersToRemove[0] != "x-api-key" {
+			t.Errorf("expected x-api-key to be removed, got %v", okResp.HeadersToRemove)
+		}
+	})
+
+	t.Run("unknown API key is denied", func(t *testing.T) {
+		resp := check(t, map[string]string{"x-api-key": "wrong-key"})
+		if resp.Status.Code == 0 {
+			t.Fatal("expected denial, got OK")
+		}
+	})
+
+	t.Run("authorization header takes precedence", func(t *testing.T) {
+		resp := check(t, map[string]string{"authorization": "Bearer user-token", "x-api-key": "wrong-key"})
+		okResp := resp.GetOkResponse()
+		if okResp == nil {
+			t.Fatalf("expected OK response, got code %d: %s", resp.Status.Code, resp.Status.Message)
+		}
+		if len(okResp.HeadersToRemove) != 1 || okResp.HeadersToRemove[0] != "authorization" {
+			t.Errorf("expected authorization to be removed, got %v", okResp.HeadersToRemove)
+		}
+	})
+}
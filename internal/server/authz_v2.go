@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	authv2 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v2"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// AuthzServerV2 serves Envoy's v2 ext_authz API (envoy.service.auth.v2.Authorization)
+// for proxies that still use transport_api_version V2, by translating checks to v3
+// The v3 messages are wire compatible with v2, so translation re-decodes each message;
+// fields v2 lacks (such as dynamic metadata and response headers) are dropped by the proxy.
+type AuthzServerV2 struct {
+	authv2.UnimplementedAuthorizationServer
+
+	v3 *AuthzServer
+}
+
+// NewAuthzServerV2 creates a v2 ext_authz server that delegates to a v3 server
+func NewAuthzServerV2(v3 *AuthzServer) *AuthzServerV2 {
+	return &AuthzServerV2{v3: v3}
+}
+
+// Check implements the v2 ext_authz check endpoint
+func (s *AuthzServerV2) Check(ctx context.Context, req *authv2.CheckRequest) (*authv2.CheckResponse, error) {
+	v3Req := &authv3.CheckRequest{}
+	if err := translateMessage(req, v3Req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to translate v2 check request: %v", err)
+	}
+
+	v3Resp, err := s.v3.Check(ctx, v3Req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &authv2.CheckResponse{}
+	if err := translateMessage(v3Resp, resp); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to translate v3 check response: %v", err)
+	}
+	return resp, nil
+}
+
+// translateMessage decodes the wire encoding of from into to
+func translateMessage(from, to proto.Message) error {
+	data, err := proto.Marshal(from)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", from.ProtoReflect().Descriptor().FullName(), err)
+	}
+	if err := proto.Unmarshal(data, to); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", to.ProtoReflect().Descriptor().FullName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authv2 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v2"
+	"google.golang.org/grpc/codes"
+
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestAuthzServerV2_Check(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServerV2(NewAuthzServer(trustStore, tokenService, nil, nil))
+
+	newRequest := func(headers map[string]string) *authv2.CheckRequest {
+		return &authv2.CheckRequest{
+			Attributes: &authv2.AttributeContext{
+				Request: &authv2.AttributeContext_Request{
+					Http: &authv2.AttributeContext_HttpRequest{
+						Method:  "POST",
+						Path:    "/orders.v1.Orders/Get",
+						Host:    "orders.example.com",
+						Headers: headers,
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("valid credential", func(t *testing.T) {
+		resp, err := authzServer.Check(ctx, newRequest(map[string]string{
+			"authorization": "Bearer valid-token",
+			"content-type":  "application/grpc",
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if codes.Code(resp.Status.Code) != codes.OK {
+			t.Fatalf("expected OK, got %s: %s", codes.Code(resp.Status.Code), resp.Status.Message)
+		}
+
+		okResp := resp.GetOkResponse()
+		if okResp == nil {
+			t.Fatal("expected OK response")
+		}
+		found := false
+		for _, header := range okResp.Headers {
+			if header.Header.Key == "Transaction-Token" && header.Header.Value != "" {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("expected Transaction-Token header in v2 response")
+		}
+	})
+
+	t.Run("missing credential", func(t *testing.T) {
+		resp, err := authzServer.Check(ctx, newRequest(nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if codes.Code(resp.Status.Code) != codes.Unauthenticated {
+			t.Fatalf("expected Unauthenticated, got %s", codes.Code(resp.Status.Code))
+		}
+		if resp.GetDeniedResponse() == nil {
+			t.Error("expected denied response")
+		}
+	})
+}
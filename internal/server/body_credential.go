@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strings"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// BodyCredentialRule extracts a bearer token from the bodies of requests with a content type
+// Envoy only sends bodies to ext_authz when its filter is configured with with_request_body.
+type BodyCredentialRule struct {
+	// ContentType is the media type of bodies the rule applies to (e.g., "application/json")
+	ContentType string
+
+	// JSONPath is a dot-separated path of object keys to a string in a JSON body
+	// (e.g., "extensions.authorization" for a GraphQL request)
+	JSONPath string
+
+	// XMLPath is a slash-separated path of element local names, from the document element,
+	// to an element whose text is the token
+	// (e.g., "Envelope/Header/Security/BinarySecurityToken" for a SOAP WS-Security header)
+	XMLPath string
+}
+
+// Validate checks the rule has a content type and exactly one path
+func (r BodyCredentialRule) Validate() error {
+	if r.ContentType == "" {
+		return fmt.Errorf("content type is required")
+	}
+	if (r.JSONPath == "") == (r.XMLPath == "") {
+		return fmt.Errorf("exactly one of JSON path or XML path is required")
+	}
+	return nil
+}
+
+// extractBodyCredential extracts a bearer token from the request body with the first
+// rule matching its content type. A token may have a "Bearer " prefix.
+// Returns nil credential and nil error if no rule matches or the body has no token.
+func extractBodyCredential(req *authv3.CheckRequest, rules []BodyCredentialRule) (trust.Credential, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	httpReq := req.GetAttributes().GetRequest().GetHttp()
+
+	body := httpReq.GetRawBody()
+	if len(body) == 0 {
+		body = []byte(httpReq.GetBody())
+	}
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(requestHeaders(httpReq).First("content-type"))
+	if err != nil {
+		return nil, nil
+	}
+
+	i := slices.IndexFunc(rules, func(r BodyCredentialRule) bool { return strings.EqualFold(r.ContentType, mediaType) })
+	if i < 0 {
+		return nil, nil
+	}
+	rule := rules[i]
+
+	var token string
+	if rule.JSONPath != "" {
+		token, err = jsonBodyToken(body, rule.JSONPath)
+	} else {
+		token, err = xmlBodyToken(body, rule.XMLPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token = strings.TrimSpace(token)
+	if t, ok := strings.CutPrefix(token, "Bearer "); ok {
+		token = strings.TrimSpace(t)
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return &trust.BearerCredential{Token: token}, nil
+}
+
+// jsonBodyToken returns the string at path in a JSON body, or "" if there is none
+func jsonBodyToken(body []byte, path string) (string, error) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", fmt.Errorf("invalid JSON body: %w", err)
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return "", nil
+		}
+		value = object[key]
+	}
+	if value == nil {
+		return "", nil
+	}
+	token, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("body %s is not a string", path)
+	}
+	return token, nil
+}
+
+// xmlBodyToken returns the text of the first element at path in an XML body, or "" if there is none
+func xmlBodyToken(body []byte, path string) (string, error) {
+	want := strings.Split(path, "/")
+	var stack []string
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid XML body: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if slices.Equal(stack, want) {
+				var element struct {
+					Text string `xml:",chardata"`
+				}
+				if err := decoder.DecodeElement(&element, &t); err != nil {
+					return "", fmt.Errorf("invalid XML body: %w", err)
+				}
+				return element.Text, nil
+			}
+			// Skip elements off the path
+			if len(stack) > len(want) || stack[len(stack)-1] != want[len(stack)-1] {
+				if err := decoder.Skip(); err != nil {
+					return "", fmt.Errorf("invalid XML body: %w", err)
+				}
+				stack = stack[:len(stack)-1]
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
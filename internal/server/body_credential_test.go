@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+const soapBody = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"
+    xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
+  <soap:Header>
+    <wsse:Security>
+      <wsse:Timestamp><wsse:BinarySecurityToken>decoy</wsse:BinarySecurityToken></wsse:Timestamp>
+      <wsse:BinarySecurityToken ValueType="urn:ietf:params:oauth:token-type:jwt">
+        soap-token
+      </wsse:BinarySecurityToken>
+    </wsse:Security>
+  </soap:Header>
+  <soap:Body><GetOrder/></soap:Body>
+</soap:Envelope>`
+
+func TestExtractBodyCredential(t *testing.T) {
+	rules := []BodyCredentialRule{
+		{ContentType: "application/json", JSONPath: "extensions.authorization"},
+		{ContentType: "application/soap+xml", XMLPath: "Envelope/Header/Security/BinarySecurityToken"},
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		rawBody     []byte
+		wantToken   string
+		wantErr     bool
+	}{
+		{
+			name:        "GraphQL extensions",
+			contentType: "application/json",
+			body:        `{"query": "{ orders { id } }", "extensions": {"authorization": "Bearer graphql-token"}}`,
+			wantToken:   "graphql-token",
+		},
+		{
+			name:        "raw body",
+			contentType: "application/json; charset=utf-8",
+			rawBody:     []byte(`{"extensions": {"authorization": "raw-token"}}`),
+			wantToken:   "raw-token",
+		},
+		{
+			name:        "JSON without token",
+			contentType: "application/json",
+			body:        `{"query": "{ orders { id } }"}`,
+		},
+		{
+			name:        "JSON token not a string",
+			contentType: "application/json",
+			body:        `{"extensions": {"authorization": 42}}`,
+			wantErr:     true,
+		},
+		{
+			name:        "invalid JSON",
+			contentType: "application/json",
+			body:        `{"extensions":`,
+			wantErr:     true,
+		},
+		{
+			name:        "SOAP WS-Security",
+			contentType: "application/soap+xml",
+			body:        soapBody,
+			wantToken:   "soap-token",
+		},
+		{
+			name:        "SOAP without security header",
+			contentType: "application/soap+xml",
+			body:        `<Envelope><Header/><Body/></Envelope>`,
+		},
+		{
+			name:        "no matching rule",
+			contentType: "text/plain",
+			body:        "Bearer text-token",
+		},
+		{
+			name:        "no body",
+			contentType: "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &authv3.CheckRequest{
+				Attributes: &authv3.AttributeContext{
+					Request: &authv3.AttributeContext_Request{
+						Http: &authv3.AttributeContext_HttpRequest{
+							Headers: map[string]string{"content-type": tt.contentType},
+							Body:    tt.body,
+							RawBody: tt.rawBody,
+						},
+					},
+				},
+			}
+
+			cred, err := extractBodyCredential(req, rules)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantToken == "" {
+				if cred != nil {
+					t.Errorf("expected no credential, got %v", cred)
+				}
+				return
+			}
+			bearer, ok := cred.(*trust.BearerCredential)
+			if !ok {
+				t.Fatalf("expected bearer credential, got %T", cred)
+			}
+			if bearer.Token != tt.wantToken {
+				t.Errorf("expected token %q, got %q", tt.wantToken, bearer.Token)
+			}
+		})
+	}
+}
+
+func TestAuthzServer_BodyCredential(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+	authzServer.BodyCredentialRules = []BodyCredentialRule{
+		{ContentType: "application/json", JSONPath: "extensions.authorization"},
+	}
+
+	req := &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method:  "POST",
+					Path:    "/graphql",
+					Headers: map[string]string{"content-type": "application/json"},
+					Body:    `{"query": "{ orders { id } }", "extensions": {"authorization": "Bearer graphql-token"}}`,
+				},
+			},
+		},
+	}
+
+	resp, err := authzServer.Check(ctx, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	okResp := resp.GetOkResponse()
+	if okResp == nil {
+		t.Fatalf("expected OK response, got code %d: %s", resp.Status.Code, resp.Status.Message)
+	}
+	if len(okResp.HeadersToRemove) != 0 {
+		t.Errorf("expected no headers removed, got %v", okResp.HeadersToRemove)
+	}
+}
+
+func TestBodyCredentialRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    BodyCredentialRule
+		wantErr bool
+	}{
+		{name: "JSON", rule: BodyCredentialRule{ContentType: "application/json", JSONPath: "token"}},
+		{name: "XML", rule: BodyCredentialRule{ContentType: "text/xml", XMLPath: "Envelope/Token"}},
+		{name: "no content type", rule: BodyCredentialRule{JSONPath: "token"}, wantErr: true},
+		{name: "no path", rule: BodyCredentialRule{ContentType: "application/json"}, wantErr: true},
+		{
+			name:    "both paths",
+			rule:    BodyCredentialRule{ContentType: "application/json", JSONPath: "token", XMLPath: "Token"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
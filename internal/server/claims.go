@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+)
+
+// ClaimsPath is the HTTP path prefix of the claims retrieval endpoint
+// Claims references are this path followed by an opaque handle.
+const ClaimsPath = "/v1/claims/"
+
+// ClaimsServer serves the context claims transaction tokens reference instead of
+// carrying (see issuer.ClaimsReferenceClaim)
+//
+// The response body is the exact document whose hash is in the token's reference,
+// so consumers can check it. Unknown, expired, and revoked references are not found,
+// so callers cannot tell why.
+type ClaimsServer struct {
+	store               tokenstore.Store
+	denylist            denylist.Denylist
+	clientAuthenticator *clientauth.Authenticator
+	clock               clock.Clock
+}
+
+// ClaimsServerConfig configures the claims retrieval endpoint
+type ClaimsServerConfig struct {
+	// Store holds the referenced claims
+	Store tokenstore.Store
+
+	// Denylist, if set, holds revoked tokens, whose claims are not served
+	Denylist denylist.Denylist
+
+	// ClientAuthenticator authenticates the services retrieving claims
+	ClientAuthenticator *clientauth.Authenticator
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// NewClaimsServer creates a new claims retrieval server
+func NewClaimsServer(cfg ClaimsServerConfig) *ClaimsServer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &ClaimsServer{
+		store:               cfg.Store,
+		denylist:            cfg.Denylist,
+		clientAuthenticator: cfg.ClientAuthenticator,
+		clock:               clk,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (s *ClaimsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.clientAuthenticator == nil {
+		writeNoStoreJSON(w, http.StatusUnauthorized, oauthErrorResponse{
+			Error:            oauthInvalidClient,
+			ErrorDescription: "client authentication is not configured",
+		})
+		return
+	}
+	if _, err := s.clientAuthenticator.Authenticate(r.Context(), httpClientCredentials(r)); err != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="parsec"`)
+		writeNoStoreJSON(w, http.StatusUnauthorized, oauthErrorResponse{
+			Error:            oauthInvalidClient,
+			ErrorDescription: "client authentication failed: " + err.Error(),
+		})
+		return
+	}
+
+	handle := strings.TrimPrefix(r.URL.Path, ClaimsPath)
+	if handle == "" || strings.Contains(handle, "/") {
+		writeNoStoreJSON(w, http.StatusNotFound, oauthErrorResponse{Error: "not_found"})
+		return
+	}
+
+	record, err := s.lookup(r.Context(), handle)
+	if err != nil {
+		writeNoStoreJSON(w, http.StatusInternalServerError, oauthErrorResponse{
+			Error:            "server_error",
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+	if record == nil {
+		writeNoStoreJSON(w, http.StatusNotFound, oauthErrorResponse{Error: "not_found"})
+		return
+	}
+
+	// Encoded as at issuance, without a trailing newline, to match the hash
+	body, err := json.Marshal(record.Claims)
+	if err != nil {
+		writeNoStoreJSON(w, http.StatusInternalServerError, oauthErrorResponse{
+			Error:            "server_error",
+			ErrorDescription: "failed to encode claims: " + err.Error(),
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// lookup returns the record of the claims referenced by handle, or nil if they are
+// unknown, expired, or revoked
+func (s *ClaimsServer) lookup(ctx context.Context, handle string) (*tokenstore.Record, error) {
+	record, err := s.store.Get(ctx, tokenstore.Key(handle))
+	if errors.Is(err, tokenstore.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up claims: %w", err)
+	}
+	if record.TokenType != issuer.ClaimsReferenceTokenType || !s.clock.Now().Before(record.ExpiresAt) {
+		return nil, nil
+	}
+	if tokenID, _ := record.Claims["jti"].(string); tokenID != "" && s.denylist != nil {
+		denied, err := s.denylist.IsDenied(ctx, tokenID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check denylist: %w", err)
+		}
+		if denied {
+			return nil, nil
+		}
+	}
+	return record, nil
+}
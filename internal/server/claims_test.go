@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestClaimsServer(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := tokenstore.NewMemoryStore(clk)
+	denied := denylist.NewMemoryDenylist(clk)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	txnIssuer := issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+		Clock:     clk,
+		TransactionContextMappers: []service.ClaimMapper{service.NewStubClaimMapper(claims.Claims{
+			"user":         "alice",
+			"entitlements": []any{"orders:read", "orders:write"},
+		})},
+		ClaimsReferences: &issuer.ClaimsReferences{
+			Store:  store,
+			URL:    "https://parsec.test" + ClaimsPath,
+			Claims: []string{"tctx.entitlements"},
+		},
+	})
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients: []*clientauth.Client{
+			{ID: "orders", Method: clientauth.MethodClientSecretBasic, Secret: "orders-secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	claimsServer := NewClaimsServer(ClaimsServerConfig{
+		Store:               store,
+		Denylist:            denied,
+		ClientAuthenticator: authenticator,
+		Clock:               clk,
+	})
+
+	// issue returns a token's claims reference
+	issue := func(t *testing.T) (jwt.Token, map[string]any) {
+		t.Helper()
+		token, err := txnIssuer.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "user@example.com"},
+			RequestAttributes:  &request.RequestAttributes{},
+			Audiences:          []string{"parsec.test"},
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+		parsed, err := jwt.ParseInsecure([]byte(token.Value))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		reference, _ := parsed.Get(issuer.ClaimsReferenceClaim)
+		ref, ok := reference.(map[string]any)
+		if !ok {
+			t.Fatalf("expected a claims reference, got %v", reference)
+		}
+		return parsed, ref
+	}
+
+	retrieve := func(uri, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, uri, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		claimsServer.ServeHTTP(rec, req)
+		return rec
+	}
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("orders:orders-secret"))
+
+	t.Run("serves referenced claims matching their hash", func(t *testing.T) {
+		token, ref := issue(t)
+		if !strings.HasPrefix(ref["uri"].(string), "https://parsec.test"+ClaimsPath) {
+			t.Errorf("unexpected reference URI: %v", ref["uri"])
+		}
+		rec := retrieve(ref["uri"].(string), basic)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		body, _ := io.ReadAll(rec.Body)
+		sum := sha256.Sum256(body)
+		if hash := base64.RawURLEncoding.EncodeToString(sum[:]); ref["sha256"] != hash {
+			t.Errorf("expected the body to match the reference hash %v, got %s", ref["sha256"], hash)
+		}
+
+		var document struct {
+			JTI  string         `json:"jti"`
+			TCtx map[string]any `json:"tctx"`
+		}
+		if err := json.Unmarshal(body, &document); err != nil {
+			t.Fatalf("failed to decode claims: %v", err)
+		}
+		if document.JTI != token.JwtID() || len(document.TCtx["entitlements"].([]any)) != 2 {
+			t.Errorf("unexpected claims: %s", body)
+		}
+		if tctx, _ := token.Get("tctx"); tctx.(map[string]any)["entitlements"] != nil {
+			t.Errorf("expected entitlements to be kept out of the token, got %v", tctx)
+		}
+	})
+
+	t.Run("requires client authentication", func(t *testing.T) {
+		_, ref := issue(t)
+		for _, authorization := range []string{"", "Basic " + base64.StdEncoding.EncodeToString([]byte("orders:wrong"))} {
+			if rec := retrieve(ref["uri"].(string), authorization); rec.Code != http.StatusUnauthorized {
+				t.Errorf("expected 401, got %d", rec.Code)
+			}
+		}
+	})
+
+	t.Run("unknown references are not found", func(t *testing.T) {
+		if rec := retrieve("https://parsec.test"+ClaimsPath+"unknown", basic); rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("claims of revoked tokens are not found", func(t *testing.T) {
+		token, ref := issue(t)
+		if err := denied.Deny(ctx, token.JwtID(), clk.Now().Add(5*time.Minute)); err != nil {
+			t.Fatalf("failed to deny token: %v", err)
+		}
+		if rec := retrieve(ref["uri"].(string), basic); rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("claims of expired tokens are not found", func(t *testing.T) {
+		_, ref := issue(t)
+		clk.Advance(10 * time.Minute)
+		if rec := retrieve(ref["uri"].(string), basic); rec.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/alechenninger/parsec/internal/clientauth"
+)
+
+// clientCredentialsRequest is a request carrying client authentication parameters,
+// such as token exchange and introspection requests
+type clientCredentialsRequest interface {
+	GetClientId() string
+	GetClientSecret() string
+	GetClientAssertionType() string
+	GetClientAssertion() string
+}
+
+// clientCredentials collects the client authentication credentials of a request:
+// its client parameters, the Authorization header, and the TLS client certificate
+func clientCredentials(ctx context.Context, req clientCredentialsRequest) *clientauth.Credentials {
+	creds := &clientauth.Credentials{
+		ClientID:            req.GetClientId(),
+		ClientSecret:        req.GetClientSecret(),
+		ClientAssertionType: req.GetClientAssertionType(),
+		ClientAssertion:     req.GetClientAssertion(),
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if authorization := md.Get("authorization"); len(authorization) > 0 {
+			creds.Authorization = authorization[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+			creds.Certificate = tlsInfo.State.PeerCertificates[0]
+		}
+	}
+	return creds
+}
+
+// httpClientCredentials collects the client authentication credentials of a plain
+// HTTP request: its Authorization header, client_id query parameter, and TLS client
+// certificate
+func httpClientCredentials(r *http.Request) *clientauth.Credentials {
+	creds := &clientauth.Credentials{
+		ClientID:      r.URL.Query().Get("client_id"),
+		Authorization: r.Header.Get("Authorization"),
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		creds.Certificate = r.TLS.PeerCertificates[0]
+	}
+	return creds
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// CookieSpec configures delivering an issued token to the browser as a cookie.
+// Cookies are always Secure and HttpOnly so scripts cannot read the token and
+// it is never sent over plain HTTP.
+type CookieSpec struct {
+	// Name is the cookie name. A "__Host-" prefix additionally binds the
+	// cookie to the exact host, which requires an empty Domain and Path "/".
+	Name string
+
+	// Domain is the cookie Domain attribute. If empty, the cookie is host-only.
+	Domain string
+
+	// Path is the cookie Path attribute (default: "/")
+	Path string
+
+	// SameSite controls cross-site sending, the main CSRF defense for cookie credentials
+	// (default: http.SameSiteStrictMode)
+	SameSite http.SameSite
+}
+
+// ParseSameSite parses a SameSite cookie attribute value: "strict", "lax", or "none".
+// An empty value is strict.
+func ParseSameSite(s string) (http.SameSite, error) {
+	switch strings.ToLower(s) {
+	case "", "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("invalid same_site %q (supported: strict, lax, none)", s)
+	}
+}
+
+// Validate checks the cookie attributes are usable
+func (c CookieSpec) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("cookie name is required")
+	}
+	if err := c.cookie(&service.Token{Value: "x"}).Valid(); err != nil {
+		return fmt.Errorf("invalid cookie: %w", err)
+	}
+	if strings.HasPrefix(c.Name, "__Host-") {
+		if c.Domain != "" {
+			return fmt.Errorf("cookie %s must not set a domain", c.Name)
+		}
+		if c.Path != "" && c.Path != "/" {
+			return fmt.Errorf("cookie %s must use path /", c.Name)
+		}
+	}
+	return nil
+}
+
+// setCookie renders the Set-Cookie header value that delivers token
+func (c CookieSpec) setCookie(token *service.Token) string {
+	return c.cookie(token).String()
+}
+
+func (c CookieSpec) cookie(token *service.Token) *http.Cookie {
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	sameSite := c.SameSite
+	if sameSite == 0 || sameSite == http.SameSiteDefaultMode {
+		sameSite = http.SameSiteStrictMode
+	}
+
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    token.Value,
+		Domain:   c.Domain,
+		Path:     path,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: sameSite,
+	}
+
+	// Expire the cookie with the token so browsers do not keep sending a dead credential
+	if !token.ExpiresAt.IsZero() {
+		cookie.Expires = token.ExpiresAt.UTC()
+		if !token.IssuedAt.IsZero() {
+			if maxAge := int(token.ExpiresAt.Sub(token.IssuedAt).Seconds()); maxAge > 0 {
+				cookie.MaxAge = maxAge
+			}
+		}
+	}
+
+	return cookie
+}
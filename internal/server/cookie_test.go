@@ -0,0 +1,80 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+func TestCookieSpec_SetCookie(t *testing.T) {
+	issuedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	token := &service.Token{
+		Value:     "eyJhbGciOi.payload.sig",
+		IssuedAt:  issuedAt,
+		ExpiresAt: issuedAt.Add(10 * time.Minute),
+	}
+
+	spec := CookieSpec{
+		Name:     "txn_token",
+		Domain:   "app.example.com",
+		Path:     "/api",
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	got := spec.setCookie(token)
+	want := "txn_token=eyJhbGciOi.payload.sig; Path=/api; Domain=app.example.com; Expires=Wed, 01 Jan 2025 12:10:00 GMT; Max-Age=600; HttpOnly; Secure; SameSite=Lax"
+	if got != want {
+		t.Errorf("unexpected Set-Cookie\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestCookieSpec_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    CookieSpec
+		wantErr string
+	}{
+		{"valid", CookieSpec{Name: "txn_token"}, ""},
+		{"valid host prefix", CookieSpec{Name: "__Host-txn_token", Path: "/"}, ""},
+		{"missing name", CookieSpec{}, "name is required"},
+		{"invalid name", CookieSpec{Name: "txn token"}, "invalid cookie"},
+		{"host prefix with domain", CookieSpec{Name: "__Host-txn_token", Domain: "example.com"}, "must not set a domain"},
+		{"host prefix with path", CookieSpec{Name: "__Host-txn_token", Path: "/api"}, "must use path /"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	for input, want := range map[string]http.SameSite{
+		"":       http.SameSiteStrictMode,
+		"strict": http.SameSiteStrictMode,
+		"Lax":    http.SameSiteLaxMode,
+		"none":   http.SameSiteNoneMode,
+	} {
+		got, err := ParseSameSite(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSameSite(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+
+	if _, err := ParseSameSite("sometimes"); err == nil {
+		t.Error("expected error for invalid same_site")
+	}
+}
@@ -0,0 +1,344 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// Debug endpoint paths
+const (
+	DebugConfigPath = "/debug/config"
+	DebugKeysPath   = "/debug/keys"
+	DebugCachesPath = "/debug/caches"
+	DebugPprofPath  = "/debug/pprof/"
+)
+
+// DebugServer serves troubleshooting endpoints on a listener of its own: pprof
+// profiles, the sanitized configuration, the state of signing keys, and cache statistics
+type DebugServer struct {
+	address            string
+	tokens             [][]byte
+	config             any
+	signerRegistry     *keys.SignerRegistry
+	validationCache    *ValidationCache
+	dataSourceRegistry *service.DataSourceRegistry
+
+	mux        *http.ServeMux
+	httpServer *http.Server
+}
+
+// DebugServerConfig configures the debug server
+type DebugServerConfig struct {
+	// Address is the address to listen on (e.g., "localhost:6060")
+	Address string
+
+	// Tokens, if set, are bearer tokens one of which every request must carry
+	Tokens []string
+
+	// Config is the configuration served at DebugConfigPath, already sanitized
+	Config any
+
+	// SignerRegistry provides the signers whose keys are described
+	SignerRegistry *keys.SignerRegistry
+
+	// ValidationCache is ext_authz's validation cache, if enabled
+	ValidationCache *ValidationCache
+
+	// DataSourceRegistry provides the data sources whose caches are described
+	DataSourceRegistry *service.DataSourceRegistry
+}
+
+// NewDebugServer creates a new debug server
+func NewDebugServer(cfg DebugServerConfig) *DebugServer {
+	tokens := make([][]byte, 0, len(cfg.Tokens))
+	for _, token := range cfg.Tokens {
+		if token != "" {
+			tokens = append(tokens, []byte(token))
+		}
+	}
+
+	s := &DebugServer{
+		address:            cfg.Address,
+		tokens:             tokens,
+		config:             cfg.Config,
+		signerRegistry:     cfg.SignerRegistry,
+		validationCache:    cfg.ValidationCache,
+		dataSourceRegistry: cfg.DataSourceRegistry,
+		mux:                http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET "+DebugConfigPath, s.serveConfig)
+	s.mux.HandleFunc("GET "+DebugKeysPath, s.serveKeys)
+	s.mux.HandleFunc("GET "+DebugCachesPath, s.serveCaches)
+	s.mux.HandleFunc(DebugPprofPath, pprof.Index)
+	s.mux.HandleFunc(DebugPprofPath+"cmdline", pprof.Cmdline)
+	s.mux.HandleFunc(DebugPprofPath+"profile", pprof.Profile)
+	s.mux.HandleFunc(DebugPprofPath+"symbol", pprof.Symbol)
+	s.mux.HandleFunc(DebugPprofPath+"trace", pprof.Trace)
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *DebugServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "a valid debug bearer token is required", http.StatusUnauthorized)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Start starts listening, returning once the listener is open
+func (s *DebugServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on debug address %s: %w", s.address, err)
+	}
+
+	// No write timeout: CPU profiles and traces stream for as long as requested
+	s.httpServer = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		slog.Info("Debug server listening", "address", listener.Addr().String())
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Error("Debug server failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Stop gracefully stops the debug server
+func (s *DebugServer) Stop(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+// authenticated reports whether r carries one of the debug tokens, if any are required
+func (s *DebugServer) authenticated(r *http.Request) bool {
+	if len(s.tokens) == 0 {
+		return true
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+	for _, expected := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), expected) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *DebugServer) serveConfig(w http.ResponseWriter, r *http.Request) {
+	writeDebugJSON(w, s.config)
+}
+
+// debugSigner describes a signer's keys
+type debugSigner struct {
+	ID               string             `json:"id"`
+	SigningKeyID     string             `json:"signing_kid,omitempty"`
+	SigningAlgorithm string             `json:"signing_alg,omitempty"`
+	PublishedKeys    []debugKey         `json:"published_keys"`
+	Slots            []debugSlot        `json:"slots,omitempty"`
+	Migration        *debugMigration    `json:"migration,omitempty"`
+	SigningLimit     *debugSigningLimit `json:"signing_limit,omitempty"`
+	Errors           []string           `json:"errors,omitempty"`
+}
+
+type debugKey struct {
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+}
+
+type debugSlot struct {
+	Position            string     `json:"position"`
+	KeyProviderID       string     `json:"key_provider"`
+	KeyID               string     `json:"kid,omitempty"`
+	Algorithm           string     `json:"alg,omitempty"`
+	Active              bool       `json:"active"`
+	PreparingAt         *time.Time `json:"preparing_at,omitempty"`
+	RotationCompletedAt *time.Time `json:"rotation_completed_at,omitempty"`
+	UsableAt            *time.Time `json:"usable_at,omitempty"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+	RevokedAt           *time.Time `json:"revoked_at,omitempty"`
+	Error               string     `json:"error,omitempty"`
+}
+
+type debugMigration struct {
+	Phase         string    `json:"phase"`
+	FromAlgorithm string    `json:"from_alg"`
+	ToAlgorithm   string    `json:"to_alg"`
+	CutoverAt     time.Time `json:"cutover_at"`
+	RetireAt      time.Time `json:"retire_at"`
+}
+
+// debugSigningLimit describes the load on a signer's signing limiter
+type debugSigningLimit struct {
+	MaxConcurrent int   `json:"max_concurrent"`
+	MaxQueued     int   `json:"max_queued"`
+	InFlight      int   `json:"in_flight"`
+	Queued        int   `json:"queued"`
+	Signed        int64 `json:"signed"`
+	Rejected      int64 `json:"rejected"`
+	TimedOut      int64 `json:"timed_out"`
+}
+
+func (s *DebugServer) serveKeys(w http.ResponseWriter, r *http.Request) {
+	signers := []debugSigner{}
+	if s.signerRegistry != nil {
+		for _, id := range s.signerRegistry.IDs() {
+			signer, err := s.signerRegistry.Get(id)
+			if err != nil {
+				continue
+			}
+			signers = append(signers, describeSigner(r.Context(), id, signer))
+		}
+	}
+	writeDebugJSON(w, map[string]any{"signers": signers})
+}
+
+// describeSigner describes a signer's keys, recording what could not be read
+func describeSigner(ctx context.Context, id string, signer keys.RotatingSigner) debugSigner {
+	desc := debugSigner{ID: id, PublishedKeys: []debugKey{}}
+
+	if _, keyID, alg, err := signer.GetCurrentSigner(ctx); err != nil {
+		desc.Errors = append(desc.Errors, fmt.Sprintf("signing key: %v", err))
+	} else {
+		desc.SigningKeyID, desc.SigningAlgorithm = string(keyID), string(alg)
+	}
+
+	if publicKeys, err := signer.PublicKeys(ctx); err != nil {
+		desc.Errors = append(desc.Errors, fmt.Sprintf("published keys: %v", err))
+	} else {
+		for _, key := range publicKeys {
+			desc.PublishedKeys = append(desc.PublishedKeys, debugKey{KeyID: key.KeyID, Algorithm: key.Algorithm})
+		}
+	}
+
+	if reporter, ok := signer.(keys.SlotReporter); ok {
+		states, err := reporter.SlotStates(ctx)
+		if err != nil {
+			desc.Errors = append(desc.Errors, fmt.Sprintf("slots: %v", err))
+		}
+		for _, state := range states {
+			desc.Slots = append(desc.Slots, debugSlot{
+				Position:            string(state.Position),
+				KeyProviderID:       state.KeyProviderID,
+				KeyID:               string(state.KeyID),
+				Algorithm:           string(state.Algorithm),
+				Active:              state.Active,
+				PreparingAt:         state.PreparingAt,
+				RotationCompletedAt: state.RotationCompletedAt,
+				UsableAt:            state.UsableAt,
+				ExpiresAt:           state.ExpiresAt,
+				RevokedAt:           state.RevokedAt,
+				Error:               state.Error,
+			})
+		}
+	}
+
+	if migrator, ok := signer.(keys.AlgorithmMigrator); ok {
+		status, err := migrator.MigrationStatus(ctx)
+		if err != nil {
+			desc.Errors = append(desc.Errors, fmt.Sprintf("migration: %v", err))
+		} else {
+			desc.Migration = &debugMigration{
+				Phase:         string(status.Phase),
+				FromAlgorithm: string(status.FromAlgorithm),
+				ToAlgorithm:   string(status.ToAlgorithm),
+				CutoverAt:     status.CutoverAt,
+				RetireAt:      status.RetireAt,
+			}
+		}
+	}
+
+	if reporter, ok := signer.(keys.SigningLimitReporter); ok {
+		if limiter := reporter.SigningLimiter(); limiter != nil {
+			stats := limiter.Stats()
+			desc.SigningLimit = &debugSigningLimit{
+				MaxConcurrent: stats.MaxConcurrent,
+				MaxQueued:     stats.MaxQueued,
+				InFlight:      stats.InFlight,
+				Queued:        stats.Queued,
+				Signed:        stats.Signed,
+				Rejected:      stats.Rejected,
+				TimedOut:      stats.TimedOut,
+			}
+		}
+	}
+
+	return desc
+}
+
+// debugCacheStats describes a cache
+type debugCacheStats struct {
+	Entries   int64 `json:"entries"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
+
+func newDebugCacheStats(stats service.CacheStats) *debugCacheStats {
+	return &debugCacheStats{
+		Entries:   stats.Entries,
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Evictions: stats.Evictions,
+	}
+}
+
+func (s *DebugServer) serveCaches(w http.ResponseWriter, r *http.Request) {
+	var resp struct {
+		ValidationCache *debugCacheStats            `json:"validation_cache,omitempty"`
+		DataSources     map[string]*debugCacheStats `json:"data_sources"`
+	}
+	if s.validationCache != nil {
+		resp.ValidationCache = newDebugCacheStats(s.validationCache.CacheStats())
+	}
+
+	resp.DataSources = make(map[string]*debugCacheStats)
+	if s.dataSourceRegistry != nil {
+		for _, name := range s.dataSourceRegistry.Names() {
+			// Look through decorators, such as tracing, for a cache
+			source := s.dataSourceRegistry.Get(name)
+			for {
+				wrapper, ok := source.(service.DataSourceWrapper)
+				if !ok {
+					break
+				}
+				source = wrapper.Unwrap()
+			}
+			if reporter, ok := source.(service.CacheStatsReporter); ok {
+				resp.DataSources[name] = newDebugCacheStats(reporter.CacheStats())
+			}
+		}
+	}
+
+	writeDebugJSON(w, resp)
+}
+
+// writeDebugJSON writes v as indented JSON, for reading in a terminal
+func writeDebugJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/datasource"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// cacheableDataSource is a cacheable data source with fixed data
+type cacheableDataSource struct{}
+
+func (cacheableDataSource) Name() string { return "roles" }
+
+func (cacheableDataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	return &service.DataSourceResult{Data: []byte(`["admin"]`), ContentType: service.ContentTypeJSON}, nil
+}
+
+func (cacheableDataSource) CacheKey(input *service.DataSourceInput) service.DataSourceInput {
+	return *input
+}
+
+func (cacheableDataSource) CacheTTL() time.Duration { return time.Minute }
+
+func TestDebugServer(t *testing.T) {
+	ctx := context.Background()
+
+	signer := keys.NewDualSlotRotatingSigner(keys.DualSlotRotatingSignerConfig{
+		Namespace:           string(service.TokenTypeTransactionToken),
+		TrustDomain:         "example.com",
+		KeyProviderID:       "memory",
+		KeyProviderRegistry: map[string]keys.KeyProvider{"memory": keys.NewInMemoryKeyProvider(keys.KeyTypeECP256, "ES256")},
+		SlotStore:           keys.NewInMemoryKeySlotStore(),
+	})
+	if err := signer.Start(ctx); err != nil {
+		t.Fatalf("failed to start signer: %v", err)
+	}
+	t.Cleanup(signer.Stop)
+	signerRegistry := keys.NewSignerRegistry()
+	if err := signerRegistry.Register("txn-signer", signer); err != nil {
+		t.Fatalf("failed to register signer: %v", err)
+	}
+
+	dataSources := service.NewDataSourceRegistry()
+	roles := datasource.NewInMemoryCachingDataSource(cacheableDataSource{})
+	dataSources.Register(roles)
+	input := &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}}
+	for i := 0; i < 3; i++ {
+		if _, err := roles.Fetch(ctx, input); err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+	}
+
+	validationCache := NewValidationCache(ValidationCacheConfig{TTL: time.Minute})
+	validationCache.get("missing")
+
+	debugServer := NewDebugServer(DebugServerConfig{
+		Tokens:             []string{"debug-token"},
+		Config:             map[string]any{"trust_domain": "example.com"},
+		SignerRegistry:     signerRegistry,
+		ValidationCache:    validationCache,
+		DataSourceRegistry: dataSources,
+	})
+	get := func(t *testing.T, path string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer debug-token")
+		rec := httptest.NewRecorder()
+		debugServer.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return body
+	}
+
+	t.Run("requires token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		debugServer.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DebugConfigPath, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", rec.Code)
+		}
+	})
+
+	t.Run("config", func(t *testing.T) {
+		if body := get(t, DebugConfigPath); body["trust_domain"] != "example.com" {
+			t.Errorf("unexpected config %v", body)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		body := get(t, DebugKeysPath)
+		signers := body["signers"].([]any)
+		if len(signers) != 1 {
+			t.Fatalf("expected 1 signer, got %v", body)
+		}
+		desc := signers[0].(map[string]any)
+		slots := desc["slots"].([]any)
+		if desc["id"] != "txn-signer" || desc["signing_alg"] != "ES256" || len(slots) != 1 {
+			t.Fatalf("unexpected signer %v", desc)
+		}
+		slot := slots[0].(map[string]any)
+		if slot["kid"] != desc["signing_kid"] || slot["active"] != true || slot["expires_at"] == nil {
+			t.Errorf("expected active slot with the signing key, got %v", slot)
+		}
+	})
+
+	t.Run("caches", func(t *testing.T) {
+		body := get(t, DebugCachesPath)
+		roles := body["data_sources"].(map[string]any)["roles"].(map[string]any)
+		if roles["entries"] != 1.0 || roles["hits"] != 2.0 || roles["misses"] != 1.0 {
+			t.Errorf("unexpected data source cache stats %v", roles)
+		}
+		validation := body["validation_cache"].(map[string]any)
+		if validation["misses"] != 1.0 {
+			t.Errorf("unexpected validation cache stats %v", validation)
+		}
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, DebugPprofPath+"goroutine?debug=1", nil)
+		req.Header.Set("Authorization", "Bearer debug-token")
+		rec := httptest.NewRecorder()
+		debugServer.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+			t.Errorf("expected goroutine profile, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// DenialSpec configures the HTTP responses ext_authz denies requests with
+type DenialSpec struct {
+	// UnauthenticatedStatus is the HTTP status of requests denied for missing or invalid
+	// credentials (default: 401). Requests denied for lack of permission are always 403.
+	UnauthenticatedStatus int
+
+	// WWWAuthenticate adds a Bearer WWW-Authenticate challenge (RFC 6750 section 3) to 401
+	// responses, and to 403 responses for tokens without the scopes a route requires
+	WWWAuthenticate bool
+
+	// Realm is the realm of WWW-Authenticate challenges, if any
+	Realm string
+
+	// JSONBody renders the denial as an OAuth-style JSON error
+	// ({"error": ..., "error_description": ...}) instead of a plain-text message
+	JSONBody bool
+}
+
+// Validate checks the statuses are usable
+func (d DenialSpec) Validate() error {
+	switch d.UnauthenticatedStatus {
+	case 0, http.StatusUnauthorized, http.StatusForbidden:
+		return nil
+	default:
+		return fmt.Errorf("invalid unauthenticated status %d (supported: 401, 403)", d.UnauthenticatedStatus)
+	}
+}
+
+// Bearer token error codes (RFC 6750 section 3.1)
+const (
+	bearerErrorInvalidToken      = "invalid_token"
+	bearerErrorInsufficientScope = "insufficient_scope"
+)
+
+// bearerChallenge is the parameters of a WWW-Authenticate challenge beyond the realm
+type bearerChallenge struct {
+	// Error is the RFC 6750 error code, or "" if the request had no credential
+	Error string
+
+	// Scope lists the scopes the request needed, for insufficient_scope challenges
+	Scope string
+}
+
+// denyResponse creates a denial response
+func (s *AuthzServer) denyResponse(code codes.Code, message string) *authv3.CheckResponse {
+	return s.denyChallenge(code, bearerChallenge{}, message)
+}
+
+// denyChallenge creates a denial response whose WWW-Authenticate challenge, if enabled,
+// has the parameters of challenge
+func (s *AuthzServer) denyChallenge(code codes.Code, challenge bearerChallenge, message string) *authv3.CheckResponse {
+	httpStatus := s.Denial.httpStatus(code)
+
+	var headers []*corev3.HeaderValueOption
+	if s.Denial.WWWAuthenticate &&
+		(httpStatus == http.StatusUnauthorized || challenge.Error == bearerErrorInsufficientScope) {
+		headers = append(headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{
+				Key:   "WWW-Authenticate",
+				Value: s.Denial.challenge(challenge),
+			},
+		})
+	}
+
+	body := message
+	if s.Denial.JSONBody {
+		errorCode := challenge.Error
+		if errorCode == "" {
+			errorCode = denialErrorCode(code)
+		}
+		// Marshalling a map of strings cannot fail
+		data, _ := json.Marshal(map[string]string{
+			"error":             errorCode,
+			"error_description": message,
+		})
+		body = string(data)
+		headers = append(headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{
+				Key:   "Content-Type",
+				Value: "application/json",
+			},
+		})
+	}
+
+	return &authv3.CheckResponse{
+		Status: &status.Status{
+			Code:    int32(code),
+			Message: message,
+		},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode(httpStatus)},
+				Headers: headers,
+				Body:    body,
+			},
+		},
+	}
+}
+
+// httpStatus returns the HTTP status of a denial with the given gRPC code
+func (d DenialSpec) httpStatus(code codes.Code) int {
+	switch code {
+	case codes.Unauthenticated:
+		if d.UnauthenticatedStatus != 0 {
+			return d.UnauthenticatedStatus
+		}
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// challenge renders a WWW-Authenticate header value
+func (d DenialSpec) challenge(challenge bearerChallenge) string {
+	var params []string
+	if d.Realm != "" {
+		params = append(params, "realm="+quoteAuthParam(d.Realm))
+	}
+	if challenge.Error != "" {
+		params = append(params, "error="+quoteAuthParam(challenge.Error))
+	}
+	if challenge.Scope != "" {
+		params = append(params, "scope="+quoteAuthParam(challenge.Scope))
+	}
+	if len(params) == 0 {
+		return "Bearer"
+	}
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// quoteAuthParam quotes an auth-param value (RFC 9110 section 5.6.4)
+func quoteAuthParam(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// denialErrorCode returns the JSON error code of a denial without a bearer error code
+func denialErrorCode(code codes.Code) string {
+	switch code {
+	case codes.Unauthenticated:
+		return "unauthorized"
+	case codes.PermissionDenied:
+		return "access_denied"
+	default:
+		return "server_error"
+	}
+}
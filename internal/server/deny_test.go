@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestAuthzServer_Denial(t *testing.T) {
+	ctx := context.Background()
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	tests := []struct {
+		name              string
+		denial            DenialSpec
+		validatorErr      error
+		authorization     string
+		contextExtensions map[string]string
+		wantStatus        int
+		wantChallenge     string
+		wantJSONError     string
+	}{
+		{
+			name:          "missing credential",
+			denial:        DenialSpec{WWWAuthenticate: true, Realm: "parsec"},
+			wantStatus:    401,
+			wantChallenge: `Bearer realm="parsec"`,
+		},
+		{
+			name:          "invalid token",
+			denial:        DenialSpec{WWWAuthenticate: true},
+			validatorErr:  trust.ErrInvalidToken,
+			authorization: "Bearer bad",
+			wantStatus:    401,
+			wantChallenge: `Bearer error="invalid_token"`,
+		},
+		{
+			name:          "invalid token without challenge",
+			validatorErr:  trust.ErrInvalidToken,
+			authorization: "Bearer bad",
+			wantStatus:    401,
+		},
+		{
+			name:          "unauthenticated as forbidden",
+			denial:        DenialSpec{UnauthenticatedStatus: 403, WWWAuthenticate: true},
+			validatorErr:  trust.ErrInvalidToken,
+			authorization: "Bearer bad",
+			wantStatus:    403,
+		},
+		{
+			name:          "insufficient scope",
+			denial:        DenialSpec{WWWAuthenticate: true, JSONBody: true},
+			authorization: "Bearer good",
+			contextExtensions: map[string]string{
+				ContextExtensionRequiredScopes: "admin",
+			},
+			wantStatus:    403,
+			wantChallenge: `Bearer error="insufficient_scope", scope="admin"`,
+			wantJSONError: "insufficient_scope",
+		},
+		{
+			name:          "JSON body",
+			denial:        DenialSpec{JSONBody: true},
+			wantStatus:    401,
+			wantJSONError: "unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := trust.NewStubValidator(trust.CredentialTypeBearer)
+			if tt.validatorErr != nil {
+				validator.WithError(tt.validatorErr)
+			}
+			trustStore := trust.NewStubStore()
+			trustStore.AddValidator(validator)
+
+			authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+			authzServer.Denial = tt.denial
+
+			headers := map[string]string{}
+			if tt.authorization != "" {
+				headers["authorization"] = tt.authorization
+			}
+			req := &authv3.CheckRequest{
+				Attributes: &authv3.AttributeContext{
+					Request: &authv3.AttributeContext_Request{
+						Http: &authv3.AttributeContext_HttpRequest{
+							Method:  "GET",
+							Path:    "/app",
+							Headers: headers,
+						},
+					},
+					ContextExtensions: tt.contextExtensions,
+				},
+			}
+
+			resp, err := authzServer.Check(ctx, req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			denied := resp.GetDeniedResponse()
+			if denied == nil {
+				t.Fatalf("expected denied response, got %v", resp)
+			}
+			if got := int(denied.GetStatus().GetCode()); got != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, got)
+			}
+
+			if got := deniedHeader(denied.Headers, "WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("expected WWW-Authenticate %q, got %q", tt.wantChallenge, got)
+			}
+
+			if tt.wantJSONError == "" {
+				if denied.Body != resp.Status.Message {
+					t.Errorf("expected plain-text body %q, got %q", resp.Status.Message, denied.Body)
+				}
+				return
+			}
+			if got := deniedHeader(denied.Headers, "Content-Type"); got != "application/json" {
+				t.Errorf("expected JSON content type, got %q", got)
+			}
+			var body map[string]string
+			if err := json.Unmarshal([]byte(denied.Body), &body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if body["error"] != tt.wantJSONError {
+				t.Errorf("expected error %s, got %s", tt.wantJSONError, body["error"])
+			}
+			if body["error_description"] != resp.Status.Message {
+				t.Errorf("expected error_description %q, got %q", resp.Status.Message, body["error_description"])
+			}
+		})
+	}
+}
+
+func TestDenialSpec_Validate(t *testing.T) {
+	for _, status := range []int{0, 401, 403} {
+		if err := (DenialSpec{UnauthenticatedStatus: status}).Validate(); err != nil {
+			t.Errorf("status %d: unexpected error: %v", status, err)
+		}
+	}
+	if err := (DenialSpec{UnauthenticatedStatus: 500}).Validate(); err == nil {
+		t.Error("expected error for status 500, got nil")
+	}
+}
+
+func deniedHeader(headers []*corev3.HeaderValueOption, name string) string {
+	for _, h := range headers {
+		if h.Header.Key == name {
+			return h.Header.Value
+		}
+	}
+	return ""
+}
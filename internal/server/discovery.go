@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// tokenExchangeGrantType is the only grant type the token exchange endpoint accepts
+const tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// DiscoveryServer implements the Discovery gRPC service
+// It describes the token types this instance can issue so clients can configure
+// themselves dynamically instead of hardcoding token type URNs
+type DiscoveryServer struct {
+	parsecv1.UnimplementedDiscoveryServer
+
+	trustDomain     string
+	issuerRegistry  service.Registry
+	authzTokenTypes []TokenTypeSpec
+	jwksPublication JWKSPublication
+}
+
+// DiscoveryServerConfig configures the discovery server
+type DiscoveryServerConfig struct {
+	// TrustDomain is the audience of issued tokens
+	TrustDomain string
+
+	// IssuerRegistry provides the issuable token types and their issuers
+	IssuerRegistry service.Registry
+
+	// AuthzTokenTypes are the token types ext_authz issues and the headers it puts them in
+	// Typically AuthzServer.TokenTypesToIssue
+	AuthzTokenTypes []TokenTypeSpec
+
+	// JWKSPublication is how the JWKS server publishes keys, to advertise the right jwks_uri
+	// Typically JWKSServerConfig.Publication (default: JWKSPublicationAggregated)
+	JWKSPublication JWKSPublication
+}
+
+// NewDiscoveryServer creates a new discovery server
+func NewDiscoveryServer(cfg DiscoveryServerConfig) *DiscoveryServer {
+	return &DiscoveryServer{
+		trustDomain:     cfg.TrustDomain,
+		issuerRegistry:  cfg.IssuerRegistry,
+		authzTokenTypes: cfg.AuthzTokenTypes,
+		jwksPublication: cfg.JWKSPublication,
+	}
+}
+
+// ListTokenTypes implements the Discovery service
+func (s *DiscoveryServer) ListTokenTypes(ctx context.Context, req *parsecv1.ListTokenTypesRequest) (*parsecv1.ListTokenTypesResponse, error) {
+	tokenTypes := s.issuerRegistry.ListTokenTypes()
+	sort.Slice(tokenTypes, func(i, j int) bool { return tokenTypes[i] < tokenTypes[j] })
+
+	authzHeaders := make(map[service.TokenType]string, len(s.authzTokenTypes))
+	for _, spec := range s.authzTokenTypes {
+		authzHeaders[spec.Type] = spec.HeaderName
+	}
+
+	resp := &parsecv1.ListTokenTypesResponse{
+		TrustDomain: s.trustDomain,
+		TokenTypes:  make([]*parsecv1.TokenTypeCapability, 0, len(tokenTypes)),
+	}
+
+	for _, tokenType := range tokenTypes {
+		issuer, err := s.issuerRegistry.GetIssuer(tokenType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get issuer for %s: %w", tokenType, err)
+		}
+
+		capability, err := s.describe(ctx, tokenType, issuer)
+		if err != nil {
+			return nil, err
+		}
+		capability.AuthzHeader = authzHeaders[tokenType]
+
+		resp.TokenTypes = append(resp.TokenTypes, capability)
+	}
+
+	return resp, nil
+}
+
+// GetTransactionTokenMetadata implements the Discovery service
+// The JWKS and token endpoints are assumed to be served at the transaction token issuer URL,
+// which is the case when the issuer URL is parsec's external HTTP address.
+func (s *DiscoveryServer) GetTransactionTokenMetadata(ctx context.Context, req *parsecv1.GetTransactionTokenMetadataRequest) (*parsecv1.TransactionTokenMetadata, error) {
+	txnIssuer, err := s.issuerRegistry.GetIssuer(service.TokenTypeTransactionToken)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "transaction tokens are not issued by this instance")
+	}
+
+	describable, ok := txnIssuer.(service.DescribableIssuer)
+	if !ok || describable.Describe().IssuerURL == "" {
+		return nil, status.Error(codes.NotFound, "transaction token issuer has no issuer URL")
+	}
+
+	issuerURL := describable.Describe().IssuerURL
+	baseURL := strings.TrimSuffix(issuerURL, "/")
+	jwksPath := "/.well-known/jwks.json"
+	if s.jwksPublication.PerIssuer() {
+		jwksPath = IssuerJWKSPath(service.TokenTypeTransactionToken)
+	}
+	return &parsecv1.TransactionTokenMetadata{
+		Issuer:           issuerURL,
+		JwksUri:          baseURL + jwksPath,
+		TxnTokenEndpoint: baseURL + "/v1/token",
+	}, nil
+}
+
+// describe builds the capability of a single token type
+func (s *DiscoveryServer) describe(ctx context.Context, tokenType service.TokenType, issuer service.Issuer) (*parsecv1.TokenTypeCapability, error) {
+	capability := &parsecv1.TokenTypeCapability{
+		TokenType:          string(tokenType),
+		Default:            tokenType == service.TokenTypeTransactionToken,
+		ExchangeParameters: s.exchangeParameters(tokenType),
+	}
+
+	if describable, ok := issuer.(service.DescribableIssuer); ok {
+		desc := describable.Describe()
+		capability.Issuer = desc.IssuerURL
+		capability.Format = string(desc.Format)
+		capability.Ttl = &parsecv1.TTLRange{
+			MinSeconds: int64(desc.MinTTL.Seconds()),
+			MaxSeconds: int64(desc.MaxTTL.Seconds()),
+		}
+	}
+
+	publicKeys, err := issuer.PublicKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public keys for %s: %w", tokenType, err)
+	}
+	capability.SigningAlgValuesSupported = signingAlgorithms(publicKeys)
+
+	return capability, nil
+}
+
+// exchangeParameters lists the RFC 8693 request parameters for requesting tokenType
+// These mirror the validation ExchangeServer.Exchange performs
+func (s *DiscoveryServer) exchangeParameters(tokenType service.TokenType) []*parsecv1.RequestParameter {
+	return []*parsecv1.RequestParameter{
+		{
+			Name:        "grant_type",
+			Required:    true,
+			Value:       tokenExchangeGrantType,
+			Description: "Token exchange grant type",
+		},
+		{
+			Name:        "subject_token",
+			Required:    true,
+			Description: "Credential of the subject the token is issued for",
+		},
+		{
+			Name:        "subject_token_type",
+			Required:    true,
+			Description: "Token type URN of subject_token",
+		},
+		{
+			Name: "requested_token_type",
+			// Only the default token type may be requested implicitly
+			Required:    tokenType != service.TokenTypeTransactionToken,
+			Value:       string(tokenType),
+			Description: "Token type to issue",
+		},
+		{
+			Name:        "audience",
+			Value:       s.trustDomain,
+			Description: "Audience of the issued token; must be the trust domain or an allowed audience. May be repeated",
+		},
+		{
+			Name:        "resource",
+			Description: "URI of a resource the issued token is for; must be an allowed audience. May be repeated",
+		},
+		{
+			Name:        "scope",
+			Description: "Space-delimited scopes for the issued token",
+		},
+		{
+			Name:        "actor_token",
+			Description: "Credential of the party acting on behalf of the subject, recorded in the act claim",
+		},
+		{
+			Name:        "actor_token_type",
+			Description: "Token type URN of actor_token; required with actor_token",
+		},
+		{
+			Name:        "request_context",
+			Description: "Base64-encoded JSON request context, filtered by what the actor may assert",
+		},
+	}
+}
+
+// signingAlgorithms returns the distinct algorithms of keys, in first-seen order
+func signingAlgorithms(keys []service.PublicKey) []string {
+	var algs []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if key.Algorithm == "" || seen[key.Algorithm] {
+			continue
+		}
+		seen[key.Algorithm] = true
+		algs = append(algs, key.Algorithm)
+	}
+	return algs
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+func TestDiscoveryServer_ListTokenTypes(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	}))
+	registry.Register(service.TokenTypeRHIdentity, issuer.NewRHIdentityIssuer(issuer.RHIdentityIssuerConfig{
+		TokenType: string(service.TokenTypeRHIdentity),
+	}))
+
+	discoveryServer := NewDiscoveryServer(DiscoveryServerConfig{
+		TrustDomain:    "prod.example.com",
+		IssuerRegistry: registry,
+		AuthzTokenTypes: []TokenTypeSpec{
+			{Type: service.TokenTypeTransactionToken, HeaderName: "Transaction-Token"},
+		},
+	})
+
+	resp, err := discoveryServer.ListTokenTypes(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTokenTypes failed: %v", err)
+	}
+
+	if resp.TrustDomain != "prod.example.com" {
+		t.Errorf("expected trust domain prod.example.com, got %s", resp.TrustDomain)
+	}
+	if len(resp.TokenTypes) != 2 {
+		t.Fatalf("expected 2 token types, got %d", len(resp.TokenTypes))
+	}
+
+	t.Run("token types are sorted", func(t *testing.T) {
+		if resp.TokenTypes[0].TokenType != string(service.TokenTypeTransactionToken) {
+			t.Errorf("expected txn_token first, got %s", resp.TokenTypes[0].TokenType)
+		}
+		if resp.TokenTypes[1].TokenType != string(service.TokenTypeRHIdentity) {
+			t.Errorf("expected rh-identity second, got %s", resp.TokenTypes[1].TokenType)
+		}
+	})
+
+	t.Run("signed token type", func(t *testing.T) {
+		txn := resp.TokenTypes[0]
+
+		if txn.Issuer != "https://parsec.example.com" {
+			t.Errorf("expected issuer https://parsec.example.com, got %s", txn.Issuer)
+		}
+		if txn.Format != "jwt" {
+			t.Errorf("expected format jwt, got %s", txn.Format)
+		}
+		if len(txn.SigningAlgValuesSupported) != 1 || txn.SigningAlgValuesSupported[0] != "ES256" {
+			t.Errorf("expected algorithms [ES256], got %v", txn.SigningAlgValuesSupported)
+		}
+		if txn.Ttl.GetMinSeconds() != 300 || txn.Ttl.GetMaxSeconds() != 300 {
+			t.Errorf("expected ttl 300-300s, got %d-%d", txn.Ttl.GetMinSeconds(), txn.Ttl.GetMaxSeconds())
+		}
+		if !txn.Default {
+			t.Error("expected txn_token to be the default token type")
+		}
+		if txn.AuthzHeader != "Transaction-Token" {
+			t.Errorf("expected authz header Transaction-Token, got %q", txn.AuthzHeader)
+		}
+	})
+
+	t.Run("unsigned token type", func(t *testing.T) {
+		rh := resp.TokenTypes[1]
+
+		if rh.Issuer != "" {
+			t.Errorf("expected no issuer, got %s", rh.Issuer)
+		}
+		if rh.Format != "rh_identity" {
+			t.Errorf("expected format rh_identity, got %s", rh.Format)
+		}
+		if len(rh.SigningAlgValuesSupported) != 0 {
+			t.Errorf("expected no algorithms, got %v", rh.SigningAlgValuesSupported)
+		}
+		if rh.Ttl.GetMaxSeconds() != 0 {
+			t.Errorf("expected non-expiring tokens, got max ttl %d", rh.Ttl.GetMaxSeconds())
+		}
+		if rh.Default {
+			t.Error("expected rh-identity not to be the default token type")
+		}
+		if rh.AuthzHeader != "" {
+			t.Errorf("expected no authz header, got %q", rh.AuthzHeader)
+		}
+	})
+
+	t.Run("exchange parameters", func(t *testing.T) {
+		params := make(map[string]bool)
+		for _, p := range resp.TokenTypes[1].ExchangeParameters {
+			params[p.Name] = p.Required
+			switch p.Name {
+			case "grant_type":
+				if p.Value != tokenExchangeGrantType {
+					t.Errorf("expected grant_type value %s, got %s", tokenExchangeGrantType, p.Value)
+				}
+			case "requested_token_type":
+				if p.Value != string(service.TokenTypeRHIdentity) {
+					t.Errorf("expected requested_token_type value %s, got %s", service.TokenTypeRHIdentity, p.Value)
+				}
+			case "audience":
+				if p.Value != "prod.example.com" {
+					t.Errorf("expected audience value prod.example.com, got %s", p.Value)
+				}
+			}
+		}
+
+		for name, wantRequired := range map[string]bool{
+			"grant_type":           true,
+			"subject_token":        true,
+			"subject_token_type":   true,
+			"requested_token_type": true,
+			"audience":             false,
+			"resource":             false,
+			"scope":                false,
+		} {
+			required, ok := params[name]
+			if !ok {
+				t.Errorf("expected parameter %s", name)
+				continue
+			}
+			if required != wantRequired {
+				t.Errorf("expected %s required=%v, got %v", name, wantRequired, required)
+			}
+		}
+
+		// requested_token_type may be omitted for the default token type
+		for _, p := range resp.TokenTypes[0].ExchangeParameters {
+			if p.Name == "requested_token_type" && p.Required {
+				t.Error("expected requested_token_type to be optional for the default token type")
+			}
+		}
+	})
+}
+
+func TestDiscoveryServer_GetTransactionTokenMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	get := func(t *testing.T, registry service.Registry, publication JWKSPublication) *httptest.ResponseRecorder {
+		t.Helper()
+		mux := runtime.NewServeMux()
+		discoveryServer := NewDiscoveryServer(DiscoveryServerConfig{
+			TrustDomain:     "prod.example.com",
+			IssuerRegistry:  registry,
+			JWKSPublication: publication,
+		})
+		if err := parsecv1.RegisterDiscoveryHandlerServer(ctx, mux, discoveryServer); err != nil {
+			t.Fatalf("failed to register discovery handler: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/transaction-token", nil))
+		return rec
+	}
+
+	txnRegistry := service.NewSimpleRegistry()
+	txnRegistry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.example.com/",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	}))
+
+	t.Run("served from the transaction token issuer URL", func(t *testing.T) {
+		rec := get(t, txnRegistry, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var metadata map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		want := map[string]string{
+			"issuer":             "https://parsec.example.com/",
+			"jwks_uri":           "https://parsec.example.com/.well-known/jwks.json",
+			"txn_token_endpoint": "https://parsec.example.com/v1/token",
+		}
+		for field, value := range want {
+			if metadata[field] != value {
+				t.Errorf("expected %s %q, got %q", field, value, metadata[field])
+			}
+		}
+	})
+
+	t.Run("not found without a transaction token issuer", func(t *testing.T) {
+		registry := service.NewSimpleRegistry()
+		registry.Register(service.TokenTypeRHIdentity, issuer.NewRHIdentityIssuer(issuer.RHIdentityIssuerConfig{
+			TokenType: string(service.TokenTypeRHIdentity),
+		}))
+
+		rec := get(t, registry, "")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("points at the issuer's own key set when published per issuer", func(t *testing.T) {
+		rec := get(t, txnRegistry, JWKSPublicationPerIssuer)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var metadata map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &metadata); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		want := "https://parsec.example.com/v1/issuers/urn:ietf:params:oauth:token-type:txn_token/jwks.json"
+		if metadata["jwks_uri"] != want {
+			t.Errorf("expected jwks_uri %q, got %q", want, metadata["jwks_uri"])
+		}
+	})
+}
@@ -4,15 +4,30 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/audit"
 	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/logging"
+	"github.com/alechenninger/parsec/internal/reexchange"
 	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/scope"
 	"github.com/alechenninger/parsec/internal/service"
 	"github.com/alechenninger/parsec/internal/trust"
 )
 
+// tokenTypeSAML2 is the RFC 8693 token type of a base64url-encoded SAML 2.0 assertion
+const tokenTypeSAML2 = "urn:ietf:params:oauth:token-type:saml2"
+
 // ExchangeServer implements the TokenExchange gRPC service
 type ExchangeServer struct {
 	parsecv1.UnimplementedTokenExchangeServer
@@ -21,6 +36,49 @@ type ExchangeServer struct {
 	tokenService         *service.TokenService
 	claimsFilterRegistry ClaimsFilterRegistry
 	observer             service.TokenExchangeObserver
+
+	// AllowedAudiences are the audiences and resources clients may request besides
+	// the trust domain
+	AllowedAudiences []string
+
+	// ScopePolicy decides which requested scopes are granted; all are if nil
+	ScopePolicy scope.Policy
+
+	// RejectDeniedScopes fails exchanges requesting a scope ScopePolicy denies with
+	// invalid_scope, rather than issuing the token with only the granted scopes
+	RejectDeniedScopes bool
+
+	// ClientAuthenticator, if set, requires clients to authenticate (RFC 6749 section 2.3)
+	ClientAuthenticator *clientauth.Authenticator
+
+	// ReexchangeTokens, if set, issues a re-exchange token (refresh_token) with each
+	// exchanged token, which the same caller can redeem for fresh tokens without the
+	// original subject_token
+	ReexchangeTokens *reexchange.Issuer
+
+	// CertificateBoundTokens binds issued tokens to the caller's TLS client certificate,
+	// when it was validated as the actor credential, with a cnf claim (RFC 8705 section 3)
+	CertificateBoundTokens bool
+
+	// Audit, if set, records every exchange's decision
+	Audit *audit.Logger
+
+	// AuthzRules, if set, may deny exchanges before any token is issued
+	AuthzRules service.AuthorizationPolicy
+
+	// RateLimit, if set, limits the rate of all exchanges together
+	RateLimit *RateLimiter
+
+	// ClientRateLimit, if set, limits the rate of exchanges by each client: the
+	// authenticated client, or else the actor. Anonymous callers share one limit.
+	ClientRateLimit *RateLimiter
+
+	// MaxTokenBytes, if positive, is the largest subject_token or actor_token accepted
+	MaxTokenBytes int
+
+	// MaxRequestBytes, if positive, is the largest HTTP request body the token
+	// endpoint reads; larger requests fail with HTTP 413
+	MaxRequestBytes int64
 }
 
 // NewExchangeServer creates a new token exchange server
@@ -38,20 +96,151 @@ func NewExchangeServer(trustStore trust.Store, tokenService *service.TokenServic
 }
 
 // Exchange implements the token exchange endpoint (RFC 8693)
-func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.TokenExchangeRequest) (*parsecv1.TokenExchangeResponse, error) {
+// Invalid requests fail with OAuth error codes (see oauthError)
+func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.TokenExchangeRequest) (resp *parsecv1.TokenExchangeResponse, err error) {
 	// Create request-scoped probe
 	ctx, probe := s.observer.TokenExchangeStarted(ctx, req.GrantType, req.RequestedTokenType, req.Audience, req.Scope)
 	defer probe.End()
 
-	// 1. Validate the grant type
-	if req.GrantType != "urn:ietf:params:oauth:grant-type:token-exchange" {
-		return nil, fmt.Errorf("unsupported grant_type: %s", req.GrantType)
+	// Report the decision, however the exchange ends
+	defer func() {
+		if err != nil {
+			probe.ExchangeFailed(exchangeError(err))
+		} else {
+			probe.ExchangeSucceeded(resp.IssuedTokenType)
+		}
+	}()
+
+	// Audit the decision, however the exchange ends
+	decision := &audit.Record{
+		Event:     audit.EventTokenExchange,
+		Audiences: req.Audience,
+		Scope:     req.Scope,
+	}
+	if s.Audit != nil {
+		defer func() { s.auditExchange(ctx, decision, err) }()
+	}
+
+	// Shed load before any validation work
+	if s.RateLimit != nil {
+		if ok, retryAfter := s.RateLimit.Allow(""); !ok {
+			return nil, slowDownError(retryAfter, "too many token exchange requests")
+		}
+	}
+
+	// 1-10. Validate and authorize the exchange
+	ex, err := s.authorizeExchange(ctx, req, probe, decision, true)
+	if err != nil {
+		return nil, err
+	}
+	issueRequest := ex.issueRequest
+	requestedTokenType := issueRequest.TokenTypes[0]
+
+	// 11. Issue the token via TokenService
+	tokens, err := s.tokenService.IssueTokens(ctx, issueRequest)
+	if err != nil {
+		if errors.Is(err, keys.ErrSigningSaturated) {
+			return nil, status.Errorf(codes.Unavailable, "failed to issue token: %v", err)
+		}
+		if errors.Is(err, service.ErrIssuanceDenied) {
+			return nil, oauthError(oauthInvalidGrant, "token exchange denied: %v", err)
+		}
+		return nil, fmt.Errorf("failed to issue token: %w", err)
+	}
+
+	token, ok := tokens[requestedTokenType]
+	if !ok {
+		return nil, fmt.Errorf("token service did not return requested token type %s", requestedTokenType)
+	}
+	recordIssuedTokens(ctx, decision, tokens)
+
+	// 12. Issue a re-exchange token, if enabled
+	// Redeeming one does not extend it: a new token is only issued for a new subject_token
+	var refreshToken string
+	if s.ReexchangeTokens != nil && ex.grant == nil {
+		refreshToken, _, err = s.ReexchangeTokens.Issue(ctx, &reexchange.Grant{
+			Subject:   issueRequest.Subject,
+			Actor:     issueRequest.Actor,
+			Caller:    ex.caller.Subject,
+			ClientID:  clientID(ex.client),
+			Audiences: issueRequest.Audiences,
+			Scope:     issueRequest.Scope,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue re-exchange token: %w", err)
+		}
 	}
 
-	// 2. Extract actor credential from gRPC context
+	// 13. Return response
+	return &parsecv1.TokenExchangeResponse{
+		AccessToken:     token.Value,
+		IssuedTokenType: string(requestedTokenType),
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(token.ExpiresAt.Sub(token.IssuedAt).Seconds()),
+		Scope:           issueRequest.Scope,
+		RefreshToken:    refreshToken,
+	}, nil
+}
+
+// authorizedExchange is a token exchange that passed every check before issuance
+type authorizedExchange struct {
+	// issueRequest requests the token, of the one requested token type
+	issueRequest *service.IssueRequest
+
+	// grant is the redeemed re-exchange token's grant, if one was redeemed
+	grant *reexchange.Grant
+
+	// caller is the validated caller, or the anonymous result
+	caller *trust.Result
+
+	// client is the authenticated client, if clients authenticate
+	client *clientauth.Client
+}
+
+// authorizeExchange validates and authorizes a token exchange request, up to issuance,
+// recording the decision's details as they are known
+// If rateLimited, the caller is charged against ClientRateLimit once identified.
+func (s *ExchangeServer) authorizeExchange(ctx context.Context, req *parsecv1.TokenExchangeRequest, probe service.TokenExchangeProbe, decision *audit.Record, rateLimited bool) (*authorizedExchange, error) {
+	var err error
+
+	// 1. Validate the grant type, the requested token type, and token sizes
+	if req.GrantType != tokenExchangeGrantType {
+		return nil, oauthError(oauthUnsupportedGrantType, "unsupported grant_type %s", req.GrantType)
+	}
+
+	// RFC 8693: If requested_token_type is not specified, default to access_token
+	// For parsec, we default to transaction tokens
+	requestedTokenType := service.TokenTypeTransactionToken
+	if req.RequestedTokenType != "" {
+		requestedTokenType = service.TokenType(req.RequestedTokenType)
+	}
+	decision.TokenTypes = []string{string(requestedTokenType)}
+	if !s.tokenService.SupportsTokenType(requestedTokenType) {
+		return nil, oauthError(oauthInvalidRequest, "unsupported requested_token_type %s", requestedTokenType)
+	}
+	if s.MaxTokenBytes > 0 {
+		if len(req.SubjectToken) > s.MaxTokenBytes {
+			return nil, oauthError(oauthInvalidRequest, "subject_token exceeds %d bytes", s.MaxTokenBytes)
+		}
+		if len(req.ActorToken) > s.MaxTokenBytes {
+			return nil, oauthError(oauthInvalidRequest, "actor_token exceeds %d bytes", s.MaxTokenBytes)
+		}
+	}
+
+	// 2. Authenticate the client, if required
+	var client *clientauth.Client
+	if s.ClientAuthenticator != nil {
+		client, err = s.ClientAuthenticator.Authenticate(ctx, clientCredentials(ctx, req))
+		if err != nil {
+			return nil, oauthError(oauthInvalidClient, "client authentication failed: %v", err)
+		}
+		decision.ClientID = client.ID
+	}
+
+	// 3. Extract actor credential from gRPC context
 	actorCred, err := extractActorCredential(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract actor credential: %w", err)
+		return nil, oauthError(oauthInvalidClient, "failed to extract actor credential: %v", err)
 	}
 
 	var actor *trust.Result
@@ -60,29 +249,36 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.TokenExchan
 		actor, validationErr = s.trustStore.Validate(ctx, actorCred)
 		if validationErr != nil {
 			probe.ActorValidationFailed(validationErr)
-			return nil, fmt.Errorf("actor validation failed: %w", validationErr)
+			return nil, oauthError(oauthInvalidClient, "actor validation failed: %v", validationErr)
 		}
 		probe.ActorValidationSucceeded(actor)
 	} else {
 		actor = trust.AnonymousResult()
 		probe.ActorValidationSucceeded(actor)
 	}
+	decision.Actor = audit.IdentityOf(actor)
 
-	// 3. Parse and filter client-provided request_context claims
+	if rateLimited && s.ClientRateLimit != nil {
+		if ok, retryAfter := s.ClientRateLimit.Allow(rateLimitKey(client, actor)); !ok {
+			return nil, slowDownError(retryAfter, "too many token exchange requests from this client")
+		}
+	}
+
+	// 4. Parse and filter client-provided request_context claims
 	var reqAttrs *request.RequestAttributes
 	if req.RequestContext != "" {
 		// Decode base64-encoded request_context (per transaction token spec)
 		decodedJSON, err := base64.StdEncoding.DecodeString(req.RequestContext)
 		if err != nil {
 			probe.RequestContextParseFailed(err)
-			return nil, fmt.Errorf("failed to decode request_context base64: %w", err)
+			return nil, oauthError(oauthInvalidRequest, "failed to decode request_context base64: %v", err)
 		}
 
 		// Parse request_context JSON
 		var requestContextClaims claims.Claims
 		if err := json.Unmarshal(decodedJSON, &requestContextClaims); err != nil {
 			probe.RequestContextParseFailed(err)
-			return nil, fmt.Errorf("failed to parse request_context JSON: %w", err)
+			return nil, oauthError(oauthInvalidRequest, "failed to parse request_context JSON: %v", err)
 		}
 
 		// Get the claims filter for this actor
@@ -106,26 +302,137 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.TokenExchan
 
 	// Add metadata from the token exchange request itself to Additional
 	// These are not client-provided claims but server-side request metadata
-	if req.Audience != "" {
-		reqAttrs.Additional["requested_audience"] = req.Audience
+	// Repeated audience and resource parameters are space-delimited, like scope
+	if len(req.Audience) > 0 {
+		reqAttrs.Additional["requested_audience"] = strings.Join(req.Audience, " ")
+	}
+	if len(req.Resource) > 0 {
+		reqAttrs.Additional["requested_resource"] = strings.Join(req.Resource, " ")
 	}
 	if req.Scope != "" {
 		reqAttrs.Additional["requested_scope"] = req.Scope
 	}
+	// Only an authenticated client sets client_id; callers cannot claim one in request_context
+	delete(reqAttrs.Additional, "client_id")
+	var clientID string
+	if client != nil {
+		clientID = client.ID
+		reqAttrs.Additional["client_id"] = clientID
+	}
 
-	// 4. Filter trust store based on actor permissions
+	// 5. Validate requested audiences and resources, and filter trust store based on
+	// actor permissions and the trust domain they belong to
+	audiences, err := s.targetAudiences(req)
+	if err != nil {
+		return nil, err
+	}
+	domain, err := s.tokenService.ResolveTrustDomain("", audiences)
+	if err != nil {
+		return nil, oauthError(oauthInvalidTarget, "%v", err)
+	}
 	filteredStore, err := s.trustStore.ForActor(ctx, actor, reqAttrs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to filter trust store: %w", err)
 	}
+	if domain != nil && domain.Validators != nil {
+		restricting, ok := filteredStore.(trust.ValidatorRestrictingStore)
+		if !ok {
+			return nil, fmt.Errorf("trust store cannot restrict validators per trust domain")
+		}
+		filteredStore = restricting.WithValidators(domain.Validators...)
+	}
 
-	// 5. Validate subject_token
+	// 6. Validate subject_token and actor_token, or redeem a re-exchange token
+	var result, actingParty *trust.Result
+	var grant *reexchange.Grant
+	if s.ReexchangeTokens != nil && req.SubjectTokenType == reexchange.TokenType {
+		grant, err = s.redeemReexchangeToken(ctx, req, actor, client)
+		if err != nil {
+			probe.SubjectTokenValidationFailed(err)
+			return nil, err
+		}
+		result, actingParty = grant.Subject, grant.Actor
+		probe.SubjectTokenValidationSucceeded(result)
+		decision.Policy = map[string]string{"grant": "reexchange"}
+	} else {
+		result, actingParty, err = s.validateTokens(ctx, filteredStore, actor, req, probe)
+		if err != nil {
+			return nil, err
+		}
+	}
+	decision.Subject = audit.IdentityOf(result)
+	decision.Actor = audit.IdentityOf(actingParty)
+	logging.AddAttrs(ctx, logging.Subject(result.Subject))
+
+	// 7. A re-exchange is limited to the audiences of the original exchange
+	if grant != nil {
+		audiences, err = s.reexchangeAudiences(grant, audiences)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 8. Decide the granted scopes
+	// A re-exchange is limited to the scopes granted by the original exchange
+	var grantedScope string
+	if grant != nil {
+		grantedScope, err = reexchangeScope(grant, req.Scope)
+	} else {
+		grantedScope, err = s.grantScope(ctx, result, actingParty, reqAttrs, req.Scope)
+	}
+	if err != nil {
+		return nil, err
+	}
+	decision.Audiences = audiences
+	decision.Scope = grantedScope
+	if grantedScope != req.Scope {
+		if decision.Policy == nil {
+			decision.Policy = make(map[string]string)
+		}
+		decision.Policy["requested_scope"] = req.Scope
+	}
+
+	// 9. Record the acting party as acting on behalf of the subject (RFC 8693 section 4.1),
+	// keeping any delegation chain the subject token already carries
+	delegation, err := trust.Delegate(actingParty, result.Delegation)
+	if err != nil {
+		return nil, oauthError(oauthInvalidGrant, "token validation failed: %v", err)
+	}
+
+	// 10. Build the issue request, and check the authz rules
+	issueRequest := &service.IssueRequest{
+		Subject:               result,
+		Actor:                 actingParty,
+		RequestAttributes:     reqAttrs,
+		Delegation:            delegation,
+		Audiences:             audiences,
+		CertificateThumbprint: s.certificateThumbprint(actorCred),
+		TokenTypes:            []service.TokenType{requestedTokenType},
+		Scope:                 grantedScope,
+		ClientID:              clientID,
+	}
+	if s.AuthzRules != nil {
+		if err := s.AuthzRules.Authorize(ctx, issueRequest); err != nil {
+			if errors.Is(err, service.ErrIssuanceDenied) {
+				return nil, oauthError(oauthInvalidGrant, "token exchange denied: %v", err)
+			}
+			return nil, fmt.Errorf("failed to evaluate authz rules: %w", err)
+		}
+	}
+
+	return &authorizedExchange{issueRequest: issueRequest, grant: grant, caller: actor, client: client}, nil
+}
+
+// validateTokens validates the subject_token and the actor_token, if any, against the
+// trust store filtered for the caller
+// Returns the subject and the party acting on its behalf: the actor token's, or actor's.
+func (s *ExchangeServer) validateTokens(ctx context.Context, filteredStore trust.Store, actor *trust.Result, req *parsecv1.TokenExchangeRequest, probe service.TokenExchangeProbe) (*trust.Result, *trust.Result, error) {
 	// Create strongly-typed credential based on token type
-	// In production, we'd parse the token_type to determine the specific credential type
-	// For now, we'll treat all as bearer tokens
-	// TODO: Parse subject_token_type to determine specific credential type (JWT, OIDC, etc.)
-	cred := &trust.BearerCredential{
-		Token: req.SubjectToken,
+	// TODO: Parse other subject_token_types to determine specific credential type (JWT, OIDC, etc.)
+	cred, err := tokenCredential("subject_token", req.SubjectToken, req.SubjectTokenType)
+	if err != nil {
+		probe.SubjectTokenValidationFailed(err)
+		return nil, nil, oauthError(oauthInvalidRequest, "%v", err)
 	}
 
 	// Validate subject credential against filtered trust store
@@ -133,48 +440,108 @@ func (s *ExchangeServer) Exchange(ctx context.Context, req *parsecv1.TokenExchan
 	result, err := filteredStore.Validate(ctx, cred)
 	if err != nil {
 		probe.SubjectTokenValidationFailed(err)
-		return nil, fmt.Errorf("token validation failed: %w", err)
+		return nil, nil, oauthError(oauthInvalidGrant, "token validation failed: %v", err)
 	}
 	probe.SubjectTokenValidationSucceeded(result)
 
-	// 6. Determine which token type to issue
-	// RFC 8693: If requested_token_type is not specified, default to access_token
-	// For parsec, we default to transaction tokens
-	requestedTokenType := service.TokenTypeTransactionToken
-	if req.RequestedTokenType != "" {
-		requestedTokenType = service.TokenType(req.RequestedTokenType)
+	// The actor token identifies the party acting on behalf of the subject (RFC 8693
+	// section 2.1). Like the subject token, it is validated against the filtered store.
+	if req.ActorToken == "" && req.ActorTokenType == "" {
+		return result, actor, nil
+	}
+	if req.ActorToken == "" || req.ActorTokenType == "" {
+		return nil, nil, oauthError(oauthInvalidRequest, "actor_token and actor_token_type must be provided together")
 	}
+	actorTokenCred, err := tokenCredential("actor_token", req.ActorToken, req.ActorTokenType)
+	if err != nil {
+		probe.ActorValidationFailed(err)
+		return nil, nil, oauthError(oauthInvalidRequest, "%v", err)
+	}
+	actingParty, err := filteredStore.Validate(ctx, actorTokenCred)
+	if err != nil {
+		probe.ActorValidationFailed(err)
+		return nil, nil, oauthError(oauthInvalidGrant, "actor_token validation failed: %v", err)
+	}
+	probe.ActorValidationSucceeded(actingParty)
+	return result, actingParty, nil
+}
 
-	// 7. Validate audience matches trust domain (per transaction token spec)
-	// The audience for transaction tokens is always the trust domain
-	if req.Audience != "" && req.Audience != s.tokenService.TrustDomain() {
-		return nil, fmt.Errorf("requested audience %q does not match trust domain %q",
-			req.Audience, s.tokenService.TrustDomain())
+// targetAudiences returns the audiences of the issued token: the requested audiences and
+// resources, or nil for the default (the trust domain) if none were requested
+// Each must be the trust domain, an allowed audience, or an audience of another trust
+// domain of the token service (RFC 8693 invalid_target)
+func (s *ExchangeServer) targetAudiences(req *parsecv1.TokenExchangeRequest) ([]string, error) {
+	trustDomain := s.tokenService.TrustDomain()
+	var audiences []string
+	for _, target := range slices.Concat(req.Audience, req.Resource) {
+		if target != trustDomain && !slices.Contains(s.AllowedAudiences, target) && !s.tokenService.AllowsAudience(target) {
+			return nil, oauthError(oauthInvalidTarget,
+				"requested audience %q is neither the trust domain %q nor an allowed audience", target, trustDomain)
+		}
+		if !slices.Contains(audiences, target) {
+			audiences = append(audiences, target)
+		}
 	}
+	return audiences, nil
+}
 
-	// 8. Issue the token via TokenService
-	tokens, err := s.tokenService.IssueTokens(ctx, &service.IssueRequest{
-		Subject:           result,
+// certificateThumbprint returns the thumbprint of the client certificate to bind the
+// issued token to, or empty if tokens are not certificate-bound or the actor did not
+// authenticate with one
+func (s *ExchangeServer) certificateThumbprint(actorCred trust.Credential) string {
+	if !s.CertificateBoundTokens {
+		return ""
+	}
+	mtlsCred, ok := actorCred.(*trust.MTLSCredential)
+	if !ok {
+		return ""
+	}
+	return trust.CertificateThumbprint(mtlsCred.Certificate)
+}
+
+// grantScope returns the scope to issue the token with: the requested scopes the scope
+// policy grants, or the requested scope unchanged if there is no policy
+func (s *ExchangeServer) grantScope(ctx context.Context, subject, actor *trust.Result, reqAttrs *request.RequestAttributes, requested string) (string, error) {
+	if s.ScopePolicy == nil {
+		return requested, nil
+	}
+	scopes := scope.Parse(requested)
+	if len(scopes) == 0 {
+		return "", nil
+	}
+
+	granted, err := s.ScopePolicy.Grant(ctx, &scope.Request{
+		Subject:           subject,
 		Actor:             actor,
 		RequestAttributes: reqAttrs,
-		TokenTypes:        []service.TokenType{requestedTokenType},
-		Scope:             req.Scope,
+		Scopes:            scopes,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to issue token: %w", err)
+		return "", fmt.Errorf("failed to evaluate scope policy: %w", err)
 	}
-
-	token, ok := tokens[requestedTokenType]
-	if !ok {
-		return nil, fmt.Errorf("token service did not return requested token type %s", requestedTokenType)
+	if s.RejectDeniedScopes {
+		for _, requestedScope := range scopes {
+			if !slices.Contains(granted, requestedScope) {
+				return "", oauthError(oauthInvalidScope, "scope %q is not allowed", requestedScope)
+			}
+		}
 	}
+	return scope.Format(granted), nil
+}
 
-	// 9. Return response
-	return &parsecv1.TokenExchangeResponse{
-		AccessToken:     token.Value,
-		IssuedTokenType: string(requestedTokenType),
-		TokenType:       "Bearer",
-		ExpiresIn:       int64(token.ExpiresAt.Sub(token.IssuedAt).Seconds()),
-		Scope:           req.Scope,
-	}, nil
+// tokenCredential creates the credential for a subject_token or actor_token (param)
+// of the given token type
+// Tokens of types without a more specific credential are bearer tokens
+func tokenCredential(param, token, tokenType string) (trust.Credential, error) {
+	switch tokenType {
+	case tokenTypeSAML2:
+		// RFC 8693 section 3: the assertion is base64url-encoded; tolerate padding
+		assertion, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(token, "="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid saml2 %s: %w", param, err)
+		}
+		return &trust.SAMLCredential{Assertion: assertion}, nil
+	default:
+		return &trust.BearerCredential{Token: token}, nil
+	}
 }
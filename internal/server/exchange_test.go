@@ -2,19 +2,34 @@ package server
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 
 	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clientauth"
 	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
 	"github.com/alechenninger/parsec/internal/mapper"
+	"github.com/alechenninger/parsec/internal/reexchange"
+	"github.com/alechenninger/parsec/internal/scope"
 	"github.com/alechenninger/parsec/internal/service"
 	"github.com/alechenninger/parsec/internal/trust"
 )
@@ -74,7 +89,7 @@ func TestExchangeServer_WithActorFiltering(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken: "external-token",
-			Audience:     "parsec.test",
+			Audience:     []string{"parsec.test"},
 		}
 
 		_, err := exchangeServer.Exchange(ctx, req)
@@ -87,6 +102,9 @@ func TestExchangeServer_WithActorFiltering(t *testing.T) {
 		if !strings.Contains(err.Error(), "token validation failed") {
 			t.Errorf("expected 'token validation failed' in error, got: %v", err)
 		}
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "invalid_grant") {
+			t.Errorf("expected invalid_grant, got: %v", err)
+		}
 	})
 
 	t.Run("actor credentials via gRPC metadata - Bearer token", func(t *testing.T) {
@@ -122,7 +140,7 @@ func TestExchangeServer_WithActorFiltering(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken: "external-token",
-			Audience:     "parsec.test",
+			Audience:     []string{"parsec.test"},
 		}
 
 		resp, err := exchangeServerWithClient.Exchange(actorCtx, req)
@@ -165,7 +183,7 @@ func TestExchangeServer_WithActorFiltering(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken: "subject-token",
-			Audience:     "parsec.test",
+			Audience:     []string{"parsec.test"},
 		}
 
 		_, err := exchangeServerFailing.Exchange(actorCtx, req)
@@ -178,6 +196,9 @@ func TestExchangeServer_WithActorFiltering(t *testing.T) {
 		if !strings.Contains(err.Error(), "actor validation failed") {
 			t.Errorf("expected 'actor validation failed' in error, got: %v", err)
 		}
+		if status.Code(err) != codes.Unauthenticated || !strings.Contains(err.Error(), "invalid_client") {
+			t.Errorf("expected invalid_client, got: %v", err)
+		}
 	})
 
 	t.Run("actor allows access to different validators based on claims", func(t *testing.T) {
@@ -232,7 +253,7 @@ func TestExchangeServer_WithActorFiltering(t *testing.T) {
 		adminReq := &parsecv1.TokenExchangeRequest{
 			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken: "admin-subject-token",
-			Audience:     "parsec.test",
+			Audience:     []string{"parsec.test"},
 		}
 
 		adminResp, err := exchangeServerRoleBased.Exchange(adminCtx, adminReq)
@@ -307,7 +328,7 @@ func TestExchangeServer_WithActorFilteringByAudience(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken: "prod-token",
-			Audience:     "prod.example.com",
+			Audience:     []string{"prod.example.com"},
 		}
 
 		resp, err := exchangeServer.Exchange(ctx, req)
@@ -338,7 +359,7 @@ func TestExchangeServer_WithActorFilteringByAudience(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken: "dev-token",
-			Audience:     "dev.example.com",
+			Audience:     []string{"dev.example.com"},
 		}
 
 		resp, err := devExchangeServer.Exchange(ctx, req)
@@ -370,7 +391,7 @@ func TestExchangeServer_WithActorFilteringByAudience(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken: "prod-token",
-			Audience:     "wrong.example.com",
+			Audience:     []string{"wrong.example.com"},
 		}
 
 		_, err := wrongExchangeServer.Exchange(ctx, req)
@@ -444,7 +465,7 @@ func TestExchangeServer_ActorPassedToTokenIssuance(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken: "user-token",
-			Audience:     "parsec.test",
+			Audience:     []string{"parsec.test"},
 		}
 
 		resp, err := exchangeServer.Exchange(actorCtx, req)
@@ -525,7 +546,7 @@ func TestExchangeServer_RequestContextFiltering(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:      "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken:   "test-token",
-			Audience:       "parsec.test",
+			Audience:       []string{"parsec.test"},
 			RequestContext: requestContextBase64,
 		}
 
@@ -595,7 +616,7 @@ func TestExchangeServer_RequestContextFiltering(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:      "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken:   "test-token",
-			Audience:       "parsec.test",
+			Audience:       []string{"parsec.test"},
 			RequestContext: requestContextBase64,
 		}
 
@@ -642,7 +663,7 @@ func TestExchangeServer_RequestContextFiltering(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:      "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken:   "test-token",
-			Audience:       "parsec.test",
+			Audience:       []string{"parsec.test"},
 			RequestContext: "", // No request context
 		}
 
@@ -663,7 +684,7 @@ func TestExchangeServer_RequestContextFiltering(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:      "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken:   "test-token",
-			Audience:       "parsec.test",
+			Audience:       []string{"parsec.test"},
 			RequestContext: "not-valid-base64!@#$",
 		}
 
@@ -688,7 +709,7 @@ func TestExchangeServer_RequestContextFiltering(t *testing.T) {
 		req := &parsecv1.TokenExchangeRequest{
 			GrantType:      "urn:ietf:params:oauth:grant-type:token-exchange",
 			SubjectToken:   "test-token",
-			Audience:       "parsec.test",
+			Audience:       []string{"parsec.test"},
 			RequestContext: requestContextBase64,
 		}
 
@@ -738,3 +759,762 @@ func parseTestTokenRequestContext(token string) (map[string]any, error) {
 
 	return reqCtx, nil
 }
+
+func TestExchangeServer_SAML2SubjectToken(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject:     "bearer-user",
+		TrustDomain: "bearer",
+	}))
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeSAML).WithResult(&trust.Result{
+		Subject:     "saml-user",
+		TrustDomain: "corp",
+	}))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL:                 "https://parsec.test",
+		TTL:                       5 * time.Minute,
+		TransactionContextMappers: []service.ClaimMapper{service.NewPassthroughSubjectMapper()},
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+
+	t.Run("saml2 subject token is validated as a SAML assertion", func(t *testing.T) {
+		assertion := base64.RawURLEncoding.EncodeToString([]byte(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion"/>`))
+		resp, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     assertion,
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:saml2",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(resp.AccessToken, "saml-user") {
+			t.Errorf("expected token for saml-user, got %s", resp.AccessToken)
+		}
+	})
+
+	t.Run("rejects saml2 subject token that is not base64url", func(t *testing.T) {
+		_, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     "<saml:Assertion/>",
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:saml2",
+		})
+		if err == nil {
+			t.Error("expected error for undecodable saml2 subject token")
+		}
+	})
+}
+
+func TestExchangeServer_DelegationChain(t *testing.T) {
+	ctx := context.Background()
+
+	// The actor authenticates with a bearer token, in metadata or as actor_token; the subject token is a SAML assertion
+	// that was itself issued to a frontend acting on behalf of the user
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject: "gateway",
+		Issuer:  "https://workload-idp.example.com",
+	}))
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeSAML).WithResult(&trust.Result{
+		Subject:    "user@example.com",
+		Delegation: &trust.Delegation{Subject: "frontend"},
+	}))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+
+	newRequest := func() *parsecv1.TokenExchangeRequest {
+		return &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     base64.RawURLEncoding.EncodeToString([]byte(`<saml:Assertion/>`)),
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:saml2",
+		}
+	}
+	exchange := func(t *testing.T, ctx context.Context, req *parsecv1.TokenExchangeRequest) map[string]any {
+		t.Helper()
+		resp, err := exchangeServer.Exchange(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		token, err := jwt.ParseInsecure([]byte(resp.AccessToken))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		act, ok := token.Get(trust.ActClaim)
+		if !ok {
+			t.Fatal("expected act claim")
+		}
+		return act.(map[string]any)
+	}
+
+	t.Run("actor is added to the subject's chain", func(t *testing.T) {
+		actorCtx := metadata.NewIncomingContext(ctx, metadata.New(map[string]string{
+			"authorization": "Bearer gateway-token",
+		}))
+		act := exchange(t, actorCtx, newRequest())
+		if act["sub"] != "gateway" || act["iss"] != "https://workload-idp.example.com" {
+			t.Errorf("expected gateway as current actor, got %v", act)
+		}
+		prior, _ := act["act"].(map[string]any)
+		if prior["sub"] != "frontend" {
+			t.Errorf("expected frontend as prior actor, got %v", act["act"])
+		}
+	})
+
+	t.Run("chain is propagated without an actor", func(t *testing.T) {
+		act := exchange(t, ctx, newRequest())
+		if act["sub"] != "frontend" || act["act"] != nil {
+			t.Errorf("expected the subject's chain unchanged, got %v", act)
+		}
+	})
+
+	t.Run("actor_token is recorded as the acting party", func(t *testing.T) {
+		req := newRequest()
+		req.ActorToken = "gateway-token"
+		req.ActorTokenType = "urn:ietf:params:oauth:token-type:jwt"
+		act := exchange(t, ctx, req)
+		if act["sub"] != "gateway" {
+			t.Errorf("expected gateway as current actor, got %v", act)
+		}
+		prior, _ := act["act"].(map[string]any)
+		if prior["sub"] != "frontend" {
+			t.Errorf("expected frontend as prior actor, got %v", act["act"])
+		}
+	})
+
+	t.Run("actor_token requires actor_token_type", func(t *testing.T) {
+		req := newRequest()
+		req.ActorToken = "gateway-token"
+		if _, err := exchangeServer.Exchange(ctx, req); err == nil {
+			t.Error("expected error for actor_token without actor_token_type")
+		}
+	})
+}
+
+func TestExchangeServer_RequestedTokenType(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject: "user-456",
+	}))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	for _, tokenType := range []service.TokenType{service.TokenTypeTransactionToken, service.TokenTypeAccessToken} {
+		issuerRegistry.Register(tokenType, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+			IssuerURL: "https://parsec.test",
+			TTL:       5 * time.Minute,
+		}))
+	}
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+
+	exchange := func(requestedTokenType string) (*parsecv1.TokenExchangeResponse, error) {
+		return exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+			GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:       "user-token",
+			SubjectTokenType:   "urn:ietf:params:oauth:token-type:jwt",
+			RequestedTokenType: requestedTokenType,
+		})
+	}
+
+	t.Run("defaults to transaction token", func(t *testing.T) {
+		resp, err := exchange("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.IssuedTokenType != string(service.TokenTypeTransactionToken) {
+			t.Errorf("expected transaction token, got %s", resp.IssuedTokenType)
+		}
+	})
+
+	t.Run("issues requested token type", func(t *testing.T) {
+		resp, err := exchange(string(service.TokenTypeAccessToken))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.IssuedTokenType != string(service.TokenTypeAccessToken) {
+			t.Errorf("expected access token, got %s", resp.IssuedTokenType)
+		}
+	})
+
+	t.Run("rejects unsupported token type as invalid_request", func(t *testing.T) {
+		_, err := exchange(string(service.TokenTypeRHIdentity))
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "invalid_request") {
+			t.Errorf("expected invalid_request, got %v", err)
+		}
+	})
+}
+
+func TestExchangeServer_Audiences(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject: "user-456",
+	}))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+	exchangeServer.AllowedAudiences = []string{"orders.example.com", "https://api.example.com/billing"}
+
+	exchange := func(audience, resource []string) ([]string, error) {
+		resp, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     "user-token",
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
+			Audience:         audience,
+			Resource:         resource,
+		})
+		if err != nil {
+			return nil, err
+		}
+		token, err := jwt.ParseInsecure([]byte(resp.AccessToken))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		return token.Audience(), nil
+	}
+
+	t.Run("defaults to the trust domain", func(t *testing.T) {
+		aud, err := exchange(nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(aud, []string{"parsec.test"}) {
+			t.Errorf("expected aud [parsec.test], got %v", aud)
+		}
+	})
+
+	t.Run("issues every requested audience and resource", func(t *testing.T) {
+		aud, err := exchange(
+			[]string{"parsec.test", "orders.example.com", "orders.example.com"},
+			[]string{"https://api.example.com/billing"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []string{"parsec.test", "orders.example.com", "https://api.example.com/billing"}
+		if !slices.Equal(aud, want) {
+			t.Errorf("expected aud %v, got %v", want, aud)
+		}
+	})
+
+	t.Run("rejects audience that is not allowed as invalid_target", func(t *testing.T) {
+		_, err := exchange([]string{"orders.example.com", "other.example.com"}, nil)
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "invalid_target") {
+			t.Errorf("expected invalid_target, got %v", err)
+		}
+	})
+
+	t.Run("rejects resource that is not allowed as invalid_target", func(t *testing.T) {
+		_, err := exchange(nil, []string{"https://other.example.com"})
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "invalid_target") {
+			t.Errorf("expected invalid_target, got %v", err)
+		}
+	})
+}
+
+func TestExchangeServer_ScopePolicy(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject:     "user-456",
+		TrustDomain: "users",
+	}))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+	exchangeServer.ScopePolicy = scope.NewAllowlistPolicy(scope.AllowlistPolicyConfig{
+		SubjectScopes: map[string][]string{"users": {"orders:read", "orders:write"}},
+	})
+
+	exchange := func(requestedScope string) (*parsecv1.TokenExchangeResponse, error) {
+		return exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     "user-token",
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
+			Scope:            requestedScope,
+		})
+	}
+
+	t.Run("grants allowed scopes", func(t *testing.T) {
+		resp, err := exchange("orders:read orders:write")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Scope != "orders:read orders:write" {
+			t.Errorf("expected granted scope %q, got %q", "orders:read orders:write", resp.Scope)
+		}
+	})
+
+	t.Run("downgrades denied scopes", func(t *testing.T) {
+		resp, err := exchange("admin orders:read")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Scope != "orders:read" {
+			t.Errorf("expected granted scope %q, got %q", "orders:read", resp.Scope)
+		}
+	})
+
+	t.Run("rejects denied scopes as invalid_scope", func(t *testing.T) {
+		exchangeServer.RejectDeniedScopes = true
+		defer func() { exchangeServer.RejectDeniedScopes = false }()
+
+		_, err := exchange("admin orders:read")
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "invalid_scope") {
+			t.Errorf("expected invalid_scope, got %v", err)
+		}
+
+		if _, err := exchange("orders:read"); err != nil {
+			t.Errorf("unexpected error for allowed scope: %v", err)
+		}
+	})
+}
+
+func TestExchangeServer_Observability(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject:     "user-456",
+		TrustDomain: "users",
+	}))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	fakeObs := service.NewFakeObserver(t)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), fakeObs)
+	exchangeServer.ScopePolicy = scope.NewAllowlistPolicy(scope.AllowlistPolicyConfig{
+		SubjectScopes: map[string][]string{"users": {"orders:read"}},
+	})
+	exchangeServer.RejectDeniedScopes = true
+
+	exchange := func(requestedScope string) error {
+		_, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     "user-token",
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
+			Scope:            requestedScope,
+		})
+		return err
+	}
+
+	if err := exchange("orders:read"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := exchange("admin"); err == nil {
+		t.Fatal("expected invalid_scope")
+	}
+
+	fakeObs.AssertProbeCount(2)
+	fakeObs.GetProbe(0).AssertProbeSequence(
+		"ActorValidationSucceeded",
+		"RequestContextParsed",
+		"SubjectTokenValidationSucceeded",
+		service.ProbeCall("ExchangeSucceeded", string(service.TokenTypeTransactionToken)),
+		"End",
+	)
+	fakeObs.GetProbe(1).AssertProbeSequence(
+		"ActorValidationSucceeded",
+		"RequestContextParsed",
+		"SubjectTokenValidationSucceeded",
+		service.ProbeCall("ExchangeFailed", "invalid_scope", `scope "admin" is not allowed`),
+		"End",
+	)
+}
+
+func TestExchangeServer_ClientAuthentication(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject: "user-456",
+	}))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL:             "https://parsec.test",
+		TTL:                   5 * time.Minute,
+		RequestContextMappers: []service.ClaimMapper{service.NewRequestAttributesMapper()},
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients: []*clientauth.Client{
+			{ID: "gateway", Method: clientauth.MethodClientSecretBasic, Secret: "gateway-secret"},
+			{ID: "portal", Method: clientauth.MethodClientSecretPost, Secret: "portal-secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	exchangeServer.ClientAuthenticator = authenticator
+
+	newRequest := func() *parsecv1.TokenExchangeRequest {
+		return &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     "user-token",
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
+		}
+	}
+
+	t.Run("client_secret_basic", func(t *testing.T) {
+		basicCtx := metadata.NewIncomingContext(ctx, metadata.New(map[string]string{
+			"authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("gateway:gateway-secret")),
+		}))
+		resp, err := exchangeServer.Exchange(basicCtx, newRequest())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// The stub issuer includes the request attributes, which record the client
+		if !strings.Contains(resp.AccessToken, `"client_id":"gateway"`) {
+			t.Errorf("expected client_id in request attributes, got %s", resp.AccessToken)
+		}
+	})
+
+	t.Run("client_secret_post", func(t *testing.T) {
+		req := newRequest()
+		req.ClientId = "portal"
+		req.ClientSecret = "portal-secret"
+		if _, err := exchangeServer.Exchange(ctx, req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ignores client_id in request_context", func(t *testing.T) {
+		req := newRequest()
+		req.ClientId = "portal"
+		req.ClientSecret = "portal-secret"
+		req.RequestContext = base64.StdEncoding.EncodeToString([]byte(`{"client_id":"gateway"}`))
+		resp, err := exchangeServer.Exchange(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(resp.AccessToken, `"client_id":"portal"`) || strings.Contains(resp.AccessToken, "gateway") {
+			t.Errorf("expected client_id of the authenticated client, got %s", resp.AccessToken)
+		}
+
+		exchangeServer.ClientAuthenticator = nil
+		defer func() { exchangeServer.ClientAuthenticator = authenticator }()
+		resp, err = exchangeServer.Exchange(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(resp.AccessToken, "client_id") {
+			t.Errorf("expected no client_id without client authentication, got %s", resp.AccessToken)
+		}
+	})
+
+	t.Run("rejects unauthenticated clients as invalid_client", func(t *testing.T) {
+		_, err := exchangeServer.Exchange(ctx, newRequest())
+		if status.Code(err) != codes.Unauthenticated || !strings.Contains(err.Error(), "invalid_client") {
+			t.Errorf("expected invalid_client, got %v", err)
+		}
+	})
+
+	t.Run("rejects wrong secret as invalid_client", func(t *testing.T) {
+		req := newRequest()
+		req.ClientId = "portal"
+		req.ClientSecret = "gateway-secret"
+		_, err := exchangeServer.Exchange(ctx, req)
+		if status.Code(err) != codes.Unauthenticated || !strings.Contains(err.Error(), "invalid_client") {
+			t.Errorf("expected invalid_client, got %v", err)
+		}
+	})
+}
+
+func TestExchangeServer_CertificateBoundTokens(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeMTLS).WithResult(&trust.Result{
+		Subject: "spiffe://example.org/gateway",
+	}))
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject: "user@example.com",
+	}))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	clientCert := &x509.Certificate{Raw: []byte("gateway-certificate")}
+	mtlsCtx := peer.NewContext(ctx, &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}},
+	})
+
+	confirmation := func(t *testing.T, exchangeServer *ExchangeServer, ctx context.Context) any {
+		t.Helper()
+		resp, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken: "user-token",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		token, err := jwt.ParseInsecure([]byte(resp.AccessToken))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		cnf, _ := token.Get(trust.ConfirmationClaim)
+		return cnf
+	}
+
+	t.Run("bound to the actor's client certificate when enabled", func(t *testing.T) {
+		exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+		exchangeServer.CertificateBoundTokens = true
+
+		cnf, ok := confirmation(t, exchangeServer, mtlsCtx).(map[string]any)
+		if !ok {
+			t.Fatalf("expected %s claim", trust.ConfirmationClaim)
+		}
+		want := trust.CertificateThumbprint(clientCert.Raw)
+		if cnf[trust.X509ThumbprintConfirmation] != want {
+			t.Errorf("expected x5t#S256 %s, got %v", want, cnf[trust.X509ThumbprintConfirmation])
+		}
+	})
+
+	t.Run("bearer token without a client certificate", func(t *testing.T) {
+		exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+		exchangeServer.CertificateBoundTokens = true
+
+		if cnf := confirmation(t, exchangeServer, ctx); cnf != nil {
+			t.Errorf("expected no %s claim, got %v", trust.ConfirmationClaim, cnf)
+		}
+	})
+
+	t.Run("bearer token by default", func(t *testing.T) {
+		exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+
+		if cnf := confirmation(t, exchangeServer, mtlsCtx); cnf != nil {
+			t.Errorf("expected no %s claim, got %v", trust.ConfirmationClaim, cnf)
+		}
+	})
+}
+
+func TestExchangeServer_ReexchangeTokens(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeMTLS).WithResult(&trust.Result{
+		Subject: "spiffe://example.org/batch",
+	}))
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject:     "user@example.com",
+		TrustDomain: "users",
+	}))
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+	exchangeServer.AllowedAudiences = []string{"orders.example.com", "billing.example.com"}
+	exchangeServer.ReexchangeTokens = reexchange.NewIssuer(reexchange.IssuerConfig{
+		TrustDomain: "parsec.test",
+		Signer:      signer,
+	})
+
+	batchCtx := peer.NewContext(ctx, &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Raw: []byte("batch-certificate")}},
+		}},
+	})
+
+	resp, err := exchangeServer.Exchange(batchCtx, &parsecv1.TokenExchangeRequest{
+		GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
+		SubjectToken: "user-token",
+		Audience:     []string{"orders.example.com"},
+		Scope:        "orders:read orders:write",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.RefreshToken == "" {
+		t.Fatal("expected a re-exchange token")
+	}
+
+	reexchangeRequest := func() *parsecv1.TokenExchangeRequest {
+		return &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     resp.RefreshToken,
+			SubjectTokenType: reexchange.TokenType,
+		}
+	}
+
+	t.Run("redeemed for a fresh token for the original subject", func(t *testing.T) {
+		reResp, err := exchangeServer.Exchange(batchCtx, reexchangeRequest())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if reResp.RefreshToken != "" {
+			t.Error("expected no new re-exchange token")
+		}
+		if reResp.Scope != "orders:read orders:write" {
+			t.Errorf("expected the granted scope, got %q", reResp.Scope)
+		}
+
+		token, err := jwt.ParseInsecure([]byte(reResp.AccessToken))
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		if token.Subject() != "user@example.com" {
+			t.Errorf("expected subject user@example.com, got %s", token.Subject())
+		}
+		if !slices.Equal(token.Audience(), []string{"orders.example.com"}) {
+			t.Errorf("expected the granted audiences, got %v", token.Audience())
+		}
+		act, _ := token.Get(trust.ActClaim)
+		if actor, _ := act.(map[string]any); actor["sub"] != "spiffe://example.org/batch" {
+			t.Errorf("expected the batch job as actor, got %v", act)
+		}
+	})
+
+	t.Run("narrower scope", func(t *testing.T) {
+		req := reexchangeRequest()
+		req.Scope = "orders:read"
+		reResp, err := exchangeServer.Exchange(batchCtx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if reResp.Scope != "orders:read" {
+			t.Errorf("expected scope orders:read, got %q", reResp.Scope)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		modify   func(*parsecv1.TokenExchangeRequest)
+		wantCode string
+	}{
+		{
+			name:     "scope that was not granted",
+			ctx:      batchCtx,
+			modify:   func(req *parsecv1.TokenExchangeRequest) { req.Scope = "orders:admin" },
+			wantCode: oauthInvalidScope,
+		},
+		{
+			name:     "audience that was not granted",
+			ctx:      batchCtx,
+			modify:   func(req *parsecv1.TokenExchangeRequest) { req.Audience = []string{"billing.example.com"} },
+			wantCode: oauthInvalidTarget,
+		},
+		{
+			name:     "another caller",
+			ctx:      ctx,
+			modify:   func(*parsecv1.TokenExchangeRequest) {},
+			wantCode: oauthInvalidGrant,
+		},
+		{
+			name:     "tampered token",
+			ctx:      batchCtx,
+			modify:   func(req *parsecv1.TokenExchangeRequest) { req.SubjectToken += "x" },
+			wantCode: oauthInvalidGrant,
+		},
+		{
+			name: "with an actor token",
+			ctx:  batchCtx,
+			modify: func(req *parsecv1.TokenExchangeRequest) {
+				req.ActorToken = "actor-token"
+				req.ActorTokenType = "urn:ietf:params:oauth:token-type:jwt"
+			},
+			wantCode: oauthInvalidRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := reexchangeRequest()
+			tt.modify(req)
+			_, err := exchangeServer.Exchange(tt.ctx, req)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if code := oauthErrorCode(status.Convert(err)); code != tt.wantCode {
+				t.Errorf("expected %s, got %s (%v)", tt.wantCode, code, err)
+			}
+		})
+	}
+
+	t.Run("not issued unless enabled", func(t *testing.T) {
+		disabled := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+		resp, err := disabled.Exchange(batchCtx, &parsecv1.TokenExchangeRequest{
+			GrantType:    "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken: "user-token",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.RefreshToken != "" {
+			t.Error("expected no re-exchange token")
+		}
+	})
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // FormMarshaler implements runtime.Marshaler for application/x-www-form-urlencoded
@@ -53,11 +55,12 @@ func (m *FormMarshaler) Unmarshal(data []byte, v any) error {
 	}
 
 	// Convert to a flat map for easier handling
+	// Repeated fields (e.g. audience) are always lists, even with a single value
 	dataMap := make(map[string]any)
 	for key, vals := range values {
-		if len(vals) == 1 {
+		if len(vals) == 1 && !isRepeatedField(v, key) {
 			dataMap[key] = vals[0]
-		} else if len(vals) > 1 {
+		} else if len(vals) > 0 {
 			dataMap[key] = vals
 		}
 	}
@@ -72,6 +75,20 @@ func (m *FormMarshaler) Unmarshal(data []byte, v any) error {
 	return m.jsonMarshaler.Unmarshal(jsonData, v)
 }
 
+// isRepeatedField reports whether v is a proto message with a repeated field named key
+func isRepeatedField(v any, key string) bool {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return false
+	}
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	field := fields.ByName(protoreflect.Name(key))
+	if field == nil {
+		field = fields.ByJSONName(key)
+	}
+	return field != nil && field.IsList()
+}
+
 // NewDecoder creates a decoder for form-urlencoded data
 func (m *FormMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
 	return &formDecoder{reader: r, marshaler: m}
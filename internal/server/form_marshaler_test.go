@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"slices"
 	"testing"
 
 	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
@@ -26,7 +27,7 @@ func TestFormMarshaler_Unmarshal(t *testing.T) {
 				GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
 				SubjectToken:     "eyJhbGc.payload.signature",
 				SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
-				Audience:         "https://example.com",
+				Audience:         []string{"https://example.com"},
 			},
 			wantErr: false,
 		},
@@ -41,11 +42,27 @@ func TestFormMarshaler_Unmarshal(t *testing.T) {
 				GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
 				SubjectToken:     "token123",
 				SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
-				Resource:         "https://api.example.com",
+				Resource:         []string{"https://api.example.com"},
 				Scope:            "read write",
 			},
 			wantErr: false,
 		},
+		{
+			name: "repeated audience and resource",
+			data: "grant_type=urn%3Aietf%3Aparams%3Aoauth%3Agrant-type%3Atoken-exchange" +
+				"&subject_token=token123" +
+				"&subject_token_type=urn%3Aietf%3Aparams%3Aoauth%3Atoken-type%3Ajwt" +
+				"&audience=a.example.com&audience=b.example.com" +
+				"&resource=https%3A%2F%2Fapi.example.com",
+			want: &parsecv1.TokenExchangeRequest{
+				GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+				SubjectToken:     "token123",
+				SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
+				Audience:         []string{"a.example.com", "b.example.com"},
+				Resource:         []string{"https://api.example.com"},
+			},
+			wantErr: false,
+		},
 		{
 			name:    "invalid form data",
 			data:    "%ZZ%invalid",
@@ -77,10 +94,10 @@ func TestFormMarshaler_Unmarshal(t *testing.T) {
 			if got.SubjectTokenType != tt.want.SubjectTokenType {
 				t.Errorf("SubjectTokenType = %v, want %v", got.SubjectTokenType, tt.want.SubjectTokenType)
 			}
-			if got.Audience != tt.want.Audience {
+			if !slices.Equal(got.Audience, tt.want.Audience) {
 				t.Errorf("Audience = %v, want %v", got.Audience, tt.want.Audience)
 			}
-			if got.Resource != tt.want.Resource {
+			if !slices.Equal(got.Resource, tt.want.Resource) {
 				t.Errorf("Resource = %v, want %v", got.Resource, tt.want.Resource)
 			}
 			if got.Scope != tt.want.Scope {
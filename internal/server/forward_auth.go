@@ -0,0 +1,184 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc/codes"
+)
+
+// ForwardAuthPath is the HTTP path of the forward-auth endpoint
+const ForwardAuthPath = "/v1/forward-auth"
+
+// forwardAuthPrefixPattern matches checks from Envoy's HTTP ext_authz service, which
+// appends the original request's path to the configured path_prefix
+const forwardAuthPrefixPattern = ForwardAuthPath + "/{path=**}"
+
+// envoyHeadersToRemoveHeader lists the request headers Envoy's HTTP ext_authz filter
+// should remove before forwarding an allowed request
+const envoyHeadersToRemoveHeader = "x-envoy-auth-headers-to-remove"
+
+// forwardAuthMethods are the methods the forward-auth endpoint answers.
+// Traefik always checks with GET, but nginx auth_request subrequests keep the
+// method of the original request.
+var forwardAuthMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// ForwardAuthHandler exposes the ext_authz check as a plain HTTP endpoint, for proxies
+// without ext_authz support (nginx auth_request, Traefik ForwardAuth, Caddy forward_auth)
+//
+// The proxy sends the original request's headers, with its method, URI, and host in
+// X-Forwarded-Method/X-Original-Method, X-Forwarded-Uri/X-Original-URI, and X-Forwarded-Host.
+// Query parameters of the forward-auth request are treated as context extensions, so
+// routes are configured with the same parsec.* keys as with Envoy.
+// Envoy's HTTP ext_authz service (Istio's envoyExtAuthzHttp provider) instead sends the
+// original method and host, with the original path appended to ForwardAuthPath.
+// An allowed request gets a 200 with the issued tokens in response headers, for the
+// proxy to copy to the upstream request, and the credential headers to remove in
+// x-envoy-auth-headers-to-remove; a denied request gets the denial as is.
+type ForwardAuthHandler struct {
+	authz *AuthzServer
+}
+
+// NewForwardAuthHandler creates a forward-auth handler that checks requests with authz
+func NewForwardAuthHandler(authz *AuthzServer) *ForwardAuthHandler {
+	return &ForwardAuthHandler{authz: authz}
+}
+
+// ServeHTTP implements http.Handler
+func (h *ForwardAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.authz.Check(tlsPeerContext(r), forwardAuthCheckRequest(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if codes.Code(resp.GetStatus().GetCode()) == codes.OK {
+		okResp := resp.GetOkResponse()
+		writeHeaders(w.Header(), okResp.GetHeaders())
+		writeHeaders(w.Header(), okResp.GetResponseHeadersToAdd())
+		if remove := okResp.GetHeadersToRemove(); len(remove) > 0 {
+			w.Header().Set(envoyHeadersToRemoveHeader, strings.Join(remove, ","))
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	denied := resp.GetDeniedResponse()
+	writeHeaders(w.Header(), denied.GetHeaders())
+	httpStatus := int(denied.GetStatus().GetCode())
+	if httpStatus == 0 {
+		httpStatus = http.StatusForbidden
+	}
+	w.WriteHeader(httpStatus)
+	_, _ = w.Write([]byte(denied.GetBody()))
+}
+
+// forwardAuthCheckRequest describes the proxied request the way Envoy would
+func forwardAuthCheckRequest(r *http.Request) *authv3.CheckRequest {
+	headers := make(map[string]string, len(r.Header))
+	for name, values := range r.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	method := firstHeader(r, "X-Forwarded-Method", "X-Original-Method")
+	if method == "" {
+		method = r.Method
+	}
+	path := firstHeader(r, "X-Forwarded-Uri", "X-Original-URI")
+	originalPath, prefixed := strings.CutPrefix(r.URL.RequestURI(), ForwardAuthPath+"/")
+	if prefixed {
+		// Envoy's HTTP ext_authz service: the query belongs to the original request
+		path = "/" + originalPath
+	}
+	if path == "" {
+		path = "/"
+	}
+	host := firstHeader(r, "X-Forwarded-Host")
+	if host == "" {
+		host = r.Host
+	}
+	scheme := firstHeader(r, "X-Forwarded-Proto")
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	contextExtensions := make(map[string]string)
+	if !prefixed {
+		for name, values := range r.URL.Query() {
+			contextExtensions[name] = values[0]
+		}
+	}
+
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Source: &authv3.AttributeContext_Peer{
+				Address: &corev3.Address{
+					Address: &corev3.Address_SocketAddress{
+						SocketAddress: &corev3.SocketAddress{Address: forwardAuthClientIP(r)},
+					},
+				},
+			},
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method:   method,
+					Path:     path,
+					Host:     host,
+					Scheme:   scheme,
+					Protocol: r.Proto,
+					Headers:  headers,
+				},
+			},
+			ContextExtensions: contextExtensions,
+		},
+	}
+}
+
+// forwardAuthClientIP returns the address of the original client: the first address
+// in X-Forwarded-For, X-Real-IP, or the address of the proxy itself
+func forwardAuthClientIP(r *http.Request) string {
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		client, _, _ := strings.Cut(forwardedFor, ",")
+		return strings.TrimSpace(client)
+	}
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// firstHeader returns the value of the first of names r has
+func firstHeader(r *http.Request, names ...string) string {
+	for _, name := range names {
+		if value := r.Header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// writeHeaders sets headers on an HTTP response as Envoy would apply them
+func writeHeaders(dst http.Header, headers []*corev3.HeaderValueOption) {
+	for _, header := range headers {
+		key, value := header.GetHeader().GetKey(), header.GetHeader().GetValue()
+		if header.GetAppendAction() == corev3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD {
+			dst.Add(key, value)
+		} else {
+			dst.Set(key, value)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestForwardAuthHandler(t *testing.T) {
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+	authzServer.Denial = DenialSpec{WWWAuthenticate: true, Realm: "parsec"}
+	handler := NewForwardAuthHandler(authzServer)
+
+	tests := []struct {
+		name          string
+		target        string
+		headers       map[string]string
+		wantStatus    int
+		wantHeader    string
+		wantChallenge string
+	}{
+		{
+			name:   "Traefik ForwardAuth",
+			target: ForwardAuthPath,
+			headers: map[string]string{
+				"Authorization":      "Bearer valid-token",
+				"X-Forwarded-Method": "POST",
+				"X-Forwarded-Uri":    "/orders",
+				"X-Forwarded-Host":   "shop.example.com",
+				"X-Forwarded-Proto":  "https",
+			},
+			wantStatus: http.StatusOK,
+			wantHeader: "Transaction-Token",
+		},
+		{
+			name:   "nginx auth_request",
+			target: ForwardAuthPath,
+			headers: map[string]string{
+				"Authorization":     "Bearer valid-token",
+				"X-Original-URI":    "/orders?page=2",
+				"X-Original-Method": "GET",
+			},
+			wantStatus: http.StatusOK,
+			wantHeader: "Transaction-Token",
+		},
+		{
+			name:          "missing credential",
+			target:        ForwardAuthPath,
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer realm="parsec"`,
+		},
+		{
+			name:   "route requires scope",
+			target: ForwardAuthPath + "?parsec.required_scopes=admin",
+			headers: map[string]string{
+				"Authorization": "Bearer valid-token",
+			},
+			wantStatus:    http.StatusForbidden,
+			wantChallenge: `Bearer realm="parsec", error="insufficient_scope", scope="admin"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantHeader != "" && rec.Header().Get(tt.wantHeader) == "" {
+				t.Errorf("expected %s header in response", tt.wantHeader)
+			}
+			if tt.wantStatus == http.StatusOK && rec.Header().Get(envoyHeadersToRemoveHeader) != "authorization" {
+				t.Errorf("expected authorization in %s, got %q", envoyHeadersToRemoveHeader, rec.Header().Get(envoyHeadersToRemoveHeader))
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("expected challenge %q, got %q", tt.wantChallenge, got)
+			}
+		})
+	}
+}
+
+func TestForwardAuthCheckRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, ForwardAuthPath+"?parsec.token_types=access_token", nil)
+	req.RemoteAddr = "10.0.0.2:41234"
+	req.Header.Set("X-Forwarded-Method", "DELETE")
+	req.Header.Set("X-Forwarded-Uri", "/orders/42")
+	req.Header.Set("X-Forwarded-Host", "shop.example.com")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+
+	checkReq := forwardAuthCheckRequest(req)
+	httpReq := checkReq.GetAttributes().GetRequest().GetHttp()
+
+	if httpReq.Method != "DELETE" || httpReq.Path != "/orders/42" || httpReq.Host != "shop.example.com" {
+		t.Errorf("expected DELETE shop.example.com/orders/42, got %s %s%s", httpReq.Method, httpReq.Host, httpReq.Path)
+	}
+	if httpReq.Headers["accept"] != "text/html,application/json" {
+		t.Errorf("expected combined accept header, got %q", httpReq.Headers["accept"])
+	}
+	if ip := checkReq.GetAttributes().GetSource().GetAddress().GetSocketAddress().GetAddress(); ip != "203.0.113.7" {
+		t.Errorf("expected client IP 203.0.113.7, got %s", ip)
+	}
+	if got := checkReq.GetAttributes().GetContextExtensions()[ContextExtensionTokenTypes]; got != "access_token" {
+		t.Errorf("expected token types context extension, got %q", got)
+	}
+}
+
+func TestForwardAuthCheckRequest_EnvoyHTTPService(t *testing.T) {
+	// Envoy's HTTP ext_authz service appends the original path, query and all, to the path prefix
+	req := httptest.NewRequest(http.MethodPost, ForwardAuthPath+"/orders/42?parsec.required_scopes=admin", nil)
+	req.Host = "shop.example.com"
+
+	checkReq := forwardAuthCheckRequest(req)
+	httpReq := checkReq.GetAttributes().GetRequest().GetHttp()
+
+	if httpReq.Method != "POST" || httpReq.Path != "/orders/42?parsec.required_scopes=admin" || httpReq.Host != "shop.example.com" {
+		t.Errorf("expected original request, got %s %s%s", httpReq.Method, httpReq.Host, httpReq.Path)
+	}
+	if len(checkReq.GetAttributes().GetContextExtensions()) != 0 {
+		t.Errorf("expected the original query not to configure the route, got %v", checkReq.GetAttributes().GetContextExtensions())
+	}
+}
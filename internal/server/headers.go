@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+)
+
+// HeaderPolicy configures how ext_authz changes the headers of allowed requests
+// before Envoy forwards them upstream. The headers a credential was read from
+// are always removed.
+type HeaderPolicy struct {
+	// StripHeaders are further request headers to remove (e.g., "cookie")
+	StripHeaders []string
+
+	// AppendHeaders are headers parsec adds whose values are appended to any the
+	// request already has. Others overwrite the request's values.
+	AppendHeaders []string
+
+	// MaxHeaderSize, if positive, is the largest header value, in bytes, parsec adds.
+	// A request that would get a larger header, such as a token with many claims,
+	// is denied rather than forwarded without it.
+	MaxHeaderSize int
+
+	// ForwardedUserHeader, if set, names a header (e.g., "x-forwarded-user") that
+	// carries the validated subject upstream. It always overwrites the request's
+	// value, so clients cannot spoof it.
+	ForwardedUserHeader string
+}
+
+// Validate checks the policy only names headers Envoy can change
+func (p HeaderPolicy) Validate() error {
+	for _, header := range p.StripHeaders {
+		if header == "" || strings.HasPrefix(header, ":") || strings.EqualFold(header, "host") {
+			return fmt.Errorf("cannot strip header %q", header)
+		}
+	}
+	if p.MaxHeaderSize < 0 {
+		return fmt.Errorf("max header size must not be negative")
+	}
+	if strings.HasPrefix(p.ForwardedUserHeader, ":") {
+		return fmt.Errorf("invalid forwarded user header %q", p.ForwardedUserHeader)
+	}
+	return nil
+}
+
+// headersToRemove returns the request headers to remove, given those credentials were read from
+func (p HeaderPolicy) headersToRemove(credentialHeaders []string) []string {
+	headers := slices.Clone(credentialHeaders)
+	for _, header := range p.StripHeaders {
+		header = strings.ToLower(header)
+		if !slices.Contains(headers, header) {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// upstreamHeader returns the option that sets header to value on the upstream request
+func (p HeaderPolicy) upstreamHeader(header, value string) (*corev3.HeaderValueOption, error) {
+	if p.MaxHeaderSize > 0 && len(value) > p.MaxHeaderSize {
+		return nil, fmt.Errorf("header %s is %d bytes, more than the limit of %d", header, len(value), p.MaxHeaderSize)
+	}
+
+	action := corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD
+	if !strings.EqualFold(header, p.ForwardedUserHeader) &&
+		slices.ContainsFunc(p.AppendHeaders, func(h string) bool { return strings.EqualFold(h, header) }) {
+		action = corev3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD
+	}
+
+	return &corev3.HeaderValueOption{
+		Header: &corev3.HeaderValue{
+			Key:   header,
+			Value: value,
+		},
+		AppendAction: action,
+	}, nil
+}
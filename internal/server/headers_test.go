@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc/codes"
+
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestAuthzServer_HeaderPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	req := &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{
+					Method: "GET",
+					Path:   "/app",
+					Headers: map[string]string{
+						"authorization":    "Bearer test-token-123",
+						"cookie":           "session=abc",
+						"x-forwarded-user": "mallory",
+					},
+				},
+			},
+		},
+	}
+
+	findHeader := func(headers []*corev3.HeaderValueOption, name string) *corev3.HeaderValueOption {
+		for _, h := range headers {
+			if h.Header.Key == name {
+				return h
+			}
+		}
+		return nil
+	}
+
+	t.Run("default policy", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		okResp := resp.GetOkResponse()
+		if okResp == nil {
+			t.Fatalf("expected OK response, got code %d: %s", resp.Status.Code, resp.Status.Message)
+		}
+		if !slices.Equal(okResp.HeadersToRemove, []string{"authorization"}) {
+			t.Errorf("expected only authorization removed, got %v", okResp.HeadersToRemove)
+		}
+		token := findHeader(okResp.Headers, "Transaction-Token")
+		if token == nil || token.AppendAction != corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD {
+			t.Errorf("expected Transaction-Token to overwrite, got %v", token)
+		}
+		if findHeader(okResp.Headers, "x-forwarded-user") != nil {
+			t.Error("expected no forwarded user header")
+		}
+	})
+
+	t.Run("configured policy", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+		authzServer.Headers = HeaderPolicy{
+			StripHeaders:        []string{"Cookie", "authorization"},
+			AppendHeaders:       []string{"transaction-token", "x-forwarded-user"},
+			ForwardedUserHeader: "x-forwarded-user",
+		}
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		okResp := resp.GetOkResponse()
+		if okResp == nil {
+			t.Fatalf("expected OK response, got code %d: %s", resp.Status.Code, resp.Status.Message)
+		}
+		if !slices.Equal(okResp.HeadersToRemove, []string{"authorization", "cookie"}) {
+			t.Errorf("expected authorization and cookie removed, got %v", okResp.HeadersToRemove)
+		}
+		token := findHeader(okResp.Headers, "Transaction-Token")
+		if token == nil || token.AppendAction != corev3.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD {
+			t.Errorf("expected Transaction-Token to append, got %v", token)
+		}
+		user := findHeader(okResp.Headers, "x-forwarded-user")
+		if user == nil || user.Header.Value != "test-subject" {
+			t.Fatalf("expected x-forwarded-user test-subject, got %v", user)
+		}
+		if user.AppendAction != corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD {
+			t.Error("expected forwarded user header to overwrite the client's value")
+		}
+	})
+
+	t.Run("header too large", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+		authzServer.Headers = HeaderPolicy{MaxHeaderSize: 16}
+
+		resp, err := authzServer.Check(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if codes.Code(resp.Status.Code) != codes.Internal {
+			t.Fatalf("expected code %s, got %s", codes.Internal, codes.Code(resp.Status.Code))
+		}
+		if !strings.Contains(resp.Status.Message, "Transaction-Token") {
+			t.Errorf("expected message to name the header, got %q", resp.Status.Message)
+		}
+	})
+}
+
+func TestHeaderPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  HeaderPolicy
+		wantErr bool
+	}{
+		{name: "empty", policy: HeaderPolicy{}},
+		{name: "strip cookie", policy: HeaderPolicy{StripHeaders: []string{"cookie"}}},
+		{name: "strip pseudo-header", policy: HeaderPolicy{StripHeaders: []string{":path"}}, wantErr: true},
+		{name: "strip host", policy: HeaderPolicy{StripHeaders: []string{"Host"}}, wantErr: true},
+		{name: "negative size", policy: HeaderPolicy{MaxHeaderSize: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+)
+
+// IntrospectionServer implements the TokenIntrospection gRPC service (RFC 7662)
+// It answers for opaque tokens, whose claims are kept in a token store
+type IntrospectionServer struct {
+	parsecv1.UnimplementedTokenIntrospectionServer
+
+	store               tokenstore.Store
+	denylist            denylist.Denylist
+	clientAuthenticator *clientauth.Authenticator
+	clock               clock.Clock
+}
+
+// IntrospectionServerConfig configures the introspection server
+type IntrospectionServerConfig struct {
+	// Store holds the records of issued opaque tokens
+	Store tokenstore.Store
+
+	// Denylist, if set, holds revoked tokens, which are inactive
+	Denylist denylist.Denylist
+
+	// ClientAuthenticator authenticates the resource servers calling the endpoint
+	// Required: RFC 7662 section 2.1 requires the endpoint to be protected.
+	ClientAuthenticator *clientauth.Authenticator
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// NewIntrospectionServer creates a new introspection server
+func NewIntrospectionServer(cfg IntrospectionServerConfig) *IntrospectionServer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &IntrospectionServer{
+		store:               cfg.Store,
+		denylist:            cfg.Denylist,
+		clientAuthenticator: cfg.ClientAuthenticator,
+		clock:               clk,
+	}
+}
+
+// Introspect implements the introspection endpoint (RFC 7662)
+// Unknown, expired, and revoked tokens are inactive rather than errors, so callers
+// cannot tell why.
+func (s *IntrospectionServer) Introspect(ctx context.Context, req *parsecv1.IntrospectionRequest) (*structpb.Struct, error) {
+	if s.clientAuthenticator == nil {
+		return nil, oauthError(oauthInvalidClient, "client authentication is not configured")
+	}
+	if _, err := s.clientAuthenticator.Authenticate(ctx, clientCredentials(ctx, req)); err != nil {
+		return nil, oauthError(oauthInvalidClient, "client authentication failed: %v", err)
+	}
+	if req.Token == "" {
+		return nil, oauthError(oauthInvalidRequest, "token is required")
+	}
+
+	record, err := s.store.Get(ctx, tokenstore.Key(req.Token))
+	if errors.Is(err, tokenstore.ErrNotFound) {
+		return inactiveToken(), nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to look up token: %v", err)
+	}
+	if !s.clock.Now().Before(record.ExpiresAt) {
+		return inactiveToken(), nil
+	}
+	if tokenID, _ := record.Claims["jti"].(string); tokenID != "" && s.denylist != nil {
+		denied, err := s.denylist.IsDenied(ctx, tokenID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to check denylist: %v", err)
+		}
+		if denied {
+			return inactiveToken(), nil
+		}
+	}
+
+	resp, err := activeToken(record)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to build introspection response: %v", err)
+	}
+	return resp, nil
+}
+
+// inactiveToken is the introspection response for tokens that are not active
+func inactiveToken() *structpb.Struct {
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"active": structpb.NewBoolValue(false),
+	}}
+}
+
+// activeToken is the introspection response for an active token: its claims and
+// "active": true
+func activeToken(record *tokenstore.Record) (*structpb.Struct, error) {
+	// Claims may hold any JSON-encodable values; round trip them through JSON so
+	// they are the generic values structpb accepts
+	data, err := json.Marshal(record.Claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal claims: %w", err)
+	}
+	if claims == nil {
+		claims = make(map[string]any)
+	}
+	claims["active"] = true
+	return structpb.NewStruct(claims)
+}
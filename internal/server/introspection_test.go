@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestIntrospectionServer(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := tokenstore.NewMemoryStore(clk)
+
+	// Exchange for an opaque access token
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject: "user@example.com",
+	}))
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeAccessToken, issuer.NewOpaqueIssuer(issuer.OpaqueIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TokenType: string(service.TokenTypeAccessToken),
+		TTL:       5 * time.Minute,
+		Store:     store,
+		Clock:     clk,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(trustStore, tokenService, NewStubClaimsFilterRegistry(), nil)
+
+	exchanged, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+		GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
+		RequestedTokenType: string(service.TokenTypeAccessToken),
+		SubjectToken:       "user-token",
+		SubjectTokenType:   "urn:ietf:params:oauth:token-type:jwt",
+		Scope:              "orders:read",
+	})
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	if strings.Count(exchanged.AccessToken, ".") != 0 {
+		t.Fatalf("expected an opaque token, got %s", exchanged.AccessToken)
+	}
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients: []*clientauth.Client{
+			{ID: "orders", Method: clientauth.MethodClientSecretBasic, Secret: "orders-secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	introspectionServer := NewIntrospectionServer(IntrospectionServerConfig{
+		Store:               store,
+		ClientAuthenticator: authenticator,
+		Clock:               clk,
+	})
+
+	authCtx := metadata.NewIncomingContext(ctx, metadata.New(map[string]string{
+		"authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("orders:orders-secret")),
+	}))
+
+	t.Run("active token", func(t *testing.T) {
+		resp, err := introspectionServer.Introspect(authCtx, &parsecv1.IntrospectionRequest{Token: exchanged.AccessToken})
+		if err != nil {
+			t.Fatalf("introspection failed: %v", err)
+		}
+		fields := resp.AsMap()
+		if fields["active"] != true {
+			t.Fatalf("expected active token, got %v", fields)
+		}
+		want := map[string]any{
+			"iss":   "https://parsec.test",
+			"sub":   "user@example.com",
+			"scope": "orders:read",
+			"exp":   float64(clk.Now().Add(5 * time.Minute).Unix()),
+		}
+		for name, value := range want {
+			if fields[name] != value {
+				t.Errorf("expected %s=%v, got %v", name, value, fields[name])
+			}
+		}
+		if aud, _ := fields["aud"].([]any); len(aud) != 1 || aud[0] != "parsec.test" {
+			t.Errorf("expected aud [parsec.test], got %v", fields["aud"])
+		}
+	})
+
+	t.Run("unknown token is inactive", func(t *testing.T) {
+		resp, err := introspectionServer.Introspect(authCtx, &parsecv1.IntrospectionRequest{Token: "unknown"})
+		if err != nil {
+			t.Fatalf("introspection failed: %v", err)
+		}
+		if fields := resp.AsMap(); len(fields) != 1 || fields["active"] != false {
+			t.Errorf("expected only active=false, got %v", fields)
+		}
+	})
+
+	t.Run("rejects unauthenticated clients as invalid_client", func(t *testing.T) {
+		_, err := introspectionServer.Introspect(ctx, &parsecv1.IntrospectionRequest{Token: exchanged.AccessToken})
+		if status.Code(err) != codes.Unauthenticated || !strings.Contains(err.Error(), "invalid_client") {
+			t.Errorf("expected invalid_client, got %v", err)
+		}
+	})
+
+	t.Run("requires a token", func(t *testing.T) {
+		_, err := introspectionServer.Introspect(authCtx, &parsecv1.IntrospectionRequest{})
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "invalid_request") {
+			t.Errorf("expected invalid_request, got %v", err)
+		}
+	})
+
+	t.Run("expired token is inactive", func(t *testing.T) {
+		clk.Advance(5 * time.Minute)
+		resp, err := introspectionServer.Introspect(authCtx, &parsecv1.IntrospectionRequest{Token: exchanged.AccessToken})
+		if err != nil {
+			t.Fatalf("introspection failed: %v", err)
+		}
+		if fields := resp.AsMap(); fields["active"] != false {
+			t.Errorf("expected inactive token, got %v", fields)
+		}
+	})
+}
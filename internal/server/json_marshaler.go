@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// JSONMarshaler implements runtime.Marshaler for application/json
+// It is grpc-gateway's default JSON marshaler, except that repeated fields also
+// accept a single value, so "audience": "a" decodes like "audience": ["a"]. This
+// keeps clients that send RFC 8693 parameters as JSON strings working.
+type JSONMarshaler struct {
+	runtime.Marshaler
+}
+
+// NewJSONMarshaler creates a new JSON marshaler
+func NewJSONMarshaler() *JSONMarshaler {
+	return &JSONMarshaler{
+		Marshaler: &runtime.HTTPBodyMarshaler{
+			Marshaler: &runtime.JSONPb{
+				MarshalOptions: protojson.MarshalOptions{
+					EmitUnpopulated: true,
+				},
+				UnmarshalOptions: protojson.UnmarshalOptions{
+					DiscardUnknown: true,
+				},
+			},
+		},
+	}
+}
+
+// Unmarshal converts JSON to a proto message, accepting single values for repeated fields
+func (m *JSONMarshaler) Unmarshal(data []byte, v any) error {
+	return m.Marshaler.Unmarshal(singleValuesToLists(data, v), v)
+}
+
+// NewDecoder creates a decoder for JSON data
+func (m *JSONMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v any) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read JSON data: %w", err)
+		}
+		return m.Unmarshal(data, v)
+	})
+}
+
+// singleValuesToLists wraps the values of v's repeated fields in lists when data has
+// them as single values. Data that is not a JSON object is returned unchanged, for
+// the underlying marshaler to report.
+func singleValuesToLists(data []byte, v any) []byte {
+	if _, ok := v.(proto.Message); !ok {
+		return data
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return data
+	}
+
+	changed := false
+	for key, value := range fields {
+		trimmed := bytes.TrimSpace(value)
+		if !isRepeatedField(v, key) || bytes.HasPrefix(trimmed, []byte("[")) || bytes.Equal(trimmed, []byte("null")) {
+			continue
+		}
+		fields[key] = append(append([]byte("["), trimmed...), ']')
+		changed = true
+	}
+	if !changed {
+		return data
+	}
+
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return normalized
+}
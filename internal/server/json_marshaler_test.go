@@ -0,0 +1,66 @@
+package server
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+)
+
+func TestJSONMarshaler_Unmarshal(t *testing.T) {
+	marshaler := NewJSONMarshaler()
+
+	tests := []struct {
+		name         string
+		data         string
+		wantAudience []string
+		wantResource []string
+	}{
+		{
+			name:         "single values",
+			data:         `{"subject_token": "token123", "audience": "a.example.com", "resource": "https://api.example.com"}`,
+			wantAudience: []string{"a.example.com"},
+			wantResource: []string{"https://api.example.com"},
+		},
+		{
+			name:         "lists",
+			data:         `{"subject_token": "token123", "audience": ["a.example.com", "b.example.com"], "resource": []}`,
+			wantAudience: []string{"a.example.com", "b.example.com"},
+		},
+		{
+			name:         "json names",
+			data:         `{"subjectToken": "token123", "audience": "a.example.com"}`,
+			wantAudience: []string{"a.example.com"},
+		},
+		{
+			name: "null",
+			data: `{"subject_token": "token123", "audience": null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got parsecv1.TokenExchangeRequest
+			if err := marshaler.NewDecoder(strings.NewReader(tt.data)).Decode(&got); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if got.SubjectToken != "token123" {
+				t.Errorf("expected subject_token token123, got %q", got.SubjectToken)
+			}
+			if !slices.Equal(got.Audience, tt.wantAudience) {
+				t.Errorf("expected audience %v, got %v", tt.wantAudience, got.Audience)
+			}
+			if !slices.Equal(got.Resource, tt.wantResource) {
+				t.Errorf("expected resource %v, got %v", tt.wantResource, got.Resource)
+			}
+		})
+	}
+
+	t.Run("rejects values of the wrong type", func(t *testing.T) {
+		var got parsecv1.TokenExchangeRequest
+		if err := marshaler.Unmarshal([]byte(`{"audience": 1}`), &got); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
@@ -1,38 +1,85 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/big"
+	"net/url"
 	"sync"
 	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
 	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
 	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/jwkspub"
 	"github.com/alechenninger/parsec/internal/service"
 )
 
+// JWKSPublication selects which key sets the JWKS server publishes
+type JWKSPublication string
+
+const (
+	// JWKSPublicationAggregated publishes one key set with the keys of all issuers
+	JWKSPublicationAggregated JWKSPublication = "aggregated"
+
+	// JWKSPublicationPerIssuer publishes a key set per token type
+	JWKSPublicationPerIssuer JWKSPublication = "per_issuer"
+
+	// JWKSPublicationBoth publishes the aggregated key set and a key set per token type
+	JWKSPublicationBoth JWKSPublication = "both"
+)
+
+// Aggregated reports whether the key set of all issuers is published
+func (p JWKSPublication) Aggregated() bool {
+	return p == JWKSPublicationAggregated || p == JWKSPublicationBoth
+}
+
+// PerIssuer reports whether a key set per token type is published
+func (p JWKSPublication) PerIssuer() bool {
+	return p == JWKSPublicationPerIssuer || p == JWKSPublicationBoth
+}
+
+// IssuerJWKSPath returns the HTTP path of the key set of tokenType
+func IssuerJWKSPath(tokenType service.TokenType) string {
+	return "/v1/issuers/" + url.PathEscape(string(tokenType)) + "/jwks.json"
+}
+
 // JWKSServer implements the JWKS gRPC service
-// It serves JSON Web Key Sets containing public keys from all configured issuers
-// The response is cached and periodically refreshed for efficiency
+// It serves JSON Web Key Sets containing public keys from all configured issuers,
+// aggregated into one set and/or per token type depending on its publication.
+// Responses are cached and periodically refreshed for efficiency
 type JWKSServer struct {
 	parsecv1.UnimplementedJWKSServer
 
 	issuerRegistry  service.Registry
 	clock           clock.Clock
 	refreshInterval time.Duration
+	publication     JWKSPublication
 
-	// Cached response
-	mu             sync.RWMutex
-	cachedResponse *parsecv1.GetJWKSResponse
-	cachedError    error
+	// Cached responses
+	mu                    sync.RWMutex
+	cachedResponse        *parsecv1.GetJWKSResponse
+	cachedError           error
+	cachedIssuerResponses map[service.TokenType]*parsecv1.GetJWKSResponse
 
 	// Background refresh
 	ticker clock.Ticker
+
+	// External distribution
+	publisher     jwkspub.Publisher
+	publishMu     sync.Mutex
+	lastPublished []byte
 }
 
 // JWKSServerConfig configures the JWKS server
@@ -46,6 +93,13 @@ type JWKSServerConfig struct {
 
 	// Clock is used for time operations (defaults to system clock)
 	Clock clock.Clock
+
+	// Publisher optionally publishes the JWKS document externally whenever it changes
+	// It always publishes the aggregated key set
+	Publisher jwkspub.Publisher
+
+	// Publication selects which key sets are served (default: JWKSPublicationAggregated)
+	Publication JWKSPublication
 }
 
 // NewJWKSServer creates a new JWKS server with caching
@@ -56,11 +110,16 @@ func NewJWKSServer(cfg JWKSServerConfig) *JWKSServer {
 	if cfg.Clock == nil {
 		cfg.Clock = clock.NewSystemClock()
 	}
+	if cfg.Publication == "" {
+		cfg.Publication = JWKSPublicationAggregated
+	}
 
 	return &JWKSServer{
 		issuerRegistry:  cfg.IssuerRegistry,
 		clock:           cfg.Clock,
 		refreshInterval: cfg.RefreshInterval,
+		publication:     cfg.Publication,
+		publisher:       cfg.Publisher,
 	}
 }
 
@@ -88,6 +147,10 @@ func (s *JWKSServer) Stop() {
 // GetJWKS implements the JWKS service
 // Returns a cached JSON Web Key Set containing all public keys from all configured issuers
 func (s *JWKSServer) GetJWKS(ctx context.Context, req *parsecv1.GetJWKSRequest) (*parsecv1.GetJWKSResponse, error) {
+	if !s.publication.Aggregated() {
+		return nil, status.Error(codes.NotFound, "keys are published per issuer at /v1/issuers/{token_type}/jwks.json")
+	}
+
 	// Try to serve from cache first
 	s.mu.RLock()
 	cachedResp := s.cachedResponse
@@ -109,13 +172,40 @@ func (s *JWKSServer) GetJWKS(ctx context.Context, req *parsecv1.GetJWKSRequest)
 	return s.buildJWKSResponse(ctx)
 }
 
-// refreshCache updates the cached JWKS response in the background
+// GetIssuerJWKS implements the JWKS service
+// Returns a cached JSON Web Key Set containing the public keys of the issuer of one token type
+func (s *JWKSServer) GetIssuerJWKS(ctx context.Context, req *parsecv1.GetIssuerJWKSRequest) (*parsecv1.GetJWKSResponse, error) {
+	if !s.publication.PerIssuer() {
+		return nil, status.Error(codes.NotFound, "keys are only published as one set at /.well-known/jwks.json")
+	}
+
+	tokenType := service.TokenType(req.GetTokenType())
+
+	s.mu.RLock()
+	cachedResp := s.cachedIssuerResponses[tokenType]
+	s.mu.RUnlock()
+
+	if cachedResp != nil {
+		return cachedResp, nil
+	}
+
+	// Not cached yet (first request, new issuer, or failing issuer)
+	return s.buildIssuerJWKSResponse(ctx, tokenType)
+}
+
+// refreshCache updates the cached JWKS responses in the background
 func (s *JWKSServer) refreshCache(ctx context.Context) error {
+	var issuerErr error
+	if s.publication.PerIssuer() {
+		issuerErr = s.refreshIssuerCache(ctx)
+	}
+	if !s.publication.Aggregated() {
+		return issuerErr
+	}
+
 	resp, err := s.buildJWKSResponse(ctx)
 
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if resp != nil {
 		s.cachedResponse = resp
 		s.cachedError = nil
@@ -126,8 +216,84 @@ func (s *JWKSServer) refreshCache(ctx context.Context) error {
 			s.cachedError = err
 		}
 	}
+	s.mu.Unlock()
+
+	if resp != nil && s.publisher != nil {
+		if pubErr := s.publish(ctx, resp); pubErr != nil {
+			slog.WarnContext(ctx, "Failed to publish JWKS", "error", pubErr)
+			if err == nil {
+				err = pubErr
+			}
+		}
+	}
 
-	return err
+	return errors.Join(err, issuerErr)
+}
+
+// refreshIssuerCache updates the cached per-issuer JWKS responses
+// An issuer that fails keeps serving its previous key set
+func (s *JWKSServer) refreshIssuerCache(ctx context.Context) error {
+	s.mu.RLock()
+	previous := s.cachedIssuerResponses
+	s.mu.RUnlock()
+
+	responses := make(map[service.TokenType]*parsecv1.GetJWKSResponse)
+	var errs []error
+	for _, tokenType := range s.issuerRegistry.ListTokenTypes() {
+		resp, err := s.buildIssuerJWKSResponse(ctx, tokenType)
+		if err != nil {
+			errs = append(errs, err)
+			resp = previous[tokenType]
+		}
+		if resp != nil {
+			responses[tokenType] = resp
+		}
+	}
+
+	s.mu.Lock()
+	s.cachedIssuerResponses = responses
+	s.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// publish pushes the JWKS document to the external publisher if it changed since the last
+// successful publish. A failed publish is retried on the next refresh.
+func (s *JWKSServer) publish(ctx context.Context, resp *parsecv1.GetJWKSResponse) error {
+	doc, err := MarshalJWKSDocument(resp)
+	if err != nil {
+		return err
+	}
+
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+
+	if bytes.Equal(doc, s.lastPublished) {
+		return nil
+	}
+
+	if err := s.publisher.Publish(ctx, doc); err != nil {
+		return err
+	}
+
+	s.lastPublished = doc
+	return nil
+}
+
+// MarshalJWKSDocument renders a JWKS response as a stable, compact RFC 7517 JSON document.
+// The same key set always produces the same bytes, so documents can be compared for changes.
+func MarshalJWKSDocument(resp *parsecv1.GetJWKSResponse) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JWKS: %w", err)
+	}
+
+	// protojson output whitespace is deliberately unstable; compact it
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to compact JWKS: %w", err)
+	}
+	return buf.Bytes(), nil
 }
 
 // buildJWKSResponse builds a fresh JWKS response from all issuers
@@ -144,22 +310,41 @@ func (s *JWKSServer) buildJWKSResponse(ctx context.Context) (*parsecv1.GetJWKSRe
 	// The errors are still propagated via the error return for observability
 	// but we prioritize serving available keys to clients
 
-	// Convert service.PublicKey to parsecv1.JSONWebKey
-	var allKeys []*parsecv1.JSONWebKey
+	// Return the keys. If there were partial failures (err != nil but len(publicKeys) > 0),
+	// we still return success to serve the available keys
+	return &parsecv1.GetJWKSResponse{
+		Keys: convertToJSONWebKeys(publicKeys),
+	}, nil
+}
+
+// buildIssuerJWKSResponse builds a fresh JWKS response from the issuer of tokenType
+func (s *JWKSServer) buildIssuerJWKSResponse(ctx context.Context, tokenType service.TokenType) (*parsecv1.GetJWKSResponse, error) {
+	issuer, err := s.issuerRegistry.GetIssuer(tokenType)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "no issuer for token type %s", tokenType)
+	}
+
+	publicKeys, err := issuer.PublicKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public keys for %s: %w", tokenType, err)
+	}
+
+	return &parsecv1.GetJWKSResponse{
+		Keys: convertToJSONWebKeys(publicKeys),
+	}, nil
+}
+
+// convertToJSONWebKeys converts public keys to JSON Web Keys, skipping keys that can't be converted
+func convertToJSONWebKeys(publicKeys []service.PublicKey) []*parsecv1.JSONWebKey {
+	var jwks []*parsecv1.JSONWebKey
 	for _, pk := range publicKeys {
 		jwk, err := convertToJSONWebKey(pk)
 		if err != nil {
-			// Skip keys that can't be converted
 			continue
 		}
-		allKeys = append(allKeys, jwk)
+		jwks = append(jwks, jwk)
 	}
-
-	// Return the keys. If there were partial failures (err != nil but len(allKeys) > 0),
-	// we still return success to serve the available keys
-	return &parsecv1.GetJWKSResponse{
-		Keys: allKeys,
-	}, nil
+	return jwks
 }
 
 // convertToJSONWebKey converts a service.PublicKey to a parsecv1.JSONWebKey
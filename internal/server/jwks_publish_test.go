@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+type recordingPublisher struct {
+	docs [][]byte
+	err  error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, doc []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.docs = append(p.docs, doc)
+	return nil
+}
+
+func TestJWKSServerPublishing(t *testing.T) {
+	ctx := context.Background()
+
+	newKey := func(kid string) service.PublicKey {
+		privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		return service.PublicKey{KeyID: kid, Algorithm: "ES256", Use: "sig", Key: &privateKey.PublicKey}
+	}
+
+	t.Run("publishes on start and only when key set changes", func(t *testing.T) {
+		issuer := &testIssuerWithKeys{publicKeys: []service.PublicKey{newKey("key-a")}}
+		registry := service.NewSimpleRegistry()
+		registry.Register(service.TokenTypeTransactionToken, issuer)
+
+		publisher := &recordingPublisher{}
+		clk := clock.NewFixtureClock(time.Now())
+		jwksServer := NewJWKSServer(JWKSServerConfig{
+			IssuerRegistry:  registry,
+			RefreshInterval: 1 * time.Minute,
+			Clock:           clk,
+			Publisher:       publisher,
+		})
+
+		if err := jwksServer.Start(ctx); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer jwksServer.Stop()
+
+		if len(publisher.docs) != 1 {
+			t.Fatalf("expected 1 publish on start, got %d", len(publisher.docs))
+		}
+
+		var doc struct {
+			Keys []map[string]any `json:"keys"`
+		}
+		if err := json.Unmarshal(publisher.docs[0], &doc); err != nil {
+			t.Fatalf("published document is not valid JSON: %v", err)
+		}
+		if len(doc.Keys) != 1 || doc.Keys[0]["kid"] != "key-a" || doc.Keys[0]["kty"] != "EC" {
+			t.Errorf("unexpected published document: %s", publisher.docs[0])
+		}
+
+		// Unchanged key set is not republished
+		clk.Advance(1 * time.Minute)
+		if len(publisher.docs) != 1 {
+			t.Errorf("expected no publish for unchanged key set, got %d", len(publisher.docs))
+		}
+
+		// Rotation publishes the new key set
+		issuer.publicKeys = append(issuer.publicKeys, newKey("key-b"))
+		clk.Advance(1 * time.Minute)
+		if len(publisher.docs) != 2 {
+			t.Fatalf("expected publish after key set change, got %d", len(publisher.docs))
+		}
+	})
+
+	t.Run("retries failed publish on next refresh", func(t *testing.T) {
+		issuer := &testIssuerWithKeys{publicKeys: []service.PublicKey{newKey("key-a")}}
+		registry := service.NewSimpleRegistry()
+		registry.Register(service.TokenTypeTransactionToken, issuer)
+
+		publisher := &recordingPublisher{err: errors.New("bucket unavailable")}
+		clk := clock.NewFixtureClock(time.Now())
+		jwksServer := NewJWKSServer(JWKSServerConfig{
+			IssuerRegistry:  registry,
+			RefreshInterval: 1 * time.Minute,
+			Clock:           clk,
+			Publisher:       publisher,
+		})
+
+		if err := jwksServer.Start(ctx); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer jwksServer.Stop()
+
+		// Serving is unaffected by publish failures
+		resp, err := jwksServer.GetJWKS(ctx, nil)
+		if err != nil || len(resp.Keys) != 1 {
+			t.Fatalf("expected JWKS to be served despite publish failure, got %v, %v", resp, err)
+		}
+
+		publisher.err = nil
+		clk.Advance(1 * time.Minute)
+		if len(publisher.docs) != 1 {
+			t.Errorf("expected publish to be retried, got %d", len(publisher.docs))
+		}
+	})
+}
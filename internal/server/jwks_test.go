@@ -7,6 +7,10 @@ import (
 	"crypto/rand"
 	"testing"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
 	"github.com/alechenninger/parsec/internal/service"
 )
 
@@ -229,6 +233,103 @@ func TestJWKSServer(t *testing.T) {
 	})
 }
 
+func TestJWKSServer_PerIssuer(t *testing.T) {
+	ctx := context.Background()
+
+	txnKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	accessKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	registry := service.NewSimpleRegistry()
+	registry.Register(service.TokenTypeTransactionToken, &testIssuerWithKeys{
+		publicKeys: []service.PublicKey{{KeyID: "txn-key", Algorithm: "ES256", Use: "sig", Key: &txnKey.PublicKey}},
+	})
+	registry.Register(service.TokenTypeAccessToken, &testIssuerWithKeys{
+		publicKeys: []service.PublicKey{{KeyID: "access-key", Algorithm: "ES384", Use: "sig", Key: &accessKey.PublicKey}},
+	})
+	registry.Register(service.TokenTypeJWT, &testIssuerWithError{})
+
+	newServer := func(t *testing.T, publication JWKSPublication) *JWKSServer {
+		t.Helper()
+		jwksServer := NewJWKSServer(JWKSServerConfig{
+			IssuerRegistry: registry,
+			Publication:    publication,
+		})
+		jwksServer.Start(ctx)
+		t.Cleanup(jwksServer.Stop)
+		return jwksServer
+	}
+
+	issuerKeyIDs := func(t *testing.T, jwksServer *JWKSServer, tokenType service.TokenType) []string {
+		t.Helper()
+		resp, err := jwksServer.GetIssuerJWKS(ctx, &parsecv1.GetIssuerJWKSRequest{TokenType: string(tokenType)})
+		if err != nil {
+			t.Fatalf("GetIssuerJWKS failed: %v", err)
+		}
+		var kids []string
+		for _, key := range resp.Keys {
+			kids = append(kids, key.Kid)
+		}
+		return kids
+	}
+
+	t.Run("serves only the keys of the requested issuer", func(t *testing.T) {
+		jwksServer := newServer(t, JWKSPublicationPerIssuer)
+
+		if kids := issuerKeyIDs(t, jwksServer, service.TokenTypeTransactionToken); len(kids) != 1 || kids[0] != "txn-key" {
+			t.Errorf("expected [txn-key], got %v", kids)
+		}
+		if kids := issuerKeyIDs(t, jwksServer, service.TokenTypeAccessToken); len(kids) != 1 || kids[0] != "access-key" {
+			t.Errorf("expected [access-key], got %v", kids)
+		}
+	})
+
+	t.Run("aggregated set is not served", func(t *testing.T) {
+		jwksServer := newServer(t, JWKSPublicationPerIssuer)
+
+		_, err := jwksServer.GetJWKS(ctx, nil)
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound, got %v", err)
+		}
+	})
+
+	t.Run("unknown and failing issuers", func(t *testing.T) {
+		jwksServer := newServer(t, JWKSPublicationPerIssuer)
+
+		_, err := jwksServer.GetIssuerJWKS(ctx, &parsecv1.GetIssuerJWKSRequest{TokenType: "urn:example:unknown"})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound for unknown token type, got %v", err)
+		}
+		_, err = jwksServer.GetIssuerJWKS(ctx, &parsecv1.GetIssuerJWKSRequest{TokenType: string(service.TokenTypeJWT)})
+		if err == nil {
+			t.Error("expected error for failing issuer")
+		}
+	})
+
+	t.Run("both serves aggregated and per-issuer sets", func(t *testing.T) {
+		jwksServer := newServer(t, JWKSPublicationBoth)
+
+		resp, err := jwksServer.GetJWKS(ctx, nil)
+		if err != nil {
+			t.Fatalf("GetJWKS failed: %v", err)
+		}
+		if len(resp.Keys) != 2 {
+			t.Errorf("expected 2 aggregated keys, got %d", len(resp.Keys))
+		}
+		if kids := issuerKeyIDs(t, jwksServer, service.TokenTypeTransactionToken); len(kids) != 1 || kids[0] != "txn-key" {
+			t.Errorf("expected [txn-key], got %v", kids)
+		}
+	})
+
+	t.Run("aggregated by default", func(t *testing.T) {
+		jwksServer := newServer(t, "")
+
+		_, err := jwksServer.GetIssuerJWKS(ctx, &parsecv1.GetIssuerJWKSRequest{TokenType: string(service.TokenTypeTransactionToken)})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound, got %v", err)
+		}
+	})
+}
+
 // testIssuerWithKeys is a test issuer that returns a predefined set of public keys
 type testIssuerWithKeys struct {
 	publicKeys []service.PublicKey
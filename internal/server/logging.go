@@ -0,0 +1,23 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	"github.com/alechenninger/parsec/internal/logging"
+)
+
+// requestLoggingInterceptor starts the logging scope of each call, so records logged
+// while serving it carry its fields, like its subject and transaction ID
+func requestLoggingInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(logging.WithRequest(ctx), req)
+}
+
+// requestLoggingHandler starts the logging scope of each HTTP request next serves
+func requestLoggingHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(logging.WithRequest(r.Context())))
+	})
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// OAuth error codes returned by the token endpoint (RFC 6749 section 5.2, RFC 8707,
+// RFC 8628 section 3.5) and the revocation endpoint (RFC 7009 section 2.2.1)
+const (
+	oauthInvalidRequest       = "invalid_request"
+	oauthInvalidClient        = "invalid_client"
+	oauthInvalidGrant         = "invalid_grant"
+	oauthUnauthorizedClient   = "unauthorized_client"
+	oauthUnsupportedGrantType = "unsupported_grant_type"
+	oauthUnsupportedTokenType = "unsupported_token_type"
+	oauthInvalidTarget        = "invalid_target"
+	oauthInvalidScope         = "invalid_scope"
+	oauthSlowDown             = "slow_down"
+)
+
+// oauthErrorDomain is the ErrorInfo domain of gRPC errors that carry an OAuth error code
+const oauthErrorDomain = "oauth2"
+
+// oauthError returns a gRPC error for an OAuth error response
+// The OAuth error code prefixes the message and is attached as an ErrorInfo detail,
+// so HTTP clients get an RFC 6749 error response (see OAuthErrorHandler)
+// invalid_client is Unauthenticated (HTTP 401), slow_down is ResourceExhausted (HTTP 429),
+// and other codes are InvalidArgument (HTTP 400)
+func oauthError(code, format string, args ...any) error {
+	grpcCode := codes.InvalidArgument
+	switch code {
+	case oauthInvalidClient:
+		grpcCode = codes.Unauthenticated
+	case oauthSlowDown:
+		grpcCode = codes.ResourceExhausted
+	}
+
+	st := status.New(grpcCode, code+": "+fmt.Sprintf(format, args...))
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{Reason: code, Domain: oauthErrorDomain})
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// slowDownError returns a slow_down error for a rate-limited request
+// retryAfter, if known, is attached as a RetryInfo detail, which HTTP clients get as
+// a Retry-After header.
+func slowDownError(retryAfter time.Duration, format string, args ...any) error {
+	st, _ := status.FromError(oauthError(oauthSlowDown, format, args...))
+	if retryAfter <= 0 {
+		return st.Err()
+	}
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// oauthErrorResponse is an RFC 6749 section 5.2 error response
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// writeNoStoreJSON writes a JSON response that must not be cached, like the responses
+// of the OAuth endpoints (RFC 6749 section 5.1)
+func writeNoStoreJSON(w http.ResponseWriter, httpStatus int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(httpStatus)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// OAuthErrorHandler is a grpc-gateway error handler that writes errors carrying an
+// OAuth error code as RFC 6749 error responses
+// Other errors are handled by runtime.DefaultHTTPErrorHandler.
+func OAuthErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+		return
+	}
+	code := oauthErrorCode(st)
+	if code == "" {
+		runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if code == oauthInvalidClient {
+		// Challenge with the scheme the client authenticated with (RFC 6749 section 5.2)
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Basic ") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="parsec"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_client"`)
+		}
+	}
+	if retryAfter := retryDelay(st); retryAfter > 0 {
+		// Retry-After is in whole seconds (RFC 9110 section 10.2.3)
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+	}
+	w.WriteHeader(runtime.HTTPStatusFromCode(st.Code()))
+	_ = json.NewEncoder(w).Encode(oauthErrorResponse{
+		Error:            code,
+		ErrorDescription: strings.TrimPrefix(st.Message(), code+": "),
+	})
+}
+
+// oauthErrorCode returns the OAuth error code attached to st, if any
+func oauthErrorCode(st *status.Status) string {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == oauthErrorDomain {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+// exchangeError returns the OAuth error code and description of err, an exchange's error.
+// Errors without an OAuth error code are server errors.
+func exchangeError(err error) (code string, description string) {
+	st := status.Convert(err)
+	code = oauthErrorCode(st)
+	if code == "" {
+		code = "server_error"
+	}
+	return code, strings.TrimPrefix(st.Message(), code+": ")
+}
+
+// retryDelay returns the retry delay attached to st, if any
+func retryDelay(st *status.Status) time.Duration {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			return info.RetryDelay.AsDuration()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOAuthErrorHandler(t *testing.T) {
+	handle := func(err error) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/token", nil)
+		OAuthErrorHandler(context.Background(), runtime.NewServeMux(), &runtime.JSONPb{}, w, r, err)
+		return w
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) oauthErrorResponse {
+		t.Helper()
+		var resp oauthErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("writes OAuth errors as RFC 6749 error responses", func(t *testing.T) {
+		w := handle(oauthError(oauthInvalidGrant, "token validation failed: %s", "expired"))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("expected Cache-Control no-store, got %q", cc)
+		}
+		resp := decode(t, w)
+		if resp.Error != oauthInvalidGrant {
+			t.Errorf("expected error %s, got %s", oauthInvalidGrant, resp.Error)
+		}
+		if resp.ErrorDescription != "token validation failed: expired" {
+			t.Errorf("unexpected error_description %q", resp.ErrorDescription)
+		}
+	})
+
+	t.Run("invalid_client is unauthorized", func(t *testing.T) {
+		w := handle(oauthError(oauthInvalidClient, "actor validation failed"))
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status 401, got %d", w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Error("expected WWW-Authenticate header")
+		}
+		if resp := decode(t, w); resp.Error != oauthInvalidClient {
+			t.Errorf("expected error %s, got %s", oauthInvalidClient, resp.Error)
+		}
+	})
+
+	t.Run("slow_down is too many requests", func(t *testing.T) {
+		w := handle(slowDownError(1500*time.Millisecond, "too many token exchange requests"))
+
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("expected status 429, got %d", w.Code)
+		}
+		if ra := w.Header().Get("Retry-After"); ra != "2" {
+			t.Errorf("expected Retry-After rounded up to 2, got %q", ra)
+		}
+		if resp := decode(t, w); resp.Error != oauthSlowDown {
+			t.Errorf("expected error %s, got %s", oauthSlowDown, resp.Error)
+		}
+	})
+
+	t.Run("other errors use the default handler", func(t *testing.T) {
+		w := handle(status.Error(codes.Internal, "failed to issue token"))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", w.Code)
+		}
+		var resp map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if _, ok := resp["error"]; ok {
+			t.Errorf("expected a gRPC status response, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"strings"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+)
+
+// peerAttributes describes the source or destination of a checked request, for
+// request.additional.source and request.additional.destination
+// Returns nil if Envoy reported nothing about the peer besides its address.
+//
+// In an Istio mesh, principals are SPIFFE IDs of the form
+// spiffe://<trust domain>/ns/<namespace>/sa/<service account>; their namespace and
+// service account are broken out. Destination labels are the workload's pod labels
+// when ext_authz sets bootstrap_metadata_labels_key (Istio's node metadata key is LABELS).
+func peerAttributes(peer *authv3.AttributeContext_Peer) map[string]any {
+	if peer.GetPrincipal() == "" && peer.GetService() == "" && len(peer.GetLabels()) == 0 {
+		return nil
+	}
+
+	attrs := map[string]any{
+		"principal": peer.GetPrincipal(),
+		"service":   peer.GetService(),
+	}
+	labels := peer.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	attrs["labels"] = labels
+
+	if namespace, serviceAccount, ok := parseIstioPrincipal(peer.GetPrincipal()); ok {
+		attrs["namespace"] = namespace
+		attrs["service_account"] = serviceAccount
+	}
+	return attrs
+}
+
+// parseIstioPrincipal returns the namespace and service account of an Istio workload's
+// SPIFFE ID (spiffe://cluster.local/ns/default/sa/frontend)
+func parseIstioPrincipal(principal string) (namespace, serviceAccount string, ok bool) {
+	rest, ok := strings.CutPrefix(principal, "spiffe://")
+	if !ok {
+		return "", "", false
+	}
+	segments := strings.Split(rest, "/")
+	if len(segments) != 5 || segments[1] != "ns" || segments[3] != "sa" || segments[2] == "" || segments[4] == "" {
+		return "", "", false
+	}
+	return segments[2], segments[4], true
+}
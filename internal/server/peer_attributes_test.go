@@ -0,0 +1,84 @@
+package server
+
+import (
+	"testing"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+)
+
+func TestParseIstioPrincipal(t *testing.T) {
+	tests := []struct {
+		principal          string
+		wantNamespace      string
+		wantServiceAccount string
+		wantOK             bool
+	}{
+		{
+			principal:          "spiffe://cluster.local/ns/default/sa/frontend",
+			wantNamespace:      "default",
+			wantServiceAccount: "frontend",
+			wantOK:             true,
+		},
+		{principal: "spiffe://cluster.local/workload/frontend"},
+		{principal: "spiffe://cluster.local/ns//sa/frontend"},
+		{principal: "cluster.local/ns/default/sa/frontend"},
+		{principal: "frontend.default.svc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.principal, func(t *testing.T) {
+			namespace, serviceAccount, ok := parseIstioPrincipal(tt.principal)
+			if ok != tt.wantOK || namespace != tt.wantNamespace || serviceAccount != tt.wantServiceAccount {
+				t.Errorf("expected %q, %q, %v, got %q, %q, %v",
+					tt.wantNamespace, tt.wantServiceAccount, tt.wantOK, namespace, serviceAccount, ok)
+			}
+		})
+	}
+}
+
+func TestAuthzServer_PeerRequestAttributes(t *testing.T) {
+	authzServer := NewAuthzServer(nil, nil, nil, nil)
+
+	req := &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Source: &authv3.AttributeContext_Peer{
+				Principal: "spiffe://cluster.local/ns/shop/sa/frontend",
+			},
+			Destination: &authv3.AttributeContext_Peer{
+				Principal: "spiffe://cluster.local/ns/shop/sa/orders",
+				Labels:    map[string]string{"app": "orders", "version": "v2"},
+			},
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{Method: "GET", Path: "/orders"},
+			},
+		},
+	}
+
+	attrs := authzServer.buildRequestAttributes(req)
+
+	source, ok := attrs.Additional["source"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected source attributes, got %v", attrs.Additional["source"])
+	}
+	if source["namespace"] != "shop" || source["service_account"] != "frontend" {
+		t.Errorf("expected shop/frontend, got %v/%v", source["namespace"], source["service_account"])
+	}
+
+	destination, ok := attrs.Additional["destination"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected destination attributes, got %v", attrs.Additional["destination"])
+	}
+	if labels := destination["labels"].(map[string]string); labels["app"] != "orders" {
+		t.Errorf("expected destination label app=orders, got %v", labels)
+	}
+
+	// Peers Envoy reports nothing about are left out
+	attrs = authzServer.buildRequestAttributes(&authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Request: req.Attributes.Request,
+		},
+	})
+	if _, ok := attrs.Additional["source"]; ok {
+		t.Errorf("expected no source attributes, got %v", attrs.Additional["source"])
+	}
+}
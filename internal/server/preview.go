@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/audit"
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// PreviewPath is the HTTP path of the token preview endpoint
+const PreviewPath = "/v1/token:preview"
+
+// PreviewServer previews token exchanges: a request, the same as to the token endpoint,
+// is validated and authorized, and every data source and claim mapper runs, but the
+// response is the claims of the token that would be issued, not a token.
+//
+// Nothing is signed or stored, and previews are neither observed nor audited as
+// exchanges, so platform teams can debug claim mappers safely in production. Callers
+// must authenticate as clients, even where the token endpoint does not require it.
+// Previews are limited by the exchange server's global rate limit, since they do the
+// work of an exchange, but not by its per-client limit on issuance.
+type PreviewServer struct {
+	exchange *ExchangeServer
+}
+
+// NewPreviewServer creates a preview server for the exchanges of exchange
+func NewPreviewServer(exchange *ExchangeServer) *PreviewServer {
+	return &PreviewServer{exchange: exchange}
+}
+
+// TokenPreview is the token an exchange would issue
+type TokenPreview struct {
+	IssuedTokenType string         `json:"issued_token_type"`
+	ExpiresIn       int64          `json:"expires_in"`
+	Scope           string         `json:"scope,omitempty"`
+	Audience        []string       `json:"audience"`
+	Claims          map[string]any `json:"claims"`
+
+	// RedactedClaims are the paths of the claims redacted from the token
+	RedactedClaims []string `json:"redacted_claims,omitempty"`
+}
+
+// ServeHTTP implements http.Handler
+func (s *PreviewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := parsePreviewRequest(w, r, s.exchange.MaxRequestBytes)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeNoStoreJSON(w, http.StatusRequestEntityTooLarge, oauthErrorResponse{
+				Error:            oauthInvalidRequest,
+				ErrorDescription: fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit),
+			})
+			return
+		}
+		writeNoStoreJSON(w, http.StatusBadRequest, oauthErrorResponse{
+			Error:            oauthInvalidRequest,
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+
+	// Credentials reach the exchange as the gateway passes them to the token endpoint,
+	// including the client certificate, for tls_client_auth clients and mTLS actors
+	ctx := tlsPeerContext(r)
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+	}
+
+	resp, err := s.preview(ctx, req)
+	if err != nil {
+		writePreviewError(w, err)
+		return
+	}
+	writeNoStoreJSON(w, http.StatusOK, resp)
+}
+
+// preview previews the exchange of req for an authenticated client
+func (s *PreviewServer) preview(ctx context.Context, req *parsecv1.TokenExchangeRequest) (*TokenPreview, error) {
+	if s.exchange.ClientAuthenticator == nil {
+		return nil, oauthError(oauthInvalidClient, "client authentication is not configured")
+	}
+	return s.exchange.Preview(ctx, req)
+}
+
+// Preview runs the exchange of req up to issuance, then previews the token instead of
+// issuing it. Errors are OAuth errors, as from Exchange.
+func (s *ExchangeServer) Preview(ctx context.Context, req *parsecv1.TokenExchangeRequest) (*TokenPreview, error) {
+	// A preview validates credentials and runs data sources and mappers as an exchange
+	// does, so it sheds load with exchanges
+	if s.RateLimit != nil {
+		if ok, retryAfter := s.RateLimit.Allow(""); !ok {
+			return nil, slowDownError(retryAfter, "too many token exchange requests")
+		}
+	}
+
+	// The probe and decision are discarded, and the client's exchange rate limit is not
+	// charged: a preview is not an exchange
+	_, probe := service.NoOpTokenExchangeObserver().TokenExchangeStarted(ctx, req.GrantType, req.RequestedTokenType, req.Audience, req.Scope)
+	defer probe.End()
+	ex, err := s.authorizeExchange(ctx, req, probe, &audit.Record{}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	issueRequest := ex.issueRequest
+	requestedTokenType := issueRequest.TokenTypes[0]
+	tokens, err := s.tokenService.PreviewTokens(ctx, issueRequest)
+	if err != nil {
+		if errors.Is(err, service.ErrPreviewNotSupported) {
+			return nil, oauthError(oauthInvalidRequest, "%v", err)
+		}
+		if errors.Is(err, service.ErrIssuanceDenied) {
+			return nil, oauthError(oauthInvalidGrant, "token exchange denied: %v", err)
+		}
+		return nil, fmt.Errorf("failed to preview token: %w", err)
+	}
+	token, ok := tokens[requestedTokenType]
+	if !ok {
+		return nil, fmt.Errorf("token service did not preview requested token type %s", requestedTokenType)
+	}
+
+	// Tokens without requested audiences are for the trust domain
+	audiences := issueRequest.Audiences
+	if len(audiences) == 0 {
+		audiences = []string{s.tokenService.TrustDomain()}
+	}
+
+	return &TokenPreview{
+		IssuedTokenType: string(requestedTokenType),
+		ExpiresIn:       int64(token.ExpiresAt.Sub(token.IssuedAt).Seconds()),
+		Scope:           issueRequest.Scope,
+		Audience:        audiences,
+		Claims:          token.Claims,
+		RedactedClaims:  token.RedactedClaims,
+	}, nil
+}
+
+// parsePreviewRequest reads a token exchange request from a form or JSON body of at
+// most maxBytes, if positive
+// The grant type defaults to token exchange.
+func parsePreviewRequest(w http.ResponseWriter, r *http.Request, maxBytes int64) (*parsecv1.TokenExchangeRequest, error) {
+	if maxBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	req := &parsecv1.TokenExchangeRequest{}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := protojson.Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+	} else {
+		if err := NewFormMarshaler().Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("invalid form body: %w", err)
+		}
+	}
+
+	if req.GrantType == "" {
+		req.GrantType = tokenExchangeGrantType
+	}
+	return req, nil
+}
+
+// writePreviewError writes err as an OAuth error response, like the token endpoint's
+func writePreviewError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	code := oauthErrorCode(st)
+	if code == "" {
+		writeNoStoreJSON(w, http.StatusInternalServerError, oauthErrorResponse{
+			Error:            "server_error",
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+
+	if code == oauthInvalidClient {
+		w.Header().Set("WWW-Authenticate", `Basic realm="parsec"`)
+	}
+	if retryAfter := retryDelay(st); retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+	}
+	writeNoStoreJSON(w, runtime.HTTPStatusFromCode(st.Code()), oauthErrorResponse{
+		Error:            code,
+		ErrorDescription: strings.TrimPrefix(st.Message(), code+": "),
+	})
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/mapper"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestPreviewServer(t *testing.T) {
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject: "user-456",
+	}))
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeMTLS).WithResult(&trust.Result{
+		Subject: "spiffe://example.org/mesh",
+	}))
+
+	claimMapper, err := mapper.NewCELMapper(`{"user": subject.subject, "client": request.additional.client_id}`)
+	if err != nil {
+		t.Fatalf("failed to create mapper: %v", err)
+	}
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewUnsignedIssuer(issuer.UnsignedIssuerConfig{
+		TokenType:    string(service.TokenTypeTransactionToken),
+		ClaimMappers: []service.ClaimMapper{claimMapper},
+	}))
+	issuerRegistry.Register(service.TokenTypeAccessToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+	exchangeServer := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+
+	// Served as the server serves it, through the gateway's mux
+	mux := runtime.NewServeMux()
+	preview := NewPreviewServer(exchangeServer)
+	if err := mux.HandlePath(http.MethodPost, PreviewPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		preview.ServeHTTP(w, r)
+	}); err != nil {
+		t.Fatalf("failed to register preview handler: %v", err)
+	}
+
+	post := func(form url.Values, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, PreviewPath, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	form := url.Values{
+		"subject_token":      {"user-token"},
+		"subject_token_type": {"urn:ietf:params:oauth:token-type:jwt"},
+	}
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("platform:platform-secret"))
+
+	t.Run("requires client authentication to be configured", func(t *testing.T) {
+		if rec := post(form, basic); rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d: %s", rec.Code, rec.Body)
+		}
+	})
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients: []*clientauth.Client{
+			{ID: "platform", Method: clientauth.MethodClientSecretBasic, Secret: "platform-secret"},
+			{ID: "mesh", Method: clientauth.MethodTLSClientAuth, SANs: []string{"spiffe://example.org/mesh"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	exchangeServer.ClientAuthenticator = authenticator
+
+	t.Run("returns the claims that would be issued", func(t *testing.T) {
+		rec := post(form, basic)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		var resp TokenPreview
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Claims["user"] != "user-456" || resp.Claims["client"] != "platform" {
+			t.Errorf("expected the mapped claims, got %v", resp.Claims)
+		}
+		if resp.IssuedTokenType != string(service.TokenTypeTransactionToken) || len(resp.Audience) != 1 || resp.Audience[0] != "parsec.test" {
+			t.Errorf("unexpected preview %+v", resp)
+		}
+	})
+
+	t.Run("authenticates clients by TLS certificate", func(t *testing.T) {
+		meshForm := url.Values{"client_id": {"mesh"}}
+		for key, values := range form {
+			meshForm[key] = values
+		}
+		req := httptest.NewRequest(http.MethodPost, PreviewPath, strings.NewReader(meshForm.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{
+			URIs: []*url.URL{{Scheme: "spiffe", Host: "example.org", Path: "/mesh"}},
+		}}}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		var resp TokenPreview
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Claims["client"] != "mesh" {
+			t.Errorf("expected the certificate's client, got %v", resp.Claims)
+		}
+	})
+
+	t.Run("rejects unauthenticated clients", func(t *testing.T) {
+		rec := post(form, "")
+		if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "invalid_client") {
+			t.Errorf("expected invalid_client, got %d: %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("rejects token types that cannot be previewed", func(t *testing.T) {
+		accessToken := url.Values{"requested_token_type": {string(service.TokenTypeAccessToken)}}
+		for key, values := range form {
+			accessToken[key] = values
+		}
+		rec := post(accessToken, basic)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "not supported") {
+			t.Errorf("expected invalid_request, got %d: %s", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("does not charge the client's exchange rate limit", func(t *testing.T) {
+		exchangeServer.ClientRateLimit = NewRateLimiter(RateLimiterConfig{Rate: 1, Burst: 1, Clock: clock.NewFixtureClock(time.Now())})
+		defer func() { exchangeServer.ClientRateLimit = nil }()
+
+		for i := 0; i < 3; i++ {
+			if rec := post(form, basic); rec.Code != http.StatusOK {
+				t.Fatalf("preview %d: expected 200, got %d: %s", i, rec.Code, rec.Body)
+			}
+		}
+		if _, err := exchangeServer.Exchange(metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", basic)), &parsecv1.TokenExchangeRequest{
+			GrantType:        tokenExchangeGrantType,
+			SubjectToken:     "user-token",
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
+		}); err != nil {
+			t.Errorf("expected the exchange to be within the rate limit, got %v", err)
+		}
+	})
+
+	t.Run("is limited by the global exchange rate limit", func(t *testing.T) {
+		exchangeServer.RateLimit = NewRateLimiter(RateLimiterConfig{Rate: 1, Burst: 1, Clock: clock.NewFixtureClock(time.Now())})
+		defer func() { exchangeServer.RateLimit = nil }()
+
+		if rec := post(form, basic); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		rec := post(form, basic)
+		if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "slow_down") {
+			t.Errorf("expected 429 slow_down, got %d: %s", rec.Code, rec.Body)
+		}
+		if rec.Header().Get("Retry-After") == "" {
+			t.Error("expected a Retry-After header")
+		}
+	})
+
+	t.Run("rejects bodies over MaxRequestBytes", func(t *testing.T) {
+		exchangeServer.MaxRequestBytes = 64
+		defer func() { exchangeServer.MaxRequestBytes = 0 }()
+
+		large := url.Values{"subject_token": {strings.Repeat("a", 100)}}
+		if rec := post(large, basic); rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "invalid_request") {
+			t.Errorf("expected 413, got %d: %s", rec.Code, rec.Body)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// RateLimiterConfig configures a RateLimiter
+type RateLimiterConfig struct {
+	// Rate is how many requests per second each key is allowed on average
+	Rate float64
+
+	// Burst is how many requests a key may make at once (default: Rate, at least 1)
+	Burst int
+
+	// MaxKeys bounds the number of keys tracked at once (default: 10000)
+	// Beyond it, keys whose buckets are full are forgotten first.
+	MaxKeys int
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// RateLimiter limits the rate of requests by key with token buckets
+// Each key's bucket holds up to Burst tokens and refills at Rate tokens per second;
+// a request takes one token, and is rejected when none is left.
+type RateLimiter struct {
+	rate    float64
+	burst   float64
+	maxKeys int
+	clock   clock.Clock
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// defaultRateLimiterMaxKeys is the default RateLimiterConfig.MaxKeys
+const defaultRateLimiterMaxKeys = 10000
+
+// NewRateLimiter creates a new rate limiter
+func NewRateLimiter(cfg RateLimiterConfig) *RateLimiter {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	burst := float64(cfg.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(cfg.Rate))
+	}
+
+	maxKeys := cfg.MaxKeys
+	if maxKeys <= 0 {
+		maxKeys = defaultRateLimiterMaxKeys
+	}
+
+	return &RateLimiter{
+		rate:    cfg.Rate,
+		burst:   burst,
+		maxKeys: maxKeys,
+		clock:   clk,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Allow takes a token from key's bucket, reporting whether one was left
+// If not, retryAfter is how long until the bucket has a token again.
+func (l *RateLimiter) Allow(key string) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	bucket, found := l.buckets[key]
+	if found {
+		bucket.refill(now, l.rate, l.burst)
+	} else {
+		if len(l.buckets) >= l.maxKeys {
+			l.evict(now)
+		}
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	if l.rate <= 0 {
+		return false, 0
+	}
+	return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+}
+
+// refill adds the tokens earned since the bucket was last used
+func (b *tokenBucket) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed.Seconds()*rate)
+	}
+	b.last = now
+}
+
+// evict makes room for a new key: buckets that have refilled are forgotten, since a
+// new bucket starts full anyway, and if none have, an arbitrary one is
+// Must be called with mu held.
+func (l *RateLimiter) evict(now time.Time) {
+	for key, bucket := range l.buckets {
+		bucket.refill(now, l.rate, l.burst)
+		if bucket.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	if len(l.buckets) < l.maxKeys {
+		return
+	}
+	for key := range l.buckets {
+		delete(l.buckets, key)
+		return
+	}
+}
+
+// rateLimitKey returns the key a token exchange is rate limited by: the authenticated
+// client if any, else the actor's identity; anonymous actors share a key
+func rateLimitKey(client *clientauth.Client, actor *trust.Result) string {
+	if client != nil {
+		return "client\x00" + client.ID
+	}
+	if actor != nil && actor.Subject != "" {
+		return "actor\x00" + actor.Issuer + "\x00" + actor.Subject
+	}
+	return "anonymous"
+}
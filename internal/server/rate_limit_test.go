@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("allows bursts and refills at the rate", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Now())
+		limiter := NewRateLimiter(RateLimiterConfig{Rate: 2, Burst: 3, Clock: clk})
+
+		for i := 0; i < 3; i++ {
+			if ok, _ := limiter.Allow("a"); !ok {
+				t.Fatalf("expected request %d of the burst to be allowed", i+1)
+			}
+		}
+		ok, retryAfter := limiter.Allow("a")
+		if ok {
+			t.Fatal("expected request beyond the burst to be rejected")
+		}
+		if retryAfter != 500*time.Millisecond {
+			t.Errorf("expected retry after 500ms, got %s", retryAfter)
+		}
+
+		if ok, _ := limiter.Allow("b"); !ok {
+			t.Error("expected another key to have its own bucket")
+		}
+
+		clk.Advance(500 * time.Millisecond)
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Error("expected a token to be refilled")
+		}
+		if ok, _ := limiter.Allow("a"); ok {
+			t.Error("expected only one token to be refilled")
+		}
+	})
+
+	t.Run("forgets full buckets beyond max keys", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Now())
+		limiter := NewRateLimiter(RateLimiterConfig{Rate: 1, MaxKeys: 2, Clock: clk})
+
+		limiter.Allow("a")
+		clk.Advance(time.Second)
+		limiter.Allow("b")
+		limiter.Allow("c")
+
+		if _, ok := limiter.buckets["a"]; ok || len(limiter.buckets) != 2 {
+			t.Errorf("expected the refilled bucket to be evicted, got %v", limiter.buckets)
+		}
+	})
+}
+
+func TestExchangeServer_Limits(t *testing.T) {
+	ctx := context.Background()
+
+	store := trust.NewStubStore()
+	store.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer).WithResult(&trust.Result{
+		Subject: "user-456",
+	}))
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	exchange := func(s *ExchangeServer, subjectToken string) error {
+		_, err := s.Exchange(ctx, &parsecv1.TokenExchangeRequest{
+			GrantType:        "urn:ietf:params:oauth:grant-type:token-exchange",
+			SubjectToken:     subjectToken,
+			SubjectTokenType: "urn:ietf:params:oauth:token-type:jwt",
+		})
+		return err
+	}
+
+	t.Run("rate limited exchanges fail with slow_down", func(t *testing.T) {
+		for name, configure := range map[string]func(*ExchangeServer, *RateLimiter){
+			"global":     func(s *ExchangeServer, l *RateLimiter) { s.RateLimit = l },
+			"per client": func(s *ExchangeServer, l *RateLimiter) { s.ClientRateLimit = l },
+		} {
+			t.Run(name, func(t *testing.T) {
+				s := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+				configure(s, NewRateLimiter(RateLimiterConfig{Rate: 1, Clock: clock.NewFixtureClock(time.Now())}))
+
+				if err := exchange(s, "user-token"); err != nil {
+					t.Fatalf("expected first exchange to succeed, got %v", err)
+				}
+				err := exchange(s, "user-token")
+				st := status.Convert(err)
+				if st.Code() != codes.ResourceExhausted || oauthErrorCode(st) != oauthSlowDown {
+					t.Fatalf("expected slow_down, got %v", err)
+				}
+				if retryDelay(st) != time.Second {
+					t.Errorf("expected retry delay of 1s, got %s", retryDelay(st))
+				}
+			})
+		}
+	})
+
+	t.Run("rejects tokens over the size limit as invalid_request", func(t *testing.T) {
+		s := NewExchangeServer(store, tokenService, NewStubClaimsFilterRegistry(), nil)
+		s.MaxTokenBytes = 16
+
+		if err := exchange(s, strings.Repeat("a", 16)); err != nil {
+			t.Fatalf("expected token at the limit to be accepted, got %v", err)
+		}
+		err := exchange(s, strings.Repeat("a", 17))
+		if oauthErrorCode(status.Convert(err)) != oauthInvalidRequest {
+			t.Errorf("expected invalid_request, got %v", err)
+		}
+	})
+}
+
+func TestLimitTokenRequestBody(t *testing.T) {
+	s := New(Config{ExchangeServer: &ExchangeServer{MaxRequestBytes: 8}})
+	handler := s.limitTokenRequestBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	post := func(path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(tokenExchangePath, "12345678"); rec.Code != http.StatusOK || rec.Body.String() != "12345678" {
+		t.Errorf("expected body within the limit to be passed on, got %d %q", rec.Code, rec.Body.String())
+	}
+	rec := post(tokenExchangePath, "123456789")
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), oauthInvalidRequest) {
+		t.Errorf("expected 413 invalid_request, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := post("/v1/verify", "123456789"); rec.Code != http.StatusOK {
+		t.Errorf("expected other paths not to be limited, got %d", rec.Code)
+	}
+}
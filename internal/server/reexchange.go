@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"slices"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/reexchange"
+	"github.com/alechenninger/parsec/internal/scope"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// redeemReexchangeToken redeems the re-exchange token in subject_token
+// Only the caller and client it was issued to may redeem it, and it stands in for both
+// the subject_token and actor_token of the original exchange.
+func (s *ExchangeServer) redeemReexchangeToken(ctx context.Context, req *parsecv1.TokenExchangeRequest, caller *trust.Result, client *clientauth.Client) (*reexchange.Grant, error) {
+	if req.ActorToken != "" || req.ActorTokenType != "" {
+		return nil, oauthError(oauthInvalidRequest, "actor_token cannot be used with a re-exchange token")
+	}
+	grant, err := s.ReexchangeTokens.Redeem(ctx, req.SubjectToken)
+	if err != nil {
+		return nil, oauthError(oauthInvalidGrant, "%v", err)
+	}
+	if grant.Caller != caller.Subject || grant.ClientID != clientID(client) {
+		return nil, oauthError(oauthInvalidGrant, "re-exchange token was issued to another client")
+	}
+	if grant.Actor == nil {
+		grant.Actor = trust.AnonymousResult()
+	}
+	return grant, nil
+}
+
+// reexchangeAudiences returns the audiences of a token issued for a re-exchange: the
+// requested audiences, which must have been granted, or all granted audiences
+func (s *ExchangeServer) reexchangeAudiences(grant *reexchange.Grant, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return grant.Audiences, nil
+	}
+	// No granted audiences means the original token was issued for the trust domain
+	granted := grant.Audiences
+	if len(granted) == 0 {
+		granted = []string{s.tokenService.TrustDomain()}
+	}
+	for _, audience := range requested {
+		if !slices.Contains(granted, audience) {
+			return nil, oauthError(oauthInvalidTarget, "audience %q was not granted by the re-exchange token", audience)
+		}
+	}
+	return requested, nil
+}
+
+// reexchangeScope returns the scope of a token issued for a re-exchange: the requested
+// scopes, which must have been granted, or all granted scopes
+func reexchangeScope(grant *reexchange.Grant, requested string) (string, error) {
+	scopes := scope.Parse(requested)
+	if len(scopes) == 0 {
+		return grant.Scope, nil
+	}
+	granted := scope.Parse(grant.Scope)
+	for _, requestedScope := range scopes {
+		if !slices.Contains(granted, requestedScope) {
+			return "", oauthError(oauthInvalidScope, "scope %q was not granted by the re-exchange token", requestedScope)
+		}
+	}
+	return scope.Format(scopes), nil
+}
+
+// clientID returns the ID of an authenticated client, or empty if there is none
+func clientID(client *clientauth.Client) string {
+	if client == nil {
+		return ""
+	}
+	return client.ID
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+)
+
+// RevocationServer implements the TokenRevocation gRPC service (RFC 7009)
+// Revoked tokens are added to a denylist by their jti claim, until they expire.
+// It revokes opaque tokens from the token store and JWTs signed by the issuers'
+// current public keys.
+type RevocationServer struct {
+	parsecv1.UnimplementedTokenRevocationServer
+
+	denylist            denylist.Denylist
+	store               tokenstore.Store
+	issuerRegistry      service.Registry
+	clientAuthenticator *clientauth.Authenticator
+	clock               clock.Clock
+}
+
+// RevocationServerConfig configures the revocation server
+type RevocationServerConfig struct {
+	// Denylist records revoked tokens
+	Denylist denylist.Denylist
+
+	// Store holds the records of issued opaque tokens, if any issuer issues them
+	Store tokenstore.Store
+
+	// IssuerRegistry provides the public keys JWTs are verified with before they are revoked
+	IssuerRegistry service.Registry
+
+	// ClientAuthenticator authenticates the clients calling the endpoint
+	// Required: RFC 7009 section 2.1 requires clients to authenticate.
+	ClientAuthenticator *clientauth.Authenticator
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// NewRevocationServer creates a new revocation server
+func NewRevocationServer(cfg RevocationServerConfig) *RevocationServer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &RevocationServer{
+		denylist:            cfg.Denylist,
+		store:               cfg.Store,
+		issuerRegistry:      cfg.IssuerRegistry,
+		clientAuthenticator: cfg.ClientAuthenticator,
+		clock:               clk,
+	}
+}
+
+// revocableToken is what the revocation server needs to know about a token
+type revocableToken struct {
+	id        string
+	clientID  string
+	expiresAt time.Time
+}
+
+// Revoke implements the revocation endpoint (RFC 7009)
+// Invalid and expired tokens succeed without effect (RFC 7009 section 2.2). Tokens
+// issued to another client, or to no known client, fail with unauthorized_client:
+// clients may only revoke their own tokens (RFC 7009 section 2.1).
+func (s *RevocationServer) Revoke(ctx context.Context, req *parsecv1.RevocationRequest) (*parsecv1.RevocationResponse, error) {
+	if s.clientAuthenticator == nil {
+		return nil, oauthError(oauthInvalidClient, "client authentication is not configured")
+	}
+	client, err := s.clientAuthenticator.Authenticate(ctx, clientCredentials(ctx, req))
+	if err != nil {
+		return nil, oauthError(oauthInvalidClient, "client authentication failed: %v", err)
+	}
+	if req.Token == "" {
+		return nil, oauthError(oauthInvalidRequest, "token is required")
+	}
+
+	token, err := s.lookup(ctx, req.Token)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to look up token: %v", err)
+	}
+	if token == nil || !s.clock.Now().Before(token.expiresAt) {
+		return &parsecv1.RevocationResponse{}, nil
+	}
+	if token.clientID == "" {
+		return nil, oauthError(oauthUnauthorizedClient, "token was not issued to a client")
+	}
+	if token.clientID != client.ID {
+		return nil, oauthError(oauthUnauthorizedClient, "token was not issued to client %s", client.ID)
+	}
+	if token.id == "" {
+		return nil, oauthError(oauthUnsupportedTokenType, "token has no jti claim")
+	}
+
+	if err := s.denylist.Deny(ctx, token.id, token.expiresAt); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke token: %v", err)
+	}
+	return &parsecv1.RevocationResponse{}, nil
+}
+
+// lookup returns the token if this instance issued it, or nil if it did not or the
+// token is invalid
+func (s *RevocationServer) lookup(ctx context.Context, value string) (*revocableToken, error) {
+	if s.store != nil {
+		record, err := s.store.Get(ctx, tokenstore.Key(value))
+		if err == nil {
+			id, _ := record.Claims["jti"].(string)
+			clientID, _ := record.Claims["client_id"].(string)
+			return &revocableToken{id: id, clientID: clientID, expiresAt: record.ExpiresAt}, nil
+		}
+		if !errors.Is(err, tokenstore.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	keySet, err := s.keySet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := jwt.Parse(
+		[]byte(value),
+		jwt.WithKeySet(keySet),
+		jwt.WithValidate(true),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithClock(jwt.ClockFunc(s.clock.Now)),
+	)
+	if err != nil {
+		return nil, nil
+	}
+
+	token := &revocableToken{id: parsed.JwtID(), expiresAt: parsed.Expiration()}
+	if clientID, ok := parsed.Get("client_id"); ok {
+		token.clientID, _ = clientID.(string)
+	}
+	return token, nil
+}
+
+// keySet returns the issuers' public keys as a JWK set
+// Keys that cannot be used are skipped; tokens they signed are not revocable.
+func (s *RevocationServer) keySet(ctx context.Context) (jwk.Set, error) {
+	if s.issuerRegistry == nil {
+		return jwk.NewSet(), nil
+	}
+
+	// Serve what is available when some issuers fail, like the JWKS endpoint
+	publicKeys, err := s.issuerRegistry.GetAllPublicKeys(ctx)
+	if len(publicKeys) == 0 && err != nil {
+		return nil, err
+	}
+	return publicKeySet(publicKeys), nil
+}
+
+// publicKeySet converts public keys to a JWK set, skipping keys that cannot be
+// represented as JWKs
+func publicKeySet(publicKeys []service.PublicKey) jwk.Set {
+	set := jwk.NewSet()
+	for _, publicKey := range publicKeys {
+		key, err := jwk.FromRaw(publicKey.Key)
+		if err != nil {
+			continue
+		}
+		if key.Set(jwk.KeyIDKey, publicKey.KeyID) != nil ||
+			key.Set(jwk.AlgorithmKey, jwa.SignatureAlgorithm(publicKey.Algorithm)) != nil {
+			continue
+		}
+		_ = set.AddKey(key)
+	}
+	return set
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestRevocationServer(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := tokenstore.NewMemoryStore(clk)
+	denied := denylist.NewMemoryDenylist(clk)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	opaqueIssuer := issuer.NewOpaqueIssuer(issuer.OpaqueIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TokenType: string(service.TokenTypeAccessToken),
+		TTL:       5 * time.Minute,
+		Store:     store,
+		Clock:     clk,
+	})
+	txnIssuer := issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+		Clock:     clk,
+	})
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeAccessToken, opaqueIssuer)
+	issuerRegistry.Register(service.TokenTypeTransactionToken, txnIssuer)
+
+	issue := func(t *testing.T, iss service.Issuer, clientID string) string {
+		t.Helper()
+		token, err := iss.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "user@example.com"},
+			Audiences:          []string{"parsec.test"},
+			ClientID:           clientID,
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+		return token.Value
+	}
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients: []*clientauth.Client{
+			{ID: "orders", Method: clientauth.MethodClientSecretBasic, Secret: "orders-secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	revocationServer := NewRevocationServer(RevocationServerConfig{
+		Denylist:            denied,
+		Store:               store,
+		IssuerRegistry:      issuerRegistry,
+		ClientAuthenticator: authenticator,
+		Clock:               clk,
+	})
+	introspectionServer := NewIntrospectionServer(IntrospectionServerConfig{
+		Store:               store,
+		Denylist:            denied,
+		ClientAuthenticator: authenticator,
+		Clock:               clk,
+	})
+
+	authCtx := metadata.NewIncomingContext(ctx, metadata.New(map[string]string{
+		"authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte("orders:orders-secret")),
+	}))
+
+	t.Run("revoked opaque token is inactive", func(t *testing.T) {
+		token := issue(t, opaqueIssuer, "orders")
+
+		if _, err := revocationServer.Revoke(authCtx, &parsecv1.RevocationRequest{Token: token}); err != nil {
+			t.Fatalf("revocation failed: %v", err)
+		}
+
+		resp, err := introspectionServer.Introspect(authCtx, &parsecv1.IntrospectionRequest{Token: token})
+		if err != nil {
+			t.Fatalf("introspection failed: %v", err)
+		}
+		if fields := resp.AsMap(); fields["active"] != false {
+			t.Errorf("expected revoked token to be inactive, got %v", fields)
+		}
+	})
+
+	t.Run("revokes jwts by jti", func(t *testing.T) {
+		token := issue(t, txnIssuer, "orders")
+
+		if _, err := revocationServer.Revoke(authCtx, &parsecv1.RevocationRequest{Token: token}); err != nil {
+			t.Fatalf("revocation failed: %v", err)
+		}
+
+		lookedUp, err := revocationServer.lookup(ctx, token)
+		if err != nil || lookedUp == nil {
+			t.Fatalf("expected the token to be found, got %v, %v", lookedUp, err)
+		}
+		isDenied, err := denied.IsDenied(ctx, lookedUp.id)
+		if err != nil {
+			t.Fatalf("failed to check denylist: %v", err)
+		}
+		if !isDenied {
+			t.Errorf("expected jti %s to be denied", lookedUp.id)
+		}
+	})
+
+	t.Run("unknown token succeeds", func(t *testing.T) {
+		if _, err := revocationServer.Revoke(authCtx, &parsecv1.RevocationRequest{Token: "unknown"}); err != nil {
+			t.Errorf("expected unknown tokens to be ignored, got %v", err)
+		}
+	})
+
+	t.Run("rejects tokens issued to other clients as unauthorized_client", func(t *testing.T) {
+		token := issue(t, opaqueIssuer, "billing")
+
+		_, err := revocationServer.Revoke(authCtx, &parsecv1.RevocationRequest{Token: token})
+		if !strings.Contains(err.Error(), "unauthorized_client") {
+			t.Errorf("expected unauthorized_client, got %v", err)
+		}
+
+		resp, err := introspectionServer.Introspect(authCtx, &parsecv1.IntrospectionRequest{Token: token})
+		if err != nil {
+			t.Fatalf("introspection failed: %v", err)
+		}
+		if fields := resp.AsMap(); fields["active"] != true {
+			t.Errorf("expected token to remain active, got %v", fields)
+		}
+	})
+
+	t.Run("rejects tokens issued to no client as unauthorized_client", func(t *testing.T) {
+		for name, iss := range map[string]service.Issuer{"opaque": opaqueIssuer, "jwt": txnIssuer} {
+			token := issue(t, iss, "")
+
+			_, err := revocationServer.Revoke(authCtx, &parsecv1.RevocationRequest{Token: token})
+			if status.Code(err) == codes.OK || !strings.Contains(err.Error(), "unauthorized_client") {
+				t.Errorf("%s: expected unauthorized_client, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("rejects unauthenticated clients as invalid_client", func(t *testing.T) {
+		token := issue(t, opaqueIssuer, "orders")
+
+		_, err := revocationServer.Revoke(ctx, &parsecv1.RevocationRequest{Token: token})
+		if status.Code(err) != codes.Unauthenticated || !strings.Contains(err.Error(), "invalid_client") {
+			t.Errorf("expected invalid_client, got %v", err)
+		}
+	})
+
+	t.Run("requires a token", func(t *testing.T) {
+		_, err := revocationServer.Revoke(authCtx, &parsecv1.RevocationRequest{})
+		if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), "invalid_request") {
+			t.Errorf("expected invalid_request, got %v", err)
+		}
+	})
+}
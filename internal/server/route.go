@@ -0,0 +1,155 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// Envoy context_extensions keys that configure ext_authz for a route
+// Set them in the route's (or virtual host's) ext_authz per-filter config, e.g.:
+//
+//	typed_per_filter_config:
+//	  envoy.filters.http.ext_authz:
+//	    "@type": type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthzPerRoute
+//	    check_settings:
+//	      context_extensions:
+//	        parsec.token_types: "urn:ietf:params:oauth:token-type:txn_token=Transaction-Token"
+//	        parsec.required_scopes: "orders:read"
+//	        parsec.validators: "corporate-idp"
+//	        parsec.trust_domain: "partners.example.com"
+const (
+	// ContextExtensionTokenTypes lists the token types to issue for the route, comma-separated
+	// Each entry is a token type, optionally followed by =Header-Name to deliver it in that
+	// header. Without a header, the type is delivered as configured for the server.
+	ContextExtensionTokenTypes = "parsec.token_types"
+
+	// ContextExtensionRequiredScopes lists scopes, space-separated, the subject's credential
+	// must have for the request to be allowed
+	ContextExtensionRequiredScopes = "parsec.required_scopes"
+
+	// ContextExtensionValidators lists the validators, comma-separated, that may validate
+	// the subject's credential
+	ContextExtensionValidators = "parsec.validators"
+
+	// ContextExtensionValidationCache set to "false" makes the route validate every
+	// credential, even if the server has a ValidationCache
+	ContextExtensionValidationCache = "parsec.validation_cache"
+
+	// ContextExtensionTrustDomain selects the trust domain that issues the route's tokens,
+	// which also limits the validators to those of the trust domain
+	ContextExtensionTrustDomain = "parsec.trust_domain"
+)
+
+// routeConfig is the ext_authz configuration for the route of one request
+type routeConfig struct {
+	tokenTypes     []TokenTypeSpec
+	requiredScopes []string
+	// validators is nil if the route allows every validator
+	validators []string
+	// cacheValidation is whether the route may use the server's ValidationCache
+	cacheValidation bool
+	// trustDomain is empty for the token service's own trust domain
+	trustDomain string
+}
+
+// resolveRoute returns the configuration for the request's route: the server's,
+// overridden by any of the route's context extensions
+func (s *AuthzServer) resolveRoute(req *authv3.CheckRequest) (*routeConfig, error) {
+	extensions := req.GetAttributes().GetContextExtensions()
+	route := &routeConfig{tokenTypes: s.TokenTypesToIssue, cacheValidation: true}
+
+	if value, ok := extensions[ContextExtensionTokenTypes]; ok {
+		tokenTypes, err := s.parseRouteTokenTypes(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ContextExtensionTokenTypes, err)
+		}
+		route.tokenTypes = tokenTypes
+	}
+
+	route.requiredScopes = strings.Fields(extensions[ContextExtensionRequiredScopes])
+
+	if value, ok := extensions[ContextExtensionValidators]; ok {
+		route.validators = splitList(value)
+		if len(route.validators) == 0 {
+			return nil, fmt.Errorf("invalid %s: no validators", ContextExtensionValidators)
+		}
+	}
+
+	if value, ok := extensions[ContextExtensionValidationCache]; ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", ContextExtensionValidationCache, err)
+		}
+		route.cacheValidation = enabled
+	}
+
+	if value, ok := extensions[ContextExtensionTrustDomain]; ok {
+		route.trustDomain = strings.TrimSpace(value)
+		if route.trustDomain == "" {
+			return nil, fmt.Errorf("invalid %s: no trust domain", ContextExtensionTrustDomain)
+		}
+	}
+
+	return route, nil
+}
+
+// parseRouteTokenTypes parses the value of ContextExtensionTokenTypes
+func (s *AuthzServer) parseRouteTokenTypes(value string) ([]TokenTypeSpec, error) {
+	entries := splitList(value)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no token types")
+	}
+
+	specs := make([]TokenTypeSpec, 0, len(entries))
+	for _, entry := range entries {
+		tokenType, header, hasHeader := strings.Cut(entry, "=")
+		tokenType = strings.TrimSpace(tokenType)
+		header = strings.TrimSpace(header)
+
+		// Start from the server's delivery for this type, so cookies are kept
+		spec := TokenTypeSpec{Type: service.TokenType(tokenType)}
+		if i := slices.IndexFunc(s.TokenTypesToIssue, func(t TokenTypeSpec) bool { return t.Type == spec.Type }); i >= 0 {
+			spec = s.TokenTypesToIssue[i]
+		}
+
+		if hasHeader {
+			if header == "" {
+				return nil, fmt.Errorf("token type %s has an empty header name", tokenType)
+			}
+			spec.HeaderName = header
+		}
+		if spec.HeaderName == "" && spec.Cookie == nil {
+			return nil, fmt.Errorf("token type %s needs a header name", tokenType)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// hasScopes reports whether scope, a space-separated list, includes every one of required
+func hasScopes(scope string, required []string) bool {
+	granted := strings.Fields(scope)
+	for _, s := range required {
+		if !slices.Contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
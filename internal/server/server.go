@@ -1,14 +1,22 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"time"
 
+	authv2 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v2"
 	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 
@@ -20,12 +28,22 @@ type Server struct {
 	grpcServer *grpc.Server
 	httpServer *http.Server
 
-	grpcPort int
-	httpPort int
+	grpcPort        int
+	httpPort        int
+	tls             *TLSConfig
+	shutdownTimeout time.Duration
+	inFlight        *inFlightRequests
 
-	authzServer    *AuthzServer
-	exchangeServer *ExchangeServer
-	jwksServer     *JWKSServer
+	authzServer         *AuthzServer
+	exchangeServer      *ExchangeServer
+	jwksServer          *JWKSServer
+	discoveryServer     *DiscoveryServer
+	adminServer         *AdminServer
+	introspectionServer *IntrospectionServer
+	revocationServer    *RevocationServer
+	verifyServer        *VerifyServer
+	claimsServer        *ClaimsServer
+	previewServer       *PreviewServer
 }
 
 // Config contains server configuration
@@ -33,31 +51,101 @@ type Config struct {
 	GRPCPort int
 	HTTPPort int
 
+	// TLS, if set, serves gRPC over TLS (and mTLS, with a client CA)
+	TLS *TLSConfig
+
+	// ShutdownTimeout is how long Stop waits for in-flight requests to finish
+	// before closing their connections (default: DefaultShutdownTimeout)
+	ShutdownTimeout time.Duration
+
 	AuthzServer    *AuthzServer
 	ExchangeServer *ExchangeServer
 	JWKSServer     *JWKSServer
+
+	// DiscoveryServer is optional; token type discovery is not served if nil
+	DiscoveryServer *DiscoveryServer
+
+	// AdminServer is optional; the admin API is not served if nil
+	AdminServer *AdminServer
+
+	// IntrospectionServer is optional; token introspection is not served if nil
+	IntrospectionServer *IntrospectionServer
+
+	// RevocationServer is optional; token revocation is not served if nil
+	RevocationServer *RevocationServer
+
+	// VerifyServer is optional; token verification is not served if nil
+	VerifyServer *VerifyServer
+
+	// ClaimsServer is optional; referenced claims are not served if nil
+	ClaimsServer *ClaimsServer
+
+	// PreviewServer is optional; token previews are not served if nil
+	PreviewServer *PreviewServer
 }
 
 // New creates a new server with the given configuration
 func New(cfg Config) *Server {
+	shutdownTimeout := cfg.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+
 	return &Server{
-		grpcPort:       cfg.GRPCPort,
-		httpPort:       cfg.HTTPPort,
-		authzServer:    cfg.AuthzServer,
-		exchangeServer: cfg.ExchangeServer,
-		jwksServer:     cfg.JWKSServer,
+		grpcPort:            cfg.GRPCPort,
+		httpPort:            cfg.HTTPPort,
+		tls:                 cfg.TLS,
+		shutdownTimeout:     shutdownTimeout,
+		inFlight:            newInFlightRequests(),
+		authzServer:         cfg.AuthzServer,
+		exchangeServer:      cfg.ExchangeServer,
+		jwksServer:          cfg.JWKSServer,
+		discoveryServer:     cfg.DiscoveryServer,
+		adminServer:         cfg.AdminServer,
+		introspectionServer: cfg.IntrospectionServer,
+		revocationServer:    cfg.RevocationServer,
+		verifyServer:        cfg.VerifyServer,
+		claimsServer:        cfg.ClaimsServer,
+		previewServer:       cfg.PreviewServer,
 	}
 }
 
 // Start starts both the gRPC and HTTP servers
 func (s *Server) Start(ctx context.Context) error {
 	// Create gRPC server
-	s.grpcServer = grpc.NewServer()
+	grpcOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(s.inFlight.unaryInterceptor, traceContextInterceptor, requestLoggingInterceptor)}
+	dialCreds := insecure.NewCredentials()
+	var tlsConfig *tls.Config
+	if s.tls != nil {
+		var err error
+		tlsConfig, err = s.tls.serverConfig()
+		if err != nil {
+			return fmt.Errorf("failed to configure TLS: %w", err)
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		// The gateway dials its own process over loopback; the server certificate
+		// is issued for the service's external names, not localhost
+		dialCreds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	}
+	s.grpcServer = grpc.NewServer(grpcOpts...)
 
 	// Register services
 	authv3.RegisterAuthorizationServer(s.grpcServer, s.authzServer)
+	authv2.RegisterAuthorizationServer(s.grpcServer, NewAuthzServerV2(s.authzServer))
 	parsecv1.RegisterTokenExchangeServer(s.grpcServer, s.exchangeServer)
 	parsecv1.RegisterJWKSServer(s.grpcServer, s.jwksServer)
+	if s.discoveryServer != nil {
+		parsecv1.RegisterDiscoveryServer(s.grpcServer, s.discoveryServer)
+	}
+	if s.adminServer != nil {
+		parsecv1.RegisterAdminServer(s.grpcServer, s.adminServer)
+	}
+	if s.introspectionServer != nil {
+		parsecv1.RegisterTokenIntrospectionServer(s.grpcServer, s.introspectionServer)
+	}
+	if s.revocationServer != nil {
+		parsecv1.RegisterTokenRevocationServer(s.grpcServer, s.revocationServer)
+	}
 
 	// Register reflection service for grpcurl and other tools
 	reflection.Register(s.grpcServer)
@@ -69,18 +157,23 @@ func (s *Server) Start(ctx context.Context) error {
 	}
 
 	go func() {
-		fmt.Printf("gRPC server listening on :%d\n", s.grpcPort)
+		slog.Info("gRPC server listening", "port", s.grpcPort)
 		if err := s.grpcServer.Serve(grpcListener); err != nil {
-			fmt.Printf("gRPC server error: %v\n", err)
+			slog.Error("gRPC server failed", "error", err)
 		}
 	}()
 
 	// Create HTTP server with grpc-gateway
-	// Register custom marshaler for application/x-www-form-urlencoded (RFC 8693 compliance)
+	// Register custom marshaler for application/x-www-form-urlencoded (RFC 8693 compliance),
+	// accept single values for repeated fields in JSON, and write token exchange errors as OAuth error responses (RFC 6749)
+	// Forward trace context so exchanges over HTTP continue the caller's trace
 	mux := runtime.NewServeMux(
 		runtime.WithMarshalerOption("application/x-www-form-urlencoded", NewFormMarshaler()),
+		runtime.WithMarshalerOption("application/json", NewJSONMarshaler()),
+		runtime.WithErrorHandler(OAuthErrorHandler),
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
 	)
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(dialCreds)}
 
 	// Register HTTP handlers (transcoding from gRPC)
 	endpoint := fmt.Sprintf("localhost:%d", s.grpcPort)
@@ -90,32 +183,160 @@ func (s *Server) Start(ctx context.Context) error {
 	if err := parsecv1.RegisterJWKSHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
 		return fmt.Errorf("failed to register JWKS handler: %w", err)
 	}
+	if s.discoveryServer != nil {
+		if err := parsecv1.RegisterDiscoveryHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+			return fmt.Errorf("failed to register discovery handler: %w", err)
+		}
+	}
+	if s.adminServer != nil {
+		if err := parsecv1.RegisterAdminHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+			return fmt.Errorf("failed to register admin handler: %w", err)
+		}
+	}
+	if s.introspectionServer != nil {
+		if err := parsecv1.RegisterTokenIntrospectionHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+			return fmt.Errorf("failed to register introspection handler: %w", err)
+		}
+	}
+	if s.revocationServer != nil {
+		if err := parsecv1.RegisterTokenRevocationHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+			return fmt.Errorf("failed to register revocation handler: %w", err)
+		}
+	}
+
+	// Serve the ext_authz check to proxies that call out over plain HTTP
+	forwardAuth := NewForwardAuthHandler(s.authzServer)
+	for _, method := range forwardAuthMethods {
+		for _, pattern := range []string{ForwardAuthPath, forwardAuthPrefixPattern} {
+			if err := mux.HandlePath(method, pattern, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+				forwardAuth.ServeHTTP(w, r)
+			}); err != nil {
+				return fmt.Errorf("failed to register forward-auth handler: %w", err)
+			}
+		}
+	}
+
+	if s.verifyServer != nil {
+		if err := mux.HandlePath(http.MethodPost, VerifyPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			s.verifyServer.ServeHTTP(w, r)
+		}); err != nil {
+			return fmt.Errorf("failed to register verify handler: %w", err)
+		}
+	}
+
+	if s.claimsServer != nil {
+		if err := mux.HandlePath(http.MethodGet, ClaimsPath+"{handle}", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			s.claimsServer.ServeHTTP(w, r)
+		}); err != nil {
+			return fmt.Errorf("failed to register claims handler: %w", err)
+		}
+	}
+
+	if s.previewServer != nil {
+		if err := mux.HandlePath(http.MethodPost, PreviewPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			s.previewServer.ServeHTTP(w, r)
+		}); err != nil {
+			return fmt.Errorf("failed to register preview handler: %w", err)
+		}
+	}
 
-	// Start HTTP server
+	// Start HTTP server, over TLS with the same certificate as gRPC if configured
 	s.httpServer = &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.httpPort),
-		Handler: mux,
+		Handler: s.inFlight.handler(requestLoggingHandler(s.limitTokenRequestBody(mux))),
+	}
+	if tlsConfig != nil {
+		s.httpServer.TLSConfig = tlsConfig.Clone()
 	}
 
 	go func() {
-		fmt.Printf("HTTP server (grpc-gateway) listening on :%d\n", s.httpPort)
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			fmt.Printf("HTTP server error: %v\n", err)
+		slog.Info("HTTP server (grpc-gateway) listening", "port", s.httpPort)
+		var err error
+		if s.httpServer.TLSConfig != nil {
+			// The certificate comes from TLSConfig.GetCertificate
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("HTTP server failed", "error", err)
 		}
 	}()
 
 	return nil
 }
 
-// Stop gracefully stops both servers
+// Stop stops accepting connections and waits for in-flight requests to finish,
+// up to the shutdown timeout or until ctx is done. Requests still in flight then
+// are cut off and reported in a *ShutdownError.
 func (s *Server) Stop(ctx context.Context) error {
-	if s.grpcServer != nil {
-		s.grpcServer.GracefulStop()
+	ctx, cancel := context.WithTimeout(ctx, s.shutdownTimeout)
+	defer cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		// HTTP first: the gateway serves its requests with calls to the gRPC server
+		if s.httpServer != nil {
+			_ = s.httpServer.Shutdown(context.Background())
+		}
+		if s.grpcServer != nil {
+			s.grpcServer.GracefulStop()
+		}
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
 	}
 
+	cutOff := s.inFlight.snapshot()
 	if s.httpServer != nil {
-		return s.httpServer.Shutdown(ctx)
+		_ = s.httpServer.Close()
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
 	}
+	<-drained
 
-	return nil
+	if len(cutOff) == 0 {
+		return nil
+	}
+	return &ShutdownError{Timeout: s.shutdownTimeout, InFlight: cutOff}
+}
+
+// tokenExchangePath is the HTTP path of the token exchange endpoint
+const tokenExchangePath = "/v1/token"
+
+// limitTokenRequestBody rejects token exchange and preview requests with bodies larger
+// than the exchange server's MaxRequestBytes with HTTP 413, before the gateway decodes them
+func (s *Server) limitTokenRequestBody(next http.Handler) http.Handler {
+	if s.exchangeServer == nil || s.exchangeServer.MaxRequestBytes <= 0 {
+		return next
+	}
+	maxBytes := s.exchangeServer.MaxRequestBytes
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tokenExchangePath && r.URL.Path != PreviewPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if !errors.As(err, &tooLarge) {
+				http.Error(w, "failed to read request body", http.StatusBadRequest)
+				return
+			}
+			writeNoStoreJSON(w, http.StatusRequestEntityTooLarge, oauthErrorResponse{
+				Error:            oauthInvalidRequest,
+				ErrorDescription: fmt.Sprintf("request body exceeds %d bytes", maxBytes),
+			})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
 }
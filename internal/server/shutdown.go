@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultShutdownTimeout is how long Stop waits for in-flight requests by default
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ShutdownError reports the requests still in flight when the shutdown timeout
+// ran out, which were cut off when their connections were closed
+type ShutdownError struct {
+	// Timeout is how long in-flight requests were given to finish
+	Timeout time.Duration
+
+	// InFlight counts the requests cut off, by gRPC method or HTTP method and path
+	// Requests served through the HTTP gateway are counted under both.
+	InFlight map[string]int
+}
+
+func (e *ShutdownError) Error() string {
+	methods := make([]string, 0, len(e.InFlight))
+	total := 0
+	for method, n := range e.InFlight {
+		methods = append(methods, fmt.Sprintf("%s (%d)", method, n))
+		total += n
+	}
+	sort.Strings(methods)
+	return fmt.Sprintf("shutdown timed out after %s with %d requests in flight: %s",
+		e.Timeout, total, strings.Join(methods, ", "))
+}
+
+// inFlightRequests counts the requests being served, by method
+type inFlightRequests struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newInFlightRequests() *inFlightRequests {
+	return &inFlightRequests{requests: make(map[string]int)}
+}
+
+// begin records a request to method, returning a func to call when it is done
+func (f *inFlightRequests) begin(method string) func() {
+	f.mu.Lock()
+	f.requests[method]++
+	f.mu.Unlock()
+
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.requests[method]--; f.requests[method] == 0 {
+			delete(f.requests, method)
+		}
+	}
+}
+
+// snapshot returns a copy of the in-flight request counts
+func (f *inFlightRequests) snapshot() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requests := make(map[string]int, len(f.requests))
+	for method, n := range f.requests {
+		requests[method] = n
+	}
+	return requests
+}
+
+// unaryInterceptor counts gRPC calls while they are served
+func (f *inFlightRequests) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	defer f.begin(info.FullMethod)()
+	return handler(ctx, req)
+}
+
+// handler counts HTTP requests while next serves them
+func (f *inFlightRequests) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer f.begin(r.Method + " " + r.URL.Path)()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServerStop(t *testing.T) {
+	// serve starts s's HTTP server with a handler that takes delay to respond
+	serve := func(t *testing.T, s *Server, delay time.Duration) (started <-chan struct{}, done <-chan error) {
+		t.Helper()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		startedCh := make(chan struct{})
+		s.httpServer = &http.Server{Handler: s.inFlight.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(startedCh)
+			time.Sleep(delay)
+			w.WriteHeader(http.StatusOK)
+		}))}
+		go func() { _ = s.httpServer.Serve(listener) }()
+
+		doneCh := make(chan error, 1)
+		go func() {
+			resp, err := http.Get("http://" + listener.Addr().String() + "/v1/token")
+			if err == nil {
+				resp.Body.Close()
+			}
+			doneCh <- err
+		}()
+		return startedCh, doneCh
+	}
+
+	t.Run("drains in-flight requests", func(t *testing.T) {
+		s := New(Config{ShutdownTimeout: 5 * time.Second})
+		started, done := serve(t, s, 100*time.Millisecond)
+		<-started
+
+		if err := s.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop failed: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("expected in-flight request to complete, got %v", err)
+		}
+	})
+
+	t.Run("reports requests cut off", func(t *testing.T) {
+		s := New(Config{ShutdownTimeout: 50 * time.Millisecond})
+		started, done := serve(t, s, time.Second)
+		<-started
+
+		err := s.Stop(context.Background())
+		var shutdownErr *ShutdownError
+		if !errors.As(err, &shutdownErr) {
+			t.Fatalf("expected ShutdownError, got %v", err)
+		}
+		if shutdownErr.InFlight["GET /v1/token"] != 1 {
+			t.Errorf("expected the request to be reported, got %v", shutdownErr.InFlight)
+		}
+		if err := <-done; err == nil {
+			t.Error("expected the request to be cut off")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// writeTestServerCertificate writes a self-signed certificate and key for commonName
+func writeTestServerCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func TestTLSConfig_ReloadsRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeTestServerCertificate(t, certFile, keyFile, "parsec-1")
+
+	tlsConfig, err := TLSConfig{CertFile: certFile, KeyFile: keyFile}.serverConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tlsConfig.ClientAuth != 0 {
+		t.Errorf("expected no client authentication without a client CA, got %v", tlsConfig.ClientAuth)
+	}
+
+	commonName := func() string {
+		t.Helper()
+		cert, err := tlsConfig.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		return leaf.Subject.CommonName
+	}
+
+	if got := commonName(); got != "parsec-1" {
+		t.Fatalf("expected parsec-1, got %s", got)
+	}
+
+	writeTestServerCertificate(t, certFile, keyFile, "parsec-2")
+	// Make the rotation visible even on filesystems with coarse modification times
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, later, later); err != nil {
+		t.Fatalf("failed to touch certificate: %v", err)
+	}
+
+	if got := commonName(); got != "parsec-2" {
+		t.Errorf("expected rotated certificate parsec-2, got %s", got)
+	}
+}
+
+func TestTLSConfig_ClientCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeTestServerCertificate(t, certFile, keyFile, "parsec")
+
+	caCert, _ := newTestClientCertificate(t, "spiffe://cluster.local/ns/istio-system/sa/istio-ingressgateway")
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw}), 0o600); err != nil {
+		t.Fatalf("failed to write CA: %v", err)
+	}
+
+	tlsConfig, err := TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}.serverConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tlsConfig.ClientCAs == nil {
+		t.Error("expected client CAs")
+	}
+
+	if _, err := (TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile + ".missing"}).serverConfig(); err == nil {
+		t.Error("expected error for missing client CA file")
+	}
+	if _, err := (TLSConfig{CertFile: keyFile, KeyFile: keyFile}).serverConfig(); err == nil {
+		t.Error("expected error for invalid certificate")
+	}
+}
+
+func TestTLSPeerContext(t *testing.T) {
+	cert, _ := newTestClientCertificate(t, "spiffe://cluster.local/ns/ingress/sa/nginx")
+
+	req := httptest.NewRequest(http.MethodGet, ForwardAuthPath, nil)
+	if cred, err := extractActorCredential(tlsPeerContext(req)); err != nil || cred != nil {
+		t.Fatalf("expected no actor credential without TLS, got %v, %v", cred, err)
+	}
+
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	cred, err := extractActorCredential(tlsPeerContext(req))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mtls, ok := cred.(*trust.MTLSCredential)
+	if !ok || !bytes.Equal(mtls.Certificate, cert.Raw) {
+		t.Errorf("expected the client certificate as the actor credential, got %#v", cred)
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// traceContext propagates W3C trace context (the traceparent and tracestate headers)
+var traceContext = propagation.TraceContext{}
+
+// traceContextInterceptor continues the trace a caller propagated in gRPC metadata,
+// so spans parsec records are part of it
+func traceContextInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = traceContext.Extract(ctx, metadataCarrier(md))
+	}
+	return handler(ctx, req)
+}
+
+// gatewayHeaderMatcher forwards trace context headers from HTTP requests to the gRPC
+// services behind the gateway, along with the headers the gateway forwards by default
+func gatewayHeaderMatcher(key string) (string, bool) {
+	for _, field := range traceContext.Fields() {
+		if strings.EqualFold(key, field) {
+			return field, true
+		}
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// withTraceParent continues the trace of the request being checked, from its headers,
+// unless ctx already continues one (the proxy's own call to parsec is more specific)
+func withTraceParent(ctx context.Context, headers map[string]string) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return traceContext.Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// metadataCarrier adapts gRPC metadata to propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testTraceParent  = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	testTraceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+	proxyTraceParent = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	proxyTraceID     = "0af7651916cd43dd8448eb211c80319c"
+)
+
+func TestTraceContextPropagation(t *testing.T) {
+	traceID := func(ctx context.Context) string {
+		return trace.SpanContextFromContext(ctx).TraceID().String()
+	}
+
+	t.Run("gRPC metadata", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", proxyTraceParent))
+		var got context.Context
+		_, _ = traceContextInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+			got = ctx
+			return nil, nil
+		})
+		if traceID(got) != proxyTraceID {
+			t.Errorf("expected trace %s, got %s", proxyTraceID, traceID(got))
+		}
+	})
+
+	t.Run("checked request headers", func(t *testing.T) {
+		ctx := withTraceParent(context.Background(), map[string]string{"traceparent": testTraceParent})
+		if traceID(ctx) != testTraceID {
+			t.Errorf("expected trace %s, got %s", testTraceID, traceID(ctx))
+		}
+	})
+
+	t.Run("proxy trace takes precedence", func(t *testing.T) {
+		ctx := traceContext.Extract(context.Background(), metadataCarrier(metadata.Pairs("traceparent", proxyTraceParent)))
+		ctx = withTraceParent(ctx, map[string]string{"traceparent": testTraceParent})
+		if traceID(ctx) != proxyTraceID {
+			t.Errorf("expected trace %s, got %s", proxyTraceID, traceID(ctx))
+		}
+	})
+
+	t.Run("gateway forwards trace headers", func(t *testing.T) {
+		if key, ok := gatewayHeaderMatcher("Traceparent"); !ok || key != "traceparent" {
+			t.Errorf("expected traceparent to be forwarded, got %q %v", key, ok)
+		}
+		if key, ok := gatewayHeaderMatcher("Authorization"); !ok || key != "grpcgateway-Authorization" {
+			t.Errorf("expected default matching of other headers, got %q %v", key, ok)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// ValidationCacheConfig configures a ValidationCache
+type ValidationCacheConfig struct {
+	// TTL is how long a validation is remembered; keep it short, since a token revoked
+	// or a key removed at its issuer is still accepted until its entries expire
+	TTL time.Duration
+
+	// MaxEntries bounds the number of remembered validations (default: 10000)
+	MaxEntries int
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// ValidationCache remembers successful subject validations for a short time, so ext_authz
+// does not re-validate the same credential for each request of a burst
+// Entries are keyed by a hash of the credential, the actor, the validators the request
+// may be validated with, and the route's context extensions, and never outlive the
+// credential.
+type ValidationCache struct {
+	ttl        time.Duration
+	maxEntries int
+	clock      clock.Clock
+
+	mu        sync.Mutex
+	entries   map[string]validationCacheEntry
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+type validationCacheEntry struct {
+	result    *trust.Result
+	expiresAt time.Time
+}
+
+// defaultValidationCacheMaxEntries is the default ValidationCacheConfig.MaxEntries
+const defaultValidationCacheMaxEntries = 10000
+
+// NewValidationCache creates a new validation cache
+func NewValidationCache(cfg ValidationCacheConfig) *ValidationCache {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	maxEntries := cfg.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultValidationCacheMaxEntries
+	}
+
+	return &ValidationCache{
+		ttl:        cfg.TTL,
+		maxEntries: maxEntries,
+		clock:      clk,
+		entries:    make(map[string]validationCacheEntry),
+	}
+}
+
+// validationCacheKey returns the cache key for validating cred on behalf of actor, with
+// validators, for a route, or "" if the credential is not cacheable
+// Validators are filtered per request, such as by path, so a validation is reused only
+// with the same validators.
+func validationCacheKey(cred trust.Credential, actor *trust.Result, validators []string, contextExtensions map[string]string) string {
+	var secret string
+	switch c := cred.(type) {
+	case *trust.BearerCredential:
+		secret = c.Token
+	case *trust.APIKeyCredential:
+		secret = c.Header + "\x00" + c.Key
+	default:
+		return ""
+	}
+
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	write(string(cred.Type()))
+	write(secret)
+	write(actor.TrustDomain)
+	write(actor.Subject)
+	write(strconv.Itoa(len(validators)))
+	for _, validator := range validators {
+		write(validator)
+	}
+
+	names := make([]string, 0, len(contextExtensions))
+	for name := range contextExtensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		write(name)
+		write(contextExtensions[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the remembered result for key, if it has not expired
+func (c *ValidationCache) get(key string) (*trust.Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	if !c.clock.Now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return entry.result, true
+}
+
+// put remembers result for key until the TTL passes or the result expires
+func (c *ValidationCache) put(key string, result *trust.Result) {
+	now := c.clock.Now()
+	expiresAt := now.Add(c.ttl)
+	if !result.ExpiresAt.IsZero() && result.ExpiresAt.Before(expiresAt) {
+		expiresAt = result.ExpiresAt
+	}
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	// Still full: evict an arbitrary entry rather than grow
+	if len(c.entries) >= c.maxEntries {
+		for k := range c.entries {
+			delete(c.entries, k)
+			c.evictions++
+			break
+		}
+	}
+	c.entries[key] = validationCacheEntry{result: result, expiresAt: expiresAt}
+}
+
+// CacheStats implements service.CacheStatsReporter
+func (c *ValidationCache) CacheStats() service.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return service.CacheStats{
+		Entries:   int64(len(c.entries)),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+// Purge forgets all remembered validations, such as when validators are reconfigured
+func (c *ValidationCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
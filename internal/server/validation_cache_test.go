@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc/codes"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestValidationCache(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	cache := NewValidationCache(ValidationCacheConfig{TTL: 10 * time.Second, MaxEntries: 2, Clock: clk})
+
+	result := &trust.Result{Subject: "alice"}
+	cache.put("a", result)
+	if got, ok := cache.get("a"); !ok || got != result {
+		t.Fatalf("expected cached result, got %v, %v", got, ok)
+	}
+
+	clk.Advance(10 * time.Second)
+	if _, ok := cache.get("a"); ok {
+		t.Error("expected entry to expire after the TTL")
+	}
+
+	// Entries never outlive the credential
+	cache.put("b", &trust.Result{Subject: "bob", ExpiresAt: clk.Now().Add(time.Second)})
+	clk.Advance(time.Second)
+	if _, ok := cache.get("b"); ok {
+		t.Error("expected entry to expire with the credential")
+	}
+	cache.put("c", &trust.Result{Subject: "carol", ExpiresAt: clk.Now()})
+	if _, ok := cache.get("c"); ok {
+		t.Error("expected expired credential not to be cached")
+	}
+
+	for _, key := range []string{"d", "e", "f"} {
+		cache.put(key, result)
+	}
+	if len(cache.entries) > 2 {
+		t.Errorf("expected at most 2 entries, got %d", len(cache.entries))
+	}
+}
+
+func TestValidationCacheKey(t *testing.T) {
+	actor := &trust.Result{Subject: "envoy", TrustDomain: "mesh"}
+	bearer := &trust.BearerCredential{Token: "token-1"}
+	validators := []string{"idp"}
+	route := map[string]string{"parsec.required_scopes": "read"}
+
+	key := validationCacheKey(bearer, actor, validators, route)
+	if key == "" {
+		t.Fatal("expected bearer credential to be cacheable")
+	}
+
+	tests := []struct {
+		name       string
+		cred       trust.Credential
+		actor      *trust.Result
+		validators []string
+		route      map[string]string
+	}{
+		{name: "other token", cred: &trust.BearerCredential{Token: "token-2"}, actor: actor, validators: validators, route: route},
+		{name: "other actor", cred: bearer, actor: &trust.Result{Subject: "gateway", TrustDomain: "mesh"}, validators: validators, route: route},
+		{name: "other validators", cred: bearer, actor: actor, validators: []string{"idp", "admin-idp"}, route: route},
+		{name: "no validators", cred: bearer, actor: actor, route: route},
+		{name: "other route", cred: bearer, actor: actor, validators: validators, route: map[string]string{"parsec.required_scopes": "write"}},
+		{name: "API key with same secret", cred: &trust.APIKeyCredential{Key: "token-1"}, actor: actor, validators: validators, route: route},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if other := validationCacheKey(tt.cred, tt.actor, tt.validators, tt.route); other == key {
+				t.Error("expected a different key")
+			}
+		})
+	}
+
+	if got := validationCacheKey(&trust.SAMLCredential{Assertion: []byte("<Assertion/>")}, actor, validators, route); got != "" {
+		t.Errorf("expected SAML credential not to be cacheable, got key %s", got)
+	}
+}
+
+func TestAuthzServer_ValidationCache(t *testing.T) {
+	ctx := context.Background()
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	newRequest := func(contextExtensions map[string]string) *authv3.CheckRequest {
+		return &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method: "GET",
+						Path:   "/app",
+						Headers: map[string]string{
+							"authorization": "Bearer valid-token",
+						},
+					},
+				},
+				ContextExtensions: contextExtensions,
+			},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		contextExtensions map[string]string
+		wantSecondCode    codes.Code
+		wantSecondProbes  []any
+	}{
+		{
+			name:           "repeated credential reuses validation",
+			wantSecondCode: codes.OK,
+			wantSecondProbes: []any{
+				"RequestAttributesParsed",
+				"ActorValidationSucceeded",
+				"SubjectCredentialExtracted",
+				"SubjectValidationCacheHit",
+				"SubjectValidationSucceeded",
+				"CheckAllowed",
+				"End",
+			},
+		},
+		{
+			name:              "route opts out",
+			contextExtensions: map[string]string{ContextExtensionValidationCache: "false"},
+			wantSecondCode:    codes.Unauthenticated,
+			wantSecondProbes: []any{
+				"RequestAttributesParsed",
+				"ActorValidationSucceeded",
+				"SubjectCredentialExtracted",
+				"SubjectValidationFailed",
+				"CheckDenied",
+				"End",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := trust.NewStubValidator(trust.CredentialTypeBearer)
+			trustStore := trust.NewStubStore()
+			trustStore.AddValidator(validator)
+
+			fakeObs := service.NewFakeObserver(t)
+			authzServer := NewAuthzServer(trustStore, tokenService, nil, fakeObs)
+			authzServer.ValidationCache = NewValidationCache(ValidationCacheConfig{TTL: time.Minute})
+
+			resp, err := authzServer.Check(ctx, newRequest(tt.contextExtensions))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if codes.Code(resp.Status.Code) != codes.OK {
+				t.Fatalf("expected OK, got %s: %s", codes.Code(resp.Status.Code), resp.Status.Message)
+			}
+
+			// The token is revoked at its issuer, but a cached validation still accepts it
+			validator.WithError(trust.ErrInvalidToken)
+
+			resp, err = authzServer.Check(ctx, newRequest(tt.contextExtensions))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if codes.Code(resp.Status.Code) != tt.wantSecondCode {
+				t.Fatalf("expected %s, got %s: %s", tt.wantSecondCode, codes.Code(resp.Status.Code), resp.Status.Message)
+			}
+			fakeObs.GetProbe(1).AssertProbeSequence(tt.wantSecondProbes...)
+		})
+	}
+}
+
+func TestAuthzServer_ValidationCacheWithFilteredValidators(t *testing.T) {
+	ctx := context.Background()
+
+	// The admin validator is only trusted on admin paths
+	trustStore, err := trust.NewFilteredStore(
+		trust.WithCELFilter(`validator_name != "admin-validator" || request.path.startsWith("/admin")`),
+	)
+	if err != nil {
+		t.Fatalf("failed to create filtered store: %v", err)
+	}
+	adminValidator := trust.NewStubValidator(trust.CredentialTypeBearer)
+	adminValidator.WithResult(&trust.Result{Subject: "admin", Issuer: "https://admin-idp.test", TrustDomain: "admin"})
+	trustStore.AddValidator("admin-validator", adminValidator)
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewStubIssuer(issuer.StubIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	fakeObs := service.NewFakeObserver(t)
+	authzServer := NewAuthzServer(trustStore, tokenService, nil, fakeObs)
+	authzServer.ValidationCache = NewValidationCache(ValidationCacheConfig{TTL: time.Minute})
+
+	newRequest := func(path string) *authv3.CheckRequest {
+		return &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method: "GET",
+						Path:   path,
+						Headers: map[string]string{
+							"authorization": "Bearer admin-token",
+						},
+					},
+				},
+			},
+		}
+	}
+
+	resp, err := authzServer.Check(ctx, newRequest("/admin/users"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if codes.Code(resp.Status.Code) != codes.OK {
+		t.Fatalf("expected OK on the admin path, got %s: %s", codes.Code(resp.Status.Code), resp.Status.Message)
+	}
+
+	// The validation cached on the admin path must not be reused where the validator is filtered out
+	resp, err = authzServer.Check(ctx, newRequest("/app"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if codes.Code(resp.Status.Code) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated on the other path, got %s: %s", codes.Code(resp.Status.Code), resp.Status.Message)
+	}
+	fakeObs.GetProbe(1).AssertProbeSequence(
+		"RequestAttributesParsed",
+		"ActorValidationSucceeded",
+		"SubjectCredentialExtracted",
+		"SubjectValidationCacheMissed",
+		"SubjectValidationFailed",
+		"CheckDenied",
+		"End",
+	)
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/pkg/verifier"
+)
+
+// VerifyPath is the HTTP path of the token verification endpoint
+const VerifyPath = "/v1/verify"
+
+// VerifyServer verifies JWTs parsec issued with the checks pkg/verifier makes locally:
+// signature, audience, time-based claims (with verifier.DefaultClockSkew), and revocation
+//
+// It is meant for services and proxy-wasm filters that cannot verify tokens themselves,
+// and for checking a local verifier's configuration: a valid response includes the
+// thumbprint of the signing key, for verifier.Config.PinnedKeys.
+type VerifyServer struct {
+	issuerRegistry service.Registry
+	trustDomain    string
+	denylist       denylist.Denylist
+	clock          clock.Clock
+}
+
+// VerifyServerConfig configures the verification endpoint
+type VerifyServerConfig struct {
+	// IssuerRegistry provides the public keys tokens are verified with
+	IssuerRegistry service.Registry
+
+	// TrustDomain is the default expected audience
+	TrustDomain string
+
+	// Denylist, if set, holds revoked tokens, which are invalid
+	Denylist denylist.Denylist
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// NewVerifyServer creates a new verification server
+func NewVerifyServer(cfg VerifyServerConfig) *VerifyServer {
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &VerifyServer{
+		issuerRegistry: cfg.IssuerRegistry,
+		trustDomain:    cfg.TrustDomain,
+		denylist:       cfg.Denylist,
+		clock:          clk,
+	}
+}
+
+// verifyRequest is the body of a verification request, as JSON or a form
+type verifyRequest struct {
+	// Token is the token to verify
+	Token string `json:"token"`
+
+	// TokenType is the type of token expected (default: transaction token)
+	TokenType string `json:"token_type,omitempty"`
+
+	// Audience is the expected aud claim (default: the trust domain)
+	Audience string `json:"audience,omitempty"`
+}
+
+// verifyResponse is the result of a verification
+// Tokens that fail verification are answered with valid false and the reason, not an
+// HTTP error, so callers can tell a bad token from a failed call.
+type verifyResponse struct {
+	Valid         bool           `json:"valid"`
+	Error         string         `json:"error,omitempty"`
+	TokenType     string         `json:"token_type,omitempty"`
+	KeyID         string         `json:"key_id,omitempty"`
+	KeyThumbprint string         `json:"key_thumbprint,omitempty"`
+	Claims        map[string]any `json:"claims,omitempty"`
+}
+
+// ServeHTTP implements http.Handler
+func (s *VerifyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := parseVerifyRequest(r)
+	if err != nil {
+		writeNoStoreJSON(w, http.StatusBadRequest, oauthErrorResponse{
+			Error:            oauthInvalidRequest,
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+
+	resp, err := s.verify(r.Context(), req)
+	if err != nil {
+		writeNoStoreJSON(w, http.StatusInternalServerError, oauthErrorResponse{
+			Error:            "server_error",
+			ErrorDescription: err.Error(),
+		})
+		return
+	}
+	writeNoStoreJSON(w, http.StatusOK, resp)
+}
+
+// parseVerifyRequest reads a verification request from a JSON or form body
+func parseVerifyRequest(r *http.Request) (*verifyRequest, error) {
+	req := &verifyRequest{}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, fmt.Errorf("invalid form body: %w", err)
+		}
+		req.Token = r.PostForm.Get("token")
+		req.TokenType = r.PostForm.Get("token_type")
+		req.Audience = r.PostForm.Get("audience")
+	}
+
+	if req.Token == "" {
+		return nil, fmt.Errorf("token is required")
+	}
+	if req.TokenType == "" {
+		req.TokenType = string(service.TokenTypeTransactionToken)
+	}
+	return req, nil
+}
+
+// verify verifies a token against the current keys of the issuer of its type
+func (s *VerifyServer) verify(ctx context.Context, req *verifyRequest) (*verifyResponse, error) {
+	tokenType := service.TokenType(req.TokenType)
+	issuer, err := s.issuerRegistry.GetIssuer(tokenType)
+	if err != nil {
+		return &verifyResponse{Error: fmt.Sprintf("no issuer for token type %s", tokenType)}, nil
+	}
+	publicKeys, err := issuer.PublicKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public keys for %s: %w", tokenType, err)
+	}
+	keySet := publicKeySet(publicKeys)
+
+	msg, err := jws.Parse([]byte(req.Token))
+	if err != nil || len(msg.Signatures()) != 1 {
+		return &verifyResponse{Error: "token is not a signed JWT"}, nil
+	}
+	kid := msg.Signatures()[0].ProtectedHeaders().KeyID()
+	key, ok := keySet.LookupKeyID(kid)
+	if !ok {
+		return &verifyResponse{Error: fmt.Sprintf("unknown signing key %q", kid)}, nil
+	}
+
+	audience := req.Audience
+	if audience == "" {
+		audience = s.trustDomain
+	}
+	opts := []jwt.ParseOption{
+		jwt.WithKeySet(keySet),
+		jwt.WithValidate(true),
+		jwt.WithAudience(audience),
+		jwt.WithRequiredClaim(jwt.ExpirationKey),
+		jwt.WithAcceptableSkew(verifier.DefaultClockSkew),
+		jwt.WithClock(jwt.ClockFunc(s.clock.Now)),
+	}
+	if tokenType == service.TokenTypeTransactionToken {
+		opts = append(opts, jwt.WithRequiredClaim(jwt.SubjectKey), jwt.WithRequiredClaim("txn"))
+	}
+	parsed, err := jwt.Parse([]byte(req.Token), opts...)
+	if errors.Is(err, jwt.ErrTokenExpired()) {
+		return &verifyResponse{Error: "token expired"}, nil
+	}
+	if err != nil {
+		return &verifyResponse{Error: fmt.Sprintf("invalid token: %v", err)}, nil
+	}
+
+	if s.denylist != nil && parsed.JwtID() != "" {
+		denied, err := s.denylist.IsDenied(ctx, parsed.JwtID())
+		if err != nil {
+			return nil, fmt.Errorf("failed to check denylist: %w", err)
+		}
+		if denied {
+			return &verifyResponse{Error: "token revoked"}, nil
+		}
+	}
+
+	claims, err := parsed.AsMap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read claims: %w", err)
+	}
+	thumbprint, err := verifier.KeyThumbprint(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifyResponse{
+		Valid:         true,
+		TokenType:     string(tokenType),
+		KeyID:         kid,
+		KeyThumbprint: thumbprint,
+		Claims:        claims,
+	}, nil
+}
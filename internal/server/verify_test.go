@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+func TestVerifyServer(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	denied := denylist.NewMemoryDenylist(clk)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	txnIssuer := issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+		Clock:     clk,
+	})
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, txnIssuer)
+
+	verifyServer := NewVerifyServer(VerifyServerConfig{
+		IssuerRegistry: issuerRegistry,
+		TrustDomain:    "parsec.test",
+		Denylist:       denied,
+		Clock:          clk,
+	})
+
+	issue := func(t *testing.T) *service.Token {
+		t.Helper()
+		token, err := txnIssuer.Issue(ctx, &service.IssueContext{
+			Subject:            &trust.Result{Subject: "user@example.com"},
+			RequestAttributes:  &request.RequestAttributes{},
+			Audiences:          []string{"parsec.test"},
+			DataSourceRegistry: service.NewDataSourceRegistry(),
+		})
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+		return token
+	}
+
+	verify := func(t *testing.T, form url.Values) (int, verifyResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, VerifyPath, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		verifyServer.ServeHTTP(rec, req)
+
+		var resp verifyResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		token := issue(t)
+
+		code, resp := verify(t, url.Values{"token": {token.Value}})
+		if code != http.StatusOK || !resp.Valid {
+			t.Fatalf("expected valid token, got %d %+v", code, resp)
+		}
+		if resp.Claims["sub"] != "user@example.com" || resp.Claims["txn"] != token.TransactionID {
+			t.Errorf("unexpected claims %v", resp.Claims)
+		}
+		if resp.KeyID == "" || resp.KeyThumbprint == "" {
+			t.Errorf("expected signing key id and thumbprint, got %q and %q", resp.KeyID, resp.KeyThumbprint)
+		}
+	})
+
+	t.Run("JSON request", func(t *testing.T) {
+		body, _ := json.Marshal(verifyRequest{Token: issue(t).Value})
+		req := httptest.NewRequest(http.MethodPost, VerifyPath, strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		verifyServer.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"valid":true`) {
+			t.Errorf("expected valid token, got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("other audience", func(t *testing.T) {
+		code, resp := verify(t, url.Values{"token": {issue(t).Value}, "audience": {"other.test"}})
+		if code != http.StatusOK || resp.Valid {
+			t.Errorf("expected invalid token, got %d %+v", code, resp)
+		}
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		token := issue(t)
+		jti, _ := token.Claims["jti"].(string)
+		if err := denied.Deny(ctx, jti, token.ExpiresAt); err != nil {
+			t.Fatalf("failed to deny token: %v", err)
+		}
+
+		_, resp := verify(t, url.Values{"token": {token.Value}})
+		if resp.Valid || resp.Error != "token revoked" {
+			t.Errorf("expected revoked token, got %+v", resp)
+		}
+	})
+
+	t.Run("not a JWT", func(t *testing.T) {
+		_, resp := verify(t, url.Values{"token": {"opaque-token"}})
+		if resp.Valid {
+			t.Errorf("expected invalid token, got %+v", resp)
+		}
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		if code, _ := verify(t, url.Values{}); code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", code)
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		token := issue(t)
+		clk.Advance(10 * time.Minute)
+
+		_, resp := verify(t, url.Values{"token": {token.Value}})
+		if resp.Valid || resp.Error != "token expired" {
+			t.Errorf("expected expired token, got %+v", resp)
+		}
+	})
+}
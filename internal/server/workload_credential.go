@@ -0,0 +1,130 @@
+package server
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// forwardedClientCertHeader is the header Envoy uses to forward client certificate details
+const forwardedClientCertHeader = "x-forwarded-client-cert"
+
+// extractWorkloadCredential extracts the requesting workload's client certificate
+// This identifies the workload that connected to the gateway over mTLS
+//
+// The certificate comes from the source peer certificate Envoy sends with the check
+// (with include_peer_certificate enabled), or, if trustXFCC is set, from the
+// x-forwarded-client-cert header of a proxy in front of it.
+// Returns nil credential and nil error if the workload did not present a certificate
+func extractWorkloadCredential(req *authv3.CheckRequest, trustXFCC bool) (trust.Credential, error) {
+	if peerCert := req.GetAttributes().GetSource().GetCertificate(); peerCert != "" {
+		certs, err := parseEncodedPEMCertificates(peerCert)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer certificate: %w", err)
+		}
+		return trust.NewX509Credential(certs[0], certs[1:]), nil
+	}
+
+	if !trustXFCC {
+		return nil, nil
+	}
+
+	xfcc := requestHeaders(req.GetAttributes().GetRequest().GetHttp()).First(forwardedClientCertHeader)
+	if xfcc == "" {
+		return nil, nil
+	}
+
+	// Each proxy appends an element; the last describes the client of the nearest proxy
+	elements := splitQuoted(xfcc, ',')
+	fields := parseXFCCElement(elements[len(elements)-1])
+	if fields["cert"] == "" {
+		return nil, fmt.Errorf("%s has no Cert; configure the proxy to forward the client certificate", forwardedClientCertHeader)
+	}
+
+	certs, err := parseEncodedPEMCertificates(fields["cert"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s certificate: %w", forwardedClientCertHeader, err)
+	}
+	var chain []*x509.Certificate
+	if fields["chain"] != "" {
+		chain, err = parseEncodedPEMCertificates(fields["chain"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s chain: %w", forwardedClientCertHeader, err)
+		}
+	}
+
+	return trust.NewX509Credential(certs[0], append(certs[1:], chain...)), nil
+}
+
+// parseXFCCElement parses the key=value pairs of one x-forwarded-client-cert element
+// Keys are lowercased and quoted values are unquoted
+func parseXFCCElement(element string) map[string]string {
+	fields := make(map[string]string)
+	for _, pair := range splitQuoted(element, ';') {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+		}
+		fields[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return fields
+}
+
+// splitQuoted splits s on sep, ignoring separators inside double quotes
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && inQuotes:
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseEncodedPEMCertificates parses URL-encoded PEM certificates, as Envoy encodes them
+func parseEncodedPEMCertificates(encoded string) ([]*x509.Certificate, error) {
+	data, err := url.PathUnescape(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode certificate: %w", err)
+	}
+
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// newTestClientCertificate creates a CA and a client certificate it issued with a SPIFFE ID
+// Returns the CA certificate and the URL-encoded PEM of the client certificate, as Envoy sends it
+func newTestClientCertificate(t *testing.T, spiffeID string) (*x509.Certificate, string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "workload-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	uri, _ := url.Parse(spiffeID)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{uri},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	pemCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return caCert, url.PathEscape(string(pemCert))
+}
+
+func TestAuthzServer_WorkloadCredential(t *testing.T) {
+	ctx := context.Background()
+
+	const spiffeID = "spiffe://example.org/ns/default/sa/frontend"
+	caCert, encodedCert := newTestClientCertificate(t, spiffeID)
+	_, untrustedCert := newTestClientCertificate(t, spiffeID)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	x509Validator, err := trust.NewX509Validator(trust.X509ValidatorConfig{
+		Roots:       roots,
+		TrustDomain: "example.org",
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+	trustStore.AddValidator(x509Validator)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(privateKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TTL:       5 * time.Minute,
+		Signer:    signer,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	check := func(t *testing.T, authzServer *AuthzServer, peerCert string, headers map[string]string) *authv3.CheckResponse {
+		t.Helper()
+		headers["authorization"] = "Bearer user-token"
+		resp, err := authzServer.Check(ctx, &authv3.CheckRequest{
+			Attributes: &authv3.AttributeContext{
+				Request: &authv3.AttributeContext_Request{
+					Http: &authv3.AttributeContext_HttpRequest{
+						Method:  "GET",
+						Path:    "/api/resource",
+						Headers: headers,
+					},
+				},
+				Source: &authv3.AttributeContext_Peer{
+					Certificate: peerCert,
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	issuedClaim := func(t *testing.T, resp *authv3.CheckResponse, name string) any {
+		t.Helper()
+		if resp.Status.Code != 0 {
+			t.Fatalf("expected OK status, got code %d: %s", resp.Status.Code, resp.Status.Message)
+		}
+		for _, header := range resp.GetOkResponse().Headers {
+			if header.Header.Key != "Transaction-Token" {
+				continue
+			}
+			token, err := jwt.ParseInsecure([]byte(header.Header.Value))
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+			value, _ := token.Get(name)
+			return value
+		}
+		t.Fatal("transaction token header not found")
+		return nil
+	}
+
+	requestingWorkload := func(t *testing.T, resp *authv3.CheckResponse) any {
+		t.Helper()
+		return issuedClaim(t, resp, "req_wl")
+	}
+
+	t.Run("peer certificate identifies the requesting workload", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+
+		resp := check(t, authzServer, encodedCert, map[string]string{})
+		if reqWL := requestingWorkload(t, resp); reqWL != spiffeID {
+			t.Errorf("expected req_wl %s, got %v", spiffeID, reqWL)
+		}
+	})
+
+	t.Run("no certificate means no requesting workload", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+
+		resp := check(t, authzServer, "", map[string]string{})
+		if reqWL := requestingWorkload(t, resp); reqWL != nil {
+			t.Errorf("expected no req_wl, got %v", reqWL)
+		}
+	})
+
+	t.Run("untrusted certificate is denied", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+
+		resp := check(t, authzServer, untrustedCert, map[string]string{})
+		if resp.Status.Code == 0 {
+			t.Fatal("expected denial, got OK")
+		}
+		if !strings.Contains(resp.Status.Message, "workload validation failed") {
+			t.Errorf("expected workload validation failure, got %q", resp.Status.Message)
+		}
+	})
+
+	t.Run("forwarded client cert header when trusted", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+		authzServer.TrustForwardedClientCert = true
+
+		xfcc := `By=spiffe://example.org/gateway;Hash=abc;Cert="` + untrustedCert + `",` +
+			`By=spiffe://example.org/gateway;Hash=def;Cert="` + encodedCert + `";URI=` + spiffeID
+		resp := check(t, authzServer, "", map[string]string{"x-forwarded-client-cert": xfcc})
+		if reqWL := requestingWorkload(t, resp); reqWL != spiffeID {
+			t.Errorf("expected req_wl %s, got %v", spiffeID, reqWL)
+		}
+	})
+
+	t.Run("forwarded client cert header ignored by default", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+
+		xfcc := `Hash=abc;Cert="` + untrustedCert + `"`
+		resp := check(t, authzServer, "", map[string]string{"x-forwarded-client-cert": xfcc})
+		if reqWL := requestingWorkload(t, resp); reqWL != nil {
+			t.Errorf("expected no req_wl, got %v", reqWL)
+		}
+	})
+	t.Run("tokens bound to the certificate when enabled", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+		authzServer.CertificateBoundTokens = true
+
+		pemCert, err := url.PathUnescape(encodedCert)
+		if err != nil {
+			t.Fatalf("failed to decode certificate: %v", err)
+		}
+		block, _ := pem.Decode([]byte(pemCert))
+		wantThumbprint := trust.CertificateThumbprint(block.Bytes)
+
+		resp := check(t, authzServer, encodedCert, map[string]string{})
+		cnf, ok := issuedClaim(t, resp, trust.ConfirmationClaim).(map[string]any)
+		if !ok {
+			t.Fatalf("expected %s claim", trust.ConfirmationClaim)
+		}
+		if cnf[trust.X509ThumbprintConfirmation] != wantThumbprint {
+			t.Errorf("expected x5t#S256 %s, got %v", wantThumbprint, cnf[trust.X509ThumbprintConfirmation])
+		}
+	})
+
+	t.Run("tokens not bound to the certificate by default", func(t *testing.T) {
+		authzServer := NewAuthzServer(trustStore, tokenService, nil, nil)
+
+		resp := check(t, authzServer, encodedCert, map[string]string{})
+		if cnf := issuedClaim(t, resp, trust.ConfirmationClaim); cnf != nil {
+			t.Errorf("expected no %s claim, got %v", trust.ConfirmationClaim, cnf)
+		}
+	})
+}
@@ -49,6 +49,30 @@ type Cacheable interface {
 	CacheTTL() time.Duration
 }
 
+// CacheStats describes how well a cache is working
+type CacheStats struct {
+	// Entries is the number of results cached
+	Entries int64
+
+	// Hits and Misses count fetches answered from the cache, and not
+	Hits   int64
+	Misses int64
+
+	// Evictions counts results removed before they expired
+	Evictions int64
+}
+
+// CacheStatsReporter is implemented by data sources that cache the results of another
+type CacheStatsReporter interface {
+	CacheStats() CacheStats
+}
+
+// DataSourceWrapper is implemented by data sources that decorate another, such as
+// with tracing, so the decorated source can be inspected
+type DataSourceWrapper interface {
+	Unwrap() DataSource
+}
+
 // DataSourceContentType identifies the serialization format of data source results
 type DataSourceContentType string
 
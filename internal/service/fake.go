@@ -51,7 +51,7 @@ func (o *FakeObserver) TokenExchangeStarted(
 	ctx context.Context,
 	grantType string,
 	requestedTokenType string,
-	audience string,
+	audiences []string,
 	scope string,
 ) (context.Context, TokenExchangeProbe) {
 	probe := &FakeProbe{
@@ -60,7 +60,7 @@ func (o *FakeObserver) TokenExchangeStarted(
 		StartArgs: map[string]any{
 			"grantType":          grantType,
 			"requestedTokenType": requestedTokenType,
-			"audience":           audience,
+			"audiences":          audiences,
 			"scope":              scope,
 		},
 		calls: []probeCall{},
@@ -83,6 +83,56 @@ func (o *FakeObserver) AuthzCheckStarted(
 	return ctx, probe
 }
 
+// ConfigReloadStarted implements ConfigReloadObserver
+func (o *FakeObserver) ConfigReloadStarted(
+	ctx context.Context,
+) (context.Context, ConfigReloadProbe) {
+	probe := &FakeProbe{
+		t:           o.t,
+		StartMethod: "ConfigReloadStarted",
+		StartArgs:   map[string]any{},
+		calls:       []probeCall{},
+	}
+	o.Probes = append(o.Probes, probe)
+	return ctx, probe
+}
+
+// ValidationStarted implements ValidatorObserver
+func (o *FakeObserver) ValidationStarted(
+	ctx context.Context,
+	validator string,
+	credentialType trust.CredentialType,
+) (context.Context, ValidationProbe) {
+	probe := &FakeProbe{
+		t:           o.t,
+		StartMethod: "ValidationStarted",
+		StartArgs: map[string]any{
+			"validator":      validator,
+			"credentialType": credentialType,
+		},
+		calls: []probeCall{},
+	}
+	o.Probes = append(o.Probes, probe)
+	return ctx, probe
+}
+
+// DataSourceFetchStarted implements DataSourceObserver
+func (o *FakeObserver) DataSourceFetchStarted(
+	ctx context.Context,
+	dataSource string,
+) (context.Context, DataSourceFetchProbe) {
+	probe := &FakeProbe{
+		t:           o.t,
+		StartMethod: "DataSourceFetchStarted",
+		StartArgs: map[string]any{
+			"dataSource": dataSource,
+		},
+		calls: []probeCall{},
+	}
+	o.Probes = append(o.Probes, probe)
+	return ctx, probe
+}
+
 // AssertProbeCount verifies the expected number of probes were created
 func (o *FakeObserver) AssertProbeCount(expected int) {
 	o.t.Helper()
@@ -211,6 +261,14 @@ func (p *FakeProbe) SubjectTokenValidationFailed(err error) {
 	p.recordCall("SubjectTokenValidationFailed", err)
 }
 
+func (p *FakeProbe) ExchangeSucceeded(issuedTokenType string) {
+	p.recordCall("ExchangeSucceeded", issuedTokenType)
+}
+
+func (p *FakeProbe) ExchangeFailed(code string, description string) {
+	p.recordCall("ExchangeFailed", code, description)
+}
+
 // AuthzCheckProbe methods
 func (p *FakeProbe) RequestAttributesParsed(attrs *request.RequestAttributes) {
 	p.recordCall("RequestAttributesParsed", attrs)
@@ -232,6 +290,61 @@ func (p *FakeProbe) SubjectValidationFailed(err error) {
 	p.recordCall("SubjectValidationFailed", err)
 }
 
+func (p *FakeProbe) SubjectValidationCacheHit(subject *trust.Result) {
+	p.recordCall("SubjectValidationCacheHit", subject)
+}
+
+func (p *FakeProbe) SubjectValidationCacheMissed() {
+	p.recordCall("SubjectValidationCacheMissed")
+}
+
+func (p *FakeProbe) CheckAllowed() {
+	p.recordCall("CheckAllowed")
+}
+
+func (p *FakeProbe) CheckDenied(code string, reason string) {
+	p.recordCall("CheckDenied", code, reason)
+}
+
+// ConfigReloadProbe methods
+func (p *FakeProbe) ComponentReloaded(component string) {
+	p.recordCall("ComponentReloaded", component)
+}
+
+func (p *FakeProbe) RestartRequired(options []string) {
+	p.recordCall("RestartRequired", options)
+}
+
+func (p *FakeProbe) ConfigReloadFailed(err error) {
+	p.recordCall("ConfigReloadFailed", err)
+}
+
+// ValidationProbe methods
+func (p *FakeProbe) ValidationSucceeded(result *trust.Result) {
+	p.recordCall("ValidationSucceeded", result)
+}
+
+func (p *FakeProbe) ValidationFailed(err error) {
+	p.recordCall("ValidationFailed", err)
+}
+
+// DataSourceFetchProbe methods
+func (p *FakeProbe) CacheHit() {
+	p.recordCall("CacheHit")
+}
+
+func (p *FakeProbe) CacheMissed() {
+	p.recordCall("CacheMissed")
+}
+
+func (p *FakeProbe) FetchSucceeded(result *DataSourceResult) {
+	p.recordCall("FetchSucceeded", result)
+}
+
+func (p *FakeProbe) FetchFailed(err error) {
+	p.recordCall("FetchFailed", err)
+}
+
 // End is common to all probes
 func (p *FakeProbe) End() {
 	p.recordCall("End")
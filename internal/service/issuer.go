@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"crypto"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/alechenninger/parsec/internal/claims"
@@ -19,46 +21,68 @@ type IssueContext struct {
 	// Actor identity (attested claims from actor credential, e.g., mTLS)
 	Actor *trust.Result
 
+	// Workload identity of the requesting workload, if authenticated
+	Workload *trust.Result
+
 	// RequestAttributes contains information about the request
 	RequestAttributes *request.RequestAttributes
 
-	// Audience for the token (aud claim) - typically the trust domain
-	Audience string
+	// Delegation is the delegation chain of the token (RFC 8693 act claim), if any
+	Delegation *trust.Delegation
+
+	// Audiences for the token (aud claim) - typically just the trust domain
+	Audiences []string
+
+	// CertificateThumbprint is the x5t#S256 thumbprint of the client certificate the
+	// token is bound to (cnf claim), if any
+	CertificateThumbprint string
 
 	// Scope for the token (scope claim)
 	Scope string
 
+	// ClientID is the ID of the authenticated client the token is issued to, if any
+	ClientID string
+
 	// DataSourceRegistry provides access to data sources for lazy fetching
 	DataSourceRegistry *DataSourceRegistry
 }
 
-// ToClaims applies a set of claim mappers to produce claims
-// This is a convenience method to reduce duplication in issuer implementations
-func (ic *IssueContext) ToClaims(ctx context.Context, mappers []ClaimMapper) (claims.Claims, error) {
-	// Build data source input
-	dataSourceInput := &DataSourceInput{
-		Subject:           ic.Subject,
-		Actor:             ic.Actor,
-		RequestAttributes: ic.RequestAttributes,
-	}
-
-	// Build mapper input
-	mapperInput := &MapperInput{
+// MapperInput returns the input of claim mappers, and of other policies that see the
+// same variables, for this context
+func (ic *IssueContext) MapperInput() *MapperInput {
+	return &MapperInput{
 		Subject:            ic.Subject,
 		Actor:              ic.Actor,
+		Workload:           ic.Workload,
 		RequestAttributes:  ic.RequestAttributes,
 		DataSourceRegistry: ic.DataSourceRegistry,
-		DataSourceInput:    dataSourceInput,
+		DataSourceInput: &DataSourceInput{
+			Subject:           ic.Subject,
+			Actor:             ic.Actor,
+			RequestAttributes: ic.RequestAttributes,
+		},
 	}
+}
+
+// ToClaims applies a set of claim mappers to produce claims
+// This is a convenience method to reduce duplication in issuer implementations
+func (ic *IssueContext) ToClaims(ctx context.Context, mappers []ClaimMapper) (claims.Claims, error) {
+	mapperInput := ic.MapperInput()
 
-	// Apply mappers
+	// Apply mappers in order, each merged into the claims of those before it
 	result := make(claims.Claims)
-	for _, mapper := range mappers {
+	for i, mapper := range mappers {
 		mapperClaims, err := mapper.Map(ctx, mapperInput)
 		if err != nil {
 			return nil, err
 		}
-		result.Merge(mapperClaims)
+		strategy := claims.MergeOverwrite
+		if m, ok := mapper.(MergingClaimMapper); ok {
+			strategy = m.MergeStrategy()
+		}
+		if err := result.MergeWith(mapperClaims, strategy); err != nil {
+			return nil, fmt.Errorf("claim mapper %d: %w", i, err)
+		}
 	}
 
 	return result, nil
@@ -93,6 +117,130 @@ type Issuer interface {
 	PublicKeys(ctx context.Context) ([]PublicKey, error)
 }
 
+// TokenFormat identifies how an issued token is encoded
+type TokenFormat string
+
+const (
+	// TokenFormatJWT is a signed JWT (JWS compact serialization)
+	TokenFormatJWT TokenFormat = "jwt"
+
+	// TokenFormatCWT is a CWT signed with COSE_Sign1 (RFC 8392), base64url-encoded
+	TokenFormatCWT TokenFormat = "cwt"
+
+	// TokenFormatBiscuit is a Biscuit token (https://www.biscuitsec.org), base64url-encoded
+	TokenFormatBiscuit TokenFormat = "biscuit"
+
+	// TokenFormatBase64JSON is unsigned, base64-encoded JSON claims
+	TokenFormatBase64JSON TokenFormat = "base64_json"
+
+	// TokenFormatRHIdentity is the unsigned x-rh-identity format: base64(JSON({"identity": {...}}))
+	TokenFormatRHIdentity TokenFormat = "rh_identity"
+
+	// TokenFormatStub is an opaque placeholder token for testing
+	TokenFormatStub TokenFormat = "stub"
+
+	// TokenFormatOpaque is a random reference token whose claims are available by introspection
+	TokenFormatOpaque TokenFormat = "opaque"
+)
+
+// TTLPolicy decides the lifetime of each token when it is issued, such as from its
+// subject or scope, rather than every token of an issuer living as long
+type TTLPolicy interface {
+	// TTL returns the lifetime of the token for issueCtx, or zero for the issuer's TTL
+	TTL(ctx context.Context, issueCtx *IssueContext) (time.Duration, error)
+}
+
+// IssuerDescription describes the tokens an issuer produces, for capability discovery
+type IssuerDescription struct {
+	// IssuerURL is the iss claim of issued tokens, or empty if tokens carry no issuer
+	IssuerURL string
+
+	// Format is how issued tokens are encoded
+	Format TokenFormat
+
+	// MinTTL and MaxTTL bound the lifetime of issued tokens
+	// A zero MaxTTL means issued tokens do not expire
+	MinTTL time.Duration
+	MaxTTL time.Duration
+}
+
+// DescribableIssuer is an optional interface for issuers that can describe their tokens.
+// Signing algorithms are not part of the description; they are derived from PublicKeys.
+type DescribableIssuer interface {
+	Issuer
+
+	// Describe returns a description of the tokens this issuer produces
+	Describe() IssuerDescription
+}
+
+// PreviewingIssuer is an optional interface for issuers that can build a token's claims
+// without issuing it, to debug claim mappers
+type PreviewingIssuer interface {
+	Issuer
+
+	// Preview returns the token Issue would, but without a Value: nothing is signed or
+	// stored. Its Claims are the claims the token would carry.
+	Preview(ctx context.Context, issueCtx *IssueContext) (*Token, error)
+}
+
+// ErrKeyRotationNotSupported is returned by KeyRotatingIssuers whose keys cannot be rotated on demand
+var ErrKeyRotationNotSupported = errors.New("key rotation not supported")
+
+// KeyRotatingIssuer is an optional interface for issuers whose signing key can be rotated on demand,
+// such as in response to a key compromise.
+type KeyRotatingIssuer interface {
+	Issuer
+
+	// RotateKey generates a new signing key immediately, regardless of the current key's age
+	RotateKey(ctx context.Context) error
+}
+
+// ErrKeyRevocationNotSupported is returned by KeyRevokingIssuers whose keys cannot be revoked
+var ErrKeyRevocationNotSupported = errors.New("key revocation not supported")
+
+// KeyRevokingIssuer is an optional interface for issuers that can revoke a key before it expires,
+// such as when it has been exposed.
+type KeyRevokingIssuer interface {
+	Issuer
+
+	// RevokeKey removes the key from PublicKeys and stops signing with it
+	RevokeKey(ctx context.Context, keyID string) error
+}
+
+// SigningStatus describes how an issuer currently signs tokens
+type SigningStatus struct {
+	// KeyID and Algorithm identify the key currently signing tokens
+	KeyID     string
+	Algorithm string
+
+	// Migration is set if the issuer is migrating between signing algorithms
+	Migration *AlgorithmMigrationStatus
+}
+
+// AlgorithmMigrationStatus describes the progress of a signing algorithm migration
+type AlgorithmMigrationStatus struct {
+	// Phase is "dual_publish" before cutover, "cutover" while the old keys are
+	// still published, and "complete" once they are retired
+	Phase string
+
+	FromAlgorithm string
+	ToAlgorithm   string
+
+	// CutoverAt is when signing switches to the new algorithm
+	CutoverAt time.Time
+
+	// RetireAt is when the old algorithm's keys stop being published
+	RetireAt time.Time
+}
+
+// SigningStatusIssuer is an optional interface for issuers that sign tokens and can report how
+type SigningStatusIssuer interface {
+	Issuer
+
+	// SigningStatus returns the key currently signing tokens and any algorithm migration in progress
+	SigningStatus(ctx context.Context) (SigningStatus, error)
+}
+
 // Token represents an issued transaction token
 type Token struct {
 	// Value is the encoded token (e.g., JWT string)
@@ -106,6 +254,20 @@ type Token struct {
 
 	// IssuedAt is when the token was issued
 	IssuedAt time.Time
+
+	// ID is the token's jti, or empty for tokens without one
+	ID string
+
+	// TransactionID is the token's txn claim, for tokens that identify a transaction
+	TransactionID string
+
+	// Claims are the claims the token carries, for issuers that expose them
+	// Values are JSON-native (strings, float64 numbers, bools, []any, and map[string]any).
+	Claims map[string]any
+
+	// RedactedClaims are the paths of the mapped claims removed or hashed before
+	// issuance, like "tctx.email", for the audit log
+	RedactedClaims []string
 }
 
 // TokenClaims represents the claims in a transaction token
@@ -135,4 +297,7 @@ type TokenClaims struct {
 
 	// Scope (OAuth2)
 	Scope string `json:"scope,omitempty"`
+
+	// Actor is the delegation chain, if the subject is acted on behalf of (RFC 8693)
+	Actor *trust.Delegation `json:"act,omitempty"`
 }
@@ -16,6 +16,15 @@ type ClaimMapper interface {
 	Map(ctx context.Context, input *MapperInput) (claims.Claims, error)
 }
 
+// MergingClaimMapper is implemented by claim mappers whose claims are merged into
+// the claims of the mappers before them with a strategy other than overwriting
+type MergingClaimMapper interface {
+	ClaimMapper
+
+	// MergeStrategy returns how the mapper's claims are merged
+	MergeStrategy() claims.MergeStrategy
+}
+
 // MapperInput contains all inputs available to a claim mapper
 type MapperInput struct {
 	// Subject identity (attested claims from validated credential)
@@ -24,6 +33,9 @@ type MapperInput struct {
 	// Actor identity (attested claims from actor credential)
 	Actor *trust.Result
 
+	// Workload identity of the requesting workload, if authenticated
+	Workload *trust.Result
+
 	// RequestAttributes contains information about the request
 	RequestAttributes *request.RequestAttributes
 
@@ -49,7 +49,7 @@ type TokenIssuanceProbe interface {
 type TokenExchangeObserver interface {
 	// TokenExchangeStarted creates a new request-scoped probe for a token exchange request.
 	// Returns an instrumented context and a probe scoped to this request.
-	TokenExchangeStarted(ctx context.Context, grantType string, requestedTokenType string, audience string, scope string) (context.Context, TokenExchangeProbe)
+	TokenExchangeStarted(ctx context.Context, grantType string, requestedTokenType string, audiences []string, scope string) (context.Context, TokenExchangeProbe)
 }
 
 // TokenExchangeProbe provides request-scoped observability for a single token exchange operation.
@@ -72,6 +72,13 @@ type TokenExchangeProbe interface {
 	// SubjectTokenValidationFailed is called when subject token validation fails.
 	SubjectTokenValidationFailed(err error)
 
+	// ExchangeSucceeded is called when a token of issuedTokenType is returned to the client.
+	ExchangeSucceeded(issuedTokenType string)
+
+	// ExchangeFailed is called when the exchange is answered with an error, with its OAuth
+	// error code (such as invalid_grant or access_denied) and description.
+	ExchangeFailed(code string, description string)
+
 	// End terminates the observation. Should be deferred to ensure cleanup.
 	End()
 }
@@ -107,17 +114,123 @@ type AuthzCheckProbe interface {
 	// SubjectValidationFailed is called when subject credential validation fails.
 	SubjectValidationFailed(err error)
 
+	// SubjectValidationCacheHit is called when a cached validation of the subject credential is reused.
+	// SubjectValidationSucceeded follows. Together with SubjectValidationCacheMissed, it gives the cache's hit rate.
+	SubjectValidationCacheHit(subject *trust.Result)
+
+	// SubjectValidationCacheMissed is called when the subject credential is cacheable but has no cached validation.
+	SubjectValidationCacheMissed()
+
+	// CheckAllowed is called when the check allows the request.
+	CheckAllowed()
+
+	// CheckDenied is called when the check denies the request, with the gRPC status code of
+	// the denial (such as Unauthenticated or PermissionDenied) and its reason.
+	CheckDenied(code string, reason string)
+
+	// End terminates the observation. Should be deferred to ensure cleanup.
+	End()
+}
+
+// ConfigReloadObserver creates probes for reloads of the configuration while running.
+// Follows the same pattern as TokenServiceObserver.
+type ConfigReloadObserver interface {
+	// ConfigReloadStarted creates a new probe for applying a changed configuration.
+	// Returns an instrumented context and a probe scoped to this reload.
+	ConfigReloadStarted(ctx context.Context) (context.Context, ConfigReloadProbe)
+}
+
+// ConfigReloadProbe provides observability for a single configuration reload.
+type ConfigReloadProbe interface {
+	// ComponentReloaded is called when a component is rebuilt from the new configuration and swapped in.
+	ComponentReloaded(component string)
+
+	// RestartRequired is called with the changed options that take effect only on restart.
+	RestartRequired(options []string)
+
+	// ConfigReloadFailed is called when the new configuration cannot be applied.
+	// The running components are kept.
+	ConfigReloadFailed(err error)
+
 	// End terminates the observation. Should be deferred to ensure cleanup.
 	End()
 }
 
+// ValidatorObserver creates probes for attempts to validate a credential with a single validator.
+// Follows the same pattern as TokenServiceObserver.
+type ValidatorObserver interface {
+	// ValidationStarted creates a new probe for an attempt by validator to validate a credential of credentialType.
+	// Returns an instrumented context and a probe scoped to this attempt.
+	ValidationStarted(ctx context.Context, validator string, credentialType trust.CredentialType) (context.Context, ValidationProbe)
+}
+
+// ValidationProbe provides observability for a single validation attempt.
+// The attempt's latency is the time from ValidationStarted to its outcome.
+type ValidationProbe interface {
+	// ValidationSucceeded is called when the validator accepts the credential.
+	ValidationSucceeded(result *trust.Result)
+
+	// ValidationFailed is called when the validator rejects the credential, or cannot validate it.
+	ValidationFailed(err error)
+
+	// End terminates the observation. Should be deferred to ensure cleanup.
+	End()
+}
+
+// DataSourceObserver creates probes for fetches from a data source.
+// Follows the same pattern as TokenServiceObserver.
+type DataSourceObserver interface {
+	// DataSourceFetchStarted creates a new probe for a fetch from dataSource.
+	// Returns an instrumented context and a probe scoped to this fetch.
+	DataSourceFetchStarted(ctx context.Context, dataSource string) (context.Context, DataSourceFetchProbe)
+}
+
+// DataSourceFetchProbe provides observability for a single data source fetch.
+// The fetch's latency is the time from DataSourceFetchStarted to its outcome.
+type DataSourceFetchProbe interface {
+	// CacheHit is called by caching data sources when the fetch is answered from the cache.
+	CacheHit()
+
+	// CacheMissed is called by caching data sources when the fetch is not cached and is fetched from the source.
+	CacheMissed()
+
+	// FetchSucceeded is called with the data fetched. The result may be nil if the source has no data.
+	FetchSucceeded(result *DataSourceResult)
+
+	// FetchFailed is called when the fetch fails.
+	FetchFailed(err error)
+
+	// End terminates the observation. Should be deferred to ensure cleanup.
+	End()
+}
+
+type dataSourceFetchProbeKey struct{}
+
+// WithDataSourceFetchProbe returns a context carrying probe, so the caching data sources a
+// fetch passes through can report whether it was cached
+func WithDataSourceFetchProbe(ctx context.Context, probe DataSourceFetchProbe) context.Context {
+	return context.WithValue(ctx, dataSourceFetchProbeKey{}, probe)
+}
+
+// DataSourceFetchProbeFromContext returns the probe of the fetch ctx is for, or a probe
+// that does nothing
+func DataSourceFetchProbeFromContext(ctx context.Context) DataSourceFetchProbe {
+	if probe, ok := ctx.Value(dataSourceFetchProbeKey{}).(DataSourceFetchProbe); ok {
+		return probe
+	}
+	return &NoOpDataSourceFetchProbe{}
+}
+
 // ApplicationObserver provides a unified interface for all observability concerns in the application.
-// Concrete implementations can implement all three interfaces in a single type.
+// Concrete implementations can implement all of these interfaces in a single type.
 // Implementations can embed the NoOp* types to get default behavior for methods they don't care about.
 type ApplicationObserver interface {
 	TokenServiceObserver
 	TokenExchangeObserver
 	AuthzCheckObserver
+	ConfigReloadObserver
+	ValidatorObserver
+	DataSourceObserver
 }
 
 // compositeObserver delegates to multiple observers in order.
@@ -150,12 +263,12 @@ func (c *compositeObserver) TokenExchangeStarted(
 	ctx context.Context,
 	grantType string,
 	requestedTokenType string,
-	audience string,
+	audiences []string,
 	scope string,
 ) (context.Context, TokenExchangeProbe) {
 	probes := make([]TokenExchangeProbe, len(c.observers))
 	for i, obs := range c.observers {
-		ctx, probes[i] = obs.TokenExchangeStarted(ctx, grantType, requestedTokenType, audience, scope)
+		ctx, probes[i] = obs.TokenExchangeStarted(ctx, grantType, requestedTokenType, audiences, scope)
 	}
 	return ctx, &compositeTokenExchangeProbe{probes: probes}
 }
@@ -170,6 +283,39 @@ func (c *compositeObserver) AuthzCheckStarted(
 	return ctx, &compositeAuthzCheckProbe{probes: probes}
 }
 
+func (c *compositeObserver) ConfigReloadStarted(
+	ctx context.Context,
+) (context.Context, ConfigReloadProbe) {
+	probes := make([]ConfigReloadProbe, len(c.observers))
+	for i, obs := range c.observers {
+		ctx, probes[i] = obs.ConfigReloadStarted(ctx)
+	}
+	return ctx, &compositeConfigReloadProbe{probes: probes}
+}
+
+func (c *compositeObserver) ValidationStarted(
+	ctx context.Context,
+	validator string,
+	credentialType trust.CredentialType,
+) (context.Context, ValidationProbe) {
+	probes := make([]ValidationProbe, len(c.observers))
+	for i, obs := range c.observers {
+		ctx, probes[i] = obs.ValidationStarted(ctx, validator, credentialType)
+	}
+	return ctx, &compositeValidationProbe{probes: probes}
+}
+
+func (c *compositeObserver) DataSourceFetchStarted(
+	ctx context.Context,
+	dataSource string,
+) (context.Context, DataSourceFetchProbe) {
+	probes := make([]DataSourceFetchProbe, len(c.observers))
+	for i, obs := range c.observers {
+		ctx, probes[i] = obs.DataSourceFetchStarted(ctx, dataSource)
+	}
+	return ctx, &compositeDataSourceFetchProbe{probes: probes}
+}
+
 // compositeTokenIssuanceProbe delegates to multiple probes in order.
 type compositeTokenIssuanceProbe struct {
 	probes []TokenIssuanceProbe
@@ -246,6 +392,18 @@ func (c *compositeTokenExchangeProbe) SubjectTokenValidationFailed(err error) {
 	}
 }
 
+func (c *compositeTokenExchangeProbe) ExchangeSucceeded(issuedTokenType string) {
+	for _, probe := range c.probes {
+		probe.ExchangeSucceeded(issuedTokenType)
+	}
+}
+
+func (c *compositeTokenExchangeProbe) ExchangeFailed(code string, description string) {
+	for _, probe := range c.probes {
+		probe.ExchangeFailed(code, description)
+	}
+}
+
 func (c *compositeTokenExchangeProbe) End() {
 	for _, probe := range c.probes {
 		probe.End()
@@ -299,12 +457,123 @@ func (c *compositeAuthzCheckProbe) SubjectValidationFailed(err error) {
 	}
 }
 
+func (c *compositeAuthzCheckProbe) SubjectValidationCacheHit(subject *trust.Result) {
+	for _, probe := range c.probes {
+		probe.SubjectValidationCacheHit(subject)
+	}
+}
+
+func (c *compositeAuthzCheckProbe) SubjectValidationCacheMissed() {
+	for _, probe := range c.probes {
+		probe.SubjectValidationCacheMissed()
+	}
+}
+
+func (c *compositeAuthzCheckProbe) CheckAllowed() {
+	for _, probe := range c.probes {
+		probe.CheckAllowed()
+	}
+}
+
+func (c *compositeAuthzCheckProbe) CheckDenied(code string, reason string) {
+	for _, probe := range c.probes {
+		probe.CheckDenied(code, reason)
+	}
+}
+
 func (c *compositeAuthzCheckProbe) End() {
 	for _, probe := range c.probes {
 		probe.End()
 	}
 }
 
+// compositeConfigReloadProbe delegates to multiple ConfigReloadProbe instances
+type compositeConfigReloadProbe struct {
+	probes []ConfigReloadProbe
+}
+
+func (c *compositeConfigReloadProbe) ComponentReloaded(component string) {
+	for _, probe := range c.probes {
+		probe.ComponentReloaded(component)
+	}
+}
+
+func (c *compositeConfigReloadProbe) RestartRequired(options []string) {
+	for _, probe := range c.probes {
+		probe.RestartRequired(options)
+	}
+}
+
+func (c *compositeConfigReloadProbe) ConfigReloadFailed(err error) {
+	for _, probe := range c.probes {
+		probe.ConfigReloadFailed(err)
+	}
+}
+
+func (c *compositeConfigReloadProbe) End() {
+	for _, probe := range c.probes {
+		probe.End()
+	}
+}
+
+// compositeValidationProbe delegates to multiple ValidationProbe instances
+type compositeValidationProbe struct {
+	probes []ValidationProbe
+}
+
+func (c *compositeValidationProbe) ValidationSucceeded(result *trust.Result) {
+	for _, probe := range c.probes {
+		probe.ValidationSucceeded(result)
+	}
+}
+
+func (c *compositeValidationProbe) ValidationFailed(err error) {
+	for _, probe := range c.probes {
+		probe.ValidationFailed(err)
+	}
+}
+
+func (c *compositeValidationProbe) End() {
+	for _, probe := range c.probes {
+		probe.End()
+	}
+}
+
+// compositeDataSourceFetchProbe delegates to multiple DataSourceFetchProbe instances
+type compositeDataSourceFetchProbe struct {
+	probes []DataSourceFetchProbe
+}
+
+func (c *compositeDataSourceFetchProbe) CacheHit() {
+	for _, probe := range c.probes {
+		probe.CacheHit()
+	}
+}
+
+func (c *compositeDataSourceFetchProbe) CacheMissed() {
+	for _, probe := range c.probes {
+		probe.CacheMissed()
+	}
+}
+
+func (c *compositeDataSourceFetchProbe) FetchSucceeded(result *DataSourceResult) {
+	for _, probe := range c.probes {
+		probe.FetchSucceeded(result)
+	}
+}
+
+func (c *compositeDataSourceFetchProbe) FetchFailed(err error) {
+	for _, probe := range c.probes {
+		probe.FetchFailed(err)
+	}
+}
+
+func (c *compositeDataSourceFetchProbe) End() {
+	for _, probe := range c.probes {
+		probe.End()
+	}
+}
+
 // NoOpTokenIssuanceProbe is an exported null object implementation of TokenIssuanceProbe.
 // Implementations can embed this to get default no-op behavior, allowing new methods
 // to be added to the interface without breaking existing implementations.
@@ -326,6 +595,8 @@ func (n *NoOpTokenExchangeProbe) RequestContextParsed(attrs *request.RequestAttr
 func (n *NoOpTokenExchangeProbe) RequestContextParseFailed(err error)                   {}
 func (n *NoOpTokenExchangeProbe) SubjectTokenValidationSucceeded(subject *trust.Result) {}
 func (n *NoOpTokenExchangeProbe) SubjectTokenValidationFailed(err error)                {}
+func (n *NoOpTokenExchangeProbe) ExchangeSucceeded(issuedTokenType string)              {}
+func (n *NoOpTokenExchangeProbe) ExchangeFailed(code string, description string)        {}
 func (n *NoOpTokenExchangeProbe) End()                                                  {}
 
 // NoOpAuthzCheckProbe is an exported null object implementation of AuthzCheckProbe.
@@ -340,8 +611,39 @@ func (n *NoOpAuthzCheckProbe) SubjectCredentialExtracted(cred trust.Credential,
 func (n *NoOpAuthzCheckProbe) SubjectCredentialExtractionFailed(err error)      {}
 func (n *NoOpAuthzCheckProbe) SubjectValidationSucceeded(subject *trust.Result) {}
 func (n *NoOpAuthzCheckProbe) SubjectValidationFailed(err error)                {}
+func (n *NoOpAuthzCheckProbe) SubjectValidationCacheHit(subject *trust.Result)  {}
+func (n *NoOpAuthzCheckProbe) SubjectValidationCacheMissed()                    {}
+func (n *NoOpAuthzCheckProbe) CheckAllowed()                                    {}
+func (n *NoOpAuthzCheckProbe) CheckDenied(code string, reason string)           {}
 func (n *NoOpAuthzCheckProbe) End()                                             {}
 
+// NoOpConfigReloadProbe is an exported null object implementation of ConfigReloadProbe.
+// Implementations can embed this to get default no-op behavior.
+type NoOpConfigReloadProbe struct{}
+
+func (n *NoOpConfigReloadProbe) ComponentReloaded(component string) {}
+func (n *NoOpConfigReloadProbe) RestartRequired(options []string)   {}
+func (n *NoOpConfigReloadProbe) ConfigReloadFailed(err error)       {}
+func (n *NoOpConfigReloadProbe) End()                               {}
+
+// NoOpValidationProbe is an exported null object implementation of ValidationProbe.
+// Implementations can embed this to get default no-op behavior.
+type NoOpValidationProbe struct{}
+
+func (n *NoOpValidationProbe) ValidationSucceeded(result *trust.Result) {}
+func (n *NoOpValidationProbe) ValidationFailed(err error)               {}
+func (n *NoOpValidationProbe) End()                                     {}
+
+// NoOpDataSourceFetchProbe is an exported null object implementation of DataSourceFetchProbe.
+// Implementations can embed this to get default no-op behavior.
+type NoOpDataSourceFetchProbe struct{}
+
+func (n *NoOpDataSourceFetchProbe) CacheHit()                               {}
+func (n *NoOpDataSourceFetchProbe) CacheMissed()                            {}
+func (n *NoOpDataSourceFetchProbe) FetchSucceeded(result *DataSourceResult) {}
+func (n *NoOpDataSourceFetchProbe) FetchFailed(err error)                   {}
+func (n *NoOpDataSourceFetchProbe) End()                                    {}
+
 // NoOpApplicationObserver implements ApplicationObserver with no-op behavior.
 // Use this as a default when no observability is needed.
 type NoOpApplicationObserver struct{}
@@ -371,10 +673,22 @@ func (n *NoOpApplicationObserver) TokenIssuanceStarted(ctx context.Context, subj
 	return ctx, &NoOpTokenIssuanceProbe{}
 }
 
-func (n *NoOpApplicationObserver) TokenExchangeStarted(ctx context.Context, grantType string, requestedTokenType string, audience string, scope string) (context.Context, TokenExchangeProbe) {
+func (n *NoOpApplicationObserver) TokenExchangeStarted(ctx context.Context, grantType string, requestedTokenType string, audiences []string, scope string) (context.Context, TokenExchangeProbe) {
 	return ctx, &NoOpTokenExchangeProbe{}
 }
 
 func (n *NoOpApplicationObserver) AuthzCheckStarted(ctx context.Context) (context.Context, AuthzCheckProbe) {
 	return ctx, &NoOpAuthzCheckProbe{}
 }
+
+func (n *NoOpApplicationObserver) ConfigReloadStarted(ctx context.Context) (context.Context, ConfigReloadProbe) {
+	return ctx, &NoOpConfigReloadProbe{}
+}
+
+func (n *NoOpApplicationObserver) ValidationStarted(ctx context.Context, validator string, credentialType trust.CredentialType) (context.Context, ValidationProbe) {
+	return ctx, &NoOpValidationProbe{}
+}
+
+func (n *NoOpApplicationObserver) DataSourceFetchStarted(ctx context.Context, dataSource string) (context.Context, DataSourceFetchProbe) {
+	return ctx, &NoOpDataSourceFetchProbe{}
+}
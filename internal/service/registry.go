@@ -3,20 +3,30 @@ package service
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
-// SimpleRegistry is a simple in-memory registry of issuers by token type
+// SimpleRegistry is a simple in-memory registry of issuers by token type and audience
 type SimpleRegistry struct {
-	mu      sync.RWMutex
-	issuers map[TokenType]Issuer
+	mu              sync.RWMutex
+	issuers         map[TokenType]Issuer
+	audienceIssuers map[TokenType][]audienceIssuer
+}
+
+// audienceIssuer is an issuer registered for some audiences
+type audienceIssuer struct {
+	audiences []string
+	issuer    Issuer
 }
 
 // NewSimpleRegistry creates a new simple issuer registry
 func NewSimpleRegistry() *SimpleRegistry {
 	return &SimpleRegistry{
-		issuers: make(map[TokenType]Issuer),
+		issuers:         make(map[TokenType]Issuer),
+		audienceIssuers: make(map[TokenType][]audienceIssuer),
 	}
 }
 
@@ -28,7 +38,20 @@ func (r *SimpleRegistry) Register(tokenType TokenType, issuer Issuer) *SimpleReg
 	return r
 }
 
-// GetIssuer returns an issuer for the specified token type
+// RegisterForAudiences registers an issuer for a token type, for tokens whose every
+// audience is one of audiences, such as to sign them with other keys or give them
+// another TTL. Issuers registered earlier for the same audiences take precedence.
+func (r *SimpleRegistry) RegisterForAudiences(tokenType TokenType, audiences []string, issuer Issuer) *SimpleRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audienceIssuers[tokenType] = append(r.audienceIssuers[tokenType], audienceIssuer{
+		audiences: slices.Clone(audiences),
+		issuer:    issuer,
+	})
+	return r
+}
+
+// GetIssuer returns the issuer for the specified token type, for any audience
 func (r *SimpleRegistry) GetIssuer(tokenType TokenType) (Issuer, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -41,6 +64,37 @@ func (r *SimpleRegistry) GetIssuer(tokenType TokenType) (Issuer, error) {
 	return issuer, nil
 }
 
+// GetIssuerFor returns the issuer for the specified token type and audiences
+func (r *SimpleRegistry) GetIssuerFor(tokenType TokenType, audiences []string) (Issuer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(audiences) > 0 {
+		for _, candidate := range r.audienceIssuers[tokenType] {
+			if candidate.covers(audiences) {
+				return candidate.issuer, nil
+			}
+		}
+	}
+
+	issuer, ok := r.issuers[tokenType]
+	if !ok {
+		return nil, fmt.Errorf("no issuer registered for token type %s and audiences %s", tokenType, strings.Join(audiences, ", "))
+	}
+
+	return issuer, nil
+}
+
+// covers reports whether the issuer is registered for every one of audiences
+func (a audienceIssuer) covers(audiences []string) bool {
+	for _, aud := range audiences {
+		if !slices.Contains(a.audiences, aud) {
+			return false
+		}
+	}
+	return true
+}
+
 // ListTokenTypes returns all registered token types
 func (r *SimpleRegistry) ListTokenTypes() []TokenType {
 	r.mu.RLock()
@@ -50,6 +104,11 @@ func (r *SimpleRegistry) ListTokenTypes() []TokenType {
 	for tokenType := range r.issuers {
 		types = append(types, tokenType)
 	}
+	for tokenType := range r.audienceIssuers {
+		if _, ok := r.issuers[tokenType]; !ok {
+			types = append(types, tokenType)
+		}
+	}
 
 	return types
 }
@@ -75,6 +134,17 @@ func (r *SimpleRegistry) GetAllPublicKeys(ctx context.Context) ([]PublicKey, err
 
 		allKeys = append(allKeys, keys...)
 	}
+	for tokenType, issuers := range r.audienceIssuers {
+		for _, candidate := range issuers {
+			keys, err := candidate.issuer.PublicKeys(ctx)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("issuer for %s (audiences %s): %w",
+					tokenType, strings.Join(candidate.audiences, ", "), err))
+				continue
+			}
+			allKeys = append(allKeys, keys...)
+		}
+	}
 
 	// Return collected keys along with aggregated errors (if any)
 	if len(errs) > 0 {
@@ -84,6 +154,49 @@ func (r *SimpleRegistry) GetAllPublicKeys(ctx context.Context) ([]PublicKey, err
 	return allKeys, nil
 }
 
+// ReloadableRegistry is a Registry whose underlying registry can be replaced while
+// it is in use, such as when issuers are reconfigured without a restart
+type ReloadableRegistry struct {
+	current atomic.Pointer[registryRef]
+}
+
+// registryRef holds a Registry, since atomic.Pointer needs a concrete type
+type registryRef struct {
+	registry Registry
+}
+
+// NewReloadableRegistry creates a reloadable registry that starts out as registry
+func NewReloadableRegistry(registry Registry) *ReloadableRegistry {
+	r := &ReloadableRegistry{}
+	r.current.Store(&registryRef{registry: registry})
+	return r
+}
+
+// Swap replaces the underlying registry, returning the one it replaced
+func (r *ReloadableRegistry) Swap(registry Registry) Registry {
+	return r.current.Swap(&registryRef{registry: registry}).registry
+}
+
+// GetIssuer implements Registry
+func (r *ReloadableRegistry) GetIssuer(tokenType TokenType) (Issuer, error) {
+	return r.current.Load().registry.GetIssuer(tokenType)
+}
+
+// GetIssuerFor implements Registry
+func (r *ReloadableRegistry) GetIssuerFor(tokenType TokenType, audiences []string) (Issuer, error) {
+	return r.current.Load().registry.GetIssuerFor(tokenType, audiences)
+}
+
+// ListTokenTypes implements Registry
+func (r *ReloadableRegistry) ListTokenTypes() []TokenType {
+	return r.current.Load().registry.ListTokenTypes()
+}
+
+// GetAllPublicKeys implements Registry
+func (r *ReloadableRegistry) GetAllPublicKeys(ctx context.Context) ([]PublicKey, error) {
+	return r.current.Load().registry.GetAllPublicKeys(ctx)
+}
+
 // newPublicKeysError creates an aggregated error from multiple issuer errors
 func newPublicKeysError(errs []error) error {
 	if len(errs) == 0 {
@@ -236,6 +236,59 @@ func TestSimpleRegistry_GetAllPublicKeys(t *testing.T) {
 }
 
 // testIssuerWithKeys is a test issuer that returns a predefined set of public keys
+func TestSimpleRegistry_GetIssuerFor(t *testing.T) {
+	ctx := context.Background()
+	general := &testIssuerWithKeys{publicKeys: []PublicKey{{KeyID: "general"}}}
+	billing := &testIssuerWithKeys{publicKeys: []PublicKey{{KeyID: "billing"}}}
+	registry := NewSimpleRegistry().
+		Register(TokenTypeTransactionToken, general).
+		RegisterForAudiences(TokenTypeTransactionToken, []string{"billing.example.com", "invoices.example.com"}, billing).
+		RegisterForAudiences(TokenTypeAccessToken, []string{"billing.example.com"}, billing)
+
+	tests := []struct {
+		name      string
+		tokenType TokenType
+		audiences []string
+		want      Issuer
+	}{
+		{"issuer for the audience", TokenTypeTransactionToken, []string{"billing.example.com"}, billing},
+		{"issuer for every audience", TokenTypeTransactionToken, []string{"billing.example.com", "invoices.example.com"}, billing},
+		{"issuer for any audience if one is not covered", TokenTypeTransactionToken, []string{"billing.example.com", "orders.example.com"}, general},
+		{"issuer for any audience without audiences", TokenTypeTransactionToken, nil, general},
+		{"token type with only audience issuers", TokenTypeAccessToken, []string{"billing.example.com"}, billing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.GetIssuerFor(tt.tokenType, tt.audiences)
+			if err != nil {
+				t.Fatalf("GetIssuerFor failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("no issuer for other audiences", func(t *testing.T) {
+		if _, err := registry.GetIssuerFor(TokenTypeAccessToken, []string{"orders.example.com"}); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("lists and publishes audience issuers", func(t *testing.T) {
+		if types := registry.ListTokenTypes(); len(types) != 2 {
+			t.Errorf("expected 2 token types, got %v", types)
+		}
+		keys, err := registry.GetAllPublicKeys(ctx)
+		if err != nil {
+			t.Fatalf("GetAllPublicKeys failed: %v", err)
+		}
+		if len(keys) != 3 {
+			t.Errorf("expected 3 keys, got %d", len(keys))
+		}
+	})
+}
+
 type testIssuerWithKeys struct {
 	publicKeys []PublicKey
 }
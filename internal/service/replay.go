@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// maxReplayAttempts is how many times a token is issued before issuance fails
+// because every token had the ID of one issued before
+const maxReplayAttempts = 3
+
+// errTokenIDSeen is returned when an issued token's ID was already issued
+var errTokenIDSeen = errors.New("token ID was already issued")
+
+// ReplayDetector records the IDs (jti) of issued tokens, so no two unexpired tokens
+// are issued with the same ID. Stores shared by instances detect collisions between them.
+type ReplayDetector interface {
+	// Seen records tokenID until expiresAt, reporting whether it was already recorded
+	Seen(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error)
+}
+
+// WithReplayDetector checks the ID of every issued token with detector. A token
+// whose ID was already issued is issued again, with a new ID.
+func WithReplayDetector(detector ReplayDetector) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.replayDetector = detector
+	}
+}
+
+// ReplayCheckingIssuer is an optional interface for issuers that keep the tokens they
+// issue, such as the records of opaque tokens. Their tokens' IDs are checked before
+// they are kept, so a token rejected for its ID is never valid.
+type ReplayCheckingIssuer interface {
+	Issuer
+
+	// IssueChecked issues a token as Issue does, but keeps it only if check, called
+	// before anything is kept, returns nil; otherwise it returns check's error
+	IssueChecked(ctx context.Context, issueCtx *IssueContext, check func(context.Context, *Token) error) (*Token, error)
+}
+
+// defaultMaxReplayIDs is the default number of IDs a MemoryReplayDetector records
+const defaultMaxReplayIDs = 100000
+
+// MemoryReplayDetector is a ReplayDetector of one instance's tokens
+type MemoryReplayDetector struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	maxIDs    int
+	expiresAt map[string]time.Time
+	nextSweep time.Time
+}
+
+// NewMemoryReplayDetector creates an in-memory replay detector
+// clk decides when recorded IDs expire (defaults to the system clock). At most maxIDs
+// IDs are recorded at once (default: 100000); when full, the ID expiring soonest is
+// forgotten.
+func NewMemoryReplayDetector(clk clock.Clock, maxIDs int) *MemoryReplayDetector {
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	if maxIDs <= 0 {
+		maxIDs = defaultMaxReplayIDs
+	}
+	return &MemoryReplayDetector{clock: clk, maxIDs: maxIDs, expiresAt: make(map[string]time.Time)}
+}
+
+// Seen implements ReplayDetector
+func (d *MemoryReplayDetector) Seen(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.clock.Now()
+	if now.After(d.nextSweep) {
+		d.sweep(now)
+	}
+
+	if exp, ok := d.expiresAt[tokenID]; ok && exp.After(now) {
+		return true, nil
+	}
+	if len(d.expiresAt) >= d.maxIDs {
+		d.evict(now)
+	}
+	d.expiresAt[tokenID] = expiresAt
+	return false, nil
+}
+
+// sweep forgets expired IDs
+// Must be called with mu held.
+func (d *MemoryReplayDetector) sweep(now time.Time) {
+	for id, exp := range d.expiresAt {
+		if !exp.After(now) {
+			delete(d.expiresAt, id)
+		}
+	}
+	d.nextSweep = now.Add(time.Minute)
+}
+
+// evict makes room for a new ID: expired IDs are forgotten, and if none have
+// expired, the ID expiring soonest is
+// Must be called with mu held.
+func (d *MemoryReplayDetector) evict(now time.Time) {
+	d.sweep(now)
+	if len(d.expiresAt) < d.maxIDs {
+		return
+	}
+	var soonestID string
+	var soonest time.Time
+	for id, exp := range d.expiresAt {
+		if soonestID == "" || exp.Before(soonest) {
+			soonestID, soonest = id, exp
+		}
+	}
+	delete(d.expiresAt, soonestID)
+}
+
+// checkTokenID records the ID of token, returning errTokenIDSeen if it was already issued
+func (ts *TokenService) checkTokenID(ctx context.Context, token *Token) error {
+	if token.ID == "" {
+		return nil
+	}
+	seen, err := ts.replayDetector.Seen(ctx, token.ID, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to check token ID: %w", err)
+	}
+	if seen {
+		return fmt.Errorf("%w: %s", errTokenIDSeen, token.ID)
+	}
+	return nil
+}
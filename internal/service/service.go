@@ -2,7 +2,11 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/alechenninger/parsec/internal/request"
 	"github.com/alechenninger/parsec/internal/trust"
@@ -16,6 +20,41 @@ type TokenService struct {
 	dataSources    *DataSourceRegistry
 	issuerRegistry Registry
 	observer       TokenServiceObserver
+	maxTTLs        map[TokenType]time.Duration
+	policies       []AuthorizationPolicy
+	trustDomains   []*TrustDomain
+	replayDetector ReplayDetector
+}
+
+// ErrIssuanceDenied is returned (wrapped) by IssueTokens when an authorization policy
+// denies the request
+var ErrIssuanceDenied = errors.New("issuance denied")
+
+// AuthorizationPolicy decides whether tokens may be issued for a request, after its
+// credentials are validated and before any token is issued
+type AuthorizationPolicy interface {
+	// Authorize returns an error wrapping ErrIssuanceDenied if the request is denied,
+	// or another error if no decision could be made
+	Authorize(ctx context.Context, req *IssueRequest) error
+}
+
+// TokenServiceOption is a functional option for configuring TokenService
+type TokenServiceOption func(*TokenService)
+
+// WithMaxTTLs caps the lifetime of issued tokens by token type, regardless of issuer configuration.
+// Issuance fails rather than returning a token that lives longer than its type's maximum.
+func WithMaxTTLs(maxTTLs map[TokenType]time.Duration) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.maxTTLs = maxTTLs
+	}
+}
+
+// WithAuthorizationPolicy denies issuance unless policy allows it
+// Policies are consulted in the order added; all must allow a request.
+func WithAuthorizationPolicy(policy AuthorizationPolicy) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.policies = append(ts.policies, policy)
+	}
 }
 
 // NewTokenService creates a new token service
@@ -24,17 +63,25 @@ func NewTokenService(
 	dataSources *DataSourceRegistry,
 	issuerRegistry Registry,
 	observer TokenServiceObserver,
+	opts ...TokenServiceOption,
 ) *TokenService {
 	// Use null object pattern - default to no-op observer if none provided
 	if observer == nil {
 		observer = NoOpTokenServiceObserver()
 	}
-	return &TokenService{
+	ts := &TokenService{
 		trustDomain:    trustDomain,
 		dataSources:    dataSources,
 		issuerRegistry: issuerRegistry,
 		observer:       observer,
 	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(ts)
+	}
+
+	return ts
 }
 
 // TrustDomain returns the trust domain for this token service
@@ -43,6 +90,20 @@ func (ts *TokenService) TrustDomain() string {
 	return ts.trustDomain
 }
 
+// SupportsTokenType reports whether an issuer is registered for the token type, in
+// any trust domain
+func (ts *TokenService) SupportsTokenType(tokenType TokenType) bool {
+	if slices.Contains(ts.issuerRegistry.ListTokenTypes(), tokenType) {
+		return true
+	}
+	for _, domain := range ts.trustDomains {
+		if slices.Contains(domain.Issuers.ListTokenTypes(), tokenType) {
+			return true
+		}
+	}
+	return false
+}
+
 // IssueRequest contains the inputs for token issuance
 type IssueRequest struct {
 	// Subject identity (attested claims from validated credential)
@@ -52,14 +113,38 @@ type IssueRequest struct {
 	// May be nil if actor identity is not available
 	Actor *trust.Result
 
+	// Workload identity (attested claims from the requesting workload's credential, e.g., its X.509-SVID)
+	// May be nil if the request did not come from an authenticated workload
+	Workload *trust.Result
+
 	// RequestAttributes contains information about the request
 	RequestAttributes *request.RequestAttributes
 
+	// Delegation is the delegation chain to record in issued tokens (RFC 8693 act claim)
+	// May be nil if the subject is not acted on behalf of
+	Delegation *trust.Delegation
+
+	// Audiences of the issued tokens (aud claim)
+	// If empty, tokens are issued for the trust domain
+	Audiences []string
+
+	// TrustDomain selects the trust domain that issues the tokens (see WithTrustDomains)
+	// If empty, the trust domain is the one the audiences belong to
+	TrustDomain string
+
+	// CertificateThumbprint is the x5t#S256 thumbprint of the validated client certificate
+	// to bind issued tokens to (RFC 8705 section 3), or empty for bearer tokens
+	CertificateThumbprint string
+
 	// TokenTypes specifies which token types to issue
 	TokenTypes []TokenType
 
 	// Scope for the tokens
 	Scope string
+
+	// ClientID is the ID of the OAuth client that authenticated for the tokens, if any
+	// Unlike RequestAttributes, callers cannot set it.
+	ClientID string
 }
 
 // IssueTokens orchestrates the complete token issuance process
@@ -69,15 +154,9 @@ func (ts *TokenService) IssueTokens(ctx context.Context, req *IssueRequest) (map
 	ctx, probe := ts.observer.TokenIssuanceStarted(ctx, req.Subject, req.Actor, req.Scope, req.TokenTypes)
 	defer probe.End()
 
-	// Build issue context with base information needed for all issuers
-	// Audience is always the trust domain per transaction token spec
-	issueCtx := &IssueContext{
-		Subject:            req.Subject,
-		Actor:              req.Actor,
-		RequestAttributes:  req.RequestAttributes,
-		Audience:           ts.trustDomain,
-		Scope:              req.Scope,
-		DataSourceRegistry: ts.dataSources,
+	issueCtx, issuerRegistry, err := ts.authorize(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
 	// Issue tokens for each requested type
@@ -85,21 +164,200 @@ func (ts *TokenService) IssueTokens(ctx context.Context, req *IssueRequest) (map
 	for _, tokenType := range req.TokenTypes {
 		probe.TokenTypeIssuanceStarted(tokenType)
 
-		iss, err := ts.issuerRegistry.GetIssuer(tokenType)
+		iss, err := issuerRegistry.GetIssuerFor(tokenType, issueCtx.Audiences)
 		if err != nil {
 			probe.IssuerNotFound(tokenType, err)
 			return nil, fmt.Errorf("no issuer for token type %s: %w", tokenType, err)
 		}
 
-		token, err := iss.Issue(ctx, issueCtx)
+		token, err := ts.issue(ctx, iss, issueCtx)
 		if err != nil {
 			probe.TokenTypeIssuanceFailed(tokenType, err)
 			return nil, fmt.Errorf("failed to issue %s: %w", tokenType, err)
 		}
 
+		if err := ts.checkMaxTTL(tokenType, token); err != nil {
+			probe.TokenTypeIssuanceFailed(tokenType, err)
+			return nil, fmt.Errorf("failed to issue %s: %w", tokenType, err)
+		}
+
 		probe.TokenTypeIssuanceSucceeded(tokenType, token)
 		tokens[tokenType] = token
 	}
 
 	return tokens, nil
 }
+
+// PreviewTokens returns the tokens IssueTokens would issue, without their values, so
+// claim mappers can be debugged safely: the request is authorized and every mapper and
+// data source runs, but nothing is signed or stored, and no issuance is observed.
+// Token types whose issuers cannot preview fail with ErrPreviewNotSupported.
+func (ts *TokenService) PreviewTokens(ctx context.Context, req *IssueRequest) (map[TokenType]*Token, error) {
+	issueCtx, issuerRegistry, err := ts.authorize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[TokenType]*Token)
+	for _, tokenType := range req.TokenTypes {
+		iss, err := issuerRegistry.GetIssuerFor(tokenType, issueCtx.Audiences)
+		if err != nil {
+			return nil, fmt.Errorf("no issuer for token type %s: %w", tokenType, err)
+		}
+		previewing, ok := iss.(PreviewingIssuer)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrPreviewNotSupported, tokenType)
+		}
+
+		token, err := previewing.Preview(ctx, issueCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to preview %s: %w", tokenType, err)
+		}
+		if err := ts.checkMaxTTL(tokenType, token); err != nil {
+			return nil, fmt.Errorf("failed to preview %s: %w", tokenType, err)
+		}
+		tokens[tokenType] = token
+	}
+
+	return tokens, nil
+}
+
+// ErrPreviewNotSupported is returned by PreviewTokens for token types whose issuers
+// cannot preview their tokens
+var ErrPreviewNotSupported = errors.New("token preview not supported")
+
+// authorize checks req against the service's authorization policies, returning the
+// context tokens are issued in and the registry of the issuers to issue them
+func (ts *TokenService) authorize(ctx context.Context, req *IssueRequest) (*IssueContext, Registry, error) {
+	// Tokens are issued by the selected trust domain's issuers
+	trustDomain, issuerRegistry := ts.trustDomain, ts.issuerRegistry
+	domain, err := ts.ResolveTrustDomain(req.TrustDomain, req.Audiences)
+	if err != nil {
+		return nil, nil, err
+	}
+	if domain != nil {
+		trustDomain, issuerRegistry = domain.Name, domain.Issuers
+	}
+
+	// Build issue context with base information needed for all issuers
+	// Audience defaults to the trust domain per transaction token spec
+	audiences := req.Audiences
+	if len(audiences) == 0 {
+		audiences = []string{trustDomain}
+	}
+	issueCtx := &IssueContext{
+		Subject:               req.Subject,
+		Actor:                 req.Actor,
+		Workload:              req.Workload,
+		RequestAttributes:     req.RequestAttributes,
+		Delegation:            req.Delegation,
+		Audiences:             audiences,
+		CertificateThumbprint: req.CertificateThumbprint,
+		Scope:                 req.Scope,
+		ClientID:              req.ClientID,
+		DataSourceRegistry:    ts.dataSources,
+	}
+
+	for _, policy := range ts.policies {
+		if err := policy.Authorize(ctx, req); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return issueCtx, issuerRegistry, nil
+}
+
+// issue issues a token with iss, again if its ID was already issued
+// A ReplayCheckingIssuer keeps none of the tokens rejected for their IDs.
+func (ts *TokenService) issue(ctx context.Context, iss Issuer, issueCtx *IssueContext) (*Token, error) {
+	if ts.replayDetector == nil {
+		return iss.Issue(ctx, issueCtx)
+	}
+	checking, _ := iss.(ReplayCheckingIssuer)
+	for attempt := 1; ; attempt++ {
+		var token *Token
+		var err error
+		if checking != nil {
+			token, err = checking.IssueChecked(ctx, issueCtx, ts.checkTokenID)
+		} else if token, err = iss.Issue(ctx, issueCtx); err == nil {
+			err = ts.checkTokenID(ctx, token)
+		}
+		if err == nil {
+			return token, nil
+		}
+		if !errors.Is(err, errTokenIDSeen) {
+			return nil, err
+		}
+		if attempt == maxReplayAttempts {
+			return nil, fmt.Errorf("%w, %d times", err, attempt)
+		}
+	}
+}
+
+// checkMaxTTL returns an error if the token lives longer than the maximum for its type
+func (ts *TokenService) checkMaxTTL(tokenType TokenType, token *Token) error {
+	maxTTL, ok := ts.maxTTLs[tokenType]
+	if !ok {
+		return nil
+	}
+	if token.ExpiresAt.IsZero() {
+		return fmt.Errorf("token does not expire, but max TTL is %s", maxTTL)
+	}
+	if ttl := token.ExpiresAt.Sub(token.IssuedAt); ttl > maxTTL {
+		return fmt.Errorf("token TTL %s exceeds max TTL %s", ttl, maxTTL)
+	}
+	return nil
+}
+
+// DecisionDataSourcePolicy is an AuthorizationPolicy whose decisions are the results
+// of a data source, such as an OPA policy
+// The result must be true, or an object whose "allow" field is true. An object's
+// "reason" field, if a string, explains a denial.
+type DecisionDataSourcePolicy struct {
+	source DataSource
+}
+
+// NewDecisionDataSourcePolicy creates a policy deciding with source
+func NewDecisionDataSourcePolicy(source DataSource) *DecisionDataSourcePolicy {
+	return &DecisionDataSourcePolicy{source: source}
+}
+
+// Authorize implements AuthorizationPolicy
+func (p *DecisionDataSourcePolicy) Authorize(ctx context.Context, req *IssueRequest) error {
+	result, err := p.source.Fetch(ctx, &DataSourceInput{
+		Subject:           req.Subject,
+		Actor:             req.Actor,
+		RequestAttributes: req.RequestAttributes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch decision: %w", err)
+	}
+	if result == nil {
+		return fmt.Errorf("%w: no decision", ErrIssuanceDenied)
+	}
+	if result.ContentType != ContentTypeJSON {
+		return fmt.Errorf("decision has unsupported content type %s", result.ContentType)
+	}
+
+	var allowed bool
+	if err := json.Unmarshal(result.Data, &allowed); err == nil {
+		if !allowed {
+			return ErrIssuanceDenied
+		}
+		return nil
+	}
+	var decision struct {
+		Allow  bool `json:"allow"`
+		Reason any  `json:"reason"`
+	}
+	if err := json.Unmarshal(result.Data, &decision); err != nil {
+		return fmt.Errorf("decision must be a boolean or an object: %w", err)
+	}
+	if !decision.Allow {
+		if reason, ok := decision.Reason.(string); ok && reason != "" {
+			return fmt.Errorf("%w: %s", ErrIssuanceDenied, reason)
+		}
+		return ErrIssuanceDenied
+	}
+	return nil
+}
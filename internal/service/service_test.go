@@ -3,9 +3,12 @@ package service
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/alechenninger/parsec/internal/clock"
 	"github.com/alechenninger/parsec/internal/trust"
 )
 
@@ -212,3 +215,247 @@ func (i *testIssuerStub) Issue(ctx context.Context, issueCtx *IssueContext) (*To
 func (i *testIssuerStub) PublicKeys(ctx context.Context) ([]PublicKey, error) {
 	return nil, nil
 }
+
+func TestTokenService_IssueTokens_MaxTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	issue := func(token *Token) error {
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeTransactionToken, &testIssuerStub{token: token})
+
+		service := NewTokenService("trust.example.com", nil, registry, nil,
+			WithMaxTTLs(map[TokenType]time.Duration{TokenTypeTransactionToken: 15 * time.Minute}))
+
+		_, err := service.IssueTokens(ctx, &IssueRequest{
+			Subject:    &trust.Result{Subject: "user-123"},
+			TokenTypes: []TokenType{TokenTypeTransactionToken},
+		})
+		return err
+	}
+
+	t.Run("within max TTL", func(t *testing.T) {
+		if err := issue(&Token{IssuedAt: now, ExpiresAt: now.Add(15 * time.Minute)}); err != nil {
+			t.Errorf("expected issuance to succeed, got %v", err)
+		}
+	})
+
+	t.Run("exceeds max TTL", func(t *testing.T) {
+		if err := issue(&Token{IssuedAt: now, ExpiresAt: now.Add(7 * 24 * time.Hour)}); err == nil {
+			t.Error("expected issuance to fail")
+		}
+	})
+
+	t.Run("never expires", func(t *testing.T) {
+		if err := issue(&Token{IssuedAt: now}); err == nil {
+			t.Error("expected issuance to fail")
+		}
+	})
+}
+
+// staticDataSource returns the same JSON for every input
+type staticDataSource struct {
+	name string
+	data string
+}
+
+func (s *staticDataSource) Name() string {
+	return s.name
+}
+
+func (s *staticDataSource) Fetch(ctx context.Context, input *DataSourceInput) (*DataSourceResult, error) {
+	if s.data == "" {
+		return nil, nil
+	}
+	return &DataSourceResult{Data: []byte(s.data), ContentType: ContentTypeJSON}, nil
+}
+
+func TestTokenService_IssueTokens_Decision(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	issue := func(decision string) error {
+		registry := NewSimpleRegistry()
+		registry.Register(TokenTypeTransactionToken, &testIssuerStub{token: &Token{IssuedAt: now, ExpiresAt: now.Add(time.Minute)}})
+		policy := NewDecisionDataSourcePolicy(&staticDataSource{name: "policy", data: decision})
+
+		service := NewTokenService("trust.example.com", nil, registry, nil, WithAuthorizationPolicy(policy))
+
+		_, err := service.IssueTokens(ctx, &IssueRequest{
+			Subject:    &trust.Result{Subject: "user-123"},
+			TokenTypes: []TokenType{TokenTypeTransactionToken},
+		})
+		return err
+	}
+
+	for _, decision := range []string{`true`, `{"allow":true}`} {
+		if err := issue(decision); err != nil {
+			t.Errorf("expected %s to allow issuance, got %v", decision, err)
+		}
+	}
+	for _, decision := range []string{`false`, `{"allow":false}`, `{}`, ``} {
+		if err := issue(decision); !errors.Is(err, ErrIssuanceDenied) {
+			t.Errorf("expected %q to deny issuance, got %v", decision, err)
+		}
+	}
+
+	err := issue(`{"allow":false,"reason":"subject is suspended"}`)
+	if !errors.Is(err, ErrIssuanceDenied) || !strings.Contains(err.Error(), "subject is suspended") {
+		t.Errorf("expected denial with reason, got %v", err)
+	}
+
+	if err := issue(`"yes"`); err == nil || errors.Is(err, ErrIssuanceDenied) {
+		t.Errorf("expected a malformed decision to fail issuance, got %v", err)
+	}
+}
+
+// sequenceIssuer issues tokens with the next of its IDs
+type sequenceIssuer struct {
+	ids []string
+}
+
+func (i *sequenceIssuer) Issue(ctx context.Context, issueCtx *IssueContext) (*Token, error) {
+	id := i.ids[0]
+	i.ids = i.ids[1:]
+	now := time.Now()
+	return &Token{Value: id, ID: id, IssuedAt: now, ExpiresAt: now.Add(time.Minute)}, nil
+}
+
+func (i *sequenceIssuer) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	return nil, nil
+}
+
+func TestTokenService_IssueTokens_ReplayDetection(t *testing.T) {
+	ctx := context.Background()
+
+	issuer := &sequenceIssuer{ids: []string{"a", "a", "b", "a", "b", "b", "a"}}
+	registry := NewSimpleRegistry().Register(TokenTypeTransactionToken, issuer)
+	service := NewTokenService("trust.example.com", nil, registry, nil,
+		WithReplayDetector(NewMemoryReplayDetector(nil, 0)))
+
+	issue := func() (string, error) {
+		tokens, err := service.IssueTokens(ctx, &IssueRequest{
+			Subject:    &trust.Result{Subject: "user-123"},
+			TokenTypes: []TokenType{TokenTypeTransactionToken},
+		})
+		if err != nil {
+			return "", err
+		}
+		return tokens[TokenTypeTransactionToken].Value, nil
+	}
+
+	if id, err := issue(); err != nil || id != "a" {
+		t.Fatalf("expected token a, got %q, %v", id, err)
+	}
+	if id, err := issue(); err != nil || id != "b" {
+		t.Fatalf("expected a to be reissued as b, got %q, %v", id, err)
+	}
+	if _, err := issue(); err == nil {
+		t.Fatal("expected issuance to fail after every attempt reused an ID")
+	}
+}
+
+// keepingIssuer is a ReplayCheckingIssuer that keeps the IDs of the tokens it issues
+type keepingIssuer struct {
+	sequenceIssuer
+	kept []string
+}
+
+func (i *keepingIssuer) IssueChecked(ctx context.Context, issueCtx *IssueContext, check func(context.Context, *Token) error) (*Token, error) {
+	token, err := i.Issue(ctx, issueCtx)
+	if err != nil {
+		return nil, err
+	}
+	if err := check(ctx, token); err != nil {
+		return nil, err
+	}
+	i.kept = append(i.kept, token.ID)
+	return token, nil
+}
+
+// failingReplayDetector fails every check
+type failingReplayDetector struct{}
+
+func (failingReplayDetector) Seen(ctx context.Context, tokenID string, expiresAt time.Time) (bool, error) {
+	return false, errors.New("detector unavailable")
+}
+
+func TestTokenService_IssueTokens_ReplayCheckingIssuer(t *testing.T) {
+	ctx := context.Background()
+	req := &IssueRequest{
+		Subject:    &trust.Result{Subject: "user-123"},
+		TokenTypes: []TokenType{TokenTypeTransactionToken},
+	}
+
+	t.Run("keeps only tokens with new IDs", func(t *testing.T) {
+		issuer := &keepingIssuer{sequenceIssuer: sequenceIssuer{ids: []string{"a", "a", "b"}}}
+		registry := NewSimpleRegistry().Register(TokenTypeTransactionToken, issuer)
+		service := NewTokenService("trust.example.com", nil, registry, nil,
+			WithReplayDetector(NewMemoryReplayDetector(nil, 0)))
+
+		for range 2 {
+			if _, err := service.IssueTokens(ctx, req); err != nil {
+				t.Fatalf("IssueTokens() failed: %v", err)
+			}
+		}
+		if !slices.Equal(issuer.kept, []string{"a", "b"}) {
+			t.Errorf("expected tokens a and b to be kept, got %v", issuer.kept)
+		}
+	})
+
+	t.Run("keeps nothing when IDs cannot be checked", func(t *testing.T) {
+		issuer := &keepingIssuer{sequenceIssuer: sequenceIssuer{ids: []string{"a"}}}
+		registry := NewSimpleRegistry().Register(TokenTypeTransactionToken, issuer)
+		service := NewTokenService("trust.example.com", nil, registry, nil,
+			WithReplayDetector(failingReplayDetector{}))
+
+		if _, err := service.IssueTokens(ctx, req); err == nil {
+			t.Fatal("expected issuance to fail")
+		}
+		if len(issuer.kept) != 0 {
+			t.Errorf("expected no tokens to be kept, got %v", issuer.kept)
+		}
+	})
+}
+
+func TestMemoryReplayDetector(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	detector := NewMemoryReplayDetector(clk, 2)
+
+	seen := func(id string, ttl time.Duration) bool {
+		t.Helper()
+		seen, err := detector.Seen(ctx, id, clk.Now().Add(ttl))
+		if err != nil {
+			t.Fatalf("Seen() failed: %v", err)
+		}
+		return seen
+	}
+
+	if seen("a", time.Minute) || seen("b", time.Hour) {
+		t.Fatal("expected new IDs to be unseen")
+	}
+	if !seen("a", time.Minute) {
+		t.Error("expected a to be seen")
+	}
+
+	// Full: the ID expiring soonest is forgotten
+	if seen("c", time.Hour) {
+		t.Error("expected c to be unseen")
+	}
+	if !seen("b", time.Hour) {
+		t.Error("expected b to still be recorded")
+	}
+	if seen("a", time.Minute) {
+		t.Error("expected a to have been evicted")
+	}
+
+	// Expired IDs are forgotten
+	clk.Advance(2 * time.Hour)
+	if seen("b", time.Hour) {
+		t.Error("expected b to have expired")
+	}
+	if len(detector.expiresAt) != 1 {
+		t.Errorf("expected expired IDs to be swept, %d recorded", len(detector.expiresAt))
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"maps"
+	"strings"
 
 	"github.com/alechenninger/parsec/internal/claims"
 )
@@ -41,11 +42,15 @@ func (p *PassthroughSubjectMapper) Map(ctx context.Context, input *MapperInput)
 }
 
 // RequestAttributesMapper creates claims from request attributes
-type RequestAttributesMapper struct{}
+type RequestAttributesMapper struct {
+	headers []string
+}
 
-// NewRequestAttributesMapper creates a mapper that includes request attributes
-func NewRequestAttributesMapper() *RequestAttributesMapper {
-	return &RequestAttributesMapper{}
+// NewRequestAttributesMapper creates a mapper that includes request attributes and
+// the named headers, with every value of repeated headers. Other headers, which may
+// carry credentials, are not included.
+func NewRequestAttributesMapper(headers ...string) *RequestAttributesMapper {
+	return &RequestAttributesMapper{headers: headers}
 }
 
 // Map implements the ClaimMapper interface
@@ -67,6 +72,32 @@ func (r *RequestAttributesMapper) Map(ctx context.Context, input *MapperInput) (
 	if input.RequestAttributes.UserAgent != "" {
 		result["user_agent"] = input.RequestAttributes.UserAgent
 	}
+	if input.RequestAttributes.Protocol != "" {
+		result["protocol"] = input.RequestAttributes.Protocol
+	}
+	if input.RequestAttributes.Authority != "" {
+		result["authority"] = input.RequestAttributes.Authority
+	}
+	if input.RequestAttributes.ContentType != "" {
+		result["content_type"] = input.RequestAttributes.ContentType
+	}
+	if grpc := input.RequestAttributes.GRPC; grpc != nil {
+		result["grpc"] = map[string]any{
+			"service":   grpc.Service,
+			"method":    grpc.Method,
+			"authority": grpc.Authority,
+		}
+	}
+
+	headers := make(map[string]any)
+	for _, name := range r.headers {
+		if values := input.RequestAttributes.Headers.Values(name); len(values) > 0 {
+			headers[strings.ToLower(name)] = values
+		}
+	}
+	if len(headers) > 0 {
+		result["headers"] = headers
+	}
 
 	// Include all items from Additional map
 	maps.Copy(result, input.RequestAttributes.Additional)
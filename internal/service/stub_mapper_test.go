@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/request"
+)
+
+func TestRequestAttributesMapper(t *testing.T) {
+	headers := request.Headers{}
+	headers.Add("X-Forwarded-For", "203.0.113.1")
+	headers.Add("X-Forwarded-For", "198.51.100.2")
+	headers.Add("X-Region", "us-east-1")
+	headers.Add("Authorization", "Bearer secret")
+	input := &MapperInput{
+		RequestAttributes: &request.RequestAttributes{Method: "GET", Path: "/orders", Headers: headers},
+	}
+
+	t.Run("includes every value of the named headers", func(t *testing.T) {
+		claims, err := NewRequestAttributesMapper("x-forwarded-for", "X-Region", "x-missing").Map(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Map failed: %v", err)
+		}
+		want := map[string]any{
+			"x-forwarded-for": []string{"203.0.113.1", "198.51.100.2"},
+			"x-region":        []string{"us-east-1"},
+		}
+		if !reflect.DeepEqual(claims["headers"], want) {
+			t.Errorf("expected headers %v, got %v", want, claims["headers"])
+		}
+		if claims["method"] != "GET" || claims["path"] != "/orders" {
+			t.Errorf("expected method and path, got %v", claims)
+		}
+	})
+
+	t.Run("includes no headers unless named", func(t *testing.T) {
+		claims, err := NewRequestAttributesMapper().Map(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Map failed: %v", err)
+		}
+		if _, ok := claims["headers"]; ok {
+			t.Errorf("expected no headers, got %v", claims["headers"])
+		}
+	})
+}
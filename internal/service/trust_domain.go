@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownTrustDomain is returned (wrapped) when a request selects a trust domain
+// that is not configured, or audiences of more than one trust domain
+var ErrUnknownTrustDomain = errors.New("unknown trust domain")
+
+// TrustDomain is an additional trust domain a TokenService issues tokens for, with
+// its own issuers (and so issuer URL and keys), audiences, and validators
+type TrustDomain struct {
+	// Name is the trust domain, which is the default audience of its tokens
+	Name string
+
+	// Audiences are the audiences of the trust domain, besides its name
+	// An entry like "*.example.com" matches any subdomain of example.com.
+	Audiences []string
+
+	// Issuers issue the trust domain's tokens
+	Issuers Registry
+
+	// Validators, if set, are the only validators that may validate credentials
+	// for tokens of the trust domain
+	Validators []string
+}
+
+// WithTrustDomains lets the service issue tokens for trust domains besides its own
+// A request selects a trust domain by name or by its audiences; requests that select
+// none are issued by the service's own trust domain.
+func WithTrustDomains(domains ...*TrustDomain) TokenServiceOption {
+	return func(ts *TokenService) {
+		ts.trustDomains = append(ts.trustDomains, domains...)
+	}
+}
+
+// HasAudience reports whether aud is the trust domain or one of its audiences
+func (d *TrustDomain) HasAudience(aud string) bool {
+	if aud == d.Name {
+		return true
+	}
+	for _, pattern := range d.Audiences {
+		if pattern == aud {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(aud, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveTrustDomain returns the trust domain a request is issued by: the one named,
+// if name is set, or else the one all of audiences belong to. It returns nil for the
+// service's own trust domain.
+func (ts *TokenService) ResolveTrustDomain(name string, audiences []string) (*TrustDomain, error) {
+	if name != "" {
+		if name == ts.trustDomain {
+			return nil, nil
+		}
+		i := slices.IndexFunc(ts.trustDomains, func(d *TrustDomain) bool { return d.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTrustDomain, name)
+		}
+		return ts.trustDomains[i], nil
+	}
+
+	var resolved *TrustDomain
+	for i, aud := range audiences {
+		domain := ts.trustDomainOf(aud)
+		if i > 0 && domain != resolved {
+			return nil, fmt.Errorf("%w: audiences %s belong to more than one trust domain",
+				ErrUnknownTrustDomain, strings.Join(audiences, ", "))
+		}
+		resolved = domain
+	}
+	return resolved, nil
+}
+
+// AllowsAudience reports whether aud belongs to a trust domain of the service, other
+// than its own trust domain's name
+func (ts *TokenService) AllowsAudience(aud string) bool {
+	return ts.trustDomainOf(aud) != nil
+}
+
+// trustDomainOf returns the first trust domain aud belongs to, or nil for the service's own
+func (ts *TokenService) trustDomainOf(aud string) *TrustDomain {
+	if aud == ts.trustDomain {
+		return nil
+	}
+	for _, domain := range ts.trustDomains {
+		if domain.HasAudience(aud) {
+			return domain
+		}
+	}
+	return nil
+}
+
+// TrustDomainsRegistry is a Registry of a service's own issuers whose public keys
+// include those of its other trust domains, so one JWKS can verify all of them
+type TrustDomainsRegistry struct {
+	Registry
+	domains []*TrustDomain
+}
+
+// NewTrustDomainsRegistry creates a registry of registry's issuers and the keys of
+// domains' issuers
+func NewTrustDomainsRegistry(registry Registry, domains []*TrustDomain) *TrustDomainsRegistry {
+	return &TrustDomainsRegistry{Registry: registry, domains: domains}
+}
+
+// GetAllPublicKeys implements Registry
+func (r *TrustDomainsRegistry) GetAllPublicKeys(ctx context.Context) ([]PublicKey, error) {
+	allKeys, err := r.Registry.GetAllPublicKeys(ctx)
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, domain := range r.domains {
+		keys, err := domain.Issuers.GetAllPublicKeys(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("trust domain %s: %w", domain.Name, err))
+		}
+		allKeys = append(allKeys, keys...)
+	}
+	return allKeys, errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// echoIssuer issues tokens whose value is its name and first audience
+type echoIssuer struct {
+	name string
+}
+
+func (i *echoIssuer) Issue(ctx context.Context, issueCtx *IssueContext) (*Token, error) {
+	return &Token{Value: i.name + ":" + issueCtx.Audiences[0]}, nil
+}
+
+func (i *echoIssuer) PublicKeys(ctx context.Context) ([]PublicKey, error) {
+	return []PublicKey{{KeyID: i.name}}, nil
+}
+
+func TestTokenService_TrustDomains(t *testing.T) {
+	ctx := context.Background()
+
+	partners := &TrustDomain{
+		Name:      "partners.example.com",
+		Audiences: []string{"*.partners.example.com", "billing.example.net"},
+		Issuers:   NewSimpleRegistry().Register(TokenTypeTransactionToken, &echoIssuer{name: "partners"}),
+	}
+	registry := NewSimpleRegistry().Register(TokenTypeTransactionToken, &echoIssuer{name: "default"})
+	ts := NewTokenService("trust.example.com", nil, registry, nil, WithTrustDomains(partners))
+
+	issue := func(t *testing.T, req *IssueRequest) (string, error) {
+		t.Helper()
+		req.Subject = &trust.Result{Subject: "alice"}
+		req.TokenTypes = []TokenType{TokenTypeTransactionToken}
+		tokens, err := ts.IssueTokens(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		return tokens[TokenTypeTransactionToken].Value, nil
+	}
+
+	tests := []struct {
+		name string
+		req  *IssueRequest
+		want string
+	}{
+		{"defaults to the service's trust domain", &IssueRequest{}, "default:trust.example.com"},
+		{"selects a trust domain by name", &IssueRequest{TrustDomain: "partners.example.com"}, "partners:partners.example.com"},
+		{"selects the service's trust domain by name", &IssueRequest{TrustDomain: "trust.example.com"}, "default:trust.example.com"},
+		{"selects a trust domain by audience", &IssueRequest{Audiences: []string{"billing.example.net"}}, "partners:billing.example.net"},
+		{"matches subdomain audiences", &IssueRequest{Audiences: []string{"api.partners.example.com"}}, "partners:api.partners.example.com"},
+		{"other audiences belong to the service's trust domain", &IssueRequest{Audiences: []string{"orders.example.com"}}, "default:orders.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := issue(t, tt.req)
+			if err != nil {
+				t.Fatalf("IssueTokens failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("rejects unknown trust domains and mixed audiences", func(t *testing.T) {
+		for _, req := range []*IssueRequest{
+			{TrustDomain: "unknown.example.com"},
+			{Audiences: []string{"api.partners.example.com", "orders.example.com"}},
+		} {
+			if _, err := issue(t, req); !errors.Is(err, ErrUnknownTrustDomain) {
+				t.Errorf("expected ErrUnknownTrustDomain for %+v, got %v", req, err)
+			}
+		}
+	})
+
+	t.Run("wildcards match only subdomains", func(t *testing.T) {
+		if partners.HasAudience("evilpartners.example.com") {
+			t.Error("expected evilpartners.example.com not to match *.partners.example.com")
+		}
+		if !ts.AllowsAudience("api.partners.example.com") || ts.AllowsAudience("orders.example.com") {
+			t.Error("expected only the trust domain's audiences to be allowed")
+		}
+	})
+
+	t.Run("publishes the keys of every trust domain", func(t *testing.T) {
+		keys, err := NewTrustDomainsRegistry(registry, []*TrustDomain{partners}).GetAllPublicKeys(ctx)
+		if err != nil {
+			t.Fatalf("GetAllPublicKeys failed: %v", err)
+		}
+		if len(keys) != 2 {
+			t.Errorf("expected the keys of both trust domains, got %v", keys)
+		}
+	})
+}
@@ -15,15 +15,27 @@ const (
 	// TokenTypeJWT is a JWT token (generic)
 	TokenTypeJWT TokenType = "urn:ietf:params:oauth:token-type:jwt"
 
+	// TokenTypeTransactionTokenCWT is a transaction token encoded as a CWT, for clients that
+	// select the CWT format with requested_token_type
+	TokenTypeTransactionTokenCWT TokenType = "urn:parsec:params:oauth:token-type:txn_token_cwt"
+
+	// TokenTypeBiscuit is an attenuable Biscuit token
+	TokenTypeBiscuit TokenType = "urn:parsec:params:oauth:token-type:biscuit"
+
 	// TokenTypeRHIdentity is a Red Hat identity token (x-rh-identity format)
 	TokenTypeRHIdentity TokenType = "urn:redhat:params:oauth:token-type:rh-identity"
 )
 
-// Registry manages multiple issuers by token type
+// Registry manages multiple issuers by token type, and optionally by audience
 type Registry interface {
-	// GetIssuer returns an issuer for the specified token type
+	// GetIssuer returns the issuer for the specified token type, for any audience
 	GetIssuer(tokenType TokenType) (Issuer, error)
 
+	// GetIssuerFor returns the issuer for the specified token type and audiences:
+	// the first registered for every one of the audiences, or else the issuer for any
+	// audience. Audiences are often trust domains.
+	GetIssuerFor(tokenType TokenType, audiences []string) (Issuer, error)
+
 	// ListTokenTypes returns all registered token types
 	ListTokenTypes() []TokenType
 
@@ -0,0 +1,56 @@
+package tokenstore
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// MemoryStore keeps token records in memory
+// Records are not shared between replicas, so it suits single-replica deployments and tests.
+type MemoryStore struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+// NewMemoryStore creates a new in-memory token store
+// clk is optional (defaults to system clock)
+func NewMemoryStore(clk clock.Clock) *MemoryStore {
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &MemoryStore{
+		clock:   clk,
+		records: make(map[string]*Record),
+	}
+}
+
+// Put implements Store
+// Expired records are removed as new ones are added.
+func (s *MemoryStore) Put(_ context.Context, key string, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	maps.DeleteFunc(s.records, func(_ string, r *Record) bool {
+		return !now.Before(r.ExpiresAt)
+	})
+	s.records[key] = record
+	return nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(_ context.Context, key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if !ok || !s.clock.Now().Before(record.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
@@ -0,0 +1,93 @@
+package tokenstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// DefaultRedisKeyPrefix is the default prefix of the Redis keys holding token records
+const DefaultRedisKeyPrefix = "parsec:tokens:"
+
+// RedisStore keeps token records in Redis, so every replica sharing the Redis server
+// can introspect tokens any replica issued
+// Each record is a JSON string that Redis expires with the token.
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+	clock  clock.Clock
+}
+
+// RedisStoreConfig configures the Redis token store
+type RedisStoreConfig struct {
+	// Client is the Redis client. The caller owns it.
+	Client redis.UniversalClient
+
+	// KeyPrefix prefixes the Redis keys of records (default: DefaultRedisKeyPrefix)
+	KeyPrefix string
+
+	// Clock is an optional clock for testing (defaults to system clock)
+	Clock clock.Clock
+}
+
+// NewRedisStore creates a new Redis-backed token store
+func NewRedisStore(cfg RedisStoreConfig) (*RedisStore, error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("redis token store requires a client")
+	}
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultRedisKeyPrefix
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+	return &RedisStore{
+		client: cfg.Client,
+		prefix: prefix,
+		clock:  clk,
+	}, nil
+}
+
+// Put implements Store
+func (s *RedisStore) Put(ctx context.Context, key string, record *Record) error {
+	ttl := record.ExpiresAt.Sub(s.clock.Now())
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token record: %w", err)
+	}
+	if err := s.client.Set(ctx, s.prefix+key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store token record: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store
+func (s *RedisStore) Get(ctx context.Context, key string) (*Record, error) {
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token record: %w", err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token record: %w", err)
+	}
+	// Redis expiry has millisecond precision; don't return a record that just expired
+	if !s.clock.Now().Before(record.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
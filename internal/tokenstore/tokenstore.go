@@ -0,0 +1,42 @@
+package tokenstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a store has no record for a token, or it has expired
+var ErrNotFound = errors.New("token not found")
+
+// Record is what a store keeps about an opaque token
+type Record struct {
+	// TokenType is the OAuth token type URN the token was issued as
+	TokenType string `json:"token_type"`
+
+	// Claims are the token's claims, as they would be in a JWT
+	Claims map[string]any `json:"claims"`
+
+	// ExpiresAt is when the token expires; stores may forget it afterwards
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Store keeps the records of opaque tokens, so they can be introspected
+// Records are keyed by Key, never by the token itself, so the contents of a store
+// cannot be used as tokens.
+type Store interface {
+	// Put stores the record of the token with the given key until it expires
+	Put(ctx context.Context, key string, record *Record) error
+
+	// Get returns the record of the token with the given key
+	// Returns ErrNotFound if there is none or it has expired.
+	Get(ctx context.Context, key string) (*Record, error)
+}
+
+// Key returns the key of a token's record: its base64url-encoded SHA-256 hash
+func Key(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,95 @@
+package tokenstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+func testStore(t *testing.T, newStore func(t *testing.T, clk clock.Clock) Store) {
+	ctx := context.Background()
+
+	t.Run("round trips records", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		store := newStore(t, clk)
+
+		key := Key("token")
+		record := &Record{
+			TokenType: "urn:ietf:params:oauth:token-type:access_token",
+			Claims:    map[string]any{"sub": "user@example.com"},
+			ExpiresAt: clk.Now().Add(time.Minute),
+		}
+		if err := store.Put(ctx, key, record); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+
+		got, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("failed to get: %v", err)
+		}
+		if got.TokenType != record.TokenType || got.Claims["sub"] != "user@example.com" {
+			t.Errorf("unexpected record: %+v", got)
+		}
+		if !got.ExpiresAt.Equal(record.ExpiresAt) {
+			t.Errorf("expected expiry %v, got %v", record.ExpiresAt, got.ExpiresAt)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		store := newStore(t, clock.NewSystemClock())
+		if _, err := store.Get(ctx, Key("unknown")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("expired record", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+		store := newStore(t, clk)
+
+		key := Key("token")
+		record := &Record{Claims: map[string]any{}, ExpiresAt: clk.Now().Add(time.Minute)}
+		if err := store.Put(ctx, key, record); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+
+		clk.Advance(time.Minute)
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T, clk clock.Clock) Store {
+		return NewMemoryStore(clk)
+	})
+}
+
+func TestRedisStore(t *testing.T) {
+	testStore(t, func(t *testing.T, clk clock.Clock) Store {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		store, err := NewRedisStore(RedisStoreConfig{Client: client, Clock: clk})
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		return store
+	})
+}
+
+func TestKey(t *testing.T) {
+	if Key("a") == Key("b") {
+		t.Error("expected different tokens to have different keys")
+	}
+	if Key("token") == "token" {
+		t.Error("expected the key not to be the token")
+	}
+}
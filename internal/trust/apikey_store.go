@@ -0,0 +1,244 @@
+package trust
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/alechenninger/parsec/internal/claims"
+)
+
+// StaticAPIKeyStore looks up API keys in a fixed set, indexed by key digest
+type StaticAPIKeyStore struct {
+	keys map[string]*APIKey
+}
+
+// StaticAPIKeyEntry is one key of a static store
+type StaticAPIKeyEntry struct {
+	// KeySHA256 is the hex SHA-256 digest of the key (see HashAPIKey)
+	KeySHA256 string `yaml:"key_sha256" json:"key_sha256"`
+
+	// Key is the key itself, as an alternative to KeySHA256
+	Key string `yaml:"key" json:"key"`
+
+	Owner  string         `yaml:"owner" json:"owner"`
+	Tenant string         `yaml:"tenant" json:"tenant"`
+	Claims map[string]any `yaml:"claims" json:"claims"`
+
+	// ExpiresAt is an RFC 3339 timestamp; empty if the key does not expire
+	ExpiresAt string `yaml:"expires_at" json:"expires_at"`
+}
+
+// NewStaticAPIKeyStore creates a static store from entries
+func NewStaticAPIKeyStore(entries []StaticAPIKeyEntry) (*StaticAPIKeyStore, error) {
+	store := &StaticAPIKeyStore{keys: make(map[string]*APIKey, len(entries))}
+	for i, entry := range entries {
+		digest := strings.ToLower(entry.KeySHA256)
+		switch {
+		case digest != "" && entry.Key != "":
+			return nil, fmt.Errorf("api key %d: key and key_sha256 are mutually exclusive", i)
+		case entry.Key != "":
+			digest = HashAPIKey(entry.Key)
+		case digest == "":
+			return nil, fmt.Errorf("api key %d: key or key_sha256 is required", i)
+		}
+		if raw, err := hex.DecodeString(digest); err != nil || len(raw) != 32 {
+			return nil, fmt.Errorf("api key %d: key_sha256 must be a hex SHA-256 digest", i)
+		}
+		if entry.Owner == "" {
+			return nil, fmt.Errorf("api key %d: owner is required", i)
+		}
+		if _, exists := store.keys[digest]; exists {
+			return nil, fmt.Errorf("api key %d: duplicate key", i)
+		}
+
+		key := &APIKey{
+			Owner:  entry.Owner,
+			Tenant: entry.Tenant,
+			Claims: claims.Claims(entry.Claims),
+		}
+		if entry.ExpiresAt != "" {
+			expiresAt, err := time.Parse(time.RFC3339, entry.ExpiresAt)
+			if err != nil {
+				return nil, fmt.Errorf("api key %d: invalid expires_at: %w", i, err)
+			}
+			key.ExpiresAt = expiresAt
+		}
+		store.keys[digest] = key
+	}
+	return store, nil
+}
+
+// LoadStaticAPIKeyStore creates a static store from a YAML (or JSON) file with a
+// top-level "keys" list of StaticAPIKeyEntry
+func LoadStaticAPIKeyStore(path string) (*StaticAPIKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api keys file: %w", err)
+	}
+	var file struct {
+		Keys []StaticAPIKeyEntry `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse api keys file %s: %w", path, err)
+	}
+	store, err := NewStaticAPIKeyStore(file.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api keys file %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Lookup implements APIKeyStore
+func (s *StaticAPIKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	// Keys are indexed by digest, so lookups do not compare the key itself
+	apiKey, ok := s.keys[HashAPIKey(key)]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return apiKey, nil
+}
+
+// SQLAPIKeyStore looks up API keys in a SQL database by key digest
+type SQLAPIKeyStore struct {
+	db    *sql.DB
+	query string
+}
+
+// SQLAPIKeyStoreConfig configures the SQL API key store
+type SQLAPIKeyStoreConfig struct {
+	// DB is the database handle. The caller owns it and must register the driver.
+	DB *sql.DB
+
+	// Query selects a key by digest. It is run with the key's hex SHA-256 digest
+	// (see HashAPIKey) as its only argument, and must return the columns
+	// owner, tenant, claims (a JSON object), and expires_at; the last three may be NULL.
+	// Use the driver's placeholder syntax, e.g.:
+	//   SELECT owner, tenant, claims, expires_at FROM api_keys WHERE key_sha256 = $1
+	Query string
+}
+
+// NewSQLAPIKeyStore creates a new SQL-backed API key store
+func NewSQLAPIKeyStore(cfg SQLAPIKeyStoreConfig) (*SQLAPIKeyStore, error) {
+	if cfg.DB == nil {
+		return nil, fmt.Errorf("sql api key store requires a database")
+	}
+	if cfg.Query == "" {
+		return nil, fmt.Errorf("sql api key store requires a query")
+	}
+	return &SQLAPIKeyStore{db: cfg.DB, query: cfg.Query}, nil
+}
+
+// Lookup implements APIKeyStore
+func (s *SQLAPIKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	var owner string
+	var tenant, claimsJSON sql.NullString
+	var expiresAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, s.query, HashAPIKey(key)).Scan(&owner, &tenant, &claimsJSON, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api key: %w", err)
+	}
+
+	apiKey := &APIKey{
+		Owner:     owner,
+		Tenant:    tenant.String,
+		ExpiresAt: expiresAt.Time,
+	}
+	if claimsJSON.Valid && claimsJSON.String != "" {
+		if err := json.Unmarshal([]byte(claimsJSON.String), &apiKey.Claims); err != nil {
+			return nil, fmt.Errorf("invalid claims for api key: %w", err)
+		}
+	}
+	return apiKey, nil
+}
+
+// HTTPAPIKeyStore looks up API keys with an HTTP lookup service
+//
+// The store POSTs {"key": "<key>"} as JSON to the lookup URL. The service responds
+// 404 for an unknown key, or 200 with
+// {"owner": "...", "tenant": "...", "claims": {...}, "expires_at": "<RFC 3339>"}.
+type HTTPAPIKeyStore struct {
+	url        string
+	httpClient *http.Client
+}
+
+// HTTPAPIKeyStoreConfig configures the HTTP API key store
+type HTTPAPIKeyStoreConfig struct {
+	// URL is the lookup endpoint
+	URL string
+
+	// HTTPClient is an optional HTTP client for lookups
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+}
+
+// NewHTTPAPIKeyStore creates a new HTTP-backed API key store
+func NewHTTPAPIKeyStore(cfg HTTPAPIKeyStoreConfig) (*HTTPAPIKeyStore, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("http api key store requires a url")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &HTTPAPIKeyStore{url: cfg.URL, httpClient: httpClient}, nil
+}
+
+// Lookup implements APIKeyStore
+func (s *HTTPAPIKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	body, err := json.Marshal(map[string]string{"key": key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode api key lookup: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key lookup request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("api key lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrAPIKeyNotFound
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("api key lookup returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var found struct {
+		Owner     string         `json:"owner"`
+		Tenant    string         `json:"tenant"`
+		Claims    map[string]any `json:"claims"`
+		ExpiresAt time.Time      `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return nil, fmt.Errorf("failed to decode api key lookup response: %w", err)
+	}
+	return &APIKey{
+		Owner:     found.Owner,
+		Tenant:    found.Tenant,
+		Claims:    claims.Claims(found.Claims),
+		ExpiresAt: found.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,144 @@
+package trust
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// ErrAPIKeyNotFound is returned by an APIKeyStore when it does not know a key
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey describes the owner of an API key
+type APIKey struct {
+	// Owner identifies who the key was issued to; it becomes the subject
+	Owner string
+
+	// Tenant is the tenant the owner belongs to, if any
+	Tenant string
+
+	// Claims are additional claims about the owner
+	Claims claims.Claims
+
+	// ExpiresAt is when the key expires (zero if it does not expire)
+	ExpiresAt time.Time
+}
+
+// APIKeyStore looks up API keys
+type APIKeyStore interface {
+	// Lookup returns the API key's details, or ErrAPIKeyNotFound if the key is unknown
+	Lookup(ctx context.Context, key string) (*APIKey, error)
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 digest of an API key
+// Stores keep digests rather than keys
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyValidator validates API keys sent in a request header against an APIKeyStore
+type APIKeyValidator struct {
+	header      string
+	store       APIKeyStore
+	trustDomain string
+	clock       clock.Clock
+}
+
+// APIKeyValidatorConfig contains configuration for API key validation
+type APIKeyValidatorConfig struct {
+	// Header is the request header carrying the API key (e.g., "X-API-Key")
+	Header string
+
+	// Store looks up keys
+	Store APIKeyStore
+
+	// TrustDomain is the trust domain of key owners
+	TrustDomain string
+
+	// Clock is the time source for expiry checks
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// NewAPIKeyValidator creates a new API key validator
+func NewAPIKeyValidator(cfg APIKeyValidatorConfig) (*APIKeyValidator, error) {
+	if cfg.Header == "" {
+		return nil, fmt.Errorf("header is required")
+	}
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &APIKeyValidator{
+		header:      strings.ToLower(cfg.Header),
+		store:       cfg.Store,
+		trustDomain: cfg.TrustDomain,
+		clock:       clk,
+	}, nil
+}
+
+// Header returns the (lowercase) request header the validator reads keys from
+func (v *APIKeyValidator) Header() string {
+	return v.header
+}
+
+// CredentialTypes implements the Validator interface
+func (v *APIKeyValidator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeAPIKey}
+}
+
+// Validate implements the Validator interface
+// The subject is the key's owner; the owner and tenant are also the owner and tenant claims
+func (v *APIKeyValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	apiKey, ok := credential.(*APIKeyCredential)
+	if !ok {
+		return nil, fmt.Errorf("unsupported credential type for API key validator: %T", credential)
+	}
+	if !strings.EqualFold(apiKey.Header, v.header) {
+		return nil, fmt.Errorf("API key in header %s, validator reads %s", apiKey.Header, v.header)
+	}
+	if apiKey.Key == "" {
+		return nil, fmt.Errorf("%w: empty API key", ErrInvalidToken)
+	}
+
+	key, err := v.store.Lookup(ctx, apiKey.Key)
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, fmt.Errorf("%w: unknown API key", ErrInvalidToken)
+		}
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if !key.ExpiresAt.IsZero() && !v.clock.Now().Before(key.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+	if key.Owner == "" {
+		return nil, fmt.Errorf("%w: API key has no owner", ErrInvalidToken)
+	}
+
+	resultClaims := make(claims.Claims, len(key.Claims)+2)
+	resultClaims.Merge(key.Claims)
+	resultClaims["owner"] = key.Owner
+	if key.Tenant != "" {
+		resultClaims["tenant"] = key.Tenant
+	}
+
+	return &Result{
+		Subject:     key.Owner,
+		TrustDomain: v.trustDomain,
+		Claims:      resultClaims,
+		ExpiresAt:   key.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,217 @@
+package trust
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+func TestAPIKeyValidator(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	store, err := NewStaticAPIKeyStore([]StaticAPIKeyEntry{
+		{Key: "build-key", Owner: "build-bot", Tenant: "acme", Claims: map[string]any{"team": "ci"}},
+		{KeySHA256: HashAPIKey("expired-key"), Owner: "old-bot", ExpiresAt: "2025-01-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	validator, err := NewAPIKeyValidator(APIKeyValidatorConfig{
+		Header:      "X-API-Key",
+		Store:       store,
+		TrustDomain: "internal-tools",
+		Clock:       clock.NewFixtureClock(now),
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	t.Run("valid key", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &APIKeyCredential{Key: "build-key", Header: "x-api-key"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "build-bot" {
+			t.Errorf("expected subject build-bot, got %s", result.Subject)
+		}
+		if result.TrustDomain != "internal-tools" {
+			t.Errorf("expected trust domain internal-tools, got %s", result.TrustDomain)
+		}
+		if result.Claims["owner"] != "build-bot" || result.Claims["tenant"] != "acme" {
+			t.Errorf("expected owner and tenant claims, got %v", result.Claims)
+		}
+		if result.Claims["team"] != "ci" {
+			t.Errorf("expected team claim, got %v", result.Claims["team"])
+		}
+	})
+
+	t.Run("rejects unknown key", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &APIKeyCredential{Key: "nope", Header: "x-api-key"})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects expired key", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &APIKeyCredential{Key: "expired-key", Header: "x-api-key"})
+		if !errors.Is(err, ErrExpiredToken) {
+			t.Errorf("expected ErrExpiredToken, got %v", err)
+		}
+	})
+
+	t.Run("ignores keys from other headers", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &APIKeyCredential{Key: "build-key", Header: "x-other-key"})
+		if err == nil {
+			t.Error("expected error for key in another header")
+		}
+	})
+}
+
+func TestStaticAPIKeyStore_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	content := `keys:
+  - key_sha256: "` + HashAPIKey("deploy-key") + `"
+    owner: deployer
+    tenant: acme
+    claims:
+      roles: [deploy]
+    expires_at: "2030-01-01T00:00:00Z"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write keys file: %v", err)
+	}
+
+	store, err := LoadStaticAPIKeyStore(path)
+	if err != nil {
+		t.Fatalf("failed to load store: %v", err)
+	}
+
+	key, err := store.Lookup(context.Background(), "deploy-key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.Owner != "deployer" || key.Tenant != "acme" {
+		t.Errorf("expected deployer in acme, got %s in %s", key.Owner, key.Tenant)
+	}
+	if !key.ExpiresAt.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected expiry %v", key.ExpiresAt)
+	}
+
+	if _, err := store.Lookup(context.Background(), "other"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	if _, err := NewStaticAPIKeyStore([]StaticAPIKeyEntry{{Key: "k", KeySHA256: HashAPIKey("k"), Owner: "x"}}); err == nil {
+		t.Error("expected error for key with key_sha256")
+	}
+}
+
+func TestSQLAPIKeyStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE api_keys (
+		key_sha256 TEXT PRIMARY KEY, owner TEXT NOT NULL, tenant TEXT, claims TEXT, expires_at TIMESTAMP)`); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := db.ExecContext(ctx, `INSERT INTO api_keys VALUES (?, 'reporter', 'acme', '{"team":"data"}', ?), (?, 'scraper', NULL, NULL, NULL)`,
+		HashAPIKey("report-key"), expiresAt, HashAPIKey("scrape-key")); err != nil {
+		t.Fatalf("failed to insert keys: %v", err)
+	}
+
+	store, err := NewSQLAPIKeyStore(SQLAPIKeyStoreConfig{
+		DB:    db,
+		Query: "SELECT owner, tenant, claims, expires_at FROM api_keys WHERE key_sha256 = ?",
+	})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	key, err := store.Lookup(ctx, "report-key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.Owner != "reporter" || key.Tenant != "acme" || key.Claims["team"] != "data" {
+		t.Errorf("unexpected key %+v", key)
+	}
+	if !key.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expected expiry %v, got %v", expiresAt, key.ExpiresAt)
+	}
+
+	key, err = store.Lookup(ctx, "scrape-key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.Owner != "scraper" || key.Tenant != "" || key.Claims != nil || !key.ExpiresAt.IsZero() {
+		t.Errorf("unexpected key %+v", key)
+	}
+
+	if _, err := store.Lookup(ctx, "unknown"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestHTTPAPIKeyStore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var lookup struct {
+			Key string `json:"key"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&lookup); err != nil || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch lookup.Key {
+		case "tool-key":
+			json.NewEncoder(w).Encode(map[string]any{
+				"owner":      "tool",
+				"tenant":     "acme",
+				"claims":     map[string]any{"scope": "read"},
+				"expires_at": "2030-01-01T00:00:00Z",
+			})
+		case "broken-key":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewHTTPAPIKeyStore(HTTPAPIKeyStoreConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	ctx := context.Background()
+
+	key, err := store.Lookup(ctx, "tool-key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.Owner != "tool" || key.Tenant != "acme" || key.Claims["scope"] != "read" {
+		t.Errorf("unexpected key %+v", key)
+	}
+
+	if _, err := store.Lookup(ctx, "unknown"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if _, err := store.Lookup(ctx, "broken-key"); err == nil || errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected lookup error, got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package trust
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// AWSSigV4TokenPrefix prefixes bearer tokens carrying a presigned STS GetCallerIdentity URL
+// The rest of the token is the URL, base64url-encoded without padding
+const AWSSigV4TokenPrefix = "aws-sigv4."
+
+// AWSAudienceHeader is the signed header binding a presigned request to one audience
+// Without it, a request presigned for one service could be replayed to another
+const AWSAudienceHeader = "x-parsec-audience"
+
+// defaultSTSHost matches the global and regional STS endpoints
+var defaultSTSHost = regexp.MustCompile(`^sts(\.[a-z0-9-]+)?\.amazonaws\.com(\.cn)?$`)
+
+// AWSSigV4Validator validates workloads holding IAM credentials
+//
+// The workload presigns an STS GetCallerIdentity request (SigV4, query string) and
+// sends the URL as a bearer token (see AWSSigV4TokenPrefix). The validator sends
+// the request to STS, which verifies the signature and returns the caller's ARN.
+type AWSSigV4Validator struct {
+	trustDomain     string
+	audience        string
+	allowedAccounts []string
+	stsHosts        []string
+	httpClient      *http.Client
+	clock           clock.Clock
+}
+
+// AWSSigV4ValidatorConfig contains configuration for AWS SigV4 validation
+type AWSSigV4ValidatorConfig struct {
+	// TrustDomain is the trust domain of validated callers
+	// If empty, the trust domain is the caller's AWS account ID
+	TrustDomain string
+
+	// Audience, if set, must be the value of the signed x-parsec-audience header
+	Audience string
+
+	// AllowedAccounts restricts callers to these AWS account IDs
+	// If empty, callers from any account are accepted
+	AllowedAccounts []string
+
+	// STSHosts are the STS endpoint hosts presigned requests may target
+	// If empty, the global and regional AWS STS endpoints are allowed
+	STSHosts []string
+
+	// HTTPClient is an optional HTTP client for STS requests
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+
+	// Clock is the time source for request expiry checks
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// NewAWSSigV4Validator creates a new AWS SigV4 validator
+func NewAWSSigV4Validator(cfg AWSSigV4ValidatorConfig) (*AWSSigV4Validator, error) {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &AWSSigV4Validator{
+		trustDomain:     cfg.TrustDomain,
+		audience:        cfg.Audience,
+		allowedAccounts: cfg.AllowedAccounts,
+		stsHosts:        cfg.STSHosts,
+		httpClient:      httpClient,
+		clock:           clk,
+	}, nil
+}
+
+// CredentialTypes implements the Validator interface
+func (v *AWSSigV4Validator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeBearer}
+}
+
+// Validate implements the Validator interface
+// The subject is the caller's IAM ARN; for assumed roles it is the role's ARN,
+// with the session name in the session_name claim
+func (v *AWSSigV4Validator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	bearer, ok := credential.(*BearerCredential)
+	if !ok {
+		return nil, fmt.Errorf("unsupported credential type for AWS SigV4 validator: %T", credential)
+	}
+	encoded, ok := strings.CutPrefix(bearer.Token, AWSSigV4TokenPrefix)
+	if !ok {
+		return nil, fmt.Errorf("not an AWS SigV4 token")
+	}
+	rawURL, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed AWS SigV4 token: %v", ErrInvalidToken, err)
+	}
+
+	stsURL, signedAt, expiresAt, err := v.checkPresignedURL(string(rawURL))
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := v.getCallerIdentity(ctx, stsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	arn, err := parseAWSARN(identity.Arn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if len(v.allowedAccounts) > 0 && !slices.Contains(v.allowedAccounts, identity.Account) {
+		return nil, fmt.Errorf("%w: AWS account %s is not allowed", ErrInvalidToken, identity.Account)
+	}
+
+	trustDomain := v.trustDomain
+	if trustDomain == "" {
+		trustDomain = identity.Account
+	}
+
+	resultClaims := claims.Claims{
+		"arn":       identity.Arn,
+		"account":   identity.Account,
+		"user_id":   identity.UserId,
+		"partition": arn.partition,
+	}
+	subject := identity.Arn
+	if arn.service == "sts" && strings.HasPrefix(arn.resource, "assumed-role/") {
+		// Session names are chosen by the caller; identify the role instead
+		parts := strings.SplitN(strings.TrimPrefix(arn.resource, "assumed-role/"), "/", 2)
+		subject = fmt.Sprintf("arn:%s:iam::%s:role/%s", arn.partition, arn.account, parts[0])
+		if len(parts) == 2 {
+			resultClaims["session_name"] = parts[1]
+		}
+	}
+
+	return &Result{
+		Subject:     subject,
+		Issuer:      "https://" + stsURL.Host,
+		TrustDomain: trustDomain,
+		Claims:      resultClaims,
+		ExpiresAt:   expiresAt,
+		IssuedAt:    signedAt,
+	}, nil
+}
+
+// checkPresignedURL checks that rawURL is a presigned GetCallerIdentity request to an allowed
+// STS endpoint, returning the URL and when it was signed and expires
+func (v *AWSSigV4Validator) checkPresignedURL(rawURL string) (*url.URL, time.Time, time.Time, error) {
+	var zero time.Time
+	stsURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, zero, zero, fmt.Errorf("%w: invalid STS URL: %v", ErrInvalidToken, err)
+	}
+	if stsURL.Scheme != "https" || stsURL.User != nil || !v.allowedSTSHost(stsURL.Host) {
+		return nil, zero, zero, fmt.Errorf("%w: %s is not an allowed STS endpoint", ErrInvalidToken, stsURL.Redacted())
+	}
+	if stsURL.Path != "" && stsURL.Path != "/" {
+		return nil, zero, zero, fmt.Errorf("%w: unexpected STS path %s", ErrInvalidToken, stsURL.Path)
+	}
+
+	query := stsURL.Query()
+	if query.Get("Action") != "GetCallerIdentity" {
+		return nil, zero, zero, fmt.Errorf("%w: presigned request is not GetCallerIdentity", ErrInvalidToken)
+	}
+	if query.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" || query.Get("X-Amz-Signature") == "" {
+		return nil, zero, zero, fmt.Errorf("%w: request is not presigned with SigV4", ErrInvalidToken)
+	}
+
+	signedAt, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, zero, zero, fmt.Errorf("%w: invalid X-Amz-Date: %v", ErrInvalidToken, err)
+	}
+	expiresIn, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expiresIn <= 0 {
+		return nil, zero, zero, fmt.Errorf("%w: invalid X-Amz-Expires", ErrInvalidToken)
+	}
+	expiresAt := signedAt.Add(time.Duration(expiresIn) * time.Second)
+	if !v.clock.Now().Before(expiresAt) {
+		return nil, zero, zero, ErrExpiredToken
+	}
+
+	if v.audience != "" {
+		signedHeaders := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+		if !slices.Contains(signedHeaders, AWSAudienceHeader) {
+			return nil, zero, zero, fmt.Errorf("%w: presigned request does not sign %s", ErrInvalidToken, AWSAudienceHeader)
+		}
+	}
+
+	return stsURL, signedAt, expiresAt, nil
+}
+
+// allowedSTSHost reports whether presigned requests may target host
+func (v *AWSSigV4Validator) allowedSTSHost(host string) bool {
+	if len(v.stsHosts) > 0 {
+		return slices.Contains(v.stsHosts, host)
+	}
+	return defaultSTSHost.MatchString(host)
+}
+
+// callerIdentity is the GetCallerIdentity result
+type callerIdentity struct {
+	Account string
+	Arn     string
+	UserId  string
+}
+
+// getCallerIdentity sends the presigned request to STS
+// STS verifies the signature; a request it rejects is an invalid token
+func (v *AWSSigV4Validator) getCallerIdentity(ctx context.Context, stsURL *url.URL) (*callerIdentity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, stsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create STS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if v.audience != "" {
+		// The signature only verifies if this matches the value the caller signed
+		req.Header.Set(AWSAudienceHeader, v.audience)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("STS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read STS response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return nil, fmt.Errorf("%w: STS rejected the request (status %d)", ErrInvalidToken, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("STS returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		GetCallerIdentityResponse struct {
+			GetCallerIdentityResult callerIdentity
+		}
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode STS response: %w", err)
+	}
+	identity := response.GetCallerIdentityResponse.GetCallerIdentityResult
+	if identity.Arn == "" || identity.Account == "" {
+		return nil, fmt.Errorf("STS response has no caller identity")
+	}
+	return &identity, nil
+}
+
+// awsARN is a parsed ARN (arn:partition:service:region:account:resource)
+type awsARN struct {
+	partition string
+	service   string
+	account   string
+	resource  string
+}
+
+func parseAWSARN(arn string) (awsARN, error) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return awsARN{}, fmt.Errorf("invalid ARN %q", arn)
+	}
+	return awsARN{
+		partition: parts[1],
+		service:   parts[2],
+		account:   parts[4],
+		resource:  parts[5],
+	}, nil
+}
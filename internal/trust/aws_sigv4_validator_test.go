@@ -0,0 +1,181 @@
+package trust
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+func TestAWSSigV4Validator(t *testing.T) {
+	ctx := context.Background()
+	signedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// Fake STS: accepts requests with signature "valid" and the expected audience
+	sts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("Action") != "GetCallerIdentity" || query.Get("X-Amz-Signature") != "valid" ||
+			r.Header.Get(AWSAudienceHeader) != "parsec.example.com" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"GetCallerIdentityResponse": map[string]any{
+				"GetCallerIdentityResult": map[string]any{
+					"Account": "123456789012",
+					"Arn":     "arn:aws:sts::123456789012:assumed-role/billing-worker/i-0abc",
+					"UserId":  "AROAEXAMPLE:i-0abc",
+				},
+			},
+		})
+	}))
+	defer sts.Close()
+	stsHost := sts.Listener.Addr().String()
+
+	token := func(host, signature string, modify func(url.Values)) string {
+		query := url.Values{
+			"Action":              {"GetCallerIdentity"},
+			"Version":             {"2011-06-15"},
+			"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+			"X-Amz-Credential":    {"AKIAEXAMPLE/20250601/us-east-1/sts/aws4_request"},
+			"X-Amz-Date":          {signedAt.Format("20060102T150405Z")},
+			"X-Amz-Expires":       {"900"},
+			"X-Amz-SignedHeaders": {"host;" + AWSAudienceHeader},
+			"X-Amz-Signature":     {signature},
+		}
+		if modify != nil {
+			modify(query)
+		}
+		presigned := "https://" + host + "/?" + query.Encode()
+		return AWSSigV4TokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presigned))
+	}
+
+	newValidator := func(t *testing.T, cfg AWSSigV4ValidatorConfig) *AWSSigV4Validator {
+		t.Helper()
+		cfg.STSHosts = []string{stsHost}
+		cfg.Audience = "parsec.example.com"
+		cfg.HTTPClient = sts.Client()
+		cfg.Clock = clock.NewFixtureClock(signedAt.Add(time.Minute))
+		validator, err := NewAWSSigV4Validator(cfg)
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		return validator
+	}
+	validator := newValidator(t, AWSSigV4ValidatorConfig{})
+
+	t.Run("assumed role", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &BearerCredential{Token: token(stsHost, "valid", nil)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "arn:aws:iam::123456789012:role/billing-worker" {
+			t.Errorf("expected role ARN subject, got %s", result.Subject)
+		}
+		if result.TrustDomain != "123456789012" {
+			t.Errorf("expected account trust domain, got %s", result.TrustDomain)
+		}
+		if result.Claims["session_name"] != "i-0abc" {
+			t.Errorf("expected session_name claim, got %v", result.Claims["session_name"])
+		}
+		if result.Claims["arn"] != "arn:aws:sts::123456789012:assumed-role/billing-worker/i-0abc" {
+			t.Errorf("expected arn claim, got %v", result.Claims["arn"])
+		}
+		if !result.ExpiresAt.Equal(signedAt.Add(15 * time.Minute)) {
+			t.Errorf("expected expiry at end of presigned window, got %v", result.ExpiresAt)
+		}
+	})
+
+	t.Run("configured trust domain", func(t *testing.T) {
+		result, err := newValidator(t, AWSSigV4ValidatorConfig{TrustDomain: "aws"}).
+			Validate(ctx, &BearerCredential{Token: token(stsHost, "valid", nil)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.TrustDomain != "aws" {
+			t.Errorf("expected trust domain aws, got %s", result.TrustDomain)
+		}
+	})
+
+	t.Run("rejects signature STS rejects", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: token(stsHost, "forged", nil)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects endpoint that is not STS", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: token("attacker.example.com", "valid", nil)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects other actions", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: token(stsHost, "valid", func(q url.Values) {
+			q.Set("Action", "AssumeRole")
+		})})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects request without signed audience", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: token(stsHost, "valid", func(q url.Values) {
+			q.Set("X-Amz-SignedHeaders", "host")
+		})})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects expired request", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: token(stsHost, "valid", func(q url.Values) {
+			q.Set("X-Amz-Expires", "30")
+		})})
+		if !errors.Is(err, ErrExpiredToken) {
+			t.Errorf("expected ErrExpiredToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects account that is not allowed", func(t *testing.T) {
+		_, err := newValidator(t, AWSSigV4ValidatorConfig{AllowedAccounts: []string{"210987654321"}}).
+			Validate(ctx, &BearerCredential{Token: token(stsHost, "valid", nil)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("ignores other bearer tokens", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: "eyJhbGciOi..."})
+		if err == nil {
+			t.Error("expected error for token without prefix")
+		}
+	})
+}
+
+func TestAWSSigV4Validator_DefaultSTSHosts(t *testing.T) {
+	validator, err := NewAWSSigV4Validator(AWSSigV4ValidatorConfig{})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	for host, allowed := range map[string]bool{
+		"sts.amazonaws.com":                  true,
+		"sts.us-west-2.amazonaws.com":        true,
+		"sts.cn-north-1.amazonaws.com.cn":    true,
+		"sts.amazonaws.com.attacker.example": false,
+		"evil-sts.amazonaws.com":             false,
+		"sts.amazonaws.com:8443":             false,
+	} {
+		if got := validator.allowedSTSHost(host); got != allowed {
+			t.Errorf("allowedSTSHost(%q) = %v, want %v", host, got, allowed)
+		}
+	}
+}
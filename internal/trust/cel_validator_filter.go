@@ -15,7 +15,7 @@ import (
 // This provides compile-time declarations for:
 //   - actor - the actor's Result object as a map (subject, issuer, trust_domain, claims, etc.)
 //   - validator_name - the name of the validator being checked (string)
-//   - request - the request attributes as a map (method, path, headers, additional, etc.)
+//   - request - the request attributes as a map (method, path, headers, header_values, additional, etc.)
 //
 // The CEL expression should evaluate to a boolean indicating whether the validator is allowed.
 //
@@ -84,6 +84,11 @@ func ConvertRequestAttributesToMap(attrs *request.RequestAttributes) (map[string
 		return nil, err
 	}
 
+	// headers serializes as combined values; also expose every value of repeated headers
+	if len(attrs.Headers) > 0 {
+		m["header_values"] = map[string][]string(attrs.Headers)
+	}
+
 	return m, nil
 }
 
@@ -117,7 +122,7 @@ type CelValidatorFilter struct {
 // It has access to:
 //   - actor: the actor's Result object as a map (subject, issuer, trust_domain, claims, etc.)
 //   - validator_name: the name of the validator being checked
-//   - request: the request attributes as a map (method, path, headers, additional, etc.)
+//   - request: the request attributes as a map (method, path, headers, header_values, additional, etc.)
 func NewCelValidatorFilter(script string) (*CelValidatorFilter, error) {
 	if script == "" {
 		return nil, fmt.Errorf("CEL filter script cannot be empty")
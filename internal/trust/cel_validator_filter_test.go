@@ -379,8 +379,24 @@ func TestCelValidatorFilter_WithRequestAttributes(t *testing.T) {
 			},
 			validatorName: "api-validator",
 			requestAttrs: &request.RequestAttributes{
-				Headers: map[string]string{
-					"x-api-key": "secret",
+				Headers: request.Headers{
+					"x-api-key": {"secret"},
+				},
+			},
+			wantAllowed: true,
+			wantErr:     false,
+		},
+		{
+			name:   "check repeated request header values",
+			script: `"b" in request.header_values["x-forwarded-for"] && request.headers["x-forwarded-for"] == "a, b"`,
+			actor: &Result{
+				Subject:     "user",
+				TrustDomain: "test",
+			},
+			validatorName: "api-validator",
+			requestAttrs: &request.RequestAttributes{
+				Headers: request.Headers{
+					"x-forwarded-for": {"a", "b"},
 				},
 			},
 			wantAllowed: true,
@@ -476,9 +492,9 @@ func TestConvertRequestAttributesToMap(t *testing.T) {
 		{
 			name: "with headers",
 			attrs: &request.RequestAttributes{
-				Headers: map[string]string{
-					"authorization": "Bearer token",
-					"content-type":  "application/json",
+				Headers: request.Headers{
+					"authorization": {"Bearer token"},
+					"content-type":  {"application/json"},
 				},
 			},
 			wantNil: false,
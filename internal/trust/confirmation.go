@@ -0,0 +1,21 @@
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// ConfirmationClaim is the RFC 7800 confirmation claim, which binds a token to a key
+// its presenter must prove possession of
+const ConfirmationClaim = "cnf"
+
+// X509ThumbprintConfirmation is the confirmation method binding a token to a client
+// certificate by its SHA-256 thumbprint (RFC 8705 section 3.1)
+const X509ThumbprintConfirmation = "x5t#S256"
+
+// CertificateThumbprint returns the base64url-encoded SHA-256 thumbprint of a DER-encoded
+// certificate, as used by the x5t#S256 confirmation method
+func CertificateThumbprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package trust
+
+import (
+	"fmt"
+)
+
+// ActClaim is the RFC 8693 actor claim, which records a token's delegation chain
+const ActClaim = "act"
+
+// MaxDelegationDepth is the longest delegation chain accepted or issued
+const MaxDelegationDepth = 10
+
+// Delegation is an RFC 8693 delegation chain: the actor currently acting on behalf
+// of the subject, and the actors that acted before it, most recent first
+type Delegation struct {
+	// Subject identifies the actor
+	Subject string `json:"sub"`
+
+	// Issuer is the issuer of the actor's identity, if known
+	Issuer string `json:"iss,omitempty"`
+
+	// Prior is the delegation chain of the actor's own token, if it had one
+	Prior *Delegation `json:"act,omitempty"`
+}
+
+// Delegate returns the chain of actor acting on behalf of a subject with the given chain
+// If actor is nil or anonymous, the subject's chain is returned unchanged
+func Delegate(actor *Result, prior *Delegation) (*Delegation, error) {
+	if actor == nil || actor.Subject == "" {
+		return prior, nil
+	}
+	d := &Delegation{Subject: actor.Subject, Issuer: actor.Issuer, Prior: prior}
+	if d.Depth() > MaxDelegationDepth {
+		return nil, fmt.Errorf("delegation chain exceeds %d actors", MaxDelegationDepth)
+	}
+	return d, nil
+}
+
+// Depth returns the number of actors in the chain
+func (d *Delegation) Depth() int {
+	depth := 0
+	for ; d != nil; d = d.Prior {
+		depth++
+	}
+	return depth
+}
+
+// Claim returns the chain as an act claim value
+func (d *Delegation) Claim() map[string]any {
+	claim := map[string]any{"sub": d.Subject}
+	if d.Issuer != "" {
+		claim["iss"] = d.Issuer
+	}
+	if d.Prior != nil {
+		claim[ActClaim] = d.Prior.Claim()
+	}
+	return claim
+}
+
+// ParseDelegation parses an act claim value
+// Every actor must be an object with a sub claim, and the chain may be at most
+// MaxDelegationDepth actors long. Other claims about actors are ignored.
+func ParseDelegation(claim any) (*Delegation, error) {
+	var head *Delegation
+	next := &head
+	for depth := 1; claim != nil; depth++ {
+		if depth > MaxDelegationDepth {
+			return nil, fmt.Errorf("%w: delegation chain exceeds %d actors", ErrInvalidToken, MaxDelegationDepth)
+		}
+		actor, ok := claim.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: act claim must be an object", ErrInvalidToken)
+		}
+		subject, _ := actor["sub"].(string)
+		if subject == "" {
+			return nil, fmt.Errorf("%w: act claim is missing sub", ErrInvalidToken)
+		}
+		issuer, ok := actor["iss"].(string)
+		if !ok && actor["iss"] != nil {
+			return nil, fmt.Errorf("%w: act claim iss must be a string", ErrInvalidToken)
+		}
+
+		*next = &Delegation{Subject: subject, Issuer: issuer}
+		next = &(*next).Prior
+		claim = actor[ActClaim]
+	}
+	return head, nil
+}
+
+// delegationFromClaims parses the delegation chain in a credential's claims, if it has one
+func delegationFromClaims(claims map[string]any) (*Delegation, error) {
+	act, ok := claims[ActClaim]
+	if !ok {
+		return nil, nil
+	}
+	return ParseDelegation(act)
+}
@@ -0,0 +1,87 @@
+package trust
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDelegation(t *testing.T) {
+	t.Run("parses nested actors", func(t *testing.T) {
+		claim := map[string]any{
+			"sub": "gateway",
+			"iss": "https://idp.example.com",
+			"act": map[string]any{"sub": "frontend", "client_id": "web"},
+		}
+		delegation, err := ParseDelegation(claim)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := &Delegation{
+			Subject: "gateway",
+			Issuer:  "https://idp.example.com",
+			Prior:   &Delegation{Subject: "frontend"},
+		}
+		if !reflect.DeepEqual(delegation, want) {
+			t.Errorf("got %+v, want %+v", delegation, want)
+		}
+		if delegation.Depth() != 2 {
+			t.Errorf("expected depth 2, got %d", delegation.Depth())
+		}
+
+		roundTripped, err := ParseDelegation(delegation.Claim())
+		if err != nil || !reflect.DeepEqual(roundTripped, want) {
+			t.Errorf("expected claim to round trip, got %+v, %v", roundTripped, err)
+		}
+	})
+
+	deep := map[string]any{"sub": "actor-0"}
+	for i := 1; i <= MaxDelegationDepth; i++ {
+		deep = map[string]any{"sub": "actor", "act": deep}
+	}
+
+	for name, claim := range map[string]any{
+		"not an object":       "gateway",
+		"missing sub":         map[string]any{"iss": "https://idp.example.com"},
+		"non-string iss":      map[string]any{"sub": "gateway", "iss": 1},
+		"invalid prior actor": map[string]any{"sub": "gateway", "act": map[string]any{}},
+		"too deep":            deep,
+	} {
+		t.Run("rejects "+name, func(t *testing.T) {
+			if _, err := ParseDelegation(claim); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDelegate(t *testing.T) {
+	prior := &Delegation{Subject: "frontend"}
+
+	t.Run("anonymous actor keeps chain", func(t *testing.T) {
+		delegation, err := Delegate(AnonymousResult(), prior)
+		if err != nil || delegation != prior {
+			t.Errorf("expected prior chain, got %+v, %v", delegation, err)
+		}
+	})
+
+	t.Run("actor acts on behalf of prior chain", func(t *testing.T) {
+		delegation, err := Delegate(&Result{Subject: "gateway", Issuer: "https://idp.example.com"}, prior)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if delegation.Subject != "gateway" || delegation.Issuer != "https://idp.example.com" || delegation.Prior != prior {
+			t.Errorf("unexpected chain %+v", delegation)
+		}
+	})
+
+	t.Run("rejects chain longer than maximum", func(t *testing.T) {
+		long := prior
+		for long.Depth() < MaxDelegationDepth {
+			long = &Delegation{Subject: "actor", Prior: long}
+		}
+		if _, err := Delegate(&Result{Subject: "gateway"}, long); err == nil {
+			t.Error("expected error for chain longer than maximum")
+		}
+	})
+}
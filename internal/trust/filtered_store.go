@@ -3,6 +3,7 @@ package trust
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/alechenninger/parsec/internal/request"
 )
@@ -153,6 +154,42 @@ func (s *FilteredStore) ForActor(ctx context.Context, actor *Result, requestAttr
 	return filtered, nil
 }
 
+// WithValidators implements ValidatorRestrictingStore
+// The returned store keeps the validators' order and filter.
+func (s *FilteredStore) WithValidators(names ...string) Store {
+	restricted := &FilteredStore{
+		validatorsByType: make(map[CredentialType][]NamedValidator),
+		validators:       make([]NamedValidator, 0, len(names)),
+		filter:           s.filter,
+	}
+	for _, nv := range s.validators {
+		if slices.Contains(names, nv.Name) {
+			restricted.AddValidator(nv.Name, nv.Validator)
+		}
+	}
+	return restricted
+}
+
+// Close closes the store's validators that hold resources, such as JWKS refreshes
+// Stores returned by ForActor and WithValidators share the validators, so close
+// only the store they came from.
+func (s *FilteredStore) Close() error {
+	validators := make([]Validator, len(s.validators))
+	for i, nv := range s.validators {
+		validators[i] = nv.Validator
+	}
+	return closeValidators(validators)
+}
+
+// ValidatorNames implements ValidatorListingStore
+func (s *FilteredStore) ValidatorNames() []string {
+	names := make([]string, len(s.validators))
+	for i, nv := range s.validators {
+		names[i] = nv.Name
+	}
+	return names
+}
+
 // Validators returns all named validators in the store
 func (s *FilteredStore) Validators() []NamedValidator {
 	return s.validators
@@ -255,6 +255,33 @@ func TestFilteredStore_NoFilterReturnsAllValidators(t *testing.T) {
 	}
 }
 
+func TestFilteredStore_WithValidators(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewFilteredStore()
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	store.AddValidator("prod-validator", NewStubValidator(CredentialTypeBearer).
+		WithResult(&Result{Subject: "prod-user", TrustDomain: "prod"}))
+	store.AddValidator("dev-validator", NewStubValidator(CredentialTypeBearer).
+		WithResult(&Result{Subject: "dev-user", TrustDomain: "dev"}))
+
+	cred := &BearerCredential{Token: "test-token"}
+
+	result, err := store.WithValidators("dev-validator", "unknown").Validate(ctx, cred)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Subject != "dev-user" {
+		t.Errorf("expected dev-validator to validate, got subject %s", result.Subject)
+	}
+
+	if _, err := store.WithValidators("unknown").Validate(ctx, cred); err == nil {
+		t.Error("expected error with no matching validators, got nil")
+	}
+}
+
 func TestFilteredStore_NilActorError(t *testing.T) {
 	ctx := context.Background()
 
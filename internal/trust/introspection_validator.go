@@ -0,0 +1,202 @@
+package trust
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// IntrospectionValidator validates opaque bearer tokens with an OAuth 2.0
+// token introspection endpoint (RFC 7662)
+type IntrospectionValidator struct {
+	endpoint     string
+	clientID     string
+	clientSecret string
+	issuer       string
+	trustDomain  string
+	httpClient   *http.Client
+	clock        clock.Clock
+}
+
+// IntrospectionValidatorConfig contains configuration for token introspection
+type IntrospectionValidatorConfig struct {
+	// Endpoint is the introspection endpoint URL
+	Endpoint string
+
+	// ClientID and ClientSecret authenticate parsec to the introspection endpoint
+	// (client_secret_basic)
+	ClientID     string
+	ClientSecret string
+
+	// Issuer is the expected issuer of introspected tokens
+	// If set, tokens whose introspection response has a different iss are rejected,
+	// and it is the result's issuer when the response has no iss
+	Issuer string
+
+	// TrustDomain is the trust domain introspected tokens belong to
+	TrustDomain string
+
+	// HTTPClient is an optional HTTP client for introspection requests
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+
+	// Clock is the time source for expiry checks
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// NewIntrospectionValidator creates a new token introspection validator
+func NewIntrospectionValidator(cfg IntrospectionValidatorConfig) (*IntrospectionValidator, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("introspection endpoint is required")
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("client ID is required")
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &IntrospectionValidator{
+		endpoint:     cfg.Endpoint,
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		issuer:       cfg.Issuer,
+		trustDomain:  cfg.TrustDomain,
+		httpClient:   httpClient,
+		clock:        clk,
+	}, nil
+}
+
+// CredentialTypes implements the Validator interface
+func (v *IntrospectionValidator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeBearer}
+}
+
+// Validate implements the Validator interface
+// Every call introspects the token with the introspection endpoint
+func (v *IntrospectionValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	bearer, ok := credential.(*BearerCredential)
+	if !ok {
+		return nil, fmt.Errorf("unsupported credential type for introspection validator: %T", credential)
+	}
+
+	response, err := v.introspect(ctx, bearer.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if active, _ := response["active"].(bool); !active {
+		return nil, fmt.Errorf("%w: token is not active", ErrInvalidToken)
+	}
+
+	issuer := v.issuer
+	if iss, ok := response["iss"].(string); ok && iss != "" {
+		if v.issuer != "" && iss != v.issuer {
+			return nil, fmt.Errorf("%w: unexpected issuer %s", ErrInvalidToken, iss)
+		}
+		issuer = iss
+	}
+
+	expiresAt := numericDate(response["exp"])
+	if !expiresAt.IsZero() && !v.clock.Now().Before(expiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	subject, _ := response["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject in introspection response", ErrInvalidToken)
+	}
+
+	delegation, err := delegationFromClaims(response)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Subject:     subject,
+		Issuer:      issuer,
+		TrustDomain: v.trustDomain,
+		Claims:      make(claims.Claims, len(response)),
+		ExpiresAt:   expiresAt,
+		IssuedAt:    numericDate(response["iat"]),
+		Delegation:  delegation,
+	}
+	for name, value := range response {
+		if name != "active" {
+			result.Claims[name] = value
+		}
+	}
+	if scope, ok := response["scope"].(string); ok {
+		result.Scope = scope
+	}
+	switch aud := response["aud"].(type) {
+	case string:
+		result.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				result.Audience = append(result.Audience, s)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// introspect posts the token to the introspection endpoint and decodes the response
+func (v *IntrospectionValidator) introspect(ctx context.Context, token string) (map[string]any, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// Client credentials are form-encoded before basic auth (RFC 6749 section 2.3.1)
+	req.SetBasicAuth(url.QueryEscape(v.clientID), url.QueryEscape(v.clientSecret))
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("introspection endpoint returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var response map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+	return response, nil
+}
+
+// numericDate converts a JSON NumericDate (seconds since the epoch) to a time
+// Returns the zero time if the value is missing or not a number
+func numericDate(value any) time.Time {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
@@ -0,0 +1,162 @@
+package trust
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+func TestIntrospectionValidator(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+
+	// Introspection responses by token
+	responses := map[string]map[string]any{
+		"active-token": {
+			"active":    true,
+			"sub":       "alice",
+			"iss":       "https://legacy.example.com",
+			"scope":     "read write",
+			"client_id": "webapp",
+			"aud":       "api",
+			"exp":       now.Add(time.Hour).Unix(),
+			"iat":       now.Add(-time.Minute).Unix(),
+		},
+		"inactive-token": {"active": false},
+		"expired-token": {
+			"active": true,
+			"sub":    "alice",
+			"exp":    now.Add(-time.Minute).Unix(),
+		},
+		"other-issuer-token": {
+			"active": true,
+			"sub":    "alice",
+			"iss":    "https://other.example.com",
+		},
+		"no-subject-token": {
+			"active":    true,
+			"client_id": "batch",
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Basic auth credentials are form-encoded (RFC 6749 section 2.3.1)
+		clientID, clientSecret, ok := r.BasicAuth()
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+		if !ok || clientID != "parsec" || clientSecret != "s3cr%t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		response, ok := responses[r.PostFormValue("token")]
+		if !ok {
+			response = map[string]any{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	newValidator := func(t *testing.T, clientSecret string) *IntrospectionValidator {
+		t.Helper()
+		validator, err := NewIntrospectionValidator(IntrospectionValidatorConfig{
+			Endpoint:     server.URL,
+			ClientID:     "parsec",
+			ClientSecret: clientSecret,
+			Issuer:       "https://legacy.example.com",
+			TrustDomain:  "legacy",
+			Clock:        clock.NewFixtureClock(now),
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		return validator
+	}
+	validator := newValidator(t, "s3cr%t")
+
+	t.Run("active token", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &BearerCredential{Token: "active-token"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "alice" {
+			t.Errorf("expected subject alice, got %s", result.Subject)
+		}
+		if result.Issuer != "https://legacy.example.com" {
+			t.Errorf("expected issuer from response, got %s", result.Issuer)
+		}
+		if result.TrustDomain != "legacy" {
+			t.Errorf("expected trust domain legacy, got %s", result.TrustDomain)
+		}
+		if result.Scope != "read write" {
+			t.Errorf("expected scope 'read write', got %q", result.Scope)
+		}
+		if len(result.Audience) != 1 || result.Audience[0] != "api" {
+			t.Errorf("expected audience [api], got %v", result.Audience)
+		}
+		if !result.ExpiresAt.Equal(now.Add(time.Hour)) {
+			t.Errorf("expected expiry %v, got %v", now.Add(time.Hour), result.ExpiresAt)
+		}
+		if result.Claims["client_id"] != "webapp" {
+			t.Errorf("expected client_id claim, got %v", result.Claims["client_id"])
+		}
+		if _, ok := result.Claims["active"]; ok {
+			t.Error("expected active to be omitted from claims")
+		}
+	})
+
+	t.Run("rejects inactive token", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: "inactive-token"})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects unknown token", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: "unknown"})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects expired token", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: "expired-token"})
+		if !errors.Is(err, ErrExpiredToken) {
+			t.Errorf("expected ErrExpiredToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects token from another issuer", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: "other-issuer-token"})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects token without subject", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &BearerCredential{Token: "no-subject-token"})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("endpoint errors are not invalid tokens", func(t *testing.T) {
+		_, err := newValidator(t, "wrong").Validate(ctx, &BearerCredential{Token: "active-token"})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected endpoint error, got %v", err)
+		}
+	})
+}
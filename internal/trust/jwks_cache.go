@@ -0,0 +1,314 @@
+package trust
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// jwksRefreshJitter is the fraction of the refresh interval refreshes are moved earlier by at random,
+// so instances started together do not all refresh at once
+const jwksRefreshJitter = 0.1
+
+// JWKSCache caches a JSON Web Key Set, refreshing it in the background
+//
+// Refreshes happen every RefreshInterval, less some jitter, or sooner if the
+// response's Cache-Control max-age is shorter. If a refresh fails, the cache keeps
+// serving the keys it has and retries after MinRefreshInterval. A key ID the cache
+// does not know triggers an immediate refresh, at most once per MinRefreshInterval,
+// so keys an IdP rotates in are picked up without waiting.
+type JWKSCache struct {
+	url                string
+	httpClient         *http.Client
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	cacheFile          string
+	clock              clock.Clock
+	ticker             clock.Ticker
+
+	// refreshMu serializes fetches
+	refreshMu sync.Mutex
+
+	mu          sync.RWMutex
+	set         jwk.Set
+	lastFetch   time.Time
+	nextRefresh time.Time
+}
+
+// JWKSCacheConfig configures a JWKS cache
+type JWKSCacheConfig struct {
+	// URL is the JWKS URL
+	URL string
+
+	// HTTPClient is an optional HTTP client for fetching the JWKS
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+
+	// RefreshInterval is the longest keys are cached before refreshing (default: 15 minutes)
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval is the shortest time between fetches, bounding how often
+	// Cache-Control, failed refreshes, and unknown key IDs cause refetches (default: 1 minute)
+	MinRefreshInterval time.Duration
+
+	// CacheFile is an optional path where the last successfully fetched JWKS is persisted
+	// If the JWKS cannot be fetched at startup, the persisted copy is used instead
+	CacheFile string
+
+	// Clock drives the refresh schedule (default: system clock)
+	Clock clock.Clock
+
+	// Lazy skips fetching the JWKS on creation: it is first fetched when a key is
+	// needed, or by the background refresh
+	Lazy bool
+}
+
+// NewJWKSCache fetches the JWKS and starts refreshing it in the background
+// It fails if the JWKS can be neither fetched nor loaded from the cache file, unless
+// the cache is lazy
+func NewJWKSCache(ctx context.Context, cfg JWKSCacheConfig) (*JWKSCache, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("JWKS URL is required")
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	refreshInterval := cfg.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = 15 * time.Minute
+	}
+	minRefreshInterval := cfg.MinRefreshInterval
+	if minRefreshInterval == 0 {
+		minRefreshInterval = time.Minute
+	}
+	minRefreshInterval = min(minRefreshInterval, refreshInterval)
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	c := &JWKSCache{
+		url:                cfg.URL,
+		httpClient:         httpClient,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+		cacheFile:          cfg.CacheFile,
+		clock:              clk,
+	}
+
+	if cfg.Lazy {
+		c.set = jwk.NewSet()
+	} else if err := c.Refresh(ctx); err != nil {
+		if cfg.CacheFile == "" {
+			return nil, fmt.Errorf("failed to fetch initial JWKS: %w", err)
+		}
+		// Start from the last known good copy; background refreshes keep retrying
+		set, loadErr := loadJWKSCache(cfg.CacheFile)
+		if loadErr != nil {
+			return nil, fmt.Errorf("failed to fetch initial JWKS: %w (and no usable cached copy: %v)", err, loadErr)
+		}
+		slog.Warn("Failed to fetch JWKS, using cached copy", "url", c.url, "cache_file", cfg.CacheFile, "error", err)
+		c.mu.Lock()
+		c.set = set
+		c.mu.Unlock()
+	}
+
+	// Check for due refreshes as often as they are allowed to happen
+	c.ticker = clk.Ticker(minRefreshInterval)
+	if err := c.ticker.Start(func(ctx context.Context) {
+		c.mu.RLock()
+		due := !c.clock.Now().Before(c.nextRefresh)
+		c.mu.RUnlock()
+		if due {
+			if err := c.Refresh(ctx); err != nil {
+				slog.WarnContext(ctx, "Failed to refresh JWKS, keeping cached keys", "url", c.url, "error", err)
+			}
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("failed to start JWKS refresh: %w", err)
+	}
+
+	return c, nil
+}
+
+// Keys returns the cached keys
+// If keyID is not among them, the keys are refreshed first, unless they were
+// fetched within MinRefreshInterval
+func (c *JWKSCache) Keys(ctx context.Context, keyID string) jwk.Set {
+	c.mu.RLock()
+	set := c.set
+	recentlyFetched := c.clock.Now().Sub(c.lastFetch) < c.minRefreshInterval
+	c.mu.RUnlock()
+
+	if keyID == "" || recentlyFetched {
+		return set
+	}
+	if _, ok := set.LookupKeyID(keyID); ok {
+		return set
+	}
+
+	c.refreshMu.Lock()
+	// Another request may have refreshed while this one waited
+	if c.clock.Now().Sub(c.lastFetch) >= c.minRefreshInterval {
+		if err := c.refreshLocked(ctx); err != nil {
+			slog.WarnContext(ctx, "Failed to refresh JWKS for unknown key", "url", c.url, "key_id", keyID, "error", err)
+		}
+	}
+	c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.set
+}
+
+// Refresh fetches the JWKS now
+// On failure the cached keys are kept and the next refresh is after MinRefreshInterval
+func (c *JWKSCache) Refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.refreshLocked(ctx)
+}
+
+// refreshLocked fetches the JWKS; the caller holds refreshMu
+func (c *JWKSCache) refreshLocked(ctx context.Context) error {
+	set, maxAge, err := c.fetch(ctx)
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	c.lastFetch = now
+	if err != nil {
+		c.nextRefresh = now.Add(c.minRefreshInterval)
+		c.mu.Unlock()
+		return err
+	}
+	c.set = set
+	c.nextRefresh = now.Add(c.nextRefreshInterval(maxAge))
+	c.mu.Unlock()
+
+	if c.cacheFile != "" {
+		if err := saveJWKSCache(c.cacheFile, set); err != nil {
+			slog.Warn("Failed to persist JWKS", "url", c.url, "cache_file", c.cacheFile, "error", err)
+		}
+	}
+	return nil
+}
+
+// Close stops background refreshes
+func (c *JWKSCache) Close() {
+	c.ticker.Stop()
+}
+
+// nextRefreshInterval returns how long to cache keys from a response with the given max-age
+// (zero if it had none): the refresh interval, or max-age if shorter but at least the
+// minimum refresh interval, less jitter
+func (c *JWKSCache) nextRefreshInterval(maxAge time.Duration) time.Duration {
+	interval := c.refreshInterval
+	if maxAge > 0 && maxAge < interval {
+		interval = max(maxAge, c.minRefreshInterval)
+	}
+	jitter := time.Duration(rand.Float64() * jwksRefreshJitter * float64(interval))
+	return interval - jitter
+}
+
+// fetch fetches and parses the JWKS, returning it and the response's Cache-Control max-age
+func (c *JWKSCache) fetch(ctx context.Context) (jwk.Set, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("JWKS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	set, err := parseJWKS(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return set, cacheControlMaxAge(resp.Header.Get("Cache-Control")), nil
+}
+
+// cacheControlMaxAge returns the max-age directive of a Cache-Control header, or zero if it has none
+func cacheControlMaxAge(header string) time.Duration {
+	for _, directive := range strings.Split(header, ",") {
+		value, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(directive)), "max-age=")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err != nil || seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+// saveJWKSCache atomically writes set to path
+func saveJWKSCache(path string, set jwk.Set) error {
+	data, err := json.Marshal(set)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JWKS: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write JWKS: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write JWKS: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadJWKSCache reads a JWKS persisted by saveJWKSCache
+func loadJWKSCache(path string) (jwk.Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cached JWKS %s: %w", path, err)
+	}
+	if set.Len() == 0 {
+		return nil, fmt.Errorf("cached JWKS %s has no keys", path)
+	}
+	return set, nil
+}
@@ -0,0 +1,174 @@
+package trust
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/httpfixture"
+)
+
+func TestJWKSCache(t *testing.T) {
+	ctx := context.Background()
+	const jwksURL = "https://test-issuer.example.com/.well-known/jwks.json"
+
+	newFixture := func(t *testing.T, keyID string) *httpfixture.JWKSFixture {
+		t.Helper()
+		fixture, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+			Issuer:  "https://test-issuer.example.com",
+			JWKSURL: jwksURL,
+			KeyID:   keyID,
+		})
+		if err != nil {
+			t.Fatalf("failed to create JWKS fixture: %v", err)
+		}
+		return fixture
+	}
+
+	// idp serves the current fixture's keys, or fails if it is nil
+	type idp struct {
+		current      atomic.Pointer[httpfixture.JWKSFixture]
+		cacheControl string
+		fetches      atomic.Int32
+	}
+	newCache := func(t *testing.T, server *idp, clk *clock.FixtureClock, lazy bool) *JWKSCache {
+		t.Helper()
+		cache, err := NewJWKSCache(ctx, JWKSCacheConfig{
+			URL: jwksURL,
+			HTTPClient: &http.Client{Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+				Provider: httpfixture.NewFuncProvider(func(req *http.Request) *httpfixture.Fixture {
+					server.fetches.Add(1)
+					fixture := server.current.Load()
+					if fixture == nil {
+						return &httpfixture.Fixture{StatusCode: http.StatusServiceUnavailable}
+					}
+					response := fixture.GetFixture(req)
+					if server.cacheControl != "" {
+						response.Headers["Cache-Control"] = server.cacheControl
+					}
+					return response
+				}),
+				Strict: true,
+			})},
+			RefreshInterval:    15 * time.Minute,
+			MinRefreshInterval: time.Minute,
+			Clock:              clk,
+			Lazy:               lazy,
+		})
+		if err != nil {
+			t.Fatalf("failed to create cache: %v", err)
+		}
+		t.Cleanup(cache.Close)
+		return cache
+	}
+	hasKey := func(cache *JWKSCache, keyID string) bool {
+		_, ok := cache.Keys(ctx, "").LookupKeyID(keyID)
+		return ok
+	}
+
+	t.Run("refreshes in the background", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		oldKeys, newKeys := newFixture(t, "key-1"), newFixture(t, "key-2")
+		server := &idp{}
+		server.current.Store(oldKeys)
+		cache := newCache(t, server, clk, false)
+
+		server.current.Store(newKeys)
+		clk.Advance(13 * time.Minute)
+		if !hasKey(cache, oldKeys.KeyID()) {
+			t.Error("expected old keys before the refresh interval, less jitter, elapses")
+		}
+		clk.Advance(2 * time.Minute)
+		if !hasKey(cache, newKeys.KeyID()) || hasKey(cache, oldKeys.KeyID()) {
+			t.Error("expected new keys after the refresh interval")
+		}
+	})
+
+	t.Run("serves cached keys when refresh fails", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		keys := newFixture(t, "key-1")
+		server := &idp{}
+		server.current.Store(keys)
+		cache := newCache(t, server, clk, false)
+
+		server.current.Store(nil)
+		clk.Advance(15 * time.Minute)
+		if !hasKey(cache, keys.KeyID()) {
+			t.Error("expected cached keys after failed refresh")
+		}
+
+		// Failed refreshes are retried after the minimum refresh interval
+		fetches := server.fetches.Load()
+		server.current.Store(keys)
+		clk.Advance(time.Minute)
+		if server.fetches.Load() != fetches+1 {
+			t.Errorf("expected a retry after the minimum refresh interval, got %d fetches", server.fetches.Load()-fetches)
+		}
+	})
+
+	t.Run("refreshes on unknown key ID at most once per minimum interval", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		oldKeys, newKeys := newFixture(t, "key-1"), newFixture(t, "key-2")
+		server := &idp{}
+		server.current.Store(oldKeys)
+		cache := newCache(t, server, clk, false)
+		server.current.Store(newKeys)
+
+		fetches := server.fetches.Load()
+		if _, ok := cache.Keys(ctx, newKeys.KeyID()).LookupKeyID(newKeys.KeyID()); ok {
+			t.Error("expected no refresh right after a fetch")
+		}
+		clk.Advance(time.Minute)
+		if _, ok := cache.Keys(ctx, newKeys.KeyID()).LookupKeyID(newKeys.KeyID()); !ok {
+			t.Error("expected refresh for unknown key ID")
+		}
+		cache.Keys(ctx, "unknown")
+		if got := server.fetches.Load() - fetches; got != 1 {
+			t.Errorf("expected 1 fetch, got %d", got)
+		}
+	})
+
+	t.Run("lazy cache fetches when a key is needed", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		keys := newFixture(t, "key-1")
+		server := &idp{}
+		cache := newCache(t, server, clk, true)
+		if server.fetches.Load() != 0 {
+			t.Fatalf("expected no fetch on creation, got %d", server.fetches.Load())
+		}
+
+		server.current.Store(keys)
+		if _, ok := cache.Keys(ctx, keys.KeyID()).LookupKeyID(keys.KeyID()); !ok {
+			t.Error("expected keys to be fetched for the unknown key ID")
+		}
+	})
+
+	t.Run("honors shorter Cache-Control max-age", func(t *testing.T) {
+		clk := clock.NewFixtureClock(time.Time{})
+		server := &idp{cacheControl: "public, max-age=120"}
+		server.current.Store(newFixture(t, "key-1"))
+		cache := newCache(t, server, clk, false)
+
+		next := cache.nextRefresh.Sub(clk.Now())
+		if next <= 108*time.Second || next > 120*time.Second {
+			t.Errorf("expected next refresh within jitter of max-age, got %v", next)
+		}
+	})
+}
+
+func TestCacheControlMaxAge(t *testing.T) {
+	for header, want := range map[string]time.Duration{
+		"":                            0,
+		"no-cache":                    0,
+		"public, max-age=300":         5 * time.Minute,
+		"Max-Age=60, must-revalidate": time.Minute,
+		"max-age=abc":                 0,
+	} {
+		if got := cacheControlMaxAge(header); got != want {
+			t.Errorf("cacheControlMaxAge(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package trust
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/knadh/koanf/providers/file"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// JWKSSource provides the keys a JWT validator verifies signatures with
+type JWKSSource interface {
+	// Keys returns the current key set
+	// keyID is the ID of the key the token is signed with, if it has one
+	Keys(ctx context.Context, keyID string) jwk.Set
+
+	// Close releases resources, such as background refreshes
+	Close()
+}
+
+var (
+	_ JWKSSource = (*JWKSCache)(nil)
+	_ JWKSSource = (*StaticJWKSSource)(nil)
+	_ JWKSSource = (*FileJWKSSource)(nil)
+)
+
+// StaticJWKSSource serves a fixed key set, such as one configured inline
+type StaticJWKSSource struct {
+	set jwk.Set
+}
+
+// NewStaticJWKSSource creates a source serving the JWKS in data
+func NewStaticJWKSSource(data []byte) (*StaticJWKSSource, error) {
+	set, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticJWKSSource{set: set}, nil
+}
+
+// Keys implements JWKSSource
+func (s *StaticJWKSSource) Keys(ctx context.Context, keyID string) jwk.Set {
+	return s.set
+}
+
+// Close implements JWKSSource
+func (s *StaticJWKSSource) Close() {}
+
+// FileJWKSSource serves the key set in a local file, reloading it when the file changes
+// If a changed file cannot be parsed, the previous keys are kept
+type FileJWKSSource struct {
+	path    string
+	watcher *file.File
+
+	mu  sync.RWMutex
+	set jwk.Set
+}
+
+// NewFileJWKSSource loads the JWKS in path and watches it for changes
+func NewFileJWKSSource(path string) (*FileJWKSSource, error) {
+	s := &FileJWKSSource{path: path, watcher: file.Provider(path)}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	if err := s.watcher.Watch(func(event interface{}, err error) {
+		if err != nil {
+			slog.Warn("Stopped watching JWKS file", "path", path, "error", err)
+			return
+		}
+		if err := s.reload(); err != nil {
+			slog.Warn("Failed to reload JWKS file, keeping previous keys", "path", path, "error", err)
+			return
+		}
+		slog.Info("Reloaded JWKS", "path", path)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to watch JWKS file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// reload reads and parses the file, replacing the keys if it is valid
+func (s *FileJWKSSource) reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	set, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("invalid JWKS file %s: %w", s.path, err)
+	}
+	s.mu.Lock()
+	s.set = set
+	s.mu.Unlock()
+	return nil
+}
+
+// Keys implements JWKSSource
+func (s *FileJWKSSource) Keys(ctx context.Context, keyID string) jwk.Set {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.set
+}
+
+// Close stops watching the file
+func (s *FileJWKSSource) Close() {
+	s.watcher.Unwatch()
+}
+
+// parseJWKS parses a JWKS, requiring at least one key
+func parseJWKS(data []byte) (jwk.Set, error) {
+	set, err := jwk.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	if set.Len() == 0 {
+		return nil, fmt.Errorf("JWKS has no keys")
+	}
+	return set, nil
+}
@@ -0,0 +1,111 @@
+package trust
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/httpfixture"
+)
+
+// jwksJSON returns the JWKS document a fixture serves
+func jwksJSON(t *testing.T, fixture *httpfixture.JWKSFixture) []byte {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, fixture.JWKSURL(), nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	return []byte(fixture.GetFixture(req).Body)
+}
+
+func TestStaticJWKSSource(t *testing.T) {
+	fixture := setupTestJWKSFixture(t)
+
+	source, err := NewStaticJWKSSource(jwksJSON(t, fixture))
+	if err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+	if _, ok := source.Keys(context.Background(), "").LookupKeyID(fixture.KeyID()); !ok {
+		t.Error("expected fixture key")
+	}
+
+	for name, data := range map[string]string{
+		"malformed": `{"keys":`,
+		"empty":     `{"keys":[]}`,
+	} {
+		if _, err := NewStaticJWKSSource([]byte(data)); err == nil {
+			t.Errorf("expected error for %s JWKS", name)
+		}
+	}
+}
+
+func TestFileJWKSSource(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	newFixture := func(keyID string) *httpfixture.JWKSFixture {
+		fixture, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+			Issuer:  "https://test-issuer.example.com",
+			JWKSURL: "https://test-issuer.example.com/.well-known/jwks.json",
+			KeyID:   keyID,
+		})
+		if err != nil {
+			t.Fatalf("failed to create JWKS fixture: %v", err)
+		}
+		return fixture
+	}
+	oldKeys, newKeys := newFixture("key-1"), newFixture("key-2")
+
+	if _, err := NewFileJWKSSource(path); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+
+	if err := os.WriteFile(path, jwksJSON(t, oldKeys), 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+	source, err := NewFileJWKSSource(path)
+	if err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+	t.Cleanup(source.Close)
+
+	hasKey := func(keyID string) bool {
+		_, ok := source.Keys(ctx, keyID).LookupKeyID(keyID)
+		return ok
+	}
+	eventually := func(cond func() bool) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if cond() {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	if !hasKey(oldKeys.KeyID()) {
+		t.Fatal("expected keys from file")
+	}
+
+	t.Run("keeps keys when file becomes invalid", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(`{"keys":`), 0o600); err != nil {
+			t.Fatalf("failed to write JWKS: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if !hasKey(oldKeys.KeyID()) {
+			t.Error("expected previous keys after invalid change")
+		}
+	})
+
+	t.Run("reloads when file changes", func(t *testing.T) {
+		if err := os.WriteFile(path, jwksJSON(t, newKeys), 0o600); err != nil {
+			t.Fatalf("failed to write JWKS: %v", err)
+		}
+		if !eventually(func() bool { return hasKey(newKeys.KeyID()) && !hasKey(oldKeys.KeyID()) }) {
+			t.Error("expected new keys after file changed")
+		}
+	})
+}
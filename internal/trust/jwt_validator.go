@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"maps"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 
 	"github.com/alechenninger/parsec/internal/claims"
@@ -19,9 +20,16 @@ import (
 type JWTValidator struct {
 	issuer      string
 	jwksURL     string
-	cache       *jwk.Cache
+	keys        JWKSSource
 	trustDomain string
 	clock       clock.Clock
+
+	requiredAudiences []string
+	allowedAudiences  []string
+	authorizedParties []string
+
+	clockSkew   time.Duration
+	maxTokenAge time.Duration
 }
 
 // JWTValidatorConfig contains configuration for JWT validation
@@ -30,15 +38,46 @@ type JWTValidatorConfig struct {
 	Issuer string
 
 	// JWKSURL is the URL to fetch JSON Web Key Set from
-	// If empty, will attempt to discover from issuer/.well-known/jwks.json
+	// If empty, and neither JWKS nor JWKSFile is set, will attempt to discover
+	// from issuer/.well-known/jwks.json
 	JWKSURL string
 
+	// JWKS is an inline JSON Web Key Set to use instead of fetching one,
+	// for environments that cannot reach the IdP
+	JWKS []byte
+
+	// JWKSFile is the path of a JSON Web Key Set file to use instead of fetching one
+	// The file is reloaded when it changes
+	JWKSFile string
+
 	// TrustDomain is the trust domain this issuer belongs to
 	TrustDomain string
 
+	// RequiredAudiences must all be in the token's aud claim
+	RequiredAudiences []string
+
+	// AllowedAudiences, if set, restricts tokens to those with at least one of
+	// these audiences in their aud claim
+	AllowedAudiences []string
+
+	// AuthorizedParties, if set, requires an azp claim with one of these client IDs
+	AuthorizedParties []string
+
+	// ClockSkew is the leeway allowed when checking exp, nbf, and iat,
+	// to tolerate clock differences between the IdP and parsec (default: none)
+	ClockSkew time.Duration
+
+	// MaxTokenAge, if set, rejects tokens issued (iat) longer ago than this,
+	// even if they have not expired. Tokens without iat are rejected.
+	MaxTokenAge time.Duration
+
 	// RefreshInterval for JWKS cache (default: 15 minutes)
 	RefreshInterval time.Duration
 
+	// MinRefreshInterval is the shortest time between JWKS fetches, e.g. when a token
+	// has an unknown key ID or the IdP's Cache-Control max-age is short (default: 1 minute)
+	MinRefreshInterval time.Duration
+
 	// HTTPClient is an optional HTTP client for JWKS fetching
 	// If nil, http.DefaultClient will be used
 	// This is useful for testing with fixtures or custom transports
@@ -48,6 +87,14 @@ type JWTValidatorConfig struct {
 	// If nil, uses system clock
 	// This is useful for testing time-dependent behavior
 	Clock clock.Clock
+
+	// CacheFile is an optional path where the last successfully fetched JWKS is persisted
+	// If the JWKS cannot be fetched at startup, the persisted copy is used instead
+	// until a fetch succeeds, so a temporarily unreachable IdP does not prevent startup
+	CacheFile string
+
+	// LazyJWKS defers fetching the JWKS from JWKSURL until a token is validated
+	LazyJWKS bool
 }
 
 // NewJWTValidator creates a new JWT validator with JWKS support
@@ -56,35 +103,14 @@ func NewJWTValidator(cfg JWTValidatorConfig) (*JWTValidator, error) {
 		return nil, fmt.Errorf("issuer is required")
 	}
 
-	jwksURL := cfg.JWKSURL
-	if jwksURL == "" {
-		// Default: try standard OIDC discovery endpoint
-		jwksURL = cfg.Issuer + "/.well-known/jwks.json"
-	}
-
-	refreshInterval := cfg.RefreshInterval
-	if refreshInterval == 0 {
-		refreshInterval = 15 * time.Minute
-	}
-
-	// Create JWKS cache with auto-refresh
-	cache := jwk.NewCache(context.Background())
-
-	// Register the JWKS URL with the cache
-	registerOpts := []jwk.RegisterOption{jwk.WithMinRefreshInterval(refreshInterval)}
-	if cfg.HTTPClient != nil {
-		registerOpts = append(registerOpts, jwk.WithHTTPClient(cfg.HTTPClient))
-	}
-	if err := cache.Register(jwksURL, registerOpts...); err != nil {
-		return nil, fmt.Errorf("failed to register JWKS URL: %w", err)
+	sources := 0
+	for _, set := range []bool{cfg.JWKSURL != "", len(cfg.JWKS) > 0, cfg.JWKSFile != ""} {
+		if set {
+			sources++
+		}
 	}
-
-	// Pre-fetch the JWKS
-	// TODO: could make this lazy as opposed to eager fetch on creation
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if _, err := cache.Refresh(ctx, jwksURL); err != nil {
-		return nil, fmt.Errorf("failed to fetch initial JWKS: %w", err)
+	if sources > 1 {
+		return nil, fmt.Errorf("only one of JWKS URL, inline JWKS, or JWKS file may be set")
 	}
 
 	// Use provided clock or default to system clock
@@ -93,12 +119,53 @@ func NewJWTValidator(cfg JWTValidatorConfig) (*JWTValidator, error) {
 		clk = clock.NewSystemClock()
 	}
 
+	var (
+		jwksURL string
+		keys    JWKSSource
+		err     error
+	)
+	switch {
+	case len(cfg.JWKS) > 0:
+		keys, err = NewStaticJWKSSource(cfg.JWKS)
+	case cfg.JWKSFile != "":
+		keys, err = NewFileJWKSSource(cfg.JWKSFile)
+	default:
+		jwksURL = cfg.JWKSURL
+		if jwksURL == "" {
+			// Default: try standard OIDC discovery endpoint
+			jwksURL = cfg.Issuer + "/.well-known/jwks.json"
+		}
+
+		// Fetch the JWKS and keep it fresh in the background
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		keys, err = NewJWKSCache(ctx, JWKSCacheConfig{
+			URL:                jwksURL,
+			HTTPClient:         cfg.HTTPClient,
+			RefreshInterval:    cfg.RefreshInterval,
+			MinRefreshInterval: cfg.MinRefreshInterval,
+			CacheFile:          cfg.CacheFile,
+			Clock:              clk,
+			Lazy:               cfg.LazyJWKS,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	return &JWTValidator{
 		issuer:      cfg.Issuer,
 		jwksURL:     jwksURL,
-		cache:       cache,
+		keys:        keys,
 		trustDomain: cfg.TrustDomain,
 		clock:       clk,
+
+		requiredAudiences: cfg.RequiredAudiences,
+		allowedAudiences:  cfg.AllowedAudiences,
+		authorizedParties: cfg.AuthorizedParties,
+
+		clockSkew:   cfg.ClockSkew,
+		maxTokenAge: cfg.MaxTokenAge,
 	}, nil
 }
 
@@ -122,11 +189,12 @@ func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Re
 		return nil, fmt.Errorf("unsupported credential type for JWT validator: %T", credential)
 	}
 
-	// Fetch the current JWKS
-	jwks, err := v.cache.Get(ctx, v.jwksURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	// Get the cached JWKS, refreshed if the token is signed with a key it does not have yet
+	msg, err := jws.Parse([]byte(tokenString))
+	if err != nil || len(msg.Signatures()) == 0 {
+		return nil, fmt.Errorf("%w: malformed JWT: %v", ErrInvalidToken, err)
 	}
+	jwks := v.keys.Keys(ctx, msg.Signatures()[0].ProtectedHeaders().KeyID())
 
 	// Parse and validate the JWT using the validator's clock
 	token, err := jwt.Parse(
@@ -137,7 +205,7 @@ func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Re
 		jwt.WithClock(jwt.ClockFunc(func() time.Time {
 			return v.clock.Now()
 		})),
-		// TODO: validate aud
+		jwt.WithAcceptableSkew(v.clockSkew),
 	)
 	if err != nil {
 		// Check if it's an expiration error
@@ -151,6 +219,16 @@ func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Re
 		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
 
+	if v.maxTokenAge > 0 {
+		issuedAt := token.IssuedAt()
+		if issuedAt.IsZero() {
+			return nil, fmt.Errorf("%w: missing iat claim", ErrInvalidToken)
+		}
+		if v.clock.Now().Sub(issuedAt) > v.maxTokenAge+v.clockSkew {
+			return nil, ErrExpiredToken
+		}
+	}
+
 	// Ensure there is a subject
 	subject := token.Subject()
 	if subject == "" {
@@ -168,8 +246,16 @@ func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Re
 	claimsMap := make(claims.Claims)
 	maps.Copy(claimsMap, allClaims)
 
-	// Extract audience
+	// Ensure the token was minted for us
 	audiences := token.Audience()
+	if err := v.checkAudience(token, audiences); err != nil {
+		return nil, err
+	}
+
+	delegation, err := delegationFromClaims(claimsMap)
+	if err != nil {
+		return nil, err
+	}
 
 	// Extract scope (OAuth2/OIDC)
 	scope := ""
@@ -188,14 +274,35 @@ func (v *JWTValidator) Validate(ctx context.Context, credential Credential) (*Re
 		IssuedAt:    token.IssuedAt(),
 		Audience:    audiences,
 		Scope:       scope,
+		Delegation:  delegation,
 	}, nil
 }
 
+// checkAudience checks the token's aud and azp claims against the configured audiences
+// and authorized parties
+func (v *JWTValidator) checkAudience(token jwt.Token, audiences []string) error {
+	for _, required := range v.requiredAudiences {
+		if !slices.Contains(audiences, required) {
+			return fmt.Errorf("%w: token audience does not include %s", ErrInvalidToken, required)
+		}
+	}
+	if len(v.allowedAudiences) > 0 && !slices.ContainsFunc(audiences, func(aud string) bool {
+		return slices.Contains(v.allowedAudiences, aud)
+	}) {
+		return fmt.Errorf("%w: token audience %v is not allowed", ErrInvalidToken, audiences)
+	}
+	if len(v.authorizedParties) > 0 {
+		azp, _ := token.Get("azp")
+		azpStr, _ := azp.(string)
+		if !slices.Contains(v.authorizedParties, azpStr) {
+			return fmt.Errorf("%w: authorized party %q is not allowed", ErrInvalidToken, azpStr)
+		}
+	}
+	return nil
+}
+
 // Close cleans up resources (stops JWKS cache refresh)
 func (v *JWTValidator) Close() error {
-	// The cache doesn't have an explicit Close method, but stopping the context
-	// used during creation will stop background refreshes.
-	// For now, we rely on garbage collection.
-	// TODO: reexamine this
+	v.keys.Close()
 	return nil
 }
@@ -2,7 +2,10 @@ package trust
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -330,6 +333,147 @@ func TestJWTValidator(t *testing.T) {
 	})
 }
 
+func TestJWTValidatorAudience(t *testing.T) {
+	ctx := context.Background()
+	fixture := setupTestJWKSFixture(t)
+
+	newValidator := func(t *testing.T, cfg JWTValidatorConfig) *JWTValidator {
+		t.Helper()
+		cfg.Issuer = fixture.Issuer()
+		cfg.JWKSURL = fixture.JWKSURL()
+		cfg.HTTPClient = &http.Client{
+			Transport: httpfixture.NewTransport(httpfixture.TransportConfig{Provider: fixture, Strict: true}),
+		}
+		cfg.Clock = fixture.Clock()
+		validator, err := NewJWTValidator(cfg)
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		return validator
+	}
+	validate := func(t *testing.T, validator *JWTValidator, tokenClaims map[string]interface{}) error {
+		t.Helper()
+		tokenClaims["sub"] = "user@example.com"
+		tokenString, err := fixture.CreateAndSignToken(tokenClaims)
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		_, err = validator.Validate(ctx, &BearerCredential{Token: tokenString})
+		return err
+	}
+
+	t.Run("required audiences must all be present", func(t *testing.T) {
+		validator := newValidator(t, JWTValidatorConfig{RequiredAudiences: []string{"parsec", "gateway"}})
+
+		if err := validate(t, validator, map[string]interface{}{"aud": []string{"gateway", "parsec", "other"}}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := validate(t, validator, map[string]interface{}{"aud": "parsec"}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken for missing audience, got %v", err)
+		}
+	})
+
+	t.Run("allowed audiences require one match", func(t *testing.T) {
+		validator := newValidator(t, JWTValidatorConfig{AllowedAudiences: []string{"parsec", "parsec-staging"}})
+
+		if err := validate(t, validator, map[string]interface{}{"aud": []string{"other", "parsec-staging"}}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := validate(t, validator, map[string]interface{}{"aud": "billing"}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken for other audience, got %v", err)
+		}
+		if err := validate(t, validator, map[string]interface{}{}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken for token without audience, got %v", err)
+		}
+	})
+
+	t.Run("authorized parties restrict azp", func(t *testing.T) {
+		validator := newValidator(t, JWTValidatorConfig{AuthorizedParties: []string{"web-frontend"}})
+
+		if err := validate(t, validator, map[string]interface{}{"azp": "web-frontend"}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := validate(t, validator, map[string]interface{}{"azp": "cli"}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken for other azp, got %v", err)
+		}
+		if err := validate(t, validator, map[string]interface{}{}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken for token without azp, got %v", err)
+		}
+	})
+
+	t.Run("audience is not checked by default", func(t *testing.T) {
+		validator := newValidator(t, JWTValidatorConfig{})
+		if err := validate(t, validator, map[string]interface{}{"aud": "anything"}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestJWTValidatorClockSkew(t *testing.T) {
+	ctx := context.Background()
+	issuedAt := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	fixture, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+		Issuer:  "https://test-issuer.example.com",
+		JWKSURL: "https://test-issuer.example.com/.well-known/jwks.json",
+		Clock:   clock.NewFixtureClock(issuedAt),
+	})
+	if err != nil {
+		t.Fatalf("failed to create fixture: %v", err)
+	}
+	// Valid from issuedAt for an hour
+	tokenString, err := fixture.CreateAndSignToken(map[string]interface{}{"sub": "user@example.com"})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	// validateAt validates the token with parsec's clock at now
+	validateAt := func(t *testing.T, now time.Time, cfg JWTValidatorConfig) error {
+		t.Helper()
+		cfg.Issuer = fixture.Issuer()
+		cfg.JWKSURL = fixture.JWKSURL()
+		cfg.HTTPClient = &http.Client{
+			Transport: httpfixture.NewTransport(httpfixture.TransportConfig{Provider: fixture, Strict: true}),
+		}
+		cfg.Clock = clock.NewFixtureClock(now)
+		validator, err := NewJWTValidator(cfg)
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		_, err = validator.Validate(ctx, &BearerCredential{Token: tokenString})
+		return err
+	}
+
+	t.Run("tolerates expiry within skew", func(t *testing.T) {
+		justExpired := issuedAt.Add(time.Hour + 2*time.Second)
+		if err := validateAt(t, justExpired, JWTValidatorConfig{}); err != ErrExpiredToken {
+			t.Errorf("expected ErrExpiredToken without skew, got %v", err)
+		}
+		if err := validateAt(t, justExpired, JWTValidatorConfig{ClockSkew: 5 * time.Second}); err != nil {
+			t.Errorf("expected token to be valid within skew, got %v", err)
+		}
+	})
+
+	t.Run("tolerates iat in the future within skew", func(t *testing.T) {
+		behindIdP := issuedAt.Add(-2 * time.Second)
+		if err := validateAt(t, behindIdP, JWTValidatorConfig{}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken without skew, got %v", err)
+		}
+		if err := validateAt(t, behindIdP, JWTValidatorConfig{ClockSkew: 5 * time.Second}); err != nil {
+			t.Errorf("expected token to be valid within skew, got %v", err)
+		}
+	})
+
+	t.Run("rejects tokens older than max token age", func(t *testing.T) {
+		later := issuedAt.Add(10 * time.Minute)
+		if err := validateAt(t, later, JWTValidatorConfig{MaxTokenAge: 5 * time.Minute}); err != ErrExpiredToken {
+			t.Errorf("expected ErrExpiredToken for old token, got %v", err)
+		}
+		if err := validateAt(t, later, JWTValidatorConfig{MaxTokenAge: 15 * time.Minute}); err != nil {
+			t.Errorf("expected token to be valid, got %v", err)
+		}
+	})
+}
+
 func TestJWTValidatorConfig(t *testing.T) {
 	t.Run("requires issuer", func(t *testing.T) {
 		_, err := NewJWTValidator(JWTValidatorConfig{
@@ -375,3 +519,165 @@ func TestJWTValidatorConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestJWTValidatorCacheFile(t *testing.T) {
+	ctx := context.Background()
+	fixture := setupTestJWKSFixture(t)
+	cacheFile := filepath.Join(t.TempDir(), "jwks", "issuer.json")
+
+	unreachable := &http.Client{
+		Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+			Provider: httpfixture.NewFuncProvider(func(*http.Request) *httpfixture.Fixture { return nil }),
+			Strict:   true,
+		}),
+	}
+
+	t.Run("fails without cached copy when IdP is unreachable", func(t *testing.T) {
+		_, err := NewJWTValidator(JWTValidatorConfig{
+			Issuer:     fixture.Issuer(),
+			JWKSURL:    fixture.JWKSURL(),
+			HTTPClient: unreachable,
+			CacheFile:  cacheFile,
+		})
+		if err == nil {
+			t.Fatal("expected error when JWKS is unreachable and nothing is cached")
+		}
+	})
+
+	t.Run("persists fetched JWKS", func(t *testing.T) {
+		_, err := NewJWTValidator(JWTValidatorConfig{
+			Issuer:  fixture.Issuer(),
+			JWKSURL: fixture.JWKSURL(),
+			HTTPClient: &http.Client{
+				Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+					Provider: fixture,
+					Strict:   true,
+				}),
+			},
+			CacheFile: cacheFile,
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+
+		if _, err := os.Stat(cacheFile); err != nil {
+			t.Fatalf("expected JWKS to be persisted: %v", err)
+		}
+	})
+
+	t.Run("starts from cached copy when IdP is unreachable", func(t *testing.T) {
+		validator, err := NewJWTValidator(JWTValidatorConfig{
+			Issuer:      fixture.Issuer(),
+			JWKSURL:     fixture.JWKSURL(),
+			TrustDomain: "test-domain",
+			HTTPClient:  unreachable,
+			Clock:       fixture.Clock(),
+			CacheFile:   cacheFile,
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator from cached JWKS: %v", err)
+		}
+
+		tokenString, err := fixture.CreateAndSignToken(map[string]interface{}{"sub": "user@example.com"})
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+
+		result, err := validator.Validate(ctx, &JWTCredential{BearerCredential: BearerCredential{Token: tokenString}})
+		if err != nil {
+			t.Fatalf("validation with cached JWKS failed: %v", err)
+		}
+		if result.Subject != "user@example.com" {
+			t.Errorf("expected subject 'user@example.com', got %q", result.Subject)
+		}
+	})
+}
+
+func TestJWTValidatorLocalJWKS(t *testing.T) {
+	ctx := context.Background()
+	fixture := setupTestJWKSFixture(t)
+
+	validate := func(t *testing.T, validator *JWTValidator) {
+		t.Helper()
+		tokenString, err := fixture.CreateAndSignToken(map[string]interface{}{"sub": "user@example.com"})
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		if _, err := validator.Validate(ctx, &BearerCredential{Token: tokenString}); err != nil {
+			t.Errorf("validation failed: %v", err)
+		}
+	}
+
+	t.Run("inline JWKS", func(t *testing.T) {
+		validator, err := NewJWTValidator(JWTValidatorConfig{
+			Issuer:      fixture.Issuer(),
+			JWKS:        jwksJSON(t, fixture),
+			TrustDomain: "test-domain",
+			Clock:       fixture.Clock(),
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		validate(t, validator)
+	})
+
+	t.Run("JWKS file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		if err := os.WriteFile(path, jwksJSON(t, fixture), 0o600); err != nil {
+			t.Fatalf("failed to write JWKS: %v", err)
+		}
+		validator, err := NewJWTValidator(JWTValidatorConfig{
+			Issuer:      fixture.Issuer(),
+			JWKSFile:    path,
+			TrustDomain: "test-domain",
+			Clock:       fixture.Clock(),
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		t.Cleanup(func() { validator.Close() })
+		validate(t, validator)
+	})
+
+	t.Run("rejects more than one JWKS source", func(t *testing.T) {
+		_, err := NewJWTValidator(JWTValidatorConfig{
+			Issuer:  fixture.Issuer(),
+			JWKSURL: fixture.JWKSURL(),
+			JWKS:    jwksJSON(t, fixture),
+		})
+		if err == nil {
+			t.Fatal("expected error for both JWKS URL and inline JWKS")
+		}
+	})
+}
+
+func TestJWTValidatorDelegation(t *testing.T) {
+	ctx := context.Background()
+	fixture := setupTestJWKSFixture(t)
+	validator := createValidatorWithFixture(t, fixture)
+
+	validate := func(t *testing.T, act any) (*Result, error) {
+		t.Helper()
+		tokenString, err := fixture.CreateAndSignToken(map[string]interface{}{"sub": "user@example.com", "act": act})
+		if err != nil {
+			t.Fatalf("failed to create token: %v", err)
+		}
+		return validator.Validate(ctx, &BearerCredential{Token: tokenString})
+	}
+
+	t.Run("parses act claim", func(t *testing.T) {
+		result, err := validate(t, map[string]any{"sub": "gateway", "act": map[string]any{"sub": "frontend"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Delegation.Subject != "gateway" || result.Delegation.Prior.Subject != "frontend" {
+			t.Errorf("unexpected delegation %+v", result.Delegation)
+		}
+	})
+
+	t.Run("rejects malformed act claim", func(t *testing.T) {
+		if _, err := validate(t, map[string]any{"client_id": "gateway"}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+
+	"github.com/alechenninger/parsec/internal/request"
+)
+
+// ReloadableStore is a Store whose underlying store can be replaced while it is in
+// use, such as when validators are reconfigured without a restart
+// Each call uses the store current when it began, so a validation never sees
+// validators from two configurations.
+type ReloadableStore struct {
+	current atomic.Pointer[storeRef]
+}
+
+// storeRef holds a Store, since atomic.Pointer needs a concrete type
+type storeRef struct {
+	store Store
+}
+
+// NewReloadableStore creates a reloadable store that starts out as store
+func NewReloadableStore(store Store) *ReloadableStore {
+	s := &ReloadableStore{}
+	s.current.Store(&storeRef{store: store})
+	return s
+}
+
+// Swap replaces the underlying store, returning the one it replaced
+func (s *ReloadableStore) Swap(store Store) Store {
+	return s.current.Swap(&storeRef{store: store}).store
+}
+
+// Current returns the underlying store
+func (s *ReloadableStore) Current() Store {
+	return s.current.Load().store
+}
+
+// Validate implements the Store interface
+func (s *ReloadableStore) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	return s.Current().Validate(ctx, credential)
+}
+
+// ForActor implements the Store interface
+// The returned store is filtered from the current store, and is unaffected by later swaps.
+func (s *ReloadableStore) ForActor(ctx context.Context, actor *Result, requestAttrs *request.RequestAttributes) (Store, error) {
+	return s.Current().ForActor(ctx, actor, requestAttrs)
+}
+
+// closeValidators closes the validators that implement io.Closer
+func closeValidators(validators []Validator) error {
+	var errs []error
+	for _, v := range validators {
+		if closer, ok := v.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
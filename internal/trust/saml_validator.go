@@ -0,0 +1,375 @@
+package trust
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// samlAssertionNS is the SAML 2.0 assertion namespace
+const samlAssertionNS = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+// samlBearerMethod is the bearer subject confirmation method
+const samlBearerMethod = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+// SAMLIdPMetadata is what the SAML validator needs from an IdP's metadata
+type SAMLIdPMetadata struct {
+	// EntityID is the IdP's entity ID, the expected Issuer of its assertions
+	EntityID string
+
+	// Certificates are the IdP's signing certificates
+	Certificates []*x509.Certificate
+}
+
+// ParseSAMLIdPMetadata parses the signing certificates and entity ID from an
+// IdP's SAML 2.0 metadata (an EntityDescriptor with an IDPSSODescriptor)
+func ParseSAMLIdPMetadata(data []byte) (*SAMLIdPMetadata, error) {
+	var entity struct {
+		XMLName           xml.Name
+		EntityID          string `xml:"entityID,attr"`
+		IDPSSODescriptors []struct {
+			KeyDescriptors []struct {
+				Use          string   `xml:"use,attr"`
+				Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+			} `xml:"KeyDescriptor"`
+		} `xml:"IDPSSODescriptor"`
+	}
+	if err := xml.Unmarshal(data, &entity); err != nil {
+		return nil, fmt.Errorf("failed to parse SAML metadata: %w", err)
+	}
+	if entity.XMLName.Local != "EntityDescriptor" {
+		return nil, fmt.Errorf("SAML metadata must be an EntityDescriptor, got %s", entity.XMLName.Local)
+	}
+	if entity.EntityID == "" {
+		return nil, fmt.Errorf("SAML metadata has no entityID")
+	}
+
+	metadata := &SAMLIdPMetadata{EntityID: entity.EntityID}
+	for _, idp := range entity.IDPSSODescriptors {
+		for _, key := range idp.KeyDescriptors {
+			// Keys without a use are for both signing and encryption
+			if key.Use != "" && key.Use != "signing" {
+				continue
+			}
+			for _, encoded := range key.Certificates {
+				der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+				if err != nil {
+					return nil, fmt.Errorf("invalid certificate in SAML metadata: %w", err)
+				}
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					return nil, fmt.Errorf("invalid certificate in SAML metadata: %w", err)
+				}
+				metadata.Certificates = append(metadata.Certificates, cert)
+			}
+		}
+	}
+	if len(metadata.Certificates) == 0 {
+		return nil, fmt.Errorf("SAML metadata for %s has no IdP signing certificates", entity.EntityID)
+	}
+	return metadata, nil
+}
+
+// SAMLValidator validates signed SAML 2.0 assertions from one identity provider
+//
+// The assertion itself must be signed by one of the IdP's certificates. Its
+// conditions must be current and restrict it to the configured audience, and it
+// must have a current bearer subject confirmation for the configured recipient.
+// Each assertion is accepted once: its ID is remembered until it expires.
+type SAMLValidator struct {
+	idpEntityID     string
+	idpCertificates []*x509.Certificate
+	audience        string
+	recipient       string
+	trustDomain     string
+	clock           clock.Clock
+
+	// usedAssertions are the IDs of accepted assertions, until they expire,
+	// so assertions cannot be replayed
+	mu             sync.Mutex
+	usedAssertions map[string]time.Time
+}
+
+// SAMLValidatorConfig contains configuration for SAML assertion validation
+type SAMLValidatorConfig struct {
+	// IdPEntityID is the IdP's entity ID; assertions must be issued by it
+	IdPEntityID string
+
+	// IdPCertificates are the certificates the IdP signs assertions with
+	IdPCertificates []*x509.Certificate
+
+	// Audience is this service's entity ID; assertions must be restricted to it
+	Audience string
+
+	// Recipient is where assertions are presented, such as parsec's token endpoint URL;
+	// bearer subject confirmations must name it as their Recipient (default: Audience)
+	Recipient string
+
+	// TrustDomain is the trust domain of validated subjects
+	TrustDomain string
+
+	// Clock is the time source for condition checks
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// NewSAMLValidator creates a new SAML assertion validator
+func NewSAMLValidator(cfg SAMLValidatorConfig) (*SAMLValidator, error) {
+	if cfg.IdPEntityID == "" {
+		return nil, fmt.Errorf("IdP entity ID is required")
+	}
+	if len(cfg.IdPCertificates) == 0 {
+		return nil, fmt.Errorf("at least one IdP certificate is required")
+	}
+	if cfg.Audience == "" {
+		return nil, fmt.Errorf("audience is required")
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	recipient := cfg.Recipient
+	if recipient == "" {
+		recipient = cfg.Audience
+	}
+
+	return &SAMLValidator{
+		idpEntityID:     cfg.IdPEntityID,
+		idpCertificates: cfg.IdPCertificates,
+		audience:        cfg.Audience,
+		recipient:       recipient,
+		trustDomain:     cfg.TrustDomain,
+		clock:           clk,
+		usedAssertions:  make(map[string]time.Time),
+	}, nil
+}
+
+// CredentialTypes implements the Validator interface
+func (v *SAMLValidator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeSAML}
+}
+
+// samlAssertion is the part of a SAML 2.0 assertion the validator reads
+type samlAssertion struct {
+	ID           string    `xml:"ID,attr"`
+	Version      string    `xml:"Version,attr"`
+	IssueInstant time.Time `xml:"IssueInstant,attr"`
+	Issuer       string    `xml:"Issuer"`
+	Subject      struct {
+		NameID struct {
+			Format string `xml:"Format,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"NameID"`
+		SubjectConfirmations []struct {
+			Method string `xml:"Method,attr"`
+			Data   struct {
+				NotBefore    time.Time `xml:"NotBefore,attr"`
+				NotOnOrAfter time.Time `xml:"NotOnOrAfter,attr"`
+				Recipient    string    `xml:"Recipient,attr"`
+			} `xml:"SubjectConfirmationData"`
+		} `xml:"SubjectConfirmation"`
+	} `xml:"Subject"`
+	Conditions *struct {
+		NotBefore            time.Time `xml:"NotBefore,attr"`
+		NotOnOrAfter         time.Time `xml:"NotOnOrAfter,attr"`
+		AudienceRestrictions []struct {
+			Audiences []string `xml:"Audience"`
+		} `xml:"AudienceRestriction"`
+	} `xml:"Conditions"`
+	AttributeStatements []struct {
+		Attributes []struct {
+			Name   string   `xml:"Name,attr"`
+			Values []string `xml:"AttributeValue"`
+		} `xml:"Attribute"`
+	} `xml:"AttributeStatement"`
+}
+
+// Validate implements the Validator interface
+// The subject is the assertion's NameID; attributes become claims
+func (v *SAMLValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	samlCred, ok := credential.(*SAMLCredential)
+	if !ok {
+		return nil, fmt.Errorf("unsupported credential type for SAML validator: %T", credential)
+	}
+
+	assertion, err := v.verifySignature(samlCred.Assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	if assertion.Version != "2.0" {
+		return nil, fmt.Errorf("%w: unsupported SAML version %q", ErrInvalidToken, assertion.Version)
+	}
+	if assertion.ID == "" {
+		return nil, fmt.Errorf("%w: assertion has no ID", ErrInvalidToken)
+	}
+	if strings.TrimSpace(assertion.Issuer) != v.idpEntityID {
+		return nil, fmt.Errorf("%w: assertion issued by %q, expected %q", ErrInvalidToken, assertion.Issuer, v.idpEntityID)
+	}
+
+	now := v.clock.Now()
+	conditions := assertion.Conditions
+	if conditions == nil {
+		return nil, fmt.Errorf("%w: assertion has no conditions", ErrInvalidToken)
+	}
+	if !conditions.NotBefore.IsZero() && now.Before(conditions.NotBefore) {
+		return nil, fmt.Errorf("%w: assertion is not yet valid", ErrInvalidToken)
+	}
+	if !conditions.NotOnOrAfter.IsZero() && !now.Before(conditions.NotOnOrAfter) {
+		return nil, ErrExpiredToken
+	}
+	// Every audience restriction must include this service
+	if len(conditions.AudienceRestrictions) == 0 {
+		return nil, fmt.Errorf("%w: assertion has no audience restriction", ErrInvalidToken)
+	}
+	for _, restriction := range conditions.AudienceRestrictions {
+		if !slices.Contains(restriction.Audiences, v.audience) {
+			return nil, fmt.Errorf("%w: assertion is not intended for audience %s", ErrInvalidToken, v.audience)
+		}
+	}
+
+	confirmedUntil, err := v.checkBearerConfirmation(assertion, now)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := strings.TrimSpace(assertion.Subject.NameID.Value)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: assertion has no NameID", ErrInvalidToken)
+	}
+
+	resultClaims := claims.Claims{}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			switch len(attribute.Values) {
+			case 0:
+			case 1:
+				resultClaims[attribute.Name] = attribute.Values[0]
+			default:
+				values := make([]any, len(attribute.Values))
+				for i, value := range attribute.Values {
+					values[i] = value
+				}
+				resultClaims[attribute.Name] = values
+			}
+		}
+	}
+	if assertion.Subject.NameID.Format != "" {
+		resultClaims["name_id_format"] = assertion.Subject.NameID.Format
+	}
+
+	// The assertion is only usable until both its conditions and its confirmation expire
+	expiresAt := confirmedUntil
+	if !conditions.NotOnOrAfter.IsZero() && conditions.NotOnOrAfter.Before(expiresAt) {
+		expiresAt = conditions.NotOnOrAfter
+	}
+	if err := v.useAssertion(assertion.ID, expiresAt, now); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Subject:     subject,
+		Issuer:      v.idpEntityID,
+		TrustDomain: v.trustDomain,
+		Claims:      resultClaims,
+		ExpiresAt:   expiresAt,
+		IssuedAt:    assertion.IssueInstant,
+		Audience:    []string{v.audience},
+	}, nil
+}
+
+// verifySignature verifies the assertion's enveloped signature and parses the signed content
+// Only the signed element is parsed, so content outside the signature cannot be injected
+func (v *SAMLValidator) verifySignature(data []byte) (*samlAssertion, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return nil, fmt.Errorf("%w: malformed SAML assertion: %v", ErrInvalidToken, err)
+	}
+	root := doc.Root()
+	if root == nil || root.Tag != "Assertion" || root.NamespaceURI() != samlAssertionNS {
+		return nil, fmt.Errorf("%w: not a SAML 2.0 assertion", ErrInvalidToken)
+	}
+
+	validationCtx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{
+		Roots: v.idpCertificates,
+	})
+	validationCtx.Clock = dsig.NewFakeClockAt(v.clock.Now())
+	signed, err := validationCtx.Validate(root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid SAML assertion signature: %v", ErrInvalidToken, err)
+	}
+
+	signedDoc := etree.NewDocument()
+	signedDoc.SetRoot(signed)
+	signedXML, err := signedDoc.WriteToBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize signed SAML assertion: %w", err)
+	}
+	var assertion samlAssertion
+	if err := xml.Unmarshal(signedXML, &assertion); err != nil {
+		return nil, fmt.Errorf("%w: malformed SAML assertion: %v", ErrInvalidToken, err)
+	}
+	return &assertion, nil
+}
+
+// checkBearerConfirmation checks the assertion has a current bearer subject confirmation
+// for the validator's recipient, returning when it expires
+func (v *SAMLValidator) checkBearerConfirmation(assertion *samlAssertion, now time.Time) (time.Time, error) {
+	expired := false
+	for _, confirmation := range assertion.Subject.SubjectConfirmations {
+		if confirmation.Method != samlBearerMethod {
+			continue
+		}
+		data := confirmation.Data
+		if strings.TrimSpace(data.Recipient) != v.recipient {
+			// Bearer confirmations must name where the assertion may be presented
+			continue
+		}
+		if data.NotOnOrAfter.IsZero() {
+			// Bearer confirmations must be time-limited
+			continue
+		}
+		if !data.NotBefore.IsZero() && now.Before(data.NotBefore) {
+			continue
+		}
+		if !now.Before(data.NotOnOrAfter) {
+			expired = true
+			continue
+		}
+		return data.NotOnOrAfter, nil
+	}
+	if expired {
+		return time.Time{}, ErrExpiredToken
+	}
+	return time.Time{}, fmt.Errorf("%w: assertion has no current bearer subject confirmation for %s", ErrInvalidToken, v.recipient)
+}
+
+// useAssertion records an assertion as used until it expires, failing if it already was
+func (v *SAMLValidator) useAssertion(id string, expiresAt, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	maps.DeleteFunc(v.usedAssertions, func(_ string, exp time.Time) bool {
+		return !now.Before(exp)
+	})
+	if _, ok := v.usedAssertions[id]; ok {
+		return fmt.Errorf("%w: assertion %s has already been used", ErrInvalidToken, id)
+	}
+	v.usedAssertions[id] = expiresAt
+	return nil
+}
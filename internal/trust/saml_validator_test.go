@@ -0,0 +1,311 @@
+package trust
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// testSAMLIdP signs assertions like a SAML identity provider
+type testSAMLIdP struct {
+	cert    *x509.Certificate
+	signing *dsig.SigningContext
+}
+
+func newTestSAMLIdP(t *testing.T, notBefore time.Time) *testSAMLIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	signing, err := dsig.NewSigningContext(key, [][]byte{der})
+	if err != nil {
+		t.Fatalf("failed to create signing context: %v", err)
+	}
+	return &testSAMLIdP{cert: cert, signing: signing}
+}
+
+// testAssertion describes an assertion for testSAMLIdP.sign
+type testAssertion struct {
+	id           string
+	issuer       string
+	audience     string
+	notOnOrAfter time.Time
+	confirmUntil time.Time
+	recipient    string
+}
+
+func (idp *testSAMLIdP) sign(t *testing.T, issuedAt time.Time, a testAssertion) []byte {
+	t.Helper()
+	ts := func(tm time.Time) string { return tm.UTC().Format(time.RFC3339) }
+	assertionXML := fmt.Sprintf(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="%s" Version="2.0" IssueInstant="%s">
+  <saml:Issuer>%s</saml:Issuer>
+  <saml:Subject>
+    <saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">alice@example.com</saml:NameID>
+    <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+      <saml:SubjectConfirmationData NotOnOrAfter="%s" Recipient="%s"/>
+    </saml:SubjectConfirmation>
+  </saml:Subject>
+  <saml:Conditions NotBefore="%s" NotOnOrAfter="%s">
+    <saml:AudienceRestriction><saml:Audience>%s</saml:Audience></saml:AudienceRestriction>
+  </saml:Conditions>
+  <saml:AttributeStatement>
+    <saml:Attribute Name="department"><saml:AttributeValue>finance</saml:AttributeValue></saml:Attribute>
+    <saml:Attribute Name="groups"><saml:AttributeValue>admins</saml:AttributeValue><saml:AttributeValue>users</saml:AttributeValue></saml:Attribute>
+  </saml:AttributeStatement>
+</saml:Assertion>`, a.id, ts(issuedAt), a.issuer, ts(a.confirmUntil), a.recipient, ts(issuedAt), ts(a.notOnOrAfter), a.audience)
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromString(assertionXML); err != nil {
+		t.Fatalf("failed to parse assertion: %v", err)
+	}
+	signed, err := idp.signing.SignEnveloped(doc.Root())
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+	doc.SetRoot(signed)
+	out, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatalf("failed to serialize assertion: %v", err)
+	}
+	return out
+}
+
+func TestSAMLValidator(t *testing.T) {
+	ctx := context.Background()
+	issuedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	idp := newTestSAMLIdP(t, issuedAt.Add(-time.Hour))
+	clk := clock.NewFixtureClock(issuedAt.Add(time.Minute))
+
+	validator, err := NewSAMLValidator(SAMLValidatorConfig{
+		IdPEntityID:     "https://idp.example.com/metadata",
+		IdPCertificates: []*x509.Certificate{idp.cert},
+		Audience:        "https://parsec.example.com",
+		Recipient:       "https://parsec.example.com/v1/token",
+		TrustDomain:     "corp",
+		Clock:           clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	valid := testAssertion{
+		id:           "_a1",
+		issuer:       "https://idp.example.com/metadata",
+		audience:     "https://parsec.example.com",
+		notOnOrAfter: issuedAt.Add(10 * time.Minute),
+		confirmUntil: issuedAt.Add(5 * time.Minute),
+		recipient:    "https://parsec.example.com/v1/token",
+	}
+
+	t.Run("valid assertion", func(t *testing.T) {
+		result, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, valid)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "alice@example.com" {
+			t.Errorf("expected subject alice@example.com, got %s", result.Subject)
+		}
+		if result.Issuer != "https://idp.example.com/metadata" || result.TrustDomain != "corp" {
+			t.Errorf("unexpected issuer %s or trust domain %s", result.Issuer, result.TrustDomain)
+		}
+		if result.Claims["department"] != "finance" {
+			t.Errorf("expected department claim, got %v", result.Claims["department"])
+		}
+		if groups, ok := result.Claims["groups"].([]any); !ok || len(groups) != 2 {
+			t.Errorf("expected two groups, got %v", result.Claims["groups"])
+		}
+		if !result.ExpiresAt.Equal(valid.confirmUntil) {
+			t.Errorf("expected expiry at end of subject confirmation, got %v", result.ExpiresAt)
+		}
+		if !result.IssuedAt.Equal(issuedAt) {
+			t.Errorf("expected issued at %v, got %v", issuedAt, result.IssuedAt)
+		}
+	})
+
+	t.Run("rejects replayed assertion", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, valid)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+
+		a := valid
+		a.id = "_a2"
+		if _, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, a)}); err != nil {
+			t.Errorf("expected an assertion with another ID to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("forgets used assertions once they expire", func(t *testing.T) {
+		later := issuedAt.Add(time.Hour)
+		clk.Set(later.Add(time.Minute))
+		defer clk.Set(issuedAt.Add(time.Minute))
+
+		a := valid
+		a.id = "_a3"
+		a.notOnOrAfter = later.Add(10 * time.Minute)
+		a.confirmUntil = later.Add(5 * time.Minute)
+		if _, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, later, a)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := validator.usedAssertions["_a1"]; ok {
+			t.Error("expected the expired assertion _a1 to be forgotten")
+		}
+	})
+
+	t.Run("rejects other recipient", func(t *testing.T) {
+		a := valid
+		a.id = "_a4"
+		a.recipient = "https://other-sp.example.com/acs"
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, a)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects confirmation without recipient", func(t *testing.T) {
+		a := valid
+		a.id = "_a5"
+		a.recipient = ""
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, a)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects assertion without ID", func(t *testing.T) {
+		a := valid
+		a.id = ""
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, a)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects tampered assertion", func(t *testing.T) {
+		tampered := strings.Replace(string(idp.sign(t, issuedAt, valid)), "alice@example.com", "mallory@example.com", 1)
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: []byte(tampered)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects assertion signed by another IdP", func(t *testing.T) {
+		other := newTestSAMLIdP(t, issuedAt.Add(-time.Hour))
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: other.sign(t, issuedAt, valid)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects unsigned assertion", func(t *testing.T) {
+		signed := string(idp.sign(t, issuedAt, valid))
+		start, end := strings.Index(signed, "<ds:Signature"), strings.Index(signed, "</ds:Signature>")
+		unsigned := signed[:start] + signed[end+len("</ds:Signature>"):]
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: []byte(unsigned)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects other issuer", func(t *testing.T) {
+		a := valid
+		a.issuer = "https://other-idp.example.com"
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, a)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects other audience", func(t *testing.T) {
+		a := valid
+		a.audience = "https://other-sp.example.com"
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, a)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects expired conditions", func(t *testing.T) {
+		a := valid
+		a.notOnOrAfter = issuedAt.Add(30 * time.Second)
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, a)})
+		if !errors.Is(err, ErrExpiredToken) {
+			t.Errorf("expected ErrExpiredToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects expired subject confirmation", func(t *testing.T) {
+		a := valid
+		a.confirmUntil = issuedAt.Add(30 * time.Second)
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: idp.sign(t, issuedAt, a)})
+		if !errors.Is(err, ErrExpiredToken) {
+			t.Errorf("expected ErrExpiredToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects other XML", func(t *testing.T) {
+		_, err := validator.Validate(ctx, &SAMLCredential{Assertion: []byte(`<Response/>`)})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+}
+
+func TestParseSAMLIdPMetadata(t *testing.T) {
+	idp := newTestSAMLIdP(t, time.Now().Add(-time.Hour))
+	encoded := base64.StdEncoding.EncodeToString(idp.cert.Raw)
+	metadataXML := `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="https://idp.example.com/metadata">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>
+      ` + encoded[:64] + `
+      ` + encoded[64:] + `
+    </ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+    <md:KeyDescriptor use="encryption"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>not-a-certificate</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`
+
+	metadata, err := ParseSAMLIdPMetadata([]byte(metadataXML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metadata.EntityID != "https://idp.example.com/metadata" {
+		t.Errorf("unexpected entity ID %s", metadata.EntityID)
+	}
+	if len(metadata.Certificates) != 1 || !metadata.Certificates[0].Equal(idp.cert) {
+		t.Errorf("expected the signing certificate, got %d certificates", len(metadata.Certificates))
+	}
+
+	if _, err := ParseSAMLIdPMetadata([]byte(`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="x"/>`)); err == nil {
+		t.Error("expected error for metadata without signing certificates")
+	}
+}
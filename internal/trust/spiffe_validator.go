@@ -0,0 +1,306 @@
+package trust
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/jwtbundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/federation"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/jwtsvid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// SPIFFEValidator validates SPIFFE SVIDs against the trust bundle of a trust domain
+// X.509-SVIDs arrive as X509Credential (or MTLSCredential); JWT-SVIDs arrive as bearer tokens
+type SPIFFEValidator struct {
+	trustDomain spiffeid.TrustDomain
+	bundles     spiffeBundles
+	audiences   []string
+	clock       clock.Clock
+}
+
+// SPIFFEValidatorConfig contains configuration for SPIFFE SVID validation
+type SPIFFEValidatorConfig struct {
+	// TrustDomain is the SPIFFE trust domain SVIDs must belong to (e.g., "example.org")
+	TrustDomain string
+
+	// Bundles provides the trust domain's bundle, e.g. a workloadapi.BundleSource
+	// or a SPIFFEBundleEndpointSource
+	Bundles spiffebundle.Source
+
+	// Audiences are the accepted audiences of JWT-SVIDs; a JWT-SVID must have one of them
+	// If empty, JWT-SVIDs are not accepted
+	Audiences []string
+
+	// Clock is the time source for X.509-SVID expiry checks
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// NewSPIFFEValidator creates a new SPIFFE SVID validator
+func NewSPIFFEValidator(cfg SPIFFEValidatorConfig) (*SPIFFEValidator, error) {
+	trustDomain, err := spiffeid.TrustDomainFromString(cfg.TrustDomain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust domain: %w", err)
+	}
+	if cfg.Bundles == nil {
+		return nil, fmt.Errorf("bundle source is required")
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &SPIFFEValidator{
+		trustDomain: trustDomain,
+		bundles:     spiffeBundles{cfg.Bundles},
+		audiences:   cfg.Audiences,
+		clock:       clk,
+	}, nil
+}
+
+// Validate implements the Validator interface
+// The subject is the SVID's SPIFFE ID and the trust domain is the SPIFFE ID's trust domain
+func (v *SPIFFEValidator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	switch cred := credential.(type) {
+	case *X509Credential:
+		return v.validateX509SVID(append([]*x509.Certificate{cred.Certificate}, cred.Chain...))
+	case *MTLSCredential:
+		parsed, err := parseMTLSCredential(cred)
+		if err != nil {
+			return nil, err
+		}
+		return v.validateX509SVID(append([]*x509.Certificate{parsed.Certificate}, parsed.Chain...))
+	case *JWTCredential:
+		return v.validateJWTSVID(cred.Token)
+	case *BearerCredential:
+		return v.validateJWTSVID(cred.Token)
+	default:
+		return nil, fmt.Errorf("unsupported credential type for SPIFFE validator: %T", credential)
+	}
+}
+
+// validateX509SVID verifies a leaf certificate and its intermediates
+func (v *SPIFFEValidator) validateX509SVID(certs []*x509.Certificate) (*Result, error) {
+	if len(certs) == 0 || certs[0] == nil {
+		return nil, fmt.Errorf("%w: no certificate", ErrInvalidToken)
+	}
+
+	id, _, err := x509svid.Verify(certs, v.bundles, x509svid.WithTime(v.clock.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !id.MemberOf(v.trustDomain) {
+		return nil, fmt.Errorf("%w: SPIFFE ID %s is not in trust domain %s", ErrInvalidToken, id, v.trustDomain)
+	}
+
+	leaf := certs[0]
+	return &Result{
+		Subject:     id.String(),
+		Issuer:      leaf.Issuer.String(),
+		TrustDomain: id.TrustDomain().Name(),
+		Claims:      claims.Claims{"spiffe_id": id.String()},
+		ExpiresAt:   leaf.NotAfter,
+		IssuedAt:    leaf.NotBefore,
+	}, nil
+}
+
+// validateJWTSVID verifies a JWT-SVID's signature, expiry, and audience
+func (v *SPIFFEValidator) validateJWTSVID(token string) (*Result, error) {
+	if len(v.audiences) == 0 {
+		return nil, fmt.Errorf("JWT-SVIDs are not accepted without configured audiences")
+	}
+
+	svid, err := jwtsvid.ParseAndValidate(token, v.bundles, v.audiences)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !svid.ID.MemberOf(v.trustDomain) {
+		return nil, fmt.Errorf("%w: SPIFFE ID %s is not in trust domain %s", ErrInvalidToken, svid.ID, v.trustDomain)
+	}
+
+	// Registered claims are surfaced as Result fields
+	svidClaims := make(claims.Claims, len(svid.Claims))
+	maps.Copy(svidClaims, svid.Claims)
+	for _, registered := range []string{"iss", "sub", "aud", "exp", "iat", "nbf", "jti"} {
+		delete(svidClaims, registered)
+	}
+	svidClaims["spiffe_id"] = svid.ID.String()
+
+	result := &Result{
+		Subject:     svid.ID.String(),
+		TrustDomain: svid.ID.TrustDomain().Name(),
+		Claims:      svidClaims,
+		ExpiresAt:   svid.Expiry,
+		Audience:    svid.Audience,
+	}
+	if iss, ok := svid.Claims["iss"].(string); ok {
+		result.Issuer = iss
+	}
+	if iat, ok := svid.Claims["iat"].(float64); ok {
+		result.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	return result, nil
+}
+
+// CredentialTypes implements the Validator interface
+func (v *SPIFFEValidator) CredentialTypes() []CredentialType {
+	types := []CredentialType{CredentialTypeX509, CredentialTypeMTLS}
+	if len(v.audiences) > 0 {
+		types = append(types, CredentialTypeBearer, CredentialTypeJWT)
+	}
+	return types
+}
+
+// spiffeBundles adapts a SPIFFE bundle source to the X.509 and JWT bundle sources
+// the SVID verifiers need
+type spiffeBundles struct {
+	source spiffebundle.Source
+}
+
+func (b spiffeBundles) GetX509BundleForTrustDomain(trustDomain spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	bundle, err := b.source.GetBundleForTrustDomain(trustDomain)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.X509Bundle(), nil
+}
+
+func (b spiffeBundles) GetJWTBundleForTrustDomain(trustDomain spiffeid.TrustDomain) (*jwtbundle.Bundle, error) {
+	bundle, err := b.source.GetBundleForTrustDomain(trustDomain)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.JWTBundle(), nil
+}
+
+// SPIFFEBundleEndpointSource serves the bundle of one trust domain fetched from its
+// SPIFFE bundle endpoint (https_web profile). The bundle is refetched on use once it is
+// older than its refresh hint (or the configured refresh interval); if a refetch fails,
+// the last fetched bundle keeps being served.
+type SPIFFEBundleEndpointSource struct {
+	trustDomain     spiffeid.TrustDomain
+	url             string
+	fetchOptions    []federation.FetchOption
+	refreshInterval time.Duration
+	clock           clock.Clock
+
+	mu        sync.Mutex
+	bundle    *spiffebundle.Bundle
+	fetchedAt time.Time
+}
+
+// SPIFFEBundleEndpointSourceConfig configures a SPIFFEBundleEndpointSource
+type SPIFFEBundleEndpointSourceConfig struct {
+	// TrustDomain is the trust domain whose bundle the endpoint serves
+	TrustDomain string
+
+	// URL is the bundle endpoint URL (e.g., "https://spire.example.org/bundle")
+	URL string
+
+	// RootCAs verify the endpoint's web PKI certificate (default: system roots)
+	RootCAs *x509.CertPool
+
+	// RefreshInterval is how long a bundle is used before it is refetched,
+	// if the bundle has no refresh hint (default: 5 minutes)
+	RefreshInterval time.Duration
+
+	// Clock is the time source for refreshes
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// NewSPIFFEBundleEndpointSource creates a bundle endpoint source and fetches the initial bundle
+func NewSPIFFEBundleEndpointSource(ctx context.Context, cfg SPIFFEBundleEndpointSourceConfig) (*SPIFFEBundleEndpointSource, error) {
+	trustDomain, err := spiffeid.TrustDomainFromString(cfg.TrustDomain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust domain: %w", err)
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("bundle endpoint URL is required")
+	}
+
+	refreshInterval := cfg.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = 5 * time.Minute
+	}
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	var fetchOptions []federation.FetchOption
+	if cfg.RootCAs != nil {
+		fetchOptions = append(fetchOptions, federation.WithWebPKIRoots(cfg.RootCAs))
+	}
+
+	s := &SPIFFEBundleEndpointSource{
+		trustDomain:     trustDomain,
+		url:             cfg.URL,
+		fetchOptions:    fetchOptions,
+		refreshInterval: refreshInterval,
+		clock:           clk,
+	}
+
+	if err := s.fetch(ctx); err != nil {
+		return nil, fmt.Errorf("failed to fetch initial bundle: %w", err)
+	}
+	return s, nil
+}
+
+// GetBundleForTrustDomain implements spiffebundle.Source
+func (s *SPIFFEBundleEndpointSource) GetBundleForTrustDomain(trustDomain spiffeid.TrustDomain) (*spiffebundle.Bundle, error) {
+	if trustDomain != s.trustDomain {
+		return nil, fmt.Errorf("no bundle for trust domain %s", trustDomain)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clock.Now().Sub(s.fetchedAt) >= s.refreshAfter() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.fetchLocked(ctx); err != nil {
+			slog.Warn("Failed to refresh SPIFFE bundle, using previous bundle", "url", s.url, "error", err)
+		}
+	}
+	return s.bundle, nil
+}
+
+// fetch fetches the bundle from the endpoint
+func (s *SPIFFEBundleEndpointSource) fetch(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetchLocked(ctx)
+}
+
+// fetchLocked fetches the bundle; s.mu must be held
+func (s *SPIFFEBundleEndpointSource) fetchLocked(ctx context.Context) error {
+	bundle, err := federation.FetchBundle(ctx, s.trustDomain, s.url, s.fetchOptions...)
+	if err != nil {
+		return err
+	}
+	s.bundle = bundle
+	s.fetchedAt = s.clock.Now()
+	return nil
+}
+
+// refreshAfter is how long the current bundle is used before refetching it
+func (s *SPIFFEBundleEndpointSource) refreshAfter() time.Duration {
+	if hint, ok := s.bundle.RefreshHint(); ok && hint > 0 {
+		return hint
+	}
+	return s.refreshInterval
+}
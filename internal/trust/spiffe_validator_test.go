@@ -0,0 +1,288 @@
+package trust
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spiffe/go-spiffe/v2/bundle/spiffebundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// newTestJWTSVID signs a JWT-SVID with the given key and key ID
+func newTestJWTSVID(t *testing.T, key *ecdsa.PrivateKey, keyID, spiffeID, audience string) string {
+	t.Helper()
+	signingKey, err := jwk.FromRaw(key)
+	if err != nil {
+		t.Fatalf("failed to create JWK: %v", err)
+	}
+	if err := signingKey.Set(jwk.KeyIDKey, keyID); err != nil {
+		t.Fatalf("failed to set key ID: %v", err)
+	}
+
+	token, err := jwt.NewBuilder().
+		Subject(spiffeID).
+		Audience([]string{audience}).
+		IssuedAt(time.Now()).
+		Expiration(time.Now().Add(5*time.Minute)).
+		Claim("namespace", "default").
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build token: %v", err)
+	}
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.ES256, signingKey))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return string(signed)
+}
+
+func TestSPIFFEValidator(t *testing.T) {
+	ctx := context.Background()
+
+	ca := newTestCA(t, "spire-ca")
+	jwtKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	bundle := spiffebundle.New(spiffeid.RequireTrustDomainFromString("example.org"))
+	bundle.AddX509Authority(ca.cert)
+	if err := bundle.AddJWTAuthority("jwt-key", jwtKey.Public()); err != nil {
+		t.Fatalf("failed to add JWT authority: %v", err)
+	}
+
+	validator, err := NewSPIFFEValidator(SPIFFEValidatorConfig{
+		TrustDomain: "example.org",
+		Bundles:     bundle,
+		Audiences:   []string{"parsec"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	t.Run("X.509-SVID", func(t *testing.T) {
+		cert := ca.issue(t, "frontend", "spiffe://example.org/ns/default/sa/frontend")
+
+		result, err := validator.Validate(ctx, NewX509Credential(cert, nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "spiffe://example.org/ns/default/sa/frontend" {
+			t.Errorf("expected SPIFFE ID subject, got %s", result.Subject)
+		}
+		if result.TrustDomain != "example.org" {
+			t.Errorf("expected trust domain example.org, got %s", result.TrustDomain)
+		}
+		if !result.ExpiresAt.Equal(cert.NotAfter) {
+			t.Errorf("expected expiry %v, got %v", cert.NotAfter, result.ExpiresAt)
+		}
+	})
+
+	t.Run("X.509-SVID as mTLS credential", func(t *testing.T) {
+		cert := ca.issue(t, "frontend", "spiffe://example.org/frontend")
+
+		result, err := validator.Validate(ctx, &MTLSCredential{Certificate: cert.Raw})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "spiffe://example.org/frontend" {
+			t.Errorf("expected SPIFFE ID subject, got %s", result.Subject)
+		}
+	})
+
+	t.Run("JWT-SVID", func(t *testing.T) {
+		token := newTestJWTSVID(t, jwtKey, "jwt-key", "spiffe://example.org/backend", "parsec")
+
+		result, err := validator.Validate(ctx, &BearerCredential{Token: token})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "spiffe://example.org/backend" {
+			t.Errorf("expected SPIFFE ID subject, got %s", result.Subject)
+		}
+		if result.TrustDomain != "example.org" {
+			t.Errorf("expected trust domain example.org, got %s", result.TrustDomain)
+		}
+		if result.Claims["namespace"] != "default" {
+			t.Errorf("expected namespace claim, got %v", result.Claims["namespace"])
+		}
+		if len(result.Audience) != 1 || result.Audience[0] != "parsec" {
+			t.Errorf("expected audience [parsec], got %v", result.Audience)
+		}
+	})
+
+	t.Run("rejects JWT-SVID for another audience", func(t *testing.T) {
+		token := newTestJWTSVID(t, jwtKey, "jwt-key", "spiffe://example.org/backend", "other")
+
+		_, err := validator.Validate(ctx, &BearerCredential{Token: token})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects JWT-SVID signed by unknown key", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		token := newTestJWTSVID(t, otherKey, "jwt-key", "spiffe://example.org/backend", "parsec")
+
+		_, err = validator.Validate(ctx, &BearerCredential{Token: token})
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects SVID from another trust domain", func(t *testing.T) {
+		cert := ca.issue(t, "frontend", "spiffe://other.org/frontend")
+
+		_, err := validator.Validate(ctx, NewX509Credential(cert, nil))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects SVID from untrusted CA", func(t *testing.T) {
+		cert := newTestCA(t, "rogue-ca").issue(t, "frontend", "spiffe://example.org/frontend")
+
+		_, err := validator.Validate(ctx, NewX509Credential(cert, nil))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects expired X.509-SVID", func(t *testing.T) {
+		cert := ca.issue(t, "frontend", "spiffe://example.org/frontend")
+		expiredValidator, err := NewSPIFFEValidator(SPIFFEValidatorConfig{
+			TrustDomain: "example.org",
+			Bundles:     bundle,
+			Clock:       clock.NewFixtureClock(cert.NotAfter.Add(time.Minute)),
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+
+		_, err = expiredValidator.Validate(ctx, NewX509Credential(cert, nil))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("JWT-SVIDs not accepted without audiences", func(t *testing.T) {
+		x509Only, err := NewSPIFFEValidator(SPIFFEValidatorConfig{
+			TrustDomain: "example.org",
+			Bundles:     bundle,
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		for _, credType := range x509Only.CredentialTypes() {
+			if credType == CredentialTypeBearer || credType == CredentialTypeJWT {
+				t.Errorf("expected no token credential types, got %v", x509Only.CredentialTypes())
+			}
+		}
+	})
+}
+
+func TestSPIFFEBundleEndpointSource(t *testing.T) {
+	ctx := context.Background()
+	trustDomain := spiffeid.RequireTrustDomainFromString("example.org")
+
+	var fetches atomic.Int32
+	var serveBundle atomic.Pointer[spiffebundle.Bundle]
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if serveBundle.Load() == nil {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		data, err := serveBundle.Load().Marshal()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	first := newTestCA(t, "spire-ca-1")
+	bundle := spiffebundle.New(trustDomain)
+	bundle.AddX509Authority(first.cert)
+	serveBundle.Store(bundle)
+
+	clk := clock.NewFixtureClock(time.Now())
+	source, err := NewSPIFFEBundleEndpointSource(ctx, SPIFFEBundleEndpointSourceConfig{
+		TrustDomain:     "example.org",
+		URL:             server.URL,
+		RootCAs:         roots,
+		RefreshInterval: time.Minute,
+		Clock:           clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+
+	got, err := source.GetBundleForTrustDomain(trustDomain)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.HasX509Authority(first.cert) {
+		t.Error("expected fetched bundle to contain the first CA")
+	}
+
+	if _, err := source.GetBundleForTrustDomain(spiffeid.RequireTrustDomainFromString("other.org")); err == nil {
+		t.Error("expected error for another trust domain")
+	}
+
+	second := newTestCA(t, "spire-ca-2")
+
+	t.Run("refetches after refresh interval", func(t *testing.T) {
+		rotated := spiffebundle.New(trustDomain)
+		rotated.AddX509Authority(second.cert)
+		serveBundle.Store(rotated)
+
+		got, _ := source.GetBundleForTrustDomain(trustDomain)
+		if got.HasX509Authority(second.cert) {
+			t.Error("expected cached bundle before refresh interval")
+		}
+
+		clk.Advance(time.Minute)
+		got, _ = source.GetBundleForTrustDomain(trustDomain)
+		if !got.HasX509Authority(second.cert) {
+			t.Error("expected refetched bundle after refresh interval")
+		}
+		if fetches.Load() != 2 {
+			t.Errorf("expected 2 fetches, got %d", fetches.Load())
+		}
+	})
+
+	t.Run("keeps previous bundle when refetch fails", func(t *testing.T) {
+		serveBundle.Store(nil)
+
+		clk.Advance(time.Minute)
+		got, err := source.GetBundleForTrustDomain(trustDomain)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !got.HasX509Authority(second.cert) {
+			t.Error("expected previous bundle to be served")
+		}
+	})
+}
@@ -18,3 +18,20 @@ type Store interface {
 	// for filtering decisions (e.g., path, headers, envoy context extensions).
 	ForActor(ctx context.Context, actor *Result, requestAttrs *request.RequestAttributes) (Store, error)
 }
+
+// ValidatorRestrictingStore is a Store whose validators can be restricted by name
+type ValidatorRestrictingStore interface {
+	Store
+
+	// WithValidators returns a Store that only includes the named validators
+	// Names the store does not have are ignored.
+	WithValidators(names ...string) Store
+}
+
+// ValidatorListingStore is a Store that lists the validators it validates with
+type ValidatorListingStore interface {
+	Store
+
+	// ValidatorNames returns the names of the store's validators, in order
+	ValidatorNames() []string
+}
@@ -14,6 +14,8 @@ import (
 type StubStore struct {
 	// Index validators by credential type for fast lookup
 	validatorsByType map[CredentialType][]Validator
+	// All validators in order
+	validators []Validator
 }
 
 // NewStubStore creates a new stub trust store
@@ -29,9 +31,15 @@ func (s *StubStore) AddValidator(v Validator) *StubStore {
 	for _, credType := range v.CredentialTypes() {
 		s.validatorsByType[credType] = append(s.validatorsByType[credType], v)
 	}
+	s.validators = append(s.validators, v)
 	return s
 }
 
+// Close closes the store's validators that hold resources, such as JWKS refreshes
+func (s *StubStore) Close() error {
+	return closeValidators(s.validators)
+}
+
 // Validate implements the Store interface
 // Tries validators in order until one succeeds
 func (s *StubStore) Validate(ctx context.Context, credential Credential) (*Result, error) {
@@ -66,6 +74,12 @@ func (s *StubStore) ForActor(ctx context.Context, actor *Result, requestAttrs *r
 	return s, nil
 }
 
+// ValidatorNames implements ValidatorListingStore
+// Stub validators are unnamed, and the store does not filter them, so it lists none.
+func (s *StubStore) ValidatorNames() []string {
+	return nil
+}
+
 // StubValidator is a simple stub validator for testing
 // It accepts any token and returns a fixed result
 type StubValidator struct {
@@ -2,6 +2,7 @@ package trust
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"time"
 
@@ -53,6 +54,10 @@ type Result struct {
 
 	// Scope is the OAuth2 scope if applicable
 	Scope string `json:"scope,omitempty"`
+
+	// Delegation is the delegation chain (RFC 8693 act claim) the credential carries,
+	// if it was issued to an actor on behalf of the subject
+	Delegation *Delegation `json:"delegation,omitempty"`
 }
 
 // AnonymousResult returns a Result representing an anonymous/unauthenticated actor
@@ -71,6 +76,9 @@ const (
 	CredentialTypeMTLS   CredentialType = "mtls"
 	CredentialTypeOAuth2 CredentialType = "oauth2"
 	CredentialTypeJSON   CredentialType = "json"
+	CredentialTypeX509   CredentialType = "x509"
+	CredentialTypeAPIKey CredentialType = "api_key"
+	CredentialTypeSAML   CredentialType = "saml"
 )
 
 // Credential is the interface for all credential types
@@ -135,6 +143,38 @@ func (c *MTLSCredential) Type() CredentialType {
 	return CredentialTypeMTLS
 }
 
+// X509Credential represents a workload's X.509 client certificate,
+// such as an X.509-SVID presented to the gateway over mTLS
+type X509Credential struct {
+	// Certificate is the workload's leaf certificate
+	Certificate *x509.Certificate
+
+	// Chain is the intermediate certificates presented with it, if any
+	Chain []*x509.Certificate
+
+	// SPIFFEID is the SPIFFE ID in the certificate's URI SAN, or empty if it has none
+	SPIFFEID string
+}
+
+// NewX509Credential creates an X509Credential, extracting the SPIFFE ID from cert
+func NewX509Credential(cert *x509.Certificate, chain []*x509.Certificate) *X509Credential {
+	cred := &X509Credential{
+		Certificate: cert,
+		Chain:       chain,
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			cred.SPIFFEID = uri.String()
+			break
+		}
+	}
+	return cred
+}
+
+func (c *X509Credential) Type() CredentialType {
+	return CredentialTypeX509
+}
+
 // JSONCredential represents an unsigned JSON credential with a well-defined structure
 // This is used for pre-validated or self-asserted credentials where the structure
 // follows the Result format
@@ -146,3 +186,27 @@ type JSONCredential struct {
 func (c *JSONCredential) Type() CredentialType {
 	return CredentialTypeJSON
 }
+
+// APIKeyCredential represents an API key sent in a request header
+type APIKeyCredential struct {
+	// Key is the API key
+	Key string
+
+	// Header is the (lowercase) name of the header the key was sent in
+	Header string
+}
+
+func (c *APIKeyCredential) Type() CredentialType {
+	return CredentialTypeAPIKey
+}
+
+// SAMLCredential represents a SAML 2.0 assertion, such as a saml2 subject token
+// in a token exchange request
+type SAMLCredential struct {
+	// Assertion is the assertion XML
+	Assertion []byte
+}
+
+func (c *SAMLCredential) Type() CredentialType {
+	return CredentialTypeSAML
+}
@@ -0,0 +1,265 @@
+package trust
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// X509SubjectSource selects which part of a certificate becomes the result's subject
+type X509SubjectSource string
+
+const (
+	// X509SubjectDefault uses the SPIFFE ID if the certificate has one, otherwise the subject DN
+	X509SubjectDefault X509SubjectSource = ""
+
+	// X509SubjectDN uses the certificate's subject DN
+	X509SubjectDN X509SubjectSource = "subject_dn"
+
+	// X509SubjectDNSSAN uses the certificate's first DNS SAN
+	X509SubjectDNSSAN X509SubjectSource = "dns_san"
+
+	// X509SubjectURISAN uses the certificate's first URI SAN
+	X509SubjectURISAN X509SubjectSource = "uri_san"
+
+	// X509SubjectEmailSAN uses the certificate's first email SAN
+	X509SubjectEmailSAN X509SubjectSource = "email_san"
+)
+
+// X509Validator validates client certificates against a set of trusted CAs
+// It handles workload certificates presented to the gateway (X509Credential) and
+// certificates of callers that authenticate to parsec with mTLS (MTLSCredential).
+// Certificates with a SPIFFE ID must belong to the validator's trust domain
+type X509Validator struct {
+	roots         *x509.CertPool
+	trustDomain   string
+	extKeyUsages  []x509.ExtKeyUsage
+	allowedSANs   []string
+	subjectSource X509SubjectSource
+	clock         clock.Clock
+}
+
+// X509ValidatorConfig contains configuration for X.509 certificate validation
+type X509ValidatorConfig struct {
+	// Roots are the CAs that issue client certificates (e.g., a SPIFFE trust bundle)
+	Roots *x509.CertPool
+
+	// TrustDomain is the trust domain of validated certificates
+	// For SPIFFE certificates, this is the trust domain of the SPIFFE ID (e.g., "example.org")
+	TrustDomain string
+
+	// ExtKeyUsages are the acceptable extended key usages; the certificate must be
+	// valid for one of them (default: client authentication)
+	ExtKeyUsages []x509.ExtKeyUsage
+
+	// AllowedSANs restricts certificates to those with a DNS, URI, or email SAN matching
+	// one of these patterns. A leading "*" matches any prefix (e.g., "*.svc.cluster.local")
+	// and a trailing "*" matches any suffix (e.g., "spiffe://example.org/ns/prod/*").
+	// If empty, any SAN (or none) is allowed
+	AllowedSANs []string
+
+	// SubjectSource selects the result's subject (default: SPIFFE ID, otherwise subject DN)
+	SubjectSource X509SubjectSource
+
+	// Clock is the time source for certificate expiry checks
+	// If nil, uses system clock
+	Clock clock.Clock
+}
+
+// NewX509Validator creates a new X.509 certificate validator
+func NewX509Validator(cfg X509ValidatorConfig) (*X509Validator, error) {
+	if cfg.Roots == nil {
+		return nil, fmt.Errorf("roots are required")
+	}
+	if cfg.TrustDomain == "" {
+		return nil, fmt.Errorf("trust domain is required")
+	}
+	switch cfg.SubjectSource {
+	case X509SubjectDefault, X509SubjectDN, X509SubjectDNSSAN, X509SubjectURISAN, X509SubjectEmailSAN:
+	default:
+		return nil, fmt.Errorf("unknown subject source: %s", cfg.SubjectSource)
+	}
+
+	extKeyUsages := cfg.ExtKeyUsages
+	if len(extKeyUsages) == 0 {
+		extKeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.NewSystemClock()
+	}
+
+	return &X509Validator{
+		roots:         cfg.Roots,
+		trustDomain:   cfg.TrustDomain,
+		extKeyUsages:  extKeyUsages,
+		allowedSANs:   cfg.AllowedSANs,
+		subjectSource: cfg.SubjectSource,
+		clock:         clk,
+	}, nil
+}
+
+// Validate implements the Validator interface
+// The subject is chosen by the validator's subject source
+func (v *X509Validator) Validate(ctx context.Context, credential Credential) (*Result, error) {
+	var x509Cred *X509Credential
+	switch cred := credential.(type) {
+	case *X509Credential:
+		x509Cred = cred
+	case *MTLSCredential:
+		parsed, err := parseMTLSCredential(cred)
+		if err != nil {
+			return nil, err
+		}
+		x509Cred = parsed
+	default:
+		return nil, fmt.Errorf("expected X509Credential or MTLSCredential, got %T", credential)
+	}
+	cert := x509Cred.Certificate
+	if cert == nil {
+		return nil, fmt.Errorf("%w: no certificate", ErrInvalidToken)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range x509Cred.Chain {
+		intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   v.clock.Now(),
+		KeyUsages:     v.extKeyUsages,
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if len(v.allowedSANs) > 0 && !v.hasAllowedSAN(cert) {
+		return nil, fmt.Errorf("%w: certificate has no allowed SAN", ErrInvalidToken)
+	}
+
+	result := &Result{
+		Issuer:      cert.Issuer.String(),
+		TrustDomain: v.trustDomain,
+		Claims: claims.Claims{
+			"subject_dn": cert.Subject.String(),
+		},
+		ExpiresAt: cert.NotAfter,
+		IssuedAt:  cert.NotBefore,
+	}
+
+	if x509Cred.SPIFFEID != "" {
+		id, err := url.Parse(x509Cred.SPIFFEID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid SPIFFE ID %q: %v", ErrInvalidToken, x509Cred.SPIFFEID, err)
+		}
+		if id.Host != v.trustDomain {
+			return nil, fmt.Errorf("%w: SPIFFE ID %s is not in trust domain %s", ErrInvalidToken, x509Cred.SPIFFEID, v.trustDomain)
+		}
+		result.Claims["spiffe_id"] = x509Cred.SPIFFEID
+	}
+	if len(cert.DNSNames) > 0 {
+		result.Claims["dns_names"] = cert.DNSNames
+	}
+	if len(cert.URIs) > 0 {
+		uris := make([]string, len(cert.URIs))
+		for i, uri := range cert.URIs {
+			uris[i] = uri.String()
+		}
+		result.Claims["uris"] = uris
+	}
+	if len(cert.EmailAddresses) > 0 {
+		result.Claims["email_addresses"] = cert.EmailAddresses
+	}
+
+	subject, err := v.subject(x509Cred)
+	if err != nil {
+		return nil, err
+	}
+	result.Subject = subject
+
+	return result, nil
+}
+
+// subject returns the part of the certificate the validator uses as the subject
+func (v *X509Validator) subject(cred *X509Credential) (string, error) {
+	cert := cred.Certificate
+	switch v.subjectSource {
+	case X509SubjectDN:
+		return cert.Subject.String(), nil
+	case X509SubjectDNSSAN:
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0], nil
+		}
+	case X509SubjectURISAN:
+		if len(cert.URIs) > 0 {
+			return cert.URIs[0].String(), nil
+		}
+	case X509SubjectEmailSAN:
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0], nil
+		}
+	default:
+		if cred.SPIFFEID != "" {
+			return cred.SPIFFEID, nil
+		}
+		return cert.Subject.String(), nil
+	}
+	return "", fmt.Errorf("%w: certificate has no %s for the subject", ErrInvalidToken, v.subjectSource)
+}
+
+// hasAllowedSAN reports whether any of the certificate's SANs matches an allowed pattern
+func (v *X509Validator) hasAllowedSAN(cert *x509.Certificate) bool {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+
+	for _, pattern := range v.allowedSANs {
+		for _, san := range sans {
+			if matchSAN(pattern, san) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSAN matches a SAN against a pattern with an optional leading or trailing "*"
+func matchSAN(pattern, san string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(san, suffix)
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(san, prefix)
+	}
+	return san == pattern
+}
+
+// parseMTLSCredential parses the DER certificates of an mTLS credential
+func parseMTLSCredential(cred *MTLSCredential) (*X509Credential, error) {
+	cert, err := x509.ParseCertificate(cred.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse certificate: %v", ErrInvalidToken, err)
+	}
+	chain := make([]*x509.Certificate, 0, len(cred.Chain))
+	for _, der := range cred.Chain {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to parse chain certificate: %v", ErrInvalidToken, err)
+		}
+		chain = append(chain, c)
+	}
+	return NewX509Credential(cert, chain), nil
+}
+
+// CredentialTypes implements the Validator interface
+func (v *X509Validator) CredentialTypes() []CredentialType {
+	return []CredentialType{CredentialTypeX509, CredentialTypeMTLS}
+}
@@ -0,0 +1,257 @@
+package trust
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+)
+
+// testCA issues client certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, commonName, spiffeID string) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if spiffeID != "" {
+		uri, err := url.Parse(spiffeID)
+		if err != nil {
+			t.Fatalf("invalid SPIFFE ID: %v", err)
+		}
+		template.URIs = []*url.URL{uri}
+	}
+	return ca.issueTemplate(t, template)
+}
+
+// issueTemplate issues a certificate from template, filling in its serial number,
+// validity, and key usage
+func (ca *testCA) issueTemplate(t *testing.T, template *x509.Certificate) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template.SerialNumber = big.NewInt(2)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestX509Validator(t *testing.T) {
+	ctx := context.Background()
+
+	ca := newTestCA(t, "workload-ca")
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	validator, err := NewX509Validator(X509ValidatorConfig{
+		Roots:       roots,
+		TrustDomain: "example.org",
+	})
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+
+	t.Run("SPIFFE certificate", func(t *testing.T) {
+		cert := ca.issue(t, "frontend", "spiffe://example.org/ns/default/sa/frontend")
+
+		result, err := validator.Validate(ctx, NewX509Credential(cert, nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "spiffe://example.org/ns/default/sa/frontend" {
+			t.Errorf("expected SPIFFE ID subject, got %s", result.Subject)
+		}
+		if result.TrustDomain != "example.org" {
+			t.Errorf("expected trust domain example.org, got %s", result.TrustDomain)
+		}
+		if result.Issuer != "CN=workload-ca" {
+			t.Errorf("expected issuer CN=workload-ca, got %s", result.Issuer)
+		}
+		if result.Claims["spiffe_id"] != "spiffe://example.org/ns/default/sa/frontend" {
+			t.Errorf("expected spiffe_id claim, got %v", result.Claims["spiffe_id"])
+		}
+		if !result.ExpiresAt.Equal(cert.NotAfter) {
+			t.Errorf("expected expiry %v, got %v", cert.NotAfter, result.ExpiresAt)
+		}
+	})
+
+	t.Run("certificate without SPIFFE ID uses subject DN", func(t *testing.T) {
+		cert := ca.issue(t, "batch-job", "")
+
+		result, err := validator.Validate(ctx, NewX509Credential(cert, nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "CN=batch-job" {
+			t.Errorf("expected subject CN=batch-job, got %s", result.Subject)
+		}
+		if _, ok := result.Claims["spiffe_id"]; ok {
+			t.Error("expected no spiffe_id claim")
+		}
+	})
+
+	t.Run("rejects SPIFFE ID from another trust domain", func(t *testing.T) {
+		cert := ca.issue(t, "frontend", "spiffe://other.org/frontend")
+
+		_, err := validator.Validate(ctx, NewX509Credential(cert, nil))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("rejects certificate from untrusted CA", func(t *testing.T) {
+		cert := newTestCA(t, "rogue-ca").issue(t, "frontend", "spiffe://example.org/frontend")
+
+		_, err := validator.Validate(ctx, NewX509Credential(cert, nil))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("mTLS credential", func(t *testing.T) {
+		cert := ca.issue(t, "billing", "")
+
+		result, err := validator.Validate(ctx, &MTLSCredential{Certificate: cert.Raw})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "CN=billing" {
+			t.Errorf("expected subject CN=billing, got %s", result.Subject)
+		}
+		if result.Claims["subject_dn"] != "CN=billing" {
+			t.Errorf("expected subject_dn claim, got %v", result.Claims["subject_dn"])
+		}
+	})
+
+	t.Run("rejects certificate without required EKU", func(t *testing.T) {
+		cert := ca.issueTemplate(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "web"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		})
+
+		_, err := validator.Validate(ctx, NewX509Credential(cert, nil))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+
+		serverAuth, err := NewX509Validator(X509ValidatorConfig{
+			Roots:        roots,
+			TrustDomain:  "example.org",
+			ExtKeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		if _, err := serverAuth.Validate(ctx, NewX509Credential(cert, nil)); err != nil {
+			t.Errorf("expected certificate valid for server auth, got %v", err)
+		}
+	})
+
+	t.Run("allowed SANs and subject source", func(t *testing.T) {
+		sanValidator, err := NewX509Validator(X509ValidatorConfig{
+			Roots:         roots,
+			TrustDomain:   "example.org",
+			AllowedSANs:   []string{"*.billing.svc.cluster.local", "spiffe://example.org/ns/prod/*"},
+			SubjectSource: X509SubjectDNSSAN,
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+
+		allowed := ca.issueTemplate(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "billing"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			DNSNames:    []string{"api.billing.svc.cluster.local"},
+		})
+		result, err := sanValidator.Validate(ctx, NewX509Credential(allowed, nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Subject != "api.billing.svc.cluster.local" {
+			t.Errorf("expected DNS SAN subject, got %s", result.Subject)
+		}
+
+		disallowed := ca.issueTemplate(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "search"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			DNSNames:    []string{"api.search.svc.cluster.local"},
+		})
+		if _, err := sanValidator.Validate(ctx, NewX509Credential(disallowed, nil)); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken for disallowed SAN, got %v", err)
+		}
+
+		// Allowed by URI SAN, but has no DNS SAN for the subject
+		noDNS := ca.issue(t, "prod", "spiffe://example.org/ns/prod/sa/worker")
+		if _, err := sanValidator.Validate(ctx, NewX509Credential(noDNS, nil)); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken without DNS SAN, got %v", err)
+		}
+	})
+
+	t.Run("rejects expired certificate", func(t *testing.T) {
+		cert := ca.issue(t, "frontend", "spiffe://example.org/frontend")
+		expiredValidator, err := NewX509Validator(X509ValidatorConfig{
+			Roots:       roots,
+			TrustDomain: "example.org",
+			Clock:       clock.NewFixtureClock(cert.NotAfter.Add(time.Minute)),
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+
+		_, err = expiredValidator.Validate(ctx, NewX509Credential(cert, nil))
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/service"
+)
+
+// fetchRequest is the request a data source plugin's fetch function handles
+type fetchRequest struct {
+	Config map[string]any           `json:"config,omitempty"`
+	Input  *service.DataSourceInput `json:"input"`
+}
+
+// fetchResponse is a data source plugin's response
+// Null or absent data means the plugin has nothing to contribute.
+type fetchResponse struct {
+	Data  json.RawMessage `json:"data"`
+	Error string          `json:"error"`
+}
+
+// DataSource is a service.DataSource implemented by a plugin's fetch function
+type DataSource struct {
+	name     string
+	module   *Module
+	cacheTTL time.Duration
+}
+
+// NewDataSource creates a data source backed by module, which must export fetch
+// Results are cached for cacheTTL if the data source is wrapped with a cache.
+func NewDataSource(name string, module *Module, cacheTTL time.Duration) (*DataSource, error) {
+	if name == "" {
+		return nil, fmt.Errorf("data source name is required")
+	}
+	if !module.Exports("fetch") {
+		return nil, fmt.Errorf("wasm data source %s: module does not export fetch", name)
+	}
+	return &DataSource{name: name, module: module, cacheTTL: cacheTTL}, nil
+}
+
+// Name implements service.DataSource
+func (d *DataSource) Name() string {
+	return d.name
+}
+
+// Fetch implements service.DataSource
+func (d *DataSource) Fetch(ctx context.Context, input *service.DataSourceInput) (*service.DataSourceResult, error) {
+	var resp fetchResponse
+	if err := d.module.Call(ctx, "fetch", fetchRequest{Config: d.module.config, Input: input}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("wasm data source %s: %s", d.name, resp.Error)
+	}
+	if len(resp.Data) == 0 || string(resp.Data) == "null" {
+		return nil, nil
+	}
+	return &service.DataSourceResult{
+		Data:        resp.Data,
+		ContentType: service.ContentTypeJSON,
+	}, nil
+}
+
+// CacheKey implements service.Cacheable
+// The plugin may use any of the input, so all of it is the key.
+func (d *DataSource) CacheKey(input *service.DataSourceInput) service.DataSourceInput {
+	return *input
+}
+
+// CacheTTL implements service.Cacheable
+func (d *DataSource) CacheTTL() time.Duration {
+	return d.cacheTTL
+}
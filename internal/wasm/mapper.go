@@ -0,0 +1,104 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alechenninger/parsec/internal/claims"
+	"github.com/alechenninger/parsec/internal/request"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// mapRequest is the request a claim mapper plugin's map function handles
+type mapRequest struct {
+	Config      map[string]any             `json:"config,omitempty"`
+	Subject     *trust.Result              `json:"subject,omitempty"`
+	Actor       *trust.Result              `json:"actor,omitempty"`
+	Workload    *trust.Result              `json:"workload,omitempty"`
+	Request     *request.RequestAttributes `json:"request,omitempty"`
+	DataSources map[string]json.RawMessage `json:"datasources,omitempty"`
+}
+
+// mapResponse is a claim mapper plugin's response
+type mapResponse struct {
+	Claims claims.Claims `json:"claims"`
+	Error  string        `json:"error"`
+}
+
+// ClaimMapper is a service.ClaimMapper implemented by a plugin's map function
+// Plugins cannot call back into parsec, so the data sources a mapper needs are
+// fetched up front and passed in its request.
+type ClaimMapper struct {
+	module      *Module
+	dataSources []string
+}
+
+// NewClaimMapper creates a claim mapper backed by module, which must export map
+// dataSources name the data sources to fetch for each request.
+func NewClaimMapper(module *Module, dataSources []string) (*ClaimMapper, error) {
+	if !module.Exports("map") {
+		return nil, fmt.Errorf("wasm claim mapper: module does not export map")
+	}
+	return &ClaimMapper{module: module, dataSources: dataSources}, nil
+}
+
+// Map implements service.ClaimMapper
+func (m *ClaimMapper) Map(ctx context.Context, input *service.MapperInput) (claims.Claims, error) {
+	if input == nil {
+		return nil, fmt.Errorf("mapper input cannot be nil")
+	}
+
+	req := mapRequest{
+		Config:   m.module.config,
+		Subject:  input.Subject,
+		Actor:    input.Actor,
+		Workload: input.Workload,
+		Request:  input.RequestAttributes,
+	}
+	for _, name := range m.dataSources {
+		data, err := fetchDataSource(ctx, input, name)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			if req.DataSources == nil {
+				req.DataSources = make(map[string]json.RawMessage)
+			}
+			req.DataSources[name] = data
+		}
+	}
+
+	var resp mapResponse
+	if err := m.module.Call(ctx, "map", req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("wasm claim mapper: %s", resp.Error)
+	}
+	return resp.Claims, nil
+}
+
+// fetchDataSource fetches the JSON result of the named data source, or nil if it
+// has none
+func fetchDataSource(ctx context.Context, input *service.MapperInput, name string) (json.RawMessage, error) {
+	if input.DataSourceRegistry == nil {
+		return nil, fmt.Errorf("data source %s not found", name)
+	}
+	ds := input.DataSourceRegistry.Get(name)
+	if ds == nil {
+		return nil, fmt.Errorf("data source %s not found", name)
+	}
+	result, err := ds.Fetch(ctx, input.DataSourceInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data source %s: %w", name, err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+	if result.ContentType != service.ContentTypeJSON {
+		return nil, fmt.Errorf("data source %s returned unsupported content type %s", name, result.ContentType)
+	}
+	return result.Data, nil
+}
@@ -0,0 +1,196 @@
+// Package wasm hosts WebAssembly plugins, so claim mappers, validators, and data
+// sources can be written in any language that compiles to WASI and dropped in as
+// .wasm modules
+//
+// A plugin is a WASI reactor module (e.g., built with TinyGo, or Go's
+// -buildmode=c-shared, or Rust's wasm32-wasip1 target) that exports:
+//
+//   - memory: its linear memory
+//   - alloc(size i32) i32: allocates size bytes for the host to write input to
+//   - fetch, map, or validate(ptr i32, len i32) i64: handles the JSON request at
+//     ptr, returning where its JSON response is as (ptr << 32) | len
+//
+// Each call gets a fresh instance of the module, so plugins need not be safe for
+// concurrent use, and no state leaks between requests.
+package wasm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// DefaultMemoryLimitPages caps a plugin's memory at 16 MiB, in 64 KiB pages
+const DefaultMemoryLimitPages = 256
+
+// DefaultTimeout bounds each call to a plugin
+const DefaultTimeout = 5 * time.Second
+
+// Config configures a plugin module
+type Config struct {
+	// Path is the .wasm file to load
+	Path string
+
+	// Config is passed to the plugin with every request, so one module can be
+	// configured differently for each use
+	Config map[string]any
+
+	// MemoryLimitPages caps the plugin's memory, in 64 KiB pages
+	// (default: DefaultMemoryLimitPages)
+	MemoryLimitPages uint32
+
+	// Timeout bounds each call to the plugin (default: DefaultTimeout)
+	Timeout time.Duration
+}
+
+// Module is a compiled plugin
+type Module struct {
+	path     string
+	config   map[string]any
+	timeout  time.Duration
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// Load reads and compiles the plugin at cfg.Path
+func Load(ctx context.Context, cfg Config) (*Module, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("wasm plugin path is required")
+	}
+	code, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wasm plugin %s: %w", cfg.Path, err)
+	}
+	module, err := Compile(ctx, code, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("wasm plugin %s: %w", cfg.Path, err)
+	}
+	return module, nil
+}
+
+// Compile compiles a plugin from its code, ignoring cfg.Path
+func Compile(ctx context.Context, code []byte, cfg Config) (*Module, error) {
+	memoryLimit := cfg.MemoryLimitPages
+	if memoryLimit == 0 {
+		memoryLimit = DefaultMemoryLimitPages
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(memoryLimit).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile: %w", err)
+	}
+	exports := compiled.ExportedFunctions()
+	if _, ok := exports["alloc"]; !ok {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("module does not export alloc")
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("module does not export memory")
+	}
+
+	return &Module{
+		path:     cfg.Path,
+		config:   cfg.Config,
+		timeout:  timeout,
+		runtime:  runtime,
+		compiled: compiled,
+	}, nil
+}
+
+// Exports reports whether the plugin exports function
+func (m *Module) Exports(function string) bool {
+	_, ok := m.compiled.ExportedFunctions()[function]
+	return ok
+}
+
+// Close releases the compiled module
+func (m *Module) Close(ctx context.Context) error {
+	return m.runtime.Close(ctx)
+}
+
+// Call invokes function with input as JSON, decoding its JSON response into output
+func (m *Module) Call(ctx context.Context, function string, input, output any) error {
+	request, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", function, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	// Start functions: _initialize for reactors; _start is left alone since
+	// commands exit once it returns
+	instance, err := m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStdout(os.Stderr).
+		WithStderr(os.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader))
+	if err != nil {
+		return fmt.Errorf("failed to instantiate wasm plugin: %w", err)
+	}
+	defer instance.Close(ctx)
+
+	response, err := call(ctx, instance, function, request)
+	if err != nil {
+		return fmt.Errorf("wasm plugin %s: %w", function, err)
+	}
+	if err := json.Unmarshal(response, output); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", function, err)
+	}
+	return nil
+}
+
+// call writes request to instance's memory, calls function with it, and returns
+// a copy of the response
+func call(ctx context.Context, instance api.Module, function string, request []byte) ([]byte, error) {
+	fn := instance.ExportedFunction(function)
+	if fn == nil {
+		return nil, fmt.Errorf("module does not export %s", function)
+	}
+	memory := instance.Memory()
+
+	results, err := instance.ExportedFunction("alloc").Call(ctx, uint64(len(request)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc failed: %w", err)
+	}
+	ptr := uint32(results[0])
+	if !memory.Write(ptr, request) {
+		return nil, fmt.Errorf("alloc returned out of range memory")
+	}
+
+	results, err = fn.Call(ctx, uint64(ptr), uint64(len(request)))
+	if err != nil {
+		return nil, err
+	}
+	responsePtr, responseLen := uint32(results[0]>>32), uint32(results[0])
+	response, ok := memory.Read(responsePtr, responseLen)
+	if !ok {
+		return nil, fmt.Errorf("response is out of range memory")
+	}
+	// The view is invalid once the instance is closed
+	return append([]byte(nil), response...), nil
+}
@@ -0,0 +1,210 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// plugin assembles a minimal plugin exporting memory, a bump allocator, and
+// function, which ignores its request and returns response
+func plugin(function, response string) []byte {
+	const responsePtr = 1024
+	section := func(id byte, content ...byte) []byte {
+		return append(append([]byte{id}, uleb(uint64(len(content)))...), content...)
+	}
+	name := func(s string) []byte {
+		return append(uleb(uint64(len(s))), s...)
+	}
+	body := func(code ...byte) []byte {
+		return append(uleb(uint64(len(code)+1)), append([]byte{0x00}, code...)...) // no locals
+	}
+	concat := func(parts ...[]byte) []byte {
+		var out []byte
+		for _, part := range parts {
+			out = append(out, part...)
+		}
+		return out
+	}
+
+	// alloc: return $heap, then advance it by size
+	allocBody := body(0x23, 0x00, 0x23, 0x00, 0x20, 0x00, 0x6a, 0x24, 0x00, 0x0b)
+	// function: return (responsePtr << 32) | len(response)
+	functionBody := body(concat([]byte{0x42}, sleb(responsePtr<<32|int64(len(response))), []byte{0x0b})...)
+
+	return concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		// types: (i32) -> i32, (i32, i32) -> i64
+		section(1, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e),
+		section(3, 0x02, 0x00, 0x01),
+		// one page of memory
+		section(5, 0x01, 0x00, 0x01),
+		// mutable $heap = 2048
+		section(6, concat([]byte{0x01, 0x7f, 0x01, 0x41}, sleb(2048), []byte{0x0b})...),
+		section(7, concat([]byte{0x03},
+			name("memory"), []byte{0x02, 0x00},
+			name("alloc"), []byte{0x00, 0x00},
+			name(function), []byte{0x00, 0x01})...),
+		section(10, concat([]byte{0x02}, allocBody, functionBody)...),
+		section(11, concat([]byte{0x01, 0x00, 0x41}, sleb(responsePtr), []byte{0x0b}, name(response))...),
+	)
+}
+
+func uleb(v uint64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func sleb(v int64) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func compile(t *testing.T, function, response string) *Module {
+	t.Helper()
+	ctx := context.Background()
+	module, err := Compile(ctx, plugin(function, response), Config{Config: map[string]any{"tenant": "acme"}})
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	t.Cleanup(func() { module.Close(ctx) })
+	return module
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "plugin.wasm")
+	if err := os.WriteFile(path, plugin("fetch", `{"data":null}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	module, err := Load(ctx, Config{Path: path})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	defer module.Close(ctx)
+	if !module.Exports("fetch") || module.Exports("map") {
+		t.Errorf("unexpected exports")
+	}
+
+	if _, err := Compile(ctx, []byte("not wasm"), Config{}); err == nil {
+		t.Error("expected invalid modules to fail to compile")
+	}
+}
+
+func TestDataSource(t *testing.T) {
+	ctx := context.Background()
+
+	ds, err := NewDataSource("plugin", compile(t, "fetch", `{"data":{"roles":["admin"]}}`), 0)
+	if err != nil {
+		t.Fatalf("NewDataSource failed: %v", err)
+	}
+	result, err := ds.Fetch(ctx, &service.DataSourceInput{Subject: &trust.Result{Subject: "alice"}})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if string(result.Data) != `{"roles":["admin"]}` || result.ContentType != service.ContentTypeJSON {
+		t.Errorf("unexpected result: %s (%s)", result.Data, result.ContentType)
+	}
+
+	t.Run("nothing to contribute", func(t *testing.T) {
+		ds, _ := NewDataSource("plugin", compile(t, "fetch", `{"data":null}`), 0)
+		result, err := ds.Fetch(ctx, &service.DataSourceInput{})
+		if err != nil || result != nil {
+			t.Errorf("expected no result, got %v, %v", result, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		ds, _ := NewDataSource("plugin", compile(t, "fetch", `{"error":"backend unavailable"}`), 0)
+		if _, err := ds.Fetch(ctx, &service.DataSourceInput{}); err == nil || !strings.Contains(err.Error(), "backend unavailable") {
+			t.Errorf("expected the plugin's error, got %v", err)
+		}
+	})
+
+	t.Run("requires fetch", func(t *testing.T) {
+		if _, err := NewDataSource("plugin", compile(t, "map", `{}`), 0); err == nil {
+			t.Error("expected an error for a module without fetch")
+		}
+	})
+}
+
+func TestClaimMapper(t *testing.T) {
+	ctx := context.Background()
+
+	registry := service.NewDataSourceRegistry()
+	ds, _ := NewDataSource("roles", compile(t, "fetch", `{"data":["admin"]}`), 0)
+	registry.Register(ds)
+
+	mapper, err := NewClaimMapper(compile(t, "map", `{"claims":{"department":"eng"}}`), []string{"roles"})
+	if err != nil {
+		t.Fatalf("NewClaimMapper failed: %v", err)
+	}
+	claims, err := mapper.Map(ctx, &service.MapperInput{
+		Subject:            &trust.Result{Subject: "alice"},
+		DataSourceRegistry: registry,
+		DataSourceInput:    &service.DataSourceInput{},
+	})
+	if err != nil {
+		t.Fatalf("Map failed: %v", err)
+	}
+	if claims["department"] != "eng" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+
+	t.Run("missing data source", func(t *testing.T) {
+		mapper, _ := NewClaimMapper(compile(t, "map", `{"claims":{}}`), []string{"missing"})
+		if _, err := mapper.Map(ctx, &service.MapperInput{DataSourceRegistry: registry}); err == nil {
+			t.Error("expected an error for a missing data source")
+		}
+	})
+}
+
+func TestValidator(t *testing.T) {
+	ctx := context.Background()
+
+	result := trust.Result{Subject: "alice", Issuer: "https://plugin.example.com"}
+	response, _ := json.Marshal(validateResponse{Result: &result})
+	validator, err := NewValidator(compile(t, "validate", string(response)), nil, "example.com")
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	if types := validator.CredentialTypes(); len(types) != 1 || types[0] != trust.CredentialTypeBearer {
+		t.Errorf("expected bearer credentials by default, got %v", types)
+	}
+
+	got, err := validator.Validate(ctx, &trust.BearerCredential{Token: "token"})
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if got.Subject != "alice" || got.TrustDomain != "example.com" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	t.Run("rejects", func(t *testing.T) {
+		validator, _ := NewValidator(compile(t, "validate", `{"error":"expired"}`), nil, "")
+		if _, err := validator.Validate(ctx, &trust.BearerCredential{Token: "token"}); err == nil || !strings.Contains(err.Error(), "expired") {
+			t.Errorf("expected the plugin's rejection, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package wasm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// credential is a credential as plugins see it
+// Only the fields of the credential's type are set.
+type credential struct {
+	Type trust.CredentialType `json:"type"`
+
+	// Token is the token of bearer, JWT, and OIDC credentials
+	Token string `json:"token,omitempty"`
+
+	// Issuer is the unverified issuer of JWT and OIDC credentials
+	Issuer string `json:"issuer,omitempty"`
+
+	// JSON is the document of JSON credentials
+	JSON string `json:"json,omitempty"`
+
+	// Key and Header are the key of API key credentials, and where it was sent
+	Key    string `json:"key,omitempty"`
+	Header string `json:"header,omitempty"`
+
+	// Certificate and Chain are the base64 DER certificates of X.509 credentials
+	Certificate string   `json:"certificate,omitempty"`
+	Chain       []string `json:"chain,omitempty"`
+}
+
+// validateRequest is the request a validator plugin's validate function handles
+type validateRequest struct {
+	Config     map[string]any `json:"config,omitempty"`
+	Credential credential     `json:"credential"`
+}
+
+// validateResponse is a validator plugin's response
+// An error rejects the credential.
+type validateResponse struct {
+	Result *trust.Result `json:"result"`
+	Error  string        `json:"error"`
+}
+
+// Validator is a trust.Validator implemented by a plugin's validate function
+type Validator struct {
+	module          *Module
+	credentialTypes []trust.CredentialType
+	trustDomain     string
+}
+
+// NewValidator creates a validator backed by module, which must export validate,
+// for credentials of credentialTypes (default: bearer)
+// trustDomain, if set, is the trust domain of results that do not name one.
+func NewValidator(module *Module, credentialTypes []trust.CredentialType, trustDomain string) (*Validator, error) {
+	if !module.Exports("validate") {
+		return nil, fmt.Errorf("wasm validator: module does not export validate")
+	}
+	if len(credentialTypes) == 0 {
+		credentialTypes = []trust.CredentialType{trust.CredentialTypeBearer}
+	}
+	return &Validator{module: module, credentialTypes: credentialTypes, trustDomain: trustDomain}, nil
+}
+
+// CredentialTypes implements trust.Validator
+func (v *Validator) CredentialTypes() []trust.CredentialType {
+	return v.credentialTypes
+}
+
+// Validate implements trust.Validator
+func (v *Validator) Validate(ctx context.Context, cred trust.Credential) (*trust.Result, error) {
+	c, err := toCredential(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp validateResponse
+	if err := v.module.Call(ctx, "validate", validateRequest{Config: v.module.config, Credential: c}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("credential rejected: %s", resp.Error)
+	}
+	if resp.Result == nil || resp.Result.Subject == "" {
+		return nil, fmt.Errorf("wasm validator returned no subject")
+	}
+	if resp.Result.TrustDomain == "" {
+		resp.Result.TrustDomain = v.trustDomain
+	}
+	return resp.Result, nil
+}
+
+// toCredential converts cred to the form plugins see
+func toCredential(cred trust.Credential) (credential, error) {
+	switch cred := cred.(type) {
+	case *trust.BearerCredential:
+		return credential{Type: cred.Type(), Token: cred.Token}, nil
+	case *trust.JWTCredential:
+		return credential{Type: cred.Type(), Token: cred.Token, Issuer: cred.IssuerIdentity}, nil
+	case *trust.OIDCCredential:
+		return credential{Type: cred.Type(), Token: cred.Token, Issuer: cred.IssuerIdentity}, nil
+	case *trust.JSONCredential:
+		return credential{Type: cred.Type(), JSON: string(cred.RawJSON)}, nil
+	case *trust.APIKeyCredential:
+		return credential{Type: cred.Type(), Key: cred.Key, Header: cred.Header}, nil
+	case *trust.X509Credential:
+		c := credential{Type: cred.Type(), Certificate: base64.StdEncoding.EncodeToString(cred.Certificate.Raw)}
+		for _, cert := range cred.Chain {
+			c.Chain = append(c.Chain, base64.StdEncoding.EncodeToString(cert.Raw))
+		}
+		return c, nil
+	default:
+		return credential{}, fmt.Errorf("wasm validator does not support %s credentials", cred.Type())
+	}
+}
@@ -0,0 +1,308 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/alechenninger/parsec/pkg/verifier"
+)
+
+// Token type identifiers (RFC 8693 section 3 and parsec extensions)
+const (
+	TokenTypeTransactionToken = "urn:ietf:params:oauth:token-type:txn_token"
+	TokenTypeAccessToken      = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT              = "urn:ietf:params:oauth:token-type:jwt"
+	TokenTypeIDToken          = "urn:ietf:params:oauth:token-type:id_token"
+)
+
+// tokenExchangeGrantType is the grant type of token exchange requests (RFC 8693 section 2.1)
+const tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// Client authentication methods (RFC 7591 section 2)
+const (
+	// AuthMethodClientSecretBasic sends the client credentials with HTTP Basic authentication
+	AuthMethodClientSecretBasic = "client_secret_basic"
+
+	// AuthMethodClientSecretPost sends the client credentials in the request body
+	AuthMethodClientSecretPost = "client_secret_post"
+)
+
+// Endpoint paths
+const (
+	exchangePath = "/v1/token"
+	jwksPath     = "/.well-known/jwks.json"
+)
+
+// Config configures a Client
+type Config struct {
+	// BaseURL is the URL of parsec's HTTP port (e.g., "http://parsec:8080")
+	BaseURL string
+
+	// ClientID and ClientSecret authenticate the client at the token endpoint, if
+	// parsec requires client authentication
+	ClientID     string
+	ClientSecret string
+
+	// AuthMethod is how client credentials are sent (default: AuthMethodClientSecretBasic)
+	AuthMethod string
+
+	// HTTPClient is an optional HTTP client
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+}
+
+// Client calls parsec's HTTP endpoints
+type Client struct {
+	baseURL      string
+	clientID     string
+	clientSecret string
+	authMethod   string
+	httpClient   *http.Client
+}
+
+// New creates a new Client
+func New(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required")
+	}
+	if _, err := url.Parse(cfg.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	authMethod := cfg.AuthMethod
+	if authMethod == "" {
+		authMethod = AuthMethodClientSecretBasic
+	}
+	if authMethod != AuthMethodClientSecretBasic && authMethod != AuthMethodClientSecretPost {
+		return nil, fmt.Errorf("unsupported client authentication method %s", authMethod)
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:      strings.TrimSuffix(cfg.BaseURL, "/"),
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		authMethod:   authMethod,
+		httpClient:   httpClient,
+	}, nil
+}
+
+// ExchangeRequest is a token exchange request (RFC 8693 section 2.1)
+type ExchangeRequest struct {
+	// SubjectToken is the token of the party the request is made on behalf of
+	SubjectToken string
+
+	// SubjectTokenType is the type of SubjectToken (e.g., TokenTypeAccessToken)
+	SubjectTokenType string
+
+	// ActorToken and ActorTokenType identify the party acting for the subject, if any
+	ActorToken     string
+	ActorTokenType string
+
+	// RequestedTokenType is the type of token to issue (default: transaction token)
+	RequestedTokenType string
+
+	// Audience and Resource name the services the token is for
+	Audience []string
+	Resource []string
+
+	// Scope is the space-delimited scope requested
+	Scope string
+
+	// RequestContext is context about the request for the issued transaction token,
+	// such as the method and path being called. parsec keeps only the claims the
+	// caller is allowed to provide.
+	RequestContext map[string]any
+}
+
+// ExchangeResponse is a token exchange response (RFC 8693 section 2.2)
+type ExchangeResponse struct {
+	// AccessToken is the issued token, whatever its type
+	AccessToken string `json:"access_token"`
+
+	// IssuedTokenType is the type of AccessToken
+	IssuedTokenType string `json:"issued_token_type"`
+
+	// TokenType is how the token is used (e.g., "Bearer")
+	TokenType string `json:"token_type"`
+
+	// ExpiresIn is the lifetime of the token in seconds
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+
+	// Scope is the scope of the token, if it differs from the one requested
+	Scope string `json:"scope,omitempty"`
+
+	// RefreshToken is a token for exchanging again, if parsec issued one
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// UnmarshalJSON accepts expires_in as a number or, as parsec's gateway encodes
+// 64-bit integers, a string
+func (r *ExchangeResponse) UnmarshalJSON(data []byte) error {
+	type plain ExchangeResponse
+	var decoded struct {
+		plain
+		ExpiresIn json.Number `json:"expires_in,omitempty"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = ExchangeResponse(decoded.plain)
+	if decoded.ExpiresIn != "" {
+		expiresIn, err := decoded.ExpiresIn.Int64()
+		if err != nil {
+			return fmt.Errorf("invalid expires_in: %w", err)
+		}
+		r.ExpiresIn = expiresIn
+	}
+	return nil
+}
+
+// Lifetime returns ExpiresIn as a duration
+func (r *ExchangeResponse) Lifetime() time.Duration {
+	return time.Duration(r.ExpiresIn) * time.Second
+}
+
+// Error is an OAuth error response from parsec (RFC 6749 section 5.2)
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+
+	// Code is the OAuth error code (e.g., "invalid_grant"), or "" if the response
+	// was not an OAuth error
+	Code string `json:"error"`
+
+	// Description is the human-readable error description, if any
+	Description string `json:"error_description"`
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Code != "" && e.Description != "":
+		return fmt.Sprintf("parsec: %s: %s", e.Code, e.Description)
+	case e.Code != "":
+		return fmt.Sprintf("parsec: %s", e.Code)
+	default:
+		return fmt.Sprintf("parsec: HTTP %d", e.StatusCode)
+	}
+}
+
+// Exchange exchanges a token at parsec's token endpoint
+// Errors parsec answers with are returned as *Error.
+func (c *Client) Exchange(ctx context.Context, req ExchangeRequest) (*ExchangeResponse, error) {
+	if req.SubjectToken == "" || req.SubjectTokenType == "" {
+		return nil, fmt.Errorf("subject token and subject token type are required")
+	}
+
+	form := url.Values{
+		"grant_type":         {tokenExchangeGrantType},
+		"subject_token":      {req.SubjectToken},
+		"subject_token_type": {req.SubjectTokenType},
+	}
+	setIfNotEmpty(form, "actor_token", req.ActorToken)
+	setIfNotEmpty(form, "actor_token_type", req.ActorTokenType)
+	setIfNotEmpty(form, "requested_token_type", req.RequestedTokenType)
+	setIfNotEmpty(form, "scope", req.Scope)
+	for _, audience := range req.Audience {
+		form.Add("audience", audience)
+	}
+	for _, resource := range req.Resource {
+		form.Add("resource", resource)
+	}
+	if req.RequestContext != nil {
+		data, err := json.Marshal(req.RequestContext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request context: %w", err)
+		}
+		form.Set("request_context", base64.StdEncoding.EncodeToString(data))
+	}
+	if c.clientID != "" && c.authMethod == AuthMethodClientSecretPost {
+		form.Set("client_id", c.clientID)
+		form.Set("client_secret", c.clientSecret)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+exchangePath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if c.clientID != "" && c.authMethod == AuthMethodClientSecretBasic {
+		// RFC 6749 section 2.3.1: credentials are form-encoded before Basic encoding
+		httpReq.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	}
+
+	resp := &ExchangeResponse{}
+	if err := c.do(httpReq, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// JWKS fetches parsec's current public keys
+// It does not cache; use NewVerifier to verify tokens against a cached key set.
+func (c *Client) JWKS(ctx context.Context) (jwk.Set, error) {
+	keySet, err := jwk.Fetch(ctx, c.baseURL+jwksPath, jwk.WithHTTPClient(c.httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	return keySet, nil
+}
+
+// NewVerifier creates a transaction token verifier that fetches parsec's JWKS through
+// this client. cfg.JWKSURL and cfg.HTTPClient default to the client's.
+func (c *Client) NewVerifier(cfg verifier.Config) (*verifier.Verifier, error) {
+	if cfg.JWKSURL == "" {
+		cfg.JWKSURL = c.baseURL + jwksPath
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = c.httpClient
+	}
+	return verifier.New(cfg)
+}
+
+// do sends req and decodes a successful JSON response into v
+func (c *Client) do(req *http.Request, v any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call parsec: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		// Responses that are not OAuth errors leave the code empty
+		_ = json.Unmarshal(body, apiErr)
+		return apiErr
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// setIfNotEmpty sets a form parameter if value is not empty
+func setIfNotEmpty(form url.Values, key, value string) {
+	if value != "" {
+		form.Set(key, value)
+	}
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/httpfixture"
+	"github.com/alechenninger/parsec/pkg/verifier"
+)
+
+func TestClient_Exchange(t *testing.T) {
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/token" || r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok {
+			clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if clientID != "orders" || clientSecret != "s3cr3t" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client","error_description":"client authentication failed"}`))
+			return
+		}
+
+		if got := r.PostForm.Get("grant_type"); got != tokenExchangeGrantType {
+			t.Errorf("expected token exchange grant, got %s", got)
+		}
+		if got := r.PostForm["audience"]; len(got) != 2 {
+			t.Errorf("expected two audiences, got %v", got)
+		}
+		var requestContext map[string]any
+		decoded, _ := base64.StdEncoding.DecodeString(r.PostForm.Get("request_context"))
+		if err := json.Unmarshal(decoded, &requestContext); err != nil || requestContext["path"] != "/orders" {
+			t.Errorf("expected request context with path, got %s", decoded)
+		}
+
+		// The gateway encodes int64 fields as strings
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"access_token": "txn-token",
+			"issued_token_type": "urn:ietf:params:oauth:token-type:txn_token",
+			"token_type": "Bearer",
+			"expires_in": "300"
+		}`))
+	}))
+	defer server.Close()
+
+	req := ExchangeRequest{
+		SubjectToken:     "access-token",
+		SubjectTokenType: TokenTypeAccessToken,
+		Audience:         []string{"orders", "payments"},
+		RequestContext:   map[string]any{"method": "GET", "path": "/orders"},
+	}
+
+	for _, authMethod := range []string{AuthMethodClientSecretBasic, AuthMethodClientSecretPost} {
+		t.Run(authMethod, func(t *testing.T) {
+			c, err := New(Config{
+				BaseURL:      server.URL + "/",
+				ClientID:     "orders",
+				ClientSecret: "s3cr3t",
+				AuthMethod:   authMethod,
+			})
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			resp, err := c.Exchange(ctx, req)
+			if err != nil {
+				t.Fatalf("Exchange failed: %v", err)
+			}
+			if resp.AccessToken != "txn-token" || resp.IssuedTokenType != TokenTypeTransactionToken {
+				t.Errorf("unexpected response %+v", resp)
+			}
+			if resp.Lifetime() != 5*time.Minute {
+				t.Errorf("expected lifetime 5m, got %v", resp.Lifetime())
+			}
+		})
+	}
+
+	t.Run("OAuth error", func(t *testing.T) {
+		c, err := New(Config{BaseURL: server.URL, ClientID: "orders", ClientSecret: "wrong"})
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+
+		_, err = c.Exchange(ctx, req)
+		var apiErr *Error
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected *Error, got %v", err)
+		}
+		if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != "invalid_client" {
+			t.Errorf("unexpected error %+v", apiErr)
+		}
+	})
+
+	t.Run("missing subject token", func(t *testing.T) {
+		c, err := New(Config{BaseURL: server.URL})
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		if _, err := c.Exchange(ctx, ExchangeRequest{}); err == nil {
+			t.Error("expected error for missing subject token")
+		}
+	})
+}
+
+func TestClient_Verifier(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	fixture, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+		Issuer:    "https://parsec.example.com",
+		JWKSURL:   "https://parsec.example.com/.well-known/jwks.json",
+		KeyID:     "key-1",
+		TokenType: verifier.DefaultTokenType,
+		Clock:     clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS fixture: %v", err)
+	}
+
+	c, err := New(Config{
+		BaseURL: "https://parsec.example.com",
+		HTTPClient: &http.Client{
+			Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+				Provider: fixture,
+				Strict:   true,
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	keySet, err := c.JWKS(ctx)
+	if err != nil {
+		t.Fatalf("JWKS failed: %v", err)
+	}
+	if _, ok := keySet.LookupKeyID("key-1"); !ok {
+		t.Error("expected key-1 in JWKS")
+	}
+
+	v, err := c.NewVerifier(verifier.Config{
+		Issuer:   "https://parsec.example.com",
+		Audience: "prod.example.com",
+		Now:      clk.Now,
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier failed: %v", err)
+	}
+	defer v.Close()
+
+	token, err := fixture.CreateAndSignToken(map[string]any{
+		"sub": "user-123",
+		"aud": "prod.example.com",
+		"txn": "txn-abc",
+	})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	verified, err := v.Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if verified.TransactionID != "txn-abc" {
+		t.Errorf("expected txn-abc, got %s", verified.TransactionID)
+	}
+}
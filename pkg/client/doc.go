@@ -0,0 +1,22 @@
+// Package client is a Go client for parsec's HTTP endpoints.
+//
+// Services use it to exchange tokens for transaction tokens (RFC 8693), to fetch
+// parsec's JWKS, and to verify transaction tokens they receive, instead of writing
+// their own HTTP calls:
+//
+//	c, err := client.New(client.Config{
+//		BaseURL:      "http://parsec.parsec-system.svc:8080",
+//		ClientID:     "orders",
+//		ClientSecret: os.Getenv("PARSEC_CLIENT_SECRET"),
+//	})
+//	resp, err := c.Exchange(ctx, client.ExchangeRequest{
+//		SubjectToken:     accessToken,
+//		SubjectTokenType: client.TokenTypeAccessToken,
+//	})
+//
+// # Verification
+//
+// NewVerifier returns a verifier.Verifier that fetches the JWKS through the client,
+// caching and refreshing it as described in package verifier. Verify with a long-lived
+// verifier rather than fetching the JWKS for each token.
+package client
@@ -0,0 +1,144 @@
+package verifier
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultHeader is the header parsec's ext_authz server delivers transaction tokens in
+const DefaultHeader = "Transaction-Token"
+
+// FilterConfig is the plugin configuration contract for a reference Envoy WASM filter
+// that verifies parsec transaction tokens in the data plane.
+//
+// It is serialized as JSON in the filter's plugin configuration:
+//
+//	{
+//	  "header": "Transaction-Token",
+//	  "issuer": "https://parsec.example.com",
+//	  "audience": "prod.example.com",
+//	  "token_type": "txntoken+jwt",
+//	  "jwks_cluster": "parsec",
+//	  "jwks_path": "/.well-known/jwks.json",
+//	  "clock_skew": "30s",
+//	  "refresh_interval": "5m",
+//	  "min_refresh_interval": "30s",
+//	  "pinned_keys": ["NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"]
+//	}
+//
+// A conforming filter:
+//   - reads the token from Header; a missing header is rejected with 401
+//   - verifies signature, typ, iss, aud, exp, nbf, and iat exactly as Verifier does
+//   - if PinnedKeys is set, rejects tokens signed with any key whose RFC 7638
+//     thumbprint is not pinned, even if the JWKS serves it
+//   - fetches the JWKS from JWKSPath on JWKSCluster (WASM filters cannot dial
+//     arbitrary URLs), caching it per the strategy described in the package docs
+//   - rejects invalid tokens with 401 and never forwards them upstream
+//   - forwards the verified subject in SubjectHeader, if set, after removing any
+//     client-supplied value of that header
+type FilterConfig struct {
+	// Header is the request header carrying the transaction token (default: DefaultHeader)
+	Header string `json:"header,omitempty"`
+
+	// Issuer is the expected iss claim
+	Issuer string `json:"issuer"`
+
+	// Audience is the expected aud claim (parsec's trust domain)
+	Audience string `json:"audience"`
+
+	// TokenType is the expected typ header (default: DefaultTokenType)
+	TokenType string `json:"token_type,omitempty"`
+
+	// JWKSCluster is the Envoy cluster that routes to parsec
+	JWKSCluster string `json:"jwks_cluster"`
+
+	// JWKSPath is the path of the JWKS endpoint on JWKSCluster (default: /.well-known/jwks.json)
+	JWKSPath string `json:"jwks_path,omitempty"`
+
+	// ClockSkew is a duration string like "30s" (default: DefaultClockSkew)
+	ClockSkew string `json:"clock_skew,omitempty"`
+
+	// RefreshInterval is a duration string like "5m" (default: DefaultRefreshInterval)
+	RefreshInterval string `json:"refresh_interval,omitempty"`
+
+	// MinRefreshInterval is a duration string like "30s" (default: DefaultMinRefreshInterval)
+	MinRefreshInterval string `json:"min_refresh_interval,omitempty"`
+
+	// SubjectHeader, if set, is the header to forward the verified subject in
+	SubjectHeader string `json:"subject_header,omitempty"`
+
+	// PinnedKeys, if set, are the thumbprints of the only keys tokens may be signed with
+	// (see Config.PinnedKeys)
+	PinnedKeys []string `json:"pinned_keys,omitempty"`
+}
+
+// Validate checks that required fields are set and durations parse
+func (c *FilterConfig) Validate() error {
+	if c.Issuer == "" {
+		return fmt.Errorf("issuer is required")
+	}
+	if c.Audience == "" {
+		return fmt.Errorf("audience is required")
+	}
+	if c.JWKSCluster == "" {
+		return fmt.Errorf("jwks_cluster is required")
+	}
+	for name, value := range map[string]string{
+		"clock_skew":           c.ClockSkew,
+		"refresh_interval":     c.RefreshInterval,
+		"min_refresh_interval": c.MinRefreshInterval,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// VerifierConfig converts the filter contract to an equivalent Verifier configuration.
+// baseURL is the URL JWKSCluster resolves to from the caller's point of view
+// (e.g. "http://parsec.parsec-system.svc:8080").
+func (c *FilterConfig) VerifierConfig(baseURL string, httpClient *http.Client) (Config, error) {
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	jwksPath := c.JWKSPath
+	if jwksPath == "" {
+		jwksPath = "/.well-known/jwks.json"
+	}
+
+	cfg := Config{
+		Issuer:     c.Issuer,
+		Audience:   c.Audience,
+		TokenType:  c.TokenType,
+		JWKSURL:    baseURL + jwksPath,
+		PinnedKeys: c.PinnedKeys,
+		HTTPClient: httpClient,
+	}
+
+	// Durations were validated above
+	if c.ClockSkew != "" {
+		cfg.ClockSkew, _ = time.ParseDuration(c.ClockSkew)
+	}
+	if c.RefreshInterval != "" {
+		cfg.RefreshInterval, _ = time.ParseDuration(c.RefreshInterval)
+	}
+	if c.MinRefreshInterval != "" {
+		cfg.MinRefreshInterval, _ = time.ParseDuration(c.MinRefreshInterval)
+	}
+
+	return cfg, nil
+}
+
+// HeaderName returns the configured token header or DefaultHeader
+func (c *FilterConfig) HeaderName() string {
+	if c.Header == "" {
+		return DefaultHeader
+	}
+	return c.Header
+}
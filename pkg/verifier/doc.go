@@ -0,0 +1,57 @@
+// Package verifier verifies parsec transaction tokens locally, without a round trip to parsec.
+//
+// parsec issues transaction tokens at the perimeter (ext_authz or token exchange) and
+// publishes the public keys used to sign them at its JWKS endpoint. Downstream services
+// and sidecars can use this package to close the loop: fetch the JWKS once, cache it,
+// and verify every inbound transaction token in-process.
+//
+// # Caching strategy
+//
+// The JWKS is cached and refreshed in the background every RefreshInterval.
+// parsec's dual-slot rotation publishes a new key for a grace period before it signs
+// anything with it, so a refresh interval well below that grace period (the default
+// is 5 minutes against parsec's default 2 hour grace period) means a verifier almost
+// always knows a key before it sees it in a token.
+//
+// If a token still arrives with an unknown key ID, the verifier forces a refresh,
+// but at most once per MinRefreshInterval. This bounds the load a flood of tokens
+// with bogus key IDs can put on the JWKS endpoint.
+//
+// # Token type
+//
+// parsec signs transaction tokens with the typ header "txntoken+jwt", and the
+// verifier rejects tokens without it, so other JWTs signed with the same keys, such
+// as access tokens, cannot pass as transaction tokens. Config.TokenType accepts
+// another type.
+//
+// # Clock skew
+//
+// Verification tolerates ClockSkew (default 30 seconds) on exp, nbf, and iat.
+// parsec sets iat to the issuance time, and nbf to the same time unless its issuer
+// backdates nbf by not_before_skew. Without a skew of its own, any clock drift between
+// parsec and the verifier would reject freshly issued tokens. Keep the skew small:
+// transaction tokens are short lived (parsec defaults to 5 minutes), and the skew
+// directly extends how long a leaked token stays usable.
+//
+// # Revocation
+//
+// Transaction tokens revoked at parsec's revocation endpoint stay valid to a verifier
+// until they expire, unless it is configured with a Denylist. parsec's Redis denylist
+// can be shared with verifiers, so they reject revoked tokens as soon as parsec does.
+//
+// # Key pinning
+//
+// By default any key the JWKS endpoint serves is trusted. Config.PinnedKeys restricts
+// verification to keys with the given RFC 7638 thumbprints, so a compromised or spoofed
+// JWKS endpoint cannot introduce a key of its own. parsec's /v1/verify endpoint reports
+// the thumbprint of the key a token was signed with, and KeyThumbprint computes it from
+// a JWK. Pins must be updated before parsec signs with a new key, so pinning suits
+// long-lived keys (such as KMS keys) better than automatically rotated ones.
+//
+// # Envoy WASM filter contract
+//
+// FilterConfig is the plugin configuration a reference Envoy WASM filter accepts.
+// It is the same shape as Config so a Go sidecar and a WASM filter deployed side by
+// side verify tokens identically. See FilterConfig for the expected behavior of a
+// conforming filter.
+package verifier
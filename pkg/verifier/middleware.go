@@ -0,0 +1,47 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey struct{}
+
+// FromContext returns the verified transaction token stored by Middleware, if any
+func FromContext(ctx context.Context) (*TransactionToken, bool) {
+	token, ok := ctx.Value(contextKey{}).(*TransactionToken)
+	return token, ok
+}
+
+// NewContext returns a copy of ctx carrying the verified transaction token
+func NewContext(ctx context.Context, token *TransactionToken) context.Context {
+	return context.WithValue(ctx, contextKey{}, token)
+}
+
+// Middleware verifies the transaction token in header (DefaultHeader if empty)
+// and rejects the request with 401 if it is missing or invalid.
+// On success the verified token is available to next via FromContext.
+//
+// This is the Go equivalent of the WASM filter described by FilterConfig,
+// for services that verify in-process rather than in a sidecar.
+func (v *Verifier) Middleware(header string, next http.Handler) http.Handler {
+	if header == "" {
+		header = DefaultHeader
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw := r.Header.Get(header)
+		if raw == "" {
+			http.Error(w, "missing transaction token", http.StatusUnauthorized)
+			return
+		}
+
+		token, err := v.Verify(r.Context(), raw)
+		if err != nil {
+			http.Error(w, "invalid transaction token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), token)))
+	})
+}
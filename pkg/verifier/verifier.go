@@ -0,0 +1,388 @@
+package verifier
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	// DefaultClockSkew is the default tolerance for exp, nbf, and iat checks
+	DefaultClockSkew = 30 * time.Second
+
+	// DefaultRefreshInterval is how often the JWKS is refreshed in the background
+	DefaultRefreshInterval = 5 * time.Minute
+
+	// DefaultMinRefreshInterval is the minimum time between forced refreshes
+	// triggered by tokens signed with an unknown key ID
+	DefaultMinRefreshInterval = 30 * time.Second
+
+	// DefaultTokenType is the typ header of transaction tokens
+	DefaultTokenType = "txntoken+jwt"
+)
+
+// Verification errors
+var (
+	ErrInvalidToken = errors.New("invalid transaction token")
+	ErrExpiredToken = errors.New("transaction token expired")
+	ErrUnknownKey   = errors.New("transaction token signed with unknown key")
+	ErrRevokedToken = errors.New("transaction token revoked")
+	ErrUnpinnedKey  = errors.New("transaction token signed with key that is not pinned")
+)
+
+// Denylist reports whether a token has been revoked, by its jti claim.
+// parsec's denylists implement it. Its Redis denylist keeps each revoked jti as a
+// key named "parsec:denylist:<jti>" (by default) until the token expires, so a
+// verifier sharing the Redis server can check for the key.
+type Denylist interface {
+	IsDenied(ctx context.Context, tokenID string) (bool, error)
+}
+
+// Config configures a Verifier
+type Config struct {
+	// Issuer is the expected issuer of transaction tokens (iss claim).
+	// This is the issuer_url of the parsec transaction_token issuer.
+	Issuer string
+
+	// Audience is the expected audience (aud claim).
+	// parsec always uses its trust domain as the audience of transaction tokens.
+	Audience string
+
+	// JWKSURL is the URL of parsec's JWKS endpoint.
+	// If empty, defaults to Issuer + "/.well-known/jwks.json"
+	JWKSURL string
+
+	// TokenType is the typ header tokens must have (default: DefaultTokenType).
+	// It keeps other JWTs signed with parsec's keys from passing as transaction tokens.
+	TokenType string
+
+	// ClockSkew is the tolerance for time-based claims (default: DefaultClockSkew)
+	ClockSkew time.Duration
+
+	// RefreshInterval is how often the JWKS is refreshed (default: DefaultRefreshInterval)
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval bounds how often an unknown key ID can force a JWKS refresh
+	// (default: DefaultMinRefreshInterval)
+	MinRefreshInterval time.Duration
+
+	// Denylist, if set, is consulted for every token so tokens revoked at parsec's
+	// revocation endpoint are rejected before they expire
+	Denylist Denylist
+
+	// PinnedKeys, if set, are the RFC 7638 SHA-256 thumbprints (base64url) of the only
+	// keys tokens may be signed with, whatever else the JWKS serves. This protects against
+	// a compromised or spoofed JWKS endpoint, at the cost of updating the pins before
+	// parsec signs with a new key. See KeyThumbprint.
+	PinnedKeys []string
+
+	// HTTPClient is an optional HTTP client for JWKS fetching
+	// If nil, http.DefaultClient will be used
+	HTTPClient *http.Client
+
+	// Now returns the current time, for token validation
+	// If nil, uses time.Now
+	Now func() time.Time
+}
+
+// TransactionToken contains the verified contents of a transaction token
+type TransactionToken struct {
+	// Subject is the sub claim
+	Subject string
+
+	// Issuer is the iss claim
+	Issuer string
+
+	// Audience is the aud claim
+	Audience []string
+
+	// TransactionID is the txn claim, stable across the whole call chain
+	TransactionID string
+
+	// TokenID is the jti claim, unique to this token
+	TokenID string
+
+	// Scope is the scope claim, if present
+	Scope string
+
+	// TransactionContext is the tctx claim
+	TransactionContext map[string]any
+
+	// RequestContext is the req_ctx claim
+	RequestContext map[string]any
+
+	// IssuedAt is the iat claim
+	IssuedAt time.Time
+
+	// ExpiresAt is the exp claim
+	ExpiresAt time.Time
+}
+
+// Verifier verifies transaction tokens against a cached JWKS
+type Verifier struct {
+	issuer             string
+	audience           string
+	jwksURL            string
+	tokenType          string
+	clockSkew          time.Duration
+	minRefreshInterval time.Duration
+	denylist           Denylist
+	pinnedKeys         map[string]bool
+	now                func() time.Time
+
+	cache  *jwk.Cache
+	cancel context.CancelFunc
+
+	mu            sync.Mutex
+	lastRefreshed time.Time
+}
+
+// New creates a new Verifier.
+// The JWKS is fetched lazily on first use, so a verifier can be created
+// before parsec is reachable.
+func New(cfg Config) (*Verifier, error) {
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("issuer is required")
+	}
+	if cfg.Audience == "" {
+		return nil, fmt.Errorf("audience is required")
+	}
+
+	jwksURL := cfg.JWKSURL
+	if jwksURL == "" {
+		jwksURL = cfg.Issuer + "/.well-known/jwks.json"
+	}
+
+	tokenType := cfg.TokenType
+	if tokenType == "" {
+		tokenType = DefaultTokenType
+	}
+
+	clockSkew := cfg.ClockSkew
+	if clockSkew == 0 {
+		clockSkew = DefaultClockSkew
+	}
+
+	refreshInterval := cfg.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+
+	minRefreshInterval := cfg.MinRefreshInterval
+	if minRefreshInterval == 0 {
+		minRefreshInterval = DefaultMinRefreshInterval
+	}
+
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	var pinnedKeys map[string]bool
+	if len(cfg.PinnedKeys) > 0 {
+		pinnedKeys = make(map[string]bool, len(cfg.PinnedKeys))
+		for _, thumbprint := range cfg.PinnedKeys {
+			pinnedKeys[thumbprint] = true
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// The cache checks for due refreshes every refresh window; keep it well
+	// below the refresh interval so refreshes happen close to on schedule
+	cache := jwk.NewCache(ctx, jwk.WithRefreshWindow(refreshInterval/2))
+
+	registerOpts := []jwk.RegisterOption{jwk.WithRefreshInterval(refreshInterval)}
+	if cfg.HTTPClient != nil {
+		registerOpts = append(registerOpts, jwk.WithHTTPClient(cfg.HTTPClient))
+	}
+	if err := cache.Register(jwksURL, registerOpts...); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to register JWKS URL: %w", err)
+	}
+
+	return &Verifier{
+		issuer:             cfg.Issuer,
+		audience:           cfg.Audience,
+		jwksURL:            jwksURL,
+		tokenType:          tokenType,
+		clockSkew:          clockSkew,
+		minRefreshInterval: minRefreshInterval,
+		denylist:           cfg.Denylist,
+		pinnedKeys:         pinnedKeys,
+		now:                now,
+		cache:              cache,
+		cancel:             cancel,
+	}, nil
+}
+
+// Verify verifies a serialized transaction token and returns its contents
+func (v *Verifier) Verify(ctx context.Context, token string) (*TransactionToken, error) {
+	keySet, err := v.keySetFor(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := jwt.Parse(
+		[]byte(token),
+		jwt.WithKeySet(keySet),
+		jwt.WithValidate(true),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithRequiredClaim(jwt.SubjectKey),
+		jwt.WithRequiredClaim("txn"),
+		jwt.WithAcceptableSkew(v.clockSkew),
+		jwt.WithClock(jwt.ClockFunc(v.now)),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired()) {
+			return nil, ErrExpiredToken
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if v.denylist != nil && parsed.JwtID() != "" {
+		denied, err := v.denylist.IsDenied(ctx, parsed.JwtID())
+		if err != nil {
+			return nil, fmt.Errorf("failed to check denylist: %w", err)
+		}
+		if denied {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	result := &TransactionToken{
+		Subject:   parsed.Subject(),
+		Issuer:    parsed.Issuer(),
+		Audience:  parsed.Audience(),
+		TokenID:   parsed.JwtID(),
+		IssuedAt:  parsed.IssuedAt(),
+		ExpiresAt: parsed.Expiration(),
+	}
+
+	if txn, ok := parsed.Get("txn"); ok {
+		result.TransactionID, _ = txn.(string)
+	}
+	if scope, ok := parsed.Get("scope"); ok {
+		result.Scope, _ = scope.(string)
+	}
+	if tctx, ok := parsed.Get("tctx"); ok {
+		result.TransactionContext, _ = tctx.(map[string]any)
+	}
+	if reqCtx, ok := parsed.Get("req_ctx"); ok {
+		result.RequestContext, _ = reqCtx.(map[string]any)
+	}
+
+	return result, nil
+}
+
+// Close stops background JWKS refreshes
+func (v *Verifier) Close() error {
+	v.cancel()
+	return nil
+}
+
+// keySetFor returns a key set that contains the key the token claims to be signed with,
+// forcing a rate-limited refresh if the key is not yet known
+// Tokens without the expected typ header are rejected before any key is looked up.
+func (v *Verifier) keySetFor(ctx context.Context, token string) (jwk.Set, error) {
+	msg, err := jws.Parse([]byte(token))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one signature", ErrInvalidToken)
+	}
+	headers := msg.Signatures()[0].ProtectedHeaders()
+	if !isType(headers.Type(), v.tokenType) {
+		return nil, fmt.Errorf("%w: typ header %q is not %q", ErrInvalidToken, headers.Type(), v.tokenType)
+	}
+	kid := headers.KeyID()
+	if kid == "" {
+		return nil, fmt.Errorf("%w: missing kid header", ErrInvalidToken)
+	}
+
+	keySet, err := v.cache.Get(ctx, v.jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	if key, ok := keySet.LookupKeyID(kid); ok {
+		return v.pinnedKeySet(key)
+	}
+
+	if !v.tryBeginRefresh() {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+
+	keySet, err = v.cache.Refresh(ctx, v.jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh JWKS: %w", err)
+	}
+	key, ok := keySet.LookupKeyID(kid)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+
+	return v.pinnedKeySet(key)
+}
+
+// pinnedKeySet returns a key set of only key, if it is pinned or no keys are pinned
+func (v *Verifier) pinnedKeySet(key jwk.Key) (jwk.Set, error) {
+	if v.pinnedKeys != nil {
+		thumbprint, err := KeyThumbprint(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+		}
+		if !v.pinnedKeys[thumbprint] {
+			return nil, fmt.Errorf("%w: %s", ErrUnpinnedKey, key.KeyID())
+		}
+	}
+
+	keySet := jwk.NewSet()
+	if err := keySet.AddKey(key); err != nil {
+		return nil, fmt.Errorf("failed to build key set: %w", err)
+	}
+	return keySet, nil
+}
+
+// isType reports whether the typ header typ names the media type want, which may omit
+// its "application/" prefix (RFC 7515, section 4.1.9)
+func isType(typ, want string) bool {
+	if len(typ) > len("application/") && strings.EqualFold(typ[:len("application/")], "application/") {
+		typ = typ[len("application/"):]
+	}
+	return strings.EqualFold(typ, want)
+}
+
+// KeyThumbprint returns the RFC 7638 SHA-256 thumbprint of a public key, base64url
+// encoded, as used in Config.PinnedKeys
+func KeyThumbprint(key jwk.Key) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute key thumbprint: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// tryBeginRefresh reports whether a forced refresh is allowed now,
+// recording the attempt if so
+func (v *Verifier) tryBeginRefresh() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := v.now()
+	if !v.lastRefreshed.IsZero() && now.Sub(v.lastRefreshed) < v.minRefreshInterval {
+		return false
+	}
+	v.lastRefreshed = now
+	return true
+}
@@ -0,0 +1,465 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/httpfixture"
+)
+
+const (
+	testIssuer   = "https://parsec.example.com"
+	testAudience = "prod.example.com"
+	testJWKSURL  = "https://parsec.example.com/.well-known/jwks.json"
+)
+
+func newTestFixture(t *testing.T, keyID string, clk clock.Clock) *httpfixture.JWKSFixture {
+	t.Helper()
+
+	fixture, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+		Issuer:    testIssuer,
+		JWKSURL:   testJWKSURL,
+		KeyID:     keyID,
+		TokenType: DefaultTokenType,
+		Clock:     clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWKS fixture: %v", err)
+	}
+	return fixture
+}
+
+func newTestVerifier(t *testing.T, provider httpfixture.FixtureProvider, clk clock.Clock) *Verifier {
+	t.Helper()
+
+	v, err := New(Config{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		HTTPClient: &http.Client{
+			Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+				Provider: provider,
+				Strict:   true,
+			}),
+		},
+		Now: clk.Now,
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	t.Cleanup(func() { v.Close() })
+	return v
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":   "user-123",
+		"aud":   testAudience,
+		"txn":   "txn-abc",
+		"scope": "read",
+		"tctx":  map[string]interface{}{"tenant": "acme"},
+		"req_ctx": map[string]interface{}{
+			"method": "GET",
+			"path":   "/api/widgets",
+		},
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	fixture := newTestFixture(t, "key-1", clk)
+	v := newTestVerifier(t, fixture, clk)
+
+	t.Run("valid token", func(t *testing.T) {
+		token, err := fixture.CreateAndSignToken(validClaims())
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		result, err := v.Verify(ctx, token)
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+
+		if result.Subject != "user-123" {
+			t.Errorf("expected subject user-123, got %s", result.Subject)
+		}
+		if result.TransactionID != "txn-abc" {
+			t.Errorf("expected txn txn-abc, got %s", result.TransactionID)
+		}
+		if result.Scope != "read" {
+			t.Errorf("expected scope read, got %s", result.Scope)
+		}
+		if result.TransactionContext["tenant"] != "acme" {
+			t.Errorf("expected tctx.tenant acme, got %v", result.TransactionContext["tenant"])
+		}
+		if result.RequestContext["path"] != "/api/widgets" {
+			t.Errorf("expected req_ctx.path /api/widgets, got %v", result.RequestContext["path"])
+		}
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		claims := validClaims()
+		claims["aud"] = "other.example.com"
+		token, err := fixture.CreateAndSignToken(claims)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		_, err = v.Verify(ctx, token)
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("missing txn", func(t *testing.T) {
+		claims := validClaims()
+		delete(claims, "txn")
+		token, err := fixture.CreateAndSignToken(claims)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		_, err = v.Verify(ctx, token)
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		_, err := v.Verify(ctx, "not-a-jwt")
+		if !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+}
+
+func TestVerifier_TokenType(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	signWithType := func(t *testing.T, tokenType string) (*httpfixture.JWKSFixture, string) {
+		t.Helper()
+		fixture, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+			Issuer:    testIssuer,
+			JWKSURL:   testJWKSURL,
+			KeyID:     "key-1",
+			TokenType: tokenType,
+			Clock:     clk,
+		})
+		if err != nil {
+			t.Fatalf("failed to create JWKS fixture: %v", err)
+		}
+		token, err := fixture.CreateAndSignToken(validClaims())
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return fixture, token
+	}
+
+	t.Run("rejects other JWTs", func(t *testing.T) {
+		fixture, token := signWithType(t, "JWT")
+		v := newTestVerifier(t, fixture, clk)
+
+		if _, err := v.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken, got %v", err)
+		}
+	})
+
+	t.Run("accepts the media type with its application prefix", func(t *testing.T) {
+		fixture, token := signWithType(t, "application/TxnToken+JWT")
+		v := newTestVerifier(t, fixture, clk)
+
+		if _, err := v.Verify(ctx, token); err != nil {
+			t.Errorf("Verify failed: %v", err)
+		}
+	})
+
+	t.Run("accepts the configured type", func(t *testing.T) {
+		fixture, token := signWithType(t, "at+jwt")
+		v, err := New(Config{
+			Issuer:    testIssuer,
+			Audience:  testAudience,
+			TokenType: "at+jwt",
+			HTTPClient: &http.Client{
+				Transport: httpfixture.NewTransport(httpfixture.TransportConfig{Provider: fixture, Strict: true}),
+			},
+			Now: clk.Now,
+		})
+		if err != nil {
+			t.Fatalf("failed to create verifier: %v", err)
+		}
+		defer v.Close()
+
+		if _, err := v.Verify(ctx, token); err != nil {
+			t.Errorf("Verify failed: %v", err)
+		}
+	})
+}
+
+func TestVerifier_ClockSkew(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	fixture := newTestFixture(t, "key-1", clk)
+	v := newTestVerifier(t, fixture, clk)
+
+	token, err := fixture.CreateAndSignTokenWithExpiry(validClaims(), clk.Now().Add(5*time.Minute))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	// Within skew after expiry
+	clk.Advance(5*time.Minute + DefaultClockSkew - time.Second)
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Errorf("expected token within clock skew to verify, got %v", err)
+	}
+
+	// Beyond skew
+	clk.Advance(2 * time.Second)
+	if _, err := v.Verify(ctx, token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("expected ErrExpiredToken, got %v", err)
+	}
+}
+
+func TestVerifier_UnknownKeyRefreshIsRateLimited(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	oldKey := newTestFixture(t, "key-1", clk)
+	newKey := newTestFixture(t, "key-2", clk)
+
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	provider := httpfixture.NewFuncProvider(func(req *http.Request) *httpfixture.Fixture {
+		fetches.Add(1)
+		if rotated.Load() {
+			return newKey.GetFixture(req)
+		}
+		return oldKey.GetFixture(req)
+	})
+	v := newTestVerifier(t, provider, clk)
+
+	token, err := newKey.CreateAndSignToken(validClaims())
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	// First unknown kid forces a refresh, but the JWKS still doesn't have the key
+	if _, err := v.Verify(ctx, token); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	fetchesAfterFirst := fetches.Load()
+
+	// Key is now published, but another forced refresh is not yet allowed
+	rotated.Store(true)
+	if _, err := v.Verify(ctx, token); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey while rate limited, got %v", err)
+	}
+	if fetches.Load() != fetchesAfterFirst {
+		t.Errorf("expected no JWKS fetch while rate limited, got %d more", fetches.Load()-fetchesAfterFirst)
+	}
+
+	// After the minimum refresh interval the new key is picked up
+	clk.Advance(DefaultMinRefreshInterval)
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Errorf("expected token to verify after refresh, got %v", err)
+	}
+}
+
+func TestVerifier_Denylist(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	fixture := newTestFixture(t, "key-1", clk)
+	denied := denylist.NewMemoryDenylist(clk)
+
+	v, err := New(Config{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		Denylist: denied,
+		HTTPClient: &http.Client{
+			Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+				Provider: fixture,
+				Strict:   true,
+			}),
+		},
+		Now: clk.Now,
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	t.Cleanup(func() { v.Close() })
+
+	claims := validClaims()
+	claims["jti"] = "token-1"
+	token, err := fixture.CreateAndSignToken(claims)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	result, err := v.Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.TokenID != "token-1" {
+		t.Errorf("expected jti token-1, got %s", result.TokenID)
+	}
+
+	if err := denied.Deny(ctx, "token-1", clk.Now().Add(5*time.Minute)); err != nil {
+		t.Fatalf("failed to deny token: %v", err)
+	}
+	if _, err := v.Verify(ctx, token); !errors.Is(err, ErrRevokedToken) {
+		t.Errorf("expected ErrRevokedToken, got %v", err)
+	}
+}
+
+func TestVerifier_PinnedKeys(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	fixture := newTestFixture(t, "key-1", clk)
+	httpClient := &http.Client{
+		Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+			Provider: fixture,
+			Strict:   true,
+		}),
+	}
+
+	keySet, err := jwk.Fetch(ctx, testJWKSURL, jwk.WithHTTPClient(httpClient))
+	if err != nil {
+		t.Fatalf("failed to fetch JWKS: %v", err)
+	}
+	key, ok := keySet.LookupKeyID("key-1")
+	if !ok {
+		t.Fatal("expected key-1 in JWKS")
+	}
+	thumbprint, err := KeyThumbprint(key)
+	if err != nil {
+		t.Fatalf("KeyThumbprint failed: %v", err)
+	}
+
+	token, err := fixture.CreateAndSignToken(validClaims())
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		pinnedKeys []string
+		wantErr    error
+	}{
+		{name: "pinned key", pinnedKeys: []string{"some-other-key", thumbprint}},
+		{name: "unpinned key", pinnedKeys: []string{"some-other-key"}, wantErr: ErrUnpinnedKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := New(Config{
+				Issuer:     testIssuer,
+				Audience:   testAudience,
+				PinnedKeys: tt.pinnedKeys,
+				HTTPClient: httpClient,
+				Now:        clk.Now,
+			})
+			if err != nil {
+				t.Fatalf("failed to create verifier: %v", err)
+			}
+			t.Cleanup(func() { v.Close() })
+
+			_, err = v.Verify(ctx, token)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("expected token to verify, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_Middleware(t *testing.T) {
+	clk := clock.NewFixtureClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	fixture := newTestFixture(t, "key-1", clk)
+	v := newTestVerifier(t, fixture, clk)
+
+	handler := v.Middleware("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("expected verified token in context")
+			return
+		}
+		w.Write([]byte(token.Subject))
+	}))
+
+	token, err := fixture.CreateAndSignToken(validClaims())
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{name: "valid token", header: token, wantStatus: http.StatusOK},
+		{name: "missing token", header: "", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", header: "garbage", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(DefaultHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != "user-123" {
+				t.Errorf("expected body user-123, got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestFilterConfig_VerifierConfig(t *testing.T) {
+	fc := FilterConfig{
+		Issuer:      testIssuer,
+		Audience:    testAudience,
+		JWKSCluster: "parsec",
+		TokenType:   "at+jwt",
+		ClockSkew:   "10s",
+	}
+
+	cfg, err := fc.VerifierConfig("http://parsec:8080", nil)
+	if err != nil {
+		t.Fatalf("VerifierConfig failed: %v", err)
+	}
+	if cfg.JWKSURL != "http://parsec:8080/.well-known/jwks.json" {
+		t.Errorf("unexpected JWKS URL: %s", cfg.JWKSURL)
+	}
+	if cfg.ClockSkew != 10*time.Second {
+		t.Errorf("expected clock skew 10s, got %v", cfg.ClockSkew)
+	}
+	if cfg.TokenType != "at+jwt" {
+		t.Errorf("expected token type at+jwt, got %s", cfg.TokenType)
+	}
+	if fc.HeaderName() != DefaultHeader {
+		t.Errorf("expected default header, got %s", fc.HeaderName())
+	}
+
+	fc.ClockSkew = "soon"
+	if _, err := fc.VerifierConfig("http://parsec:8080", nil); err == nil {
+		t.Error("expected error for invalid clock_skew")
+	}
+}
@@ -0,0 +1,324 @@
+package e2e_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	parsecv1 "github.com/alechenninger/parsec/api/gen/parsec/v1"
+	"github.com/alechenninger/parsec/internal/clock"
+	"github.com/alechenninger/parsec/internal/httpfixture"
+	"github.com/alechenninger/parsec/internal/idgen"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/mapper"
+	"github.com/alechenninger/parsec/internal/server"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden token files in testdata/golden")
+
+// TestGoldenTokenExchange replays token exchange requests against a fully deterministic
+// exchange server and compares the issued tokens byte-for-byte with golden files.
+//
+// Everything that would otherwise vary between runs is injected:
+//   - the clock (iat, nbf, exp)
+//   - the ID generator (txn, jti)
+//   - the signing key (kid and signature; RS256 signatures are deterministic)
+//
+// Any change to issued tokens - claims, claim values, header parameters, or
+// serialization - fails this test. If the change is intended, regenerate the
+// golden files and review the diff:
+//
+//	go test ./test/e2e -run TestGoldenTokenExchange -update
+func TestGoldenTokenExchange(t *testing.T) {
+	h := newGoldenHarness(t)
+
+	tests := []struct {
+		name           string
+		subjectClaims  map[string]interface{}
+		scope          string
+		requestContext map[string]interface{}
+	}{
+		{
+			name: "minimal",
+			subjectClaims: map[string]interface{}{
+				"sub": "alice",
+			},
+		},
+		{
+			name: "subject_claims_and_scope",
+			subjectClaims: map[string]interface{}{
+				"sub":    "alice",
+				"email":  "alice@customer.example.com",
+				"groups": []string{"developers", "admins"},
+			},
+			scope: "read write",
+		},
+		{
+			name: "request_context",
+			subjectClaims: map[string]interface{}{
+				"sub": "bob",
+			},
+			requestContext: map[string]interface{}{
+				"method": "POST",
+				"path":   "/api/v1/resources",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := h.exchange(t, tt.subjectClaims, tt.scope, tt.requestContext)
+			h.compare(t, tt.name, token)
+		})
+	}
+
+	t.Run("replay is byte-stable", func(t *testing.T) {
+		claims := map[string]interface{}{"sub": "alice"}
+		first := h.exchange(t, claims, "", nil)
+		second := h.exchange(t, claims, "", nil)
+		if first != second {
+			t.Errorf("expected identical tokens on replay\nfirst:  %s\nsecond: %s", first, second)
+		}
+	})
+}
+
+// goldenHarness is an exchange server whose output depends only on its inputs
+type goldenHarness struct {
+	clock       *clock.FixtureClock
+	ids         *idgen.FixtureGenerator
+	startTime   time.Time
+	actorJWKS   *httpfixture.JWKSFixture
+	subjectJWKS *httpfixture.JWKSFixture
+	server      *server.ExchangeServer
+}
+
+func newGoldenHarness(t *testing.T) *goldenHarness {
+	t.Helper()
+
+	startTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	clk := clock.NewFixtureClock(startTime)
+	ids := idgen.NewFixtureGenerator("golden")
+
+	actorJWKS, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+		Issuer:  "https://auth.internal.example.com",
+		JWKSURL: "https://auth.internal.example.com/.well-known/jwks.json",
+		Clock:   clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create actor JWKS fixture: %v", err)
+	}
+
+	subjectJWKS, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+		Issuer:  "https://idp.customer.example.com",
+		JWKSURL: "https://idp.customer.example.com/.well-known/jwks.json",
+		Clock:   clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create subject JWKS fixture: %v", err)
+	}
+
+	httpClient := &http.Client{
+		Transport: httpfixture.NewTransport(httpfixture.TransportConfig{
+			Provider: httpfixture.NewFuncProvider(func(req *http.Request) *httpfixture.Fixture {
+				if f := actorJWKS.GetFixture(req); f != nil {
+					return f
+				}
+				return subjectJWKS.GetFixture(req)
+			}),
+			Strict: true,
+			Clock:  clk,
+		}),
+	}
+
+	trustStore := trust.NewStubStore()
+	for _, cfg := range []struct {
+		fixture     *httpfixture.JWKSFixture
+		trustDomain string
+	}{
+		{actorJWKS, "internal.example.com"},
+		{subjectJWKS, "customer.example.com"},
+	} {
+		validator, err := trust.NewJWTValidator(trust.JWTValidatorConfig{
+			Issuer:      cfg.fixture.Issuer(),
+			JWKSURL:     cfg.fixture.JWKSURL(),
+			TrustDomain: cfg.trustDomain,
+			HTTPClient:  httpClient,
+			Clock:       clk,
+		})
+		if err != nil {
+			t.Fatalf("failed to create validator: %v", err)
+		}
+		trustStore.AddValidator(validator)
+	}
+
+	signer, err := keys.NewStaticSigner(loadGoldenSigningKey(t), "RS256")
+	if err != nil {
+		t.Fatalf("failed to create static signer: %v", err)
+	}
+
+	tctxMapper, err := mapper.NewCELMapper(`{
+		"sub": subject.subject,
+		"trust_domain": subject.trust_domain,
+		"subject_claims": subject.claims,
+		"actor": actor.subject
+	}`)
+	if err != nil {
+		t.Fatalf("failed to create CEL mapper: %v", err)
+	}
+
+	txnIssuer := issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL:                 "https://parsec.example.com",
+		TTL:                       5 * time.Minute,
+		Signer:                    signer,
+		TransactionContextMappers: []service.ClaimMapper{tctxMapper},
+		RequestContextMappers:     []service.ClaimMapper{service.NewRequestAttributesMapper()},
+		Clock:                     clk,
+		IDGenerator:               ids,
+	})
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, txnIssuer)
+
+	tokenService := service.NewTokenService("prod.example.com", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	return &goldenHarness{
+		clock:       clk,
+		ids:         ids,
+		startTime:   startTime,
+		actorJWKS:   actorJWKS,
+		subjectJWKS: subjectJWKS,
+		server:      server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil),
+	}
+}
+
+// exchange performs a token exchange from a fresh, fixed starting state and returns the issued token
+func (h *goldenHarness) exchange(t *testing.T, subjectClaims map[string]interface{}, scope string, requestContext map[string]interface{}) string {
+	t.Helper()
+
+	// Each exchange starts from the same state so golden files don't depend on test order
+	h.clock.Set(h.startTime)
+	h.ids.Reset()
+
+	actorToken, err := h.actorJWKS.CreateAndSignToken(map[string]interface{}{"sub": "api-gateway"})
+	if err != nil {
+		t.Fatalf("failed to create actor token: %v", err)
+	}
+	subjectToken, err := h.subjectJWKS.CreateAndSignToken(subjectClaims)
+	if err != nil {
+		t.Fatalf("failed to create subject token: %v", err)
+	}
+
+	req := &parsecv1.TokenExchangeRequest{
+		GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
+		Audience:           []string{"prod.example.com"},
+		RequestedTokenType: string(service.TokenTypeTransactionToken),
+		SubjectToken:       subjectToken,
+		SubjectTokenType:   "urn:ietf:params:oauth:token-type:jwt",
+		Scope:              scope,
+	}
+	if requestContext != nil {
+		data, err := json.Marshal(requestContext)
+		if err != nil {
+			t.Fatalf("failed to marshal request context: %v", err)
+		}
+		req.RequestContext = base64.StdEncoding.EncodeToString(data)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+actorToken))
+	resp, err := h.server.Exchange(ctx, req)
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	return resp.AccessToken
+}
+
+// compare checks token against its golden file, or rewrites the file with -update.
+// The golden file holds the decoded header and claims (for readable diffs) followed
+// by the compact token itself (for byte-for-byte comparison).
+func (h *goldenHarness) compare(t *testing.T, name, token string) {
+	t.Helper()
+
+	got := renderGolden(t, token)
+	path := filepath.Join("testdata", "golden", name+".golden")
+
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	if string(want) != got {
+		t.Errorf("issued token does not match %s (run with -update if intended)\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+func renderGolden(t *testing.T, token string) string {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected compact JWS, got %d parts", len(parts))
+	}
+
+	var b strings.Builder
+	for i, label := range []string{"header", "claims"} {
+		raw, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatalf("failed to decode %s: %v", label, err)
+		}
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			t.Fatalf("failed to parse %s: %v", label, err)
+		}
+		pretty, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			t.Fatalf("failed to format %s: %v", label, err)
+		}
+		fmt.Fprintf(&b, "# %s\n%s\n\n", label, pretty)
+	}
+	fmt.Fprintf(&b, "# token\n%s\n", token)
+	return b.String()
+}
+
+func loadGoldenSigningKey(t *testing.T) crypto.Signer {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "golden_signing_key.pem"))
+	if err != nil {
+		t.Fatalf("failed to read signing key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("failed to decode signing key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse signing key: %v", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		t.Fatalf("signing key is not a crypto.Signer: %T", key)
+	}
+	return signer
+}
@@ -235,7 +235,7 @@ end`,
 		// WHEN: Call the external gRPC API
 		resp, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
 			GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
-			Audience:           "prod.example.com",
+			Audience:           []string{"prod.example.com"},
 			RequestedTokenType: string(service.TokenTypeTransactionToken),
 			SubjectToken:       subjectToken,
 			SubjectTokenType:   "urn:ietf:params:oauth:token-type:jwt",
@@ -353,7 +353,7 @@ end`,
 		// WHEN: Call API with request_context
 		resp, err := exchangeServer.Exchange(ctx, &parsecv1.TokenExchangeRequest{
 			GrantType:          "urn:ietf:params:oauth:grant-type:token-exchange",
-			Audience:           "prod.example.com",
+			Audience:           []string{"prod.example.com"},
 			RequestedTokenType: string(service.TokenTypeTransactionToken),
 			SubjectToken:       subjectToken,
 			SubjectTokenType:   "urn:ietf:params:oauth:token-type:jwt",
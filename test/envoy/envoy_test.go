@@ -0,0 +1,362 @@
+package envoy_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/alechenninger/parsec/internal/httpfixture"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/keys"
+	"github.com/alechenninger/parsec/internal/server"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+const (
+	envoyImage    = "envoyproxy/envoy:v1.35-latest"
+	idpImage      = "nginx:1.29-alpine"
+	upstreamImage = "mendhak/http-https-echo:37"
+
+	idpIssuer    = "https://idp.parsec.test"
+	parsecIssuer = "https://parsec.parsec.test"
+
+	// jwksPath is where the fake IdP serves its JWKS
+	jwksPath = "/.well-known/jwks.json"
+)
+
+// TestEnvoyExtAuthz runs a real Envoy configured with parsec as its ext_authz service
+// in front of an echo upstream, with a fake IdP serving the JWKS parsec trusts.
+//
+// Parsec runs in-process; Envoy reaches it through testcontainers' host port access.
+// The echo upstream returns the headers it received, so the test can observe exactly
+// what Envoy forwarded after parsec's check.
+//
+// The test needs Docker and is skipped without it, or with -short.
+func TestEnvoyExtAuthz(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping container-based test in short mode")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx := context.Background()
+
+	nw, err := network.New(ctx)
+	testcontainers.CleanupNetwork(t, nw)
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+
+	idp, jwksURL := startIdP(ctx, t)
+	startUpstream(ctx, t, nw)
+	grpcPort, signingKey := startParsec(ctx, t, jwksURL)
+	envoyURL := startEnvoy(ctx, t, nw, grpcPort)
+
+	t.Run("valid credential is exchanged for a transaction token", func(t *testing.T) {
+		subjectToken, err := idp.CreateAndSignToken(map[string]any{"sub": "alice"})
+		if err != nil {
+			t.Fatalf("failed to create subject token: %v", err)
+		}
+
+		status, echoed := get(t, envoyURL, map[string]string{
+			"Authorization": "Bearer " + subjectToken,
+			"X-Request-Tag": "keep-me",
+		})
+		if status != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+
+		// The external credential must not reach the upstream
+		if auth, ok := echoed.Headers["authorization"]; ok {
+			t.Errorf("expected authorization header to be stripped, got %q", auth)
+		}
+		// Unrelated headers pass through untouched
+		if got := echoed.Headers["x-request-tag"]; got != "keep-me" {
+			t.Errorf("expected x-request-tag to be forwarded, got %q", got)
+		}
+
+		txnToken := echoed.Headers["transaction-token"]
+		if txnToken == "" {
+			t.Fatalf("expected transaction-token header to be injected, got headers %v", echoed.Headers)
+		}
+
+		token, err := jwt.Parse([]byte(txnToken),
+			jwt.WithKey(jwa.ES256, &signingKey.PublicKey),
+			jwt.WithValidate(true),
+			jwt.WithIssuer(parsecIssuer))
+		if err != nil {
+			t.Fatalf("transaction token does not verify with parsec's key: %v", err)
+		}
+		if token.Subject() != "alice" {
+			t.Errorf("expected subject alice, got %q", token.Subject())
+		}
+	})
+
+	t.Run("missing credential is denied", func(t *testing.T) {
+		status, _ := get(t, envoyURL, nil)
+		if status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+	})
+
+	t.Run("expired credential is denied", func(t *testing.T) {
+		subjectToken, err := idp.CreateAndSignTokenWithExpiry(map[string]any{"sub": "alice"}, time.Now().Add(-time.Minute))
+		if err != nil {
+			t.Fatalf("failed to create subject token: %v", err)
+		}
+
+		status, _ := get(t, envoyURL, map[string]string{"Authorization": "Bearer " + subjectToken})
+		if status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+	})
+
+	t.Run("credential signed by an untrusted key is denied", func(t *testing.T) {
+		impostor, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+			Issuer:  idpIssuer,
+			JWKSURL: "http://impostor" + jwksPath,
+		})
+		if err != nil {
+			t.Fatalf("failed to create impostor IdP: %v", err)
+		}
+		subjectToken, err := impostor.CreateAndSignToken(map[string]any{"sub": "alice"})
+		if err != nil {
+			t.Fatalf("failed to create subject token: %v", err)
+		}
+
+		status, _ := get(t, envoyURL, map[string]string{"Authorization": "Bearer " + subjectToken})
+		if status != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", status)
+		}
+	})
+}
+
+// startIdP serves a JWKS fixture's key set from an nginx container,
+// returning the fixture (to sign subject tokens) and the JWKS URL reachable from the host
+func startIdP(ctx context.Context, t *testing.T) (*httpfixture.JWKSFixture, string) {
+	t.Helper()
+
+	// The fixture only uses this URL to match requests; the container's URL is known after start
+	fixtureURL := "http://idp" + jwksPath
+	idp, err := httpfixture.NewJWKSFixture(httpfixture.JWKSFixtureConfig{
+		Issuer:  idpIssuer,
+		JWKSURL: fixtureURL,
+	})
+	if err != nil {
+		t.Fatalf("failed to create IdP fixture: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fixtureURL, nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	jwks := idp.GetFixture(req).Body
+
+	ctr, err := testcontainers.Run(ctx, idpImage,
+		testcontainers.WithFiles(testcontainers.ContainerFile{
+			Reader:            strings.NewReader(jwks),
+			ContainerFilePath: "/usr/share/nginx/html" + jwksPath,
+			FileMode:          0o644,
+		}),
+		testcontainers.WithExposedPorts("80/tcp"),
+		testcontainers.WithWaitStrategy(wait.ForHTTP(jwksPath).WithPort("80/tcp")),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("failed to start IdP: %v", err)
+	}
+
+	endpoint, err := ctr.PortEndpoint(ctx, "80/tcp", "http")
+	if err != nil {
+		t.Fatalf("failed to get IdP endpoint: %v", err)
+	}
+
+	return idp, endpoint + jwksPath
+}
+
+// startUpstream starts an echo server Envoy routes to as "upstream"
+func startUpstream(ctx context.Context, t *testing.T, nw *testcontainers.DockerNetwork) {
+	t.Helper()
+
+	ctr, err := testcontainers.Run(ctx, upstreamImage,
+		network.WithNetwork([]string{"upstream"}, nw),
+		testcontainers.WithEnv(map[string]string{"HTTP_PORT": "8080"}),
+		testcontainers.WithExposedPorts("8080/tcp"),
+		testcontainers.WithWaitStrategy(wait.ForListeningPort("8080/tcp")),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("failed to start upstream: %v", err)
+	}
+}
+
+// startParsec runs parsec in-process, trusting the IdP's JWKS and issuing ES256 transaction tokens.
+// It returns the gRPC port Envoy calls and the transaction token signing key.
+func startParsec(ctx context.Context, t *testing.T, jwksURL string) (int, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	validator, err := trust.NewJWTValidator(trust.JWTValidatorConfig{
+		Issuer:      idpIssuer,
+		JWKSURL:     jwksURL,
+		TrustDomain: "idp.parsec.test",
+	})
+	if err != nil {
+		t.Fatalf("failed to create JWT validator: %v", err)
+	}
+	trustStore := trust.NewStubStore().AddValidator(validator)
+
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	signer, err := keys.NewStaticSigner(signingKey, "ES256")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeTransactionToken, issuer.NewTransactionTokenIssuer(issuer.TransactionTokenIssuerConfig{
+		IssuerURL:             parsecIssuer,
+		TTL:                   5 * time.Minute,
+		Signer:                signer,
+		RequestContextMappers: []service.ClaimMapper{service.NewRequestAttributesMapper()},
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	grpcPort, httpPort := freePort(t), freePort(t)
+	srv := server.New(server.Config{
+		GRPCPort:       grpcPort,
+		HTTPPort:       httpPort,
+		AuthzServer:    server.NewAuthzServer(trustStore, tokenService, nil, nil),
+		ExchangeServer: server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil),
+		JWKSServer:     server.NewJWKSServer(server.JWKSServerConfig{IssuerRegistry: issuerRegistry}),
+	})
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("failed to start parsec: %v", err)
+	}
+	t.Cleanup(func() { srv.Stop(context.Background()) })
+
+	waitForPort(t, grpcPort, 5*time.Second)
+
+	return grpcPort, signingKey
+}
+
+// startEnvoy starts Envoy with testdata/envoy.yaml, pointed at parsec on the test host,
+// and returns the URL of its listener
+func startEnvoy(ctx context.Context, t *testing.T, nw *testcontainers.DockerNetwork, grpcPort int) string {
+	t.Helper()
+
+	config, err := os.ReadFile("testdata/envoy.yaml")
+	if err != nil {
+		t.Fatalf("failed to read envoy config: %v", err)
+	}
+	rendered := strings.ReplaceAll(string(config), "PARSEC_GRPC_PORT", strconv.Itoa(grpcPort))
+
+	ctr, err := testcontainers.Run(ctx, envoyImage,
+		network.WithNetwork([]string{"envoy"}, nw),
+		testcontainers.WithHostPortAccess(grpcPort),
+		testcontainers.WithFiles(testcontainers.ContainerFile{
+			Reader:            strings.NewReader(rendered),
+			ContainerFilePath: "/etc/envoy/envoy.yaml",
+			FileMode:          0o644,
+		}),
+		testcontainers.WithCmd("envoy", "-c", "/etc/envoy/envoy.yaml", "--log-level", "warn"),
+		testcontainers.WithExposedPorts("10000/tcp"),
+		testcontainers.WithWaitStrategy(wait.ForListeningPort("10000/tcp")),
+	)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("failed to start envoy: %v", err)
+	}
+
+	endpoint, err := ctr.PortEndpoint(ctx, "10000/tcp", "http")
+	if err != nil {
+		t.Fatalf("failed to get envoy endpoint: %v", err)
+	}
+	return endpoint
+}
+
+// echoResponse is the part of the echo upstream's response the test inspects
+type echoResponse struct {
+	Headers map[string]string `json:"headers"`
+}
+
+// get sends a request through Envoy and returns the status and, if the upstream was reached,
+// the request it echoed back
+func get(t *testing.T, envoyURL string, headers map[string]string) (int, echoResponse) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, envoyURL+"/orders/42", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request through envoy failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	var echoed echoResponse
+	if resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(body, &echoed); err != nil {
+			t.Fatalf("failed to decode echoed request: %v: %s", err, body)
+		}
+	}
+	return resp.StatusCode, echoed
+}
+
+// freePort returns a TCP port that is free on the test host
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// waitForPort polls the port until a TCP connection succeeds or the timeout is reached
+func waitForPort(t *testing.T, port int, timeout time.Duration) {
+	t.Helper()
+
+	addr := fmt.Sprintf("localhost:%d", port)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server on port %d did not become ready within %v", port, timeout)
+}
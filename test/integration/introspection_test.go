@@ -0,0 +1,144 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/server"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// TestTokenIntrospection tests that opaque tokens from token exchange can be
+// introspected per RFC 7662
+func TestTokenIntrospection(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	store := tokenstore.NewMemoryStore(nil)
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeAccessToken, issuer.NewOpaqueIssuer(issuer.OpaqueIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TokenType: string(service.TokenTypeAccessToken),
+		TTL:       5 * time.Minute,
+		Store:     store,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients: []*clientauth.Client{
+			{ID: "orders", Method: clientauth.MethodClientSecretBasic, Secret: "orders-secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client authenticator: %v", err)
+	}
+
+	srv := server.New(server.Config{
+		GRPCPort:       19097,
+		HTTPPort:       18087,
+		AuthzServer:    server.NewAuthzServer(trustStore, tokenService, nil, nil),
+		ExchangeServer: server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil),
+		JWKSServer:     server.NewJWKSServer(server.JWKSServerConfig{IssuerRegistry: issuerRegistry}),
+		IntrospectionServer: server.NewIntrospectionServer(server.IntrospectionServerConfig{
+			Store:               store,
+			ClientAuthenticator: authenticator,
+		}),
+	})
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop(ctx)
+
+	waitForServer(t, 18087, 5*time.Second)
+
+	post := func(t *testing.T, path string, form url.Values, username, password string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest("POST", "http://localhost:18087"+path, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	introspect := func(t *testing.T, token string) map[string]any {
+		t.Helper()
+		resp := post(t, "/v1/introspect", url.Values{"token": {token}}, "orders", "orders-secret")
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, body)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode introspection response: %v", err)
+		}
+		return body
+	}
+
+	resp := post(t, "/v1/token", url.Values{
+		"grant_type":           {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"requested_token_type": {string(service.TokenTypeAccessToken)},
+		"subject_token":        {"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.test"},
+		"subject_token_type":   {"urn:ietf:params:oauth:token-type:jwt"},
+		"scope":                {"orders:read"},
+	}, "", "")
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, body)
+	}
+	var exchanged struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&exchanged); err != nil {
+		t.Fatalf("Failed to decode token response: %v", err)
+	}
+
+	t.Run("active token", func(t *testing.T) {
+		body := introspect(t, exchanged.AccessToken)
+		if body["active"] != true {
+			t.Fatalf("Expected active token, got %v", body)
+		}
+		if body["iss"] != "https://parsec.test" || body["scope"] != "orders:read" {
+			t.Errorf("Unexpected introspection response: %v", body)
+		}
+		if _, ok := body["exp"].(float64); !ok {
+			t.Errorf("Expected numeric exp, got %v", body["exp"])
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		body := introspect(t, "unknown-token")
+		if len(body) != 1 || body["active"] != false {
+			t.Errorf("Expected {\"active\": false}, got %v", body)
+		}
+	})
+
+	t.Run("unauthenticated client", func(t *testing.T) {
+		resp := post(t, "/v1/introspect", url.Values{"token": {exchanged.AccessToken}}, "orders", "wrong-secret")
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", resp.StatusCode)
+		}
+	})
+}
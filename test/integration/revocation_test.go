@@ -0,0 +1,156 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alechenninger/parsec/internal/clientauth"
+	"github.com/alechenninger/parsec/internal/denylist"
+	"github.com/alechenninger/parsec/internal/issuer"
+	"github.com/alechenninger/parsec/internal/server"
+	"github.com/alechenninger/parsec/internal/service"
+	"github.com/alechenninger/parsec/internal/tokenstore"
+	"github.com/alechenninger/parsec/internal/trust"
+)
+
+// TestTokenRevocation tests that tokens revoked per RFC 7009 are no longer
+// active at the introspection endpoint
+func TestTokenRevocation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trustStore := trust.NewStubStore()
+	trustStore.AddValidator(trust.NewStubValidator(trust.CredentialTypeBearer))
+
+	store := tokenstore.NewMemoryStore(nil)
+	denied := denylist.NewMemoryDenylist(nil)
+	issuerRegistry := service.NewSimpleRegistry()
+	issuerRegistry.Register(service.TokenTypeAccessToken, issuer.NewOpaqueIssuer(issuer.OpaqueIssuerConfig{
+		IssuerURL: "https://parsec.test",
+		TokenType: string(service.TokenTypeAccessToken),
+		TTL:       5 * time.Minute,
+		Store:     store,
+	}))
+	tokenService := service.NewTokenService("parsec.test", service.NewDataSourceRegistry(), issuerRegistry, nil)
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients: []*clientauth.Client{
+			{ID: "orders", Method: clientauth.MethodClientSecretBasic, Secret: "orders-secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client authenticator: %v", err)
+	}
+
+	// Clients authenticate at the exchange, so the tokens they are issued record them
+	exchangeServer := server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil)
+	exchangeServer.ClientAuthenticator = authenticator
+
+	srv := server.New(server.Config{
+		GRPCPort:       19098,
+		HTTPPort:       18088,
+		AuthzServer:    server.NewAuthzServer(trustStore, tokenService, nil, nil),
+		ExchangeServer: exchangeServer,
+		JWKSServer:     server.NewJWKSServer(server.JWKSServerConfig{IssuerRegistry: issuerRegistry}),
+		IntrospectionServer: server.NewIntrospectionServer(server.IntrospectionServerConfig{
+			Store:               store,
+			Denylist:            denied,
+			ClientAuthenticator: authenticator,
+		}),
+		RevocationServer: server.NewRevocationServer(server.RevocationServerConfig{
+			Denylist:            denied,
+			Store:               store,
+			IssuerRegistry:      issuerRegistry,
+			ClientAuthenticator: authenticator,
+		}),
+	})
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop(ctx)
+
+	waitForServer(t, 18088, 5*time.Second)
+
+	post := func(t *testing.T, path string, form url.Values, username, password string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest("POST", "http://localhost:18088"+path, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	revoke := func(t *testing.T, token string) {
+		t.Helper()
+		resp := post(t, "/v1/revoke", url.Values{"token": {token}, "token_type_hint": {"access_token"}}, "orders", "orders-secret")
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, body)
+		}
+	}
+
+	resp := post(t, "/v1/token", url.Values{
+		"grant_type":           {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"requested_token_type": {string(service.TokenTypeAccessToken)},
+		"subject_token":        {"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.test"},
+		"subject_token_type":   {"urn:ietf:params:oauth:token-type:jwt"},
+	}, "orders", "orders-secret")
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, body)
+	}
+	var exchanged struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&exchanged); err != nil {
+		t.Fatalf("Failed to decode token response: %v", err)
+	}
+
+	t.Run("unauthenticated client", func(t *testing.T) {
+		resp := post(t, "/v1/revoke", url.Values{"token": {exchanged.AccessToken}}, "orders", "wrong-secret")
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("revoked token is inactive", func(t *testing.T) {
+		revoke(t, exchanged.AccessToken)
+
+		resp := post(t, "/v1/introspect", url.Values{"token": {exchanged.AccessToken}}, "orders", "orders-secret")
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("Expected status 200, got %d. Body: %s", resp.StatusCode, body)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode introspection response: %v", err)
+		}
+		if body["active"] != false {
+			t.Errorf("Expected revoked token to be inactive, got %v", body)
+		}
+	})
+
+	t.Run("revoking again succeeds", func(t *testing.T) {
+		revoke(t, exchanged.AccessToken)
+	})
+
+	t.Run("unknown token succeeds", func(t *testing.T) {
+		revoke(t, "unknown-token")
+	})
+}
@@ -2,6 +2,7 @@ package integration
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alechenninger/parsec/internal/clientauth"
 	"github.com/alechenninger/parsec/internal/issuer"
 	"github.com/alechenninger/parsec/internal/server"
 	"github.com/alechenninger/parsec/internal/service"
@@ -195,3 +197,230 @@ func TestTokenExchangeJSON(t *testing.T) {
 	fmt.Printf("✓ Token exchange request with JSON succeeded\n")
 	fmt.Printf("  Response: %s\n", body)
 }
+
+// TestTokenExchangeJSONRepeatedParameters tests that JSON requests can send
+// several audiences and resources as lists
+func TestTokenExchangeJSONRepeatedParameters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trustStore, tokenService, issuerRegistry := setupTestDependencies()
+
+	exchangeServer := server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil)
+	exchangeServer.AllowedAudiences = []string{"orders.parsec.test", "https://orders.parsec.test/api"}
+
+	srv := server.New(server.Config{
+		GRPCPort:       19099,
+		HTTPPort:       18089,
+		AuthzServer:    server.NewAuthzServer(trustStore, tokenService, nil, nil),
+		ExchangeServer: exchangeServer,
+		JWKSServer:     server.NewJWKSServer(server.JWKSServerConfig{IssuerRegistry: issuerRegistry}),
+	})
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop(ctx)
+
+	waitForServer(t, 18089, 5*time.Second)
+
+	jsonData := `{
+		"grant_type": "urn:ietf:params:oauth:grant-type:token-exchange",
+		"subject_token": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.test",
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"audience": ["parsec.test", "orders.parsec.test"],
+		"resource": ["https://orders.parsec.test/api"]
+	}`
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post("http://localhost:18089/v1/token", "application/json", strings.NewReader(jsonData))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d. Body: %s", resp.StatusCode, body)
+	}
+}
+
+// TestTokenExchangeOAuthError tests that exchange failures are RFC 6749 error responses
+// that off-the-shelf OAuth clients understand
+func TestTokenExchangeOAuthError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trustStore, tokenService, issuerRegistry := setupTestDependencies()
+
+	srv := server.New(server.Config{
+		GRPCPort:       19095,
+		HTTPPort:       18085,
+		AuthzServer:    server.NewAuthzServer(trustStore, tokenService, nil, nil),
+		ExchangeServer: server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil),
+		JWKSServer:     server.NewJWKSServer(server.JWKSServerConfig{IssuerRegistry: issuerRegistry}),
+	})
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop(ctx)
+
+	waitForServer(t, 18085, 5*time.Second)
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name: "unsupported grant type",
+			form: url.Values{
+				"grant_type":    {"client_credentials"},
+				"subject_token": {"token"},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "unsupported_grant_type",
+		},
+		{
+			name: "audience that is not allowed",
+			form: url.Values{
+				"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+				"subject_token":      {"token"},
+				"subject_token_type": {"urn:ietf:params:oauth:token-type:jwt"},
+				"audience":           {"other.example.com"},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid_target",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post("http://localhost:18085/v1/token",
+				"application/x-www-form-urlencoded", strings.NewReader(tt.form.Encode()))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if cc := resp.Header.Get("Cache-Control"); cc != "no-store" {
+				t.Errorf("Expected Cache-Control no-store, got %q", cc)
+			}
+
+			var body struct {
+				Error            string `json:"error"`
+				ErrorDescription string `json:"error_description"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode error response: %v", err)
+			}
+			if body.Error != tt.wantError {
+				t.Errorf("Expected error %s, got %s", tt.wantError, body.Error)
+			}
+			if body.ErrorDescription == "" {
+				t.Error("Expected error_description")
+			}
+		})
+	}
+}
+
+// TestTokenExchangeClientAuthentication tests that registered clients must authenticate
+// to the token endpoint, with HTTP Basic or request parameters
+func TestTokenExchangeClientAuthentication(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	trustStore, tokenService, issuerRegistry := setupTestDependencies()
+
+	authenticator, err := clientauth.NewAuthenticator(clientauth.AuthenticatorConfig{
+		Clients: []*clientauth.Client{
+			{ID: "gateway", Method: clientauth.MethodClientSecretBasic, Secret: "gateway-secret"},
+			{ID: "portal", Method: clientauth.MethodClientSecretPost, Secret: "portal-secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create client authenticator: %v", err)
+	}
+	exchangeServer := server.NewExchangeServer(trustStore, tokenService, server.NewStubClaimsFilterRegistry(), nil)
+	exchangeServer.ClientAuthenticator = authenticator
+
+	srv := server.New(server.Config{
+		GRPCPort:       19096,
+		HTTPPort:       18086,
+		AuthzServer:    server.NewAuthzServer(trustStore, tokenService, nil, nil),
+		ExchangeServer: exchangeServer,
+		JWKSServer:     server.NewJWKSServer(server.JWKSServerConfig{IssuerRegistry: issuerRegistry}),
+	})
+
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop(ctx)
+
+	waitForServer(t, 18086, 5*time.Second)
+
+	exchange := func(t *testing.T, form url.Values, username, password string) *http.Response {
+		t.Helper()
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:token-exchange")
+		form.Set("subject_token", "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.test")
+		form.Set("subject_token_type", "urn:ietf:params:oauth:token-type:jwt")
+		req, err := http.NewRequest("POST", "http://localhost:18086/v1/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("client_secret_basic", func(t *testing.T) {
+		resp := exchange(t, url.Values{}, "gateway", "gateway-secret")
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Errorf("Expected status 200, got %d. Body: %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("client_secret_post", func(t *testing.T) {
+		resp := exchange(t, url.Values{"client_id": {"portal"}, "client_secret": {"portal-secret"}}, "", "")
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Errorf("Expected status 200, got %d. Body: %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("unauthenticated client", func(t *testing.T) {
+		resp := exchange(t, url.Values{}, "gateway", "wrong-secret")
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected status 401, got %d", resp.StatusCode)
+		}
+		if challenge := resp.Header.Get("WWW-Authenticate"); !strings.HasPrefix(challenge, "Basic") {
+			t.Errorf("Expected Basic challenge, got %q", challenge)
+		}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode error response: %v", err)
+		}
+		if body.Error != "invalid_client" {
+			t.Errorf("Expected error invalid_client, got %s", body.Error)
+		}
+	})
+}